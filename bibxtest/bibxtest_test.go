@@ -0,0 +1,53 @@
+package bibxtest
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCorpus(t *testing.T) {
+	for _, name := range Corpus() {
+		t.Run(name, func(t *testing.T) {
+			nodes, err := parseNodes(Load(t, name))
+			if have, want := err != nil, name == Malformed; have != want {
+				t.Fatalf("have error %v; want %t", err, want)
+			}
+			if len(nodes) == 0 {
+				t.Error("have no nodes")
+			}
+		})
+	}
+}
+
+func TestGolden(t *testing.T) {
+	for _, name := range Corpus() {
+		t.Run(name, func(t *testing.T) {
+			src := Load(t, name)
+			Golden(t, name+".tokens", Tokens(src))
+			Golden(t, name+".tree", Tree(src))
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	cases := []struct {
+		name string
+		have fmt.Stringer
+		want string
+	}{
+		{"entry", Entry("article", "k", "title", "{T}", "year", "1984", "odd"), "@article{k, title = {T}, year = 1984}"},
+		{"abbrev", Abbrev("acm", "{ACM}"), "@string{acm = {ACM}}"},
+		{"preamble", Preamble(`"x"`), `@preamble{"x"}`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := c.have.String(); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+	src := "@article{k, title = {T}, year = 1984}"
+	if have := Parse(t, []byte(src))[0]; !have.Eq(Entry("article", "k", "title", "{T}", "year", "1984")) {
+		t.Errorf("have %v; want %s", have, src)
+	}
+}
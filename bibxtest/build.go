@@ -0,0 +1,27 @@
+package bibxtest
+
+import "github.com/mdm-code/bibx/internal/parse"
+
+// Entry builds an entry of the type with the cite key and the fields given
+// as pairs of keys and values, the values written as in the source, for
+// example {Title} or 1984. A trailing key without a value is ignored.
+func Entry(typ, key string, fields ...string) *parse.EntryDecl {
+	e := &parse.EntryDecl{Name: typ, CiteKey: key, Comments: &parse.CommentGroupExpr{}}
+	for i := 0; i+1 < len(fields); i += 2 {
+		e.Fields = append(e.Fields, &parse.FieldStmt{Key: fields[i], Value: fields[i+1]})
+	}
+	return e
+}
+
+// Abbrev builds a string abbreviation of the value under the key.
+func Abbrev(key, value string) *parse.AbbrevDecl {
+	return &parse.AbbrevDecl{
+		Comments: &parse.CommentGroupExpr{},
+		Field:    &parse.FieldStmt{Key: key, Value: value},
+	}
+}
+
+// Preamble builds a preamble of the value.
+func Preamble(value string) *parse.PreambleDecl {
+	return &parse.PreambleDecl{Comments: &parse.CommentGroupExpr{}, Value: value}
+}
@@ -0,0 +1,70 @@
+package bibxtest
+
+import (
+	"bytes"
+	"embed"
+	"io/fs"
+	"path"
+	"sort"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+//go:embed corpus/*.bib
+var corpus embed.FS
+
+// Malformed names the file of the corpus the parser fails on.
+const Malformed = "malformed.bib"
+
+// Corpus returns the names of the files of the corpus in lexical order.
+func Corpus() []string {
+	entries, _ := fs.ReadDir(corpus, "corpus")
+	result := make([]string, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, e.Name())
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Load returns the contents of the named file of the corpus, failing the
+// test if there is no such file.
+func Load(tb testing.TB, name string) []byte {
+	tb.Helper()
+	data, err := corpus.ReadFile(path.Join("corpus", name))
+	if err != nil {
+		tb.Fatalf("bibxtest: no corpus file %s", name)
+	}
+	return data
+}
+
+// Parse parses the source, failing the test if it is not valid BibTeX.
+func Parse(tb testing.TB, src []byte) []parse.Node {
+	tb.Helper()
+	nodes, err := parseNodes(src)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return nodes
+}
+
+// ParseNodes parses the source, returning the nodes parsed before the
+// parser stopped along with an error telling where it failed.
+func parseNodes(src []byte) ([]parse.Node, error) {
+	p := parse.NewParser(scan.NewScanner(scan.NewReader(bytes.NewReader(src))))
+	result := []parse.Node{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		result = append(result, n)
+	}
+	if p.Err() != nil {
+		return result, &parseError{pos: p.ErrPos()}
+	}
+	return result, nil
+}
+
+// ParseError reports where the parser failed.
+type parseError struct{ pos scan.Pos }
+
+func (e *parseError) Error() string { return "bibxtest: invalid BibTeX syntax at " + e.pos.String() }
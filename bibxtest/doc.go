@@ -0,0 +1,14 @@
+/*
+Bibxtest package helps test code working with BibTeX against realistic
+inputs. It ships a curated corpus of bibliographies covering the syntax the
+parser accepts, from abbreviations and concatenated values to comments,
+parenthesized entries and non-ASCII text, along with a file that fails to
+parse at a known position. Tokens and Tree render the items of a scanner
+and the nodes of a parser as text suited for comparison with golden files
+by Golden, and Entry, Abbrev and Preamble build nodes without a source.
+
+Golden files are kept in the testdata directory of the package under test
+and written anew when the BIBXTEST_UPDATE environment variable is set to a
+non-empty value.
+*/
+package bibxtest
@@ -0,0 +1,123 @@
+package bibxtest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/diff"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+// UpdateEnv names the environment variable that makes Golden write the
+// golden files instead of comparing against them.
+const UpdateEnv = "BIBXTEST_UPDATE"
+
+// Golden compares have with the golden file testdata/name of the package
+// under test and fails the test with a unified diff when they differ. The
+// golden file is written instead when the UpdateEnv variable is set.
+func Golden(tb testing.TB, name string, have []byte) {
+	tb.Helper()
+	path := filepath.Join("testdata", name)
+	if os.Getenv(UpdateEnv) != `` {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			tb.Fatal(err)
+		}
+		if err := os.WriteFile(path, have, 0o644); err != nil {
+			tb.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatalf("bibxtest: %v; set %s=1 to write it", err, UpdateEnv)
+	}
+	if !bytes.Equal(have, want) {
+		tb.Errorf("bibxtest: %s differs from the golden file:\n%s", name, diff.Unified(path, want, name, have))
+	}
+}
+
+var itemNames = map[scan.ItemType]string{
+	scan.ItemErr:        "error",
+	scan.ItemEOF:        "eof",
+	scan.ItemEntryDelim: "entry-delim",
+	scan.ItemLeftBrace:  "left-brace",
+	scan.ItemRightBrace: "right-brace",
+	scan.ItemLeftDelim:  "left-delim",
+	scan.ItemRightDelim: "right-delim",
+	scan.ItemLeftParen:  "left-paren",
+	scan.ItemRightParen: "right-paren",
+	scan.ItemEqSgn:      "equal-sign",
+	scan.ItemComma:      "comma",
+	scan.ItemCiteKey:    "cite-key",
+	scan.ItemEntry:      "entry",
+	scan.ItemComment:    "comment",
+	scan.ItemAbbrev:     "abbrev",
+	scan.ItemPreamble:   "preamble",
+	scan.ItemFieldType:  "field-type",
+	scan.ItemFieldText:  "field-text",
+	scan.ItemTexCode:    "tex-code",
+}
+
+// Tokens renders the items scanned from the source one per line with their
+// positions, ending with the end of the input or the error.
+func Tokens(src []byte) []byte {
+	s := scan.NewScanner(scan.NewReader(bytes.NewReader(src)))
+	var b bytes.Buffer
+	for {
+		i := s.Next()
+		fmt.Fprintf(&b, "%s %s %q\n", s.Pos(), itemNames[i.T], i.Val)
+		if i.T == scan.ItemEOF || i.T == scan.ItemErr {
+			return b.Bytes()
+		}
+	}
+}
+
+// Tree renders the nodes parsed from the source one declaration per line
+// with their positions, indenting the fields of entries under them, and
+// ends with the position the parser failed at if it did.
+func Tree(src []byte) []byte {
+	nodes, err := parseNodes(src)
+	var b bytes.Buffer
+	for _, n := range nodes {
+		writeNode(&b, n)
+	}
+	if err != nil {
+		fmt.Fprintln(&b, err)
+	}
+	return b.Bytes()
+}
+
+func writeNode(b *bytes.Buffer, n parse.Node) {
+	switch n := n.(type) {
+	case *parse.EntryDecl:
+		writeComments(b, n.Comments)
+		fmt.Fprintf(b, "%s @%s{%s}\n", n.Pos, n.Name, n.CiteKey)
+		for _, f := range n.Fields {
+			fmt.Fprintf(b, "  %s %s\n", f.Pos, f)
+		}
+	case *parse.AbbrevDecl:
+		writeComments(b, n.Comments)
+		fmt.Fprintf(b, "%s %s\n", n.Pos, n)
+	case *parse.PreambleDecl:
+		writeComments(b, n.Comments)
+		fmt.Fprintf(b, "%s %s\n", n.Pos, n)
+	case *parse.CommentGroupExpr:
+		writeComments(b, n)
+	default:
+		fmt.Fprintf(b, "- %s\n", n.Kind())
+	}
+}
+
+func writeComments(b *bytes.Buffer, c *parse.CommentGroupExpr) {
+	if c == nil {
+		return
+	}
+	for _, v := range c.Values {
+		fmt.Fprintf(b, "- comment %q\n", strings.TrimSpace(v.Value))
+	}
+}
@@ -0,0 +1,43 @@
+//go:build js && wasm
+
+// Command bibx-wasm exposes the bibx parser, linter and formatter to
+// JavaScript when compiled with GOOS=js GOARCH=wasm. It defines a global
+// bibx object whose parse, lint, fix and format functions take the BibTeX
+// source as a string and return an object with either the result or the
+// error message:
+//
+//	const {result, error} = bibx.format(source);
+//
+// The results of parse and lint are JSON strings.
+package main
+
+import (
+	"syscall/js"
+
+	"github.com/mdm-code/bibx/internal/bindings"
+)
+
+func main() {
+	js.Global().Set("bibx", js.ValueOf(map[string]any{
+		"parse":  wrap(bindings.Parse),
+		"lint":   wrap(bindings.Lint),
+		"fix":    wrap(bindings.Fix),
+		"format": wrap(bindings.Format),
+	}))
+	select {}
+}
+
+// Wrap turns the function into a JavaScript function taking the source as
+// its only argument.
+func wrap(f func(string) (string, error)) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) != 1 || args[0].Type() != js.TypeString {
+			return map[string]any{"error": "expected the BibTeX source as a string"}
+		}
+		result, err := f(args[0].String())
+		if err != nil {
+			return map[string]any{"error": err.Error()}
+		}
+		return map[string]any{"result": result}
+	})
+}
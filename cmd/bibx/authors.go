@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/dedupe"
+	"github.com/mdm-code/bibx/internal/format"
+)
+
+// AuthorsCmd lists the names written in more than one way across the
+// entries, each under its canonical form, or offers to unify them.
+func authorsCmd(args []string) error {
+	fs := flag.NewFlagSet("authors", flag.ExitOnError)
+	interactive := fs.Bool("i", false, "review the variants of a single file one by one and write the unified names back")
+	dryRun := fs.Bool("dry-run", false, "with -i, print a unified diff of the changes instead; nothing is written")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bibx authors [-i [-dry-run]] [file ...]")
+		fmt.Fprintln(fs.Output(), "\nEach name written in several ways is listed in its most complete form,")
+		fmt.Fprintln(fs.Output(), "followed by its variants and the keys of the entries they are found in.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *interactive {
+		if fs.NArg() != 1 {
+			return errors.New("authors -i needs exactly one file")
+		}
+		return unifyFile(fs.Arg(0), bufio.NewReader(os.Stdin), writeMode{write: true, dryRun: *dryRun})
+	}
+	es, err := readEntries(fs.Args())
+	if err != nil {
+		return err
+	}
+	for _, c := range dedupe.NameVariants(es) {
+		showVariants(stdout, c)
+	}
+	return nil
+}
+
+// ShowVariants writes the variants of the cluster numbered from 1, the
+// canonical one first.
+func showVariants(w io.Writer, c dedupe.NameCluster) {
+	for i, v := range c {
+		fmt.Fprintf(w, "%d. %s\t%s\n", i+1, v.Text, strings.Join(v.Keys, " "))
+	}
+	fmt.Fprintln(w)
+}
+
+// UnifyFile shows the clusters of name variants of the file and asks the
+// user which variant, if any, the others are rewritten to. The file is
+// written back once the user is done or quits, as the mode says.
+func unifyFile(path string, in *bufio.Reader, mode writeMode) error {
+	src, err := readSource(path)
+	if err != nil {
+		return err
+	}
+	nodes, err := parseSource(path, src)
+	if err != nil {
+		return err
+	}
+	es := entries(nodes)
+	canonical := map[string]string{}
+clusters:
+	for _, c := range dedupe.NameVariants(es) {
+		showVariants(os.Stderr, c)
+		for {
+			answer, err := ask(in, fmt.Sprintf("unify as [1-%d], s to skip, q to quit [1]: ", len(c)))
+			if err != nil {
+				return err
+			}
+			if answer == "q" {
+				break clusters
+			}
+			if answer == "s" {
+				break
+			}
+			if answer == "" {
+				answer = "1"
+			}
+			i, err := strconv.Atoi(answer)
+			if err != nil || i < 1 || i > len(c) {
+				fmt.Fprintf(os.Stderr, "answer a number from 1 to %d, s or q\n", len(c))
+				continue
+			}
+			for _, v := range c {
+				if v.Text != c[i-1].Text {
+					canonical[v.Text] = c[i-1].Text
+				}
+			}
+			break
+		}
+	}
+	if len(canonical) == 0 {
+		return nil
+	}
+	n := dedupe.UnifyNames(es, canonical)
+	fmt.Fprintf(os.Stderr, "bibx: unified %d name(s)\n", n)
+	var b bytes.Buffer
+	if err := format.Nodes(&b, nodes); err != nil {
+		return err
+	}
+	return writeResult(stdout, path, src, b.Bytes(), mode)
+}
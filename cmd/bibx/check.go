@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/lint"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/report"
+	"github.com/mdm-code/bibx/internal/resolve"
+	"github.com/mdm-code/bibx/internal/validate"
+)
+
+// CheckCmd lints and validates each of the files on its own and fails if any
+// of them has errors. Lint findings are warnings and do not fail the check,
+// and files that cannot be read are reported as errors without stopping it.
+func checkCmd(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	stdinFiles := fs.Bool("stdin-files", false, "also check the files listed on stdin, one path per line or NUL-terminated")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	var target *lint.Target
+	fs.Func("target", "report as errors what the `backend` rejects or drops: biber, bibtex8 or bibtex", func(name string) error {
+		b, ok := lint.ParseBackend(name)
+		if !ok {
+			return fmt.Errorf("unknown backend %q", name)
+		}
+		target = &lint.Target{Backend: b}
+		return nil
+	})
+	set := validate.Builtin
+	fs.Func("schema", "load additional schemas and house rules from a JSON or YAML `file`; may be repeated", func(path string) error {
+		s, err := validate.LoadFile(path)
+		if err != nil {
+			return err
+		}
+		set = set.Merge(s)
+		return nil
+	})
+	fs.Parse(args)
+
+	paths := fs.Args()
+	if *stdinFiles {
+		list, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, splitPaths(list)...)
+	}
+	rep := &report.Report{}
+	for i, path := range paths {
+		src, err := readSource(path)
+		if err != nil {
+			rep.Add(path, report.Unreadable(err))
+			trackFiles(i+1, len(paths))
+			continue
+		}
+		checkSource(rep, path, src, set, target)
+		rep.Quote(path, src)
+		logFile(path)
+		trackFiles(i+1, len(paths))
+	}
+	if err := writeReport(rep, *asJSON); err != nil {
+		return err
+	}
+	if n := rep.Count(report.Error); n > 0 {
+		return fmt.Errorf("%d error(s) found", n)
+	}
+	return nil
+}
+
+// SplitPaths splits the list of paths on NUL characters if there are any,
+// as written by `git diff -z` and `find -print0`, and on line feeds
+// otherwise. Empty paths are skipped.
+func splitPaths(list []byte) []string {
+	sep := "\n"
+	if bytes.IndexByte(list, 0) >= 0 {
+		sep = "\x00"
+	}
+	result := []string{}
+	for _, p := range strings.Split(string(list), sep) {
+		if p = strings.TrimRight(p, "\r"); p != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// CheckSource adds the syntax error, or else the lint findings, the schema
+// violations and the broken references of the source to the report, along
+// with what the target backend does not handle unless target is nil.
+func checkSource(rep *report.Report, path string, src []byte, set *validate.Set, target *lint.Target) {
+	p := newParser(bytes.NewReader(src))
+	nodes := []parse.Node{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		nodes = append(nodes, n)
+	}
+	if err := p.Err(); err != nil {
+		rep.Add(path, report.Failure(err, p.ErrPos()))
+		return
+	}
+	rules := lint.DefaultRules()
+	if target != nil {
+		rules = lint.BackendRules(target.Backend)
+	}
+	for _, f := range lint.Run(nodes, rules...) {
+		rep.Add(path, f.Report())
+	}
+	if target != nil {
+		for _, f := range lint.Run(nodes, *target) {
+			r := f.Report()
+			r.Severity = report.Error
+			rep.Add(path, r)
+		}
+	}
+	es := entries(nodes)
+	for _, e := range es {
+		for _, v := range set.Validate(e) {
+			rep.Add(path, v.Report())
+		}
+	}
+	for _, err := range resolve.Check(es) {
+		if e, ok := err.(*resolve.Error); ok {
+			rep.Add(path, e.Report())
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/config"
+)
+
+// ConfigCmd prints the effective settings along with the layers they come
+// from.
+func configCmd(args []string) error {
+	fs := flag.NewFlagSet("config", flag.ExitOnError)
+	path := fs.String("config", "", "project configuration `file`; defaults to the closest "+config.FileName+" up from the working directory")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bibx config [-config file] [key ...]")
+		fmt.Fprintln(fs.Output(), "\nSettings are layered from the built-in defaults, the user configuration file,")
+		fmt.Fprintln(fs.Output(), "the project configuration file and the command-line flags, each overriding the")
+		fmt.Fprintln(fs.Output(), "previous ones. Each setting is printed with the layer it comes from.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	cfg, paths, err := loadConfig(*path)
+	if err != nil {
+		return err
+	}
+	keys := fs.Args()
+	if len(keys) == 0 {
+		if user, ok := config.UserFile(); ok && (len(paths) == 0 || paths[0] != user) {
+			fmt.Fprintf(stdout, "# not found: %s\n", user)
+		}
+		for _, p := range paths {
+			fmt.Fprintf(stdout, "# loaded: %s\n", p)
+		}
+		keys = config.Keys
+	}
+	width := 0
+	for _, k := range keys {
+		if len(k) > width {
+			width = len(k)
+		}
+	}
+	for _, k := range keys {
+		v, ok := cfg.Value(k)
+		if !ok {
+			return fmt.Errorf("unknown key %q", k)
+		}
+		fmt.Fprintf(stdout, "%-*s = %s  # %s\n", width, k, v, cfg.Source(k))
+	}
+	return nil
+}
+
+// LoadConfig resolves the settings from the defaults, the user configuration
+// file and the project configuration file, which is found from the working
+// directory up unless its path is given. It returns the paths of the files
+// read.
+func loadConfig(path string) (*config.Config, []string, error) {
+	if path == "" {
+		return config.Resolve(".")
+	}
+	cfg := config.Default()
+	paths := []string{}
+	if user, ok := config.UserFile(); ok {
+		err := cfg.LoadFile(user)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, nil, err
+		}
+		if err == nil {
+			paths = append(paths, user)
+		}
+	}
+	if err := cfg.LoadFile(path); err != nil {
+		return nil, nil, err
+	}
+	return cfg, append(paths, path), nil
+}
+
+// ConfigFlags defines flags overriding the settings under the keys. The
+// flags are named after the keys without their table and are applied by
+// the returned function once the flags are parsed.
+func configFlags(fs *flag.FlagSet, usages map[string]string) func(*config.Config) error {
+	values := make(map[string]*string)
+	for key, usage := range usages {
+		_, name, _ := strings.Cut(key, ".")
+		values[key] = fs.String(name, "", usage)
+	}
+	return func(cfg *config.Config) error {
+		var err error
+		fs.Visit(func(f *flag.Flag) {
+			for key, v := range values {
+				if _, name, _ := strings.Cut(key, "."); name == f.Name && err == nil {
+					err = cfg.Set(key, *v, config.FlagSource)
+				}
+			}
+		})
+		return err
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/compressed"
+	"github.com/mdm-code/bibx/internal/formats"
+	"github.com/mdm-code/bibx/internal/jsonl"
+	"github.com/mdm-code/bibx/internal/parallel"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/plugin"
+	"github.com/mdm-code/bibx/internal/tabular"
+	"github.com/mdm-code/bibx/internal/transform"
+)
+
+// Delimiters of the tabular input formats read with a column mapping.
+var delimiters = map[string]rune{
+	"csv": ',',
+	"tsv": '\t',
+}
+
+// ConvertCmd translates entries between the bibliography formats.
+func convertCmd(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	from := fs.String("from", "bibtex", "input format: bibtex, nbib, arxiv, bibtexml, csl, text, csv, tsv or a bibx-convert-<format> plugin")
+	to := fs.String("to", "bibtex", "output format: bibtex, jsonl, ooxml, bibtexml, csl or a bibx-convert-<format> plugin")
+	columns := fs.String("columns", "", "csv/tsv column mapping as `column=field,...`")
+	typeColumn := fs.String("type-column", "", "csv/tsv column holding the entry type")
+	keyColumn := fs.String("key-column", "", "csv/tsv column holding the cite key")
+	defaultType := fs.String("default-type", "misc", "csv/tsv entry type used when the type column is empty")
+	encoding := fs.String("encoding", "unicode", "bibtex output encoding: unicode, or ascii to escape non-ASCII characters")
+	jobs := fs.Int("j", runtime.NumCPU(), "number of files read concurrently")
+	fs.Parse(args)
+
+	enc, ok := encodings[*encoding]
+	if !ok {
+		return fmt.Errorf("unknown encoding %q", *encoding)
+	}
+
+	read, ok := formats.Readers[*from]
+	if comma, isTabular := delimiters[*from]; isTabular {
+		m := tabular.Mapping{
+			Comma:       comma,
+			TypeColumn:  *typeColumn,
+			KeyColumn:   *keyColumn,
+			DefaultType: *defaultType,
+		}
+		if *columns != "" {
+			m.Columns = make(map[string]string)
+			for _, pair := range strings.Split(*columns, ",") {
+				col, field, found := strings.Cut(pair, "=")
+				if !found {
+					return fmt.Errorf("invalid column mapping %q", pair)
+				}
+				m.Columns[col] = field
+			}
+		}
+		read, ok = func(r io.Reader) ([]*parse.EntryDecl, error) { return tabular.Read(r, m) }, true
+	}
+	// Formats not built in are converted by bibx-convert-<format> plugins.
+	if !ok {
+		var err error
+		if read, err = plugin.Reader(*from); err != nil {
+			return fmt.Errorf("unknown input format %q", *from)
+		}
+	}
+	write, ok := formats.Writers[*to]
+	if !ok {
+		var err error
+		if write, err = plugin.Writer(*to); err != nil {
+			return fmt.Errorf("unknown output format %q", *to)
+		}
+	}
+	paths := fs.Args()
+	if *from == "bibtex" && *to == "jsonl" {
+		return streamJSONL(paths)
+	}
+	result := []*parse.EntryDecl{}
+	if len(paths) == 0 {
+		es, err := read(compressed.NewReader(os.Stdin))
+		if err != nil {
+			return err
+		}
+		result = es
+	}
+	// The files are read concurrently, and their entries are collected in
+	// the order of the files.
+	type batch struct {
+		es  []*parse.EntryDecl
+		err error
+	}
+	work := func(i int) batch {
+		f, err := openSource(paths[i])
+		if err != nil {
+			return batch{err: err}
+		}
+		defer f.Close()
+		es, err := read(compressed.NewReader(f))
+		if err != nil {
+			return batch{err: fmt.Errorf("%s: %w", paths[i], err)}
+		}
+		logFile(paths[i])
+		return batch{es: es}
+	}
+	err := parallel.Ordered(len(paths), *jobs, work, func(i int, res batch) error {
+		trackFiles(i+1, len(paths))
+		result = append(result, res.es...)
+		return res.err
+	})
+	if err != nil {
+		return err
+	}
+	if *to == "bibtex" {
+		for _, e := range result {
+			transform.Encode(e, enc)
+		}
+	}
+	return write(stdout, result)
+}
+
+// StreamJSONL writes BibTeX entries as JSON Lines as soon as they are parsed
+// instead of collecting them first.
+func streamJSONL(paths []string) error {
+	if len(paths) == 0 {
+		if err := jsonl.Stream(stdout, newParser(os.Stdin)); err != nil {
+			return fmt.Errorf("<stdin>: %w", err)
+		}
+		return nil
+	}
+	for _, path := range paths {
+		f, err := openSource(path)
+		if err != nil {
+			return err
+		}
+		err = jsonl.Stream(stdout, newParser(f))
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/mdm-code/bibx/internal/citekey"
+	"github.com/mdm-code/bibx/internal/dedupe"
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/library"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// DedupeCmd lists pairs of entries likely to be duplicates of each other.
+func dedupeCmd(args []string) error {
+	fs := flag.NewFlagSet("dedupe", flag.ExitOnError)
+	threshold := fs.Float64("threshold", 0.85, "minimum similarity score between 0 and 1 of the reported pairs")
+	exact := fs.Bool("exact", false, "list groups of entries with identical content only")
+	interactive := fs.Bool("i", false, "review the pairs of a single file one by one and write the merged duplicates back")
+	dryRun := fs.Bool("dry-run", false, "let -i print a unified diff of the merges instead; nothing is written")
+	fs.Parse(args)
+
+	if *interactive {
+		if fs.NArg() != 1 {
+			return errors.New("dedupe -i needs exactly one file")
+		}
+		return dedupeFile(fs.Arg(0), *threshold, bufio.NewReader(os.Stdin), writeMode{write: !*dryRun, dryRun: *dryRun})
+	}
+	es, err := readEntries(fs.Args())
+	if err != nil {
+		return err
+	}
+	if *exact {
+		for _, g := range dedupe.Exact(es) {
+			keys := []string{}
+			for _, e := range g {
+				keys = append(keys, e.CiteKey)
+			}
+			fmt.Fprintln(stdout, strings.Join(keys, "\t"))
+		}
+		return nil
+	}
+	for _, p := range candidates(es, *threshold) {
+		fmt.Fprintf(stdout, "%.2f\t%s\t%s\n", p.Score, p.A.CiteKey, p.B.CiteKey)
+	}
+	return nil
+}
+
+// DedupeFile shows the candidate pairs of the file side by side and asks
+// the user whether to merge them, which key survives and which value of
+// each conflicting field is kept. The references to the key dropped are
+// updated, and the result is handed over as the mode tells once the user is
+// done or quits.
+func dedupeFile(path string, threshold float64, in *bufio.Reader, mode writeMode) error {
+	src, err := readSource(path)
+	if err != nil {
+		return err
+	}
+	nodes, err := parseSource(path, src)
+	if err != nil {
+		return err
+	}
+	// Entries merged into others map to nil, and the surviving entries map
+	// to their merged replacements.
+	merged := map[*parse.EntryDecl]*parse.EntryDecl{}
+	current := func(e *parse.EntryDecl) *parse.EntryDecl {
+		for e != nil {
+			next, ok := merged[e]
+			if !ok {
+				return e
+			}
+			e = next
+		}
+		return nil
+	}
+	renamed := map[string]string{}
+pairs:
+	for _, pair := range candidates(entries(nodes), threshold) {
+		a, b := current(pair.A), current(pair.B)
+		if a == nil || b == nil || a == b {
+			continue
+		}
+		showPair(os.Stderr, pair.Score, a, b)
+		switch answer, err := ask(in, "merge? [y/N/q] "); {
+		case err != nil:
+			return err
+		case answer == "q":
+			break pairs
+		case answer != "y":
+			continue
+		}
+		keep, err := choose(in, fmt.Sprintf("key [1] %s [2] %s: ", a.CiteKey, b.CiteKey))
+		if err != nil {
+			return err
+		}
+		key, dropped := a.CiteKey, b.CiteKey
+		if keep == 2 {
+			key, dropped = dropped, key
+		}
+		var perr error
+		e := dedupe.Merge(a, b, key, func(f dedupe.Field) string {
+			n, err := choose(in, fmt.Sprintf("%s [1] %s [2] %s: ", f.Key, f.A, f.B))
+			if err != nil {
+				perr = err
+			}
+			if n == 2 {
+				return f.B
+			}
+			return f.A
+		})
+		if perr != nil {
+			return perr
+		}
+		merged[a], merged[b] = e, nil
+		renamed[dropped] = key
+	}
+	if len(renamed) == 0 {
+		return nil
+	}
+	result := []parse.Node{}
+	for _, n := range nodes {
+		if e, ok := n.(*parse.EntryDecl); ok {
+			if e = current(e); e == nil {
+				continue
+			}
+			n = e
+		}
+		result = append(result, n)
+	}
+	citekey.Rewrite(entries(result), chainRenames(renamed))
+	var b bytes.Buffer
+	if err := format.Nodes(&b, result); err != nil {
+		return err
+	}
+	return writeResult(stdout, path, src, b.Bytes(), mode)
+}
+
+// Candidates returns the pairs of entries scoring at least the threshold.
+// Above dedupe.Unrelated the entries are only scored against those sharing
+// a DOI or an author with them, looked up in the indexes of a library, and
+// against those naming no authors.
+func candidates(es []*parse.EntryDecl, threshold float64) []dedupe.Pair {
+	if threshold <= dedupe.Unrelated {
+		return dedupe.Candidates(es, threshold)
+	}
+	nodes := make([]parse.Node, len(es))
+	anonymous := []*parse.EntryDecl{}
+	for i, e := range es {
+		nodes[i] = e
+		if !dedupe.HasAuthors(e) {
+			anonymous = append(anonymous, e)
+		}
+	}
+	var result []dedupe.Pair
+	library.New(nodes).View(func(tx *library.Tx) error {
+		result = dedupe.Related(es, threshold, func(e *parse.EntryDecl) []*parse.EntryDecl {
+			if !dedupe.HasAuthors(e) {
+				return es
+			}
+			return append(tx.Related(e), anonymous...)
+		})
+		return nil
+	})
+	return result
+}
+
+// ChainRenames maps each old key onto the key it ends up under after the
+// renames that follow, so that b renamed to a, which is then renamed to c,
+// maps to c.
+func chainRenames(renamed map[string]string) map[string]string {
+	result := make(map[string]string, len(renamed))
+	for old, key := range renamed {
+		// A key renamed back to one renamed before ends the chain.
+		for i := 0; i < len(renamed); i++ {
+			next, ok := renamed[key]
+			if !ok {
+				break
+			}
+			key = next
+		}
+		result[old] = key
+	}
+	return result
+}
+
+// ShowPair writes the entries side by side, marking the conflicting fields.
+func showPair(w io.Writer, score float64, a, b *parse.EntryDecl) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "\n%.2f\t%s\t%s\n", score, a.CiteKey, b.CiteKey)
+	for _, f := range dedupe.Fields(a, b) {
+		mark := " "
+		if f.Conflict() {
+			mark = "*"
+		}
+		fmt.Fprintf(tw, "%s %s\t%s\t%s\n", mark, f.Key, clip(f.A), clip(f.B))
+	}
+	tw.Flush()
+}
+
+// Clip shortens long values to keep the columns on screen.
+func clip(s string) string {
+	const width = 40
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:width-1]) + "…"
+}
+
+// Ask prompts the user and returns the answer in lower case. The end of the
+// input answers q.
+func ask(in *bufio.Reader, question string) (string, error) {
+	fmt.Fprint(os.Stderr, question)
+	line, err := in.ReadString('\n')
+	if err == io.EOF && line == "" {
+		fmt.Fprintln(os.Stderr)
+		return "q", nil
+	}
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.ToLower(strings.TrimSpace(line)), nil
+}
+
+// Choose prompts the user until they pick 1 or 2, taking an empty answer or
+// the end of the input for 1.
+func choose(in *bufio.Reader, question string) (int, error) {
+	for {
+		answer, err := ask(in, question)
+		if err != nil {
+			return 0, err
+		}
+		switch answer {
+		case "", "q", "1":
+			return 1, nil
+		case "2":
+			return 2, nil
+		}
+		fmt.Fprintln(os.Stderr, "answer 1 or 2")
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/mdm-code/bibx/internal/doctor"
+)
+
+// DoctorCmd diagnoses files that fail to parse or misbehave, reporting the
+// encoding, the first syntax error and suspicious constructs with fixes.
+func doctorCmd(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bibx doctor [file ...]")
+		fmt.Fprintln(fs.Output(), "\nReports the probable encoding, the first syntax error and suspicious")
+		fmt.Fprintln(fs.Output(), "constructs such as unbalanced braces and stray @ signs, with suggested fixes.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	sick := 0
+	diagnose := func(path string, src []byte) error {
+		r := doctor.Diagnose(src)
+		if !r.OK() {
+			sick++
+		}
+		return r.WriteText(stdout, path, src)
+	}
+	if fs.NArg() == 0 {
+		src, err := readStdin()
+		if err != nil {
+			return err
+		}
+		if err := diagnose("<stdin>", src); err != nil {
+			return err
+		}
+	}
+	for _, path := range fs.Args() {
+		src, err := readSource(path)
+		if err != nil {
+			return err
+		}
+		if err := diagnose(path, src); err != nil {
+			return err
+		}
+	}
+	if sick > 0 {
+		return fmt.Errorf("problems found in %d file(s)", sick)
+	}
+	return nil
+}
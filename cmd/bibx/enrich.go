@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/crossref"
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/lint"
+	"github.com/mdm-code/bibx/internal/openlibrary"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// EnrichCmd fills in the fields missing from entries with a DOI using the
+// metadata registered with Crossref, and from books with an ISBN using Open
+// Library.
+func enrichCmd(args []string) error {
+	fs := flag.NewFlagSet("enrich", flag.ExitOnError)
+	write := fs.Bool("w", false, "write the result to the source files instead of stdout")
+	dryRun := fs.Bool("dry-run", false, "print a unified diff of the changes instead of the result; nothing is written")
+	cache := fs.String("cache", defaultCache(), "`directory` caching the Open Library lookups; empty to disable")
+	offline := fs.Bool("offline", false, "look books up in the cache only")
+	interval := fs.Duration("interval", openlibrary.DefaultInterval, "least time between two Open Library requests")
+	net := networkFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bibx enrich [-w] [-dry-run] [-cache dir] [-offline] [-timeout d] [-retries n] [file ...]")
+		fmt.Fprintln(fs.Output(), "\nEntries with a DOI missing volume, pages or publisher are looked up on Crossref.")
+		fmt.Fprintln(fs.Output(), "Books with an ISBN missing publisher, year, edition or author are looked up on Open Library.")
+		fmt.Fprintln(fs.Output(), "The fields filled in are listed in a comment above each entry.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	ctx, cancel := net.context()
+	defer cancel()
+	c := &enricher{
+		crossref:    &crossref.Client{HTTP: net.client(), Retry: net.policy()},
+		openlibrary: &openlibrary.Client{Cache: *cache, Offline: *offline, Interval: *interval, HTTP: net.client(), Retry: net.policy()},
+	}
+	failed := 0
+	if fs.NArg() == 0 {
+		src, err := readStdin()
+		if err != nil {
+			return err
+		}
+		res, n, err := enrichSource(ctx, c, src)
+		if err != nil {
+			return fmt.Errorf("<stdin>: %w", err)
+		}
+		failed += n
+		if err := writeResult(stdout, "<stdin>", src, res, writeMode{dryRun: *dryRun}); err != nil {
+			return err
+		}
+	}
+	mode := writeMode{write: *write, dryRun: *dryRun}
+	for i, path := range fs.Args() {
+		src, err := readSource(path)
+		if err != nil {
+			return err
+		}
+		res, n, err := enrichSource(ctx, c, src)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		logFile(path)
+		trackFiles(i+1, fs.NArg())
+		failed += n
+		if err := writeResult(stdout, path, src, res, mode); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d lookup(s) failed", failed)
+	}
+	return nil
+}
+
+// Enricher holds the clients of the services entries are looked up on.
+type enricher struct {
+	crossref    *crossref.Client
+	openlibrary *openlibrary.Client
+}
+
+// DefaultCache returns the directory of the Open Library cache in the user
+// cache directory, or nothing if there is none.
+func defaultCache() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "bibx", "openlibrary")
+}
+
+// EnrichSource enriches the entries of the source and returns it formatted
+// along with the number of failed lookups, which are reported on stderr.
+func enrichSource(ctx context.Context, c *enricher, src []byte) ([]byte, int, error) {
+	p := newParser(bytes.NewReader(src))
+	// The lookups take the time, so entries count once looked up.
+	p.Progress = nil
+	nodes := []parse.Node{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		nodes = append(nodes, n)
+	}
+	if err := p.Err(); err != nil {
+		return nil, 0, err
+	}
+	failed := 0
+	for _, e := range entries(nodes) {
+		filled := []string{}
+		if f, ok := e.Get("doi"); ok && missing(e, "volume", "pages", "publisher") {
+			w, err := c.crossref.Fetch(ctx, lint.NormalizeDOI(parse.Unquote(f.Value)))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", e.CiteKey, err)
+				failed++
+			} else {
+				filled = append(filled, w.Enrich(e)...)
+			}
+		}
+		if f, ok := e.Get("isbn"); ok && e.Name == "book" && missing(e, "publisher", "year", "edition", "author") {
+			b, err := c.openlibrary.Fetch(ctx, parse.Unquote(f.Value))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", e.CiteKey, err)
+				failed++
+			} else {
+				filled = append(filled, b.Enrich(e)...)
+			}
+		}
+		if len(filled) > 0 {
+			fmt.Fprintf(os.Stderr, "%s: filled %s\n", e.CiteKey, strings.Join(filled, ", "))
+			logger.Info("fix applied", "rule", "enrich", "key", e.CiteKey, "fields", filled)
+		}
+		if bar != nil {
+			bar.Entries(1)
+		}
+	}
+	var b bytes.Buffer
+	if err := format.Nodes(&b, nodes); err != nil {
+		return nil, 0, err
+	}
+	return b.Bytes(), failed, nil
+}
+
+// Missing checks if any of the fields is missing from the entry.
+func missing(e *parse.EntryDecl, keys ...string) bool {
+	for _, key := range keys {
+		if _, ok := e.Lookup(key); !ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/arxiv"
+	"github.com/mdm-code/bibx/internal/citekey"
+	"github.com/mdm-code/bibx/internal/crossref"
+	"github.com/mdm-code/bibx/internal/dblp"
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/orcid"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// FetchCmd retrieves entries by their identifiers and prints them as BibTeX.
+func fetchCmd(args []string) error {
+	fs := flag.NewFlagSet("fetch", flag.ExitOnError)
+	search := fs.String("search", "", "search Crossref and dblp for the `words` of a title instead of fetching ids")
+	rows := fs.Int("n", 5, "number of search candidates asked from each source")
+	pick := fs.Int("pick", 0, "select the candidate with the `number` instead of prompting")
+	appendTo := fs.String("append", "", "append the entries to the BibTeX `file` instead of printing them")
+	net := networkFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bibx fetch [-append file] [-timeout d] [-retries n] id ...")
+		fmt.Fprintln(fs.Output(), "       bibx fetch -search words [-n rows] [-pick number] [-append file]")
+		fmt.Fprintln(fs.Output(), "\nIdentifiers are arXiv IDs such as arXiv:1706.03762, or ORCID iDs such as")
+		fmt.Fprintln(fs.Output(), "orcid:0000-0002-1825-0097 standing for all the public works of the researcher.")
+		fmt.Fprintln(fs.Output(), "A search lists the candidates found and prompts for the one to keep.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	ctx, cancel := net.context()
+	defer cancel()
+	if *search != "" {
+		e, err := searchEntry(ctx, net, *search, *rows, *pick)
+		if err != nil || e == nil {
+			return err
+		}
+		return writeFetched(*appendTo, []*parse.EntryDecl{e})
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	eprints, researchers := []string{}, []string{}
+	for _, id := range fs.Args() {
+		switch {
+		case arxiv.IsID(id):
+			eprints = append(eprints, id)
+		case orcid.IsID(id):
+			researchers = append(researchers, id)
+		default:
+			return fmt.Errorf("unrecognized identifier %q", id)
+		}
+	}
+	entries := []*parse.EntryDecl{}
+	if len(eprints) > 0 {
+		c := arxiv.Client{HTTP: net.client(), Retry: net.policy()}
+		es, err := c.Fetch(ctx, eprints...)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, es...)
+	}
+	oc := orcid.Client{HTTP: net.client(), Retry: net.policy()}
+	for _, id := range researchers {
+		es, err := oc.Fetch(ctx, id)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, es...)
+	}
+	return writeFetched(*appendTo, entries)
+}
+
+// WriteFetched prints the entries, or appends them to the file, with cite
+// keys made unique among themselves and the entries of the file, and the
+// references between them following the renamed keys. Keys are unique within
+// each response but may collide across them.
+func writeFetched(path string, entries []*parse.EntryDecl) error {
+	taken := map[string]bool{}
+	if path != "" {
+		existing, err := readEntries([]string{path})
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		for _, e := range existing {
+			taken[e.CiteKey] = true
+		}
+	}
+	logRenamed(citekey.Import(entries, taken))
+	nodes := make([]parse.Node, 0, len(entries))
+	for _, e := range entries {
+		nodes = append(nodes, e)
+	}
+	if path == "" {
+		return format.Nodes(stdout, nodes)
+	}
+	var b bytes.Buffer
+	if err := format.Nodes(&b, nodes); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		// Entries are separated by a blank line as format writes them.
+		f.WriteString("\n")
+	}
+	_, err = f.Write(b.Bytes())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Candidate is an entry found by a search along with its source.
+type candidate struct {
+	entry  *parse.EntryDecl
+	source string
+}
+
+// SearchEntry searches Crossref and dblp for the query with the network
+// settings and returns the candidate picked by its number, or by the user
+// when the number is zero. It returns nil if the user picks none.
+func searchEntry(ctx context.Context, net *network, query string, rows, pick int) (*parse.EntryDecl, error) {
+	candidates := []candidate{}
+	seen := map[string]bool{}
+	add := func(e *parse.EntryDecl, source string) {
+		id := strings.ToLower(fieldText(e, "doi"))
+		if id == "" {
+			id = strings.ToLower(fieldText(e, "title")) + "/" + fieldText(e, "year")
+		}
+		if !seen[id] {
+			seen[id] = true
+			candidates = append(candidates, candidate{e, source})
+		}
+	}
+	cc := crossref.Client{HTTP: net.client(), Retry: net.policy()}
+	works, err := cc.Search(ctx, query, rows)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bibx: %s\n", err)
+	}
+	for _, w := range works {
+		add(w.Entry(), "Crossref")
+	}
+	dc := dblp.Client{HTTP: net.client(), Retry: net.policy()}
+	hits, err := dc.Search(ctx, query, rows)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bibx: %s\n", err)
+	}
+	for _, h := range hits {
+		add(h.Entry(), "dblp")
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("nothing found for %q", query)
+	}
+	for i, c := range candidates {
+		venue := fieldText(c.entry, "journal") + fieldText(c.entry, "booktitle")
+		fmt.Fprintf(os.Stderr, "%2d. %s\n    %s (%s) %s [%s]\n", i+1,
+			fieldText(c.entry, "title"), fieldText(c.entry, "author"), fieldText(c.entry, "year"), venue, c.source)
+	}
+	if pick == 0 {
+		fmt.Fprintf(os.Stderr, "select 1-%d, or press enter to skip: ", len(candidates))
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return nil, nil
+		}
+		if line = strings.TrimSpace(line); line == "" {
+			return nil, nil
+		}
+		if pick, err = strconv.Atoi(line); err != nil {
+			return nil, fmt.Errorf("invalid selection %q", line)
+		}
+	}
+	if pick < 1 || pick > len(candidates) {
+		return nil, fmt.Errorf("no candidate %d", pick)
+	}
+	return candidates[pick-1].entry, nil
+}
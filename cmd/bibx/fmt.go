@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"runtime"
+
+	"github.com/mdm-code/bibx/internal/diff"
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/journal"
+	"github.com/mdm-code/bibx/internal/parallel"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/tex"
+	"github.com/mdm-code/bibx/internal/transform"
+)
+
+// FmtCmd rewrites BibTeX files in the canonical layout.
+func fmtCmd(args []string) error {
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := fs.Bool("w", false, "write the result to the source files instead of stdout")
+	check := fs.Bool("check", false, "only report files that are not formatted; nothing is written")
+	list := fs.Bool("l", false, "list files whose formatting differs instead of printing the result")
+	showDiff := fs.Bool("d", false, "print a unified diff of the changes instead of the result")
+	encoding := fs.String("encoding", "unicode", "output encoding: unicode, or ascii to escape non-ASCII characters for BibTeX and pdfLaTeX")
+	month := fs.String("month", "", "rewrite month fields in the style: macro, number, or name")
+	normalizePages := fs.Bool("pages", false, "rewrite page ranges with -- and expand abbreviated last pages")
+	normalizeNames := fs.Bool("names", false, "separate the names of author, editor and other name lists with and only")
+	journals := fs.String("journals", "", "rewrite journal names in the style: full, or abbrev for ISO 4 abbreviations")
+	jobs := fs.Int("j", runtime.NumCPU(), "number of files formatted concurrently")
+	fs.Parse(args)
+
+	enc, ok := encodings[*encoding]
+	if !ok {
+		return fmt.Errorf("unknown encoding %q", *encoding)
+	}
+	passes := []func(parse.Node){}
+	if enc != tex.PassThrough {
+		passes = append(passes, func(n parse.Node) { transform.Encode(n, enc) })
+	}
+	if *normalizeNames {
+		passes = append(passes, func(n parse.Node) {
+			if e, ok := n.(*parse.EntryDecl); ok {
+				transform.NormalizeSeparators(e)
+			}
+		})
+	}
+	if *normalizePages {
+		passes = append(passes, func(n parse.Node) {
+			if e, ok := n.(*parse.EntryDecl); ok {
+				transform.NormalizePages(e)
+			}
+		})
+	}
+	if *journals != "" {
+		style, ok := journalStyles[*journals]
+		if !ok {
+			return fmt.Errorf("unknown journal style %q", *journals)
+		}
+		passes = append(passes, func(n parse.Node) {
+			if e, ok := n.(*parse.EntryDecl); ok {
+				journal.Builtin.Apply(e, style)
+			}
+		})
+	}
+	if *month != "" {
+		style, ok := monthStyles[*month]
+		if !ok {
+			return fmt.Errorf("unknown month style %q", *month)
+		}
+		passes = append(passes, func(n parse.Node) {
+			if e, ok := n.(*parse.EntryDecl); ok {
+				transform.NormalizeMonth(e, style)
+			}
+		})
+	}
+
+	mode := fmtMode{check: *check, write: *write, list: *list, diff: *showDiff}
+	if fs.NArg() == 0 {
+		src, err := readStdin()
+		if err != nil {
+			return err
+		}
+		mode.write = false
+		return fmtSource(stdout, "<stdin>", src, passes, mode)
+	}
+	// The files are formatted concurrently, and the output of each is held
+	// back until the files before it are done.
+	type result struct {
+		out bytes.Buffer
+		err error
+	}
+	paths := fs.Args()
+	unformatted := 0
+	work := func(i int) *result {
+		res := &result{}
+		src, err := readSource(paths[i])
+		if err != nil {
+			res.err = err
+			return res
+		}
+		res.err = fmtSource(&res.out, paths[i], src, passes, mode)
+		logFile(paths[i])
+		return res
+	}
+	err := parallel.Ordered(len(paths), *jobs, work, func(i int, res *result) error {
+		trackFiles(i+1, len(paths))
+		if _, err := res.out.WriteTo(stdout); err != nil {
+			return err
+		}
+		if res.err == errUnformatted {
+			unformatted++
+			return nil
+		}
+		return res.err
+	})
+	if err != nil {
+		return err
+	}
+	if unformatted > 0 {
+		return fmt.Errorf("%d file(s) not formatted", unformatted)
+	}
+	return nil
+}
+
+var errUnformatted = errors.New("not formatted")
+
+// Encodings maps the names of the output encodings onto their values.
+var encodings = map[string]tex.Encoding{
+	"unicode": tex.PassThrough,
+	"ascii":   tex.ASCII,
+}
+
+// JournalStyles maps the names of the journal styles onto their values.
+var journalStyles = map[string]journal.Style{
+	"full":   journal.Full,
+	"abbrev": journal.Abbreviated,
+}
+
+// MonthStyles maps the names of the month styles onto their values.
+var monthStyles = map[string]transform.MonthStyle{
+	"macro":  transform.MonthMacro,
+	"number": transform.MonthNumber,
+	"name":   transform.MonthName,
+}
+
+// FmtMode tells what fmtSource does with the formatted source. The result is
+// printed unless it is written back, listed or diffed.
+type fmtMode struct {
+	check, write, list, diff bool
+}
+
+// FmtSource formats the source read from path and prints the result to out,
+// or writes it back to path, as the mode tells.
+func fmtSource(out io.Writer, path string, src []byte, passes []func(parse.Node), mode fmtMode) error {
+	res, err := rewriteSource(path, src, passes)
+	if err != nil {
+		return err
+	}
+	changed := !bytes.Equal(res, src)
+	if mode.check {
+		if changed {
+			fmt.Fprintln(out, path)
+			return errUnformatted
+		}
+		return nil
+	}
+	if mode.list && changed {
+		fmt.Fprintln(out, path)
+	}
+	if mode.diff && changed {
+		if _, err := out.Write(diff.Unified(path+".orig", src, path, res)); err != nil {
+			return err
+		}
+	}
+	if mode.write && changed {
+		return writeResult(out, path, src, res, writeMode{write: true})
+	}
+	if !mode.write && !mode.list && !mode.diff {
+		_, err := out.Write(res)
+		return err
+	}
+	return nil
+}
+
+// RewriteSource formats the source read from path after running the passes
+// on each of its declarations.
+func rewriteSource(path string, src []byte, passes []func(parse.Node)) ([]byte, error) {
+	nodes, err := parseSource(path, src)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nodes {
+		for _, pass := range passes {
+			pass(n)
+		}
+	}
+	var b bytes.Buffer
+	if err := format.Nodes(&b, nodes); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
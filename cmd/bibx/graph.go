@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/graph"
+)
+
+// GraphCmd prints the graph of the references between entries in the DOT
+// or GraphML format.
+func graphCmd(args []string) error {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	format := fs.String("format", "dot", "output format: dot or graphml")
+	cites := fs.Bool("cites", false, "follow the cites field as well")
+	fields := fs.String("fields", "", "comma-separated `list` of extra fields holding cite keys to follow")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bibx graph [-format dot|graphml] [-cites] [-fields list] [file ...]")
+		fmt.Fprintln(fs.Output(), "\nEdges follow the crossref, xdata and related fields. Entries referred to but")
+		fmt.Fprintln(fs.Output(), "not defined are drawn dashed. Render DOT output with Graphviz, e.g. dot -Tsvg.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	follow := append([]string{}, graph.DefaultFields...)
+	if *cites {
+		follow = append(follow, "cites")
+	}
+	for _, f := range strings.Split(*fields, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			follow = append(follow, strings.ToLower(f))
+		}
+	}
+	entries, err := readEntries(fs.Args())
+	if err != nil {
+		return err
+	}
+	g := graph.Build(entries, follow)
+	switch *format {
+	case "dot":
+		return g.WriteDOT(stdout)
+	case "graphml":
+		return g.WriteGraphML(stdout)
+	default:
+		return fmt.Errorf("unknown output format %q", *format)
+	}
+}
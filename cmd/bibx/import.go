@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mdm-code/bibx/internal/citekey"
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/pdf"
+)
+
+// ImportCmd prints draft entries made from the metadata of PDF files and
+// linked to them through the file field.
+func importCmd(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	scan := fs.Bool("scan-doi", false, "look for a DOI in the text when the metadata has none")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bibx import [-scan-doi] file.pdf ...")
+		fmt.Fprintln(fs.Output(), "\nThe XMP metadata is preferred to the document information dictionary.")
+		fmt.Fprintln(fs.Output(), "Review the drafts: PDF metadata is often missing or wrong.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	entries := []*parse.EntryDecl{}
+	for _, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		m, err := pdf.Read(f, *scan)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		entries = append(entries, m.Entry(path))
+	}
+	logRenamed(citekey.Import(entries, map[string]bool{}))
+	nodes := make([]parse.Node, 0, len(entries))
+	for _, e := range entries {
+		nodes = append(nodes, e)
+	}
+	return format.Nodes(stdout, nodes)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+// KeysCmd prints the cite keys of the entries, one per line, optionally
+// followed by the values of some of their fields, to back citation pickers.
+func keysCmd(args []string) error {
+	fs := flag.NewFlagSet("keys", flag.ExitOnError)
+	columns := fs.String("columns", "", "comma-separated `list` of fields printed after the key, separated by tabs")
+	asJSON := fs.Bool("json", false, "print a JSON object with the key, type and columns per line")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bibx keys [-columns list] [-json] [file ...]")
+		fmt.Fprintln(fs.Output(), "\nEntries are printed as they are parsed, e.g. to feed a fuzzy finder:")
+		fmt.Fprintln(fs.Output(), "  bibx keys -columns author,year,title refs.bib | fzf")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	fields := []string{}
+	for _, f := range strings.Split(*columns, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, strings.ToLower(f))
+		}
+	}
+	w := bufio.NewWriter(stdout)
+	enc := json.NewEncoder(w)
+	print := func(p *parse.Parser) error {
+		for n, ok := p.Next(); ok; n, ok = p.Next() {
+			e, ok := n.(*parse.EntryDecl)
+			if !ok {
+				continue
+			}
+			if *asJSON {
+				o := map[string]string{"key": e.CiteKey, "type": e.Name}
+				for _, f := range fields {
+					o[f] = fieldText(e, f)
+				}
+				if err := enc.Encode(o); err != nil {
+					return err
+				}
+				continue
+			}
+			w.WriteString(e.CiteKey)
+			for _, f := range fields {
+				w.WriteString("\t" + fieldText(e, f))
+			}
+			w.WriteByte('\n')
+		}
+		return p.Err()
+	}
+	err := func() error {
+		if fs.NArg() == 0 {
+			return print(newParser(os.Stdin))
+		}
+		for _, path := range fs.Args() {
+			f, err := openSource(path)
+			if err != nil {
+				return err
+			}
+			err = print(newParser(f))
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		return nil
+	}()
+	// The keys printed before a syntax error are still useful to a picker.
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// FieldText returns the plain text of the field on a single line.
+func fieldText(e *parse.EntryDecl, field string) string {
+	f, ok := e.Get(field)
+	if !ok {
+		return ""
+	}
+	return strings.Join(strings.Fields(tex.Decode(parse.Unquote(f.Value))), " ")
+}
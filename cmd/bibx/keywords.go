@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/keywords"
+)
+
+// KeywordsCmd lists the keywords used across the files with the numbers of
+// entries using them, or rewrites the keywords of the files.
+func keywordsCmd(args []string) error {
+	fs := flag.NewFlagSet("keywords", flag.ExitOnError)
+	normalize := fs.Bool("normalize", false, "rewrite the keywords in lower case as comma-separated lists without duplicates")
+	write := fs.Bool("w", false, "write the result to the source files instead of stdout")
+	dryRun := fs.Bool("dry-run", false, "print a unified diff of the changes instead of the result; nothing is written")
+	asJSON := fs.Bool("json", false, "print the keywords and their counts as JSON")
+	renames := map[string]string{}
+	fs.Func("rename", "rename the keyword `old=new` in all entries, or remove it when new is empty; may be repeated", func(s string) error {
+		from, to, ok := strings.Cut(s, "=")
+		if !ok || strings.TrimSpace(from) == "" {
+			return fmt.Errorf("invalid rename %q", s)
+		}
+		renames[from] = strings.TrimSpace(to)
+		return nil
+	})
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bibx keywords [-json] [file ...]")
+		fmt.Fprintln(fs.Output(), "       bibx keywords [-normalize] [-rename old=new ...] [-w] [-dry-run] [file ...]")
+		fmt.Fprintln(fs.Output(), "\nKeywords are matched ignoring case. Renaming several keywords to the same one merges them.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if !*normalize && len(renames) == 0 {
+		es, err := readEntries(fs.Args())
+		if err != nil {
+			return err
+		}
+		counts := keywords.Vocabulary(es)
+		if *asJSON {
+			enc := json.NewEncoder(stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(counts)
+		}
+		for _, c := range counts {
+			fmt.Fprintf(stdout, "%d\t%s\n", c.Count, c.Keyword)
+		}
+		return nil
+	}
+	rewrite := func(path string, src []byte) ([]byte, error) {
+		nodes, err := parseSource(path, src)
+		if err != nil {
+			return nil, err
+		}
+		es := entries(nodes)
+		if *normalize {
+			for _, e := range es {
+				keywords.Normalize(e)
+			}
+		}
+		keywords.Rename(es, renames)
+		var b bytes.Buffer
+		if err := format.Nodes(&b, nodes); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	}
+	if fs.NArg() == 0 {
+		src, err := readStdin()
+		if err != nil {
+			return err
+		}
+		res, err := rewrite("<stdin>", src)
+		if err != nil {
+			return err
+		}
+		return writeResult(stdout, "<stdin>", src, res, writeMode{dryRun: *dryRun})
+	}
+	mode := writeMode{write: *write, dryRun: *dryRun}
+	for i, path := range fs.Args() {
+		src, err := readSource(path)
+		if err != nil {
+			return err
+		}
+		res, err := rewrite(path, src)
+		if err != nil {
+			return err
+		}
+		if err := writeResult(stdout, path, src, res, mode); err != nil {
+			return err
+		}
+		logFile(path)
+		trackFiles(i+1, fs.NArg())
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+
+	"github.com/mdm-code/bibx/internal/lint"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/remote"
+	"github.com/mdm-code/bibx/internal/report"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+// LinksCmd follows the url and doi fields of the entries and reports the
+// links that are broken, giving up on them as -timeout and -retries tell.
+func linksCmd(args []string) error {
+	fs := flag.NewFlagSet("links", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	net := networkFlags(fs)
+	fs.Parse(args)
+
+	ctx, cancel := net.context()
+	defer cancel()
+	hc, p := net.client(), net.policy()
+	rep := &report.Report{}
+	if fs.NArg() == 0 {
+		src, err := readStdin()
+		if err != nil {
+			return err
+		}
+		checkLinks(ctx, hc, p, rep, "<stdin>", src)
+	}
+	for _, path := range fs.Args() {
+		src, err := readSource(path)
+		if err != nil {
+			return err
+		}
+		checkLinks(ctx, hc, p, rep, path, src)
+	}
+	if err := writeReport(rep, *asJSON); err != nil {
+		return err
+	}
+	if n := len(rep.Findings); n > 0 {
+		return fmt.Errorf("%d broken link(s) found", n)
+	}
+	return nil
+}
+
+// CheckLinks adds the broken links of the entries of the source to the
+// report.
+func checkLinks(ctx context.Context, hc *http.Client, p remote.Policy, rep *report.Report, path string, src []byte) {
+	for _, e := range entries(parseNodes(bytes.NewReader(src))) {
+		for _, l := range links(e) {
+			if msg := checkLink(ctx, hc, p, l.url); msg != "" {
+				rep.Add(path, report.Finding{
+					Severity: report.Warning,
+					Rule:     "broken-link",
+					Pos:      l.pos,
+					Key:      e.CiteKey,
+					Field:    l.field,
+					Message:  fmt.Sprintf("%s: %s", l.url, msg),
+				})
+			}
+		}
+	}
+	rep.Quote(path, src)
+}
+
+// Link is a URL given by a field of an entry.
+type link struct {
+	field, url string
+	pos        scan.Pos
+}
+
+// Links returns the URLs of the url and doi fields of the entry.
+func links(e *parse.EntryDecl) []link {
+	result := []link{}
+	if f, ok := e.Get("url"); ok {
+		if u := parse.Unquote(f.Value); remote.IsURL(u) {
+			result = append(result, link{field: f.Key, url: u, pos: f.Pos})
+		}
+	}
+	if f, ok := e.Get("doi"); ok {
+		if doi := lint.NormalizeDOI(parse.Unquote(f.Value)); doi != "" {
+			result = append(result, link{field: f.Key, url: "https://doi.org/" + doi, pos: f.Pos})
+		}
+	}
+	return result
+}
+
+// CheckLink returns why the link cannot be followed, or nothing if it can.
+func checkLink(ctx context.Context, hc *http.Client, p remote.Policy, url string) string {
+	status, err := remote.Check(ctx, hc, p, url)
+	switch {
+	case err != nil:
+		return err.Error()
+	case status >= 400:
+		return fmt.Sprintf("%d %s", status, http.StatusText(status))
+	}
+	return ""
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/lint"
+	"github.com/mdm-code/bibx/internal/parallel"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/plugin"
+	"github.com/mdm-code/bibx/internal/report"
+)
+
+// LintCmd reports common mistakes in BibTeX files and optionally fixes them.
+func lintCmd(args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	fix := fs.Bool("fix", false, "apply automatic fixes, rewriting the files or printing stdin to stdout")
+	engine := fs.String("engine", "bibtex", "target engine: bibtex, bibtex8, or biber for engines reading Unicode")
+	normalizeNames := fs.Bool("normalize-names", false, "let -fix rewrite names in the dominant format of the file")
+	keys := fs.String("keys", "", "cite key convention: authoryear, ascii, or a regular expression")
+	rekey := fs.Bool("rekey", false, "let -fix rename entries whose cite keys break the -keys convention")
+	dryRun := fs.Bool("dry-run", false, "let -fix print a unified diff of the changes instead; nothing is written")
+	month := fs.String("month", "", "enforce the month style: macro, number, or name")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	jobs := fs.Int("j", runtime.NumCPU(), "number of files linted concurrently")
+	macros := make(parse.MacroTable)
+	fs.Func("strings", "read additional @string definitions from a BibTeX `file`; may be repeated", func(path string) error {
+		nodes, err := readNodes([]string{path})
+		if err != nil {
+			return err
+		}
+		for k, v := range parse.NewMacroTable(nodes) {
+			macros[k] = v
+		}
+		return nil
+	})
+	var plugins []lint.Rule
+	fs.Func("plugin", "run the lint rule of the bibx-lint-`name` executable on the PATH; may be repeated", func(name string) error {
+		r, err := plugin.NewRule(name)
+		if err != nil {
+			return err
+		}
+		plugins = append(plugins, r)
+		return nil
+	})
+	fs.Parse(args)
+	backend, ok := lint.ParseBackend(*engine)
+	if !ok {
+		return fmt.Errorf("unknown engine %q", *engine)
+	}
+
+	// Files linted together share their @string definitions.
+	used := make(map[string]bool)
+	if fs.NArg() > 1 {
+		// The progress shown is that of the linting proper.
+		shown := bar
+		bar = nil
+		nodes, err := readNodes(fs.Args())
+		bar = shown
+		if err != nil {
+			return err
+		}
+		for k, v := range parse.NewMacroTable(nodes) {
+			macros[k] = v
+		}
+		used = lint.MacroUses(nodes)
+	}
+	rules := []lint.Rule{
+		lint.Special{},
+		lint.Sanitize{},
+	}
+	if backend != lint.Biber {
+		// Biber knows the biblatex names of the fields.
+		rules = append(rules, lint.FieldAlias{})
+	}
+	rules = append(rules,
+		lint.Eprint{},
+		lint.Year{},
+		lint.NameFormat{Normalize: *normalizeNames},
+		lint.DuplicateDOI{},
+		lint.UndefinedMacro{Extra: macros},
+		lint.UnusedMacro{Used: used},
+	)
+	if *keys != "" {
+		pattern, ok := lint.KeySchemes[*keys]
+		if !ok {
+			var err error
+			if pattern, err = regexp.Compile(*keys); err != nil {
+				return err
+			}
+		}
+		rules = append(rules, lint.CiteKey{Pattern: pattern, Rekey: *rekey})
+	}
+	if *month != "" {
+		style, ok := monthStyles[*month]
+		if !ok {
+			return fmt.Errorf("unknown month style %q", *month)
+		}
+		rules = append(rules, lint.Month{Style: style})
+	}
+	rules = append(rules, lint.Target{Backend: backend})
+	rules = append(rules, plugins...)
+
+	rep := &report.Report{}
+	if fs.NArg() == 0 {
+		src, err := readStdin()
+		if err != nil {
+			return err
+		}
+		if err := lintSource(rep, stdout, "<stdin>", src, rules, *fix, writeMode{dryRun: *dryRun}); err != nil {
+			return err
+		}
+	}
+	// The files are linted concurrently, each into a report and a diff of its
+	// own, and the reports are merged in the order of the files.
+	type result struct {
+		rep report.Report
+		out bytes.Buffer
+		err error
+	}
+	mode := writeMode{write: true, dryRun: *dryRun}
+	paths := fs.Args()
+	work := func(i int) *result {
+		res := &result{}
+		src, err := readSource(paths[i])
+		if err != nil {
+			res.err = err
+			return res
+		}
+		res.err = lintSource(&res.rep, &res.out, paths[i], src, rules, *fix, mode)
+		logFile(paths[i])
+		return res
+	}
+	err := parallel.Ordered(len(paths), *jobs, work, func(i int, res *result) error {
+		trackFiles(i+1, len(paths))
+		rep.Add(paths[i], res.rep.Findings...)
+		if _, err := res.out.WriteTo(stdout); err != nil {
+			return err
+		}
+		return res.err
+	})
+	if err != nil {
+		return err
+	}
+	// Fixed stdin goes to stdout, so the findings go to stderr.
+	out := io.Writer(stdout)
+	if *fix && fs.NArg() == 0 {
+		out = os.Stderr
+	}
+	if err := writeReportTo(out, rep, *asJSON); err != nil {
+		return err
+	}
+	if n := len(rep.Findings); n > 0 {
+		return fmt.Errorf("%d problem(s) found", n)
+	}
+	return nil
+}
+
+// LintSource checks the source and adds the problems left to the report.
+// With fix set the fixable problems are corrected, and the result is handed
+// over to w or the file as the mode tells.
+func lintSource(rep *report.Report, w io.Writer, path string, src []byte, rules []lint.Rule, fix bool, mode writeMode) error {
+	nodes, err := parseSource(path, src)
+	if err != nil {
+		return err
+	}
+	findings := lint.Run(nodes, rules...)
+	fixed := make([]bool, len(findings))
+	if fix {
+		nodes, fixed = lint.Apply(nodes, findings)
+	}
+	for i, f := range findings {
+		if fixed[i] {
+			logger.Info("fix applied", "path", path, "rule", f.Rule, "key", f.Key, "field", f.Field)
+			continue
+		}
+		rep.Add(path, f.Report())
+	}
+	rep.Quote(path, src)
+	if !fix {
+		return nil
+	}
+	var b bytes.Buffer
+	if err := format.Nodes(&b, nodes); err != nil {
+		return err
+	}
+	return writeResult(w, path, src, b.Bytes(), mode)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/mdm-code/bibx/internal/lsp"
+	"github.com/mdm-code/bibx/internal/validate"
+)
+
+// LspCmd runs a language server for BibTeX files speaking the Language
+// Server Protocol over stdin and stdout.
+func lspCmd(args []string) error {
+	fs := flag.NewFlagSet("lsp", flag.ExitOnError)
+	set := validate.Builtin
+	fs.Func("schema", "load additional schemas and house rules from a JSON or YAML `file`; may be repeated", func(path string) error {
+		s, err := validate.LoadFile(path)
+		if err != nil {
+			return err
+		}
+		set = set.Merge(s)
+		return nil
+	})
+	metricsAddr := fs.String("metrics", "", "serve Prometheus metrics at /metrics on the `address`")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bibx lsp [-schema file] [-metrics host:port]")
+		fmt.Fprintln(fs.Output(), "\nThe server talks to the editor over stdin and stdout.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	s := lsp.NewServer(os.Stdin, os.Stdout)
+	s.Schemas = set
+	if *metricsAddr != "" {
+		l, err := net.Listen("tcp", *metricsAddr)
+		if err != nil {
+			return err
+		}
+		defer l.Close()
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", s.Metrics())
+		go http.Serve(l, mux)
+	}
+	return s.Run()
+}
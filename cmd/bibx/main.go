@@ -1,50 +1,614 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/mdm-code/bibx/internal/atomicfile"
+	"github.com/mdm-code/bibx/internal/citekey"
+	"github.com/mdm-code/bibx/internal/compressed"
+	"github.com/mdm-code/bibx/internal/diff"
 	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/parsecache"
+	"github.com/mdm-code/bibx/internal/progress"
+	"github.com/mdm-code/bibx/internal/remote"
+	"github.com/mdm-code/bibx/internal/report"
 	"github.com/mdm-code/bibx/internal/scan"
 )
 
+// Commands maps subcommand names onto their implementations.
+var commands = map[string]func(args []string) error{
+	"authors":   authorsCmd,
+	"check":     checkCmd,
+	"config":    configCmd,
+	"convert":   convertCmd,
+	"dedupe":    dedupeCmd,
+	"doctor":    doctorCmd,
+	"enrich":    enrichCmd,
+	"fetch":     fetchCmd,
+	"fmt":       fmtCmd,
+	"graph":     graphCmd,
+	"import":    importCmd,
+	"keys":      keysCmd,
+	"keywords":  keywordsCmd,
+	"links":     linksCmd,
+	"lint":      lintCmd,
+	"lsp":       lspCmd,
+	"mergetool": mergetoolCmd,
+	"new":       newCmd,
+	"pandoc":    pandocCmd,
+	"preprints": preprintsCmd,
+	"prune":     pruneCmd,
+	"query":     queryCmd,
+	"render":    renderCmd,
+	"serve":     serveCmd,
+	"show":      showCmd,
+	"stats":     statsCmd,
+	"tidy":      tidyCmd,
+	"validate":  validateCmd,
+	"zotero":    zoteroCmd,
+}
+
+// Stdout is where the commands write their output. With -o it is a buffer
+// written to the file once the command is done, so that the file is replaced
+// atomically and left alone by a command exiting early.
+var stdout io.Writer = os.Stdout
+
+// Logger receives the structured events of the commands, which are
+// discarded unless -verbose is given.
+var logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// Bar shows how far the commands have got on stderr when -progress is given.
+var bar *progress.Bar
+
+// Cache keeps the declarations parsed from the files when -cache is given.
+var cache *parsecache.Cache
+
+// Backups tells where the originals of the files rewritten in place are kept
+// when -backup or -backup-dir is given: next to the files with the .bak
+// suffix, or in the directory, stamped with the time they were replaced.
+var backups struct {
+	on  bool
+	dir string
+}
+
+// Fetching holds the -timeout and -retries settings the files given as URLs
+// are fetched with.
+var fetching = &network{timeout: defaultTimeout, retries: remote.DefaultPolicy.Attempts - 1}
+
 func main() {
-	s := scan.NewScanner(scan.NewReader(os.Stdin))
-	p := parse.NewParser(s)
+	fs := flag.NewFlagSet("bibx", flag.ExitOnError)
+	verbose := fs.Bool("verbose", false, "log the files processed, declarations parsed, requests sent and fixes applied to stderr")
+	logFormat := fs.String("log-format", "text", "`format` of the log: text or json")
+	showProgress := fs.Bool("progress", false, "show the bytes read, entries processed and files done on stderr")
+	cacheDir := fs.String("cache", "", "keep the parsed files in `dir` to skip parsing them again while unchanged")
+	fs.BoolVar(&backups.on, "backup", false, "keep the original of each file rewritten in place as file.bak")
+	fs.StringVar(&backups.dir, "backup-dir", "", "keep the originals of the files rewritten in place in `dir`, stamped with the time")
+	output := fs.String("o", "", "write the output of the command to `file`, compressed with gzip when it ends in .gz")
+	fetching = networkFlags(fs)
+	fs.Usage = usage
+	fs.Parse(os.Args[1:])
+	if *verbose {
+		opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+		switch *logFormat {
+		case "text":
+			logger = slog.New(slog.NewTextHandler(os.Stderr, opts))
+		case "json":
+			logger = slog.New(slog.NewJSONHandler(os.Stderr, opts))
+		default:
+			fmt.Fprintf(os.Stderr, "bibx: unknown log format %q\n", *logFormat)
+			os.Exit(2)
+		}
+	}
+	if *showProgress {
+		bar = &progress.Bar{W: os.Stderr, Interval: 100 * time.Millisecond}
+	}
+	if backups.dir != "" {
+		backups.on = true
+	}
+	if *cacheDir != "" {
+		cache = &parsecache.Cache{Dir: *cacheDir}
+	}
+	args := fs.Args()
+	cmd, ok := func(args []string) error { dump(os.Stdin); return nil }, true
+	if len(args) > 0 {
+		if cmd, ok = commands[args[0]]; !ok {
+			usage()
+			os.Exit(2)
+		}
+		args = args[1:]
+	}
+	var out *bytes.Buffer
+	if *output != "" {
+		if arg, ok := overwrites(*output, args); ok {
+			fmt.Fprintf(os.Stderr, "bibx: -o %s would overwrite the input %s\n", *output, arg)
+			os.Exit(2)
+		}
+		out = &bytes.Buffer{}
+		stdout = out
+	}
+	err := cmd(args)
+	if out != nil {
+		if werr := writeOutput(*output, out.Bytes()); err == nil {
+			err = werr
+		}
+	}
+	if bar != nil {
+		bar.Close()
+	}
+	if cache != nil {
+		if perr := cache.Prune(); perr != nil {
+			logger.Warn("cache not pruned", "dir", cache.Dir, "err", perr)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bibx: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: bibx [-verbose] [-log-format text|json] [-progress] [-cache dir] [-backup] [-backup-dir dir] [-o file] [-timeout duration] [-retries n] [command] [flags] [file ...]")
+	fmt.Fprintln(os.Stderr, "\nWithout a command the declarations read from stdin are printed.")
+	fmt.Fprintln(os.Stderr, "Files and stdin compressed with gzip are read like plain ones; with -o file.gz the output is compressed.")
+	fmt.Fprintln(os.Stderr, "Files given as http(s) URLs are fetched and cached until they change, as -timeout and -retries tell.")
+	fmt.Fprintln(os.Stderr, "With -verbose the commands log what they do to stderr.")
+	fmt.Fprintln(os.Stderr, "With -cache the files read are parsed again only once they change.")
+	fmt.Fprintln(os.Stderr, "With -backup or -backup-dir the originals of the files rewritten in place are kept.")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	names := []string{}
+	for n := range commands {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n", n)
+	}
+}
+
+// ReadNodes parses the declarations from the files, or from stdin when no
+// files are given.
+func readNodes(paths []string) ([]parse.Node, error) {
+	if len(paths) == 0 {
+		return parseNodes(os.Stdin), nil
+	}
+	result := []parse.Node{}
+	for i, path := range paths {
+		nodes, err := parseFile(path)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, nodes...)
+		logFile(path)
+		trackFiles(i+1, len(paths))
+	}
+	return result, nil
+}
+
+// ParseFile parses the declarations of the file, or loads them from the cache
+// when the file has not changed since it was last parsed. Files given as URLs
+// are fetched and parsed each time.
+func parseFile(path string) ([]parse.Node, error) {
+	if remote.IsURL(path) {
+		src, err := readSource(path)
+		if err != nil {
+			return nil, err
+		}
+		return parseNodes(bytes.NewReader(src)), nil
+	}
+	// Large libraries are scanned straight from the page cache.
+	m, err := scan.MapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var key string
+	if cache != nil {
+		key = parsecache.Key(m.Bytes())
+		if nodes, ok := cache.Load(key); ok {
+			logger.Debug("cache hit", "path", path, "declarations", len(nodes))
+			return nodes, m.Close()
+		}
+	}
+	r := m.Reader()
+	if compressed.Detect(m.Bytes()) != compressed.None {
+		src, err := compressed.Decode(m.Bytes())
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		r = scan.NewBytesReader(src)
+	}
+	p := parse.NewParser(scan.NewScanner(r))
+	p.Logger = logger.With("path", path)
+	if bar != nil {
+		p.Progress = bar
+		bar.Read(len(m.Bytes()))
+	}
+	result := []parse.Node{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		result = append(result, n)
+	}
+	if err := m.Close(); err != nil {
+		return nil, err
+	}
+	// Only complete parses are kept, so that a broken file is parsed and
+	// reported anew each time.
+	if cache != nil && p.Err() == nil {
+		if err := cache.Store(key, result); err != nil {
+			logger.Warn("cache not stored", "path", path, "err", err)
+		}
+	}
+	return result, nil
+}
+
+// ParseSource parses the source read from path, which is looked up in the
+// cache first when -cache is given. A source that fails to parse is never
+// cached, and the error describes where it failed.
+func parseSource(path string, src []byte) ([]parse.Node, error) {
+	var key string
+	if cache != nil {
+		key = parsecache.Key(src)
+		if nodes, ok := cache.Load(key); ok {
+			logger.Debug("cache hit", "path", path, "declarations", len(nodes))
+			return nodes, nil
+		}
+	}
+	p := newParser(bytes.NewReader(src))
+	p.Logger = logger.With("path", path)
+	nodes := []parse.Node{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		nodes = append(nodes, n)
+	}
+	if p.Err() != nil {
+		return nil, syntaxError(path, src, p)
+	}
+	if cache != nil {
+		if err := cache.Store(key, nodes); err != nil {
+			logger.Warn("cache not stored", "path", path, "err", err)
+		}
+	}
+	return nodes, nil
+}
+
+// ReadEntries is like readNodes but keeps the entry declarations only.
+func readEntries(paths []string) ([]*parse.EntryDecl, error) {
+	nodes, err := readNodes(paths)
+	if err != nil {
+		return nil, err
+	}
+	return entries(nodes), nil
+}
+
+func entries(nodes []parse.Node) []*parse.EntryDecl {
+	result := []*parse.EntryDecl{}
+	for _, n := range nodes {
+		if e, ok := n.(*parse.EntryDecl); ok {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// SyntaxError describes the error that stopped the parser of the source read
+// from path along with its position, quoting the line it failed at.
+func syntaxError(path string, src []byte, p *parse.Parser) error {
+	err := fmt.Errorf("%s:%s: %w", path, p.ErrPos(), p.Err())
+	if s := scan.Snippet(src, p.ErrPos()); s != "" {
+		err = fmt.Errorf("%w\n    %s", err, strings.ReplaceAll(s, "\n", "\n    "))
+	}
+	return err
+}
+
+// WriteReport prints the report to stdout as text or JSON.
+func writeReport(rep *report.Report, asJSON bool) error {
+	return writeReportTo(stdout, rep, asJSON)
+}
+
+func writeReportTo(w io.Writer, rep *report.Report, asJSON bool) error {
+	if asJSON {
+		return rep.WriteJSON(w)
+	}
+	return rep.WriteText(w)
+}
+
+// WriteMode tells what becomes of a rewritten source: it is printed, written
+// back to its file, or only compared with it.
+type writeMode struct {
+	write  bool // write the result back to the file
+	dryRun bool // print a unified diff of the changes instead
+}
+
+// WriteResult hands over the result of rewriting the source read from path
+// as the mode tells. Unless it is written back, it goes to w. Unchanged files
+// are never written, and with dryRun set nothing is written at all.
+func writeResult(w io.Writer, path string, src, res []byte, mode writeMode) error {
+	switch {
+	case mode.dryRun:
+		if bytes.Equal(res, src) {
+			return nil
+		}
+		_, err := w.Write(diff.Unified(path+".orig", src, path, res))
+		return err
+	case !mode.write:
+		_, err := w.Write(res)
+		return err
+	case bytes.Equal(res, src):
+		return nil
+	case remote.IsURL(path):
+		return fmt.Errorf("%s: cannot write back to a URL", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if backups.on {
+		if err := backup(path, src, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return writeFile(path, res, info.Mode().Perm())
+}
+
+// WriteFile replaces the contents of the file atomically with
+// atomicfile.Write, so that a failure midway leaves the file as it was. A
+// symbolic link is followed and the file it points to is replaced. Files
+// named .gz are compressed.
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	data, err := compressed.Encode(path, data)
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(path, data, perm)
+}
+
+// WriteOutput replaces the file of -o with the output of the command,
+// keeping its permissions if it exists.
+func writeOutput(path string, data []byte) error {
+	perm := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	return writeFile(path, data, perm)
+}
+
+// Overwrites returns the argument naming the same file as path, which the
+// output of the command must not replace while it is read. Arguments are
+// taken for files, including the values of flags given as -flag=value.
+func overwrites(path string, args []string) (string, bool) {
+	out, err := os.Stat(path)
+	if err != nil {
+		return ``, false
+	}
+	for _, arg := range args {
+		name := arg
+		if i := strings.IndexByte(arg, '='); strings.HasPrefix(arg, "-") && i >= 0 {
+			name = arg[i+1:]
+		}
+		if info, err := os.Stat(name); err == nil && os.SameFile(out, info) {
+			return arg, true
+		}
+	}
+	return ``, false
+}
+
+// ReadSource reads the file, or fetches it when path is a URL, decompressing
+// it when it is compressed.
+func readSource(path string) ([]byte, error) {
+	read := os.ReadFile
+	if remote.IsURL(path) {
+		read = fetchURL
+	}
+	src, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	if src, err = compressed.Decode(src); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return src, nil
+}
+
+// FetchURL downloads the file at the URL, which is cached in the user cache
+// directory and revalidated each time it is fetched again.
+func fetchURL(url string) ([]byte, error) {
+	ctx, stop := fetching.context()
+	defer stop()
+	f := &remote.Fetcher{Client: fetching.client(), Policy: fetching.policy()}
+	if dir, err := os.UserCacheDir(); err == nil {
+		f.Cache = filepath.Join(dir, "bibx", "remote")
+	}
+	logger.Debug("fetching", "url", url, "cache", f.Cache)
+	return f.Fetch(ctx, url)
+}
+
+// OpenSource opens the file, or fetches it when path is a URL.
+func openSource(path string) (io.ReadCloser, error) {
+	if !remote.IsURL(path) {
+		return os.Open(path)
+	}
+	src, err := fetchURL(path)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(src)), nil
+}
+
+// ReadStdin reads stdin, decompressing it when it is compressed.
+func readStdin() ([]byte, error) {
+	return io.ReadAll(compressed.NewReader(os.Stdin))
+}
+
+// Backup saves the original source of the file about to be rewritten. The
+// file is not rewritten unless the backup succeeds.
+func backup(path string, src []byte, perm os.FileMode) error {
+	dst := path + ".bak"
+	if backups.dir != "" {
+		if err := os.MkdirAll(backups.dir, 0o755); err != nil {
+			return err
+		}
+		dst = backupName(backups.dir, path, time.Now())
+	}
+	src, err := compressed.Encode(path, src)
+	if err != nil {
+		return fmt.Errorf("backup of %s: %w", path, err)
+	}
+	if err := os.WriteFile(dst, src, perm); err != nil {
+		return fmt.Errorf("backup of %s: %w", path, err)
+	}
+	logger.Info("backup saved", "path", path, "backup", dst)
+	return nil
+}
+
+// BackupName returns the path of the backup of the file saved into dir at
+// the time. The name carries a hash of the directory of the file, so that the
+// backups of files with the same name in different directories stay apart.
+func backupName(dir, path string, t time.Time) string {
+	abs, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		abs = filepath.Dir(path)
+	}
+	sum := sha256.Sum256([]byte(abs))
+	stamp := t.Format("20060102T150405.000")
+	return filepath.Join(dir, fmt.Sprintf("%s.%x.%s.bak", filepath.Base(path), sum[:4], stamp))
+}
+
+// Network holds the settings of the commands talking to web services.
+type network struct {
+	timeout time.Duration
+	retries int
+}
+
+// DefaultTimeout is how long the requests are given unless -timeout says
+// otherwise, so that a service that never answers cannot hang a command.
+const defaultTimeout = 30 * time.Second
+
+// NetworkFlags defines the -timeout and -retries flags on the flag set.
+func networkFlags(fs *flag.FlagSet) *network {
+	n := &network{}
+	fs.DurationVar(&n.timeout, "timeout", defaultTimeout, "give up on the web services after the `duration`; 0 for no limit")
+	fs.IntVar(&n.retries, "retries", remote.DefaultPolicy.Attempts-1, "`number` of times a failed request is retried")
+	return n
+}
+
+// Context returns the context of the requests, which is canceled on
+// interrupt or once the timeout passes.
+func (n *network) context() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if n.timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// Client returns the HTTP client the requests are sent with, which logs
+// them.
+func (n *network) client() *http.Client {
+	return &http.Client{Transport: &remote.Transport{Logger: logger}}
+}
+
+// Policy returns the retry policy of the requests.
+func (n *network) policy() remote.Policy {
+	p := remote.DefaultPolicy
+	p.Attempts = n.retries + 1
+	if p.Attempts < 1 {
+		p.Attempts = 1
+	}
+	return p
+}
+
+func parseNodes(r io.Reader) []parse.Node {
+	p := newParser(r)
+	result := []parse.Node{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		result = append(result, n)
+	}
+	return result
+}
+
+// NewParser returns a parser of the declarations read from r, decompressing
+// them first when r is compressed.
+func newParser(r io.Reader) *parse.Parser {
+	r = compressed.NewReader(r)
+	opts := []parse.Option{parse.WithLogger(logger)}
+	if bar != nil {
+		r = &progress.Reader{R: r, Reporter: bar}
+		opts = append(opts, parse.WithProgress(bar))
+	}
+	return parse.NewParser(scan.NewScanner(scan.NewReader(r)), opts...)
+}
+
+// TrackFiles shows the files done out of the total when -progress is given.
+func trackFiles(done, total int) {
+	if bar != nil {
+		bar.Files(done, total)
+	}
+}
+
+// LogFile logs that the command is done with the file.
+func logFile(path string) {
+	logger.Info("file processed", "path", path)
+}
+
+// LogRenamed tells the user about the cite keys renamed on import, as the
+// entries are cited by the new keys.
+func logRenamed(renamed []citekey.Rename) {
+	for _, r := range renamed {
+		fmt.Fprintf(os.Stderr, "bibx: renamed %s to %s\n", r.Old, r.New)
+	}
+}
+
+func dump(r io.Reader) {
+	p := newParser(r)
 
 	n, ok := p.Next()
 	for ok {
 		switch decl := n.(type) {
 		case *parse.EntryDecl:
-			fmt.Printf("Type: %s\n", decl)
-			fmt.Printf("Cite key: %s\n", decl.CiteKey)
-			fmt.Println("Comments:")
+			fmt.Fprintf(stdout, "Type: %s\n", decl.Kind())
+			fmt.Fprintf(stdout, "Cite key: %s\n", decl.CiteKey)
+			fmt.Fprintln(stdout, "Comments:")
 			for i, c := range decl.Comments.Values {
-				fmt.Printf("%d: %s\n", i, c.Value)
+				fmt.Fprintf(stdout, "%d: %s\n", i, c.Value)
 			}
-			fmt.Println("Fields:")
+			fmt.Fprintln(stdout, "Fields:")
 			for _, f := range decl.Fields {
-				fmt.Printf("%s = %s\n", f.Key, f.Value)
+				fmt.Fprintf(stdout, "%s = %s\n", f.Key, f.Value)
 			}
-			fmt.Println()
+			fmt.Fprintln(stdout)
 		case *parse.PreambleDecl:
-			fmt.Printf("Type: %s\n", decl)
-			fmt.Println("Comments:")
+			fmt.Fprintf(stdout, "Type: %s\n", decl.Kind())
+			fmt.Fprintln(stdout, "Comments:")
 			for i, c := range decl.Comments.Values {
-				fmt.Printf("%d: %s\n", i, c.Value)
+				fmt.Fprintf(stdout, "%d: %s\n", i, c.Value)
 			}
-			fmt.Println("Value:")
-			fmt.Println(decl.Value)
+			fmt.Fprintln(stdout, "Value:")
+			fmt.Fprintln(stdout, decl.Value)
 		case *parse.AbbrevDecl:
-			fmt.Printf("Type: %s\n", decl)
-			fmt.Println("Comments:")
+			fmt.Fprintf(stdout, "Type: %s\n", decl.Kind())
+			fmt.Fprintln(stdout, "Comments:")
 			for i, c := range decl.Comments.Values {
-				fmt.Printf("%d: %s\n", i, c.Value)
+				fmt.Fprintf(stdout, "%d: %s\n", i, c.Value)
 			}
-			fmt.Println("Field:")
-			fmt.Printf("%s = %s\n", decl.Field.Key, decl.Field.Value)
+			fmt.Fprintln(stdout, "Field:")
+			fmt.Fprintf(stdout, "%s = %s\n", decl.Field.Key, decl.Field.Value)
 		default:
-			fmt.Println(decl)
+			fmt.Fprintln(stdout, decl)
 		}
 		n, ok = p.Next()
 	}
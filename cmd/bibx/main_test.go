@@ -0,0 +1,163 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mdm-code/bibx/internal/compressed"
+	"github.com/mdm-code/bibx/internal/dedupe"
+)
+
+func TestOverwrites(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.bib")
+	other := filepath.Join(dir, "other.bib")
+	for _, path := range []string{in, other} {
+		if err := os.WriteFile(path, []byte("@misc{a, year = 1}\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := filepath.Join(dir, "link.bib")
+	if err := os.Symlink(in, link); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name string
+		out  string
+		args []string
+		want string
+	}{
+		{"input", in, []string{"-w", in}, in},
+		{"link", link, []string{in}, in},
+		{"flag", in, []string{"-bib=" + in}, "-bib=" + in},
+		{"other", other, []string{in}, ``},
+		{"new", filepath.Join(dir, "new.bib"), []string{in}, ``},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			have, ok := overwrites(c.out, c.args)
+			if have != c.want || ok != (c.want != ``) {
+				t.Errorf("have %q, %v; want %q", have, ok, c.want)
+			}
+		})
+	}
+}
+
+func TestWriteOutput(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte("@misc{a, year = 1}\n")
+	plain := filepath.Join(dir, "out.bib")
+	if err := os.WriteFile(plain, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{plain, filepath.Join(dir, "out.bib.gz")} {
+		if err := writeOutput(path, data); err != nil {
+			t.Fatal(err)
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := compressed.Detect(src), compressed.FromPath(path); have != want {
+			t.Errorf("%s: have format %v; want %v", path, have, want)
+		}
+		if src, err = compressed.Decode(src); err != nil || string(src) != string(data) {
+			t.Errorf("%s: have %q, %v; want %q", path, src, err, data)
+		}
+	}
+	info, err := os.Stat(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := info.Mode().Perm(), os.FileMode(0o600); have != want {
+		t.Errorf("have mode %v; want %v", have, want)
+	}
+}
+
+func TestChainRenames(t *testing.T) {
+	have := chainRenames(map[string]string{"b": "a", "a": "c", "d": "e"})
+	want := map[string]string{"b": "c", "a": "c", "d": "e"}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v; want %v", have, want)
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	src := `@article{Cohen1963, author = {Cohen, Paul}, title = {The independence of the continuum hypothesis}, year = 1963}
+@article{cohen63, author = {P. J. Cohen}, title = {The Independence of the Continuum Hypothesis}, year = 1963}
+@article{anon, title = {The independence of the continuum hypothesis}, year = 1963}
+@article{other, author = {Smith, John}, title = {The independence of the continuum hypothesis}, year = 1963}
+@article{doi, author = {Doe, Jane}, title = {Unrelated}, doi = {10.1/x}}
+@article{doi2, author = {Roe, Jim}, title = {Unrelated}, doi = {10.1/X}}
+`
+	es := entries(parseNodes(strings.NewReader(src)))
+	if len(dedupe.Candidates(es, 0.9)) == 0 {
+		t.Fatal("have no pairs to compare")
+	}
+	for _, threshold := range []float64{0.5, 0.8, 0.9} {
+		if have, want := candidates(es, threshold), dedupe.Candidates(es, threshold); !reflect.DeepEqual(have, want) {
+			t.Errorf("threshold %.2f: have %v; want %v", threshold, have, want)
+		}
+	}
+}
+
+func TestIndexed(t *testing.T) {
+	src := `@article{a, author = {G{\"o}del, Kurt}, year = 1931, doi = {10.1/A}}
+@article{b, author = {Cohen, Paul}, year = 1963}
+@article{c, author = {Gödel, Kurt}, year = 1940}
+`
+	nodes := parseNodes(strings.NewReader(src))
+	cases := []struct {
+		name, doi, author, years string
+		want                     string
+	}{
+		{"none", "", "", "", "a b c"},
+		{"doi", "https://doi.org/10.1/a", "", "", "a"},
+		{"author", "", "godel", "", "a c"},
+		{"year", "", "", "1963", "b"},
+		{"years", "", "", "1930-1950", "a c"},
+		{"all", "", "Gödel", "1935-1945", "c"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			from, to, err := yearRange(c.years)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := indexed(nodes, c.doi, c.author, from, to)
+			keys := []string{}
+			for _, e := range entries(nodes) {
+				if found == nil || found[e] {
+					keys = append(keys, e.CiteKey)
+				}
+			}
+			if have := strings.Join(keys, " "); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+	if _, _, err := yearRange("2000-1990"); err == nil {
+		t.Error("have no error for a reversed range; want one")
+	}
+}
+
+func TestBackupName(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	a := backupName("bak", filepath.Join("a", "refs.bib"), at)
+	b := backupName("bak", filepath.Join("b", "refs.bib"), at)
+	if a == b {
+		t.Errorf("have %s for both; want distinct names", a)
+	}
+	for _, name := range []string{a, b} {
+		if !strings.HasPrefix(name, filepath.Join("bak", "refs.bib.")) || !strings.HasSuffix(name, ".20240501T123000.000.bak") {
+			t.Errorf("have %s; want bak/refs.bib.<hash>.20240501T123000.000.bak", name)
+		}
+	}
+	if have, want := backupName("bak", filepath.Join("a", "refs.bib"), at), a; have != want {
+		t.Errorf("have %s; want %s", have, want)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/merge"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// MergetoolCmd merges two versions of a BibTeX file against their common
+// ancestor entry by entry and field by field. It follows the convention of
+// git merge drivers: the result replaces ours and conflicts make it fail.
+func mergetoolCmd(args []string) error {
+	fs := flag.NewFlagSet("mergetool", flag.ExitOnError)
+	toStdout := fs.Bool("stdout", false, "print the result to stdout instead of writing it to ours")
+	dryRun := fs.Bool("dry-run", false, "print a unified diff of the changes to ours instead; nothing is written")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bibx mergetool [-stdout] [-dry-run] base ours theirs")
+		fmt.Fprintln(fs.Output(), "\nTo use it as a git merge driver for BibTeX files, add to .git/config:")
+		fmt.Fprintln(fs.Output(), "\n  [merge \"bibx\"]")
+		fmt.Fprintln(fs.Output(), "  \tname = BibTeX entry merge")
+		fmt.Fprintln(fs.Output(), "  \tdriver = bibx mergetool %O %A %B")
+		fmt.Fprintln(fs.Output(), "\nand to .gitattributes:")
+		fmt.Fprintln(fs.Output(), "\n  *.bib merge=bibx")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 3 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	versions := make([][]parse.Node, 3)
+	for i, path := range fs.Args() {
+		nodes, err := readFile(path)
+		if err != nil {
+			return err
+		}
+		versions[i] = nodes
+	}
+	nodes, conflicts := merge.Merge(versions[0], versions[1], versions[2])
+	var b bytes.Buffer
+	if err := format.Nodes(&b, nodes); err != nil {
+		return err
+	}
+	switch {
+	case *toStdout:
+		if _, err := stdout.Write(b.Bytes()); err != nil {
+			return err
+		}
+	case *dryRun:
+		ours := fs.Arg(1)
+		src, err := os.ReadFile(ours)
+		if err != nil {
+			return err
+		}
+		if err := writeResult(stdout, ours, src, b.Bytes(), writeMode{dryRun: true}); err != nil {
+			return err
+		}
+	default:
+		ours := fs.Arg(1)
+		info, err := os.Stat(ours)
+		if err != nil {
+			return err
+		}
+		if err := writeFile(ours, b.Bytes(), info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	for _, c := range conflicts {
+		fmt.Fprintf(os.Stderr, "conflict: %s\n", c.Error())
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%d conflict(s)", len(conflicts))
+	}
+	return nil
+}
+
+// ReadFile parses the declarations of the file. Unlike readNodes it fails on
+// syntax errors rather than dropping the declarations that follow them.
+func readFile(path string) ([]parse.Node, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p := newParser(f)
+	result := []parse.Node{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		result = append(result, n)
+	}
+	if err := p.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/validate"
+)
+
+// NewCmd prints, or appends to a file, a skeleton entry of the type with the
+// fields it requires left empty or filled in by the user when prompted.
+func newCmd(args []string) error {
+	fs := flag.NewFlagSet("new", flag.ExitOnError)
+	appendTo := fs.String("append", "", "append the entry to the BibTeX `file` instead of printing it")
+	interactive := fs.Bool("i", false, "prompt for the value of each required field")
+	set := validate.Builtin
+	fs.Func("schema", "load additional schemas and house rules from a JSON or YAML `file`; may be repeated", func(path string) error {
+		s, err := validate.LoadFile(path)
+		if err != nil {
+			return err
+		}
+		set = set.Merge(s)
+		return nil
+	})
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bibx new [-i] [-append file] [-schema file] type [key]")
+		fmt.Fprintln(fs.Output(), "\nThe fields required by the schema of the type and the house rules are")
+		fmt.Fprintln(fs.Output(), "included. Without a key one is generated from the author and year.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	typ := strings.ToLower(fs.Arg(0))
+	if _, ok := set.Types[typ]; !ok {
+		return fmt.Errorf("unknown entry type %q", typ)
+	}
+	if len(set.Allowed) > 0 && !containsFold(set.Allowed, typ) {
+		return fmt.Errorf("entry type %q not allowed by the house rules", typ)
+	}
+	e := &parse.EntryDecl{Name: typ, CiteKey: fs.Arg(1), Comments: &parse.CommentGroupExpr{}}
+	in := bufio.NewReader(os.Stdin)
+	for _, req := range set.RequiredFields(typ) {
+		alts := strings.Split(req, "|")
+		key, value := alts[0], ""
+		if *interactive {
+			var err error
+			if key, value, err = prompt(in, alts); err != nil {
+				return err
+			}
+		}
+		e.Fields = append(e.Fields, &parse.FieldStmt{Key: key, Value: parse.Quote(value)})
+	}
+	return writeFetched(*appendTo, []*parse.EntryDecl{e})
+}
+
+// Prompt asks the user for the value of each alternative field in turn
+// until one is given, and returns the field along with its value. The first
+// alternative is returned with no value when none is given.
+func prompt(in *bufio.Reader, alts []string) (string, string, error) {
+	for _, key := range alts {
+		fmt.Fprintf(os.Stderr, "%s: ", key)
+		line, err := in.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", "", err
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			return key, line, nil
+		}
+		if err == io.EOF {
+			fmt.Fprintln(os.Stderr)
+			break
+		}
+	}
+	return alts[0], "", nil
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
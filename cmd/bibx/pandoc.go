@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/csl"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/resolve"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+// PandocCmd serves as the bibliography of pandoc: it reads the JSON AST of a
+// document on stdin, looks up the entries it cites in the files and writes
+// the document back with the entries as CSL-JSON references in its metadata,
+// which citeproc renders. Unlike citeproc, it skips the declarations of the
+// files it cannot parse rather than rejecting the files.
+func pandocCmd(args []string) error {
+	fs := flag.NewFlagSet("pandoc", flag.ExitOnError)
+	items := fs.Bool("csl", false, "print the cited entries as CSL-JSON items instead of the document")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bibx pandoc [-csl] file ...")
+		fmt.Fprintln(fs.Output(), "\nRun it as a JSON filter ahead of citeproc from a script ignoring its arguments:")
+		fmt.Fprintln(fs.Output(), "\n    #!/bin/sh\n    exec bibx pandoc refs.bib")
+		fmt.Fprintln(fs.Output(), "\n    pandoc --filter ./bib --citeproc doc.md")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	doc, err := csl.ReadDocument(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("reading document: %w", err)
+	}
+	nodes := []parse.Node{}
+	for i, path := range fs.Args() {
+		ns, err := parseTolerant(path)
+		if err != nil {
+			return err
+		}
+		nodes = append(nodes, ns...)
+		logFile(path)
+		trackFiles(i+1, fs.NArg())
+	}
+	resolved, errs := resolve.Resolve(entries(nodes))
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "bibx: %v\n", err)
+	}
+	result := citedItems(doc.Citations(), resolved, parse.NewMacroTable(nodes))
+
+	if *items {
+		enc := json.NewEncoder(stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+	if err := doc.SetReferences(result); err != nil {
+		return err
+	}
+	return doc.Write(stdout)
+}
+
+// CitedItems converts the entries cited by the ids into items, all of them
+// when `*` is cited, with the macros of their fields expanded. Ids are
+// matched case-insensitively but kept as cited, since citeproc matches them
+// exactly.
+func citedItems(ids []string, es []*parse.EntryDecl, macros parse.MacroTable) []csl.Item {
+	byKey := map[string]*parse.EntryDecl{}
+	for _, e := range es {
+		if k := strings.ToLower(e.CiteKey); byKey[k] == nil {
+			byKey[k] = e
+		}
+	}
+	result := []csl.Item{}
+	seen := map[*parse.EntryDecl]bool{}
+	add := func(id string, e *parse.EntryDecl) {
+		if seen[e] {
+			return
+		}
+		seen[e] = true
+		it := csl.FromEntry(expandMacros(e, macros))
+		it.ID = id
+		result = append(result, it)
+	}
+	for _, id := range ids {
+		if id == "*" {
+			for _, e := range es {
+				add(e.CiteKey, e)
+			}
+			continue
+		}
+		e, ok := byKey[strings.ToLower(id)]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "bibx: no entry for citation %s\n", id)
+			continue
+		}
+		add(id, e)
+	}
+	return result
+}
+
+// ExpandMacros returns a copy of the entry with the macros of its field
+// values expanded.
+func expandMacros(e *parse.EntryDecl, macros parse.MacroTable) *parse.EntryDecl {
+	c := *e
+	c.Fields = make([]*parse.FieldStmt, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		c.Fields = append(c.Fields, &parse.FieldStmt{Key: f.Key, Value: parse.Quote(macros.Expand(f.Value)), Pos: f.Pos})
+	}
+	return &c
+}
+
+// ParseTolerant parses the declarations of the file. When the file does not
+// parse, it is split before each line starting with @ and the parts that
+// parse are kept, while the others are reported on stderr and skipped.
+func parseTolerant(path string) ([]parse.Node, error) {
+	src, err := readSource(path)
+	if err != nil {
+		return nil, err
+	}
+	nodes, p := parseChunk(src, scan.Pos{Line: 1, Column: 1})
+	if p.Err() == nil {
+		return nodes, nil
+	}
+	result := []parse.Node{}
+	line, rest := 1, src
+	for len(rest) > 0 {
+		n := nextDecl(rest)
+		nodes, p := parseChunk(rest[:n], scan.Pos{Line: line, Column: 1})
+		if p.Err() != nil {
+			fmt.Fprintf(os.Stderr, "bibx: skipped %v\n", syntaxError(path, src, p))
+		} else {
+			result = append(result, nodes...)
+		}
+		line += bytes.Count(rest[:n], []byte("\n"))
+		rest = rest[n:]
+	}
+	return result, nil
+}
+
+func parseChunk(src []byte, start scan.Pos) ([]parse.Node, *parse.Parser) {
+	p := parse.NewParser(scan.NewScanner(scan.NewBytesReader(src, scan.WithStart(start))), parse.WithLogger(logger))
+	result := []parse.Node{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		result = append(result, n)
+	}
+	return result, p
+}
+
+// NextDecl returns the offset of the first line of the source, other than
+// its very first, that starts with @, possibly indented, or the length of
+// the source when there is none.
+func nextDecl(src []byte) int {
+	off := 0
+	for first := true; off < len(src); first = false {
+		end := bytes.IndexByte(src[off:], '\n')
+		if end < 0 {
+			return len(src)
+		}
+		if !first && bytes.HasPrefix(bytes.TrimLeft(src[off:off+end], " \t"), []byte("@")) {
+			return off
+		}
+		off += end + 1
+	}
+	return off
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/arxiv"
+	"github.com/mdm-code/bibx/internal/crossref"
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/lint"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/report"
+)
+
+// PreprintsCmd reports arXiv preprints that have since been published under
+// a DOI and optionally rewrites them into entries of the published works.
+func preprintsCmd(args []string) error {
+	fs := flag.NewFlagSet("preprints", flag.ExitOnError)
+	fix := fs.Bool("fix", false, "rewrite the published preprints, rewriting the files or printing stdin to stdout")
+	dryRun := fs.Bool("dry-run", false, "let -fix print a unified diff of the changes instead; nothing is written")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	net := networkFlags(fs)
+	fs.Parse(args)
+
+	ctx, cancel := net.context()
+	defer cancel()
+	c := &preprinter{
+		arxiv:    &arxiv.Client{HTTP: net.client(), Retry: net.policy()},
+		crossref: &crossref.Client{HTTP: net.client(), Retry: net.policy()},
+	}
+
+	rep := &report.Report{}
+	if fs.NArg() == 0 {
+		src, err := readStdin()
+		if err != nil {
+			return err
+		}
+		if err := preprintsSource(ctx, c, rep, "<stdin>", src, *fix, writeMode{dryRun: *dryRun}); err != nil {
+			return err
+		}
+	}
+	for _, path := range fs.Args() {
+		src, err := readSource(path)
+		if err != nil {
+			return err
+		}
+		if err := preprintsSource(ctx, c, rep, path, src, *fix, writeMode{write: true, dryRun: *dryRun}); err != nil {
+			return err
+		}
+	}
+	// Fixed stdin goes to stdout, so the findings go to stderr.
+	out := io.Writer(stdout)
+	if *fix && fs.NArg() == 0 {
+		out = os.Stderr
+	}
+	if err := writeReportTo(out, rep, *asJSON); err != nil {
+		return err
+	}
+	if n := len(rep.Findings); n > 0 && !*fix {
+		return fmt.Errorf("%d published preprint(s) found", n)
+	}
+	return nil
+}
+
+// Preprinter holds the clients of the services preprints are looked up on.
+type preprinter struct {
+	arxiv    *arxiv.Client
+	crossref *crossref.Client
+}
+
+// PreprintsSource looks up the arXiv preprints of the source without a DOI
+// of a published work and adds those published since to the report. With
+// fix set they are rewritten, and the result is handed over as the mode
+// tells.
+func preprintsSource(ctx context.Context, c *preprinter, rep *report.Report, path string, src []byte, fix bool, mode writeMode) error {
+	nodes, err := parseSource(path, src)
+	if err != nil {
+		return err
+	}
+	// Preprints by their arXiv identifiers.
+	preprints := make(map[string][]*parse.EntryDecl)
+	ids := []string{}
+	for _, e := range entries(nodes) {
+		id, ok := arxiv.Eprint(e)
+		if !ok || publishedDOI(e) != "" {
+			continue
+		}
+		if len(preprints[id]) == 0 {
+			ids = append(ids, id)
+		}
+		preprints[id] = append(preprints[id], e)
+	}
+	if len(ids) == 0 {
+		return writePreprints(path, src, nodes, fix, mode)
+	}
+	found, err := c.arxiv.Fetch(ctx, ids...)
+	if err != nil {
+		return err
+	}
+	for _, a := range found {
+		id, _ := arxiv.Eprint(a)
+		doi := publishedDOI(a)
+		if doi == "" {
+			continue
+		}
+		w, err := c.crossref.Fetch(ctx, doi)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "arXiv:%s: %s\n", id, err)
+			continue
+		}
+		for _, e := range preprints[id] {
+			f := report.Finding{
+				Severity: report.Warning,
+				Rule:     "published-preprint",
+				Pos:      e.Pos,
+				Key:      e.CiteKey,
+				Message:  fmt.Sprintf("arXiv:%s has been published as doi:%s", id, w.DOI),
+			}
+			if fix {
+				w.Publish(e)
+				f.Severity = report.Info
+				f.Message = fmt.Sprintf("arXiv:%s rewritten as published doi:%s", id, w.DOI)
+			}
+			rep.Add(path, f)
+		}
+	}
+	rep.Quote(path, src)
+	return writePreprints(path, src, nodes, fix, mode)
+}
+
+func writePreprints(path string, src []byte, nodes []parse.Node, fix bool, mode writeMode) error {
+	if !fix {
+		return nil
+	}
+	var b bytes.Buffer
+	if err := format.Nodes(&b, nodes); err != nil {
+		return err
+	}
+	return writeResult(stdout, path, src, b.Bytes(), mode)
+}
+
+// PublishedDOI returns the DOI of the entry unless it is missing or is the
+// DOI arXiv registers for the preprint itself.
+func publishedDOI(e *parse.EntryDecl) string {
+	f, ok := e.Get("doi")
+	if !ok {
+		return ""
+	}
+	doi := lint.NormalizeDOI(parse.Unquote(f.Value))
+	if strings.HasPrefix(doi, "10.48550/") {
+		return ""
+	}
+	return doi
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/citations"
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/graph"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// PruneCmd prints the bibliography without the entries a LaTeX document does
+// not cite. Entries referred to by cited entries through crossref or xdata
+// are kept, and so are the @string and @preamble declarations.
+func pruneCmd(args []string) error {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	var cited []string
+	fs.Func("cited", "read the citations of a LaTeX .aux or .tex `file`; may be repeated", func(path string) error {
+		cited = append(cited, path)
+		return nil
+	})
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bibx prune -cited file [-cited file ...] [file ...]")
+		fmt.Fprintln(fs.Output(), "\nThe .aux files written by LaTeX are the most reliable source of citations;")
+		fmt.Fprintln(fs.Output(), "the files they include with \\@input are read as well.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if len(cited) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	set, err := citations.Load(cited...)
+	if err != nil {
+		return err
+	}
+	nodes, err := readNodes(fs.Args())
+	if err != nil {
+		return err
+	}
+	refs := make(map[string][]string)
+	for _, e := range graph.Build(entries(nodes), []string{"crossref", "xdata"}).Edges {
+		from := strings.ToLower(e.From)
+		refs[from] = append(refs[from], strings.ToLower(e.To))
+	}
+	keep := make(map[string]bool)
+	var visit func(key string)
+	visit = func(key string) {
+		if keep[key] {
+			return
+		}
+		keep[key] = true
+		for _, to := range refs[key] {
+			visit(to)
+		}
+	}
+	defined := make(map[string]bool)
+	for _, e := range entries(nodes) {
+		defined[e.CiteKey] = true
+		if set.Contains(e.CiteKey) {
+			visit(strings.ToLower(e.CiteKey))
+		}
+	}
+	for _, k := range set.Keys {
+		if !defined[k] {
+			fmt.Fprintf(os.Stderr, "bibx: cited key %s not found\n", k)
+		}
+	}
+	result := []parse.Node{}
+	for _, n := range nodes {
+		if e, ok := n.(*parse.EntryDecl); ok && !keep[strings.ToLower(e.CiteKey)] {
+			continue
+		}
+		result = append(result, n)
+	}
+	return format.Nodes(stdout, result)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/library"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/provenance"
+)
+
+// QueryCmd prints the entries of the files matching the filters, optionally
+// along with the files and lines their entries and fields come from.
+func queryCmd(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	text := fs.String("q", "", "match the `text` in the cite key or any field value")
+	typ := fs.String("type", "", "match the entry `type`")
+	doi := fs.String("doi", "", "match the `doi`, ignoring case and resolver prefixes")
+	author := fs.String("author", "", "match an author, or an editor of entries without authors, by `surname`")
+	years := fs.String("year", "", "match the `year`, or the years from-to inclusive")
+	showSource := fs.Bool("show-source", false, "print the file, line and column of the entries and their fields")
+	filters := [][2]string{}
+	fs.Func("field", "match the `field=text` in the value of the field; may be repeated", func(s string) error {
+		k, v, ok := strings.Cut(s, "=")
+		if !ok {
+			return fmt.Errorf("invalid field filter %q", s)
+		}
+		filters = append(filters, [2]string{k, v})
+		return nil
+	})
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bibx query [-q text] [-type type] [-doi doi] [-author surname] [-year from[-to]] [-field field=text ...] [-show-source] [file ...]")
+		fmt.Fprintln(fs.Output(), "\nMatching is case-insensitive and by substring, and all filters must match.")
+		fmt.Fprintln(fs.Output(), "The -doi, -author and -year filters match exactly and are looked up in indexes.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	from, to, err := yearRange(*years)
+	if err != nil {
+		return err
+	}
+
+	sources := &provenance.Table{}
+	nodes := []parse.Node{}
+	if fs.NArg() == 0 {
+		nodes = parseNodes(os.Stdin)
+		sources.Add("<stdin>", nodes)
+	}
+	for i, path := range fs.Args() {
+		ns, err := parseFile(path)
+		if err != nil {
+			return err
+		}
+		sources.Add(path, ns)
+		nodes = append(nodes, ns...)
+		logFile(path)
+		trackFiles(i+1, fs.NArg())
+	}
+	found := indexed(nodes, *doi, *author, from, to)
+	result := []parse.Node{}
+	for _, e := range entries(nodes) {
+		if found != nil && !found[e] {
+			continue
+		}
+		if queryMatches(e, *text, *typ, filters) {
+			result = append(result, e)
+		}
+	}
+	if !*showSource {
+		return format.Nodes(stdout, result)
+	}
+	w := bufio.NewWriter(stdout)
+	for _, n := range result {
+		e := n.(*parse.EntryDecl)
+		o, _ := sources.Entry(e)
+		fmt.Fprintf(w, "%s: @%s{%s}\n", o, e.Name, e.CiteKey)
+		for _, f := range e.Fields {
+			o, _ := sources.Field(e, f)
+			fmt.Fprintf(w, "%s:   %s\n", o, f)
+		}
+	}
+	return w.Flush()
+}
+
+// YearRange parses the -year filter, a year or two joined by a hyphen. Both
+// years are 0 when it is empty.
+func yearRange(s string) (from, to int, err error) {
+	if s == "" {
+		return 0, 0, nil
+	}
+	a, b, ok := strings.Cut(s, "-")
+	if !ok {
+		b = a
+	}
+	from, err1 := strconv.Atoi(strings.TrimSpace(a))
+	to, err2 := strconv.Atoi(strings.TrimSpace(b))
+	if err1 != nil || err2 != nil || from > to {
+		return 0, 0, fmt.Errorf("invalid year filter %q", s)
+	}
+	return from, to, nil
+}
+
+// Indexed looks the entries with the DOI, the author surname and a year in
+// the range up in the indexes of a library, leaving out the filters that are
+// empty. It returns nil when all of them are.
+func indexed(nodes []parse.Node, doi, author string, from, to int) map[*parse.EntryDecl]bool {
+	if doi == "" && author == "" && to == 0 {
+		return nil
+	}
+	var result map[*parse.EntryDecl]bool
+	library.New(nodes).View(func(tx *library.Tx) error {
+		lookups := [][]*parse.EntryDecl{}
+		if doi != "" {
+			lookups = append(lookups, tx.ByDOI(doi))
+		}
+		if author != "" {
+			lookups = append(lookups, tx.ByAuthor(author))
+		}
+		if to != 0 {
+			lookups = append(lookups, tx.Years(from, to))
+		}
+		for i, es := range lookups {
+			found := make(map[*parse.EntryDecl]bool, len(es))
+			for _, e := range es {
+				if i == 0 || result[e] {
+					found[e] = true
+				}
+			}
+			result = found
+		}
+		return nil
+	})
+	return result
+}
+
+// QueryMatches reports whether the entry matches the text, type and field
+// filters that are not empty.
+func queryMatches(e *parse.EntryDecl, text, typ string, filters [][2]string) bool {
+	if typ != "" && !strings.EqualFold(e.Name, typ) {
+		return false
+	}
+	if text != "" {
+		found := hasText(e.CiteKey, text)
+		for _, f := range e.Fields {
+			found = found || hasText(parse.Unquote(f.Value), text)
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, kv := range filters {
+		f, ok := e.Lookup(kv[0])
+		if !ok || !hasText(parse.Unquote(f.Value), kv[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasText(s, text string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(text))
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/mdm-code/bibx/internal/render"
+)
+
+var styles = map[string]render.Style{
+	"apa":     render.APA,
+	"chicago": render.Chicago,
+	"ieee":    render.IEEE,
+}
+
+var orders = map[string]render.Order{
+	"":       render.Unsorted,
+	"key":    render.ByKey,
+	"author": render.ByAuthor,
+	"year":   render.ByYear,
+	"title":  render.ByTitle,
+	"venue":  render.ByVenue,
+}
+
+// RenderCmd prints the entries as a formatted reference list.
+func renderCmd(args []string) error {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	style := fs.String("style", "apa", "citation style: apa, chicago or ieee")
+	format := fs.String("format", "text", "output format: text, html or markdown")
+	order := fs.String("sort", "", "sort entries by key, author, year, title or venue")
+	tmpl := fs.String("template", "", "custom text/template executed per entry; overrides -style and -format")
+	opts := render.Options{}
+	fs.IntVar(&opts.MaxNames, "max-names", 0, "truncate name lists longer than `n` names with et al.; 0 keeps all names")
+	fs.IntVar(&opts.MinNames, "min-names", 1, "keep the first `n` names of truncated name lists")
+	fs.Parse(args)
+
+	s, ok := styles[*style]
+	if !ok {
+		return fmt.Errorf("unknown style %q", *style)
+	}
+	o, ok := orders[*order]
+	if !ok {
+		return fmt.Errorf("unknown sort order %q", *order)
+	}
+	var ser *render.Serializer
+	if *tmpl != "" {
+		var err error
+		if ser, err = render.NewSerializer(*tmpl); err != nil {
+			return err
+		}
+		ser.Options = opts
+	}
+	entries, err := readEntries(fs.Args())
+	if err != nil {
+		return err
+	}
+	render.Sort(entries, o)
+	if ser != nil {
+		return ser.SerializeAll(stdout, entries)
+	}
+
+	switch *format {
+	case "text":
+		return opts.Text(stdout, entries, s)
+	case "html":
+		return opts.HTML(stdout, entries, nil)
+	case "markdown":
+		return opts.Markdown(stdout, entries, nil)
+	}
+	return fmt.Errorf("unknown format %q", *format)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/mdm-code/bibx/internal/server"
+)
+
+// ServeCmd exposes the entries of a BibTeX file over a REST API, writing
+// changes back to the file.
+func serveCmd(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	bib := fs.String("bib", "", "BibTeX `file` to serve")
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	depth := fs.Int("max-depth", server.DefaultMaxDepth, "deepest nesting of braces accepted in the values of the entries sent")
+	maxBody := fs.Int64("max-body", server.DefaultMaxBodySize, "largest request body accepted in `bytes`")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bibx serve -bib library.bib [-addr host:port]")
+		fmt.Fprintln(fs.Output(), "\nEndpoints:")
+		fmt.Fprintln(fs.Output(), "  GET    /entries        list entries; filter with q, type and field parameters")
+		fmt.Fprintln(fs.Output(), "  POST   /entries        create an entry")
+		fmt.Fprintln(fs.Output(), "  GET    /entries/{key}  fetch an entry")
+		fmt.Fprintln(fs.Output(), "  PUT    /entries/{key}  update an entry; requires If-Match")
+		fmt.Fprintln(fs.Output(), "  DELETE /entries/{key}  delete an entry; requires If-Match")
+		fmt.Fprintln(fs.Output(), "  POST   /batch          apply a JSON array of create, update and delete operations atomically")
+		fmt.Fprintln(fs.Output(), "  GET    /metrics        Prometheus metrics of the server")
+		fmt.Fprintln(fs.Output(), "\nResponses are JSON unless format=bibtex or Accept asks for application/x-bibtex.")
+		fmt.Fprintln(fs.Output(), "Changes are journaled in library.bib"+server.JournalSuffix+" until written, and recovered on start.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *bib == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	s, err := server.New(*bib)
+	if err != nil {
+		return err
+	}
+	s.MaxDepth = *depth
+	s.MaxBodySize = *maxBody
+	if n := s.Replayed(); n > 0 {
+		fmt.Fprintf(os.Stderr, "recovered %d change(s) from the journal\n", n)
+	}
+	fmt.Fprintf(os.Stderr, "serving %s on http://%s\n", *bib, *addr)
+	return http.ListenAndServe(*addr, s)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mdm-code/bibx/internal/compressed"
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/remote"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+// ShowCmd prints the entries of the file with the keys. Only the entries
+// asked for are parsed, so that editors can look entries up in libraries
+// too large to parse on every keystroke.
+func showCmd(args []string) error {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	spans := fs.Bool("spans", false, "print the line, column and byte offsets of the entries instead")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bibx show [-spans] file key ...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	path := fs.Arg(0)
+	var (
+		src []byte
+		err error
+	)
+	if remote.IsURL(path) {
+		if src, err = fetchURL(path); err != nil {
+			return err
+		}
+	} else {
+		m, err := scan.MapFile(path)
+		if err != nil {
+			return err
+		}
+		defer m.Close()
+		src = m.Bytes()
+	}
+	if compressed.Detect(src) != compressed.None {
+		// Spans are offsets into the decompressed source.
+		if src, err = compressed.Decode(src); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	x := parse.NewIndex(src)
+	logger.Debug("file indexed", "path", path, "entries", len(x.Spans()))
+	shown, failed := 0, 0
+	for _, key := range fs.Args()[1:] {
+		if *spans {
+			s, ok := x.Lookup(key)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "bibx: %s: %v %s\n", path, parse.ErrNoEntry, key)
+				failed++
+				continue
+			}
+			fmt.Fprintf(stdout, "%s\t%s\t%d\t%d\n", s.Key, s.Pos, s.Start, s.End)
+			continue
+		}
+		e, err := x.LoadEntry(key)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "bibx: %s: %v\n", path, err)
+			failed++
+			continue
+		}
+		if shown > 0 {
+			fmt.Fprintln(stdout)
+		}
+		if err := format.Node(stdout, e); err != nil {
+			return err
+		}
+		shown++
+	}
+	if failed > 0 {
+		return errors.New("some entries could not be shown")
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/mdm-code/bibx/internal/stats"
+	"github.com/mdm-code/bibx/internal/validate"
+)
+
+// StatsCmd prints the statistics of the entries.
+func statsCmd(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	format := fs.String("format", "text", "output format: text, json or markdown")
+	top := fs.Int("top", 10, "number of the most frequent venues and authors listed; 0 lists all")
+	set := validate.Builtin
+	fs.Func("schema", "measure completeness against additional schemas from a JSON or YAML `file`; may be repeated", func(path string) error {
+		s, err := validate.LoadFile(path)
+		if err != nil {
+			return err
+		}
+		set = set.Merge(s)
+		return nil
+	})
+	fs.Parse(args)
+
+	entries, err := readEntries(fs.Args())
+	if err != nil {
+		return err
+	}
+	r := stats.Compute(entries, set, *top)
+	switch *format {
+	case "text":
+		return r.WriteText(stdout)
+	case "json":
+		return r.WriteJSON(stdout)
+	case "markdown":
+		return r.WriteMarkdown(stdout)
+	default:
+		return fmt.Errorf("unknown output format %q", *format)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mdm-code/bibx/internal/config"
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/tidy"
+)
+
+// TidyCmd runs the tidy pipeline configured in the project configuration
+// file over BibTeX files and writes them in the canonical layout.
+func tidyCmd(args []string) error {
+	fs := flag.NewFlagSet("tidy", flag.ExitOnError)
+	write := fs.Bool("w", false, "write the result to the source files instead of stdout")
+	dryRun := fs.Bool("dry-run", false, "print a unified diff of the changes instead of the result; nothing is written")
+	path := fs.String("config", "", "project configuration `file`; defaults to the closest "+config.FileName+" up from the working directory")
+	override := configFlags(fs, map[string]string{
+		"tidy.steps":       "comma-separated `list` of the steps to run",
+		"tidy.months":      "month `style`: macro, number or name",
+		"tidy.sort":        "sort entries by `key`, author, year, title or venue",
+		"tidy.field-order": "comma-separated `list` of fields in the order they are written",
+	})
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bibx tidy [-w] [-dry-run] [-config file] [file ...]")
+		fmt.Fprintln(fs.Output(), "\nThe steps run in order: case, names, pages, months, sort-fields, sort-entries and dedupe,")
+		fmt.Fprintln(fs.Output(), "followed by formatting. They are configured in the [tidy] table of the user or the")
+		fmt.Fprintln(fs.Output(), "project configuration file, "+config.FileName+", and overridden by the flags:")
+		fmt.Fprintln(fs.Output(), "\n  [tidy]")
+		fmt.Fprintln(fs.Output(), "  steps = [\"case\", \"names\", \"pages\", \"months\", \"sort-fields\", \"sort-entries\", \"dedupe\"]")
+		fmt.Fprintln(fs.Output(), "  months = \"macro\"          # macro, number or name")
+		fmt.Fprintln(fs.Output(), "  sort = \"key\"              # key, author, year, title or venue")
+		fmt.Fprintln(fs.Output(), "  field-order = [\"author\", \"title\", \"year\"]")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	cfg, _, err := loadConfig(*path)
+	if err != nil {
+		return err
+	}
+	if err := override(cfg); err != nil {
+		return err
+	}
+	opts, err := tidyOptions(cfg.Tidy)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		src, err := readStdin()
+		if err != nil {
+			return err
+		}
+		res, err := tidySource("<stdin>", src, opts)
+		if err != nil {
+			return err
+		}
+		return writeResult(stdout, "<stdin>", src, res, writeMode{dryRun: *dryRun})
+	}
+	mode := writeMode{write: *write, dryRun: *dryRun}
+	for _, path := range fs.Args() {
+		src, err := readSource(path)
+		if err != nil {
+			return err
+		}
+		res, err := tidySource(path, src, opts)
+		if err != nil {
+			return err
+		}
+		if err := writeResult(stdout, path, src, res, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TidyOptions converts the configured settings into the pipeline options.
+func tidyOptions(c config.Tidy) (tidy.Options, error) {
+	opts := tidy.Options{Steps: c.Steps}
+	var ok bool
+	if opts.Months, ok = monthStyles[c.Months]; !ok {
+		return opts, fmt.Errorf("unknown month style %q", c.Months)
+	}
+	if opts.Order, ok = orders[c.Sort]; !ok {
+		return opts, fmt.Errorf("unknown sort order %q", c.Sort)
+	}
+	if len(c.FieldOrder) > 0 {
+		opts.FieldOrder = c.FieldOrder
+	}
+	return opts, nil
+}
+
+// TidySource runs the pipeline over the source and formats the result. The
+// removed duplicates are listed on stderr.
+func tidySource(path string, src []byte, opts tidy.Options) ([]byte, error) {
+	nodes, err := parseSource(path, src)
+	if err != nil {
+		return nil, err
+	}
+	r, err := tidy.Run(nodes, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range r.Removed {
+		fmt.Fprintf(os.Stderr, "%s:%s: removed duplicate entry %s\n", path, e.Pos, e.CiteKey)
+	}
+	var b bytes.Buffer
+	if err := format.Nodes(&b, r.Nodes); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/report"
+	"github.com/mdm-code/bibx/internal/resolve"
+	"github.com/mdm-code/bibx/internal/validate"
+)
+
+// ValidateCmd reports entries violating the schemas of their entry types and
+// broken crossref and xdata references.
+func validateCmd(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	set := validate.Builtin
+	fs.Func("schema", "load additional schemas and house rules from a JSON or YAML `file`; may be repeated", func(path string) error {
+		s, err := validate.LoadFile(path)
+		if err != nil {
+			return err
+		}
+		set = set.Merge(s)
+		return nil
+	})
+	fs.Parse(args)
+
+	// Paths of the files the entries come from, by lower-case cite key.
+	paths := make(map[string]string)
+	sources := make(map[string][]byte)
+	es := []*parse.EntryDecl{}
+	if fs.NArg() == 0 {
+		es = entries(parseNodes(os.Stdin))
+	}
+	for _, path := range fs.Args() {
+		src, err := readSource(path)
+		if err != nil {
+			return err
+		}
+		sources[path] = src
+		for _, e := range entries(parseNodes(bytes.NewReader(src))) {
+			if k := strings.ToLower(e.CiteKey); paths[k] == "" {
+				paths[k] = path
+			}
+			es = append(es, e)
+		}
+	}
+	rep := &report.Report{}
+	for _, e := range es {
+		for _, v := range set.Validate(e) {
+			rep.Add(paths[strings.ToLower(e.CiteKey)], v.Report())
+		}
+	}
+	for _, err := range resolve.Check(es) {
+		if e, ok := err.(*resolve.Error); ok {
+			rep.Add(paths[strings.ToLower(e.Key)], e.Report())
+		}
+	}
+	for path, src := range sources {
+		rep.Quote(path, src)
+	}
+	if err := writeReport(rep, *asJSON); err != nil {
+		return err
+	}
+	if n := len(rep.Findings); n > 0 {
+		return fmt.Errorf("%d violation(s) found", n)
+	}
+	return nil
+}
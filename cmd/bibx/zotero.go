@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/citekey"
+	"github.com/mdm-code/bibx/internal/csl"
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/lint"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/tex"
+	"github.com/mdm-code/bibx/internal/zotero"
+)
+
+// ZoteroCmd synchronizes a Zotero library or collection with a BibTeX file.
+// Pull brings the items of the library into the file, and push brings the
+// entries of the file into the library.
+func zoteroCmd(args []string) error {
+	flags := flag.NewFlagSet("zotero", flag.ExitOnError)
+	library := flags.String("library", os.Getenv("ZOTERO_LIBRARY"), "`library` to sync, users/<userID> or groups/<groupID>; defaults to $ZOTERO_LIBRARY")
+	collection := flags.String("collection", "", "`key` of the collection to sync instead of the whole library")
+	key := flags.String("key", os.Getenv("ZOTERO_API_KEY"), "Zotero API `key`; defaults to $ZOTERO_API_KEY")
+	dryRun := flags.Bool("dry-run", false, "with pull, print a unified diff of the changes to the file instead; nothing is written")
+	net := networkFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: bibx zotero pull|push [-library lib] [-collection key] [-key key] [-dry-run] [-timeout d] [-retries n] file")
+		fmt.Fprintln(flags.Output(), "\nPull updates the entries of the file from the matching items and appends the new ones.")
+		fmt.Fprintln(flags.Output(), "Push creates the items missing from the library and updates the changed ones.")
+		fmt.Fprintln(flags.Output(), "Items match entries by their citation key, or by DOI or title for items without one.")
+		flags.PrintDefaults()
+	}
+	if len(args) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	sync, ok := map[string]func(context.Context, *zotero.Client, string, string) error{
+		"pull": func(ctx context.Context, c *zotero.Client, collection, path string) error {
+			return zoteroPull(ctx, c, collection, path, writeMode{write: true, dryRun: *dryRun})
+		},
+		"push": zoteroPush,
+	}[args[0]]
+	if !ok {
+		flags.Usage()
+		os.Exit(2)
+	}
+	flags.Parse(args[1:])
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if !strings.HasPrefix(*library, "users/") && !strings.HasPrefix(*library, "groups/") {
+		return fmt.Errorf("invalid library %q: want users/<userID> or groups/<groupID>", *library)
+	}
+	ctx, cancel := net.context()
+	defer cancel()
+	c := &zotero.Client{Library: *library, Key: *key, HTTP: net.client(), Retry: net.policy()}
+	return sync(ctx, c, *collection, flags.Arg(0))
+}
+
+// ZoteroPull updates the entries of the file matching the items of the
+// collection and appends the entries of the items without a match. Fields
+// whose text has not changed keep their markup, and fields missing from the
+// items are kept.
+func zoteroPull(ctx context.Context, c *zotero.Client, collection, path string, mode writeMode) error {
+	items, err := c.Items(ctx, collection)
+	if err != nil {
+		return err
+	}
+	src, err := readSource(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	nodes, err := parseSource(path, src)
+	if err != nil {
+		return err
+	}
+	local := entries(nodes)
+	m := newZoteroMatcher(local)
+	cslItems := make([]csl.Item, 0, len(items))
+	for _, it := range items {
+		cslItems = append(cslItems, it.CSL)
+	}
+	// The keys of the entries added must not collide with the keys of the file.
+	taken := map[string]bool{}
+	for _, e := range local {
+		taken[e.CiteKey] = true
+	}
+	updated, added := 0, []*parse.EntryDecl{}
+	for i, pulled := range csl.Entries(cslItems) {
+		if e := m.match(cslItems[i]); e != nil {
+			if pullEntry(e, pulled) {
+				updated++
+			}
+			continue
+		}
+		if cslItems[i].CitationKey == "" {
+			pulled.CiteKey = citekey.Generate(pulled)
+		}
+		added = append(added, pulled)
+		m.add(pulled)
+	}
+	logRenamed(citekey.Import(added, taken))
+	for _, e := range added {
+		nodes = append(nodes, e)
+	}
+	fmt.Fprintf(os.Stderr, "%s: %d updated, %d added\n", path, updated, len(added))
+	var b bytes.Buffer
+	if err := format.Nodes(&b, nodes); err != nil {
+		return err
+	}
+	if bytes.Equal(b.Bytes(), src) {
+		return nil
+	}
+	if _, err := os.Stat(path); err == nil || mode.dryRun {
+		return writeResult(stdout, path, src, b.Bytes(), mode)
+	}
+	return writeFile(path, b.Bytes(), 0o644)
+}
+
+// ZoteroPush creates the items of the entries of the file without a
+// matching item in the collection and updates the matching items that
+// differ from their entries.
+func zoteroPush(ctx context.Context, c *zotero.Client, collection, path string) error {
+	nodes, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	items, err := c.Items(ctx, collection)
+	if err != nil {
+		return err
+	}
+	remote := make(map[string]int)
+	for i, it := range items {
+		for _, id := range zoteroLookup(it.CSL) {
+			if _, ok := remote[id]; !ok {
+				remote[id] = i
+			}
+		}
+	}
+	created := []csl.Item{}
+	updated := 0
+	for _, e := range entries(nodes) {
+		it := csl.FromEntry(e)
+		i, ok := -1, false
+		for _, id := range zoteroIdentities(it) {
+			if i, ok = remote[id]; ok {
+				break
+			}
+		}
+		if !ok {
+			created = append(created, it)
+			continue
+		}
+		if reflect.DeepEqual(zotero.Data(it), zotero.Data(items[i].CSL)) {
+			continue
+		}
+		if err := c.Update(ctx, items[i], it); err != nil {
+			return fmt.Errorf("%s: %w", e.CiteKey, err)
+		}
+		updated++
+	}
+	if err := c.Create(ctx, collection, created); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%s: %d updated, %d created\n", path, updated, len(created))
+	return nil
+}
+
+// ZoteroMatcher finds the entries matching Zotero items.
+type zoteroMatcher map[string]*parse.EntryDecl
+
+func newZoteroMatcher(es []*parse.EntryDecl) zoteroMatcher {
+	m := zoteroMatcher{}
+	for _, e := range es {
+		m.add(e)
+	}
+	return m
+}
+
+func (m zoteroMatcher) add(e *parse.EntryDecl) {
+	for _, id := range zoteroIdentities(csl.FromEntry(e)) {
+		if _, ok := m[id]; !ok {
+			m[id] = e
+		}
+	}
+}
+
+// Match returns the entry with the citation key of the item or, if the item
+// has none, the entry with the same DOI or title.
+func (m zoteroMatcher) match(it csl.Item) *parse.EntryDecl {
+	for _, id := range zoteroLookup(it) {
+		if e, ok := m[id]; ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// ZoteroIdentities lists the citation key, the DOI and the title of the
+// item in the order of precedence they are matched by.
+func zoteroIdentities(it csl.Item) []string {
+	result := []string{}
+	if it.CitationKey != "" {
+		result = append(result, "key:"+strings.ToLower(it.CitationKey))
+	}
+	if it.DOI != "" {
+		result = append(result, "doi:"+strings.ToLower(lint.NormalizeDOI(it.DOI)))
+	}
+	if it.Title != "" {
+		result = append(result, "title:"+strings.ToLower(it.Title))
+	}
+	return result
+}
+
+// ZoteroLookup is like zoteroIdentities, but items with a citation key are
+// identified by it alone so that they never match entries under another key.
+func zoteroLookup(it csl.Item) []string {
+	ids := zoteroIdentities(it)
+	if it.CitationKey != "" {
+		return ids[:1]
+	}
+	return ids
+}
+
+// PullEntry copies the fields of the pulled entry whose text differs into
+// the entry and reports whether it changed.
+func pullEntry(e, pulled *parse.EntryDecl) bool {
+	changed := false
+	if !strings.EqualFold(e.Name, pulled.Name) {
+		e.Name = pulled.Name
+		changed = true
+	}
+	want := csl.FromEntry(pulled)
+	have := csl.FromEntry(e)
+	for _, f := range pulled.Fields {
+		old, ok := e.Get(f.Key)
+		switch {
+		case !ok:
+		case f.Key == "author":
+			if reflect.DeepEqual(have.Author, want.Author) {
+				continue
+			}
+		case f.Key == "editor":
+			if reflect.DeepEqual(have.Editor, want.Editor) {
+				continue
+			}
+		case tex.Decode(parse.Unquote(old.Value)) == tex.Decode(parse.Unquote(f.Value)):
+			continue
+		}
+		e.Set(f.Key, f.Value)
+		changed = true
+	}
+	return changed
+}
@@ -0,0 +1,80 @@
+//go:build cgo
+
+// Command libbibx builds bibx as a C shared library with
+//
+//	go build -buildmode=c-shared -o libbibx.so ./cmd/libbibx
+//
+// which also writes the libbibx.h header. The exported functions take UTF-8
+// C strings and return a JSON object holding either the result string or
+// the error message, for example {"result":"..."} or {"error":"..."}. The
+// results of bibx_parse and bibx_lint are themselves JSON documents. The
+// returned strings are allocated by the library and must be released with
+// bibx_free.
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"unsafe"
+
+	"github.com/mdm-code/bibx/internal/bindings"
+)
+
+func main() {}
+
+// Bibx_parse returns the entries of the BibTeX source as a JSON array.
+//
+//export bibx_parse
+func bibx_parse(src *C.char) *C.char {
+	return respond(bindings.Parse(C.GoString(src)))
+}
+
+// Bibx_lint returns the findings of the default lint rules as a JSON array.
+//
+//export bibx_lint
+func bibx_lint(src *C.char) *C.char {
+	return respond(bindings.Lint(C.GoString(src)))
+}
+
+// Bibx_fix returns the source with the automatic lint fixes applied.
+//
+//export bibx_fix
+func bibx_fix(src *C.char) *C.char {
+	return respond(bindings.Fix(C.GoString(src)))
+}
+
+// Bibx_format returns the source in the canonical layout.
+//
+//export bibx_format
+func bibx_format(src *C.char) *C.char {
+	return respond(bindings.Format(C.GoString(src)))
+}
+
+// Bibx_convert translates the source from one format of the convert command
+// to another.
+//
+//export bibx_convert
+func bibx_convert(src, from, to *C.char) *C.char {
+	return respond(bindings.Convert(C.GoString(src), C.GoString(from), C.GoString(to)))
+}
+
+// Bibx_free releases a string returned by the library.
+//
+//export bibx_free
+func bibx_free(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
+
+// Respond encodes the result or the error as a C string.
+func respond(result string, err error) *C.char {
+	v := map[string]string{"result": result}
+	if err != nil {
+		v = map[string]string{"error": err.Error()}
+	}
+	data, _ := json.Marshal(v)
+	return C.CString(string(data))
+}
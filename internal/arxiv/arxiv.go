@@ -0,0 +1,213 @@
+package arxiv
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/citekey"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/remote"
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+// DefaultEndpoint is the query endpoint of the arXiv API.
+const DefaultEndpoint = "https://export.arxiv.org/api/query"
+
+var monthMacros = [...]string{
+	"jan", "feb", "mar", "apr", "may", "jun",
+	"jul", "aug", "sep", "oct", "nov", "dec",
+}
+
+// Identifiers of the new `2101.00001` and the old `hep-th/9901001` styles
+// with an optional version suffix.
+var idPattern = regexp.MustCompile(`^(\d{4}\.\d{4,5}|[a-z-]+(\.[A-Z]{2})?/\d{7})(v\d+)?$`)
+
+type feed struct {
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID         string   `xml:"id"`
+	Published  string   `xml:"published"`
+	Title      string   `xml:"title"`
+	Summary    string   `xml:"summary"`
+	Authors    []string `xml:"author>name"`
+	DOI        string   `xml:"http://arxiv.org/schemas/atom doi"`
+	JournalRef string   `xml:"http://arxiv.org/schemas/atom journal_ref"`
+	Primary    struct {
+		Term string `xml:"term,attr"`
+	} `xml:"http://arxiv.org/schemas/atom primary_category"`
+}
+
+// Client queries the arXiv API for entries by their identifiers. Failed
+// requests are retried as the Retry policy tells.
+type Client struct {
+	HTTP     *http.Client
+	Endpoint string
+	Retry    remote.Policy
+}
+
+// IsID checks if the string is an arXiv identifier, with or without the
+// `arXiv:` prefix.
+func IsID(s string) bool {
+	return idPattern.MatchString(trimPrefix(s))
+}
+
+// Fetch retrieves the entries identified by the arXiv identifiers.
+func (c *Client) Fetch(ctx context.Context, ids ...string) ([]*parse.EntryDecl, error) {
+	list := make([]string, len(ids))
+	for i, id := range ids {
+		list[i] = trimPrefix(id)
+	}
+	endpoint := c.Endpoint
+	if endpoint == `` {
+		endpoint = DefaultEndpoint
+	}
+	q := url.Values{
+		"id_list":     {strings.Join(list, ",")},
+		"max_results": {fmt.Sprint(len(ids))},
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := remote.Do(ctx, c.HTTP, c.Retry, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("arxiv: unexpected response status %s", resp.Status)
+	}
+	return Read(resp.Body)
+}
+
+// Read converts an arXiv API Atom feed into misc entries carrying the eprint,
+// archiveprefix and primaryclass fields.
+func Read(r io.Reader) ([]*parse.EntryDecl, error) {
+	var f feed
+	if err := xml.NewDecoder(r).Decode(&f); err != nil {
+		return nil, err
+	}
+	taken := map[string]bool{}
+	result := []*parse.EntryDecl{}
+	for _, a := range f.Entries {
+		if strings.Contains(a.ID, "/api/errors") {
+			return nil, errors.New("arxiv: " + clean(a.Summary))
+		}
+		e := a.entry()
+		e.CiteKey = citekey.Unique(citekey.Generate(e), taken)
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+func (a atomEntry) entry() *parse.EntryDecl {
+	e := &parse.EntryDecl{Name: "misc", Comments: &parse.CommentGroupExpr{}}
+	set := func(key, value string) {
+		if value != `` {
+			e.Set(key, parse.Quote(value))
+		}
+	}
+	authors := []string{}
+	for _, n := range a.Authors {
+		authors = append(authors, tex.Escape(clean(n)))
+	}
+	set("author", strings.Join(authors, " and "))
+	set("title", texText(a.Title))
+	if len(a.Published) >= 7 {
+		e.Set("year", a.Published[:4])
+		var m int
+		if _, err := fmt.Sscanf(a.Published[5:7], "%d", &m); err == nil && m >= 1 && m <= 12 {
+			e.Set("month", monthMacros[m-1])
+		}
+	}
+	id := a.ID
+	if i := strings.Index(id, "/abs/"); i >= 0 {
+		id = id[i+len("/abs/"):]
+	}
+	id = stripVersion(id)
+	set("eprint", id)
+	set("archiveprefix", "arXiv")
+	set("primaryclass", a.Primary.Term)
+	set("doi", clean(a.DOI))
+	set("note", tex.Escape(clean(a.JournalRef)))
+	set("url", "https://arxiv.org/abs/"+id)
+	set("abstract", texText(a.Summary))
+	return e
+}
+
+func trimPrefix(id string) string {
+	id = strings.TrimSpace(id)
+	if len(id) > 6 && strings.EqualFold(id[:6], "arxiv:") {
+		return id[6:]
+	}
+	return id
+}
+
+func stripVersion(id string) string {
+	if i := strings.LastIndex(id, "v"); i > 0 && i < len(id)-1 {
+		for _, r := range id[i+1:] {
+			if r < '0' || r > '9' {
+				return id
+			}
+		}
+		return id[:i]
+	}
+	return id
+}
+
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// TexText keeps the TeX markup arXiv titles and abstracts are written in,
+// escaping the text only if its braces are unbalanced.
+func texText(s string) string {
+	s = clean(s)
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+		if depth < 0 {
+			break
+		}
+	}
+	if depth != 0 {
+		return tex.Escape(s)
+	}
+	return s
+}
+
+// Eprint returns the arXiv identifier of the entry without its prefix and
+// version if the entry has an eprint field holding one. The archiveprefix or
+// eprinttype field, if present, has to name arXiv.
+func Eprint(e *parse.EntryDecl) (string, bool) {
+	f, ok := e.Get("eprint")
+	if !ok {
+		return ``, false
+	}
+	for _, key := range []string{"archiveprefix", "eprinttype"} {
+		if p, ok := e.Get(key); ok && !strings.EqualFold(parse.Unquote(p.Value), "arxiv") {
+			return ``, false
+		}
+	}
+	id := parse.Unquote(f.Value)
+	if !IsID(id) {
+		return ``, false
+	}
+	return stripVersion(trimPrefix(id)), true
+}
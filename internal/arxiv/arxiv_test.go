@@ -0,0 +1,151 @@
+package arxiv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+var testFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title type="html">ArXiv Query: id_list=1706.03762</title>
+  <entry>
+    <id>http://arxiv.org/abs/1706.03762v7</id>
+    <updated>2023-08-02T00:41:18Z</updated>
+    <published>2017-06-12T17:57:34Z</published>
+    <title>Attention Is All You
+      Need</title>
+    <summary>  The dominant sequence transduction models are based on $O(n^2)$
+      attention.
+    </summary>
+    <author><name>Ashish Vaswani</name></author>
+    <author><name>Noam Shazeer</name></author>
+    <arxiv:doi xmlns:arxiv="http://arxiv.org/schemas/atom">10.48550/arXiv.1706.03762</arxiv:doi>
+    <arxiv:journal_ref xmlns:arxiv="http://arxiv.org/schemas/atom">NIPS 2017 &amp; more</arxiv:journal_ref>
+    <arxiv:primary_category xmlns:arxiv="http://arxiv.org/schemas/atom" term="cs.CL" scheme="http://arxiv.org/schemas/atom"/>
+    <category term="cs.CL" scheme="http://arxiv.org/schemas/atom"/>
+  </entry>
+</feed>
+`
+
+var testErrorFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <id>http://arxiv.org/api/errors#incorrect_id_format_for_1234</id>
+    <title>Error</title>
+    <summary>incorrect id format for 1234</summary>
+  </entry>
+</feed>
+`
+
+var wantEntry = &parse.EntryDecl{
+	Name:     "misc",
+	CiteKey:  "Vaswani2017",
+	Comments: &parse.CommentGroupExpr{},
+	Fields: []*parse.FieldStmt{
+		{Key: "author", Value: "{Ashish Vaswani and Noam Shazeer}"},
+		{Key: "title", Value: "{Attention Is All You Need}"},
+		{Key: "year", Value: "2017"},
+		{Key: "month", Value: "jun"},
+		{Key: "eprint", Value: "{1706.03762}"},
+		{Key: "archiveprefix", Value: "{arXiv}"},
+		{Key: "primaryclass", Value: "{cs.CL}"},
+		{Key: "doi", Value: "{10.48550/arXiv.1706.03762}"},
+		{Key: "note", Value: `{NIPS 2017 \& more}`},
+		{Key: "url", Value: "{https://arxiv.org/abs/1706.03762}"},
+		{Key: "abstract", Value: "{The dominant sequence transduction models are based on $O(n^2)$ attention.}"},
+	},
+}
+
+func TestRead(t *testing.T) {
+	have, err := Read(strings.NewReader(testFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(have) != 1 {
+		t.Fatalf("have %d entries; want 1", len(have))
+	}
+	if !have[0].Eq(wantEntry) {
+		t.Errorf("have %v; want %v", have[0].Fields, wantEntry.Fields)
+	}
+}
+
+func TestReadError(t *testing.T) {
+	if _, err := Read(strings.NewReader(testErrorFeed)); err == nil {
+		t.Error("have nil; want an error")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if have := r.URL.Query().Get("id_list"); have != "1706.03762" {
+			t.Errorf("have %s; want %s", have, "1706.03762")
+		}
+		fmt.Fprint(w, testFeed)
+	}))
+	defer srv.Close()
+
+	c := Client{HTTP: srv.Client(), Endpoint: srv.URL}
+	have, err := c.Fetch(context.Background(), "arXiv:1706.03762")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(have) != 1 || !have[0].Eq(wantEntry) {
+		t.Errorf("have %v; want %v", have, wantEntry)
+	}
+}
+
+func TestIsID(t *testing.T) {
+	cases := []struct {
+		name      string
+		testInput string
+		want      bool
+	}{
+		{"new", "1706.03762", true},
+		{"new-version", "1706.03762v7", true},
+		{"prefixed", "arXiv:2101.00001", true},
+		{"old", "hep-th/9901001", true},
+		{"old-subject", "math.GT/0309136", true},
+		{"doi", "10.1073/pnas.50.6.1143", false},
+		{"text", "Cohen1963", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := IsID(c.testInput); have != c.want {
+				t.Errorf("have %t; want %t", have, c.want)
+			}
+		})
+	}
+}
+
+func TestEprint(t *testing.T) {
+	cases := []struct {
+		name   string
+		fields []string
+		want   string
+		ok     bool
+	}{
+		{"bare", []string{"eprint", "{1706.03762}"}, "1706.03762", true},
+		{"prefixed", []string{"eprint", "{arXiv:1706.03762v7}", "archiveprefix", "{arXiv}"}, "1706.03762", true},
+		{"eprinttype", []string{"eprint", "{hep-th/9901001}", "eprinttype", "{arxiv}"}, "hep-th/9901001", true},
+		{"other archive", []string{"eprint", "{1706.03762}", "archiveprefix", "{SSRN}"}, ``, false},
+		{"missing", []string{"doi", "{10.1073/pnas.50.6.1143}"}, ``, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := &parse.EntryDecl{Name: "misc", CiteKey: "key", Comments: &parse.CommentGroupExpr{}}
+			for i := 0; i+1 < len(c.fields); i += 2 {
+				e.Fields = append(e.Fields, &parse.FieldStmt{Key: c.fields[i], Value: c.fields[i+1]})
+			}
+			have, ok := Eprint(e)
+			if have != c.want || ok != c.ok {
+				t.Errorf("have %q, %t; want %q, %t", have, ok, c.want, c.ok)
+			}
+		})
+	}
+}
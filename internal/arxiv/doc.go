@@ -0,0 +1,5 @@
+/*
+Arxiv package converts responses of the arXiv API into BibTeX entries and
+fetches them by arXiv identifiers.
+*/
+package arxiv
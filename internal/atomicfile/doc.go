@@ -0,0 +1,6 @@
+/*
+Atomicfile package replaces the contents of files so that a failure midway
+leaves them as they were, and readers see either the old contents or the new
+ones but never a mix of both.
+*/
+package atomicfile
@@ -0,0 +1,191 @@
+package bibdesk
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// Plist encodes the value as a binary property list. Maps are encoded with
+// their keys sorted so that the output is stable.
+func plist(v any) []byte {
+	objects := [][]byte{}
+	var add func(v any) int
+	header := func(kind byte, n int) []byte {
+		if n < 15 {
+			return []byte{kind<<4 | byte(n)}
+		}
+		return []byte{kind<<4 | 0x0f, 0x11, byte(n >> 8), byte(n)}
+	}
+	add = func(v any) int {
+		i := len(objects)
+		objects = append(objects, nil)
+		switch v := v.(type) {
+		case string:
+			objects[i] = append(header(0x5, len(v)), v...)
+		case uid:
+			objects[i] = []byte{0x80, byte(v)}
+		case []any:
+			refs := []byte{}
+			for _, item := range v {
+				refs = append(refs, byte(add(item)))
+			}
+			objects[i] = append(header(0xa, len(v)), refs...)
+		case map[string]any:
+			keys := []string{}
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			krefs, vrefs := []byte{}, []byte{}
+			for _, k := range keys {
+				krefs = append(krefs, byte(add(k)))
+				vrefs = append(vrefs, byte(add(v[k])))
+			}
+			objects[i] = append(append(header(0xd, len(v)), krefs...), vrefs...)
+		}
+		return i
+	}
+	add(v)
+	out := []byte("bplist00")
+	offsets := []int{}
+	for _, o := range objects {
+		offsets = append(offsets, len(out))
+		out = append(out, o...)
+	}
+	table := len(out)
+	for _, off := range offsets {
+		out = append(out, byte(off>>8), byte(off))
+	}
+	trailer := make([]byte, 32)
+	trailer[6], trailer[7] = 2, 1
+	binary.BigEndian.PutUint64(trailer[8:], uint64(len(objects)))
+	binary.BigEndian.PutUint64(trailer[24:], uint64(table))
+	return append(out, trailer...)
+}
+
+func field(v any) string {
+	return "{" + base64.StdEncoding.EncodeToString(plist(v)) + "}"
+}
+
+func TestLinkedFiles(t *testing.T) {
+	long := strings.Repeat("papers/", 4) + "Cohen 1963.pdf"
+	archived := map[string]any{
+		"$archiver": "NSKeyedArchiver",
+		"$top":      map[string]any{"root": uid(1)},
+		"$objects": []any{
+			"$null",
+			map[string]any{"relativePath": uid(2), "aliasData": uid(0)},
+			"Gödel.pdf",
+		},
+	}
+	e := &parse.EntryDecl{Name: "article", CiteKey: "k", Fields: []*parse.FieldStmt{
+		{Key: "Bdsk-File-2", Value: field(archived)},
+		{Key: "title", Value: "{T}"},
+		{Key: "Bdsk-Url-1", Value: "{https://example.org}"},
+		{Key: "Bdsk-File-1", Value: field(map[string]any{"relativePath": long, "bookmark": "x"})},
+		{Key: "bdsk-file-10", Value: field(map[string]any{"relativePath": "z.pdf"})},
+	}}
+	have, err := LinkedFiles(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []LinkedFile{{"Bdsk-File-1", long}, {"Bdsk-File-2", "Gödel.pdf"}, {"bdsk-file-10", "z.pdf"}}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v; want %v", have, want)
+	}
+}
+
+func TestDecodeFileErr(t *testing.T) {
+	cases := []struct {
+		name  string
+		value string
+		want  error
+	}{
+		{"not-base64", "%%%", ErrPlist},
+		{"not-plist", base64.StdEncoding.EncodeToString([]byte("plain text, not a property list")), ErrPlist},
+		{"truncated", base64.StdEncoding.EncodeToString(plist(map[string]any{"relativePath": "a.pdf"})[:20]), ErrPlist},
+		{"no-path", strings.Trim(field(map[string]any{"bookmark": "x"}), "{}"), ErrNoPath},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := DecodeFile(c.value); !errors.Is(err, c.want) {
+				t.Errorf("have %v; want %v", err, c.want)
+			}
+		})
+	}
+}
+
+const staticGroups = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<array>
+	<dict>
+		<key>group name</key>
+		<string>Favorites</string>
+		<key>keys</key>
+		<string>Cohen1963,Godel1931</string>
+	</dict>
+</array>
+</plist>`
+
+const smartGroups = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<array>
+	<dict>
+		<key>conditions</key>
+		<array>
+			<dict>
+				<key>comparison</key>
+				<integer>2</integer>
+				<key>key</key>
+				<string>Year</string>
+				<key>value</key>
+				<string>1963</string>
+			</dict>
+		</array>
+		<key>conjunction</key>
+		<integer>1</integer>
+		<key>group name</key>
+		<string>Sixties {new}</string>
+	</dict>
+</array>
+</plist>`
+
+func TestReadGroups(t *testing.T) {
+	src := "@article{Cohen1963, title = {T}}\n\n@comment{BibDesk Static Groups{\n" + staticGroups + "\n}}\n" +
+		"@comment{BibDesk Smart Groups{\n" + base64.StdEncoding.EncodeToString([]byte(smartGroups)) + "\n}}\n"
+	groups, err := ReadGroups([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("have %d groups; want 2", len(groups))
+	}
+	fav, sixties := groups[0], groups[1]
+	if fav.Name != "Favorites" || fav.Kind != Static || !reflect.DeepEqual(fav.Keys, []string{"Cohen1963", "Godel1931"}) {
+		t.Errorf("have %+v; want the Favorites static group", fav)
+	}
+	if !fav.Contains(&parse.EntryDecl{CiteKey: "Godel1931"}) || fav.Contains(&parse.EntryDecl{CiteKey: "godel1931"}) {
+		t.Error("have static group membership other than by cite key")
+	}
+	want := []Condition{{Field: "Year", Value: "1963", Comparison: 2}}
+	if sixties.Name != "Sixties {new}" || sixties.Kind != Smart || !sixties.Any || !reflect.DeepEqual(sixties.Conditions, want) {
+		t.Errorf("have %+v; want the Sixties smart group", sixties)
+	}
+	// The group blocks are parsed as comments and written back verbatim.
+	out, err := format.Source([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, err := ReadGroups(out); err != nil || !reflect.DeepEqual(have, groups) {
+		t.Errorf("have %v, %v after formatting; want %v", have, err, groups)
+	}
+}
@@ -0,0 +1,11 @@
+/*
+Bibdesk package reads what BibDesk keeps in BibTeX files besides the entries.
+Files linked to an entry are stored in its Bdsk-File-1, Bdsk-File-2 and so on
+fields as Base64-encoded binary property lists holding the path of the file
+relative to the bibliography, and groups are stored in @comment declarations
+such as @comment{BibDesk Static Groups{...}} holding XML property lists.
+
+The parser keeps @comment declarations as they are, and the field names
+Bdsk-File-N keep their case when rewritten, so that BibDesk still finds both.
+*/
+package bibdesk
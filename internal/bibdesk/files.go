@@ -0,0 +1,91 @@
+package bibdesk
+
+import (
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// ErrNoPath is returned for linked files without a relative path, such as
+// the ones BibDesk resolves through bookmark data alone.
+var ErrNoPath = errors.New("bibdesk: linked file without a relative path")
+
+// IsField reports whether the field is one of the fields BibDesk reserves
+// for itself, such as Bdsk-File-1 or Bdsk-Url-1.
+func IsField(key string) bool {
+	return len(key) > 5 && strings.EqualFold(key[:5], "bdsk-")
+}
+
+// LinkedFile is a file linked to an entry through a Bdsk-File-N field.
+type LinkedFile struct {
+	Field string // name of the field, e.g. Bdsk-File-1
+	Path  string // path relative to the bibliography
+}
+
+// LinkedFiles returns the files linked to the entry in the order of their
+// numbers. It stops at the first field that cannot be decoded.
+func LinkedFiles(e *parse.EntryDecl) ([]LinkedFile, error) {
+	type numbered struct {
+		n int
+		f *parse.FieldStmt
+	}
+	fields := []numbered{}
+	for _, f := range e.Fields {
+		if len(f.Key) <= 10 || !strings.EqualFold(f.Key[:10], "bdsk-file-") {
+			continue
+		}
+		if n, err := strconv.Atoi(f.Key[10:]); err == nil {
+			fields = append(fields, numbered{n, f})
+		}
+	}
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].n < fields[j].n })
+	result := []LinkedFile{}
+	for _, nf := range fields {
+		path, err := DecodeFile(parse.Unquote(nf.f.Value))
+		if err != nil {
+			return result, err
+		}
+		result = append(result, LinkedFile{Field: nf.f.Key, Path: path})
+	}
+	return result, nil
+}
+
+// DecodeFile returns the relative path of the file held by the value of a
+// Bdsk-File-N field. Both the plain dictionaries of recent versions of
+// BibDesk and the NSKeyedArchiver archives of older ones are understood.
+func DecodeFile(value string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ``))
+	if err != nil {
+		return ``, ErrPlist
+	}
+	v, err := decodeBinary(data)
+	if err != nil {
+		return ``, err
+	}
+	root, ok := v.(map[string]any)
+	if !ok {
+		return ``, ErrPlist
+	}
+	if objects, ok := root["$objects"].([]any); ok {
+		// Archived objects refer to each other by their indexes.
+		resolve := func(v any) any {
+			if id, ok := v.(uid); ok && uint64(id) < uint64(len(objects)) {
+				return objects[id]
+			}
+			return v
+		}
+		top, _ := root["$top"].(map[string]any)
+		if root, ok = resolve(top["root"]).(map[string]any); !ok {
+			return ``, ErrPlist
+		}
+		root = map[string]any{"relativePath": resolve(root["relativePath"])}
+	}
+	if path, ok := root["relativePath"].(string); ok && path != `` {
+		return path, nil
+	}
+	return ``, ErrNoPath
+}
@@ -0,0 +1,150 @@
+package bibdesk
+
+import (
+	"bytes"
+	"encoding/base64"
+	"regexp"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// Kind is the kind of a BibDesk group.
+type Kind uint8
+
+const (
+	// Static groups list the cite keys of their entries.
+	Static Kind = iota
+	// Smart groups hold the entries meeting their conditions.
+	Smart
+	// URL groups hold the entries of a bibliography on the web.
+	URL
+	// Script groups hold the entries printed by a script.
+	Script
+)
+
+var kindNames = map[string]Kind{
+	"static": Static,
+	"smart":  Smart,
+	"url":    URL,
+	"script": Script,
+}
+
+// Group is a BibDesk group. Keys lists the entries of static groups, and
+// Conditions the conditions of smart groups, which hold the entries meeting
+// all of them or, with Any set, any of them. Info holds the properties of
+// the group as they are stored.
+type Group struct {
+	Name       string
+	Kind       Kind
+	Keys       []string
+	Conditions []Condition
+	Any        bool
+	Info       map[string]any
+}
+
+// Condition is a condition of a smart group comparing the value of the
+// field with Value. Comparison holds the code of the comparison as BibDesk
+// stores it.
+type Condition struct {
+	Field      string
+	Value      string
+	Comparison int64
+}
+
+// Contains reports whether a static group lists the entry.
+func (g *Group) Contains(e *parse.EntryDecl) bool {
+	for _, k := range g.Keys {
+		if k == e.CiteKey {
+			return true
+		}
+	}
+	return false
+}
+
+var groupsStart = regexp.MustCompile(`(?i)@comment\s*\{\s*BibDesk\s+(Static|Smart|URL|Script)\s+Groups\s*\{`)
+
+// ReadGroups returns the groups stored in the @comment declarations of the
+// source in the order of the source. The property lists may be written as
+// XML or encoded with Base64.
+func ReadGroups(src []byte) ([]*Group, error) {
+	result := []*Group{}
+	for _, loc := range groupsStart.FindAllSubmatchIndex(src, -1) {
+		kind := kindNames[strings.ToLower(string(src[loc[2]:loc[3]]))]
+		body := src[loc[1]:]
+		depth := 1
+		for i, c := range body {
+			if c == '{' {
+				depth++
+			} else if c == '}' {
+				if depth--; depth == 0 {
+					body = body[:i]
+					break
+				}
+			}
+		}
+		groups, err := parseGroups(kind, bytes.TrimSpace(body))
+		if err != nil {
+			return result, err
+		}
+		result = append(result, groups...)
+	}
+	return result, nil
+}
+
+func parseGroups(kind Kind, body []byte) ([]*Group, error) {
+	if !bytes.HasPrefix(body, []byte("<")) {
+		data, err := base64.StdEncoding.DecodeString(string(bytes.Join(bytes.Fields(body), nil)))
+		if err != nil {
+			return nil, ErrPlist
+		}
+		body = data
+	}
+	var v any
+	var err error
+	if bytes.HasPrefix(body, []byte("bplist00")) {
+		v, err = decodeBinary(body)
+	} else {
+		v, err = decodeXML(body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	list, ok := v.([]any)
+	if !ok {
+		return nil, ErrPlist
+	}
+	result := []*Group{}
+	for _, item := range list {
+		info, ok := item.(map[string]any)
+		if !ok {
+			return nil, ErrPlist
+		}
+		g := &Group{Kind: kind, Info: info}
+		g.Name, _ = info["group name"].(string)
+		if keys, ok := info["keys"].(string); ok {
+			for _, k := range strings.Split(keys, ",") {
+				if k = strings.TrimSpace(k); k != `` {
+					g.Keys = append(g.Keys, k)
+				}
+			}
+		}
+		if conj, ok := info["conjunction"].(int64); ok {
+			g.Any = conj == 1
+		}
+		conds, _ := info["conditions"].([]any)
+		for _, c := range conds {
+			m, ok := c.(map[string]any)
+			if !ok {
+				continue
+			}
+			cond := Condition{}
+			cond.Field, _ = m["key"].(string)
+			cond.Value, _ = m["value"].(string)
+			cond.Comparison, _ = m["comparison"].(int64)
+			g.Conditions = append(g.Conditions, cond)
+		}
+		result = append(result, g)
+	}
+	return result, nil
+}
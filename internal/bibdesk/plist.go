@@ -0,0 +1,250 @@
+package bibdesk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrPlist is returned for property lists that cannot be decoded.
+var ErrPlist = errors.New("bibdesk: invalid property list")
+
+// Uid references an object archived by NSKeyedArchiver.
+type uid uint64
+
+// MaxPlistDepth limits the nesting of the decoded collections.
+const maxPlistDepth = 32
+
+// DecodeBinary decodes a binary property list into nil, bool, int64,
+// float64, string, []byte, uid, []any and map[string]any values.
+func decodeBinary(data []byte) (any, error) {
+	if len(data) < 8+32 || !bytes.HasPrefix(data, []byte("bplist00")) {
+		return nil, ErrPlist
+	}
+	trailer := data[len(data)-32:]
+	d := &binaryDecoder{
+		data:     data,
+		offSize:  int(trailer[6]),
+		refSize:  int(trailer[7]),
+		count:    binary.BigEndian.Uint64(trailer[8:16]),
+		tableOff: binary.BigEndian.Uint64(trailer[24:32]),
+	}
+	if d.offSize < 1 || d.offSize > 8 || d.refSize < 1 || d.refSize > 8 ||
+		d.tableOff >= uint64(len(data)) || d.count > uint64(len(data)) {
+		return nil, ErrPlist
+	}
+	return d.object(binary.BigEndian.Uint64(trailer[16:24]), 0)
+}
+
+type binaryDecoder struct {
+	data             []byte
+	offSize, refSize int
+	count, tableOff  uint64
+}
+
+// Uint reads the big-endian unsigned integer of n bytes at off.
+func (d *binaryDecoder) uint(off uint64, n int) (uint64, error) {
+	if off+uint64(n) > uint64(len(d.data)) {
+		return 0, ErrPlist
+	}
+	var v uint64
+	for _, b := range d.data[off : off+uint64(n)] {
+		v = v<<8 | uint64(b)
+	}
+	return v, nil
+}
+
+func (d *binaryDecoder) object(ref uint64, depth int) (any, error) {
+	if ref >= d.count || depth > maxPlistDepth {
+		return nil, ErrPlist
+	}
+	off, err := d.uint(d.tableOff+ref*uint64(d.offSize), d.offSize)
+	if err != nil || off >= uint64(len(d.data)) {
+		return nil, ErrPlist
+	}
+	marker := d.data[off]
+	kind, low := marker>>4, int(marker&0x0f)
+	off++
+	switch kind {
+	case 0x0:
+		switch marker {
+		case 0x08:
+			return false, nil
+		case 0x09:
+			return true, nil
+		}
+		return nil, nil
+	case 0x1:
+		v, err := d.uint(off, 1<<low)
+		return int64(v), err
+	case 0x8:
+		v, err := d.uint(off, low+1)
+		return uid(v), err
+	}
+	n := uint64(low)
+	if low == 0x0f {
+		if off >= uint64(len(d.data)) || d.data[off]>>4 != 0x1 {
+			return nil, ErrPlist
+		}
+		size := 1 << (d.data[off] & 0x0f)
+		if n, err = d.uint(off+1, size); err != nil {
+			return nil, err
+		}
+		off += 1 + uint64(size)
+	}
+	switch kind {
+	case 0x4, 0x5:
+		if off+n > uint64(len(d.data)) {
+			return nil, ErrPlist
+		}
+		if kind == 0x4 {
+			return append([]byte(nil), d.data[off:off+n]...), nil
+		}
+		return string(d.data[off : off+n]), nil
+	case 0x6:
+		if off+2*n > uint64(len(d.data)) {
+			return nil, ErrPlist
+		}
+		units := make([]uint16, n)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(d.data[off+2*uint64(i):])
+		}
+		return string(utf16.Decode(units)), nil
+	case 0xa:
+		result := make([]any, 0, n)
+		for i := uint64(0); i < n; i++ {
+			v, err := d.ref(off, i, depth)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, v)
+		}
+		return result, nil
+	case 0xd:
+		result := make(map[string]any, n)
+		for i := uint64(0); i < n; i++ {
+			k, err := d.ref(off, i, depth)
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, ErrPlist
+			}
+			if result[key], err = d.ref(off, n+i, depth); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported object type %#x", ErrPlist, marker)
+}
+
+// Ref decodes the object referenced by the i-th reference of a collection
+// whose references start at off.
+func (d *binaryDecoder) ref(off, i uint64, depth int) (any, error) {
+	r, err := d.uint(off+i*uint64(d.refSize), d.refSize)
+	if err != nil {
+		return nil, err
+	}
+	return d.object(r, depth+1)
+}
+
+// DecodeXML decodes an XML property list into the values of decodeBinary,
+// with integers decoded as int64 and real numbers as float64.
+func decodeXML(data []byte) (any, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, ErrPlist
+		}
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local == "plist" {
+			v, _, err := xmlValue(dec, 0)
+			return v, err
+		}
+	}
+}
+
+// XmlValue decodes the next value of the decoder, reporting whether the end
+// of the enclosing element was reached instead.
+func xmlValue(dec *xml.Decoder, depth int) (any, bool, error) {
+	if depth > maxPlistDepth {
+		return nil, false, ErrPlist
+	}
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, false, ErrPlist
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			return nil, true, nil
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "dict":
+				result := map[string]any{}
+				for {
+					k, end, err := xmlValue(dec, depth+1)
+					if err != nil {
+						return nil, false, err
+					}
+					if end {
+						return result, false, nil
+					}
+					key, ok := k.(string)
+					if !ok {
+						return nil, false, ErrPlist
+					}
+					v, end, err := xmlValue(dec, depth+1)
+					if err != nil || end {
+						return nil, false, ErrPlist
+					}
+					result[key] = v
+				}
+			case "array":
+				result := []any{}
+				for {
+					v, end, err := xmlValue(dec, depth+1)
+					if err != nil {
+						return nil, false, err
+					}
+					if end {
+						return result, false, nil
+					}
+					result = append(result, v)
+				}
+			case "true", "false":
+				if err := dec.Skip(); err != nil {
+					return nil, false, ErrPlist
+				}
+				return t.Name.Local == "true", false, nil
+			}
+			var text string
+			if err := dec.DecodeElement(&text, &t); err != nil {
+				return nil, false, ErrPlist
+			}
+			switch t.Name.Local {
+			case "integer":
+				n, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+				if err != nil {
+					return nil, false, ErrPlist
+				}
+				return n, false, nil
+			case "real":
+				f, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+				if err != nil {
+					return nil, false, ErrPlist
+				}
+				return f, false, nil
+			}
+			// Strings, keys, dates and data are kept as text.
+			return text, false, nil
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package bibtexml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/scan"
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+// Namespace is the namespace of the BibTeXML schema.
+const Namespace = "http://bibtexml.sf.net/"
+
+const prefix = "bibtex:"
+
+// Containers of the structured BibTeXML variant listing one item per child
+// element, together with the field they map to and the item separator.
+var containers = map[string]struct{ field, sep string }{
+	"authors":  {"author", " and "},
+	"editors":  {"editor", " and "},
+	"keywords": {"keywords", ", "},
+}
+
+// Write exports the entries as a BibTeXML file in its flat variant with TeX
+// markup of field values decoded into plain text.
+func Write(w io.Writer, entries []*parse.EntryDecl) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	file := start("file")
+	file.Attr = []xml.Attr{{Name: xml.Name{Local: "xmlns:bibtex"}, Value: Namespace}}
+	if err := enc.EncodeToken(file); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		entry := start("entry")
+		entry.Attr = []xml.Attr{{Name: xml.Name{Local: "id"}, Value: e.CiteKey}}
+		typ := start(e.Name)
+		if err := encodeTokens(enc, entry, typ); err != nil {
+			return err
+		}
+		for _, f := range e.Fields {
+			el := start(strings.ToLower(f.Key))
+			text := xml.CharData(tex.Decode(parse.Unquote(f.Value)))
+			if err := encodeTokens(enc, el, text, el.End()); err != nil {
+				return err
+			}
+		}
+		if err := encodeTokens(enc, typ.End(), entry.End()); err != nil {
+			return err
+		}
+	}
+	if err := encodeTokens(enc, file.End()); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func start(name string) xml.StartElement {
+	return xml.StartElement{Name: xml.Name{Local: prefix + name}}
+}
+
+func encodeTokens(enc *xml.Encoder, tokens ...xml.Token) error {
+	for _, t := range tokens {
+		if err := enc.EncodeToken(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read imports the entries of a BibTeXML file. Both the flat variant and the
+// structured one with authors, editors and keywords containers are accepted.
+// Special characters of text values are escaped for TeX.
+func Read(r io.Reader) ([]*parse.EntryDecl, error) {
+	d := xml.NewDecoder(r)
+	result := []*parse.EntryDecl{}
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		el, ok := t.(xml.StartElement)
+		if !ok || el.Name.Space != Namespace || el.Name.Local != "entry" {
+			continue
+		}
+		e, err := readEntry(d, el)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+}
+
+func readEntry(d *xml.Decoder, el xml.StartElement) (*parse.EntryDecl, error) {
+	e := &parse.EntryDecl{Comments: &parse.CommentGroupExpr{}}
+	for _, a := range el.Attr {
+		if a.Name.Local == "id" {
+			e.CiteKey = a.Value
+		}
+	}
+	if !scan.IsValidName(e.CiteKey) {
+		return nil, fmt.Errorf("bibtexml: invalid entry id %q", e.CiteKey)
+	}
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			if e.Name != `` {
+				return nil, fmt.Errorf("bibtexml: entry %q has more than one type", e.CiteKey)
+			}
+			e.Name = strings.ToLower(t.Name.Local)
+			if err := readFields(d, e); err != nil {
+				return nil, err
+			}
+		case xml.EndElement:
+			if e.Name == `` {
+				return nil, fmt.Errorf("bibtexml: entry %q has no type", e.CiteKey)
+			}
+			return e, nil
+		}
+	}
+}
+
+func readFields(d *xml.Decoder, e *parse.EntryDecl) error {
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			key := strings.ToLower(t.Name.Local)
+			value, err := readValue(d, key)
+			if err != nil {
+				return err
+			}
+			if c, ok := containers[key]; ok {
+				key = c.field
+			}
+			if !scan.IsValidName(key) {
+				return fmt.Errorf("bibtexml: invalid field name %q", key)
+			}
+			if value == `` {
+				continue
+			}
+			if strings.Trim(value, "0123456789") == `` {
+				e.Set(key, value)
+			} else {
+				e.Set(key, parse.Quote(tex.Escape(value)))
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// ReadValue collects the text of the field element joining the items of the
+// container elements.
+func readValue(d *xml.Decoder, key string) (string, error) {
+	var b strings.Builder
+	items := []string{}
+	depth := 0
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return ``, err
+		}
+		switch t := t.(type) {
+		case xml.CharData:
+			b.Write(t)
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				b.Reset()
+			}
+		case xml.EndElement:
+			if depth == 0 {
+				if c, ok := containers[key]; ok && len(items) > 0 {
+					return strings.Join(items, c.sep), nil
+				}
+				if len(items) > 0 {
+					return ``, errors.New("bibtexml: unexpected elements in field " + key)
+				}
+				return clean(b.String()), nil
+			}
+			depth--
+			if depth == 0 {
+				items = append(items, clean(b.String()))
+				b.Reset()
+			}
+		}
+	}
+}
+
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
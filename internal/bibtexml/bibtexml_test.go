@@ -0,0 +1,125 @@
+package bibtexml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func testEntries() []*parse.EntryDecl {
+	return []*parse.EntryDecl{
+		{
+			Name:     "article",
+			CiteKey:  "Cohen1963",
+			Comments: &parse.CommentGroupExpr{},
+			Fields: []*parse.FieldStmt{
+				{Key: "author", Value: `{Paul Joseph C{\"o}hen}`},
+				{Key: "title", Value: "{Tom \\& Jerry}"},
+				{Key: "year", Value: "1963"},
+			},
+		},
+	}
+}
+
+const testXML = `<?xml version="1.0" encoding="UTF-8"?>
+<bibtex:file xmlns:bibtex="http://bibtexml.sf.net/">
+  <bibtex:entry id="Cohen1963">
+    <bibtex:article>
+      <bibtex:author>Paul Joseph Cöhen</bibtex:author>
+      <bibtex:title>Tom &amp; Jerry</bibtex:title>
+      <bibtex:year>1963</bibtex:year>
+    </bibtex:article>
+  </bibtex:entry>
+</bibtex:file>
+`
+
+func TestWrite(t *testing.T) {
+	var b bytes.Buffer
+	if err := Write(&b, testEntries()); err != nil {
+		t.Fatal(err)
+	}
+	if have := b.String(); have != testXML {
+		t.Errorf("have %s; want %s", have, testXML)
+	}
+}
+
+func TestRead(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  []*parse.EntryDecl
+	}{
+		{
+			name:  "flat",
+			input: testXML,
+			want: []*parse.EntryDecl{
+				{
+					Name:     "article",
+					CiteKey:  "Cohen1963",
+					Comments: &parse.CommentGroupExpr{},
+					Fields: []*parse.FieldStmt{
+						{Key: "author", Value: "{Paul Joseph Cöhen}"},
+						{Key: "title", Value: "{Tom \\& Jerry}"},
+						{Key: "year", Value: "1963"},
+					},
+				},
+			},
+		},
+		{
+			name: "structured",
+			input: `<file xmlns="http://bibtexml.sf.net/">
+<entry id="Doe2001"><book>
+  <authors><person>Doe, John</person><person>Roe, Jane</person></authors>
+  <keywords><keyword>sets</keyword><keyword>logic</keyword></keywords>
+</book></entry>
+</file>`,
+			want: []*parse.EntryDecl{
+				{
+					Name:     "book",
+					CiteKey:  "Doe2001",
+					Comments: &parse.CommentGroupExpr{},
+					Fields: []*parse.FieldStmt{
+						{Key: "author", Value: "{Doe, John and Roe, Jane}"},
+						{Key: "keywords", Value: "{sets, logic}"},
+					},
+				},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			have, err := Read(strings.NewReader(c.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(have) != len(c.want) {
+				t.Fatalf("have %d entries; want %d", len(have), len(c.want))
+			}
+			for i := range have {
+				if !have[i].Eq(c.want[i]) {
+					t.Errorf("have %v; want %v", have[i], c.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadErr(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+	}{
+		{"missing id", `<file xmlns="http://bibtexml.sf.net/"><entry><book/></entry></file>`},
+		{"missing type", `<file xmlns="http://bibtexml.sf.net/"><entry id="a"></entry></file>`},
+		{"malformed", `<file xmlns="http://bibtexml.sf.net/"><entry id="a"><book>`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := Read(strings.NewReader(c.input)); err == nil {
+				t.Error("have nil; want error")
+			}
+		})
+	}
+}
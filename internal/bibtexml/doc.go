@@ -0,0 +1,5 @@
+/*
+Bibtexml package converts BibTeX entries to and from the BibTeXML schema for
+use in XML-based publishing toolchains.
+*/
+package bibtexml
@@ -0,0 +1,126 @@
+package bindings
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/formats"
+	"github.com/mdm-code/bibx/internal/jsonl"
+	"github.com/mdm-code/bibx/internal/lint"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/report"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+// Entry is an entry encoded by Parse with the position of its declaration.
+type Entry struct {
+	jsonl.Object
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Parse returns the entries of the source as a JSON array of entries.
+func Parse(src string) (string, error) {
+	nodes, err := parseNodes(src)
+	if err != nil {
+		return ``, err
+	}
+	result := []Entry{}
+	for _, e := range entries(nodes) {
+		result = append(result, Entry{jsonl.NewObject(e), e.Pos.Line, e.Pos.Column})
+	}
+	return encode(result)
+}
+
+// Convert translates the entries of the source between the formats of the
+// convert command, except for the tabular ones and plugins.
+func Convert(src, from, to string) (string, error) {
+	read, ok := formats.Readers[from]
+	if !ok {
+		return ``, fmt.Errorf("bindings: unknown input format %q", from)
+	}
+	write, ok := formats.Writers[to]
+	if !ok {
+		return ``, fmt.Errorf("bindings: unknown output format %q", to)
+	}
+	es, err := read(strings.NewReader(src))
+	if err != nil {
+		return ``, err
+	}
+	var b strings.Builder
+	if err := write(&b, es); err != nil {
+		return ``, err
+	}
+	return b.String(), nil
+}
+
+// Format returns the source in the canonical layout.
+func Format(src string) (string, error) {
+	out, err := format.Source([]byte(src))
+	if err != nil {
+		return ``, err
+	}
+	return string(out), nil
+}
+
+// Lint checks the source with the default lint rules and returns the
+// findings as a JSON array in the format of the lint -json report.
+func Lint(src string) (string, error) {
+	nodes, err := parseNodes(src)
+	if err != nil {
+		return ``, err
+	}
+	result := []report.Finding{}
+	for _, f := range lint.Run(nodes, lint.DefaultRules()...) {
+		result = append(result, f.Report())
+	}
+	return encode(result)
+}
+
+// Fix applies the automatic fixes of the default lint rules to the source
+// and returns it formatted.
+func Fix(src string) (string, error) {
+	nodes, err := parseNodes(src)
+	if err != nil {
+		return ``, err
+	}
+	var b bytes.Buffer
+	findings := lint.Run(nodes, lint.DefaultRules()...)
+	if err := format.Nodes(&b, lint.Fix(nodes, findings)); err != nil {
+		return ``, err
+	}
+	return b.String(), nil
+}
+
+func parseNodes(src string) ([]parse.Node, error) {
+	p := parse.NewParser(scan.NewScanner(scan.NewReader(strings.NewReader(src))))
+	result := []parse.Node{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		result = append(result, n)
+	}
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func entries(nodes []parse.Node) []*parse.EntryDecl {
+	result := []*parse.EntryDecl{}
+	for _, n := range nodes {
+		if e, ok := n.(*parse.EntryDecl); ok {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func encode(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ``, fmt.Errorf("bindings: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,36 @@
+package bindings
+
+import "testing"
+
+var testBib = `@Article{a,title={X}, year = 2000}`
+
+func TestBindings(t *testing.T) {
+	cases := []struct {
+		name string
+		f    func(string) (string, error)
+		src  string
+		want string
+		err  bool
+	}{
+		{"parse", Parse, testBib, `[{"type":"article","key":"a","fields":{"title":"X","year":"2000"},"line":1,"column":1}]`, false},
+		{"format", Format, testBib, "@article{a,\n  title = {X},\n  year  = 2000\n}\n", false},
+		{"lint", Lint, testBib, `[]`, false},
+		{"lint-finding", Lint, `@misc{a, title = {X}, year = {n.d.}}`, `[{"severity":"warning","rule":"year","key":"a","field":"year","message":"year \"n.d.\" is not a 4-digit number","code":"BIBX0105","line":1,"column":23}]`, false},
+		{"fix", Fix, testBib, "@article{a,\n  title = {X},\n  year  = 2000\n}\n", false},
+		{"convert", func(src string) (string, error) { return Convert(src, "bibtex", "jsonl") }, testBib, `{"type":"article","key":"a","fields":{"title":"X","year":"2000"}}` + "\n", false},
+		{"convert-format", func(src string) (string, error) { return Convert(src, "bibtex", "docx") }, testBib, ``, true},
+		{"parse-error", Parse, `@article{`, ``, true},
+		{"format-error", Format, `@article{`, ``, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			have, err := c.f(c.src)
+			if (err != nil) != c.err {
+				t.Fatalf("have error %v; want error %t", err, c.err)
+			}
+			if have != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
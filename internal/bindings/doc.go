@@ -0,0 +1,7 @@
+/*
+Bindings package exposes parsing, linting, formatting and conversion of
+BibTeX sources held in strings, with the results encoded as JSON, for
+embedding bibx in environments that cannot pass Go values, such as
+JavaScript in a browser or programs linking against a C library.
+*/
+package bindings
@@ -0,0 +1,169 @@
+package citations
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Set is the set of cite keys required by a document, kept in the order of
+// their first citation. All is set by \nocite{*}.
+type Set struct {
+	Keys  []string
+	All   bool
+	index map[string]bool
+}
+
+// NewSet returns an empty set.
+func NewSet() *Set {
+	return &Set{Keys: []string{}, index: make(map[string]bool)}
+}
+
+// Add adds the cite keys to the set. The key * sets All.
+func (s *Set) Add(keys ...string) {
+	for _, k := range keys {
+		k = strings.TrimSpace(k)
+		switch {
+		case k == `*`:
+			s.All = true
+		case k != `` && !s.index[k]:
+			s.index[k] = true
+			s.Keys = append(s.Keys, k)
+		}
+	}
+}
+
+// Contains reports whether the entry under the cite key is required.
+func (s *Set) Contains(key string) bool { return s.All || s.index[key] }
+
+var (
+	// Citation matches the commands of .aux files listing cite keys and
+	// including other .aux files.
+	citation = regexp.MustCompile(`\\(citation|@input)\{([^}]*)\}`)
+
+	// Cite matches citation commands with their starred form and the
+	// optional arguments preceding the first list of keys.
+	cite = regexp.MustCompile(`\\([a-zA-Z]*(?:cite|Cite)[a-zA-Z]*)\*?((?:\s*\[[^\]]*\])*)\s*\{([^}]*)\}`)
+
+	// Next matches another list of keys of a multicite command such as
+	// \cites[see][1]{a}[2]{b}.
+	next = regexp.MustCompile(`^((?:\s*\[[^\]]*\])*)\s*\{([^}]*)\}`)
+)
+
+// NotCiting are commands with cite in their name that take no cite keys.
+var notCiting = map[string]bool{
+	"citestyle": true,
+	"citesetup": true,
+}
+
+// ReadAux adds the keys of the \citation commands of the .aux file to the
+// set and returns the files included with \@input, for LaTeX writes the
+// citations of \include'd sources to their own .aux files.
+func (s *Set) ReadAux(r io.Reader) ([]string, error) {
+	inputs := []string{}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<24)
+	for sc.Scan() {
+		for _, m := range citation.FindAllStringSubmatch(sc.Text(), -1) {
+			if m[1] == "@input" {
+				inputs = append(inputs, m[2])
+				continue
+			}
+			s.Add(strings.Split(m[2], ",")...)
+		}
+	}
+	return inputs, sc.Err()
+}
+
+// ReadTeX adds the keys of the citation commands of the LaTeX source to the
+// set, skipping comments.
+func (s *Set) ReadTeX(r io.Reader) error {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	text := stripComments(string(src))
+	for _, loc := range cite.FindAllStringSubmatchIndex(text, -1) {
+		name := text[loc[2]:loc[3]]
+		if notCiting[name] {
+			continue
+		}
+		s.Add(strings.Split(text[loc[6]:loc[7]], ",")...)
+		if !strings.HasSuffix(name, "s") {
+			continue
+		}
+		rest := text[loc[1]:]
+		for m := next.FindStringSubmatchIndex(rest); m != nil; m = next.FindStringSubmatchIndex(rest) {
+			s.Add(strings.Split(rest[m[4]:m[5]], ",")...)
+			rest = rest[m[1]:]
+		}
+	}
+	return nil
+}
+
+// StripComments removes the text from unescaped percent signs to the ends of
+// the lines.
+func stripComments(src string) string {
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(src, "\n") {
+		for i := 0; i < len(line); i++ {
+			if line[i] == '\\' {
+				i++
+				continue
+			}
+			if line[i] == '%' {
+				nl := ``
+				if strings.HasSuffix(line, "\n") {
+					nl = "\n"
+				}
+				line = line[:i] + nl
+				break
+			}
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// Load reads the cite keys of the files into a new set. Files with the .aux
+// extension are read as .aux files along with the files they include, which
+// LaTeX names relative to the output directory holding the .aux file of the
+// document. Other files are read as LaTeX sources.
+func Load(paths ...string) (*Set, error) {
+	s := NewSet()
+	seen := make(map[string]bool)
+	var load func(path, dir string) error
+	load = func(path, dir string) error {
+		if seen[path] {
+			return nil
+		}
+		seen[path] = true
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if filepath.Ext(path) != ".aux" {
+			return s.ReadTeX(f)
+		}
+		inputs, err := s.ReadAux(f)
+		if err != nil {
+			return err
+		}
+		for _, in := range inputs {
+			if err := load(filepath.Join(dir, in), dir); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, p := range paths {
+		if err := load(p, filepath.Dir(p)); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
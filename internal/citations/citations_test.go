@@ -0,0 +1,95 @@
+package citations
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadAux(t *testing.T) {
+	s := NewSet()
+	inputs, err := s.ReadAux(strings.NewReader(`\relax
+\citation{Cohen1963}
+\citation{Gödel1940,Cohen1963}
+\@input{chapters/intro.aux}
+\bibstyle{plain}
+\citation{*}
+\bibdata{refs}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Cohen1963", "Gödel1940"}; !reflect.DeepEqual(s.Keys, want) {
+		t.Errorf("have %v; want %v", s.Keys, want)
+	}
+	if want := []string{"chapters/intro.aux"}; !reflect.DeepEqual(inputs, want) {
+		t.Errorf("have inputs %v; want %v", inputs, want)
+	}
+	if !s.All || !s.Contains("Other") {
+		t.Error("have \\citation{*} not requiring all entries")
+	}
+}
+
+func TestReadTeX(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want []string
+		all  bool
+	}{
+		{"cite", `As shown by \cite{a}, and \cite{b, c}.`, []string{"a", "b", "c"}, false},
+		{"natbib", `\citep[see][p.~3]{a} \citet*{b} \citeauthor{c} \Citet{d}`, []string{"a", "b", "c", "d"}, false},
+		{"biblatex", `\parencite[12]{a} \textcite{b} \autocite{c} \footcite{d}`, []string{"a", "b", "c", "d"}, false},
+		{"multicite", `\cites[see][1]{a}[2]{b,c}{d} and then {e}`, []string{"a", "b", "c", "d"}, false},
+		{"nocite", `\nocite{*}\cite{a}`, []string{"a"}, true},
+		{"comment", "\\cite{a} % \\cite{b}\n50\\% \\cite{c}\n", []string{"a", "c"}, false},
+		{"not-citing", `\citestyle{authoryear}\bibliography{refs}`, []string{}, false},
+		{"repeated", `\cite{a}\cite{b}\cite{a}`, []string{"a", "b"}, false},
+		{"multiline", "\\cite{a,\n  b}", []string{"a", "b"}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := NewSet()
+			if err := s.ReadTeX(strings.NewReader(c.src)); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(s.Keys, c.want) {
+				t.Errorf("have %v; want %v", s.Keys, c.want)
+			}
+			if s.All != c.all {
+				t.Errorf("have All %t; want %t", s.All, c.all)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"paper.aux":          "\\citation{a}\n\\@input{chapters/one.aux}\n",
+		"chapters/one.aux":   "\\citation{b}\n\\@input{chapters/two.aux}\n",
+		"chapters/two.aux":   "\\citation{c}\n\\@input{paper.aux}\n",
+		"appendix/extra.tex": "\\cite{d}\n",
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := Load(filepath.Join(dir, "paper.aux"), filepath.Join(dir, "appendix/extra.tex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(s.Keys, want) {
+		t.Errorf("have %v; want %v", s.Keys, want)
+	}
+	if _, err := Load(filepath.Join(dir, "missing.aux")); err == nil {
+		t.Error("have nil error for a missing file")
+	}
+}
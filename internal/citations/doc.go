@@ -0,0 +1,10 @@
+/*
+Citations package extracts the cite keys required by a LaTeX document from
+the \citation commands of its .aux files, as written by LaTeX for BibTeX, or
+from the \cite commands of its .tex sources, including the natbib and
+biblatex variants. A \nocite{*} requires all entries of the bibliography.
+
+The package is not called aux after the file extension, because AUX is a
+reserved file name on Windows.
+*/
+package citations
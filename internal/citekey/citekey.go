@@ -0,0 +1,74 @@
+package citekey
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mdm-code/bibx/internal/names"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+// Generate builds an author-year cite key such as `Cohen1963` from the last
+// name of the first author, or editor, and the year of the entry. The first
+// significant word of the title stands in for missing names.
+func Generate(e *parse.EntryDecl) string {
+	key := ``
+	for _, field := range []string{"author", "editor"} {
+		if f, ok := e.Get(field); ok {
+			if ns := names.ParseList(parse.Unquote(f.Value)); len(ns) > 0 {
+				key = clean(ns[0].Last)
+				break
+			}
+		}
+	}
+	if key == `` {
+		if f, ok := e.Get("title"); ok {
+			for _, w := range strings.Fields(tex.Decode(parse.Unquote(f.Value))) {
+				if w = clean(w); len(w) > 3 {
+					key = strings.ToUpper(w[:1]) + strings.ToLower(w[1:])
+					break
+				}
+			}
+		}
+	}
+	if f, ok := e.Get("year"); ok {
+		key += clean(parse.Unquote(f.Value))
+	}
+	if key == `` {
+		key = "anon"
+	}
+	return key
+}
+
+// Unique appends the lowest free letter suffix to the key when the key is
+// already taken, e.g. `Cohen1963a`, and records the result as taken.
+func Unique(key string, taken map[string]bool) string {
+	result := key
+	for i := 0; taken[result]; i++ {
+		result = key + suffix(i)
+	}
+	taken[result] = true
+	return result
+}
+
+// Suffix enumerates the letter suffixes a, b, ..., z, aa, ab and so on.
+func suffix(i int) string {
+	s := ``
+	for i >= 0 {
+		s = string(rune('a'+i%26)) + s
+		i = i/26 - 1
+	}
+	return s
+}
+
+// Clean folds the text to ASCII and keeps only letters and digits.
+func clean(s string) string {
+	var b strings.Builder
+	for _, r := range tex.Fold(tex.Decode(s)) {
+		if r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
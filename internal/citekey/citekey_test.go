@@ -0,0 +1,150 @@
+package citekey
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestGenerate(t *testing.T) {
+	cases := []struct {
+		name   string
+		fields []*parse.FieldStmt
+		want   string
+	}{
+		{
+			name: "author-year",
+			fields: []*parse.FieldStmt{
+				{Key: "author", Value: "{Cohen, Paul J. and Thompson, M. R.}"},
+				{Key: "year", Value: "1963"},
+			},
+			want: "Cohen1963",
+		},
+		{
+			name: "accents",
+			fields: []*parse.FieldStmt{
+				{Key: "author", Value: `{Kurt G{\"o}del}`},
+				{Key: "year", Value: "{1931}"},
+			},
+			want: "Godel1931",
+		},
+		{
+			name: "von",
+			fields: []*parse.FieldStmt{
+				{Key: "author", Value: "{Ludwig van Beethoven}"},
+			},
+			want: "Beethoven",
+		},
+		{
+			name: "editor",
+			fields: []*parse.FieldStmt{
+				{Key: "editor", Value: "{Peter Isley}"},
+				{Key: "year", Value: "1993"},
+			},
+			want: "Isley1993",
+		},
+		{
+			name: "title",
+			fields: []*parse.FieldStmt{
+				{Key: "title", Value: "{The independence of the hypothesis}"},
+				{Key: "year", Value: "1963"},
+			},
+			want: "Independence1963",
+		},
+		{
+			name:   "empty",
+			fields: []*parse.FieldStmt{},
+			want:   "anon",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := &parse.EntryDecl{Name: "article", Fields: c.fields}
+			if have := Generate(e); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestUnique(t *testing.T) {
+	taken := map[string]bool{}
+	want := []string{"Cohen1963", "Cohen1963a", "Cohen1963b"}
+	for _, w := range want {
+		if have := Unique("Cohen1963", taken); have != w {
+			t.Errorf("have %s; want %s", have, w)
+		}
+	}
+	if have := suffix(26); have != "aa" {
+		t.Errorf("have %s; want %s", have, "aa")
+	}
+}
+
+func TestImport(t *testing.T) {
+	entry := func(key string, fields ...string) *parse.EntryDecl {
+		e := &parse.EntryDecl{Name: "misc", CiteKey: key, Comments: &parse.CommentGroupExpr{}}
+		for i := 0; i < len(fields); i += 2 {
+			e.Fields = append(e.Fields, &parse.FieldStmt{Key: fields[i], Value: fields[i+1]})
+		}
+		return e
+	}
+	entries := []*parse.EntryDecl{
+		entry("Proc2001", "title", "{Proceedings}"),
+		entry("Smith2001", "crossref", "{proc2001}"),
+		entry("Data", "note", "{shared}"),
+		entry("Doe2000", "xdata", `"Data, Other"`, "crossref", "{Lib}"),
+		entry(``, "author", "{Cohen, Paul}", "year", "1963"),
+		entry("Doe2000"),
+	}
+	taken := map[string]bool{"Proc2001": true, "Data": true, "Lib": true, "Cohen1963": true, "Doe2000": true}
+	renamed := Import(entries, taken)
+	have := []string{}
+	for _, e := range entries {
+		s := e.CiteKey
+		for _, f := range e.Fields {
+			if f.Key == "crossref" || f.Key == "xdata" {
+				s += " " + f.Key + "=" + f.Value
+			}
+		}
+		have = append(have, s)
+	}
+	want := []string{
+		"Proc2001a",
+		"Smith2001 crossref={Proc2001a}",
+		"Dataa",
+		`Doe2000a xdata="Dataa, Other" crossref={Lib}`,
+		"Cohen1963a",
+		"Doe2000b",
+	}
+	for i := range want {
+		if have[i] != want[i] {
+			t.Errorf("have %s; want %s", have[i], want[i])
+		}
+	}
+	if have, want := len(renamed), 5; have != want {
+		t.Errorf("have %d renamed keys; want %d", have, want)
+	}
+	if have, want := renamed[len(renamed)-1], (Rename{Old: "Doe2000", New: "Doe2000b"}); have != want {
+		t.Errorf("have %v; want %v", have, want)
+	}
+	if !taken["Doe2000b"] || !taken["Smith2001"] {
+		t.Errorf("have taken %v; want the imported keys", taken)
+	}
+}
+
+func TestImportKeepFirst(t *testing.T) {
+	entries := []*parse.EntryDecl{
+		{Name: "misc", CiteKey: "Doe2000", Comments: &parse.CommentGroupExpr{}},
+		{Name: "misc", CiteKey: "Doe2000", Comments: &parse.CommentGroupExpr{}},
+		{Name: "misc", CiteKey: "Smith2001", Comments: &parse.CommentGroupExpr{}, Fields: []*parse.FieldStmt{{Key: "crossref", Value: "{Doe2000}"}}},
+	}
+	renamed := Import(entries, map[string]bool{})
+	want := []Rename{{Old: "Doe2000", New: "Doe2000a"}}
+	if !reflect.DeepEqual(renamed, want) {
+		t.Errorf("have %v; want %v", renamed, want)
+	}
+	if have, want := entries[2].Fields[0].Value, "{Doe2000}"; have != want {
+		t.Errorf("have crossref %s; want %s", have, want)
+	}
+}
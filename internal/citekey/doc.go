@@ -0,0 +1,5 @@
+/*
+Citekey package generates cite keys for entries that lack them, for example
+entries created from other bibliography formats.
+*/
+package citekey
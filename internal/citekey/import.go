@@ -0,0 +1,83 @@
+package citekey
+
+import (
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// Import makes the cite keys of the entries imported into a library unique
+// among themselves and the taken keys of the library, recording them as
+// taken. Entries without a key get a generated one, and colliding keys get
+// the lowest free letter suffix as with Unique. The crossref and xdata
+// fields of the imported entries referring to a renamed key are rewritten to
+// the new key, so that the entries keep referring to each other rather than
+// to the entries of the library. Import returns every rename in the order of
+// the entries, including those of keys shared by several imported entries.
+func Import(entries []*parse.EntryDecl, taken map[string]bool) []Rename {
+	var result []Rename
+	renamed := map[string]string{}
+	seen := map[string]bool{} // old keys in lower case
+	for _, e := range entries {
+		old := e.CiteKey
+		if old == `` {
+			old = Generate(e)
+		}
+		e.CiteKey = Unique(old, taken)
+		if e.CiteKey != old {
+			result = append(result, Rename{Old: old, New: e.CiteKey})
+			// References to a key shared by several imported entries stand
+			// for the first of them.
+			if !seen[strings.ToLower(old)] {
+				renamed[old] = e.CiteKey
+			}
+		}
+		seen[strings.ToLower(old)] = true
+	}
+	Rewrite(entries, renamed)
+	return result
+}
+
+// Rename records the cite key of an imported entry changed by Import.
+type Rename struct {
+	Old, New string
+}
+
+// Rewrite changes the crossref and xdata fields of the entries referring to
+// the old keys of renamed, matched regardless of case, to the new keys.
+func Rewrite(entries []*parse.EntryDecl, renamed map[string]string) {
+	if len(renamed) == 0 {
+		return
+	}
+	byRef := make(map[string]string, len(renamed))
+	for old, k := range renamed {
+		byRef[strings.ToLower(old)] = k
+	}
+	for _, e := range entries {
+		if f, ok := e.Get("crossref"); ok {
+			if k, ok := byRef[strings.ToLower(strings.TrimSpace(parse.Unquote(f.Value)))]; ok {
+				f.Value = requote(f.Value, k)
+			}
+		}
+		if f, ok := e.Get("xdata"); ok {
+			keys := strings.Split(parse.Unquote(f.Value), ",")
+			changed := false
+			for i, k := range keys {
+				if n, ok := byRef[strings.ToLower(strings.TrimSpace(k))]; ok {
+					keys[i], changed = strings.Replace(k, strings.TrimSpace(k), n, 1), true
+				}
+			}
+			if changed {
+				f.Value = requote(f.Value, strings.Join(keys, ","))
+			}
+		}
+	}
+}
+
+// Requote delimits the contents like the old value of a field.
+func requote(old, contents string) string {
+	if strings.HasPrefix(old, `"`) {
+		return `"` + contents + `"`
+	}
+	return parse.Quote(contents)
+}
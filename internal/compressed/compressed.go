@@ -0,0 +1,154 @@
+package compressed
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// ErrZstd is returned for data compressed with zstd, which is recognized but
+// cannot be decompressed.
+var ErrZstd = errors.New("compressed: zstd is not supported; decompress with unzstd first")
+
+const (
+	// None is uncompressed data.
+	None Format = iota
+	// Gzip is data compressed with gzip.
+	Gzip
+	// Zstd is data compressed with zstd.
+	Zstd
+)
+
+// Format is the compression of data.
+type Format uint8
+
+var magic = [...][]byte{
+	Gzip: {0x1f, 0x8b},
+	Zstd: {0x28, 0xb5, 0x2f, 0xfd},
+}
+
+// Detect tells the compression of the data from its leading bytes.
+func Detect(data []byte) Format {
+	for f, m := range magic {
+		if m != nil && bytes.HasPrefix(data, m) {
+			return Format(f)
+		}
+	}
+	return None
+}
+
+// FromPath tells the compression of a file to be written from the extension
+// of its name: .gz for gzip and .zst for zstd.
+func FromPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz":
+		return Gzip
+	case ".zst":
+		return Zstd
+	}
+	return None
+}
+
+// Decode decompresses the data, which is returned as it is when it is not
+// compressed.
+func Decode(data []byte) ([]byte, error) {
+	switch Detect(data) {
+	case Gzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(r)
+	case Zstd:
+		return nil, ErrZstd
+	}
+	return data, nil
+}
+
+// Encode compresses the data to be written to the file at path as the
+// extension of its name tells.
+func Encode(path string, data []byte) ([]byte, error) {
+	if FromPath(path) == None {
+		return data, nil
+	}
+	var b bytes.Buffer
+	w, err := NewWriter(&b, path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Reader decompresses the stream it reads from when it is compressed, and
+// passes it through otherwise. The compression is detected on the first
+// read, which returns the error of an unsupported or corrupt stream.
+type Reader struct {
+	r       io.Reader
+	sniffed bool
+	err     error
+}
+
+// NewReader returns a Reader of r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+// Read reads the decompressed stream.
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if !r.sniffed {
+		r.sniffed = true
+		if r.r = r.sniff(bufio.NewReader(r.r)); r.err != nil {
+			return 0, r.err
+		}
+	}
+	return r.r.Read(p)
+}
+
+// Sniff returns the reader of the stream decompressed as its leading bytes
+// tell.
+func (r *Reader) sniff(br *bufio.Reader) io.Reader {
+	head, _ := br.Peek(len(magic[Zstd]))
+	switch Detect(head) {
+	case Gzip:
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			r.err = err
+			return nil
+		}
+		return zr
+	case Zstd:
+		r.err = ErrZstd
+		return nil
+	}
+	return br
+}
+
+// NewWriter returns a writer compressing what is written to w as the
+// extension of path tells. Closing it flushes the compressed stream but
+// leaves w open.
+func NewWriter(w io.Writer, path string) (io.WriteCloser, error) {
+	switch FromPath(path) {
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Zstd:
+		return nil, ErrZstd
+	}
+	return nopCloser{w}, nil
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,158 @@
+package compressed
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+const testBib = "@misc{Doe2020,\n  year = 2020\n}\n"
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+		want Format
+	}{
+		{"plain", []byte(testBib), None},
+		{"empty", []byte{}, None},
+		{"gzip", gzipped(t, testBib), Gzip},
+		{"zstd", []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00}, Zstd},
+		{"short", []byte{0x1f}, None},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := Detect(c.data); have != c.want {
+				t.Errorf("have %v; want %v", have, c.want)
+			}
+		})
+	}
+}
+
+func TestFromPath(t *testing.T) {
+	cases := []struct {
+		path string
+		want Format
+	}{
+		{"library.bib", None},
+		{"library.bib.gz", Gzip},
+		{"dumps/LIBRARY.BIB.GZ", Gzip},
+		{"library.bib.zst", Zstd},
+		{"gz", None},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			if have := FromPath(c.path); have != c.want {
+				t.Errorf("have %v; want %v", have, c.want)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+		want string
+		err  error
+	}{
+		{"plain", []byte(testBib), testBib, nil},
+		{"gzip", gzipped(t, testBib), testBib, nil},
+		{"zstd", []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00}, ``, ErrZstd},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			have, err := Decode(c.data)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("have error %v; want %v", err, c.err)
+			}
+			if string(have) != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+	if _, err := Decode([]byte{0x1f, 0x8b, 0x00}); err == nil {
+		t.Error("have no error for a corrupt stream")
+	}
+}
+
+func TestReader(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+		want string
+		err  error
+	}{
+		{"plain", []byte(testBib), testBib, nil},
+		{"empty", []byte{}, ``, nil},
+		{"gzip", gzipped(t, testBib), testBib, nil},
+		{"zstd", []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00}, ``, ErrZstd},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			have, err := io.ReadAll(NewReader(bytes.NewReader(c.data)))
+			if !errors.Is(err, c.err) {
+				t.Fatalf("have error %v; want %v", err, c.err)
+			}
+			if string(have) != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	cases := []struct {
+		path   string
+		format Format
+	}{
+		{"library.bib", None},
+		{"library.bib.gz", Gzip},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			data, err := Encode(c.path, []byte(testBib))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have := Detect(data); have != c.format {
+				t.Errorf("have %v; want %v", have, c.format)
+			}
+			have, err := Decode(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(have) != testBib {
+				t.Errorf("have %q; want %q", have, testBib)
+			}
+		})
+	}
+	if _, err := Encode("library.bib.zst", []byte(testBib)); !errors.Is(err, ErrZstd) {
+		t.Errorf("have error %v; want %v", err, ErrZstd)
+	}
+	var b strings.Builder
+	w, err := NewWriter(&b, "-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, testBib)
+	if err := w.Close(); err != nil || b.String() != testBib {
+		t.Errorf("have %q, %v; want %q", b.String(), err, testBib)
+	}
+}
@@ -0,0 +1,6 @@
+/*
+Compressed package reads and writes files compressed with gzip, telling them
+apart from plain files by their magic bytes rather than their names, so that
+archived dumps of libraries are processed like any other file.
+*/
+package compressed
@@ -0,0 +1,251 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/tidy"
+)
+
+// FileName is the name of the project configuration file.
+const FileName = ".bibx.toml"
+
+// Names of the layers settings come from besides the files they are read
+// from.
+const (
+	DefaultSource = "default"
+	FlagSource    = "flag"
+)
+
+// Keys lists the settings under their dotted TOML keys.
+var Keys = []string{"tidy.steps", "tidy.months", "tidy.sort", "tidy.field-order"}
+
+// Config holds the settings of bibx. Sources maps the keys of the settings
+// changed from their defaults onto the files or the flags that set them.
+type Config struct {
+	Tidy    Tidy
+	Sources map[string]string
+}
+
+// Tidy configures the tidy command: the pipeline Steps to run, the style of
+// the month fields, the order the entries are sorted in and the order of the
+// fields. An empty FieldOrder stands for the built-in order.
+type Tidy struct {
+	Steps      []string
+	Months     string
+	Sort       string
+	FieldOrder []string
+}
+
+// Default returns the built-in settings.
+func Default() *Config {
+	return &Config{
+		Tidy: Tidy{
+			Steps:  append([]string{}, tidy.Steps...),
+			Months: "macro",
+			Sort:   "key",
+		},
+		Sources: make(map[string]string),
+	}
+}
+
+// Load reads the configuration file on top of the built-in settings.
+func Load(path string) (*Config, error) {
+	c := Default()
+	if err := c.LoadFile(path); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Resolve layers the user configuration file and the project configuration
+// file found from the directory up on top of the built-in settings, each
+// overriding the previous ones. Missing files are skipped, and the paths of
+// the files read are returned.
+func Resolve(dir string) (*Config, []string, error) {
+	c := Default()
+	paths := []string{}
+	candidates := []string{}
+	if path, ok := UserFile(); ok {
+		candidates = append(candidates, path)
+	}
+	if path, ok := Find(dir); ok {
+		candidates = append(candidates, path)
+	}
+	for _, path := range candidates {
+		err := c.LoadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		paths = append(paths, path)
+	}
+	return c, paths, nil
+}
+
+// UserFile returns the path of the user configuration file, config.toml in
+// the bibx directory of the user configuration directory, such as
+// ~/.config/bibx/config.toml on Linux. The file may not exist.
+func UserFile() (string, bool) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ``, false
+	}
+	return filepath.Join(dir, "bibx", "config.toml"), true
+}
+
+// Find looks for the project configuration file in the directory and in its
+// parents and returns the path of the closest one.
+func Find(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ``, false
+	}
+	for {
+		path := filepath.Join(dir, FileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ``, false
+		}
+		dir = parent
+	}
+}
+
+// LoadFile overrides the settings with the ones set in the file.
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := c.Decode(data, path); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Decode overrides the settings with the ones set in the TOML document and
+// records the source as theirs. Unknown tables and keys are reported as
+// errors to catch typos.
+func (c *Config) Decode(data []byte, source string) error {
+	doc, err := decodeTOML(data)
+	if err != nil {
+		return err
+	}
+	for name, v := range doc {
+		table, ok := v.(map[string]any)
+		if !ok || name != "tidy" {
+			return fmt.Errorf("config: unknown table %s", name)
+		}
+		for key, v := range table {
+			if err := c.set(name+"."+key, v, source); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Set overrides the setting under the key with the value given as text, such
+// as the value of a command-line flag. Arrays are given as comma-separated
+// lists.
+func (c *Config) Set(key, value, source string) error {
+	switch key {
+	case "tidy.steps", "tidy.field-order":
+		list := []any{}
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s != `` {
+				list = append(list, s)
+			}
+		}
+		return c.set(key, list, source)
+	default:
+		return c.set(key, value, source)
+	}
+}
+
+func (c *Config) set(key string, v any, source string) error {
+	var err error
+	switch key {
+	case "tidy.steps":
+		c.Tidy.Steps, err = stringList(v)
+	case "tidy.months":
+		c.Tidy.Months, err = str(v)
+	case "tidy.sort":
+		c.Tidy.Sort, err = str(v)
+	case "tidy.field-order":
+		c.Tidy.FieldOrder, err = stringList(v)
+	default:
+		err = errors.New("unknown key")
+	}
+	if err != nil {
+		return fmt.Errorf("config: %s: %w", key, err)
+	}
+	c.Sources[key] = source
+	return nil
+}
+
+// Value returns the setting under the key written as a TOML value.
+func (c *Config) Value(key string) (string, bool) {
+	switch key {
+	case "tidy.steps":
+		return tomlList(c.Tidy.Steps), true
+	case "tidy.months":
+		return strconv.Quote(c.Tidy.Months), true
+	case "tidy.sort":
+		return strconv.Quote(c.Tidy.Sort), true
+	case "tidy.field-order":
+		return tomlList(c.Tidy.FieldOrder), true
+	default:
+		return ``, false
+	}
+}
+
+// Source returns the file or the flag the setting under the key comes from,
+// or DefaultSource for built-in settings.
+func (c *Config) Source(key string) string {
+	if s, ok := c.Sources[key]; ok {
+		return s
+	}
+	return DefaultSource
+}
+
+func tomlList(list []string) string {
+	quoted := make([]string, 0, len(list))
+	for _, s := range list {
+		quoted = append(quoted, strconv.Quote(s))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func str(v any) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return ``, errors.New("want a string")
+	}
+	return s, nil
+}
+
+func stringList(v any) ([]string, error) {
+	list, ok := v.([]any)
+	if !ok {
+		return nil, errors.New("want an array of strings")
+	}
+	result := []string{}
+	for _, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, errors.New("want an array of strings")
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
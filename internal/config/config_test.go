@@ -0,0 +1,165 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeTOML(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want map[string]any
+	}{
+		{"empty", "# nothing\n", map[string]any{}},
+		{
+			"values",
+			"a = \"x\\ty \\u00e9\" # comment\nb = 'c:\\dir'\nc = 1_000\nd = 1.5\ne = true\n",
+			map[string]any{"a": "x\ty é", "b": `c:\dir`, "c": int64(1000), "d": 1.5, "e": true},
+		},
+		{
+			"tables",
+			"[tidy]\nsteps = [\n  \"case\", # first\n  \"pages\",\n]\n[a.b]\nc.d = []\n",
+			map[string]any{
+				"tidy": map[string]any{"steps": []any{"case", "pages"}},
+				"a":    map[string]any{"b": map[string]any{"c": map[string]any{"d": []any{}}}},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			have, err := decodeTOML([]byte(c.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(have, c.want) {
+				t.Errorf("have %v; want %v", have, c.want)
+			}
+		})
+	}
+}
+
+func TestDecodeTOMLErrors(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"a = \"x", "config: line 1: unterminated string"},
+		{"a = 1\na = 2", "config: line 2: duplicate key a"},
+		{"[t]\n[t]", "config: line 2: table t defined twice"},
+		{"a = 1 2", `config: line 1: unexpected '2' at the end of the line`},
+		{"a = [1 2]", "config: line 1: expected , or ] in the array"},
+		{"a = yes", `config: line 1: invalid value "yes"`},
+		{"= 1", "config: line 1: expected a key"},
+	}
+	for _, c := range cases {
+		_, err := decodeTOML([]byte(c.src))
+		if err == nil || err.Error() != c.want {
+			t.Errorf("%q: have %v; want %s", c.src, err, c.want)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	c := Default()
+	if err := c.Decode([]byte("[tidy]\nsteps = [\"case\"]\nsort = \"year\"\n"), "test.toml"); err != nil {
+		t.Fatal(err)
+	}
+	want := Tidy{Steps: []string{"case"}, Months: "macro", Sort: "year"}
+	if !reflect.DeepEqual(c.Tidy, want) {
+		t.Errorf("have %+v; want %+v", c.Tidy, want)
+	}
+	if have := c.Source("tidy.sort"); have != "test.toml" {
+		t.Errorf("have source %s; want test.toml", have)
+	}
+	if have := c.Source("tidy.months"); have != DefaultSource {
+		t.Errorf("have source %s; want %s", have, DefaultSource)
+	}
+	for _, src := range []string{"[tidy]\nsort = 1\n", "[tidy]\ncurly = true\n", "[fmt]\n"} {
+		if err := Default().Decode([]byte(src), "test.toml"); err == nil {
+			t.Errorf("%q: have nil error", src)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Find(sub); ok {
+		t.Skip("configuration file found above the temporary directory")
+	}
+	path := filepath.Join(root, "a", FileName)
+	if err := os.WriteFile(path, []byte("[tidy]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if have, ok := Find(sub); !ok || have != path {
+		t.Errorf("have %s, %t; want %s", have, ok, path)
+	}
+}
+
+func TestSet(t *testing.T) {
+	cases := []struct {
+		key, value string
+		want       string
+	}{
+		{"tidy.steps", "case, pages,", `["case", "pages"]`},
+		{"tidy.field-order", "", `[]`},
+		{"tidy.months", "name", `"name"`},
+	}
+	for _, c := range cases {
+		t.Run(c.key, func(t *testing.T) {
+			cfg := Default()
+			if err := cfg.Set(c.key, c.value, FlagSource); err != nil {
+				t.Fatal(err)
+			}
+			if have, _ := cfg.Value(c.key); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+			if have := cfg.Source(c.key); have != FlagSource {
+				t.Errorf("have source %s; want %s", have, FlagSource)
+			}
+		})
+	}
+	if err := Default().Set("tidy.curly", "true", FlagSource); err == nil {
+		t.Error("have nil error for an unknown key")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	user, ok := UserFile()
+	if !ok {
+		t.Skip("no user configuration directory")
+	}
+	project := t.TempDir()
+	if _, ok := Find(project); ok {
+		t.Skip("configuration file found above the temporary directory")
+	}
+	write := func(path, src string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(user, "[tidy]\nmonths = \"name\"\nsort = \"year\"\n")
+	write(filepath.Join(project, FileName), "[tidy]\nsort = \"author\"\n")
+	c, paths, err := Resolve(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{user, filepath.Join(project, FileName)}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("have paths %v; want %v", paths, want)
+	}
+	if c.Tidy.Months != "name" || c.Tidy.Sort != "author" || c.Source("tidy.sort") != paths[1] {
+		t.Errorf("have %+v from %v", c.Tidy, c.Sources)
+	}
+}
@@ -0,0 +1,6 @@
+/*
+Config package loads the settings of bibx from the project configuration
+file, .bibx.toml, written in the subset of TOML made of tables, strings,
+numbers, booleans and arrays.
+*/
+package config
@@ -0,0 +1,285 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// TomlParser decodes the subset of TOML made of tables, dotted keys, basic
+// and literal strings, integers, floats, booleans and arrays into nested
+// maps. Inline tables, arrays of tables, multi-line strings and dates are
+// not supported.
+type tomlParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func decodeTOML(data []byte) (map[string]any, error) {
+	p := &tomlParser{src: string(data), line: 1}
+	root := map[string]any{}
+	table := root
+	for {
+		p.skip(true)
+		if p.pos >= len(p.src) {
+			return root, nil
+		}
+		if p.src[p.pos] == '[' {
+			p.pos++
+			path, err := p.keys()
+			if err != nil {
+				return nil, err
+			}
+			if !p.consume(']') {
+				return nil, p.errorf("expected ] closing the table header")
+			}
+			if table, err = p.table(root, path, true); err != nil {
+				return nil, err
+			}
+		} else {
+			path, err := p.keys()
+			if err != nil {
+				return nil, err
+			}
+			if !p.consume('=') {
+				return nil, p.errorf("expected = after key %s", strings.Join(path, "."))
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			parent, err := p.table(table, path[:len(path)-1], false)
+			if err != nil {
+				return nil, err
+			}
+			key := path[len(path)-1]
+			if _, ok := parent[key]; ok {
+				return nil, p.errorf("duplicate key %s", strings.Join(path, "."))
+			}
+			parent[key] = v
+		}
+		p.skip(false)
+		if p.pos < len(p.src) && p.src[p.pos] != '\n' {
+			return nil, p.errorf("unexpected %q at the end of the line", p.src[p.pos])
+		}
+	}
+}
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("config: line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// Table returns the table under the path, creating the missing ones. A
+// header may not open a table twice.
+func (p *tomlParser) table(root map[string]any, path []string, header bool) (map[string]any, error) {
+	t := root
+	for i, k := range path {
+		switch v := t[k].(type) {
+		case nil:
+			next := map[string]any{}
+			t[k] = next
+			t = next
+		case map[string]any:
+			if header && i == len(path)-1 {
+				return nil, p.errorf("table %s defined twice", strings.Join(path, "."))
+			}
+			t = v
+		default:
+			return nil, p.errorf("key %s is not a table", strings.Join(path[:i+1], "."))
+		}
+	}
+	return t, nil
+}
+
+// Skip skips blanks and comments, and line breaks too if lines is set.
+func (p *tomlParser) skip(lines bool) {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == '\n' && lines:
+			p.pos++
+			p.line++
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) consume(c byte) bool {
+	p.skip(false)
+	if p.pos < len(p.src) && p.src[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// Keys reads a possibly dotted key.
+func (p *tomlParser) keys() ([]string, error) {
+	result := []string{}
+	for {
+		p.skip(false)
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("expected a key")
+		}
+		var key string
+		switch p.src[p.pos] {
+		case '"', '\'':
+			s, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		default:
+			start := p.pos
+			for p.pos < len(p.src) && isBare(p.src[p.pos]) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("expected a key")
+			}
+			key = p.src[start:p.pos]
+		}
+		result = append(result, key)
+		if !p.consume('.') {
+			return result, nil
+		}
+	}
+}
+
+func isBare(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) value() (any, error) {
+	p.skip(false)
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("expected a value")
+	}
+	switch c := p.src[p.pos]; {
+	case c == '"' || c == '\'':
+		return p.str()
+	case c == '[':
+		p.pos++
+		return p.array()
+	default:
+		start := p.pos
+		for p.pos < len(p.src) && !strings.ContainsRune(" \t\r\n#,]", rune(p.src[p.pos])) {
+			p.pos++
+		}
+		word := p.src[start:p.pos]
+		switch word {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		clean := strings.ReplaceAll(word, "_", ``)
+		if n, err := strconv.ParseInt(clean, 0, 64); err == nil {
+			return n, nil
+		}
+		if f, err := strconv.ParseFloat(clean, 64); err == nil {
+			return f, nil
+		}
+		return nil, p.errorf("invalid value %q", word)
+	}
+}
+
+func (p *tomlParser) array() ([]any, error) {
+	result := []any{}
+	for {
+		p.skip(true)
+		if p.pos < len(p.src) && p.src[p.pos] == ']' {
+			p.pos++
+			return result, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v)
+		p.skip(true)
+		if p.pos < len(p.src) && p.src[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		p.skip(true)
+		if p.pos < len(p.src) && p.src[p.pos] == ']' {
+			p.pos++
+			return result, nil
+		}
+		return nil, p.errorf("expected , or ] in the array")
+	}
+}
+
+// Str reads a basic string with escapes or a literal string kept verbatim.
+func (p *tomlParser) str() (string, error) {
+	quote := p.src[p.pos]
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		case c == '\n':
+			return ``, p.errorf("unterminated string")
+		case c == '\\' && quote == '"':
+			p.pos++
+			if err := p.escape(&b); err != nil {
+				return ``, err
+			}
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return ``, p.errorf("unterminated string")
+}
+
+func (p *tomlParser) escape(b *strings.Builder) error {
+	if p.pos >= len(p.src) {
+		return p.errorf("unterminated string")
+	}
+	c := p.src[p.pos]
+	p.pos++
+	switch c {
+	case '"', '\\':
+		b.WriteByte(c)
+	case 'n':
+		b.WriteByte('\n')
+	case 't':
+		b.WriteByte('\t')
+	case 'r':
+		b.WriteByte('\r')
+	case 'b':
+		b.WriteByte('\b')
+	case 'f':
+		b.WriteByte('\f')
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.src) {
+			return p.errorf("invalid unicode escape")
+		}
+		r, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(r)) {
+			return p.errorf("invalid unicode escape")
+		}
+		b.WriteRune(rune(r))
+		p.pos += n
+	default:
+		return p.errorf("invalid escape \\%c", c)
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+package crossref
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/citekey"
+	"github.com/mdm-code/bibx/internal/pages"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/remote"
+	"github.com/mdm-code/bibx/internal/tex"
+	"github.com/mdm-code/bibx/internal/transform"
+)
+
+// DefaultEndpoint is the works endpoint of the Crossref REST API.
+const DefaultEndpoint = "https://api.crossref.org/works/"
+
+// Markup matches the JATS and HTML tags found in Crossref titles.
+var markup = regexp.MustCompile(`<[^>]*>`)
+
+// Entry types of the works types published in journals, proceedings and
+// books along with the fields naming the venue.
+var venues = map[string][2]string{
+	"journal-article":     {"article", "journal"},
+	"proceedings-article": {"inproceedings", "booktitle"},
+	"book-chapter":        {"incollection", "booktitle"},
+}
+
+// Work holds the metadata of a work used to enrich entries.
+type Work struct {
+	DOI            string   `json:"DOI"`
+	Type           string   `json:"type"`
+	Title          []string `json:"title"`
+	ContainerTitle []string `json:"container-title"`
+	Volume         string   `json:"volume"`
+	Issue          string   `json:"issue"`
+	Page           string   `json:"page"`
+	Publisher      string   `json:"publisher"`
+	Issued         Date     `json:"issued"`
+	Author         []Author `json:"author"`
+}
+
+// Author is an author of a work. Organizations have a Name only.
+type Author struct {
+	Given  string `json:"given"`
+	Family string `json:"family"`
+	Name   string `json:"name"`
+}
+
+// Date is a possibly partial date given as year, month and day.
+type Date struct {
+	Parts [][]int `json:"date-parts"`
+}
+
+// Year returns the year of the date or zero if it is unknown.
+func (d Date) Year() int {
+	if len(d.Parts) == 0 || len(d.Parts[0]) == 0 {
+		return 0
+	}
+	return d.Parts[0][0]
+}
+
+// Client queries the Crossref API for works by their DOIs or bibliographic
+// metadata. Failed requests are retried as the Retry policy tells.
+type Client struct {
+	HTTP     *http.Client
+	Endpoint string
+	Retry    remote.Policy
+}
+
+// Fetch retrieves the metadata of the work identified by the DOI.
+func (c *Client) Fetch(ctx context.Context, doi string) (*Work, error) {
+	resp, err := c.get(ctx, url.PathEscape(doi))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return Read(resp.Body)
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("crossref: DOI %s not found", doi)
+	default:
+		return nil, fmt.Errorf("crossref: unexpected response status %s", resp.Status)
+	}
+}
+
+// Search returns at most rows works best matching the query, which may mix
+// words of the title with the names of the authors, the venue and the year.
+func (c *Client) Search(ctx context.Context, query string, rows int) ([]*Work, error) {
+	q := url.Values{"query.bibliographic": {query}, "rows": {fmt.Sprint(rows)}}
+	resp, err := c.get(ctx, "?"+q.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("crossref: unexpected response status %s", resp.Status)
+	}
+	var list struct {
+		Message struct {
+			Items []*Work `json:"items"`
+		} `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("crossref: %w", err)
+	}
+	return list.Message.Items, nil
+}
+
+// Get requests the path relative to the works endpoint.
+func (c *Client) get(ctx context.Context, path string) (*http.Response, error) {
+	endpoint := c.Endpoint
+	if endpoint == `` {
+		endpoint = DefaultEndpoint
+	}
+	if strings.HasPrefix(path, "?") {
+		endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "bibx (https://github.com/mdm-code/bibx)")
+	return remote.Do(ctx, c.HTTP, c.Retry, req)
+}
+
+// Read decodes a work from a response of the Crossref works endpoint.
+func Read(r io.Reader) (*Work, error) {
+	var resp struct {
+		Message *Work `json:"message"`
+	}
+	if err := json.NewDecoder(r).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("crossref: %w", err)
+	}
+	if resp.Message == nil {
+		return nil, fmt.Errorf("crossref: missing message")
+	}
+	return resp.Message, nil
+}
+
+// Enrich fills in the volume, number, pages and publisher fields missing from
+// the entry with the metadata of the work and corrects the capitalization of
+// the title if it differs from the one of the work in case only. The changed
+// fields are listed in a comment added to the entry and returned.
+func (w *Work) Enrich(e *parse.EntryDecl) []string {
+	result := w.enrich(e)
+	if len(result) > 0 {
+		if e.Comments == nil {
+			e.Comments = &parse.CommentGroupExpr{}
+		}
+		e.Comments.Values = append(e.Comments.Values, &parse.CommentExpr{
+			Value: "% enriched from Crossref: " + strings.Join(result, ", "),
+		})
+	}
+	return result
+}
+
+func (w *Work) enrich(e *parse.EntryDecl) []string {
+	result := []string{}
+	fill := func(key, value string) {
+		if _, ok := e.Lookup(key); ok || value == `` {
+			return
+		}
+		e.Set(key, parse.Quote(value))
+		result = append(result, key)
+	}
+	fill("volume", tex.Escape(clean(w.Volume)))
+	fill("number", tex.Escape(clean(w.Issue)))
+	page := clean(w.Page)
+	if p, err := pages.Normalize(page); err == nil {
+		page = p
+	}
+	fill("pages", page)
+	fill("publisher", tex.Escape(clean(w.Publisher)))
+	if w.title() == `` {
+		return result
+	}
+	f, ok := e.Get("title")
+	if !ok {
+		fill("title", tex.Escape(w.title()))
+		return result
+	}
+	old := parse.Unquote(f.Value)
+	if old == f.Value {
+		// Titles written with macros are not touched.
+		return result
+	}
+	current := clean(strings.NewReplacer("{", ``, "}", ``).Replace(tex.Decode(old)))
+	remote := w.title()
+	if current == remote || !strings.EqualFold(current, remote) || !isOneCase(current) || isOneCase(remote) {
+		return result
+	}
+	v, ok := protect(tex.Escape(remote), old)
+	if !ok {
+		return result
+	}
+	if strings.HasPrefix(f.Value, `"`) {
+		f.Value = `"` + v + `"`
+	} else {
+		f.Value = parse.Quote(v)
+	}
+	return append(result, "title")
+}
+
+// IsOneCase tells if the letters of the title are all lower or all upper
+// case, so that its case carries no information worth keeping.
+func isOneCase(s string) bool {
+	return s == strings.ToLower(s) || s == strings.ToUpper(s)
+}
+
+// Protect encloses the parts of the title that are enclosed in braces in the
+// old one, such as {DNA}, in the same braces. Groups starting with a command
+// are markup rather than protection and are left out. It fails when a group
+// cannot be found in the title.
+func protect(title, old string) (string, bool) {
+	depth, start := 0, 0
+	for i, c := range old {
+		switch c {
+		case '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case '}':
+			depth--
+			if depth != 0 {
+				continue
+			}
+			group := old[start+1 : i]
+			if strings.HasPrefix(group, `\`) {
+				continue
+			}
+			j := strings.Index(strings.ToLower(title), strings.ToLower(group))
+			if j < 0 || group == `` {
+				return ``, false
+			}
+			title = title[:j] + "{" + group + "}" + title[j+len(group):]
+		}
+	}
+	return title, true
+}
+
+// Publish rewrites the preprint entry into an entry of the published work:
+// it gets the entry type matching the venue, the venue itself, the DOI and
+// year of the work, and the volume, number and pages. A howpublished field
+// pointing at arXiv is dropped, while the eprint fields are kept so that the
+// preprint can still be found. It reports whether the entry was changed.
+func (w *Work) Publish(e *parse.EntryDecl) bool {
+	old := &parse.EntryDecl{Name: e.Name, CiteKey: e.CiteKey, Comments: e.Comments, Fields: []*parse.FieldStmt{}}
+	for _, f := range e.Fields {
+		old.Fields = append(old.Fields, &parse.FieldStmt{Key: f.Key, Value: f.Value})
+	}
+	set := func(key, value string) {
+		if value != `` {
+			e.Set(key, parse.Quote(value))
+		}
+	}
+	if v, ok := venues[w.Type]; ok && len(w.ContainerTitle) > 0 {
+		if e.Name != v[0] {
+			// The venue of the preprint, if any, is not the one of the work.
+			e.Del("journal")
+			e.Del("booktitle")
+			e.Name = v[0]
+		}
+		set(v[1], tex.Escape(clean(html.UnescapeString(w.ContainerTitle[0]))))
+	}
+	set("doi", w.DOI)
+	if y := w.Issued.Year(); y > 0 {
+		e.Set("year", fmt.Sprint(y))
+	}
+	set("volume", tex.Escape(clean(w.Volume)))
+	set("number", tex.Escape(clean(w.Issue)))
+	page := clean(w.Page)
+	if p, err := pages.Normalize(page); err == nil {
+		page = p
+	}
+	set("pages", page)
+	if f, ok := e.Get("howpublished"); ok && strings.Contains(strings.ToLower(f.Value), "arxiv") {
+		e.Del("howpublished")
+	}
+	return !e.Eq(old)
+}
+
+// Entry returns a new entry of the work with a generated cite key.
+func (w *Work) Entry() *parse.EntryDecl {
+	e := &parse.EntryDecl{Name: "misc", Comments: &parse.CommentGroupExpr{}}
+	authors := []string{}
+	for _, a := range w.Author {
+		switch {
+		case a.Family != `` && a.Given != ``:
+			authors = append(authors, tex.Escape(clean(a.Family))+", "+tex.Escape(clean(a.Given)))
+		case a.Family != ``:
+			authors = append(authors, tex.Escape(clean(a.Family)))
+		case a.Name != ``:
+			authors = append(authors, "{"+tex.Escape(clean(a.Name))+"}")
+		}
+	}
+	if len(authors) > 0 {
+		e.Set("author", parse.Quote(strings.Join(authors, " and ")))
+	}
+	if t := w.title(); t != `` {
+		e.Set("title", parse.Quote(tex.Escape(t)))
+	}
+	if w.Type == "book" || w.Type == "monograph" {
+		e.Name = "book"
+	}
+	w.Publish(e)
+	if e.Name == "book" || e.Name == "incollection" {
+		if p := clean(w.Publisher); p != `` {
+			e.Set("publisher", parse.Quote(tex.Escape(p)))
+		}
+	}
+	transform.SortFields(e, transform.DefaultFieldOrder)
+	e.CiteKey = citekey.Generate(e)
+	return e
+}
+
+// Title returns the main title of the work stripped of its markup.
+func (w *Work) title() string {
+	if len(w.Title) == 0 {
+		return ``
+	}
+	return clean(html.UnescapeString(markup.ReplaceAllString(w.Title[0], ``)))
+}
+
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,273 @@
+package crossref
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+const testWork = `{
+  "status": "ok",
+  "message-type": "work",
+  "message": {
+    "DOI": "10.1073/pnas.50.6.1143",
+    "title": ["The Independence of the Continuum Hypothesis"],
+    "volume": "50",
+    "issue": "6",
+    "page": "1143-1148",
+    "publisher": "Proceedings of the National Academy of Sciences",
+    "container-title": ["Proceedings of the National Academy of Sciences"],
+    "type": "journal-article",
+    "issued": {"date-parts": [[1963, 12]]}
+  }
+}`
+
+var wantWork = &Work{
+	DOI:            "10.1073/pnas.50.6.1143",
+	Type:           "journal-article",
+	Title:          []string{"The Independence of the Continuum Hypothesis"},
+	ContainerTitle: []string{"Proceedings of the National Academy of Sciences"},
+	Volume:         "50",
+	Issue:          "6",
+	Page:           "1143-1148",
+	Publisher:      "Proceedings of the National Academy of Sciences",
+	Issued:         Date{Parts: [][]int{{1963, 12}}},
+}
+
+func TestRead(t *testing.T) {
+	have, err := Read(strings.NewReader(testWork))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(have, wantWork) {
+		t.Errorf("have %+v; want %+v", have, wantWork)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/works/10.1073%2Fpnas.50.6.1143" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(testWork))
+	}))
+	defer ts.Close()
+	c := Client{Endpoint: ts.URL + "/works/"}
+	have, err := c.Fetch(context.Background(), "10.1073/pnas.50.6.1143")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(have, wantWork) {
+		t.Errorf("have %+v; want %+v", have, wantWork)
+	}
+	if _, err := c.Fetch(context.Background(), "10.1000/missing"); err == nil {
+		t.Error("want an error for an unknown DOI")
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	cases := []struct {
+		name   string
+		fields []string
+		want   string
+		filled []string
+	}{
+		{
+			name:   "missing fields",
+			fields: []string{"title", "{the independence of the continuum hypothesis}", "doi", "{10.1073/pnas.50.6.1143}", "volume", "50"},
+			want: `% enriched from Crossref: number, pages, publisher, title
+@article{Cohen1963,
+  title     = {The Independence of the Continuum Hypothesis},
+  doi       = {10.1073/pnas.50.6.1143},
+  volume    = 50,
+  number    = {6},
+  pages     = {1143--1148},
+  publisher = {Proceedings of the National Academy of Sciences}
+}
+`,
+			filled: []string{"number", "pages", "publisher", "title"},
+		},
+		{
+			name:   "different title",
+			fields: []string{"title", `"Independence of CH"`, "number", "{6}", "pages", "{1143--1148}", "volume", "{50}", "publisher", "{PNAS}"},
+			want: `@article{Cohen1963,
+  title     = "Independence of CH",
+  number    = {6},
+  pages     = {1143--1148},
+  volume    = {50},
+  publisher = {PNAS}
+}
+`,
+			filled: []string{},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := &parse.EntryDecl{Name: "article", CiteKey: "Cohen1963", Comments: &parse.CommentGroupExpr{}}
+			for i := 0; i+1 < len(c.fields); i += 2 {
+				e.Fields = append(e.Fields, &parse.FieldStmt{Key: c.fields[i], Value: c.fields[i+1]})
+			}
+			filled := wantWork.Enrich(e)
+			if !reflect.DeepEqual(filled, c.filled) {
+				t.Errorf("have %v; want %v", filled, c.filled)
+			}
+			var b bytes.Buffer
+			format.Node(&b, e)
+			if have := b.String(); have != c.want {
+				t.Errorf("have:\n%s\nwant:\n%s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestEnrichTitle(t *testing.T) {
+	cases := []struct {
+		name   string
+		local  string
+		remote string
+		want   string
+	}{
+		{"lower", "{the independence of the continuum hypothesis}", "The Independence of the Continuum Hypothesis", "{The Independence of the Continuum Hypothesis}"},
+		{"upper", `"THE {DNA} OF CELLS"`, "The DNA of Cells", `"The {DNA} of Cells"`},
+		{"mixed", "{The independence of the continuum hypothesis}", "The Independence of the Continuum Hypothesis", "{The independence of the continuum hypothesis}"},
+		{"remote upper", "{the dna of cells}", "THE DNA OF CELLS", "{the dna of cells}"},
+		{"accents", `{g{\"o}del's theorem}`, "Gödel's Theorem", "{Gödel's Theorem}"},
+		{"different", "{the {dna} of cells}", "The Genome of Cells", "{the {dna} of cells}"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := &parse.EntryDecl{Name: "article", CiteKey: "Doe2020", Fields: []*parse.FieldStmt{{Key: "title", Value: c.local}}}
+			w := &Work{Title: []string{c.remote}}
+			w.enrich(e)
+			if have := e.Fields[0].Value; have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestPublish(t *testing.T) {
+	e := &parse.EntryDecl{
+		Name:     "misc",
+		CiteKey:  "Cohen1963",
+		Comments: &parse.CommentGroupExpr{},
+		Fields: []*parse.FieldStmt{
+			{Key: "author", Value: "{Paul Cohen}"},
+			{Key: "title", Value: "{The independence of the continuum hypothesis}"},
+			{Key: "howpublished", Value: "{arXiv preprint}"},
+			{Key: "year", Value: "1962"},
+			{Key: "eprint", Value: "{math/6301001}"},
+			{Key: "archiveprefix", Value: "{arXiv}"},
+		},
+	}
+	if !wantWork.Publish(e) {
+		t.Fatal("entry not changed")
+	}
+	var b bytes.Buffer
+	format.Node(&b, e)
+	want := `@article{Cohen1963,
+  author        = {Paul Cohen},
+  title         = {The independence of the continuum hypothesis},
+  year          = 1963,
+  eprint        = {math/6301001},
+  archiveprefix = {arXiv},
+  journal       = {Proceedings of the National Academy of Sciences},
+  doi           = {10.1073/pnas.50.6.1143},
+  volume        = {50},
+  number        = {6},
+  pages         = {1143--1148}
+}
+`
+	if have := b.String(); have != want {
+		t.Errorf("have:\n%s\nwant:\n%s", have, want)
+	}
+	if wantWork.Publish(e) {
+		t.Error("published entry changed again")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/works" || r.URL.Query().Get("query.bibliographic") != "independence continuum" || r.URL.Query().Get("rows") != "2" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"status": "ok", "message": {"items": [{"DOI": "10.1073/pnas.50.6.1143", "title": ["The Independence of the Continuum Hypothesis"], "author": [{"given": "Paul J.", "family": "Cohen"}]}]}}`))
+	}))
+	defer ts.Close()
+	c := Client{Endpoint: ts.URL + "/works/"}
+	have, err := c.Search(context.Background(), "independence continuum", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Work{{
+		DOI:    "10.1073/pnas.50.6.1143",
+		Title:  []string{"The Independence of the Continuum Hypothesis"},
+		Author: []Author{{Given: "Paul J.", Family: "Cohen"}},
+	}}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have %+v; want %+v", have, want)
+	}
+}
+
+func TestEntry(t *testing.T) {
+	cases := []struct {
+		name string
+		work Work
+		want string
+	}{
+		{
+			name: "article",
+			work: func() Work {
+				w := *wantWork
+				w.Author = []Author{{Given: "Paul J.", Family: "Cohen"}, {Name: "The PNAS & Co."}}
+				return w
+			}(),
+			want: `@article{Cohen1963,
+  author  = {Cohen, Paul J. and {The PNAS \& Co.}},
+  title   = {The Independence of the Continuum Hypothesis},
+  journal = {Proceedings of the National Academy of Sciences},
+  volume  = {50},
+  number  = {6},
+  pages   = {1143--1148},
+  year    = 1963,
+  doi     = {10.1073/pnas.50.6.1143}
+}
+`,
+		},
+		{
+			name: "book",
+			work: Work{
+				Type:      "book",
+				Title:     []string{"Set Theory and the Continuum Hypothesis"},
+				Publisher: "W. A. Benjamin",
+				Issued:    Date{Parts: [][]int{{1966}}},
+				Author:    []Author{{Given: "Paul J.", Family: "Cohen"}},
+			},
+			want: `@book{Cohen1966,
+  author    = {Cohen, Paul J.},
+  title     = {Set Theory and the Continuum Hypothesis},
+  publisher = {W. A. Benjamin},
+  year      = 1966
+}
+`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var b bytes.Buffer
+			format.Node(&b, c.work.Entry())
+			if have := b.String(); have != c.want {
+				t.Errorf("have:\n%s\nwant:\n%s", have, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+/*
+Crossref package retrieves the metadata of works registered with Crossref by
+their DOIs or by a bibliographic search, and fills in the fields missing from
+BibTeX entries.
+*/
+package crossref
@@ -0,0 +1,391 @@
+package csl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/citekey"
+	"github.com/mdm-code/bibx/internal/lang"
+	"github.com/mdm-code/bibx/internal/names"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/tex"
+	"github.com/mdm-code/bibx/internal/transform"
+)
+
+// CSL item types of the BibTeX entry types.
+var itemTypes = map[string]string{
+	"article":       "article-journal",
+	"book":          "book",
+	"booklet":       "pamphlet",
+	"inbook":        "chapter",
+	"incollection":  "chapter",
+	"inproceedings": "paper-conference",
+	"conference":    "paper-conference",
+	"manual":        "report",
+	"mastersthesis": "thesis",
+	"phdthesis":     "thesis",
+	"proceedings":   "book",
+	"techreport":    "report",
+	"unpublished":   "manuscript",
+	"online":        "webpage",
+	"misc":          "document",
+}
+
+// BibTeX entry types of the CSL item types. Theses are told apart by their
+// genre, and types missing here become misc entries.
+var entryTypes = map[string]string{
+	"article":           "article",
+	"article-journal":   "article",
+	"article-magazine":  "article",
+	"article-newspaper": "article",
+	"book":              "book",
+	"pamphlet":          "booklet",
+	"chapter":           "incollection",
+	"paper-conference":  "inproceedings",
+	"thesis":            "phdthesis",
+	"report":            "techreport",
+	"manuscript":        "unpublished",
+}
+
+// Item is a single CSL-JSON item. Only the variables with a BibTeX
+// counterpart are kept.
+type Item struct {
+	ID              string `json:"id"`
+	Type            string `json:"type"`
+	CitationKey     string `json:"citation-key,omitempty"`
+	Title           string `json:"title,omitempty"`
+	ContainerTitle  string `json:"container-title,omitempty"`
+	CollectionTitle string `json:"collection-title,omitempty"`
+	Publisher       string `json:"publisher,omitempty"`
+	PublisherPlace  string `json:"publisher-place,omitempty"`
+	Genre           string `json:"genre,omitempty"`
+	Edition         Number `json:"edition,omitempty"`
+	Volume          Number `json:"volume,omitempty"`
+	Issue           Number `json:"issue,omitempty"`
+	Number          Number `json:"number,omitempty"`
+	Page            string `json:"page,omitempty"`
+	DOI             string `json:"DOI,omitempty"`
+	URL             string `json:"URL,omitempty"`
+	ISBN            string `json:"ISBN,omitempty"`
+	ISSN            string `json:"ISSN,omitempty"`
+	Abstract        string `json:"abstract,omitempty"`
+	Note            string `json:"note,omitempty"`
+	Language        string `json:"language,omitempty"`
+	Author          []Name `json:"author,omitempty"`
+	Editor          []Name `json:"editor,omitempty"`
+	Issued          *Date  `json:"issued,omitempty"`
+}
+
+// Name is a personal name split into its parts or, for organizations, a
+// literal name.
+type Name struct {
+	Family   string `json:"family,omitempty"`
+	Given    string `json:"given,omitempty"`
+	Particle string `json:"non-dropping-particle,omitempty"`
+	Suffix   string `json:"suffix,omitempty"`
+	Literal  string `json:"literal,omitempty"`
+}
+
+// Number is a number variable, which CSL-JSON allows to be written both as
+// a string and as a number.
+type Number string
+
+// UnmarshalJSON accepts both strings and numbers.
+func (n *Number) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*n = Number(s)
+		return nil
+	}
+	var v json.Number
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*n = Number(v)
+	return nil
+}
+
+// Date is a possibly partial date given as year, month and day.
+type Date struct {
+	Parts [][]int `json:"date-parts,omitempty"`
+}
+
+// UnmarshalJSON accepts date parts written as strings as well as numbers.
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Parts [][]json.Number `json:"date-parts"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	d.Parts = nil
+	for _, ps := range v.Parts {
+		parts := []int{}
+		for _, p := range ps {
+			n, err := strconv.Atoi(p.String())
+			if err != nil {
+				return fmt.Errorf("csl: invalid date part %q", p)
+			}
+			parts = append(parts, n)
+		}
+		d.Parts = append(d.Parts, parts)
+	}
+	return nil
+}
+
+// Year returns the year of the date or zero if it is unknown.
+func (d *Date) Year() int {
+	if d == nil || len(d.Parts) == 0 || len(d.Parts[0]) == 0 {
+		return 0
+	}
+	return d.Parts[0][0]
+}
+
+// Month returns the month of the date or zero if it is unknown.
+func (d *Date) Month() int {
+	if d == nil || len(d.Parts) == 0 || len(d.Parts[0]) < 2 {
+		return 0
+	}
+	return d.Parts[0][1]
+}
+
+// FromEntry converts the entry into an item identified by the cite key. TeX
+// markup is decoded into plain text, and name lists are split into names.
+func FromEntry(e *parse.EntryDecl) Item {
+	text := func(key string) string {
+		if f, ok := e.Get(key); ok {
+			return tex.Decode(parse.Unquote(f.Value))
+		}
+		return ``
+	}
+	typ := strings.ToLower(e.Name)
+	it := Item{
+		ID:             e.CiteKey,
+		Type:           itemTypes[typ],
+		CitationKey:    e.CiteKey,
+		Title:          text("title"),
+		PublisherPlace: text("address"),
+		Edition:        Number(text("edition")),
+		Volume:         Number(text("volume")),
+		Page:           strings.ReplaceAll(text("pages"), "–", "-"),
+		DOI:            text("doi"),
+		URL:            text("url"),
+		ISBN:           text("isbn"),
+		ISSN:           text("issn"),
+		Abstract:       text("abstract"),
+		Note:           text("note"),
+		Author:         nameList(e, "author"),
+		Editor:         nameList(e, "editor"),
+	}
+	if it.Type == `` {
+		it.Type = "document"
+	}
+	if t, ok := transform.LanguageTag(e); ok {
+		it.Language = t
+	}
+	it.ContainerTitle = text("journal")
+	if it.ContainerTitle == `` {
+		it.ContainerTitle = text("booktitle")
+	}
+	if it.Type == "book" || it.Type == "report" {
+		it.CollectionTitle = text("series")
+	}
+	for _, key := range []string{"publisher", "school", "institution", "organization"} {
+		if it.Publisher = text(key); it.Publisher != `` {
+			break
+		}
+	}
+	switch typ {
+	case "phdthesis":
+		it.Genre = "PhD thesis"
+	case "mastersthesis":
+		it.Genre = "Master's thesis"
+	}
+	if g := text("type"); g != `` {
+		it.Genre = g
+	}
+	if it.Type == "report" {
+		it.Number = Number(text("number"))
+	} else {
+		it.Issue = Number(text("number"))
+	}
+	if year, err := strconv.Atoi(text("year")); err == nil {
+		parts := []int{year}
+		if f, ok := e.Get("month"); ok {
+			if m, ok := transform.ParseMonth(f.Value); ok {
+				parts = append(parts, m)
+			}
+		}
+		it.Issued = &Date{Parts: [][]int{parts}}
+	}
+	return it
+}
+
+// NameList converts the BibTeX name list of the field into names. Names
+// enclosed in braces as a whole are taken literally.
+func nameList(e *parse.EntryDecl, key string) []Name {
+	f, ok := e.Get(key)
+	if !ok {
+		return nil
+	}
+	result := []Name{}
+	for _, n := range names.ParseList(parse.Unquote(f.Value)) {
+		if n.IsOthers() {
+			continue
+		}
+		if n.First == `` && n.Von == `` && n.Jr == `` && strings.HasPrefix(n.Last, "{") && strings.HasSuffix(n.Last, "}") {
+			result = append(result, Name{Literal: tex.Decode(n.Last)})
+			continue
+		}
+		result = append(result, Name{
+			Family:   tex.Decode(n.Last),
+			Given:    tex.Decode(n.First),
+			Particle: tex.Decode(n.Von),
+			Suffix:   tex.Decode(n.Jr),
+		})
+	}
+	return result
+}
+
+// Entry converts the item into an entry with the cite key taken from the
+// citation key of the item, which may be empty. Text is escaped for TeX, and
+// the venue, publisher and number are stored in the fields of the entry type.
+func (it Item) Entry() *parse.EntryDecl {
+	typ, ok := entryTypes[it.Type]
+	if !ok {
+		typ = "misc"
+	}
+	if typ == "phdthesis" && strings.Contains(strings.ToLower(it.Genre), "master") {
+		typ = "mastersthesis"
+	}
+	e := &parse.EntryDecl{Name: typ, CiteKey: it.CitationKey, Comments: &parse.CommentGroupExpr{}}
+	set := func(key, value string) {
+		if value != `` {
+			e.Set(key, parse.Quote(tex.Escape(value)))
+		}
+	}
+	if len(it.Author) > 0 {
+		e.Set("author", parse.Quote(formatNames(it.Author)))
+	}
+	if len(it.Editor) > 0 {
+		e.Set("editor", parse.Quote(formatNames(it.Editor)))
+	}
+	set("title", it.Title)
+	switch typ {
+	case "article":
+		set("journal", it.ContainerTitle)
+	case "incollection", "inproceedings":
+		set("booktitle", it.ContainerTitle)
+	default:
+		set("howpublished", it.ContainerTitle)
+	}
+	switch typ {
+	case "phdthesis", "mastersthesis":
+		set("school", it.Publisher)
+	case "techreport":
+		set("institution", it.Publisher)
+		set("type", it.Genre)
+	default:
+		set("publisher", it.Publisher)
+	}
+	set("address", it.PublisherPlace)
+	set("series", it.CollectionTitle)
+	set("edition", string(it.Edition))
+	set("volume", string(it.Volume))
+	if it.Issue != `` {
+		set("number", string(it.Issue))
+	} else {
+		set("number", string(it.Number))
+	}
+	if it.Page != `` {
+		set("pages", strings.ReplaceAll(strings.ReplaceAll(it.Page, "–", "-"), "-", "--"))
+	}
+	if year := it.Issued.Year(); year != 0 {
+		e.Set("year", strconv.Itoa(year))
+	}
+	if m := it.Issued.Month(); m >= 1 && m <= 12 {
+		e.Set("month", transform.FormatMonth(m, transform.MonthMacro))
+	}
+	set("doi", it.DOI)
+	set("url", it.URL)
+	set("isbn", it.ISBN)
+	set("issn", it.ISSN)
+	set("abstract", it.Abstract)
+	set("note", it.Note)
+	if n, ok := lang.Name(it.Language); ok {
+		e.Set("langid", parse.Quote(n))
+	}
+	return e
+}
+
+// FormatNames joins the names into an escaped BibTeX name list in the `von
+// Last, Jr, First` form. Literal names are enclosed in braces.
+func formatNames(ns []Name) string {
+	result := []string{}
+	for _, n := range ns {
+		if n.Literal != `` {
+			result = append(result, "{"+tex.Escape(n.Literal)+"}")
+			continue
+		}
+		s := tex.Escape(n.Family)
+		if n.Particle != `` {
+			s = tex.Escape(n.Particle) + " " + s
+		}
+		if n.Suffix != `` {
+			s += ", " + tex.Escape(n.Suffix)
+		}
+		if n.Given != `` {
+			s += ", " + tex.Escape(n.Given)
+		}
+		result = append(result, s)
+	}
+	return strings.Join(result, " and ")
+}
+
+// Read converts the CSL-JSON array of items into entries. Items without a
+// citation key are given generated cite keys.
+func Read(r io.Reader) ([]*parse.EntryDecl, error) {
+	var items []Item
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, fmt.Errorf("csl: %w", err)
+	}
+	return Entries(items), nil
+}
+
+// Entries converts the items into entries, generating the missing cite keys
+// so that they differ from each other and from the citation keys.
+func Entries(items []Item) []*parse.EntryDecl {
+	taken := map[string]bool{}
+	for _, it := range items {
+		if it.CitationKey != `` {
+			taken[it.CitationKey] = true
+		}
+	}
+	result := []*parse.EntryDecl{}
+	for _, it := range items {
+		e := it.Entry()
+		if e.CiteKey == `` {
+			e.CiteKey = citekey.Unique(citekey.Generate(e), taken)
+		}
+		result = append(result, e)
+	}
+	return result
+}
+
+// Write writes the entries as a CSL-JSON array of items.
+func Write(w io.Writer, entries []*parse.EntryDecl) error {
+	items := make([]Item, 0, len(entries))
+	for _, e := range entries {
+		items = append(items, FromEntry(e))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent(``, "  ")
+	return enc.Encode(items)
+}
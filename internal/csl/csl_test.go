@@ -0,0 +1,205 @@
+package csl
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestFromEntry(t *testing.T) {
+	e := &parse.EntryDecl{
+		Name:     "article",
+		CiteKey:  "Cohen1963",
+		Comments: &parse.CommentGroupExpr{},
+		Fields: []*parse.FieldStmt{
+			{Key: "author", Value: "{Cohen, Paul J. and de la Vall{\\'e}e Poussin, Charles and {World Health Organization} and others}"},
+			{Key: "title", Value: "{The {Independence} of the Continuum Hypothesis}"},
+			{Key: "journal", Value: "{Proceedings of the National Academy of Sciences}"},
+			{Key: "year", Value: "1963"},
+			{Key: "month", Value: "dec"},
+			{Key: "volume", Value: "50"},
+			{Key: "number", Value: "{6}"},
+			{Key: "pages", Value: "{1143--1148}"},
+			{Key: "doi", Value: "{10.1073/pnas.50.6.1143}"},
+			{Key: "langid", Value: "{american}"},
+		},
+	}
+	want := Item{
+		ID:             "Cohen1963",
+		Type:           "article-journal",
+		CitationKey:    "Cohen1963",
+		Title:          "The Independence of the Continuum Hypothesis",
+		ContainerTitle: "Proceedings of the National Academy of Sciences",
+		Volume:         "50",
+		Issue:          "6",
+		Page:           "1143-1148",
+		DOI:            "10.1073/pnas.50.6.1143",
+		Language:       "en-US",
+		Author: []Name{
+			{Family: "Cohen", Given: "Paul J."},
+			{Family: "Vallée Poussin", Given: "Charles", Particle: "de la"},
+			{Literal: "World Health Organization"},
+		},
+		Issued: &Date{Parts: [][]int{{1963, 12}}},
+	}
+	if have := FromEntry(e); !reflect.DeepEqual(have, want) {
+		t.Errorf("have %+v; want %+v", have, want)
+	}
+}
+
+func TestEntry(t *testing.T) {
+	cases := []struct {
+		name string
+		item Item
+		want string
+	}{
+		{
+			"article",
+			Item{
+				Type:           "article-journal",
+				CitationKey:    "Cohen1963",
+				Title:          "Sets & classes",
+				ContainerTitle: "PNAS",
+				Issue:          "6",
+				Page:           "1143-1148",
+				Author:         []Name{{Family: "Poussin", Given: "Charles", Particle: "de la"}, {Literal: "WHO"}},
+				Issued:         &Date{Parts: [][]int{{1963, 12, 1}}},
+			},
+			"@article{Cohen1963, author = {de la Poussin, Charles and {WHO}}, title = {Sets \\& classes}, journal = {PNAS}, number = {6}, pages = {1143--1148}, year = 1963, month = dec}",
+		},
+		{
+			"masters thesis",
+			Item{Type: "thesis", Genre: "Master's thesis", Title: "T", Publisher: "MIT"},
+			"@mastersthesis{, title = {T}, school = {MIT}}",
+		},
+		{
+			"report",
+			Item{Type: "report", Genre: "Memo", Number: "42", Publisher: "RAND"},
+			"@techreport{, institution = {RAND}, type = {Memo}, number = {42}}",
+		},
+		{
+			"language",
+			Item{Type: "book", Title: "T", Language: "de-DE"},
+			"@book{, title = {T}, langid = {german}}",
+		},
+		{
+			"unknown type",
+			Item{Type: "dataset", ContainerTitle: "Zenodo"},
+			"@misc{, howpublished = {Zenodo}}",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := c.item.Entry()
+			fields := []string{}
+			for _, f := range e.Fields {
+				fields = append(fields, f.Key+" = "+f.Value)
+			}
+			have := "@" + e.Name + "{" + strings.Join(append([]string{e.CiteKey}, fields...), ", ") + "}"
+			if have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	var it Item
+	src := `{"id":"1","type":"book","edition":2,"volume":"3","issued":{"date-parts":[["2001","4"]]}}`
+	if err := json.Unmarshal([]byte(src), &it); err != nil {
+		t.Fatal(err)
+	}
+	if it.Edition != "2" || it.Volume != "3" || it.Issued.Year() != 2001 || it.Issued.Month() != 4 {
+		t.Errorf("have %+v", it)
+	}
+}
+
+func TestRead(t *testing.T) {
+	src := `[
+		{"id":"a","type":"book","title":"First","author":[{"family":"Doe","given":"Jane"}],"issued":{"date-parts":[[2001]]}},
+		{"id":"b","type":"book","title":"Second","author":[{"family":"Doe","given":"John"}],"issued":{"date-parts":[[2001]]}},
+		{"id":"c","type":"book","citation-key":"Doe2001","title":"Third"}
+	]`
+	es, err := Read(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	have := []string{}
+	for _, e := range es {
+		have = append(have, e.CiteKey)
+	}
+	if want := []string{"Doe2001a", "Doe2001b", "Doe2001"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v; want %v", have, want)
+	}
+}
+
+func TestCitations(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			name: "none",
+			src:  `{"pandoc-api-version":[1,23],"meta":{},"blocks":[{"t":"Para","c":[{"t":"Str","c":"Hi"}]}]}`,
+			want: []string{},
+		},
+		{
+			name: "text",
+			src: `{"meta":{},"blocks":[{"t":"Para","c":[
+				{"t":"Cite","c":[[{"citationId":"b","citationMode":{"t":"NormalCitation"}},{"citationId":"a"}],[]]},
+				{"t":"Cite","c":[[{"citationId":"b"}],[]]}]}]}`,
+			want: []string{"b", "a"},
+		},
+		{
+			name: "nocite",
+			src: `{"meta":{"nocite":{"t":"MetaInlines","c":[{"t":"Cite","c":[[{"citationId":"*"}],[]]}]}},
+				"blocks":[{"t":"Para","c":[{"t":"Cite","c":[[{"citationId":"a"}],[]]}]}]}`,
+			want: []string{"*", "a"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, err := ReadDocument(strings.NewReader(c.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have := d.Citations(); !reflect.DeepEqual(have, c.want) {
+				t.Errorf("have %v; want %v", have, c.want)
+			}
+		})
+	}
+}
+
+func TestSetReferences(t *testing.T) {
+	d, err := ReadDocument(strings.NewReader(`{"pandoc-api-version":[1,23],"meta":{},"blocks":[]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := []Item{{ID: "a", Type: "book", Issued: &Date{Parts: [][]int{{2001}}}}}
+	if err := d.SetReferences(items); err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := d.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"blocks":[],"meta":{"references":{"c":[{"c":{"id":{"c":"a","t":"MetaString"},` +
+		`"issued":{"c":{"date-parts":{"c":[{"c":[{"c":"2001","t":"MetaString"}],"t":"MetaList"}],"t":"MetaList"}},"t":"MetaMap"},` +
+		`"type":{"c":"book","t":"MetaString"}},"t":"MetaMap"}],"t":"MetaList"}},"pandoc-api-version":[1,23]}` + "\n"
+	if have := b.String(); have != want {
+		t.Errorf("have %s; want %s", have, want)
+	}
+}
+
+func TestReadDocument(t *testing.T) {
+	if _, err := ReadDocument(strings.NewReader(`[]`)); err == nil {
+		t.Error("have nil; want error")
+	}
+	if _, err := ReadDocument(strings.NewReader(`{"meta":{}}`)); err == nil {
+		t.Error("have nil; want error")
+	}
+}
@@ -0,0 +1,6 @@
+/*
+Csl package maps BibTeX entries onto CSL-JSON items, the bibliographic data
+format of the Citation Style Language used by Zotero, Mendeley and Pandoc
+among others, and back.
+*/
+package csl
@@ -0,0 +1,117 @@
+package csl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Document is the JSON AST of a pandoc document as a JSON filter receives it
+// on stdin. Only the metadata is looked into, the rest is passed through.
+type Document map[string]any
+
+// ReadDocument decodes the JSON AST of a pandoc document.
+func ReadDocument(r io.Reader) (Document, error) {
+	var d Document
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(&d); err != nil {
+		return nil, err
+	}
+	if _, ok := d["blocks"]; !ok {
+		return nil, fmt.Errorf("not a pandoc document: no blocks")
+	}
+	return d, nil
+}
+
+// Citations returns the ids of the citations of the document, both in its
+// text and in its metadata such as nocite, in the order they are first
+// cited. The id `*` stands for all the items of the bibliography.
+func (d Document) Citations() []string {
+	result := []string{}
+	seen := map[string]bool{}
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if v["t"] == "Cite" {
+				if c, ok := v["c"].([]any); ok && len(c) > 0 {
+					cites, _ := c[0].([]any)
+					for _, cite := range cites {
+						m, _ := cite.(map[string]any)
+						id, _ := m["citationId"].(string)
+						if id != `` && !seen[id] {
+							seen[id] = true
+							result = append(result, id)
+						}
+					}
+				}
+			}
+			for _, w := range v {
+				walk(w)
+			}
+		case []any:
+			for _, w := range v {
+				walk(w)
+			}
+		}
+	}
+	// Map iteration order is random, so the parts are walked in turn.
+	walk(d["meta"])
+	walk(d["blocks"])
+	return result
+}
+
+// SetReferences stores the items in the references field of the metadata,
+// where citeproc looks them up when the document names no bibliography.
+func (d Document) SetReferences(items []Item) error {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	meta, ok := d["meta"].(map[string]any)
+	if !ok {
+		meta = map[string]any{}
+		d["meta"] = meta
+	}
+	meta["references"] = metaValue(v)
+	return nil
+}
+
+// Write encodes the document as JSON AST.
+func (d Document) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(d)
+}
+
+// MetaValue converts the decoded JSON value into a pandoc metadata value.
+// Numbers become strings as they do when pandoc reads YAML metadata.
+func metaValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, w := range v {
+			m[k] = metaValue(w)
+		}
+		return map[string]any{"t": "MetaMap", "c": m}
+	case []any:
+		l := make([]any, 0, len(v))
+		for _, w := range v {
+			l = append(l, metaValue(w))
+		}
+		return map[string]any{"t": "MetaList", "c": l}
+	case bool:
+		return map[string]any{"t": "MetaBool", "c": v}
+	case float64:
+		return map[string]any{"t": "MetaString", "c": strconv.FormatFloat(v, 'f', -1, 64)}
+	case string:
+		return map[string]any{"t": "MetaString", "c": v}
+	}
+	return map[string]any{"t": "MetaString", "c": ``}
+}
@@ -0,0 +1,179 @@
+package dblp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/citekey"
+	"github.com/mdm-code/bibx/internal/pages"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/remote"
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+// DefaultEndpoint is the publication search endpoint of the dblp API.
+const DefaultEndpoint = "https://dblp.org/search/publ/api"
+
+// Homonym matches the number dblp appends to the names of different people
+// sharing a name, as in Wei Wang 0001.
+var homonym = regexp.MustCompile(`\s+\d{4}$`)
+
+// Entry types of the dblp publication types along with the fields naming
+// the venue.
+var types = map[string][2]string{
+	"Journal Articles":                {"article", "journal"},
+	"Conference and Workshop Papers":  {"inproceedings", "booktitle"},
+	"Parts in Books or Collections":   {"incollection", "booktitle"},
+	"Books and Theses":                {"book", ``},
+	"Editorship":                      {"proceedings", ``},
+	"Informal and Other Publications": {"misc", "howpublished"},
+}
+
+// Hit is a publication found by a search.
+type Hit struct {
+	Key     string  `json:"key"`
+	Type    string  `json:"type"`
+	Title   string  `json:"title"`
+	Authors Authors `json:"authors"`
+	Venue   string  `json:"venue"`
+	Volume  string  `json:"volume"`
+	Number  string  `json:"number"`
+	Pages   string  `json:"pages"`
+	Year    string  `json:"year"`
+	DOI     string  `json:"doi"`
+	URL     string  `json:"url"`
+}
+
+// Authors are the names of the authors of a publication.
+type Authors []string
+
+// UnmarshalJSON decodes the author list, which dblp gives as a single object
+// rather than an array for publications with one author.
+func (a *Authors) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Author json.RawMessage `json:"author"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	type author struct {
+		Text string `json:"text"`
+	}
+	list := []author{}
+	if err := json.Unmarshal(v.Author, &list); err != nil {
+		var one author
+		if err := json.Unmarshal(v.Author, &one); err != nil {
+			return err
+		}
+		list = append(list, one)
+	}
+	*a = Authors{}
+	for _, au := range list {
+		*a = append(*a, homonym.ReplaceAllString(au.Text, ``))
+	}
+	return nil
+}
+
+// Client searches dblp. Failed requests are retried as the Retry policy
+// tells.
+type Client struct {
+	HTTP     *http.Client
+	Endpoint string
+	Retry    remote.Policy
+}
+
+// Search returns at most n publications best matching the query.
+func (c *Client) Search(ctx context.Context, query string, n int) ([]*Hit, error) {
+	endpoint := c.Endpoint
+	if endpoint == `` {
+		endpoint = DefaultEndpoint
+	}
+	q := url.Values{"q": {query}, "format": {"json"}, "h": {fmt.Sprint(n)}}
+	req, err := http.NewRequest(http.MethodGet, endpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "bibx (https://github.com/mdm-code/bibx)")
+	resp, err := remote.Do(ctx, c.HTTP, c.Retry, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("dblp: unexpected response status %s", resp.Status)
+	}
+	return Read(resp.Body)
+}
+
+// Read decodes the publications of a response of the search endpoint.
+func Read(r io.Reader) ([]*Hit, error) {
+	var resp struct {
+		Result struct {
+			Hits struct {
+				Hit []struct {
+					Info *Hit `json:"info"`
+				} `json:"hit"`
+			} `json:"hits"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(r).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("dblp: %w", err)
+	}
+	result := []*Hit{}
+	for _, h := range resp.Result.Hits.Hit {
+		if h.Info != nil {
+			result = append(result, h.Info)
+		}
+	}
+	return result, nil
+}
+
+// Entry returns a new entry of the publication with a generated cite key.
+func (h *Hit) Entry() *parse.EntryDecl {
+	e := &parse.EntryDecl{Name: "misc", Comments: &parse.CommentGroupExpr{}}
+	set := func(key, value string) {
+		if value = strings.Join(strings.Fields(html.UnescapeString(value)), " "); value != `` {
+			e.Set(key, parse.Quote(tex.Escape(value)))
+		}
+	}
+	if len(h.Authors) > 0 {
+		list := make([]string, len(h.Authors))
+		for i, a := range h.Authors {
+			list[i] = tex.Escape(a)
+		}
+		e.Set("author", parse.Quote(strings.Join(list, " and ")))
+	}
+	// Titles in dblp end with a period.
+	set("title", strings.TrimSuffix(h.Title, "."))
+	if t, ok := types[h.Type]; ok {
+		e.Name = t[0]
+		if t[1] != `` {
+			set(t[1], h.Venue)
+		}
+	}
+	set("volume", h.Volume)
+	set("number", h.Number)
+	if p, err := pages.Normalize(h.Pages); err == nil {
+		e.Set("pages", parse.Quote(p))
+	} else {
+		set("pages", h.Pages)
+	}
+	if h.Year != `` {
+		e.Set("year", h.Year)
+	}
+	if h.DOI != `` {
+		e.Set("doi", parse.Quote(strings.ToLower(h.DOI)))
+	}
+	if h.URL != `` {
+		e.Set("biburl", parse.Quote(h.URL))
+	}
+	e.CiteKey = citekey.Generate(e)
+	return e
+}
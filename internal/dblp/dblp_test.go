@@ -0,0 +1,116 @@
+package dblp
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/format"
+)
+
+const testResponse = `{"result": {"hits": {"@total": "2", "hit": [
+  {"info": {
+    "authors": {"author": [{"@pid": "1", "text": "Ashish Vaswani"}, {"@pid": "2", "text": "Wei Wang 0001"}]},
+    "title": "Attention is All you Need.",
+    "venue": "NeurIPS",
+    "pages": "5998-6008",
+    "year": "2017",
+    "type": "Conference and Workshop Papers",
+    "key": "conf/nips/VaswaniSPUJGKP17",
+    "url": "https://dblp.org/rec/conf/nips/VaswaniSPUJGKP17"
+  }},
+  {"info": {
+    "authors": {"author": {"@pid": "3", "text": "Paul J. Cohen"}},
+    "title": "The Independence of the Continuum Hypothesis.",
+    "venue": "Proc. Natl. Acad. Sci. USA",
+    "volume": "50",
+    "number": "6",
+    "pages": "1143-1148",
+    "year": "1963",
+    "type": "Journal Articles",
+    "key": "journals/pnas/Cohen63",
+    "doi": "10.1073/PNAS.50.6.1143",
+    "url": "https://dblp.org/rec/journals/pnas/Cohen63"
+  }}
+]}}}`
+
+func TestRead(t *testing.T) {
+	have, err := Read(strings.NewReader(testResponse))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(have) != 2 {
+		t.Fatalf("have %d hits; want 2", len(have))
+	}
+	if want := (Authors{"Ashish Vaswani", "Wei Wang"}); !reflect.DeepEqual(have[0].Authors, want) {
+		t.Errorf("have %v; want %v", have[0].Authors, want)
+	}
+	if want := (Authors{"Paul J. Cohen"}); !reflect.DeepEqual(have[1].Authors, want) {
+		t.Errorf("have %v; want %v", have[1].Authors, want)
+	}
+	empty, err := Read(strings.NewReader(`{"result": {"hits": {"@total": "0"}}}`))
+	if err != nil || len(empty) != 0 {
+		t.Errorf("have %v, %v; want no hits", empty, err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("q") != "continuum hypothesis" || q.Get("format") != "json" || q.Get("h") != "5" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(testResponse))
+	}))
+	defer ts.Close()
+	c := Client{Endpoint: ts.URL}
+	have, err := c.Search(context.Background(), "continuum hypothesis", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(have) != 2 || have[1].Key != "journals/pnas/Cohen63" {
+		t.Errorf("have %+v; want the two hits", have)
+	}
+	if _, err := c.Search(context.Background(), "other", 5); err == nil {
+		t.Error("want an error for an unexpected status")
+	}
+}
+
+func TestEntry(t *testing.T) {
+	hits, err := Read(strings.NewReader(testResponse))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`@inproceedings{Vaswani2017,
+  author    = {Ashish Vaswani and Wei Wang},
+  title     = {Attention is All you Need},
+  booktitle = {NeurIPS},
+  pages     = {5998--6008},
+  year      = 2017,
+  biburl    = {https://dblp.org/rec/conf/nips/VaswaniSPUJGKP17}
+}
+`, `@article{Cohen1963,
+  author  = {Paul J. Cohen},
+  title   = {The Independence of the Continuum Hypothesis},
+  journal = {Proc. Natl. Acad. Sci. USA},
+  volume  = {50},
+  number  = {6},
+  pages   = {1143--1148},
+  year    = 1963,
+  doi     = {10.1073/pnas.50.6.1143},
+  biburl  = {https://dblp.org/rec/journals/pnas/Cohen63}
+}
+`}
+	for i, h := range hits {
+		var b bytes.Buffer
+		format.Node(&b, h.Entry())
+		if have := b.String(); have != want[i] {
+			t.Errorf("have:\n%s\nwant:\n%s", have, want[i])
+		}
+	}
+}
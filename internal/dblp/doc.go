@@ -0,0 +1,5 @@
+/*
+Dblp package searches the dblp computer science bibliography for
+publications and converts them into BibTeX entries.
+*/
+package dblp
@@ -0,0 +1,208 @@
+package dedupe
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mdm-code/bibx/internal/names"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+// NameFields are the fields holding the name lists searched for variants.
+var NameFields = []string{"author", "editor"}
+
+// Variant is one way the name of an author is written, along with the keys
+// of the entries it is written so in.
+type Variant struct {
+	Text string
+	Name names.Name
+	Keys []string
+}
+
+// NameCluster groups the variants that probably name the same author. The
+// first variant is the canonical one: the most complete and, of the equally
+// complete, the most used.
+type NameCluster []Variant
+
+// Canonical returns the text of the canonical variant.
+func (c NameCluster) Canonical() string { return c[0].Text }
+
+// NameVariants clusters the names written in more than one way across the
+// entries, such as `J. Smith`, `John Smith` and `Smith, J.`. Names are
+// clustered when their von and last parts match, ignoring case and
+// diacritics, and their first names agree, one initial standing in for any
+// name starting with it. An abbreviated name fitting the full names of
+// several authors is left out, as it cannot be told whose it is.
+func NameVariants(entries []*parse.EntryDecl) []NameCluster {
+	byText := map[string]*Variant{}
+	groups := map[string][]*Variant{}
+	order := []string{}
+	for _, e := range entries {
+		for _, key := range NameFields {
+			f, ok := e.Get(key)
+			if !ok {
+				continue
+			}
+			for _, s := range names.Split(parse.Unquote(f.Value)) {
+				text := strings.Join(strings.Fields(s), " ")
+				if v, ok := byText[text]; ok {
+					if v.Keys[len(v.Keys)-1] != e.CiteKey {
+						v.Keys = append(v.Keys, e.CiteKey)
+					}
+					continue
+				}
+				n := names.Parse(text)
+				g := nameGroup(n)
+				if n.IsOthers() || g == `` {
+					continue
+				}
+				v := &Variant{Text: text, Name: n, Keys: []string{e.CiteKey}}
+				byText[text] = v
+				if _, ok := groups[g]; !ok {
+					order = append(order, g)
+				}
+				groups[g] = append(groups[g], v)
+			}
+		}
+	}
+	sort.Strings(order)
+	result := []NameCluster{}
+	for _, g := range order {
+		for _, c := range clusterNames(groups[g]) {
+			if len(c) > 1 {
+				result = append(result, c)
+			}
+		}
+	}
+	return result
+}
+
+// ClusterNames splits the variants sharing their last name and first
+// initial into clusters, attaching each variant, from the most complete
+// down, to the one cluster all of whose variants agree with it.
+func clusterNames(vs []*Variant) []NameCluster {
+	sort.SliceStable(vs, func(i, j int) bool {
+		ci, cj := completeness(vs[i].Name), completeness(vs[j].Name)
+		if ci != cj {
+			return ci > cj
+		}
+		return len(vs[i].Keys) > len(vs[j].Keys)
+	})
+	result := []NameCluster{}
+	for _, v := range vs {
+		match := -1
+		for i, c := range result {
+			if !agreesWith(c, v.Name) {
+				continue
+			}
+			if match >= 0 {
+				match = -2
+				break
+			}
+			match = i
+		}
+		switch match {
+		case -1:
+			result = append(result, NameCluster{*v})
+		case -2:
+			// Ambiguous.
+		default:
+			result[match] = append(result[match], *v)
+		}
+	}
+	return result
+}
+
+func agreesWith(c NameCluster, n names.Name) bool {
+	for _, v := range c {
+		if !agree(v.Name, n) {
+			return false
+		}
+	}
+	return true
+}
+
+// NameGroup returns the folded von and last parts of the name and the
+// initial of its first name, or nothing for names without a first name.
+func nameGroup(n names.Name) string {
+	first := givenNames(n)
+	if len(first) == 0 {
+		return ``
+	}
+	r, _ := utf8.DecodeRuneInString(first[0])
+	return fold(n.Von+" "+n.Last) + "|" + string(r)
+}
+
+// Agree tells if the names may be the same: their suffixes match, and each
+// given name matches the one in the same place of the
+// other name or is its initial. Missing middle names are ignored.
+func agree(a, b names.Name) bool {
+	if fold(a.Jr) != fold(b.Jr) {
+		return false
+	}
+	fa, fb := givenNames(a), givenNames(b)
+	for i := 0; i < len(fa) && i < len(fb); i++ {
+		x, y := fa[i], fb[i]
+		if x == y {
+			continue
+		}
+		if utf8.RuneCountInString(x) > 1 && utf8.RuneCountInString(y) > 1 {
+			return false
+		}
+		if !strings.HasPrefix(x, y) && !strings.HasPrefix(y, x) {
+			return false
+		}
+	}
+	return true
+}
+
+// Completeness scores how fully the name is spelled out: the letters of
+// its given names, with initials counting as one.
+func completeness(n names.Name) int {
+	result := 0
+	for _, w := range givenNames(n) {
+		result += utf8.RuneCountInString(w)
+	}
+	return result
+}
+
+// GivenNames returns the folded words of the first names, with initials
+// such as `J.-P.` split into single letters.
+func givenNames(n names.Name) []string {
+	return strings.FieldsFunc(fold(n.First), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+}
+
+func fold(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(tex.Fold(tex.Decode(s)))), " ")
+}
+
+// UnifyNames rewrites the names of the entries written as the keys of the
+// map as their values, and returns the number of names rewritten.
+func UnifyNames(entries []*parse.EntryDecl, canonical map[string]string) int {
+	count := 0
+	for _, e := range entries {
+		for _, key := range NameFields {
+			f, ok := e.Get(key)
+			if !ok {
+				continue
+			}
+			list := names.Split(parse.Unquote(f.Value))
+			changed := false
+			for i, s := range list {
+				if c, ok := canonical[strings.Join(strings.Fields(s), " ")]; ok {
+					list[i], changed = c, true
+					count++
+				}
+			}
+			if changed {
+				f.Value = parse.Quote(strings.Join(list, " and "))
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,96 @@
+package dedupe
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/bibxtest"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestNameVariants(t *testing.T) {
+	cases := []struct {
+		name    string
+		entries []*parse.EntryDecl
+		want    []string
+	}{
+		{
+			name: "variants",
+			entries: []*parse.EntryDecl{
+				bibxtest.Entry("article", "a", "author", "{J. Smith and Doe, Jane}"),
+				bibxtest.Entry("article", "b", "author", "{John Smith and others}"),
+				bibxtest.Entry("article", "c", "author", `{Smith, J. and D{\"o}e, J.}`, "editor", "{John Smith}"),
+				bibxtest.Entry("article", "d", "author", "{Jane Doe}"),
+			},
+			want: []string{
+				`Doe, Jane [a] | Jane Doe [d] | D{\"o}e, J. [c]`,
+				`John Smith [b c] | J. Smith [a] | Smith, J. [c]`,
+			},
+		},
+		{
+			name: "middle-names",
+			entries: []*parse.EntryDecl{
+				bibxtest.Entry("article", "a", "author", "{John R. Smith}"),
+				bibxtest.Entry("article", "b", "author", "{Smith, John}"),
+				bibxtest.Entry("article", "c", "author", "{J. Q. Smith}"),
+			},
+			want: []string{
+				`John R. Smith [a] | Smith, John [b]`,
+			},
+		},
+		{
+			name: "ambiguous",
+			entries: []*parse.EntryDecl{
+				bibxtest.Entry("article", "a", "author", "{John Smith and Jane Smith}"),
+				bibxtest.Entry("article", "b", "author", "{J. Smith}"),
+			},
+			want: []string{},
+		},
+		{
+			name: "distinct",
+			entries: []*parse.EntryDecl{
+				bibxtest.Entry("article", "a", "author", "{John Smith and Smith, Jr., John}"),
+				bibxtest.Entry("article", "b", "author", "{Smith and Adam Smith}"),
+			},
+			want: []string{},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			have := []string{}
+			for _, cl := range NameVariants(c.entries) {
+				vs := []string{}
+				for _, v := range cl {
+					vs = append(vs, v.Text+" ["+strings.Join(v.Keys, " ")+"]")
+				}
+				have = append(have, strings.Join(vs, " | "))
+			}
+			if !reflect.DeepEqual(have, c.want) {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
+
+func TestUnifyNames(t *testing.T) {
+	es := []*parse.EntryDecl{
+		bibxtest.Entry("article", "a", "author", `"J. Smith and Doe, Jane"`, "editor", "{Smith,  J.}"),
+		bibxtest.Entry("article", "b", "author", "{John Smith}"),
+	}
+	n := UnifyNames(es, map[string]string{"J. Smith": "John Smith", "Smith, J.": "John Smith"})
+	if n != 2 {
+		t.Errorf("have %d names rewritten; want 2", n)
+	}
+	have := []string{}
+	for _, e := range es {
+		have = append(have, e.String())
+	}
+	want := []string{
+		"@article{a, author = {John Smith and Doe, Jane}, editor = {John Smith}}",
+		"@article{b, author = {John Smith}}",
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have %q; want %q", have, want)
+	}
+}
@@ -0,0 +1,6 @@
+/*
+Dedupe package finds entries that are likely to describe the same work under
+different cite keys, and names that are likely to be those of the same author
+written in different ways.
+*/
+package dedupe
@@ -0,0 +1,51 @@
+package dedupe
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// Fingerprint returns a stable SHA-256 hash of the entry content as a hex
+// string. The entry type is lower-cased, field names replaced with their
+// parse.CanonicalKey, field values canonicalized with parse.CanonicalValue,
+// and fields sorted, so entries equivalent up to layout share the
+// fingerprint. The cite key is left out to
+// detect the same content listed under different keys.
+func Fingerprint(e *parse.EntryDecl) string {
+	lines := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		lines = append(lines, parse.CanonicalKey(f.Key)+"="+parse.CanonicalValue(f.Value))
+	}
+	sort.Strings(lines)
+	h := sha256.New()
+	h.Write([]byte(strings.ToLower(e.Name) + "\n"))
+	for _, l := range lines {
+		h.Write([]byte(l + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Exact groups the entries with the same fingerprint. Only groups of two or
+// more entries are returned, in the order of their first entries.
+func Exact(entries []*parse.EntryDecl) [][]*parse.EntryDecl {
+	groups := make(map[string][]*parse.EntryDecl)
+	order := []string{}
+	for _, e := range entries {
+		fp := Fingerprint(e)
+		if _, ok := groups[fp]; !ok {
+			order = append(order, fp)
+		}
+		groups[fp] = append(groups[fp], e)
+	}
+	result := [][]*parse.EntryDecl{}
+	for _, fp := range order {
+		if len(groups[fp]) > 1 {
+			result = append(result, groups[fp])
+		}
+	}
+	return result
+}
@@ -0,0 +1,58 @@
+package dedupe
+
+import (
+	"testing"
+
+	"github.com/mdm-code/bibx/bibxtest"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestFingerprint(t *testing.T) {
+	a := bibxtest.Entry("article", "a", "title", "{On Sets}", "year", "1963")
+	cases := []struct {
+		name  string
+		other *parse.EntryDecl
+		same  bool
+	}{
+		{"layout", bibxtest.Entry("article", "b", "YEAR", `"1963"`, "title", "{On\n  Sets }"), true},
+		{"value", bibxtest.Entry("article", "a", "title", "{On sets}", "year", "1963"), false},
+		{"field", bibxtest.Entry("article", "a", "title", "{On Sets}", "year", "1963", "note", "{x}"), false},
+		{"type", &parse.EntryDecl{Name: "book", CiteKey: "a", Fields: a.Fields}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := Fingerprint(a) == Fingerprint(c.other); have != c.same {
+				t.Errorf("have %v; want %v", have, c.same)
+			}
+		})
+	}
+}
+
+func TestFingerprintStable(t *testing.T) {
+	want := "ea06a798"
+	if have := Fingerprint(bibxtest.Entry("article", "a", "title", "{On Sets}"))[:8]; have != want {
+		t.Errorf("have %s; want %s", have, want)
+	}
+}
+
+func TestExact(t *testing.T) {
+	entries := []*parse.EntryDecl{
+		bibxtest.Entry("article", "a", "title", "{On Sets}"),
+		bibxtest.Entry("article", "b", "title", "{Other}"),
+		bibxtest.Entry("article", "c", "title", `"On Sets"`),
+		bibxtest.Entry("article", "d", "title", "{Other}"),
+		bibxtest.Entry("article", "e", "title", "{Unique}"),
+	}
+	have := Exact(entries)
+	want := [][]string{{"a", "c"}, {"b", "d"}}
+	if len(have) != len(want) {
+		t.Fatalf("have %d groups; want %d", len(have), len(want))
+	}
+	for i, g := range have {
+		for j, e := range g {
+			if e.CiteKey != want[i][j] {
+				t.Errorf("have %s; want %s", e.CiteKey, want[i][j])
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package dedupe
+
+import (
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// Field pairs the values of a field in two duplicate entries as written in
+// the source, either of them empty when its entry lacks the field.
+type Field struct {
+	Key  string
+	A, B string
+}
+
+// Conflict reports whether both entries hold the field with values differing
+// other than in the delimiters and white space.
+func (f Field) Conflict() bool {
+	return f.A != `` && f.B != `` && parse.CanonicalValue(f.A) != parse.CanonicalValue(f.B)
+}
+
+// Fields pairs the fields of the entries, listing the fields of a in their
+// order followed by the fields found in b only. Field names are compared
+// regardless of case.
+func Fields(a, b *parse.EntryDecl) []Field {
+	result := []Field{}
+	seen := map[string]bool{}
+	for _, f := range a.Fields {
+		key := strings.ToLower(f.Key)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		pair := Field{Key: f.Key, A: f.Value}
+		if g, ok := b.Get(key); ok {
+			pair.B = g.Value
+		}
+		result = append(result, pair)
+	}
+	for _, f := range b.Fields {
+		if key := strings.ToLower(f.Key); !seen[key] {
+			seen[key] = true
+			result = append(result, Field{Key: f.Key, B: f.Value})
+		}
+	}
+	return result
+}
+
+// Merge combines the duplicate entries into one of the type of a with the
+// cite key. Fields held by one entry only or by both with the same value are
+// kept, and pick chooses the value of each conflicting field, which is left
+// out when pick returns nothing.
+func Merge(a, b *parse.EntryDecl, key string, pick func(Field) string) *parse.EntryDecl {
+	result := &parse.EntryDecl{Name: a.Name, CiteKey: key, Comments: a.Comments, Pos: a.Pos}
+	if result.Comments == nil {
+		result.Comments = &parse.CommentGroupExpr{}
+	}
+	for _, f := range Fields(a, b) {
+		v := f.A
+		switch {
+		case f.Conflict():
+			v = pick(f)
+		case v == ``:
+			v = f.B
+		}
+		if v != `` {
+			result.Fields = append(result.Fields, &parse.FieldStmt{Key: f.Key, Value: v})
+		}
+	}
+	return result
+}
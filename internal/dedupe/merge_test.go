@@ -0,0 +1,42 @@
+package dedupe
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/bibxtest"
+)
+
+func TestMerge(t *testing.T) {
+	a := bibxtest.Entry("article", "Smith2001", "title", "{A Paper}", "year", "2001", "pages", "{1--2}")
+	b := bibxtest.Entry("article", "Smith01", "Title", `"A  Paper"`, "year", "2002", "doi", "{10.1/x}")
+	fields := Fields(a, b)
+	conflicts := []string{}
+	for _, f := range fields {
+		if f.Conflict() {
+			conflicts = append(conflicts, f.Key)
+		}
+	}
+	if have, want := len(fields), 4; have != want {
+		t.Errorf("have %d fields; want %d", have, want)
+	}
+	if have, want := strings.Join(conflicts, ","), "year"; have != want {
+		t.Errorf("have conflicts %s; want %s", have, want)
+	}
+	cases := []struct {
+		name string
+		pick func(Field) string
+		want string
+	}{
+		{"first", func(f Field) string { return f.A }, "@article{Smith01, title = {A Paper}, year = 2001, pages = {1--2}, doi = {10.1/x}}"},
+		{"second", func(f Field) string { return f.B }, "@article{Smith01, title = {A Paper}, year = 2002, pages = {1--2}, doi = {10.1/x}}"},
+		{"none", func(Field) string { return `` }, "@article{Smith01, title = {A Paper}, pages = {1--2}, doi = {10.1/x}}"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := Merge(a, b, "Smith01", c.pick).String(); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package dedupe
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mdm-code/bibx/internal/names"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+// Weights of the title, author and year similarities in the score. Missing
+// components are left out and the remaining weights rescaled.
+const (
+	titleWeight  = 0.6
+	authorWeight = 0.25
+	yearWeight   = 0.15
+)
+
+// Pair is a candidate pair of near-duplicate entries with their similarity
+// score between 0 and 1.
+type Pair struct {
+	A, B  *parse.EntryDecl
+	Score float64
+}
+
+// Score rates the similarity of the entries between 0 and 1 by comparing
+// their titles, author last names and years. Titles are compared after
+// decoding TeX, folding diacritics and dropping punctuation, taking the
+// better of the normalized Levenshtein similarity and the token overlap, so
+// that truncated titles still match.
+func Score(a, b *parse.EntryDecl) float64 {
+	var sum, weights float64
+	if ta, tb := titleWords(a), titleWords(b); len(ta) > 0 && len(tb) > 0 {
+		sum += titleWeight * titleSimilarity(ta, tb)
+		weights += titleWeight
+	}
+	if aa, ab := lastNames(a), lastNames(b); len(aa) > 0 && len(ab) > 0 {
+		sum += authorWeight * jaccard(aa, ab)
+		weights += authorWeight
+	}
+	if ya, yb := field(a, "year"), field(b, "year"); ya != `` && yb != `` {
+		sum += yearWeight * yearSimilarity(ya, yb)
+		weights += yearWeight
+	}
+	if weights == 0 {
+		return 0
+	}
+	return sum / weights
+}
+
+// Candidates returns the pairs of entries scoring at least the threshold,
+// the most similar first. Pairs with equal scores keep the order of the
+// entries.
+func Candidates(entries []*parse.EntryDecl, threshold float64) []Pair {
+	result := []Pair{}
+	for i := range entries {
+		for j := i + 1; j < len(entries); j++ {
+			if s := Score(entries[i], entries[j]); s >= threshold {
+				result = append(result, Pair{entries[i], entries[j], s})
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Score > result[j].Score })
+	return result
+}
+
+// Unrelated is the highest score of entries that both name authors but
+// share none of them, so that above it only entries sharing an author or
+// naming none can be candidates.
+const Unrelated = titleWeight + yearWeight
+
+// Related returns the candidates among the entries like Candidates, scoring
+// each entry only against the entries near returns for it rather than
+// against every other entry. Above Unrelated, near must return at least the
+// entries sharing an author last name with the entry and those naming no
+// authors, or all of them when the entry names none.
+func Related(entries []*parse.EntryDecl, threshold float64, near func(*parse.EntryDecl) []*parse.EntryDecl) []Pair {
+	index := make(map[*parse.EntryDecl]int, len(entries))
+	for i, e := range entries {
+		index[e] = i
+	}
+	pairs := [][2]int{}
+	seen := make(map[[2]int]bool)
+	for i, e := range entries {
+		for _, r := range near(e) {
+			j, ok := index[r]
+			if !ok || j == i {
+				continue
+			}
+			p := [2]int{i, j}
+			if j < i {
+				p = [2]int{j, i}
+			}
+			if !seen[p] {
+				seen[p] = true
+				pairs = append(pairs, p)
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	result := []Pair{}
+	for _, p := range pairs {
+		a, b := entries[p[0]], entries[p[1]]
+		if s := Score(a, b); s >= threshold {
+			result = append(result, Pair{a, b, s})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Score > result[j].Score })
+	return result
+}
+
+// HasAuthors tells if the entry names authors, or editors in the absence of
+// authors, that Score compares.
+func HasAuthors(e *parse.EntryDecl) bool {
+	return len(lastNames(e)) > 0
+}
+
+func titleSimilarity(a, b []string) float64 {
+	lev := levenshteinSimilarity(strings.Join(a, " "), strings.Join(b, " "))
+	if o := overlap(a, b); o > lev {
+		return o
+	}
+	return lev
+}
+
+// Overlap is the share of the words of the shorter title found in the longer
+// one. Titles of fewer than three words are compared with the Jaccard index
+// instead since a single shared word says little.
+func overlap(a, b []string) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(a) < 3 {
+		return jaccard(a, b)
+	}
+	set := make(map[string]bool)
+	for _, w := range b {
+		set[w] = true
+	}
+	n := 0
+	for _, w := range a {
+		if set[w] {
+			n++
+		}
+	}
+	return float64(n) / float64(len(a))
+}
+
+func jaccard(a, b []string) float64 {
+	set := make(map[string]int)
+	for _, w := range a {
+		set[w] |= 1
+	}
+	for _, w := range b {
+		set[w] |= 2
+	}
+	both := 0
+	for _, v := range set {
+		if v == 3 {
+			both++
+		}
+	}
+	return float64(both) / float64(len(set))
+}
+
+func levenshteinSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func yearSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ya, errA := strconv.Atoi(a)
+	yb, errB := strconv.Atoi(b)
+	if errA == nil && errB == nil && (ya-yb == 1 || yb-ya == 1) {
+		// Preprints and their published versions are often a year apart.
+		return 0.5
+	}
+	return 0
+}
+
+func titleWords(e *parse.EntryDecl) []string {
+	return words(field(e, "title"))
+}
+
+func lastNames(e *parse.EntryDecl) []string {
+	list := ``
+	if f, ok := e.Get("author"); ok {
+		list = parse.Unquote(f.Value)
+	} else if f, ok := e.Get("editor"); ok {
+		list = parse.Unquote(f.Value)
+	}
+	result := []string{}
+	for _, n := range names.ParseList(list) {
+		if !n.IsOthers() {
+			result = append(result, strings.Join(words(tex.Decode(n.Last)), ``))
+		}
+	}
+	return result
+}
+
+// Words folds the text to lower-case ASCII and splits it into words made of
+// letters and digits.
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(tex.Fold(s)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func field(e *parse.EntryDecl, key string) string {
+	if f, ok := e.Get(key); ok {
+		return strings.TrimSpace(tex.Decode(parse.Unquote(f.Value)))
+	}
+	return ``
+}
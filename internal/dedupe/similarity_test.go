@@ -0,0 +1,101 @@
+package dedupe
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/mdm-code/bibx/bibxtest"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+var (
+	cohen = bibxtest.Entry("article", "Cohen1963",
+		"author", "{Cohen, Paul J.}",
+		"title", "{The Independence of the Continuum Hypothesis}",
+		"year", "1963",
+	)
+	cohenPunct = bibxtest.Entry("article", "cohen63",
+		"author", "{Paul J. Cohen}",
+		"title", "{The independence of the continuum hypothesis.}",
+		"year", "1963",
+	)
+	cohenTruncated = bibxtest.Entry("article", "cohen-ch",
+		"author", "{P. Cohen}",
+		"title", "{Independence of the Continuum}",
+		"year", "1964",
+	)
+	godel = bibxtest.Entry("article", "Godel1931",
+		"author", `{G{\"o}del, Kurt}`,
+		"title", "{On formally undecidable propositions}",
+		"year", "1931",
+	)
+	goedel = bibxtest.Entry("article", "Goedel1931",
+		"author", "{Kurt Gödel}",
+		"title", "{On Formally Undecidable Propositions}",
+		"year", "1931",
+	)
+)
+
+func TestScore(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b *parse.EntryDecl
+		want float64
+	}{
+		{"identical", cohen, cohen, 1},
+		{"punctuation and case", cohen, cohenPunct, 1},
+		{"tex and unicode", godel, goedel, 1},
+		{"truncated", cohen, cohenTruncated, 0.925},
+		{"different", cohen, godel, 0.082},
+		{"empty", bibxtest.Entry("article", "a"), bibxtest.Entry("article", "b"), 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := Score(c.a, c.b); math.Abs(have-c.want) > 0.01 {
+				t.Errorf("have %.3f; want %.3f", have, c.want)
+			}
+		})
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	entries := []*parse.EntryDecl{cohen, godel, cohenTruncated, goedel, cohenPunct}
+	have := Candidates(entries, 0.9)
+	want := [][2]string{
+		{"Cohen1963", "cohen63"},
+		{"Godel1931", "Goedel1931"},
+		{"Cohen1963", "cohen-ch"},
+		{"cohen-ch", "cohen63"},
+	}
+	if len(have) != len(want) {
+		t.Fatalf("have %d pairs; want %d", len(have), len(want))
+	}
+	for i, p := range have {
+		if p.A.CiteKey != want[i][0] || p.B.CiteKey != want[i][1] {
+			t.Errorf("have %s %s; want %s %s", p.A.CiteKey, p.B.CiteKey, want[i][0], want[i][1])
+		}
+	}
+}
+
+func TestRelated(t *testing.T) {
+	entries := []*parse.EntryDecl{cohen, godel, cohenTruncated, goedel, cohenPunct}
+	// Near the entry are those sharing a last name with it.
+	near := func(e *parse.EntryDecl) []*parse.EntryDecl {
+		result := []*parse.EntryDecl{}
+		for _, r := range entries {
+			if jaccard(lastNames(e), lastNames(r)) > 0 {
+				result = append(result, r)
+			}
+		}
+		return result
+	}
+	want := Candidates(entries, 0.9)
+	have := Related(entries, 0.9, near)
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v; want %v", have, want)
+	}
+	if have := Related(entries, 0.9, func(*parse.EntryDecl) []*parse.EntryDecl { return nil }); len(have) != 0 {
+		t.Errorf("have %v; want no pairs", have)
+	}
+}
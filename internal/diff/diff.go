@@ -0,0 +1,190 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Context is the number of unchanged lines shown around each change.
+const context = 3
+
+// Edit is a single line kept (` `), deleted (`-`) or inserted (`+`).
+type edit struct {
+	op   byte
+	line string
+}
+
+type pair struct{ x, y int }
+
+// Unified returns the unified diff turning old into new, labeled with the
+// names of both versions, or nil if they are the same. Lines are matched with
+// the anchored diff algorithm: the lines occurring exactly once in both
+// versions anchor the longest sequence of matches in order, which is then
+// extended with the equal lines around each anchor. Changes are thus kept
+// close to what was edited even in large files, at the cost of a diff that
+// is not always the shortest.
+func Unified(oldName string, old []byte, newName string, new []byte) []byte {
+	if bytes.Equal(old, new) {
+		return nil
+	}
+	es := edits(lines(old), lines(new))
+	// Numbers of the old and new lines preceding each edit.
+	ox, oy := make([]int, len(es)+1), make([]int, len(es)+1)
+	for i, e := range es {
+		ox[i+1], oy[i+1] = ox[i], oy[i]
+		if e.op != '+' {
+			ox[i+1]++
+		}
+		if e.op != '-' {
+			oy[i+1]++
+		}
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+	for i := 0; i < len(es); {
+		if es[i].op == ' ' {
+			i++
+			continue
+		}
+		// Changes separated by few enough unchanged lines share a hunk.
+		first, last := i, i
+		for k := i + 1; k < len(es) && k <= last+2*context+1; k++ {
+			if es[k].op != ' ' {
+				last = k
+			}
+		}
+		lo, hi := first-context, last+1+context
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > len(es) {
+			hi = len(es)
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", span(ox[lo], ox[hi]-ox[lo]), span(oy[lo], oy[hi]-oy[lo]))
+		for _, e := range es[lo:hi] {
+			b.WriteByte(e.op)
+			b.WriteString(e.line)
+		}
+		i = hi
+	}
+	return b.Bytes()
+}
+
+// Span formats the range of a hunk given the number of lines preceding it
+// and its length.
+func span(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
+
+// Lines splits the text into lines keeping their line feeds. A last line
+// missing its line feed is marked as such.
+func lines(text []byte) []string {
+	result := strings.SplitAfter(string(text), "\n")
+	if result[len(result)-1] == `` {
+		return result[:len(result)-1]
+	}
+	result[len(result)-1] += "\n\\ No newline at end of file\n"
+	return result
+}
+
+// Edits lists the edits turning x into y.
+func edits(x, y []string) []edit {
+	result := []edit{}
+	done := pair{}
+	for _, m := range anchors(x, y) {
+		if m.x < done.x || m.y < done.y {
+			continue
+		}
+		start := m
+		for start.x > done.x && start.y > done.y && x[start.x-1] == y[start.y-1] {
+			start.x--
+			start.y--
+		}
+		for _, l := range x[done.x:start.x] {
+			result = append(result, edit{'-', l})
+		}
+		for _, l := range y[done.y:start.y] {
+			result = append(result, edit{'+', l})
+		}
+		end := m
+		for end.x < len(x) && end.y < len(y) && x[end.x] == y[end.y] {
+			end.x++
+			end.y++
+		}
+		for _, l := range x[start.x:end.x] {
+			result = append(result, edit{' ', l})
+		}
+		done = end
+	}
+	return result
+}
+
+// Anchors returns the longest sequence of lines unique in both x and y that
+// appear in the same order in both, framed by the starts and the ends of the
+// texts.
+func anchors(x, y []string) []pair {
+	type count struct{ nx, ny, x, y int }
+	counts := make(map[string]*count)
+	for i, l := range x {
+		c, ok := counts[l]
+		if !ok {
+			c = &count{}
+			counts[l] = c
+		}
+		c.nx++
+		c.x = i
+	}
+	for j, l := range y {
+		if c, ok := counts[l]; ok {
+			c.ny++
+			c.y = j
+		}
+	}
+	unique := []pair{}
+	for _, l := range x {
+		if c := counts[l]; c.nx == 1 && c.ny == 1 {
+			unique = append(unique, pair{c.x, c.y})
+		}
+	}
+	result := []pair{{0, 0}}
+	result = append(result, increasing(unique)...)
+	return append(result, pair{len(x), len(y)})
+}
+
+// Increasing returns the longest subsequence of the pairs ordered by x that
+// is also ordered by y using patience sorting.
+func increasing(ps []pair) []pair {
+	// Tails holds the index of the pair ending the best subsequence of each
+	// length found so far.
+	tails := []int{}
+	prev := make([]int, len(ps))
+	for i, p := range ps {
+		k := sort.Search(len(tails), func(k int) bool { return ps[tails[k]].y > p.y })
+		prev[i] = -1
+		if k > 0 {
+			prev[i] = tails[k-1]
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+	result := make([]pair, len(tails))
+	if len(tails) == 0 {
+		return result
+	}
+	for i, k := len(tails)-1, tails[len(tails)-1]; i >= 0; i, k = i-1, prev[k] {
+		result[i] = ps[k]
+	}
+	return result
+}
@@ -0,0 +1,5 @@
+/*
+Diff package compares two versions of a text line by line and reports the
+differences in the unified diff format.
+*/
+package diff
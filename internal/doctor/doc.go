@@ -0,0 +1,7 @@
+/*
+Doctor package diagnoses BibTeX sources that fail to parse or behave
+unexpectedly. It guesses the character encoding, locates the first syntax
+error, and points out suspicious constructs such as unbalanced braces and
+stray @ signs, with a suggested fix for each problem.
+*/
+package doctor
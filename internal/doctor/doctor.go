@@ -0,0 +1,263 @@
+package doctor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+// Guessed encodings of the source.
+const (
+	ASCII       = "ASCII"
+	UTF8        = "UTF-8"
+	UTF8BOM     = "UTF-8 with a byte order mark"
+	UTF16       = "UTF-16"
+	Windows1252 = "Windows-1252"
+	Latin1      = "ISO-8859-1"
+)
+
+// Bom is the UTF-8 encoded byte order mark.
+var bom = []byte{0xEF, 0xBB, 0xBF}
+
+// Problem is a problem found in the source with a suggested fix.
+type Problem struct {
+	Pos     scan.Pos
+	Message string
+	Fix     string
+}
+
+// Report is the diagnosis of a source. Syntax is the first syntax error, or
+// nil if the source parses.
+type Report struct {
+	Encoding string
+	Syntax   *Problem
+	Problems []Problem
+}
+
+// OK reports whether nothing wrong was found.
+func (r *Report) OK() bool {
+	return r.Syntax == nil && len(r.Problems) == 0 && (r.Encoding == ASCII || r.Encoding == UTF8)
+}
+
+// Diagnose examines the source. The problems are sorted by position.
+func Diagnose(src []byte) *Report {
+	r := &Report{Encoding: Encoding(src), Problems: []Problem{}}
+	switch r.Encoding {
+	case UTF8BOM:
+		r.Problems = append(r.Problems, Problem{
+			Pos:     scan.Pos{Line: 1, Column: 1},
+			Message: "the file starts with a UTF-8 byte order mark",
+			Fix:     "remove the byte order mark, which BibTeX reads as text",
+		})
+	case UTF16:
+		r.Problems = append(r.Problems, Problem{
+			Pos:     scan.Pos{Line: 1, Column: 1},
+			Message: "the file is encoded in UTF-16",
+			Fix:     "re-encode the file as UTF-8",
+		})
+		return r
+	case Windows1252, Latin1:
+		r.Problems = append(r.Problems, Problem{
+			Pos:     invalidUTF8(src),
+			Message: "the file is not valid UTF-8 and is probably encoded in " + r.Encoding,
+			Fix:     "re-encode the file as UTF-8, e.g. iconv -f " + r.Encoding + " -t UTF-8",
+		})
+	}
+	src = bytes.TrimPrefix(src, bom)
+	p := parse.NewParser(scan.NewScanner(scan.NewBytesReader(src)))
+	for _, ok := p.Next(); ok; _, ok = p.Next() {
+	}
+	if err := p.Err(); err != nil {
+		r.Syntax = &Problem{
+			Pos:     p.ErrPos(),
+			Message: err.Error(),
+			Fix:     "check the declaration for a missing comma, quote, brace or = sign",
+		}
+	}
+	r.Problems = append(r.Problems, suspicious(src)...)
+	sort.SliceStable(r.Problems, func(i, j int) bool {
+		a, b := r.Problems[i].Pos, r.Problems[j].Pos
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	return r
+}
+
+// Encoding guesses the encoding of the source.
+func Encoding(src []byte) string {
+	switch {
+	case bytes.HasPrefix(src, bom):
+		return UTF8BOM
+	case bytes.HasPrefix(src, []byte{0xFF, 0xFE}), bytes.HasPrefix(src, []byte{0xFE, 0xFF}):
+		return UTF16
+	case utf8.Valid(src):
+		for _, b := range src {
+			if b >= utf8.RuneSelf {
+				return UTF8
+			}
+		}
+		return ASCII
+	}
+	// Windows-1252 puts printable characters where ISO-8859-1 has C1
+	// control codes, which are unlikely to appear in a bibliography.
+	for _, b := range src {
+		if b >= 0x80 && b <= 0x9F {
+			return Windows1252
+		}
+	}
+	return Latin1
+}
+
+// InvalidUTF8 returns the position of the first byte that is not valid UTF-8.
+func invalidUTF8(src []byte) scan.Pos {
+	pos := scan.Pos{Line: 1, Column: 1}
+	for len(src) > 0 {
+		c, size := utf8.DecodeRune(src)
+		if c == utf8.RuneError && size == 1 {
+			return pos
+		}
+		if c == '\n' {
+			pos = scan.Pos{Line: pos.Line + 1, Column: 1}
+		} else {
+			pos.Column++
+		}
+		src = src[size:]
+	}
+	return scan.Pos{}
+}
+
+// Suspicious looks for unbalanced braces and @ signs outside of the
+// declarations, tracking the brace depth from the start of the source.
+func suspicious(src []byte) []Problem {
+	result := []Problem{}
+	depth := 0
+	var open scan.Pos // opening brace of the current declaration
+	pos := scan.Pos{Line: 1}
+	text := []rune(string(src))
+	for i, c := range text {
+		if c == '\n' {
+			pos = scan.Pos{Line: pos.Line + 1}
+			continue
+		}
+		pos.Column++
+		switch c {
+		case '{':
+			if depth == 0 {
+				open = pos
+			}
+			depth++
+		case '}':
+			if depth == 0 {
+				result = append(result, Problem{
+					Pos:     pos,
+					Message: "closing brace without an opening brace",
+					Fix:     "remove the brace or add the missing opening brace before it",
+				})
+				continue
+			}
+			depth--
+		case '@':
+			if startsDecl(text[i+1:]) {
+				if depth > 0 && pos.Column == 1 {
+					result = append(result, Problem{
+						Pos:     open,
+						Message: fmt.Sprintf("brace opened here is still open at the declaration on line %d", pos.Line),
+						Fix:     "add the missing closing brace",
+					})
+					depth = 0
+				}
+				continue
+			}
+			if depth == 0 {
+				result = append(result, Problem{
+					Pos:     pos,
+					Message: "stray @ outside of a declaration",
+					Fix:     "remove the @ or complete the declaration as @type{key, ...}",
+				})
+			}
+		}
+	}
+	if depth > 0 {
+		result = append(result, Problem{
+			Pos:     open,
+			Message: "brace opened here is never closed",
+			Fix:     "add the missing closing brace",
+		})
+	}
+	return result
+}
+
+// StartsDecl reports whether the text following an @ sign is the type of a
+// declaration followed by its opening delimiter.
+func startsDecl(text []rune) bool {
+	i := 0
+	for i < len(text) && (unicode.IsLetter(text[i]) || unicode.IsDigit(text[i]) || text[i] == '_' || text[i] == '-') {
+		i++
+	}
+	if i == 0 {
+		return false
+	}
+	for i < len(text) && unicode.IsSpace(text[i]) {
+		i++
+	}
+	return i < len(text) && (text[i] == '{' || text[i] == '(')
+}
+
+// Snippet returns the line of the source at the position followed by a line
+// with a caret under its column, or an empty string if the position is not
+// in the source.
+func Snippet(src []byte, pos scan.Pos) string {
+	if !pos.IsValid() {
+		return ``
+	}
+	lines := strings.Split(string(src), "\n")
+	if pos.Line > len(lines) {
+		return ``
+	}
+	line := strings.TrimRight(lines[pos.Line-1], "\r")
+	var pad strings.Builder
+	for i, c := range []rune(line) {
+		if i >= pos.Column-1 {
+			break
+		}
+		// Tabs are kept so that the caret lines up however they are shown.
+		if c == '\t' {
+			pad.WriteRune('\t')
+		} else {
+			pad.WriteRune(' ')
+		}
+	}
+	return line + "\n" + pad.String() + "^"
+}
+
+// WriteText writes the report for the source read from the path.
+func (r *Report) WriteText(w io.Writer, path string, src []byte) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: encoding: %s\n", path, r.Encoding)
+	problem := func(kind string, p Problem) {
+		fmt.Fprintf(&b, "%s:%s: %s: %s\n", path, p.Pos, kind, p.Message)
+		if s := Snippet(src, p.Pos); s != `` {
+			for _, l := range strings.Split(s, "\n") {
+				fmt.Fprintf(&b, "\t%s\n", l)
+			}
+		}
+		fmt.Fprintf(&b, "\tfix: %s\n", p.Fix)
+	}
+	if r.Syntax != nil {
+		problem("syntax error", *r.Syntax)
+	}
+	for _, p := range r.Problems {
+		problem("warning", p)
+	}
+	if r.OK() {
+		fmt.Fprintf(&b, "%s: no problems found\n", path)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
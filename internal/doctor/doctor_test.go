@@ -0,0 +1,112 @@
+package doctor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+func TestEncoding(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"ascii", "@misc{a}", ASCII},
+		{"utf8", "@misc{a, title = {café}}", UTF8},
+		{"bom", "\xEF\xBB\xBF@misc{a}", UTF8BOM},
+		{"utf16", "\xFF\xFE@\x00", UTF16},
+		{"windows-1252", "{\x93quoted\x94}", Windows1252},
+		{"latin1", "{caf\xE9}", Latin1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := Encoding([]byte(c.src)); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	cases := []struct {
+		name     string
+		src      string
+		syntax   scan.Pos
+		problems []scan.Pos
+	}{
+		{"healthy", "@article{a,\n  title = {The {T}itle}\n}\n", scan.Pos{}, []scan.Pos{}},
+		{"bom", "\xEF\xBB\xBF@misc{a, title = {X}}\n", scan.Pos{}, []scan.Pos{{Line: 1, Column: 1}}},
+		{
+			"unclosed",
+			"@article{a,\n  title = {The {T}itle,\n}\n\n@book{b, title = {X}}\n",
+			scan.Pos{Line: 5, Column: 22},
+			[]scan.Pos{{Line: 1, Column: 9}},
+		},
+		{"extra-brace", "@misc{a, title = {X}}}\n", scan.Pos{}, []scan.Pos{{Line: 1, Column: 22}}},
+		{"stray-at", "% write to me@example.com\n@misc{a, title = {X}}\n", scan.Pos{Line: 2, Column: 6}, []scan.Pos{{Line: 1, Column: 14}}},
+		{"latin1", "@misc{a, title = {caf\xE9}}\n", scan.Pos{}, []scan.Pos{{Line: 1, Column: 22}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := Diagnose([]byte(c.src))
+			syntax := scan.Pos{}
+			if r.Syntax != nil {
+				syntax = r.Syntax.Pos
+			}
+			if syntax != c.syntax {
+				t.Errorf("have syntax error at %v; want %v", syntax, c.syntax)
+			}
+			problems := []scan.Pos{}
+			for _, p := range r.Problems {
+				problems = append(problems, p.Pos)
+			}
+			if !reflect.DeepEqual(problems, c.problems) {
+				t.Errorf("have problems at %v; want %v", problems, c.problems)
+			}
+			if ok := r.OK(); ok != (c.name == "healthy") {
+				t.Errorf("have OK %t", ok)
+			}
+		})
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	src := []byte("@misc{a,\n\ttitle = {X}}\n")
+	cases := []struct {
+		name string
+		pos  scan.Pos
+		want string
+	}{
+		{"first", scan.Pos{Line: 1, Column: 1}, "@misc{a,\n^"},
+		{"tab", scan.Pos{Line: 2, Column: 4}, "\ttitle = {X}}\n\t  ^"},
+		{"unknown", scan.Pos{}, ``},
+		{"beyond", scan.Pos{Line: 9, Column: 1}, ``},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := Snippet(src, c.pos); have != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	src := []byte("@misc{a, title = {X}}}\n")
+	var b strings.Builder
+	if err := Diagnose(src).WriteText(&b, "refs.bib", src); err != nil {
+		t.Fatal(err)
+	}
+	want := `refs.bib: encoding: ASCII
+refs.bib:1:22: warning: closing brace without an opening brace
+	@misc{a, title = {X}}}
+	                     ^
+	fix: remove the brace or add the missing opening brace before it
+`
+	if have := b.String(); have != want {
+		t.Errorf("have %s; want %s", have, want)
+	}
+}
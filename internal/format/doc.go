@@ -0,0 +1,5 @@
+/*
+Format package writes parsed BibTeX declarations back as BibTeX source in a
+canonical layout.
+*/
+package format
@@ -0,0 +1,124 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+const indent = "  "
+
+// Node writes the declaration in the canonical BibTeX layout: comments first,
+// one field per line indented with two spaces and equal signs aligned.
+func Node(w io.Writer, n parse.Node) error {
+	var b strings.Builder
+	switch d := n.(type) {
+	case *parse.EntryDecl:
+		comments(&b, d.Comments)
+		fmt.Fprintf(&b, "@%s{%s", d.Name, d.CiteKey)
+		fields(&b, d.Fields)
+		b.WriteString("}\n")
+	case *parse.AbbrevDecl:
+		comments(&b, d.Comments)
+		b.WriteString("@string{")
+		if d.Field != nil {
+			fmt.Fprintf(&b, "%s = %s", d.Field.Key, d.Field.Value)
+		}
+		b.WriteString("}\n")
+	case *parse.PreambleDecl:
+		comments(&b, d.Comments)
+		fmt.Fprintf(&b, "@preamble{%s}\n", d.Value)
+	case *parse.CommentGroupExpr:
+		comments(&b, d)
+	default:
+		return fmt.Errorf("format: cannot format %v", n)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Nodes writes the declarations separated with blank lines.
+func Nodes(w io.Writer, nodes []parse.Node) error {
+	for i, n := range nodes {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if err := Node(w, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Source parses the BibTeX source and returns it in the canonical layout.
+// Formatting the canonical layout again leaves it unchanged.
+func Source(src []byte) ([]byte, error) {
+	p := parse.NewParser(scan.NewScanner(scan.NewBytesReader(src)))
+	nodes := []parse.Node{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		nodes = append(nodes, n)
+	}
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if err := Nodes(&b, nodes); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Verify reports whether the BibTeX source is already in the canonical
+// layout without modifying it.
+func Verify(src []byte) (bool, error) {
+	res, err := Source(src)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(src, res), nil
+}
+
+func comments(b *strings.Builder, c *parse.CommentGroupExpr) {
+	if c == nil {
+		return
+	}
+	for _, v := range c.Values {
+		// Only @comment declarations start with an at sign, and they are
+		// written as they were read.
+		if strings.HasPrefix(v.Value, "@") {
+			b.WriteString(v.Value + "\n")
+			continue
+		}
+		for _, line := range strings.Split(v.Value, "\n") {
+			line = strings.TrimSpace(line)
+			if !strings.HasPrefix(line, "%") {
+				line = "% " + line
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+}
+
+func fields(b *strings.Builder, fs []*parse.FieldStmt) {
+	width := 0
+	for _, f := range fs {
+		if len(f.Key) > width {
+			width = len(f.Key)
+		}
+	}
+	for _, f := range fs {
+		fmt.Fprintf(b, ",\n%s%-*s = %s", indent, width, f.Key, f.Value)
+	}
+	if len(fs) > 0 {
+		b.WriteString("\n")
+	} else {
+		// The cite key has to be terminated for the entry to parse back
+		b.WriteString(",\n")
+	}
+}
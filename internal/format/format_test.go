@@ -0,0 +1,170 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestNode(t *testing.T) {
+	cases := []struct {
+		name string
+		node parse.Node
+		want string
+	}{
+		{
+			name: "entry",
+			node: &parse.EntryDecl{
+				Name:    "article",
+				CiteKey: "Cohen1963",
+				Comments: &parse.CommentGroupExpr{
+					Values: []*parse.CommentExpr{
+						{Value: "% The author never intended to write this book."},
+						{Value: "this is a comment."},
+					},
+				},
+				Fields: []*parse.FieldStmt{
+					{Key: "author", Value: `"P. J. C{\"o}hen"`},
+					{Key: "title", Value: "{The independence of the hypothesis}"},
+					{Key: "year", Value: "1963"},
+				},
+			},
+			want: `% The author never intended to write this book.
+% this is a comment.
+@article{Cohen1963,
+  author = "P. J. C{\"o}hen",
+  title  = {The independence of the hypothesis},
+  year   = 1963
+}
+`,
+		},
+		{
+			name: "empty-entry",
+			node: &parse.EntryDecl{Name: "misc", CiteKey: "empty"},
+			want: "@misc{empty,\n}\n",
+		},
+		{
+			name: "abbreviation",
+			node: &parse.AbbrevDecl{
+				Comments: &parse.CommentGroupExpr{},
+				Field:    &parse.FieldStmt{Key: "btx", Value: `"{\textsc{Bib}\TeX}"`},
+			},
+			want: "@string{btx = \"{\\textsc{Bib}\\TeX}\"}\n",
+		},
+		{
+			name: "preamble",
+			node: &parse.PreambleDecl{Value: `"\makeatletter"`},
+			want: "@preamble{\"\\makeatletter\"}\n",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := Node(&b, c.node); err != nil {
+				t.Fatal(err)
+			}
+			if have := b.String(); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestNodeBad(t *testing.T) {
+	var b strings.Builder
+	if err := Node(&b, &parse.BadDecl{}); err == nil {
+		t.Error("have nil; want an error")
+	}
+}
+
+var testSource = `
+% The author never intended to write this book.
+@article(Cohen1963,
+  % this is a comment.
+  author   = "P. J. C{\"o}hen, M. R. Thompson",
+  title    = {The independence of {,} the hypothesis},
+  year     = 1963, % this is a comment.
+  pages    = "1143--1148",
+)
+
+@PREAMBLE{ "\@ifundefined{url}{\def\url#1{\texttt{#1}}}{}" }
+@string{goossens = "Goossens, Michel"}
+@misc{empty,}
+
+@Comment{jabref-meta: grouping:
+0 AllEntriesGroup:;
+1 StaticGroup:{Nested} (braces)\;0\;1\;\;\;\;;
+}
+% Trailing comment.
+`
+
+var wantSource = `% The author never intended to write this book.
+% this is a comment.
+% this is a comment.
+@article{Cohen1963,
+  author = "P. J. C{\"o}hen, M. R. Thompson",
+  title  = {The independence of {,} the hypothesis},
+  year   = 1963,
+  pages  = "1143--1148"
+}
+
+@preamble{"\@ifundefined{url}{\def\url#1{\texttt{#1}}}{}"}
+
+@string{goossens = "Goossens, Michel"}
+
+@misc{empty,
+}
+
+@Comment{jabref-meta: grouping:
+0 AllEntriesGroup:;
+1 StaticGroup:{Nested} (braces)\;0\;1\;\;\;\;;
+}
+% Trailing comment.
+`
+
+func TestSource(t *testing.T) {
+	have, err := Source([]byte(testSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(have) != wantSource {
+		t.Errorf("have %s; want %s", have, wantSource)
+	}
+	again, err := Source(have)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(have) {
+		t.Errorf("formatting is not idempotent: have %s; want %s", again, have)
+	}
+}
+
+func TestSourceErr(t *testing.T) {
+	if _, err := Source([]byte("@article{Cohen1963, title = {x}")); err != parse.ErrSyntax {
+		t.Errorf("have %v; want %v", err, parse.ErrSyntax)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		want   bool
+	}{
+		{"canonical", wantSource, true},
+		{"unformatted", testSource, false},
+		{"empty", "", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			have, err := Verify([]byte(c.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have != c.want {
+				t.Errorf("have %t; want %t", have, c.want)
+			}
+		})
+	}
+}
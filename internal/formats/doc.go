@@ -0,0 +1,6 @@
+/*
+Formats package lists the bibliography formats entries are read from and
+written to by their names, so that the convert command and the bindings offer
+the same ones.
+*/
+package formats
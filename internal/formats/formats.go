@@ -0,0 +1,66 @@
+package formats
+
+import (
+	"io"
+
+	"github.com/mdm-code/bibx/internal/arxiv"
+	"github.com/mdm-code/bibx/internal/bibtexml"
+	"github.com/mdm-code/bibx/internal/csl"
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/freeform"
+	"github.com/mdm-code/bibx/internal/jsonl"
+	"github.com/mdm-code/bibx/internal/nbib"
+	"github.com/mdm-code/bibx/internal/ooxml"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+// Reader imports the entries read from r.
+type Reader func(r io.Reader) ([]*parse.EntryDecl, error)
+
+// Writer exports the entries to w.
+type Writer func(w io.Writer, entries []*parse.EntryDecl) error
+
+// Readers maps the names of the input formats onto their readers.
+var Readers = map[string]Reader{
+	"bibtex":   ReadBibTeX,
+	"nbib":     nbib.Read,
+	"arxiv":    arxiv.Read,
+	"bibtexml": bibtexml.Read,
+	"csl":      csl.Read,
+	"text":     freeform.Read,
+}
+
+// Writers maps the names of the output formats onto their writers.
+var Writers = map[string]Writer{
+	"bibtex":   WriteBibTeX,
+	"jsonl":    jsonl.Write,
+	"ooxml":    ooxml.Write,
+	"bibtexml": bibtexml.Write,
+	"csl":      csl.Write,
+}
+
+// ReadBibTeX returns the entries of the BibTeX source, failing on the first
+// syntax error.
+func ReadBibTeX(r io.Reader) ([]*parse.EntryDecl, error) {
+	p := parse.NewParser(scan.NewScanner(scan.NewReader(r)))
+	result := []*parse.EntryDecl{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		if e, ok := n.(*parse.EntryDecl); ok {
+			result = append(result, e)
+		}
+	}
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// WriteBibTeX writes the entries in the canonical layout.
+func WriteBibTeX(w io.Writer, entries []*parse.EntryDecl) error {
+	nodes := make([]parse.Node, 0, len(entries))
+	for _, e := range entries {
+		nodes = append(nodes, e)
+	}
+	return format.Nodes(w, nodes)
+}
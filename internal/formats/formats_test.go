@@ -0,0 +1,34 @@
+package formats
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBibTeX(t *testing.T) {
+	cases := []struct {
+		name, src, want string
+		fail            bool
+	}{
+		{"entries", "@string{x = {X}}\n@misc{a, title = {T}}\n", "@misc{a,\n  title = {T}\n}\n", false},
+		{"syntax error", "@misc{a, title = {T}\n", ``, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			es, err := ReadBibTeX(strings.NewReader(c.src))
+			if have := err != nil; have != c.fail {
+				t.Fatalf("have error %v; want failure %t", err, c.fail)
+			}
+			if c.fail {
+				return
+			}
+			var b strings.Builder
+			if err := WriteBibTeX(&b, es); err != nil {
+				t.Fatal(err)
+			}
+			if have := b.String(); have != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,7 @@
+/*
+Freeform package turns plain-text references, such as those copied from the
+bibliography of a PDF, into BibTeX entries. The parser is heuristic: it
+recognises the common author-year and numbered layouts, and scores its
+confidence in each field it fills so that doubtful guesses can be reviewed.
+*/
+package freeform
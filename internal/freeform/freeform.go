@@ -0,0 +1,406 @@
+package freeform
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/citekey"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/tex"
+	"github.com/mdm-code/bibx/internal/transform"
+)
+
+// Confidence scores of the fields recognised by the different heuristics.
+const (
+	certain  = 0.95
+	likely   = 0.8
+	possible = 0.6
+	guess    = 0.4
+)
+
+// Result is the entry parsed from a reference with the confidence, between
+// 0 and 1, in each of its fields and in its type under the "type" key.
+type Result struct {
+	Entry      *parse.EntryDecl
+	Confidence map[string]float64
+}
+
+var (
+	doi      = regexp.MustCompile(`(?i)(?:https?://(?:dx\.)?doi\.org/|doi:\s*)?(10\.\d{4,9}/[^\s"<>]+[^\s"<>.,;])`)
+	url      = regexp.MustCompile(`https?://[^\s"<>]+[^\s"<>.,;)]`)
+	urlLabel = regexp.MustCompile(`(?i)\s*(?:available|retrieved)?\s*(?:at|from)?:?\s*$`)
+	yearPar  = regexp.MustCompile(`\(((?:1[5-9]|20)\d\d)[a-z]?\)`)
+	year     = regexp.MustCompile(`\b((?:1[5-9]|20)\d\d)[a-z]?\b`)
+	marker   = regexp.MustCompile(`^\s*(?:\[\d+\]|\d+\.|\(\d+\))\s+`)
+	quoted   = regexp.MustCompile(`["“]([^"”]+?)[,.]?["”][,.]?\s*`)
+	volume   = regexp.MustCompile(`(?:^|[\s,])(?:[Vv]ol\.\s*)?(\d+)\s*(?:\((\d+(?:[–-]\d+)?)\)|,\s*(?:[Nn]o\.|[Nn]r\.)\s*(\d+))?\s*(?:[:,]\s*(?:pp?\.\s*)?(\d+\s*[–-]+\s*\d+|\d+))?`)
+	pages    = regexp.MustCompile(`\bpp?\.\s*(\d+(?:\s*[–-]+\s*\d+)?)`)
+	edition  = regexp.MustCompile(`\((\d+)(?:st|nd|rd|th) ed\.\)`)
+	place    = regexp.MustCompile(`^([A-Z][\w .]*?(?:, [A-Z]{2})?):\s*([^.:]+)\.?$`)
+	thesis   = regexp.MustCompile(`(?i)\(?\b(ph\.?\s?d\.?|doctoral|master'?s?)\b[^()]*\b(?:thesis|dissertation)\b[^()]*\)?`)
+	empty    = regexp.MustCompile(`\(\s*\)|\s+([.,])`)
+	initials = regexp.MustCompile(`^(?:[A-Z][a-z]?\.?-?)+$`)
+)
+
+// Abbreviations that do not end a sentence when followed by a period.
+var abbreviations = map[string]bool{
+	"al": true, "ed": true, "eds": true, "vol": true, "no": true, "pp": true,
+	"proc": true, "natl": true, "acad": true, "sci": true, "univ": true,
+	"int": true, "conf": true, "j": true, "trans": true, "rev": true,
+	"phys": true, "math": true, "soc": true, "am": true, "inst": true,
+	"dept": true, "st": true, "jr": true, "sr": true, "vs": true, "etc": true,
+}
+
+// Parse guesses the entry described by the plain-text reference. The cite
+// key is generated from the fields found.
+func Parse(s string) *Result {
+	r := &Result{Entry: &parse.EntryDecl{Comments: &parse.CommentGroupExpr{}}, Confidence: make(map[string]float64)}
+	s = strings.Join(strings.Fields(marker.ReplaceAllString(s, ``)), " ")
+
+	if m := doi.FindStringSubmatchIndex(s); m != nil {
+		r.set("doi", s[m[2]:m[3]], certain)
+		s = cut(s, m[0], m[1])
+	}
+	if m := url.FindStringIndex(s); m != nil {
+		r.set("url", s[m[0]:m[1]], certain)
+		s = cut(s, m[0], m[1])
+		s = urlLabel.ReplaceAllString(strings.TrimSpace(s), ``)
+	}
+
+	// Theses are marked anywhere after the title, often in parentheses.
+	if m := thesis.FindStringSubmatchIndex(s); m != nil {
+		kind := "phdthesis"
+		if strings.HasPrefix(strings.ToLower(s[m[2]:m[3]]), "master") {
+			kind = "mastersthesis"
+		}
+		r.setType(kind, likely)
+		s = cut(s, m[0], m[1])
+	}
+
+	// Author-year styles put the year in parentheses after the authors,
+	// while numbered styles often quote the title and end with the year.
+	var rest string
+	if m := yearPar.FindStringSubmatchIndex(s); m != nil {
+		r.set("year", s[m[2]:m[3]], certain)
+		r.setNames(s[:m[0]], likely)
+		rest = strings.TrimLeft(s[m[1]:], ".,: ")
+	} else {
+		if ms := year.FindAllStringSubmatchIndex(s, -1); len(ms) > 0 {
+			m := ms[len(ms)-1]
+			r.set("year", s[m[2]:m[3]], likely)
+			s = cut(s, m[0], m[1])
+		}
+		if m := quoted.FindStringIndex(s); m != nil {
+			r.setNames(s[:m[0]], likely)
+			rest = s[m[0]:]
+		} else {
+			i := sentenceEnd(s, 0)
+			j := strings.Index(s, ", ")
+			switch {
+			case looksLikeNames(s[:i]):
+				r.setNames(s[:i], possible)
+				rest = strings.TrimSpace(s[i:])
+			case j > 0 && looksLikeNames(s[:j]) && !isInitials(s[j+2:sentenceEnd(s, j)]):
+				// A single author named first, followed by the title.
+				r.setNames(s[:j], guess)
+				rest = strings.TrimSpace(s[j+2:])
+			default:
+				rest = s
+			}
+		}
+	}
+
+	if m := quoted.FindStringSubmatchIndex(rest); m != nil && strings.TrimSpace(rest[:m[0]]) == `` {
+		r.set("title", rest[m[2]:m[3]], certain)
+		rest = rest[m[1]:]
+	} else {
+		i := sentenceEnd(rest, 0)
+		r.set("title", strings.TrimRight(rest[:i], ". "), possible)
+		rest = strings.TrimSpace(rest[i:])
+	}
+	r.venue(strings.TrimRight(rest, ". "))
+	transform.SortFields(r.Entry, transform.DefaultFieldOrder)
+	r.Entry.CiteKey = citekey.Generate(r.Entry)
+	return r
+}
+
+// Venue classifies the entry by the part of the reference following the
+// title and fills the fields of its venue.
+func (r *Result) venue(s string) {
+	s = yearPar.ReplaceAllString(s, ``)
+	if m := edition.FindStringSubmatchIndex(s); m != nil {
+		r.set("edition", s[m[2]:m[3]], likely)
+		s = cut(s, m[0], m[1])
+	}
+	if m := pages.FindStringSubmatchIndex(s); m != nil {
+		r.set("pages", dash(s[m[2]:m[3]]), likely)
+		s = cut(s, m[0], m[1])
+	}
+	s = strings.Trim(s, " ,.")
+	switch {
+	case strings.HasSuffix(r.Entry.Name, "thesis"):
+		// Theses name the school last, possibly after the department.
+		if i := strings.LastIndex(s, ", "); i >= 0 {
+			s = s[i+2:]
+		}
+		r.set("school", strings.Trim(s, " ,."), possible)
+	case strings.HasPrefix(s, "In "):
+		r.setType("inproceedings", likely)
+		s = strings.TrimPrefix(s, "In ")
+		// Editors are listed before the book title in author-year styles.
+		for _, label := range []string{"(Eds.),", "(Ed.),", "(Eds.)", "(Ed.)"} {
+			if i := strings.Index(s, label); i >= 0 {
+				r.setNames(s[:i], possible, "editor")
+				s = strings.TrimSpace(s[i+len(label):])
+				break
+			}
+		}
+		book, publisher := s, ``
+		if i := sentenceEnd(s, 0); i < len(s) {
+			book, publisher = s[:i], strings.TrimSpace(s[i:])
+		}
+		r.set("booktitle", strings.Trim(book, " ,."), possible)
+		r.publisher(publisher)
+	default:
+		m := volume.FindStringSubmatchIndex(s)
+		if m != nil && m[2] > 0 {
+			r.setType("article", likely)
+			r.set("journal", strings.Trim(s[:m[0]], " ,"), likely)
+			r.set("volume", s[m[2]:m[3]], likely)
+			for _, g := range [][2]int{{m[4], m[5]}, {m[6], m[7]}} {
+				if g[0] >= 0 {
+					r.set("number", s[g[0]:g[1]], likely)
+				}
+			}
+			if m[8] >= 0 {
+				r.set("pages", dash(s[m[8]:m[9]]), likely)
+			}
+			return
+		}
+		if _, ok := r.Confidence["pages"]; ok && s != `` && !place.MatchString(s) {
+			r.setType("article", possible)
+			r.set("journal", s, guess)
+			return
+		}
+		if s == `` {
+			r.setType("misc", guess)
+			return
+		}
+		confidence := guess
+		if r.publisher(s) {
+			confidence = possible
+		}
+		r.setType("book", confidence)
+	}
+}
+
+// Publisher fills the publisher and its address given as `City: Publisher`
+// and reports whether the text had that form.
+func (r *Result) publisher(s string) bool {
+	m := place.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		if s != `` {
+			r.set("publisher", strings.Trim(s, " ,."), guess)
+		}
+		return false
+	}
+	r.set("address", m[1], possible)
+	r.set("publisher", strings.TrimSpace(m[2]), possible)
+	return true
+}
+
+// Set sets the field to the escaped value unless the value is empty.
+func (r *Result) set(key, value string, confidence float64) {
+	if value = strings.TrimSpace(value); value == `` {
+		return
+	}
+	r.Entry.Set(key, parse.Quote(tex.Escape(value)))
+	r.Confidence[key] = confidence
+}
+
+func (r *Result) setType(name string, confidence float64) {
+	r.Entry.Name = name
+	r.Confidence["type"] = confidence
+}
+
+// SetNames sets the author field, or another name field, to the names listed
+// in the text in the BibTeX form.
+func (r *Result) setNames(s string, confidence float64, field ...string) {
+	key := "author"
+	if len(field) > 0 {
+		key = field[0]
+	}
+	list := splitNames(s)
+	if len(list) == 0 {
+		return
+	}
+	for i, n := range list {
+		list[i] = tex.Escape(n)
+	}
+	r.Entry.Set(key, parse.Quote(strings.Join(list, " and ")))
+	r.Confidence[key] = confidence
+}
+
+// SplitNames splits a list of names written as `Last, F.` or `F. Last` and
+// separated by commas, semicolons, ampersands or `and`. A trailing et al.
+// becomes others.
+func splitNames(s string) []string {
+	s = strings.Trim(s, " ,;")
+	others := false
+	for _, suffix := range []string{"et al.", "et al"} {
+		if strings.HasSuffix(s, suffix) {
+			s, others = strings.Trim(strings.TrimSuffix(s, suffix), " ,"), true
+			break
+		}
+	}
+	s = strings.NewReplacer(", &", ";", "&", ";", ", and ", ";", " and ", ";").Replace(s)
+	result := []string{}
+	for _, group := range strings.Split(s, ";") {
+		parts := []string{}
+		for _, p := range strings.Split(group, ",") {
+			if p = strings.TrimSpace(p); p != `` {
+				parts = append(parts, p)
+			}
+		}
+		for i := 0; i < len(parts); i++ {
+			if i+1 < len(parts) && isInitials(parts[i+1]) {
+				result = append(result, parts[i]+", "+spaceInitials(parts[i+1]))
+				i++
+				continue
+			}
+			// The period closing a list is not part of a last name.
+			if w := strings.Fields(parts[i]); !isInitials(w[len(w)-1]) {
+				parts[i] = strings.TrimSuffix(parts[i], ".")
+			}
+			result = append(result, parts[i])
+		}
+	}
+	if others && len(result) > 0 {
+		result = append(result, "others")
+	}
+	return result
+}
+
+// LooksLikeNames reports whether the text is a list of names rather than,
+// say, the title of a reference without authors.
+func looksLikeNames(s string) bool {
+	for _, n := range splitNames(s) {
+		w := strings.Fields(strings.ReplaceAll(n, ",", ` `))
+		if len(w) < 2 || len(w) > 4 {
+			return false
+		}
+	}
+	return true
+}
+
+func isInitials(s string) bool {
+	for _, w := range strings.Fields(s) {
+		if !initials.MatchString(w) {
+			return false
+		}
+	}
+	return s != ``
+}
+
+// SpaceInitials separates run-together initials, so that P.J. becomes P. J.
+func spaceInitials(s string) string {
+	return strings.TrimSpace(strings.ReplaceAll(strings.ReplaceAll(s, ".", ". "), "  ", " "))
+}
+
+// SentenceEnd returns the index following the first period that ends a
+// sentence at or after the index, or the length of the text if there is
+// none. Periods after initials and common abbreviations do not count.
+func sentenceEnd(s string, from int) int {
+	for i := from; i < len(s); i++ {
+		if s[i] != '.' && s[i] != '?' && s[i] != '!' {
+			continue
+		}
+		if i+1 < len(s) && s[i+1] != ' ' {
+			continue
+		}
+		word := s[strings.LastIndexAny(s[:i], " .")+1 : i]
+		if s[i] == '.' && (len([]rune(word)) == 1 || abbreviations[strings.ToLower(word)]) {
+			continue
+		}
+		return i + 1
+	}
+	return len(s)
+}
+
+// Cut removes the text between the indexes along with the empty
+// parentheses and the spaces before punctuation left behind.
+func cut(s string, i, j int) string {
+	return strings.TrimSpace(empty.ReplaceAllString(s[:i]+" "+s[j:], "$1"))
+}
+
+// Dash writes page ranges with an en dash as BibTeX does.
+func dash(s string) string {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '-' || r == '–' || r == ' ' })
+	return strings.Join(parts, "--")
+}
+
+// Split splits text holding many references into the single references.
+// References are separated by blank lines, or begin with numeric markers
+// such as [1] or 1. when the text has them.
+func Split(text string) []string {
+	result := []string{}
+	var b strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(b.String()); s != `` {
+			result = append(result, s)
+		}
+		b.Reset()
+	}
+	numbered := marker.MatchString(strings.TrimSpace(text))
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.TrimSpace(line) == `` || numbered && marker.MatchString(line) {
+			flush()
+		}
+		b.WriteString(line + " ")
+	}
+	flush()
+	return result
+}
+
+// Read parses the plain-text references read from r into entries with
+// unique cite keys. The confidence in the fields of each entry is recorded
+// in a comment above it.
+func Read(r io.Reader) ([]*parse.EntryDecl, error) {
+	text, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	result := []*parse.EntryDecl{}
+	taken := make(map[string]bool)
+	for _, s := range Split(string(text)) {
+		res := Parse(s)
+		res.Entry.CiteKey = citekey.Unique(res.Entry.CiteKey, taken)
+		res.Entry.Comments.Values = append(res.Entry.Comments.Values, &parse.CommentExpr{Value: "% " + res.String()})
+		result = append(result, res.Entry)
+	}
+	return result, nil
+}
+
+// String lists the confidence in the fields, e.g. `confidence: author 0.80,
+// title 0.60`, from the least certain field.
+func (r *Result) String() string {
+	keys := make([]string, 0, len(r.Confidence))
+	for k := range r.Confidence {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := r.Confidence[keys[i]], r.Confidence[keys[j]]
+		return a < b || a == b && keys[i] < keys[j]
+	})
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s %.2f", k, r.Confidence[k])
+	}
+	return "confidence: " + strings.Join(parts, ", ")
+}
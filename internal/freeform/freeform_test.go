@@ -0,0 +1,209 @@
+package freeform
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name   string
+		ref    string
+		typ    string
+		fields map[string]string
+	}{
+		{
+			"apa-article",
+			"Cohen, P.J. (1963). The independence of the continuum hypothesis. Proceedings of the National Academy of Sciences, 50(6), 1143–1148. https://doi.org/10.1073/pnas.50.6.1143",
+			"article",
+			map[string]string{
+				"author":  "Cohen, P. J.",
+				"year":    "1963",
+				"title":   "The independence of the continuum hypothesis",
+				"journal": "Proceedings of the National Academy of Sciences",
+				"volume":  "50",
+				"number":  "6",
+				"pages":   "1143--1148",
+				"doi":     "10.1073/pnas.50.6.1143",
+			},
+		},
+		{
+			"ieee-article",
+			"[1] P. J. Cohen, “The independence of the continuum hypothesis,” Proc. Natl. Acad. Sci., vol. 50, no. 6, pp. 1143–1148, 1963.",
+			"article",
+			map[string]string{
+				"author":  "P. J. Cohen",
+				"year":    "1963",
+				"title":   "The independence of the continuum hypothesis",
+				"journal": "Proc. Natl. Acad. Sci.",
+				"volume":  "50",
+				"number":  "6",
+				"pages":   "1143--1148",
+			},
+		},
+		{
+			"book",
+			"Gödel, K. (1940). The consistency of the continuum hypothesis. Princeton: Princeton University Press.",
+			"book",
+			map[string]string{
+				"author":    "Gödel, K.",
+				"year":      "1940",
+				"title":     "The consistency of the continuum hypothesis",
+				"address":   "Princeton",
+				"publisher": "Princeton University Press",
+			},
+		},
+		{
+			"chapter",
+			"Smith, J., & Doe, A. B. (2020). Learning things. In K. Lee (Ed.), Proceedings of the Conference on Stuff (pp. 12-20). Springer.",
+			"inproceedings",
+			map[string]string{
+				"author":    "Smith, J. and Doe, A. B.",
+				"year":      "2020",
+				"title":     "Learning things",
+				"editor":    "K. Lee",
+				"booktitle": "Proceedings of the Conference on Stuff",
+				"pages":     "12--20",
+				"publisher": "Springer",
+			},
+		},
+		{
+			"thesis",
+			"Doe, J. (2019). A study of things (Doctoral dissertation). University of Somewhere.",
+			"phdthesis",
+			map[string]string{
+				"author": "Doe, J.",
+				"year":   "2019",
+				"title":  "A study of things",
+				"school": "University of Somewhere",
+			},
+		},
+		{
+			"et-al",
+			"Turing, A. M., et al. (1950). Computing machinery & intelligence. Mind, 59, 433-460.",
+			"article",
+			map[string]string{
+				"author":  "Turing, A. M. and others",
+				"year":    "1950",
+				"title":   `Computing machinery \& intelligence`,
+				"journal": "Mind",
+				"volume":  "59",
+				"pages":   "433--460",
+			},
+		},
+		{
+			"given-first",
+			"K. Gödel, The consistency of the axiom of choice. Princeton: Princeton University Press, 1940.",
+			"book",
+			map[string]string{
+				"author":    "K. Gödel",
+				"year":      "1940",
+				"title":     "The consistency of the axiom of choice",
+				"address":   "Princeton",
+				"publisher": "Princeton University Press",
+			},
+		},
+		{
+			"no-authors",
+			"Annual report on everything in the world. Tech Corp, 2001.",
+			"book",
+			map[string]string{
+				"year":      "2001",
+				"title":     "Annual report on everything in the world",
+				"publisher": "Tech Corp",
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := Parse(c.ref)
+			if r.Entry.Name != c.typ {
+				t.Errorf("have type %s; want %s", r.Entry.Name, c.typ)
+			}
+			have := make(map[string]string)
+			for _, f := range r.Entry.Fields {
+				have[f.Key] = parse.Unquote(f.Value)
+				if _, ok := r.Confidence[f.Key]; !ok {
+					t.Errorf("have no confidence in %s", f.Key)
+				}
+			}
+			if !reflect.DeepEqual(have, c.fields) {
+				t.Errorf("have %v; want %v", have, c.fields)
+			}
+		})
+	}
+}
+
+func TestConfidence(t *testing.T) {
+	r := Parse("Knuth, D. E. (1984). Literate programming. The Computer Journal, 27(2), 97-111.")
+	if r.Confidence["year"] <= r.Confidence["title"] {
+		t.Errorf("have year %.2f not above title %.2f", r.Confidence["year"], r.Confidence["title"])
+	}
+	want := "confidence: title 0.60, author 0.80, journal 0.80, number 0.80, pages 0.80, type 0.80, volume 0.80, year 0.95"
+	if have := r.String(); have != want {
+		t.Errorf("have %s; want %s", have, want)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"blank-lines", "A. First\nreference.\n\nB. Second.\n", []string{"A. First reference.", "B. Second."}},
+		{"numbered", "[1] A. First\nreference.\n[2] B. Second.\n", []string{"[1] A. First reference.", "[2] B. Second."}},
+		{"empty", "\n\n", []string{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := Split(c.text); !reflect.DeepEqual(have, c.want) {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	text := `Gödel, K. (1940). The consistency of the continuum hypothesis. Princeton: Princeton University Press.
+
+Gödel, K. (1940). Another book. Princeton: Princeton University Press.
+`
+	entries, err := Read(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := []parse.Node{}
+	for _, e := range entries {
+		nodes = append(nodes, e)
+	}
+	var b strings.Builder
+	if err := format.Nodes(&b, nodes); err != nil {
+		t.Fatal(err)
+	}
+	want := `% confidence: address 0.60, publisher 0.60, title 0.60, type 0.60, author 0.80, year 0.95
+@book{Godel1940,
+  author    = {Gödel, K.},
+  title     = {The consistency of the continuum hypothesis},
+  publisher = {Princeton University Press},
+  address   = {Princeton},
+  year      = {1940}
+}
+
+% confidence: address 0.60, publisher 0.60, title 0.60, type 0.60, author 0.80, year 0.95
+@book{Godel1940a,
+  author    = {Gödel, K.},
+  title     = {Another book},
+  publisher = {Princeton University Press},
+  address   = {Princeton},
+  year      = {1940}
+}
+`
+	if have := b.String(); have != want {
+		t.Errorf("have %s; want %s", have, want)
+	}
+}
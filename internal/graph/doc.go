@@ -0,0 +1,6 @@
+/*
+Graph package builds the graph of the references between entries made by
+their crossref, xdata, related and similar fields, and writes it in the DOT
+and GraphML formats for visualization.
+*/
+package graph
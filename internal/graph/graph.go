@@ -0,0 +1,195 @@
+package graph
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+// DefaultFields are the fields holding references followed by default.
+var DefaultFields = []string{"crossref", "xdata", "related"}
+
+// Node is an entry of the graph. Missing is set for the cite keys referred
+// to by entries but not defined.
+type Node struct {
+	Key     string
+	Type    string
+	Title   string
+	Missing bool
+}
+
+// Edge is a reference from the entry under the From key to the entry under
+// the To key made by the Field.
+type Edge struct {
+	From, To string
+	Field    string
+}
+
+// Graph is a directed graph of the references between entries.
+type Graph struct {
+	Nodes []Node
+	Edges []Edge
+}
+
+// Build creates the graph of the entries and of the references made by their
+// fields holding comma-separated lists of cite keys. Cite keys are matched
+// case-insensitively, and references repeated by the same field are kept
+// once.
+func Build(entries []*parse.EntryDecl, fields []string) *Graph {
+	g := &Graph{Nodes: []Node{}, Edges: []Edge{}}
+	keys := make(map[string]string)
+	for _, e := range entries {
+		k := strings.ToLower(e.CiteKey)
+		if _, ok := keys[k]; ok {
+			continue
+		}
+		keys[k] = e.CiteKey
+		n := Node{Key: e.CiteKey, Type: strings.ToLower(e.Name)}
+		if f, ok := e.Get("title"); ok {
+			n.Title = tex.Decode(parse.Unquote(f.Value))
+		}
+		g.Nodes = append(g.Nodes, n)
+	}
+	seen := make(map[Edge]bool)
+	for _, e := range entries {
+		for _, field := range fields {
+			f, ok := e.Get(field)
+			if !ok {
+				continue
+			}
+			for _, target := range strings.Split(parse.Unquote(f.Value), ",") {
+				target = strings.TrimSpace(target)
+				if target == `` {
+					continue
+				}
+				to, ok := keys[strings.ToLower(target)]
+				if !ok {
+					to = target
+					keys[strings.ToLower(target)] = target
+					g.Nodes = append(g.Nodes, Node{Key: target, Missing: true})
+				}
+				edge := Edge{From: e.CiteKey, To: to, Field: strings.ToLower(field)}
+				if !seen[edge] {
+					seen[edge] = true
+					g.Edges = append(g.Edges, edge)
+				}
+			}
+		}
+	}
+	return g
+}
+
+// WriteDOT writes the graph in the DOT language of Graphviz. Edges are
+// labelled with their fields, and missing entries are drawn dashed.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph bibliography {\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, n := range g.Nodes {
+		attrs := []string{"label=" + quoteDOT(n.Key)}
+		if n.Title != `` {
+			attrs = append(attrs, "tooltip="+quoteDOT(n.Title))
+		}
+		if n.Missing {
+			attrs = append(attrs, "style=dashed")
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", quoteDOT(n.Key), strings.Join(attrs, ", "))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", quoteDOT(e.From), quoteDOT(e.To), quoteDOT(e.Field))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// QuoteDOT writes the string as a quoted DOT identifier.
+func quoteDOT(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+type graphML struct {
+	XMLName xml.Name    `xml:"graphml"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	Keys    []graphKey  `xml:"key"`
+	Graph   graphMLBody `xml:"graph"`
+}
+
+type graphKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLBody struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string      `xml:"id,attr"`
+	Data []graphData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string      `xml:"source,attr"`
+	Target string      `xml:"target,attr"`
+	Data   []graphData `xml:"data"`
+}
+
+// WriteGraphML writes the graph as a GraphML document with the entry types,
+// titles and missing flags of the nodes and the fields of the edges as data.
+func (g *Graph) WriteGraphML(w io.Writer) error {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphKey{
+			{ID: "type", For: "node", Name: "type", Type: "string"},
+			{ID: "title", For: "node", Name: "title", Type: "string"},
+			{ID: "missing", For: "node", Name: "missing", Type: "boolean"},
+			{ID: "field", For: "edge", Name: "field", Type: "string"},
+		},
+		Graph: graphMLBody{ID: "bibliography", EdgeDefault: "directed"},
+	}
+	for _, n := range g.Nodes {
+		node := graphMLNode{ID: n.Key}
+		if n.Type != `` {
+			node.Data = append(node.Data, graphData{"type", n.Type})
+		}
+		if n.Title != `` {
+			node.Data = append(node.Data, graphData{"title", n.Title})
+		}
+		if n.Missing {
+			node.Data = append(node.Data, graphData{"missing", "true"})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, node)
+	}
+	for _, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: e.From,
+			Target: e.To,
+			Data:   []graphData{{"field", e.Field}},
+		})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent(``, "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,98 @@
+package graph
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/bibxtest"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+var testEntries = []*parse.EntryDecl{
+	bibxtest.Entry("inproceedings", "Smith2001", "title", `{A {"}paper{"}}`, "crossref", "{proc2001}", "cites", "{Doe2000, Nobody}"),
+	bibxtest.Entry("proceedings", "Proc2001", "title", "{Proceedings}"),
+	bibxtest.Entry("article", "Doe2000", "related", "{Smith2001,Smith2001}"),
+}
+
+func TestBuild(t *testing.T) {
+	cases := []struct {
+		name   string
+		fields []string
+		nodes  []Node
+		edges  []Edge
+	}{
+		{
+			"default fields",
+			DefaultFields,
+			[]Node{
+				{Key: "Smith2001", Type: "inproceedings", Title: `A "paper"`},
+				{Key: "Proc2001", Type: "proceedings", Title: "Proceedings"},
+				{Key: "Doe2000", Type: "article"},
+			},
+			[]Edge{{"Smith2001", "Proc2001", "crossref"}, {"Doe2000", "Smith2001", "related"}},
+		},
+		{
+			"cites",
+			[]string{"cites"},
+			[]Node{
+				{Key: "Smith2001", Type: "inproceedings", Title: `A "paper"`},
+				{Key: "Proc2001", Type: "proceedings", Title: "Proceedings"},
+				{Key: "Doe2000", Type: "article"},
+				{Key: "Nobody", Missing: true},
+			},
+			[]Edge{{"Smith2001", "Doe2000", "cites"}, {"Smith2001", "Nobody", "cites"}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := Build(testEntries, c.fields)
+			if !reflect.DeepEqual(g.Nodes, c.nodes) {
+				t.Errorf("have nodes %+v; want %+v", g.Nodes, c.nodes)
+			}
+			if !reflect.DeepEqual(g.Edges, c.edges) {
+				t.Errorf("have edges %+v; want %+v", g.Edges, c.edges)
+			}
+		})
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	var b bytes.Buffer
+	if err := Build(testEntries, []string{"crossref", "cites"}).WriteDOT(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph bibliography {
+  node [shape=box];
+  "Smith2001" [label="Smith2001", tooltip="A \"paper\""];
+  "Proc2001" [label="Proc2001", tooltip="Proceedings"];
+  "Doe2000" [label="Doe2000"];
+  "Nobody" [label="Nobody", style=dashed];
+  "Smith2001" -> "Proc2001" [label="crossref"];
+  "Smith2001" -> "Doe2000" [label="cites"];
+  "Smith2001" -> "Nobody" [label="cites"];
+}
+`
+	if have := b.String(); have != want {
+		t.Errorf("have\n%s\nwant\n%s", have, want)
+	}
+}
+
+func TestWriteGraphML(t *testing.T) {
+	var b bytes.Buffer
+	if err := Build(testEntries, DefaultFields).WriteGraphML(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<graph id="bibliography" edgedefault="directed">`,
+		`<node id="Smith2001">`,
+		`<data key="title">A &#34;paper&#34;</data>`,
+		`<edge source="Smith2001" target="Proc2001">`,
+		`<data key="field">crossref</data>`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("missing %s in\n%s", want, b.String())
+		}
+	}
+}
@@ -0,0 +1,11 @@
+/*
+Jabref package models the groups JabRef keeps in the metadata of a BibTeX
+file. The groups form a tree stored in an @Comment{jabref-meta: grouping:}
+declaration, one group per line prefixed with its depth. Static groups list
+their entries by name in the groups field of the entries, and keyword groups
+take the entries holding a term in one of their fields.
+
+The parser keeps @Comment declarations among the comments as they are, and
+the groups are read from and written back to the source of a file as text.
+*/
+package jabref
@@ -0,0 +1,361 @@
+package jabref
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// Kind is the kind of a group.
+type Kind uint8
+
+const (
+	// AllEntries is the root group holding every entry.
+	AllEntries Kind = iota
+	// Static groups hold the entries naming them in their groups field.
+	Static
+	// Keyword groups hold the entries with the term in the field.
+	Keyword
+	// Other groups are of the kinds not modeled, such as search groups,
+	// kept as they are written in Raw.
+	Other
+)
+
+var kindNames = map[Kind]string{
+	AllEntries: "AllEntriesGroup",
+	Static:     "StaticGroup",
+	Keyword:    "KeywordGroup",
+}
+
+// Context tells how a group relates to the entries of its parent.
+type Context uint8
+
+const (
+	// Independent groups hold their own entries only.
+	Independent Context = iota
+	// Refining groups hold their entries also held by the parent.
+	Refining
+	// Including groups hold their entries and the entries of their
+	// subgroups.
+	Including
+)
+
+// ErrNotStatic is returned when entries are assigned to a group other than
+// a static one, whose entries follow from their fields.
+var ErrNotStatic = errors.New("jabref: entries are assigned to static groups only")
+
+// Group is a node of the group tree. The members after Context describe
+// keyword groups, and Raw holds the definition of groups of other kinds.
+type Group struct {
+	Name          string
+	Kind          Kind
+	Context       Context
+	Field, Term   string
+	CaseSensitive bool
+	Regex         bool
+	Expanded      bool
+	Color         string
+	Icon          string
+	Description   string
+	Raw           string
+	Children      []*Group
+
+	parent *Group
+}
+
+// NewTree returns the root of an empty group tree.
+func NewTree() *Group { return &Group{Kind: AllEntries} }
+
+// Add appends the group to the subgroups of g and returns it.
+func (g *Group) Add(child *Group) *Group {
+	child.parent = g
+	g.Children = append(g.Children, child)
+	return child
+}
+
+// Find returns the first group of the tree named name in depth-first order,
+// or nil if there is none.
+func (g *Group) Find(name string) *Group {
+	if g.Kind != AllEntries && g.Name == name {
+		return g
+	}
+	for _, c := range g.Children {
+		if found := c.Find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Contains reports whether the entry belongs to the group. Groups of other
+// kinds contain no entries, and invalid regular expressions match nothing.
+func (g *Group) Contains(e *parse.EntryDecl) bool {
+	if g.own(e) {
+		return true
+	}
+	if g.Context == Including {
+		for _, c := range g.Children {
+			if c.Contains(e) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Own reports whether the entry belongs to the group leaving its subgroups
+// out. Refining groups narrow down the own entries of their parents, which
+// may themselves include the entries of the refining group.
+func (g *Group) own(e *parse.EntryDecl) bool {
+	if g.Context == Refining && g.parent != nil && !g.parent.own(e) {
+		return false
+	}
+	return g.holds(e)
+}
+
+func (g *Group) holds(e *parse.EntryDecl) bool {
+	switch g.Kind {
+	case AllEntries:
+		return true
+	case Static:
+		return contains(list(e, "groups"), g.Name, true)
+	case Keyword:
+		f, ok := e.Lookup(g.Field)
+		if !ok {
+			return false
+		}
+		if g.Regex {
+			expr := g.Term
+			if !g.CaseSensitive {
+				expr = "(?i)" + expr
+			}
+			re, err := regexp.Compile(expr)
+			return err == nil && re.MatchString(parse.Unquote(f.Value))
+		}
+		return contains(list(e, g.Field), g.Term, g.CaseSensitive)
+	}
+	return false
+}
+
+// Members returns the entries belonging to the group in their order.
+func (g *Group) Members(entries []*parse.EntryDecl) []*parse.EntryDecl {
+	result := []*parse.EntryDecl{}
+	for _, e := range entries {
+		if g.Contains(e) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Assign adds the static group to the groups field of the entry unless the
+// entry names it already.
+func Assign(e *parse.EntryDecl, g *Group) error {
+	if g.Kind != Static {
+		return ErrNotStatic
+	}
+	names := list(e, "groups")
+	if contains(names, g.Name, true) {
+		return nil
+	}
+	setList(e, append(names, g.Name))
+	return nil
+}
+
+// Unassign removes the static group from the groups field of the entry,
+// dropping the field once empty, and reports whether the entry named it.
+func Unassign(e *parse.EntryDecl, g *Group) bool {
+	names := list(e, "groups")
+	kept := names[:0]
+	for _, n := range names {
+		if n != g.Name {
+			kept = append(kept, n)
+		}
+	}
+	if len(kept) == len(names) {
+		return false
+	}
+	if len(kept) == 0 {
+		e.Del("groups")
+	} else {
+		setList(e, kept)
+	}
+	return true
+}
+
+// List splits the comma-separated value of the field.
+func list(e *parse.EntryDecl, key string) []string {
+	f, ok := e.Lookup(key)
+	if !ok {
+		return nil
+	}
+	result := []string{}
+	for _, s := range strings.Split(parse.Unquote(f.Value), ",") {
+		if s = strings.TrimSpace(s); s != `` {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func setList(e *parse.EntryDecl, names []string) {
+	e.Set("groups", parse.Quote(strings.Join(names, ", ")))
+}
+
+func contains(list []string, s string, caseSensitive bool) bool {
+	for _, v := range list {
+		if v == s || !caseSensitive && strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// String serializes the tree rooted at the group as the lines of the
+// grouping metadata, each ending with a semicolon.
+func (g *Group) String() string {
+	var b strings.Builder
+	g.write(&b, 0)
+	return b.String()
+}
+
+func (g *Group) write(b *strings.Builder, depth int) {
+	fmt.Fprintf(b, "%d %s;\n", depth, escape(g.definition()))
+	for _, c := range g.Children {
+		c.write(b, depth+1)
+	}
+}
+
+// Definition joins the type and the fields of the group, escaping the
+// values within it. The result is escaped once more as a whole.
+func (g *Group) definition() string {
+	var fields []string
+	switch g.Kind {
+	case AllEntries:
+		return kindNames[AllEntries] + ":"
+	case Static:
+		fields = []string{g.Name, context(g.Context), flag(g.Expanded), g.Color, g.Icon, g.Description}
+	case Keyword:
+		fields = []string{
+			g.Name, context(g.Context), g.Field, g.Term, flag(g.CaseSensitive), flag(g.Regex),
+			flag(g.Expanded), g.Color, g.Icon, g.Description,
+		}
+	default:
+		return g.Raw
+	}
+	var b strings.Builder
+	b.WriteString(kindNames[g.Kind] + ":")
+	for _, f := range fields {
+		b.WriteString(escape(f) + ";")
+	}
+	return b.String()
+}
+
+func context(c Context) string { return strconv.Itoa(int(c)) }
+
+func flag(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `;`, `\;`).Replace(s)
+}
+
+// Split splits the text at the unescaped semicolons, unescaping the parts.
+// Text after the last semicolon is returned as the last part when not blank.
+func split(s string) []string {
+	result := []string{}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+		case c == ';':
+			result = append(result, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if strings.TrimSpace(b.String()) != `` {
+		result = append(result, b.String())
+	}
+	return result
+}
+
+// ParseTree parses the lines of the grouping metadata into a group tree.
+func ParseTree(meta string) (*Group, error) {
+	var root *Group
+	path := []*Group{}
+	for _, line := range split(meta) {
+		line = strings.TrimSpace(line)
+		depthText, def, ok := strings.Cut(line, " ")
+		depth, err := strconv.Atoi(depthText)
+		if !ok || err != nil || depth < 0 || depth > len(path) {
+			return nil, fmt.Errorf("jabref: invalid group %q", line)
+		}
+		g, err := parseGroup(def)
+		if err != nil {
+			return nil, err
+		}
+		if depth == 0 {
+			if root != nil {
+				return nil, fmt.Errorf("jabref: second root group %q", line)
+			}
+			root = g
+		} else {
+			path[depth-1].Add(g)
+		}
+		path = append(path[:depth], g)
+	}
+	if root == nil {
+		return NewTree(), nil
+	}
+	return root, nil
+}
+
+func parseGroup(def string) (*Group, error) {
+	typ, rest, ok := strings.Cut(def, ":")
+	if !ok {
+		return nil, fmt.Errorf("jabref: invalid group %q", def)
+	}
+	fields := split(rest)
+	field := func(i int) string {
+		if i < len(fields) {
+			return fields[i]
+		}
+		return ``
+	}
+	g := &Group{Name: field(0)}
+	switch typ {
+	case kindNames[AllEntries]:
+		return &Group{Kind: AllEntries}, nil
+	case kindNames[Static]:
+		g.Kind = Static
+		g.Context, g.Expanded = parseContext(field(1)), field(2) != "0"
+		g.Color, g.Icon, g.Description = field(3), field(4), field(5)
+	case kindNames[Keyword]:
+		g.Kind = Keyword
+		g.Context, g.Field, g.Term = parseContext(field(1)), field(2), field(3)
+		g.CaseSensitive, g.Regex, g.Expanded = field(4) == "1", field(5) == "1", field(6) != "0"
+		g.Color, g.Icon, g.Description = field(7), field(8), field(9)
+	default:
+		g.Kind, g.Raw = Other, def
+	}
+	return g, nil
+}
+
+func parseContext(s string) Context {
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= int(Including) {
+		return Context(n)
+	}
+	return Independent
+}
@@ -0,0 +1,118 @@
+package jabref
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/bibxtest"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+const grouping = `@article{a, title = {X}}
+
+@Comment{jabref-meta: databaseType:bibtex;}
+
+@Comment{jabref-meta: grouping:
+0 AllEntriesGroup:;
+1 StaticGroup:Favorites\;2\;1\;0x8a8a8aff\;\;\;;
+2 KeywordGroup:ML\;0\;keywords\;machine learning\;0\;0\;1\;\;\;\;;
+2 StaticGroup:Semi\\\;colon\;1\;0\;\;\;Has a \\\\ backslash\;;
+1 SearchGroup:Recent\;0\;year >= 2020\;0\;0\;1\;\;\;\;;
+}
+`
+
+func TestReadGroups(t *testing.T) {
+	root, err := ReadGroups([]byte(grouping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fav := root.Find("Favorites")
+	if fav == nil || fav.Kind != Static || fav.Context != Including || !fav.Expanded || fav.Color != "0x8a8a8aff" {
+		t.Fatalf("have %+v; want the Favorites static group", fav)
+	}
+	ml := root.Find("ML")
+	if ml == nil || ml.Kind != Keyword || ml.Field != "keywords" || ml.Term != "machine learning" {
+		t.Fatalf("have %+v; want the ML keyword group", ml)
+	}
+	semi := root.Find("Semi;colon")
+	if semi == nil || semi.Context != Refining || semi.Description != `Has a \ backslash` {
+		t.Fatalf("have %+v; want the escaped static group", semi)
+	}
+	if g := root.Find("Recent"); g == nil || g.Kind != Other {
+		t.Fatalf("have %+v; want the search group kept", g)
+	}
+	// The groups are written back the way JabRef writes them.
+	have := string(WriteGroups([]byte(grouping), root))
+	if have != grouping {
+		t.Errorf("have\n%s\nwant\n%s", have, grouping)
+	}
+}
+
+func TestContains(t *testing.T) {
+	root, err := ReadGroups([]byte(grouping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	es := []*parse.EntryDecl{
+		bibxtest.Entry("article", "fav", "groups", "{Favorites}"),
+		bibxtest.Entry("article", "ml", "keywords", "{NLP, Machine Learning}"),
+		bibxtest.Entry("article", "semi", "groups", "{Semi;colon}"),
+		bibxtest.Entry("article", "both", "groups", "{Semi;colon, Favorites}"),
+		bibxtest.Entry("article", "none", "keywords", "{machine learning theory}"),
+	}
+	cases := []struct {
+		group string
+		want  string
+	}{
+		{"Favorites", "fav ml both"},
+		{"ML", "ml"},
+		{"Semi;colon", "both"},
+		{"Recent", ``},
+	}
+	for _, c := range cases {
+		t.Run(c.group, func(t *testing.T) {
+			keys := []string{}
+			for _, e := range root.Find(c.group).Members(es) {
+				keys = append(keys, e.CiteKey)
+			}
+			if have := strings.Join(keys, " "); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestAssign(t *testing.T) {
+	root := NewTree()
+	fav := root.Add(&Group{Name: "Favorites", Kind: Static, Expanded: true})
+	ml := root.Add(&Group{Name: "ML", Kind: Keyword, Field: "keywords", Term: "ml"})
+	e := bibxtest.Entry("article", "a", "groups", "{Other}")
+	if err := Assign(e, fav); err != nil {
+		t.Fatal(err)
+	}
+	Assign(e, fav)
+	if f, _ := e.Get("groups"); f.Value != "{Other, Favorites}" {
+		t.Errorf("have %s; want {Other, Favorites}", f.Value)
+	}
+	if err := Assign(e, ml); err != ErrNotStatic {
+		t.Errorf("have %v; want %v", err, ErrNotStatic)
+	}
+	if !Unassign(e, fav) || Unassign(e, fav) {
+		t.Error("have the group unassigned other than once")
+	}
+	Unassign(e, &Group{Name: "Other", Kind: Static})
+	if _, ok := e.Get("groups"); ok {
+		t.Error("have an empty groups field")
+	}
+	want := `0 AllEntriesGroup:;
+1 StaticGroup:Favorites\;0\;1\;\;\;\;;
+1 KeywordGroup:ML\;0\;keywords\;ml\;0\;0\;0\;\;\;\;;
+`
+	if have := root.String(); have != want {
+		t.Errorf("have %q; want %q", have, want)
+	}
+	src := WriteGroups([]byte("@misc{k}"), root)
+	if have, want := string(src), "@misc{k}\n\n@Comment{jabref-meta: grouping:\n"+want+"}\n"; have != want {
+		t.Errorf("have %q; want %q", have, want)
+	}
+}
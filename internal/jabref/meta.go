@@ -0,0 +1,63 @@
+package jabref
+
+import (
+	"regexp"
+)
+
+var groupingStart = regexp.MustCompile(`(?i)@comment\s*\{\s*jabref-meta:\s*grouping:`)
+
+// Find locates the grouping metadata in the source and returns the offsets
+// of the declaration holding it, or -1 when there is none.
+func find(src []byte) (start, end int) {
+	loc := groupingStart.FindIndex(src)
+	if loc == nil {
+		return -1, -1
+	}
+	depth := 0
+	for i := loc[0]; i < len(src); i++ {
+		switch src[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return loc[0], i + 1
+			}
+		}
+	}
+	return loc[0], len(src)
+}
+
+// ReadGroups parses the group tree kept in the metadata of the source. It
+// returns an empty tree when the source has no groups.
+func ReadGroups(src []byte) (*Group, error) {
+	start, end := find(src)
+	if start < 0 {
+		return NewTree(), nil
+	}
+	body := src[groupingStart.FindIndex(src)[1]:end]
+	if len(body) > 0 && body[len(body)-1] == '}' {
+		body = body[:len(body)-1]
+	}
+	return ParseTree(string(body))
+}
+
+// WriteGroups returns the source with its grouping metadata replaced by the
+// group tree, or with the tree appended when the source has none.
+func WriteGroups(src []byte, root *Group) []byte {
+	block := "@Comment{jabref-meta: grouping:\n" + root.String() + "}"
+	start, end := find(src)
+	result := make([]byte, 0, len(src)+len(block)+2)
+	if start < 0 {
+		result = append(result, src...)
+		if len(result) > 0 && result[len(result)-1] != '\n' {
+			result = append(result, '\n')
+		}
+		if len(result) > 0 {
+			result = append(result, '\n')
+		}
+		return append(append(result, block...), '\n')
+	}
+	result = append(result, src[:start]...)
+	result = append(result, block...)
+	return append(result, src[end:]...)
+}
@@ -0,0 +1,6 @@
+/*
+Journal package converts journal names between their full and abbreviated
+forms using a list of known journals and ISO 4 word abbreviations in the
+style of the List of Title Word Abbreviations (LTWA).
+*/
+package journal
@@ -0,0 +1,218 @@
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+const (
+	Full Style = iota
+	Abbreviated
+)
+
+// Style is the form journal names are written in.
+type Style uint8
+
+// Articles omitted from abbreviated names.
+var articles = map[string]bool{
+	"a": true, "an": true, "das": true, "der": true, "die": true, "el": true,
+	"la": true, "le": true, "les": true, "the": true,
+}
+
+// Conjunctions and prepositions omitted from abbreviated names unless they
+// start the name.
+var stopWords = map[string]bool{
+	"and": true, "at": true, "de": true, "des": true, "du": true, "et": true,
+	"for": true, "für": true, "in": true, "of": true, "on": true, "und": true,
+	"y": true,
+}
+
+//go:embed journals.txt
+var journals []byte
+
+//go:embed ltwa.txt
+var ltwa []byte
+
+// Builtin holds the common journals and title word abbreviations shipped
+// with the package.
+var Builtin = mustParse(journals, ltwa)
+
+func mustParse(journals, words []byte) *List {
+	l, err := Parse(journals, words)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// List maps known journal names onto their abbreviations and title words
+// onto their abbreviated forms used for journals missing from the list.
+type List struct {
+	abbrev map[string]string // full names by normalized name
+	full   map[string]string // full names by normalized abbreviation
+	names  map[string]string // abbreviations by full name
+	words  []word
+}
+
+type word struct {
+	stem   string
+	prefix bool
+	abbrev string
+}
+
+// Parse reads the list of journals and the list of title words, both holding
+// one `name;abbreviation` pair per line. Empty lines and lines starting with
+// `#` are skipped. A title word ending with a hyphen matches all words
+// starting with the stem.
+func Parse(journals, words []byte) (*List, error) {
+	l := &List{
+		abbrev: make(map[string]string),
+		full:   make(map[string]string),
+		names:  make(map[string]string),
+	}
+	err := pairs(journals, func(name, abbrev string) {
+		l.abbrev[normalize(name)] = name
+		l.full[normalizeAbbrev(abbrev)] = name
+		l.names[name] = abbrev
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = pairs(words, func(stem, abbrev string) {
+		w := word{stem: strings.ToLower(stem), abbrev: abbrev}
+		if strings.HasSuffix(w.stem, "-") {
+			w.stem, w.prefix = strings.TrimSuffix(w.stem, "-"), true
+		}
+		l.words = append(l.words, w)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Longer stems are tried first so that the most specific one wins.
+	sort.SliceStable(l.words, func(i, j int) bool {
+		return len(l.words[i].stem) > len(l.words[j].stem)
+	})
+	return l, nil
+}
+
+func pairs(data []byte, fn func(name, abbrev string)) error {
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == `` || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, abbrev, ok := strings.Cut(line, ";")
+		if !ok {
+			return fmt.Errorf("journal: line %d: missing abbreviation", n)
+		}
+		fn(strings.TrimSpace(name), strings.TrimSpace(abbrev))
+	}
+	return s.Err()
+}
+
+// Abbreviate returns the ISO 4 abbreviation of the journal name using the
+// built-in list.
+func Abbreviate(journal string) string { return Builtin.Abbreviate(journal) }
+
+// Expand returns the full name of the abbreviated journal using the built-in
+// list.
+func Expand(abbrev string) (string, bool) { return Builtin.Expand(abbrev) }
+
+// Abbreviate returns the abbreviation of a known journal. The names of other
+// journals are abbreviated word by word: articles are dropped, and so are
+// conjunctions and prepositions unless they start the name, while the listed
+// title words are replaced with their abbreviations. Names made of a single
+// significant word are left as they are.
+func (l *List) Abbreviate(journal string) string {
+	if name, ok := l.abbrev[normalize(journal)]; ok {
+		return l.names[name]
+	}
+	if _, ok := l.full[normalizeAbbrev(journal)]; ok {
+		return journal
+	}
+	words := strings.Fields(journal)
+	result := []string{}
+	for i, w := range words {
+		if lower := strings.ToLower(w); articles[lower] || i > 0 && stopWords[lower] {
+			continue
+		}
+		result = append(result, l.word(w))
+	}
+	if len(result) < 2 {
+		return journal
+	}
+	return strings.Join(result, " ")
+}
+
+// Expand returns the full name of a known journal given its abbreviation or
+// its full name. Unknown abbreviations are not expanded.
+func (l *List) Expand(abbrev string) (string, bool) {
+	if name, ok := l.full[normalizeAbbrev(abbrev)]; ok {
+		return name, true
+	}
+	name, ok := l.abbrev[normalize(abbrev)]
+	return name, ok
+}
+
+// Word abbreviates a single title word keeping its trailing punctuation.
+func (l *List) word(w string) string {
+	core := strings.TrimRight(w, ".,:;")
+	trail := w[len(core):]
+	lower := strings.ToLower(core)
+	for _, lw := range l.words {
+		if lower == lw.stem || lw.prefix && strings.HasPrefix(lower, lw.stem) {
+			return lw.abbrev + strings.TrimPrefix(trail, ".")
+		}
+	}
+	return w
+}
+
+// Normalize folds the case and the white space of the name.
+func normalize(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// NormalizeAbbrev also drops the periods so that abbreviations written
+// without them as in MEDLINE match.
+func normalizeAbbrev(abbrev string) string {
+	return normalize(strings.ReplaceAll(abbrev, ".", " "))
+}
+
+// Apply rewrites the journal field of the entry in the style using the list
+// and reports whether it was changed. Fields referring to @string macros are
+// left untouched as are unknown abbreviations when expanding.
+func (l *List) Apply(e *parse.EntryDecl, s Style) bool {
+	f, ok := e.Get("journal")
+	if !ok {
+		return false
+	}
+	name := parse.Unquote(f.Value)
+	if name == f.Value {
+		return false
+	}
+	v := name
+	switch s {
+	case Abbreviated:
+		v = l.Abbreviate(name)
+	default:
+		if full, ok := l.Expand(name); ok {
+			v = full
+		}
+	}
+	if v == name {
+		return false
+	}
+	if strings.HasPrefix(f.Value, `"`) {
+		f.Value = `"` + v + `"`
+	} else {
+		f.Value = parse.Quote(v)
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+package journal
+
+import (
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestAbbreviate(t *testing.T) {
+	cases := []struct {
+		journal string
+		want    string
+	}{
+		{"Physical Review Letters", "Phys. Rev. Lett."},
+		{"physical  review letters", "Phys. Rev. Lett."},
+		{"Journal of the American Medical Association", "JAMA"},
+		{"Phys. Rev. Lett.", "Phys. Rev. Lett."},
+		{"Journal of Computational Biology", "J. Comput. Biol."},
+		{"International Journal of Theoretical Physics", "Int. J. Theor. Phys."},
+		{"The Mathematical Intelligencer", "Math. Intell."},
+		{"Annals of Statistics", "Ann. Stat."},
+		{"Journal", "Journal"},
+		{"The Journal", "The Journal"},
+		{"On the Theory of Sets", "On Theor. Sets"},
+	}
+	for _, c := range cases {
+		t.Run(c.journal, func(t *testing.T) {
+			if have := Abbreviate(c.journal); have != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	cases := []struct {
+		abbrev string
+		want   string
+		ok     bool
+	}{
+		{"Phys. Rev. Lett.", "Physical Review Letters", true},
+		{"Phys Rev Lett", "Physical Review Letters", true},
+		{"J. ACM", "Journal of the ACM", true},
+		{"Nature", "Nature", true},
+		{"J. Comput. Biol.", ``, false},
+	}
+	for _, c := range cases {
+		t.Run(c.abbrev, func(t *testing.T) {
+			have, ok := Expand(c.abbrev)
+			if have != c.want || ok != c.ok {
+				t.Errorf("have %q, %t; want %q, %t", have, ok, c.want, c.ok)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	cases := []struct {
+		name  string
+		value string
+		style Style
+		want  string
+	}{
+		{"abbreviate", "{Journal of Applied Physics}", Abbreviated, "{J. Appl. Phys.}"},
+		{"quoted", `"Nature Physics"`, Abbreviated, `"Nat. Phys."`},
+		{"expand", "{Rev. Mod. Phys.}", Full, "{Reviews of Modern Physics}"},
+		{"unknown", "{J. Comput. Biol.}", Full, "{J. Comput. Biol.}"},
+		{"macro", "prl", Abbreviated, "prl"},
+		{"concatenation", "{Physical} # { Review}", Abbreviated, "{Physical} # { Review}"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := &parse.EntryDecl{Fields: []*parse.FieldStmt{{Key: "journal", Value: c.value}}}
+			changed := Builtin.Apply(e, c.style)
+			if have := e.Fields[0].Value; have != c.want || changed != (c.value != c.want) {
+				t.Errorf("have %s, %t; want %s", have, changed, c.want)
+			}
+		})
+	}
+}
+
+func TestParseErr(t *testing.T) {
+	if _, err := Parse([]byte("Nature\n"), nil); err == nil {
+		t.Error("missing abbreviation accepted")
+	}
+}
@@ -0,0 +1,5 @@
+/*
+Jsonl package writes BibTeX entries as JSON Lines, one JSON object per entry,
+for processing with tools such as jq.
+*/
+package jsonl
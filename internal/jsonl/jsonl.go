@@ -0,0 +1,105 @@
+package jsonl
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// Object is the JSON representation of a single entry. Field keys are
+// lowercased and values are stripped of their outermost delimiters.
+type Object struct {
+	Type   string            `json:"type"`
+	Key    string            `json:"key"`
+	Fields map[string]string `json:"fields"`
+}
+
+// Writer writes entries as JSON Lines, one object per line.
+type Writer struct {
+	enc *json.Encoder
+}
+
+// NewWriter creates a new Writer writing to w.
+func NewWriter(w io.Writer) *Writer {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &Writer{enc}
+}
+
+// Write writes the entry as a single line. Nothing is buffered, so the line
+// reaches the underlying writer before Write returns.
+func (w *Writer) Write(e *parse.EntryDecl) error {
+	return w.enc.Encode(NewObject(e))
+}
+
+// NewObject converts the entry into its JSON representation.
+func NewObject(e *parse.EntryDecl) Object {
+	o := Object{Type: e.Name, Key: e.CiteKey, Fields: make(map[string]string)}
+	for _, f := range e.Fields {
+		k := strings.ToLower(f.Key)
+		if _, ok := o.Fields[k]; !ok {
+			o.Fields[k] = parse.Unquote(f.Value)
+		}
+	}
+	return o
+}
+
+// Entry converts the object back into an entry with the fields sorted by
+// name. Numbers are left bare while other values are enclosed in braces.
+func (o Object) Entry() *parse.EntryDecl {
+	e := &parse.EntryDecl{Name: o.Type, CiteKey: o.Key, Comments: &parse.CommentGroupExpr{}}
+	keys := make([]string, 0, len(o.Fields))
+	for k := range o.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := o.Fields[k]
+		if !isNumber(v) {
+			v = parse.Quote(v)
+		}
+		e.Fields = append(e.Fields, &parse.FieldStmt{Key: k, Value: v})
+	}
+	return e
+}
+
+func isNumber(s string) bool {
+	if s == `` {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Write writes all the entries as JSON Lines.
+func Write(w io.Writer, entries []*parse.EntryDecl) error {
+	jw := NewWriter(w)
+	for _, e := range entries {
+		if err := jw.Write(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stream writes every entry to w as soon as the parser emits it. It returns
+// the error that stopped the parser, if any, once the entries before it are
+// written.
+func Stream(w io.Writer, p *parse.Parser) error {
+	jw := NewWriter(w)
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		if e, ok := n.(*parse.EntryDecl); ok {
+			if err := jw.Write(e); err != nil {
+				return err
+			}
+		}
+	}
+	return p.Err()
+}
@@ -0,0 +1,105 @@
+package jsonl
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+var testBib = `
+@article{Cohen1963,
+  Author  = "P. J. C{\"o}hen",
+  title   = {The <independence> & the hypothesis},
+  year    = 1963
+}
+@string{pnas = "PNAS"}
+@book{Babington1993,
+  author = {Peter Babington}
+}
+`
+
+var wantJSONL = `{"type":"article","key":"Cohen1963","fields":{"author":"P. J. C{\\\"o}hen","title":"The <independence> & the hypothesis","year":"1963"}}
+{"type":"book","key":"Babington1993","fields":{"author":"Peter Babington"}}
+`
+
+func TestStream(t *testing.T) {
+	p := parse.NewParser(scan.NewScanner(scan.NewReader(strings.NewReader(testBib))))
+	var b strings.Builder
+	if err := Stream(&b, p); err != nil {
+		t.Fatal(err)
+	}
+	if have := b.String(); have != wantJSONL {
+		t.Errorf("have %s; want %s", have, wantJSONL)
+	}
+}
+
+func TestStreamInvalid(t *testing.T) {
+	src := testBib + "@misc{broken, title = {unclosed\n"
+	p := parse.NewParser(scan.NewScanner(scan.NewReader(strings.NewReader(src))))
+	var b strings.Builder
+	if err := Stream(&b, p); err == nil {
+		t.Error("have nil; want error")
+	}
+	if have := b.String(); have != wantJSONL {
+		t.Errorf("have %s; want %s", have, wantJSONL)
+	}
+}
+
+// LineWriter records the number of writes to check that nothing is buffered.
+type lineWriter struct {
+	lines []string
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.lines = append(w.lines, string(p))
+	return len(p), nil
+}
+
+func TestWriterUnbuffered(t *testing.T) {
+	w := &lineWriter{}
+	jw := NewWriter(w)
+	e := &parse.EntryDecl{Name: "misc", CiteKey: "a"}
+	for i := 1; i <= 3; i++ {
+		if err := jw.Write(e); err != nil {
+			t.Fatal(err)
+		}
+		if len(w.lines) != i {
+			t.Errorf("have %d lines; want %d", len(w.lines), i)
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, io.ErrShortWrite }
+
+func TestWriteError(t *testing.T) {
+	err := Write(failingWriter{}, []*parse.EntryDecl{{Name: "misc", CiteKey: "a"}})
+	if err != io.ErrShortWrite {
+		t.Errorf("have %v; want %v", err, io.ErrShortWrite)
+	}
+}
+
+func TestObjectEntry(t *testing.T) {
+	o := Object{
+		Type:   "book",
+		Key:    "Babington1993",
+		Fields: map[string]string{"year": "1993", "title": "The title", "author": "Peter Babington"},
+	}
+	want := &parse.EntryDecl{
+		Name:     "book",
+		CiteKey:  "Babington1993",
+		Comments: &parse.CommentGroupExpr{},
+		Fields: []*parse.FieldStmt{
+			{Key: "author", Value: "{Peter Babington}"},
+			{Key: "title", Value: "{The title}"},
+			{Key: "year", Value: "1993"},
+		},
+	}
+	if have := o.Entry(); !have.Eq(want) {
+		t.Errorf("have %v; want %v", have, want)
+	}
+}
@@ -0,0 +1,6 @@
+/*
+Keywords package manages the keywords of the entries: it splits and joins the
+keyword lists, brings keywords to a canonical form, counts the keywords used
+across a library and renames them in bulk.
+*/
+package keywords
@@ -0,0 +1,189 @@
+package keywords
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// Field is the field holding the keywords of an entry.
+const Field = "keywords"
+
+// Split breaks the keyword list on the commas and semicolons found outside
+// of braces. Keywords are trimmed, and empty ones are dropped.
+func Split(list string) []string {
+	result := []string{}
+	depth, start := 0, 0
+	add := func(k string) {
+		if k = strings.Join(strings.Fields(k), " "); k != `` {
+			result = append(result, k)
+		}
+	}
+	for i, r := range list {
+		switch {
+		case r == '{':
+			depth++
+		case r == '}' && depth > 0:
+			depth--
+		case depth == 0 && (r == ',' || r == ';'):
+			add(list[start:i])
+			start = i + 1
+		}
+	}
+	add(list[start:])
+	return result
+}
+
+// Join writes the keywords as a comma-separated list.
+func Join(list []string) string {
+	return strings.Join(list, ", ")
+}
+
+// Canonical lowercases the keyword outside of braces, which protect
+// acronyms and proper names, collapses its spaces and strips its final
+// period.
+func Canonical(k string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range strings.Join(strings.Fields(k), " ") {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+		if depth == 0 {
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return strings.TrimSuffix(b.String(), ".")
+}
+
+// Merge returns the keywords of the lists in order, leaving out those with
+// the same canonical form as an earlier one.
+func Merge(lists ...[]string) []string {
+	result := []string{}
+	seen := map[string]bool{}
+	for _, list := range lists {
+		for _, k := range list {
+			if c := Canonical(k); !seen[c] {
+				seen[c] = true
+				result = append(result, k)
+			}
+		}
+	}
+	return result
+}
+
+// Get returns the keywords of the entry.
+func Get(e *parse.EntryDecl) []string {
+	if f, ok := e.Lookup(Field); ok {
+		return Split(parse.Unquote(f.Value))
+	}
+	return []string{}
+}
+
+// Set replaces the keywords of the entry, removing the field when the list
+// is empty.
+func Set(e *parse.EntryDecl, list []string) {
+	if len(list) > 0 {
+		if f, ok := e.Lookup(Field); ok {
+			f.Value = parse.Quote(Join(list))
+			return
+		}
+		e.Set(Field, parse.Quote(Join(list)))
+		return
+	}
+	fields := e.Fields[:0]
+	for _, f := range e.Fields {
+		if parse.CanonicalKey(f.Key) != Field {
+			fields = append(fields, f)
+		}
+	}
+	e.Fields = fields
+}
+
+// Normalize rewrites the keywords of the entry in their canonical form as
+// a comma-separated list without duplicates. It reports whether the field
+// changed.
+func Normalize(e *parse.EntryDecl) bool {
+	f, ok := e.Lookup(Field)
+	if !ok {
+		return false
+	}
+	list := Get(e)
+	for i, k := range list {
+		list[i] = Canonical(k)
+	}
+	old := f.Value
+	Set(e, Merge(list))
+	if f, ok := e.Lookup(Field); ok {
+		return f.Value != old
+	}
+	return true
+}
+
+// Rename replaces the keywords of the entries matching the keys of the map
+// in their canonical form with the values. Keywords renamed to nothing are
+// removed, and keywords renamed to one already present are merged into it.
+// It returns the number of entries changed.
+func Rename(entries []*parse.EntryDecl, renames map[string]string) int {
+	byCanonical := make(map[string]string, len(renames))
+	for from, to := range renames {
+		byCanonical[Canonical(from)] = to
+	}
+	count := 0
+	for _, e := range entries {
+		list := Get(e)
+		changed := false
+		result := []string{}
+		for _, k := range list {
+			to, ok := byCanonical[Canonical(k)]
+			if !ok {
+				result = append(result, k)
+				continue
+			}
+			changed = true
+			if to != `` {
+				result = append(result, to)
+			}
+		}
+		if changed {
+			Set(e, Merge(result))
+			count++
+		}
+	}
+	return count
+}
+
+// Count is the number of entries a keyword is used by.
+type Count struct {
+	Keyword string `json:"keyword"`
+	Count   int    `json:"count"`
+}
+
+// Vocabulary counts the entries using each keyword, telling keywords apart
+// by their canonical form, which they are reported in. The most used
+// keywords come first, and keywords used equally often are sorted.
+func Vocabulary(entries []*parse.EntryDecl) []Count {
+	counts := map[string]int{}
+	for _, e := range entries {
+		for _, k := range Merge(Get(e)) {
+			counts[Canonical(k)]++
+		}
+	}
+	result := make([]Count, 0, len(counts))
+	for k, n := range counts {
+		result = append(result, Count{k, n})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Keyword < result[j].Keyword
+	})
+	return result
+}
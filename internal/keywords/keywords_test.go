@@ -0,0 +1,118 @@
+package keywords
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mdm-code/bibx/bibxtest"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestSplit(t *testing.T) {
+	cases := []struct {
+		name string
+		list string
+		want []string
+	}{
+		{"commas", "a, b,c", []string{"a", "b", "c"}},
+		{"semicolons", "a; b ;  c d", []string{"a", "b", "c d"}},
+		{"braces", "{Smith, Jones} model, x", []string{"{Smith, Jones} model", "x"}},
+		{"empty", " , ;", []string{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := Split(c.list); !reflect.DeepEqual(have, c.want) {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	cases := []struct {
+		name string
+		k    string
+		want string
+	}{
+		{"lower", "Machine  Learning", "machine learning"},
+		{"protected", "{NLP} Tools.", "{NLP} tools"},
+		{"plain", "graphs", "graphs"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := Canonical(c.k); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		name    string
+		entry   *parse.EntryDecl
+		changed bool
+		want    string
+	}{
+		{"rewritten", bibxtest.Entry("article", "a", "keywords", "{Graphs; trees, graphs.}"), true, "@article{a, keywords = {graphs, trees}}"},
+		{"alias", bibxtest.Entry("article", "a", "keyword", `"NLP"`), true, "@article{a, keyword = {nlp}}"},
+		{"canonical", bibxtest.Entry("article", "a", "keywords", "{graphs, trees}"), false, "@article{a, keywords = {graphs, trees}}"},
+		{"emptied", bibxtest.Entry("article", "a", "keywords", "{ , }", "year", "2001"), true, "@article{a, year = 2001}"},
+		{"none", bibxtest.Entry("article", "a", "year", "2001"), false, "@article{a, year = 2001}"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := Normalize(c.entry); have != c.changed {
+				t.Errorf("have changed %t; want %t", have, c.changed)
+			}
+			if have := c.entry.String(); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestRename(t *testing.T) {
+	es := []*parse.EntryDecl{
+		bibxtest.Entry("article", "a", "keywords", "{ML, statistics}"),
+		bibxtest.Entry("article", "b", "keywords", "{machine learning, ml}"),
+		bibxtest.Entry("article", "c", "keywords", "{Draft}"),
+		bibxtest.Entry("article", "d", "keywords", "{graphs}"),
+	}
+	n := Rename(es, map[string]string{"ml": "machine learning", "draft": ``})
+	if n != 3 {
+		t.Errorf("have %d entries changed; want 3", n)
+	}
+	have := []string{}
+	for _, e := range es {
+		have = append(have, e.String())
+	}
+	want := []string{
+		"@article{a, keywords = {machine learning, statistics}}",
+		"@article{b, keywords = {machine learning}}",
+		"@article{c}",
+		"@article{d, keywords = {graphs}}",
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have %q; want %q", have, want)
+	}
+}
+
+func TestVocabulary(t *testing.T) {
+	es := []*parse.EntryDecl{
+		bibxtest.Entry("article", "a", "keywords", "{Graphs, trees, graphs}"),
+		bibxtest.Entry("article", "b", "keywords", "{graphs; Algorithms}"),
+		bibxtest.Entry("article", "c", "year", "2001"),
+	}
+	want := []Count{{"graphs", 2}, {"algorithms", 1}, {"trees", 1}}
+	if have := Vocabulary(es); !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v; want %v", have, want)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	have := Merge([]string{"a", "B"}, []string{"b", "c"})
+	if want := []string{"a", "B", "c"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %q; want %q", have, want)
+	}
+}
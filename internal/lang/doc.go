@@ -0,0 +1,6 @@
+/*
+Lang package maps between the babel and polyglossia language names used in
+the language and langid fields of BibTeX and BibLaTeX and the BCP 47 tags
+used by CSL.
+*/
+package lang
@@ -0,0 +1,176 @@
+package lang
+
+import "strings"
+
+// Languages pairs babel and polyglossia names with their BCP 47 tags. The
+// first name listed for a tag is the one it maps back onto.
+var languages = []struct {
+	name, tag string
+}{
+	{"english", "en"},
+	{"american", "en-US"},
+	{"usenglish", "en-US"},
+	{"british", "en-GB"},
+	{"ukenglish", "en-GB"},
+	{"australian", "en-AU"},
+	{"canadian", "en-CA"},
+	{"newzealand", "en-NZ"},
+	{"german", "de"},
+	{"ngerman", "de"},
+	{"austrian", "de-AT"},
+	{"naustrian", "de-AT"},
+	{"swissgerman", "de-CH"},
+	{"nswissgerman", "de-CH"},
+	{"french", "fr"},
+	{"francais", "fr"},
+	{"frenchb", "fr"},
+	{"canadien", "fr-CA"},
+	{"acadian", "fr-CA"},
+	{"spanish", "es"},
+	{"italian", "it"},
+	{"portuguese", "pt"},
+	{"portuges", "pt"},
+	{"brazilian", "pt-BR"},
+	{"brazil", "pt-BR"},
+	{"dutch", "nl"},
+	{"polish", "pl"},
+	{"russian", "ru"},
+	{"ukrainian", "uk"},
+	{"czech", "cs"},
+	{"slovak", "sk"},
+	{"slovenian", "sl"},
+	{"slovene", "sl"},
+	{"croatian", "hr"},
+	{"serbian", "sr"},
+	{"bulgarian", "bg"},
+	{"romanian", "ro"},
+	{"hungarian", "hu"},
+	{"swedish", "sv"},
+	{"danish", "da"},
+	{"norwegian", "nb"},
+	{"norsk", "nb"},
+	{"nynorsk", "nn"},
+	{"icelandic", "is"},
+	{"finnish", "fi"},
+	{"estonian", "et"},
+	{"latvian", "lv"},
+	{"lithuanian", "lt"},
+	{"greek", "el"},
+	{"turkish", "tr"},
+	{"catalan", "ca"},
+	{"basque", "eu"},
+	{"galician", "gl"},
+	{"irish", "ga"},
+	{"welsh", "cy"},
+	{"latin", "la"},
+	{"hebrew", "he"},
+	{"arabic", "ar"},
+	{"chinese", "zh"},
+	{"japanese", "ja"},
+	{"korean", "ko"},
+}
+
+var (
+	byName = make(map[string]string)
+	byTag  = make(map[string]string)
+)
+
+func init() {
+	for _, l := range languages {
+		byName[l.name] = l.tag
+		if t := strings.ToLower(l.tag); byTag[t] == `` {
+			byTag[t] = l.name
+		}
+	}
+}
+
+// Tag returns the BCP 47 tag of the babel or polyglossia language name. Well
+// formed tags are returned with their subtags in the canonical case.
+func Tag(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if t, ok := byName[strings.ToLower(s)]; ok {
+		return t, true
+	}
+	if IsTag(s) {
+		return canonical(s), true
+	}
+	return ``, false
+}
+
+// Name returns the babel or polyglossia name of the language with the BCP 47
+// tag. Tags with unknown regions fall back on the name of their primary
+// language. Language names are returned in lower case.
+func Name(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if _, ok := byName[strings.ToLower(s)]; ok {
+		return strings.ToLower(s), true
+	}
+	if !IsTag(s) {
+		return ``, false
+	}
+	t := strings.ToLower(canonical(s))
+	for {
+		if n, ok := byTag[t]; ok {
+			return n, true
+		}
+		i := strings.LastIndexByte(t, '-')
+		if i < 0 {
+			return ``, false
+		}
+		t = t[:i]
+	}
+}
+
+// Valid checks if the string is a known language name or a well-formed BCP
+// 47 tag.
+func Valid(s string) bool {
+	_, ok := Tag(s)
+	return ok
+}
+
+// IsTag checks if the string is a well-formed BCP 47 tag: a primary language
+// subtag of two or three letters followed by subtags of up to eight letters
+// and digits. Underscores are accepted in place of hyphens.
+func IsTag(s string) bool {
+	parts := strings.Split(strings.ReplaceAll(s, "_", "-"), "-")
+	if n := len(parts[0]); n < 2 || n > 3 || !isAlnum(parts[0], false) {
+		return false
+	}
+	for _, p := range parts[1:] {
+		if len(p) < 1 || len(p) > 8 || !isAlnum(p, true) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlnum(s string, digits bool) bool {
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case digits && r >= '0' && r <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Canonical writes the tag with hyphens, a lower-case language, a title-case
+// script and an upper-case region as in `zh-Hant-TW`.
+func canonical(tag string) string {
+	parts := strings.Split(strings.ReplaceAll(tag, "_", "-"), "-")
+	for i, p := range parts {
+		switch {
+		case i == 0:
+			parts[i] = strings.ToLower(p)
+		case len(p) == 4 && isAlnum(p, false):
+			parts[i] = strings.ToUpper(p[:1]) + strings.ToLower(p[1:])
+		case len(p) == 2 && isAlnum(p, false):
+			parts[i] = strings.ToUpper(p)
+		default:
+			parts[i] = strings.ToLower(p)
+		}
+	}
+	return strings.Join(parts, "-")
+}
@@ -0,0 +1,55 @@
+package lang
+
+import "testing"
+
+func TestTag(t *testing.T) {
+	cases := []struct {
+		s    string
+		want string
+		ok   bool
+	}{
+		{"english", "en", true},
+		{"NGerman", "de", true},
+		{"brazil", "pt-BR", true},
+		{"en-us", "en-US", true},
+		{"zh_hant_tw", "zh-Hant-TW", true},
+		{"es-419", "es-419", true},
+		{"englsh", ``, false},
+		{"e", ``, false},
+		{"en-", ``, false},
+	}
+	for _, c := range cases {
+		t.Run(c.s, func(t *testing.T) {
+			have, ok := Tag(c.s)
+			if have != c.want || ok != c.ok {
+				t.Errorf("have %q, %t; want %q, %t", have, ok, c.want, c.ok)
+			}
+		})
+	}
+}
+
+func TestName(t *testing.T) {
+	cases := []struct {
+		s    string
+		want string
+		ok   bool
+	}{
+		{"en", "english", true},
+		{"en-US", "american", true},
+		{"en_gb", "british", true},
+		{"de-DE", "german", true},
+		{"de-CH-1996", "swissgerman", true},
+		{"pt-BR", "brazilian", true},
+		{"French", "french", true},
+		{"xx", ``, false},
+		{"klingon", ``, false},
+	}
+	for _, c := range cases {
+		t.Run(c.s, func(t *testing.T) {
+			have, ok := Name(c.s)
+			if have != c.want || ok != c.ok {
+				t.Errorf("have %q, %t; want %q, %t", have, ok, c.want, c.ok)
+			}
+		})
+	}
+}
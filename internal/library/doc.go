@@ -0,0 +1,6 @@
+/*
+Library package holds the declarations of a bibliography shared between
+goroutines, such as the handlers of a server, and applies edits spanning
+many entries as a single atomic batch.
+*/
+package library
@@ -0,0 +1,208 @@
+package library
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mdm-code/bibx/internal/lint"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+// Index maps the entries of a library by their cite keys, DOIs, author
+// surnames and years. Each bucket lists the entries in the order they were
+// added, an entry replaced keeping the place of the one it replaces, so an
+// index built anew follows the order of the library. Years holds the years
+// with entries in ascending order for range lookups. A clone shares the maps
+// and the years with the index it was cloned from until it changes them.
+type index struct {
+	keys    buckets[string]
+	dois    buckets[string]
+	authors buckets[string]
+	years   buckets[int]
+	sorted  []int
+	shared  bool // sorted is shared with another index
+}
+
+func newIndex(nodes []parse.Node) *index {
+	x := &index{
+		keys:    newBuckets[string](),
+		dois:    newBuckets[string](),
+		authors: newBuckets[string](),
+		years:   newBuckets[int](),
+	}
+	for _, n := range nodes {
+		if e, ok := n.(*parse.EntryDecl); ok {
+			x.replace(nil, e)
+		}
+	}
+	return x
+}
+
+// Clone returns a copy of the index that can be changed without affecting
+// the index. The parts of the index are copied once the copy changes them.
+func (x *index) clone() *index {
+	return &index{
+		keys:    x.keys.share(),
+		dois:    x.dois.share(),
+		authors: x.authors.share(),
+		years:   x.years.share(),
+		sorted:  x.sorted,
+		shared:  true,
+	}
+}
+
+// Replace indexes e in place of old. Either may be nil to only add or only
+// remove an entry.
+func (x *index) replace(old, e *parse.EntryDecl) {
+	oldTerms, newTerms := terms(old), terms(e)
+	x.keys.replace(oldTerms.keys, newTerms.keys, old, e)
+	x.dois.replace(oldTerms.dois, newTerms.dois, old, e)
+	x.authors.replace(oldTerms.authors, newTerms.authors, old, e)
+	for _, y := range x.years.replace(oldTerms.years, newTerms.years, old, e) {
+		if x.shared {
+			x.sorted, x.shared = append([]int{}, x.sorted...), false
+		}
+		i := sort.SearchInts(x.sorted, y)
+		switch {
+		case len(x.years.m[y]) == 0 && i < len(x.sorted) && x.sorted[i] == y:
+			x.sorted = append(x.sorted[:i], x.sorted[i+1:]...)
+		case len(x.years.m[y]) > 0 && (i == len(x.sorted) || x.sorted[i] != y):
+			x.sorted = append(x.sorted[:i], append([]int{y}, x.sorted[i:]...)...)
+		}
+	}
+}
+
+// Terms are the index keys of an entry.
+type entryTerms struct {
+	keys, dois, authors []string
+	years               []int
+}
+
+func terms(e *parse.EntryDecl) entryTerms {
+	t := entryTerms{}
+	if e == nil {
+		return t
+	}
+	t.keys = []string{e.CiteKey}
+	if f, ok := e.Get("doi"); ok {
+		if doi := lint.NormalizeDOI(parse.Unquote(f.Value)); doi != `` {
+			t.dois = []string{doi}
+		}
+	}
+	people, err := e.Authors()
+	if errors.Is(err, parse.ErrMissingField) {
+		people, _ = e.Editors()
+	}
+	seen := make(map[string]bool)
+	for _, n := range people {
+		if s := surname(n.Last); !n.IsOthers() && s != `` && !seen[s] {
+			seen[s] = true
+			t.authors = append(t.authors, s)
+		}
+	}
+	if y, err := e.Year(); err == nil {
+		t.years = []int{y}
+	}
+	return t
+}
+
+// Surname folds the surname to lower-case ASCII letters and digits so that
+// spellings differing in TeX markup, diacritics or punctuation match.
+func surname(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, strings.ToLower(tex.Fold(tex.Decode(s))))
+}
+
+// Buckets maps index keys onto the entries under them. The lists are never
+// changed in place, so that clones may share them, and the map itself is
+// copied before the first change of a clone.
+type buckets[K comparable] struct {
+	m      map[K][]*parse.EntryDecl
+	shared bool // m is shared with another index
+}
+
+func newBuckets[K comparable]() buckets[K] {
+	return buckets[K]{m: make(map[K][]*parse.EntryDecl)}
+}
+
+// Share returns buckets sharing the map of b until they change.
+func (b buckets[K]) share() buckets[K] {
+	return buckets[K]{m: b.m, shared: true}
+}
+
+// Own copies the map of b unless b is its only user.
+func (b *buckets[K]) own() {
+	if !b.shared {
+		return
+	}
+	m := make(map[K][]*parse.EntryDecl, len(b.m))
+	for k, es := range b.m {
+		m[k] = es
+	}
+	b.m, b.shared = m, false
+}
+
+// Replace moves the entry old under the keys from to the entry e under the
+// keys to, and returns the keys whose buckets were emptied or created.
+func (b *buckets[K]) replace(from, to []K, old, e *parse.EntryDecl) []K {
+	changed := []K{}
+	if len(from) == 0 && len(to) == 0 {
+		return changed
+	}
+	b.own()
+	for _, k := range from {
+		es := b.m[k]
+		i := position(es, old)
+		if i < 0 {
+			continue
+		}
+		if contains(to, k) {
+			es = append([]*parse.EntryDecl{}, es...)
+			es[i] = e
+			b.m[k] = es
+			continue
+		}
+		es = append(es[:i:i], es[i+1:]...)
+		if len(es) == 0 {
+			delete(b.m, k)
+			changed = append(changed, k)
+			continue
+		}
+		b.m[k] = es
+	}
+	for _, k := range to {
+		if contains(from, k) && position(b.m[k], e) >= 0 {
+			continue
+		}
+		if len(b.m[k]) == 0 {
+			changed = append(changed, k)
+		}
+		b.m[k] = append(b.m[k][:len(b.m[k]):len(b.m[k])], e)
+	}
+	return changed
+}
+
+func position(es []*parse.EntryDecl, e *parse.EntryDecl) int {
+	for i := range es {
+		if es[i] == e {
+			return i
+		}
+	}
+	return -1
+}
+
+func contains[K comparable](ks []K, k K) bool {
+	for _, x := range ks {
+		if x == k {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package library
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/bibxtest"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func entry(key, year, doi, author string) *parse.EntryDecl {
+	fields := []string{}
+	for _, f := range [][2]string{{"author", author}, {"year", year}, {"doi", doi}} {
+		if f[1] != `` {
+			fields = append(fields, f[0], "{"+f[1]+"}")
+		}
+	}
+	return bibxtest.Entry("article", key, fields...)
+}
+
+func citeKeys(es []*parse.EntryDecl) string {
+	ks := []string{}
+	for _, e := range es {
+		ks = append(ks, e.CiteKey)
+	}
+	return strings.Join(ks, " ")
+}
+
+func TestIndex(t *testing.T) {
+	l := New([]parse.Node{
+		entry("a", "1999", "10.1/A", "G{\\\"o}del, Kurt and Cohen, Paul"),
+		entry("b", "2001", ``, "Paul Cohen"),
+		entry("c", "1999", "https://doi.org/10.1/a", "Turing, Alan"),
+	})
+	var before *Tx
+	l.View(func(tx *Tx) error {
+		before = &Tx{nodes: tx.nodes, idx: tx.idx}
+		return nil
+	})
+	err := l.Batch(func(tx *Tx) error {
+		tx.Put(entry("a", "2000", "10.1/b", "Gödel, Kurt"))
+		tx.Delete("c")
+		return tx.Put(entry("d", "1999", ``, "Alan Turing and others"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name         string
+		tx           *Tx
+		lookup       func(tx *Tx) []*parse.EntryDecl
+		before, want string
+	}{
+		{"doi", nil, func(tx *Tx) []*parse.EntryDecl { return tx.ByDOI("doi:10.1/a") }, "a c", ``},
+		{"new-doi", nil, func(tx *Tx) []*parse.EntryDecl { return tx.ByDOI("10.1/B") }, ``, "a"},
+		{"author", nil, func(tx *Tx) []*parse.EntryDecl { return tx.ByAuthor("godel") }, "a", "a"},
+		{"author-dropped", nil, func(tx *Tx) []*parse.EntryDecl { return tx.ByAuthor("Cohen") }, "a b", "b"},
+		{"author-added", nil, func(tx *Tx) []*parse.EntryDecl { return tx.ByAuthor("Turing") }, "c", "d"},
+		{"year", nil, func(tx *Tx) []*parse.EntryDecl { return tx.ByYear(1999) }, "a c", "d"},
+		{"years", nil, func(tx *Tx) []*parse.EntryDecl { return tx.Years(1990, 2000) }, "a c", "d a"},
+		{"years-empty", nil, func(tx *Tx) []*parse.EntryDecl { return tx.Years(2002, 2010) }, ``, ``},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := citeKeys(c.lookup(before)); have != c.before {
+				t.Errorf("before: have %q; want %q", have, c.before)
+			}
+			l.View(func(tx *Tx) error {
+				if have := citeKeys(c.lookup(tx)); have != c.want {
+					t.Errorf("have %q; want %q", have, c.want)
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestIndexRebuilt(t *testing.T) {
+	l := New(nil)
+	for i := 0; i < 200; i++ {
+		key := fmt.Sprintf("k%d", i%13)
+		l.Batch(func(tx *Tx) error {
+			if i%5 == 0 {
+				_, err := tx.Delete(key)
+				return err
+			}
+			return tx.Put(entry(key, fmt.Sprint(1990+i%7), fmt.Sprintf("10.1/%d", i%3), fmt.Sprintf("Name%d, A and Name%d, B", i%4, i%6)))
+		})
+	}
+	if have, want := dump(l.idx), dump(newIndex(l.Nodes())); have != want {
+		t.Errorf("have index\n%s\nwant\n%s", have, want)
+	}
+}
+
+// Dump lists the buckets of the index with their cite keys sorted.
+func dump(x *index) string {
+	lines := []string{fmt.Sprint(x.sorted)}
+	add := func(name string, k any, es []*parse.EntryDecl) {
+		ks := strings.Fields(citeKeys(es))
+		sort.Strings(ks)
+		lines = append(lines, fmt.Sprintf("%s %v: %s", name, k, strings.Join(ks, " ")))
+	}
+	for k, es := range x.keys.m {
+		add("key", k, es)
+	}
+	for k, es := range x.dois.m {
+		add("doi", k, es)
+	}
+	for k, es := range x.authors.m {
+		add("author", k, es)
+	}
+	for k, es := range x.years.m {
+		add("year", k, es)
+	}
+	sort.Strings(lines[1:])
+	return strings.Join(lines, "\n")
+}
+
+func TestIndexShared(t *testing.T) {
+	l := New([]parse.Node{entry("a", "1999", "10.1/a", "Cohen, Paul")})
+	before := l.idx
+	l.Batch(func(tx *Tx) error { return tx.Put(entry("b", ``, ``, ``)) })
+	if l.idx.keys.shared || len(l.idx.keys.m) != 2 {
+		t.Errorf("have keys %v; want a copy with both keys", l.idx.keys.m)
+	}
+	if !l.idx.dois.shared || !l.idx.authors.shared || !l.idx.years.shared || !l.idx.shared {
+		t.Error("have the unchanged indexes copied; want them shared")
+	}
+	if len(before.keys.m) != 1 {
+		t.Errorf("have keys %v before; want them unchanged", before.keys.m)
+	}
+}
+
+func TestRelated(t *testing.T) {
+	a := entry("a", "1999", "10.1/a", "Cohen, Paul")
+	l := New([]parse.Node{
+		a,
+		entry("b", "2001", ``, "Paul Cohen and Turing, Alan"),
+		entry("c", "1999", "10.1/A", "Gödel, Kurt"),
+		entry("d", "1999", ``, "Turing, Alan"),
+	})
+	l.View(func(tx *Tx) error {
+		if have, want := citeKeys(tx.Related(a)), "c b"; have != want {
+			t.Errorf("have %q; want %q", have, want)
+		}
+		return nil
+	})
+}
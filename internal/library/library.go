@@ -0,0 +1,238 @@
+package library
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/mdm-code/bibx/internal/lint"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// ErrReadOnly is returned by the changes attempted in a read-only
+// transaction.
+var ErrReadOnly = errors.New("library: read-only transaction")
+
+// Library holds the declarations of a bibliography. Any number of readers
+// may look at it at once, while a writer has it to itself. The declarations
+// are never changed in place: a batch works on a copy of the list, which
+// replaces the current one once the batch succeeds, so that the lists handed
+// out before stay as they were. The entries themselves are shared and must
+// be replaced rather than modified. The entries are indexed by their cite
+// keys, DOIs, author surnames and years, and the indexes are kept up to date
+// by the batches along with the declarations.
+type Library struct {
+	// Commit is called with the declarations a batch results in before
+	// they become current, while the writer still holds the library. An
+	// error aborts the batch. It is typically used to write them to disk.
+	Commit func(nodes []parse.Node) error
+	// Journal is called with the changes of a batch before Commit, so that
+	// they can be logged ahead of writing the declarations. An error aborts
+	// the batch.
+	Journal func(changes []Change) error
+
+	mu    sync.RWMutex
+	nodes []parse.Node
+	idx   *index
+}
+
+// New returns a library of the declarations.
+func New(nodes []parse.Node) *Library {
+	return &Library{nodes: nodes, idx: newIndex(nodes)}
+}
+
+// Nodes returns the current declarations.
+func (l *Library) Nodes() []parse.Node {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.nodes
+}
+
+// View calls fn with a read-only transaction over the current declarations
+// and returns its error.
+func (l *Library) View(fn func(tx *Tx) error) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return fn(&Tx{nodes: l.nodes, idx: l.idx})
+}
+
+// Batch calls fn with a transaction over the declarations that no other
+// reader or writer sees until it is done. The changes fn makes become
+// current together if it returns nil and Commit, if set, succeeds; otherwise
+// they are all discarded and the error is returned.
+func (l *Library) Batch(fn func(tx *Tx) error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	tx := &Tx{nodes: l.nodes, idx: l.idx, writable: true}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if !tx.changed {
+		return nil
+	}
+	if l.Journal != nil {
+		if err := l.Journal(tx.changes); err != nil {
+			return err
+		}
+	}
+	if l.Commit != nil {
+		if err := l.Commit(tx.nodes); err != nil {
+			return err
+		}
+	}
+	l.nodes, l.idx = tx.nodes, tx.idx
+	return nil
+}
+
+// Tx is a transaction over the declarations of a library. It is valid only
+// within the function it is passed to. The lists of entries it returns must
+// not be modified.
+type Tx struct {
+	nodes    []parse.Node
+	idx      *index
+	writable bool
+	changed  bool // nodes and idx are copies owned by the transaction
+	changes  []Change
+}
+
+// Change is an entry put into the library under its cite key, or the
+// deletion of the entry under the key when Entry is nil.
+type Change struct {
+	Key   string
+	Entry *parse.EntryDecl
+}
+
+// Nodes returns the declarations as the transaction sees them.
+func (tx *Tx) Nodes() []parse.Node { return tx.nodes }
+
+// Entries returns the entries as the transaction sees them.
+func (tx *Tx) Entries() []*parse.EntryDecl {
+	result := []*parse.EntryDecl{}
+	for _, n := range tx.nodes {
+		if e, ok := n.(*parse.EntryDecl); ok {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Get returns the entry under the cite key, or nil if there is none. Of
+// entries sharing a cite key the first one is returned.
+func (tx *Tx) Get(key string) *parse.EntryDecl {
+	if es := tx.idx.keys.m[key]; len(es) > 0 {
+		return es[0]
+	}
+	return nil
+}
+
+// ByDOI returns the entries with the DOI, which is compared in lower case
+// without resolver URL and doi: prefixes.
+func (tx *Tx) ByDOI(doi string) []*parse.EntryDecl {
+	return tx.idx.dois.m[lint.NormalizeDOI(doi)]
+}
+
+// ByAuthor returns the entries with an author, or an editor in the absence
+// of authors, of the surname. Surnames are compared without TeX markup,
+// diacritics, punctuation and case.
+func (tx *Tx) ByAuthor(name string) []*parse.EntryDecl {
+	return tx.idx.authors.m[surname(name)]
+}
+
+// ByYear returns the entries with the numeric year.
+func (tx *Tx) ByYear(year int) []*parse.EntryDecl {
+	return tx.idx.years.m[year]
+}
+
+// Years returns the entries with a numeric year between from and to
+// inclusive, ordered by year.
+func (tx *Tx) Years(from, to int) []*parse.EntryDecl {
+	result := []*parse.EntryDecl{}
+	for i := sort.SearchInts(tx.idx.sorted, from); i < len(tx.idx.sorted) && tx.idx.sorted[i] <= to; i++ {
+		result = append(result, tx.idx.years.m[tx.idx.sorted[i]]...)
+	}
+	return result
+}
+
+// Related returns the other entries sharing a DOI or an author surname with
+// e, each once, in the order they were found.
+func (tx *Tx) Related(e *parse.EntryDecl) []*parse.EntryDecl {
+	result := []*parse.EntryDecl{}
+	seen := map[*parse.EntryDecl]bool{e: true}
+	add := func(es []*parse.EntryDecl) {
+		for _, r := range es {
+			if !seen[r] {
+				seen[r] = true
+				result = append(result, r)
+			}
+		}
+	}
+	t := terms(e)
+	for _, doi := range t.dois {
+		add(tx.idx.dois.m[doi])
+	}
+	for _, s := range t.authors {
+		add(tx.idx.authors.m[s])
+	}
+	return result
+}
+
+// Put replaces the entry under the cite key of e with e, keeping its place,
+// or appends e if there is none.
+func (tx *Tx) Put(e *parse.EntryDecl) error {
+	if err := tx.own(); err != nil {
+		return err
+	}
+	old := tx.Get(e.CiteKey)
+	if i := tx.position(old); i >= 0 {
+		tx.nodes[i] = e
+	} else {
+		tx.nodes = append(tx.nodes, e)
+	}
+	tx.idx.replace(old, e)
+	tx.changes = append(tx.changes, Change{Key: e.CiteKey, Entry: e})
+	return nil
+}
+
+// Delete removes the entry under the cite key and reports whether there was
+// one.
+func (tx *Tx) Delete(key string) (bool, error) {
+	if err := tx.own(); err != nil {
+		return false, err
+	}
+	old := tx.Get(key)
+	i := tx.position(old)
+	if i < 0 {
+		return false, nil
+	}
+	tx.nodes = append(tx.nodes[:i], tx.nodes[i+1:]...)
+	tx.idx.replace(old, nil)
+	tx.changes = append(tx.changes, Change{Key: key})
+	return true, nil
+}
+
+// Own copies the declarations before the first change of the transaction
+// so that the list seen by the readers stays intact.
+func (tx *Tx) own() error {
+	if !tx.writable {
+		return ErrReadOnly
+	}
+	if !tx.changed {
+		tx.nodes = append(make([]parse.Node, 0, len(tx.nodes)+1), tx.nodes...)
+		tx.idx = tx.idx.clone()
+		tx.changed = true
+	}
+	return nil
+}
+
+// Position returns the index of the entry among the declarations.
+func (tx *Tx) position(e *parse.EntryDecl) int {
+	if e == nil {
+		return -1
+	}
+	for i, n := range tx.nodes {
+		if n == parse.Node(e) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,184 @@
+package library
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+const testBib = `@string{jx = {J. X}}
+@article{one, journal = jx, year = 1993}
+@book{two, title = {The title}}
+`
+
+func testLibrary(t *testing.T) *Library {
+	t.Helper()
+	p := parse.NewParser(scan.NewScanner(scan.NewReader(strings.NewReader(testBib))))
+	nodes := []parse.Node{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		nodes = append(nodes, n)
+	}
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return New(nodes)
+}
+
+func keys(l *Library) string {
+	ks := []string{}
+	l.View(func(tx *Tx) error {
+		for _, e := range tx.Entries() {
+			ks = append(ks, e.CiteKey)
+		}
+		return nil
+	})
+	return strings.Join(ks, " ")
+}
+
+func TestBatch(t *testing.T) {
+	errAbort := errors.New("abort")
+	cases := []struct {
+		name   string
+		commit error
+		fn     func(tx *Tx) error
+		want   string
+		err    error
+	}{
+		{
+			name: "put-and-delete",
+			fn: func(tx *Tx) error {
+				tx.Put(&parse.EntryDecl{Name: "misc", CiteKey: "three"})
+				tx.Put(&parse.EntryDecl{Name: "misc", CiteKey: "one"})
+				tx.Delete("two")
+				return nil
+			},
+			want: "one three",
+		},
+		{
+			name: "aborted",
+			fn: func(tx *Tx) error {
+				tx.Delete("one")
+				return errAbort
+			},
+			want: "one two",
+			err:  errAbort,
+		},
+		{
+			name:   "commit-failed",
+			commit: errAbort,
+			fn: func(tx *Tx) error {
+				tx.Delete("one")
+				return nil
+			},
+			want: "one two",
+			err:  errAbort,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			l := testLibrary(t)
+			before := l.Nodes()
+			l.Commit = func([]parse.Node) error { return c.commit }
+			if err := l.Batch(c.fn); err != c.err {
+				t.Errorf("have %v; want %v", err, c.err)
+			}
+			if have := keys(l); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+			if e, ok := before[1].(*parse.EntryDecl); !ok || e.CiteKey != "one" || len(before) != 3 {
+				t.Errorf("earlier declarations changed: %v", before)
+			}
+		})
+	}
+}
+
+func TestJournal(t *testing.T) {
+	errFull := errors.New("disk full")
+	cases := []struct {
+		name    string
+		journal error
+		want    string
+		commits int
+	}{
+		{"logged", nil, "put three, put one, delete two", 1},
+		{"failed", errFull, "put three, put one, delete two", 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			l := testLibrary(t)
+			logged, commits := []string{}, 0
+			l.Journal = func(changes []Change) error {
+				for _, ch := range changes {
+					if ch.Entry == nil {
+						logged = append(logged, "delete "+ch.Key)
+					} else {
+						logged = append(logged, "put "+ch.Key)
+					}
+				}
+				return c.journal
+			}
+			l.Commit = func([]parse.Node) error {
+				commits++
+				return nil
+			}
+			err := l.Batch(func(tx *Tx) error {
+				tx.Put(&parse.EntryDecl{Name: "misc", CiteKey: "three"})
+				tx.Put(&parse.EntryDecl{Name: "misc", CiteKey: "one"})
+				tx.Delete("two")
+				tx.Delete("four")
+				return nil
+			})
+			if err != c.journal {
+				t.Errorf("have %v; want %v", err, c.journal)
+			}
+			if have := strings.Join(logged, ", "); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+			if commits != c.commits {
+				t.Errorf("have %d commits; want %d", commits, c.commits)
+			}
+		})
+	}
+}
+
+func TestView(t *testing.T) {
+	l := testLibrary(t)
+	err := l.View(func(tx *Tx) error {
+		if e := tx.Get("two"); e == nil || e.Name != "book" {
+			t.Errorf("have %v; want the book", e)
+		}
+		return tx.Put(&parse.EntryDecl{Name: "misc", CiteKey: "three"})
+	})
+	if err != ErrReadOnly {
+		t.Errorf("have %v; want %v", err, ErrReadOnly)
+	}
+	if ok, err := (&Tx{}).Delete("one"); ok || err != ErrReadOnly {
+		t.Errorf("have %v, %v; want false, %v", ok, err, ErrReadOnly)
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	l := testLibrary(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			l.Batch(func(tx *Tx) error {
+				tx.Delete("one")
+				return tx.Put(&parse.EntryDecl{Name: "misc", CiteKey: "one"})
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			if have := keys(l); have != "one two" && have != "two one" {
+				t.Errorf("have %s in the middle of a batch", have)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,55 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// FieldAlias flags fields written under an alias or a common misspelling of
+// their canonical name listed in parse.Aliases.
+type FieldAlias struct{}
+
+// Name returns the name of the rule.
+func (FieldAlias) Name() string { return "field-alias" }
+
+// Check reports the aliased fields. They are renamed by the fix unless the
+// entry already has a field under the canonical name.
+func (r FieldAlias) Check(nodes []parse.Node) []Finding {
+	result := []Finding{}
+	for _, n := range nodes {
+		e, ok := n.(*parse.EntryDecl)
+		if !ok {
+			continue
+		}
+		for _, f := range e.Fields {
+			c := parse.CanonicalKey(f.Key)
+			if c == strings.ToLower(f.Key) {
+				continue
+			}
+			finding := Finding{
+				Rule:    r.Name(),
+				Key:     e.CiteKey,
+				Field:   f.Key,
+				Pos:     f.Pos,
+				Message: fmt.Sprintf("field %s is an alias of %s", f.Key, c),
+			}
+			if _, taken := e.Get(c); taken {
+				finding.Message += " which is also present"
+			} else {
+				finding.Fix = rename(f, c)
+			}
+			result = append(result, finding)
+		}
+	}
+	return result
+}
+
+// Rename fixes the problem by renaming the field.
+func rename(f *parse.FieldStmt, key string) func([]parse.Node) []parse.Node {
+	return func(nodes []parse.Node) []parse.Node {
+		f.Key = key
+		return nodes
+	}
+}
@@ -0,0 +1,14 @@
+package lint
+
+import "testing"
+
+func TestFieldAlias(t *testing.T) {
+	src := "@book{a,\n  adress = {Paris},\n  sortkey = {A}\n}\n\n@book{b,\n  address = {Rome},\n  location = {Milan}\n}\n"
+	want := []string{
+		`a: adress: field adress is an alias of address (field-alias)`,
+		`a: sortkey: field sortkey is an alias of key (field-alias)`,
+		`b: location: field location is an alias of address which is also present (field-alias)`,
+	}
+	fixed := "@book{a,\n  address = {Paris},\n  key     = {A}\n}\n\n@book{b,\n  address  = {Rome},\n  location = {Milan}\n}\n"
+	checkRule(t, FieldAlias{}, src, want, fixed)
+}
@@ -0,0 +1,49 @@
+package lint
+
+import (
+	"fmt"
+	"unicode"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+// NonASCII flags raw non-ASCII characters in field values, which classic
+// BibTeX engines fail to sort and case-convert correctly. The fix encodes
+// them as TeX markup.
+type NonASCII struct{}
+
+// Name returns the name of the rule.
+func (NonASCII) Name() string { return "non-ascii" }
+
+// Check reports each field value with non-ASCII characters once.
+func (r NonASCII) Check(nodes []parse.Node) []Finding {
+	result := []Finding{}
+	for _, f := range fields(nodes) {
+		c, ok := firstNonASCII(f.stmt.Value)
+		if !ok {
+			continue
+		}
+		finding := Finding{
+			Rule:    r.Name(),
+			Key:     f.key,
+			Field:   f.stmt.Key,
+			Pos:     f.stmt.Pos,
+			Message: fmt.Sprintf("non-ASCII character %q needs TeX escaping", c),
+		}
+		if v := tex.Encode(f.stmt.Value); v != f.stmt.Value {
+			finding.Fix = rewrite(f.stmt, tex.Encode)
+		}
+		result = append(result, finding)
+	}
+	return result
+}
+
+func firstNonASCII(s string) (rune, bool) {
+	for _, c := range s {
+		if c > unicode.MaxASCII {
+			return c, true
+		}
+	}
+	return 0, false
+}
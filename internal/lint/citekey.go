@@ -0,0 +1,79 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/citekey"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// KeySchemes are the named cite key conventions: authoryear for keys like
+// Cohen1963 or Cohen1963a as made by citekey.Generate, and ascii for keys
+// limited to characters safe in all TeX setups and file names.
+var KeySchemes = map[string]*regexp.Regexp{
+	"authoryear": regexp.MustCompile(`^[A-Za-z]+[0-9]{4}[a-z]?$`),
+	"ascii":      regexp.MustCompile(`^[A-Za-z0-9_:.-]+$`),
+}
+
+// CiteKey flags entries whose cite keys do not match the Pattern of the
+// naming convention. A generated author-year key is suggested when it matches
+// the pattern, and with Rekey set the fix renames the entry and updates the
+// crossref fields referring to it.
+type CiteKey struct {
+	Pattern *regexp.Regexp
+	Rekey   bool
+}
+
+// Name returns the name of the rule.
+func (CiteKey) Name() string { return "cite-key" }
+
+// Check reports each entry with a non-conforming cite key.
+func (r CiteKey) Check(nodes []parse.Node) []Finding {
+	taken := make(map[string]bool)
+	for _, n := range nodes {
+		if e, ok := n.(*parse.EntryDecl); ok {
+			taken[e.CiteKey] = true
+		}
+	}
+	result := []Finding{}
+	for _, n := range nodes {
+		e, ok := n.(*parse.EntryDecl)
+		if !ok || r.Pattern.MatchString(e.CiteKey) {
+			continue
+		}
+		finding := Finding{
+			Rule:    r.Name(),
+			Key:     e.CiteKey,
+			Pos:     e.Pos,
+			Message: fmt.Sprintf("cite key does not match %s", r.Pattern),
+		}
+		if key := citekey.Unique(citekey.Generate(e), taken); r.Pattern.MatchString(key) {
+			finding.Message += fmt.Sprintf("; suggested %s", key)
+			if r.Rekey {
+				finding.Fix = rekey(e, key)
+			}
+		}
+		result = append(result, finding)
+	}
+	return result
+}
+
+// Rekey fixes the problem by renaming the entry and the references to it.
+func rekey(e *parse.EntryDecl, key string) func([]parse.Node) []parse.Node {
+	return func(nodes []parse.Node) []parse.Node {
+		old := e.CiteKey
+		for _, n := range nodes {
+			other, ok := n.(*parse.EntryDecl)
+			if !ok {
+				continue
+			}
+			if f, ok := other.Get("crossref"); ok && strings.EqualFold(parse.Unquote(f.Value), old) {
+				f.Value = requote(f.Value, key)
+			}
+		}
+		e.CiteKey = key
+		return nodes
+	}
+}
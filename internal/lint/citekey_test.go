@@ -0,0 +1,37 @@
+package lint
+
+import "testing"
+
+func TestCiteKey(t *testing.T) {
+	src := `@inproceedings{Cohen1963, author = {Paul Cohen}, year = 1963, crossref = {proc}}
+@proceedings{proc, editor = {Kurt Gödel}, year = 1963}
+@misc{Gödel1931, author = {Kurt Gödel}, year = 1931}
+@misc{x, title = {Untitled}}
+`
+	want := []string{
+		`proc: cite key does not match ^[A-Za-z]+[0-9]{4}[a-z]?$; suggested Godel1963 (cite-key)`,
+		`Gödel1931: cite key does not match ^[A-Za-z]+[0-9]{4}[a-z]?$; suggested Godel1931 (cite-key)`,
+		`x: cite key does not match ^[A-Za-z]+[0-9]{4}[a-z]?$ (cite-key)`,
+	}
+	fixed := `@inproceedings{Cohen1963,
+  author   = {Paul Cohen},
+  year     = 1963,
+  crossref = {Godel1963}
+}
+
+@proceedings{Godel1963,
+  editor = {Kurt Gödel},
+  year   = 1963
+}
+
+@misc{Godel1931,
+  author = {Kurt Gödel},
+  year   = 1931
+}
+
+@misc{x,
+  title = {Untitled}
+}
+`
+	checkRule(t, CiteKey{Pattern: KeySchemes["authoryear"], Rekey: true}, src, want, fixed)
+}
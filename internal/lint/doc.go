@@ -0,0 +1,6 @@
+/*
+Lint package checks BibTeX declarations for common mistakes. Each problem is
+reported by a rule as a finding, and the findings that can be corrected
+automatically carry a fix applied to the declarations.
+*/
+package lint
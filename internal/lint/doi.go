@@ -0,0 +1,68 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// DOI prefixes stripped before the comparison of DOIs.
+var doiPrefixes = []string{
+	"https://doi.org/",
+	"http://doi.org/",
+	"https://dx.doi.org/",
+	"http://dx.doi.org/",
+	"doi:",
+}
+
+// DuplicateDOI flags entries sharing the same DOI, which almost always means
+// that a reference is listed twice under different cite keys. DOIs are
+// compared case-insensitively with resolver URL and doi: prefixes removed.
+type DuplicateDOI struct{}
+
+// Name returns the name of the rule.
+func (DuplicateDOI) Name() string { return "duplicate-doi" }
+
+// Check reports each group of entries with the same DOI on its second and
+// later entries, listing the key of the first one.
+func (r DuplicateDOI) Check(nodes []parse.Node) []Finding {
+	result := []Finding{}
+	first := make(map[string]string)
+	for _, n := range nodes {
+		e, ok := n.(*parse.EntryDecl)
+		if !ok {
+			continue
+		}
+		f, ok := e.Get("doi")
+		if !ok {
+			continue
+		}
+		doi := NormalizeDOI(parse.Unquote(f.Value))
+		if doi == `` {
+			continue
+		}
+		if key, ok := first[doi]; ok {
+			result = append(result, Finding{
+				Rule:    r.Name(),
+				Key:     e.CiteKey,
+				Field:   f.Key,
+				Pos:     f.Pos,
+				Message: fmt.Sprintf("DOI %s is also used by %s", doi, key),
+			})
+			continue
+		}
+		first[doi] = e.CiteKey
+	}
+	return result
+}
+
+// NormalizeDOI returns the DOI in lower case without resolver URL and doi:
+// prefixes.
+func NormalizeDOI(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, p := range doiPrefixes {
+		s = strings.TrimPrefix(s, p)
+	}
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,35 @@
+package lint
+
+import "testing"
+
+func TestDuplicateDOI(t *testing.T) {
+	src := `@misc{a, doi = {10.1073/pnas.50.6.1143}}
+@misc{b, doi = {https://doi.org/10.1073/PNAS.50.6.1143}}
+@misc{c, doi = {10.1000/xyz}}
+@misc{d, DOI = "doi:10.1073/pnas.50.6.1143"}
+@misc{e, title = {No DOI}}
+`
+	want := []string{
+		`b: doi: DOI 10.1073/pnas.50.6.1143 is also used by a (duplicate-doi)`,
+		`d: DOI: DOI 10.1073/pnas.50.6.1143 is also used by a (duplicate-doi)`,
+	}
+	checkRule(t, DuplicateDOI{}, src, want, ``)
+}
+
+func TestNormalizeDOI(t *testing.T) {
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{"10.1000/XYZ", "10.1000/xyz"},
+		{" https://dx.doi.org/10.1000/xyz ", "10.1000/xyz"},
+		{"DOI:10.1000/xyz", "10.1000/xyz"},
+	}
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			if have := NormalizeDOI(c.input); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
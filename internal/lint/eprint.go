@@ -0,0 +1,113 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/arxiv"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+var classPattern = regexp.MustCompile(`^[a-z-]+(\.[A-Za-z-]+)?$`)
+
+// Eprint flags arXiv eprint fields that are malformed or inconsistent with
+// their archiveprefix (or eprinttype) and primaryclass companions, and fills
+// in the companions that can be derived from the identifier.
+type Eprint struct{}
+
+// Name returns the name of the rule.
+func (Eprint) Name() string { return "arxiv-eprint" }
+
+// Check reports the problems with the eprint fields of the entries. Eprints
+// of other archives are not checked.
+func (r Eprint) Check(nodes []parse.Node) []Finding {
+	result := []Finding{}
+	for _, n := range nodes {
+		e, ok := n.(*parse.EntryDecl)
+		if !ok {
+			continue
+		}
+		result = append(result, r.check(e)...)
+	}
+	return result
+}
+
+func (r Eprint) check(e *parse.EntryDecl) []Finding {
+	result := []Finding{}
+	report := func(f *parse.FieldStmt, msg string, fix func([]parse.Node) []parse.Node) {
+		finding := Finding{Rule: r.Name(), Key: e.CiteKey, Pos: e.Pos, Message: msg, Fix: fix}
+		if f != nil {
+			finding.Field, finding.Pos = f.Key, f.Pos
+		}
+		result = append(result, finding)
+	}
+	eprint, hasEprint := e.Get("eprint")
+	prefix, hasPrefix := e.Get("archiveprefix")
+	if typ, ok := e.Get("eprinttype"); ok {
+		if hasPrefix && !strings.EqualFold(value(typ), value(prefix)) {
+			report(typ, fmt.Sprintf("eprinttype %s disagrees with archiveprefix %s", value(typ), value(prefix)), nil)
+			return result
+		}
+		prefix, hasPrefix = typ, true
+	}
+	class, hasClass := e.Get("primaryclass")
+	if c, ok := e.Get("eprintclass"); ok && !hasClass {
+		class, hasClass = c, true
+	}
+	if !hasEprint {
+		if hasPrefix && strings.EqualFold(value(prefix), "arxiv") {
+			report(prefix, fmt.Sprintf("%s without eprint", prefix.Key), nil)
+		}
+		if hasClass {
+			report(class, fmt.Sprintf("%s without eprint", class.Key), nil)
+		}
+		return result
+	}
+	if hasPrefix && !strings.EqualFold(value(prefix), "arxiv") {
+		return result
+	}
+	id := value(eprint)
+	if !arxiv.IsID(id) {
+		if hasPrefix {
+			report(eprint, fmt.Sprintf("invalid arXiv identifier %s", id), nil)
+		}
+		return result
+	}
+	if bare := strings.TrimSpace(id[strings.IndexByte(id, ':')+1:]); bare != id {
+		report(eprint, fmt.Sprintf("arXiv identifier %s has a redundant prefix", id), rewrite(eprint, func(old string) string {
+			return requote(old, bare)
+		}))
+		id = bare
+	}
+	if !hasPrefix {
+		report(eprint, "arXiv eprint without archiveprefix", addField(e, "archiveprefix", "{arXiv}"))
+	}
+	// Old-style identifiers such as hep-th/9901001 carry their class.
+	implied, _, old := strings.Cut(id, "/")
+	switch {
+	case hasClass && !classPattern.MatchString(value(class)):
+		report(class, fmt.Sprintf("invalid arXiv class %s", value(class)), nil)
+	case hasClass && old && !strings.EqualFold(value(class), implied):
+		report(class, fmt.Sprintf("primary class %s disagrees with identifier %s", value(class), id), nil)
+	case !hasClass && old:
+		report(eprint, "arXiv eprint without primaryclass", addField(e, "primaryclass", parse.Quote(implied)))
+	}
+	return result
+}
+
+// Value returns the trimmed field value stripped of its delimiters.
+func value(f *parse.FieldStmt) string {
+	return strings.TrimSpace(parse.Unquote(f.Value))
+}
+
+// AddField fixes the problem by adding the field to the entry unless it has
+// been added in the meantime.
+func addField(e *parse.EntryDecl, key, v string) func([]parse.Node) []parse.Node {
+	return func(nodes []parse.Node) []parse.Node {
+		if _, ok := e.Get(key); !ok {
+			e.Set(key, v)
+		}
+		return nodes
+	}
+}
@@ -0,0 +1,38 @@
+package lint
+
+import "testing"
+
+func TestEprint(t *testing.T) {
+	src := `@misc{ok, eprint = {2101.00001}, archiveprefix = {arXiv}, primaryclass = {cs.CL}}
+@misc{old, eprint = {hep-th/9901001}, archiveprefix = {arXiv}, primaryclass = {hep-th}}
+@misc{hal, eprint = {hal-01234567}, archiveprefix = {HAL}}
+@misc{bad, eprint = {2101.1}, eprinttype = {arxiv}}
+@misc{prefix, eprint = {arXiv:2101.00001}, archiveprefix = {arXiv}}
+@misc{missing, eprint = {2101.00001}}
+@misc{class, eprint = {math.GT/0309136}, archiveprefix = {arXiv}, primaryclass = {math.AG}}
+@misc{invalid, eprint = {2101.00001}, archiveprefix = {arXiv}, primaryclass = {Computer Science}}
+@misc{orphan, primaryclass = {cs.CL}}
+@misc{conflict, eprint = {2101.00001}, archiveprefix = {arXiv}, eprinttype = {hal}}
+`
+	want := []string{
+		`bad: eprint: invalid arXiv identifier 2101.1 (arxiv-eprint)`,
+		`prefix: eprint: arXiv identifier arXiv:2101.00001 has a redundant prefix (arxiv-eprint)`,
+		`missing: eprint: arXiv eprint without archiveprefix (arxiv-eprint)`,
+		`class: primaryclass: primary class math.AG disagrees with identifier math.GT/0309136 (arxiv-eprint)`,
+		`invalid: primaryclass: invalid arXiv class Computer Science (arxiv-eprint)`,
+		`orphan: primaryclass: primaryclass without eprint (arxiv-eprint)`,
+		`conflict: eprinttype: eprinttype hal disagrees with archiveprefix arXiv (arxiv-eprint)`,
+	}
+	checkRule(t, Eprint{}, src, want, ``)
+}
+
+func TestEprintFix(t *testing.T) {
+	src := "@misc{a,\n  eprint = {arXiv:hep-th/9901001}\n}\n"
+	want := []string{
+		`a: eprint: arXiv identifier arXiv:hep-th/9901001 has a redundant prefix (arxiv-eprint)`,
+		`a: eprint: arXiv eprint without archiveprefix (arxiv-eprint)`,
+		`a: eprint: arXiv eprint without primaryclass (arxiv-eprint)`,
+	}
+	fixed := "@misc{a,\n  eprint        = {hep-th/9901001},\n  archiveprefix = {arXiv},\n  primaryclass  = {hep-th}\n}\n"
+	checkRule(t, Eprint{}, src, want, fixed)
+}
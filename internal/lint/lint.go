@@ -0,0 +1,193 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/report"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+// Rule checks the declarations of a document.
+type Rule interface {
+	Name() string
+	Check(nodes []parse.Node) []Finding
+}
+
+// Finding is a problem reported by a rule. Key holds the cite key of the
+// entry or the name of the @string macro the problem was found in, and Field
+// the name of the offending field if there is one. Pos is the position of
+// the field or the declaration. Fix is nil unless the problem can be
+// corrected automatically, and it returns nil when it finds nothing left to
+// correct by the time it is applied.
+type Finding struct {
+	Rule    string
+	Key     string
+	Field   string
+	Pos     scan.Pos
+	Message string
+	Fix     func(nodes []parse.Node) []parse.Node
+}
+
+// String formats the finding as `key: field: message (rule)`.
+func (f Finding) String() string {
+	if f.Field == `` {
+		return fmt.Sprintf("%s: %s (%s)", f.Key, f.Message, f.Rule)
+	}
+	return fmt.Sprintf("%s: %s: %s (%s)", f.Key, f.Field, f.Message, f.Rule)
+}
+
+// Report converts the finding into a warning of a report.
+func (f Finding) Report() report.Finding {
+	return report.Finding{
+		Severity: report.Warning,
+		Rule:     f.Rule,
+		Pos:      f.Pos,
+		Key:      f.Key,
+		Field:    f.Field,
+		Message:  f.Message,
+	}
+}
+
+// DefaultRules returns the rules applied when no other rules are chosen,
+// configured with their default options, for files processed by BibTeX.
+func DefaultRules() []Rule { return BackendRules(BibTeX) }
+
+// BackendRules returns the default rules for files processed by the
+// backend. FieldAlias is left out for biber, which knows the biblatex names
+// the rule would rename to their BibTeX aliases.
+func BackendRules(b Backend) []Rule {
+	result := []Rule{Special{}, Sanitize{}}
+	if b != Biber {
+		result = append(result, FieldAlias{})
+	}
+	return append(result,
+		Eprint{},
+		Year{},
+		NameFormat{},
+		DuplicateDOI{},
+		UndefinedMacro{},
+		UnusedMacro{},
+	)
+}
+
+// Run checks the declarations with each of the rules and returns the findings
+// in the order of the rules. Findings reported without a position are given
+// the position of their field or declaration.
+func Run(nodes []parse.Node, rules ...Rule) []Finding {
+	result := []Finding{}
+	for _, r := range rules {
+		for _, f := range r.Check(nodes) {
+			if !f.Pos.IsValid() {
+				f.Pos = locate(nodes, f.Key, f.Field)
+			}
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// Locate returns the position of the field in the entry or @string
+// declaration under the key, or the position of the declaration itself when
+// the field is not found.
+func locate(nodes []parse.Node, key, field string) scan.Pos {
+	for _, n := range nodes {
+		switch d := n.(type) {
+		case *parse.EntryDecl:
+			if d.CiteKey != key {
+				continue
+			}
+			if f, ok := d.Get(field); ok {
+				return f.Pos
+			}
+			return d.Pos
+		case *parse.AbbrevDecl:
+			if d.Field != nil && d.Field.Key == key {
+				return d.Field.Pos
+			}
+		}
+	}
+	return scan.Pos{}
+}
+
+// Fix applies the fixes of the findings in order and returns the corrected
+// declarations.
+func Fix(nodes []parse.Node, findings []Finding) []parse.Node {
+	nodes, _ = Apply(nodes, findings)
+	return nodes
+}
+
+// Apply is like Fix but also tells for each of the findings whether its fix
+// took effect. It did not when the finding has no fix, or when the fix found
+// nothing to correct, as happens when the fix of another rule rewrote the
+// same text first.
+func Apply(nodes []parse.Node, findings []Finding) ([]parse.Node, []bool) {
+	fixed := make([]bool, len(findings))
+	for i, f := range findings {
+		if f.Fix == nil {
+			continue
+		}
+		if result := f.Fix(nodes); result != nil {
+			nodes, fixed[i] = result, true
+		}
+	}
+	return nodes, fixed
+}
+
+// Field is a field statement together with the name of the declaration it
+// belongs to.
+type field struct {
+	key  string
+	stmt *parse.FieldStmt
+}
+
+// Fields lists the fields of entries and @string declarations.
+func fields(nodes []parse.Node) []field {
+	result := []field{}
+	for _, n := range nodes {
+		switch d := n.(type) {
+		case *parse.EntryDecl:
+			for _, f := range d.Fields {
+				result = append(result, field{d.CiteKey, f})
+			}
+		case *parse.AbbrevDecl:
+			if d.Field != nil {
+				result = append(result, field{d.Field.Key, d.Field})
+			}
+		}
+	}
+	return result
+}
+
+// Rewrite fixes the problem by rewriting the value of the field statement.
+// The value current at the time the fix is applied is rewritten so that the
+// fixes of several rules touching the same field compose.
+func rewrite(f *parse.FieldStmt, fn func(string) string) func([]parse.Node) []parse.Node {
+	return func(nodes []parse.Node) []parse.Node {
+		f.Value = fn(f.Value)
+		return nodes
+	}
+}
+
+// Remove fixes the problem by removing the declaration.
+func remove(n parse.Node) func([]parse.Node) []parse.Node {
+	return func(nodes []parse.Node) []parse.Node {
+		result := make([]parse.Node, 0, len(nodes))
+		for _, m := range nodes {
+			if m != n {
+				result = append(result, m)
+			}
+		}
+		return result
+	}
+}
+
+// Requote encloses the new contents of a field value in the delimiters of the
+// old one.
+func requote(old, contents string) string {
+	if strings.HasPrefix(old, `"`) {
+		return `"` + contents + `"`
+	}
+	return parse.Quote(contents)
+}
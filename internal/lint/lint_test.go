@@ -0,0 +1,177 @@
+package lint
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+func parseNodes(t *testing.T, src string) []parse.Node {
+	t.Helper()
+	p := parse.NewParser(scan.NewScanner(scan.NewReader(strings.NewReader(src))))
+	result := []parse.Node{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		result = append(result, n)
+	}
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func formatNodes(t *testing.T, nodes []parse.Node) string {
+	t.Helper()
+	var b bytes.Buffer
+	if err := format.Nodes(&b, nodes); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func messages(findings []Finding) []string {
+	result := []string{}
+	for _, f := range findings {
+		result = append(result, f.String())
+	}
+	return result
+}
+
+// CheckRule runs the rule on the source and compares the findings and the
+// fixed source with the expected ones.
+func checkRule(t *testing.T, r Rule, src string, want []string, fixed string) {
+	t.Helper()
+	nodes := parseNodes(t, src)
+	findings := Run(nodes, r)
+	if have := strings.Join(messages(findings), "\n"); have != strings.Join(want, "\n") {
+		t.Errorf("have findings\n%s\nwant\n%s", have, strings.Join(want, "\n"))
+	}
+	if fixed == `` {
+		return
+	}
+	if have := formatNodes(t, Fix(nodes, findings)); have != fixed {
+		t.Errorf("have fixed\n%s\nwant\n%s", have, fixed)
+	}
+}
+
+func TestFindingString(t *testing.T) {
+	cases := []struct {
+		name    string
+		finding Finding
+		want    string
+	}{
+		{"field", Finding{Rule: "r", Key: "a", Field: "title", Message: "m"}, "a: title: m (r)"},
+		{"entry", Finding{Rule: "r", Key: "a", Message: "m"}, "a: m (r)"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := c.finding.String(); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestNonASCII(t *testing.T) {
+	src := `@string{kg = "Kurt Gödel"}
+@article{a, author = kg, title = {Über formal unentscheidbare Sätze}, year = 1931}
+@misc{b, title = {Ελληνικά}}
+`
+	want := []string{
+		`kg: kg: non-ASCII character 'ö' needs TeX escaping (non-ascii)`,
+		`a: title: non-ASCII character 'Ü' needs TeX escaping (non-ascii)`,
+		`b: title: non-ASCII character 'Ε' needs TeX escaping (non-ascii)`,
+	}
+	fixed := `@string{kg = "Kurt G{\"o}del"}
+
+@article{a,
+  author = kg,
+  title  = {{\"U}ber formal unentscheidbare S{\"a}tze},
+  year   = 1931
+}
+
+@misc{b,
+  title = {Ελληνικά}
+}
+`
+	checkRule(t, NonASCII{}, src, want, fixed)
+}
+
+func TestFixCompose(t *testing.T) {
+	nodes := parseNodes(t, "@misc{a, title = { Tom &  Gödel }}\n")
+	findings := Run(nodes, Sanitize{}, Special{}, NonASCII{})
+	want := `@misc{a,
+  title = {Tom \& G{\"o}del}
+}
+`
+	if have := formatNodes(t, Fix(nodes, findings)); have != want {
+		t.Errorf("have %s; want %s", have, want)
+	}
+}
+
+func TestFixDefaultRules(t *testing.T) {
+	nodes := parseNodes(t, "@misc{a, author = {Müller, Jürgen & Smith, A.}, title = {Tom & Jerry}}\n")
+	findings := Run(nodes, append(DefaultRules(), NonASCII{})...)
+	nodes, fixed := Apply(nodes, findings)
+	want := `@misc{a,
+  author = {M{\"u}ller, J{\"u}rgen and Smith, A.},
+  title  = {Tom \& Jerry}
+}
+`
+	if have := formatNodes(t, nodes); have != want {
+		t.Errorf("have %s; want %s", have, want)
+	}
+	for i, f := range findings {
+		if !fixed[i] {
+			t.Errorf("have %s not fixed; want fixed", f)
+		}
+	}
+}
+
+func TestApplyNothingToFix(t *testing.T) {
+	nodes := parseNodes(t, "@misc{a, author = {Doe, J. & Smith, A.}}\n")
+	findings := Run(nodes, NameFormat{})
+	// Another fix escapes the separator before the one of NameFormat runs.
+	nodes[0].(*parse.EntryDecl).Fields[0].Value = `{Doe, J. \& Smith, A.}`
+	if _, fixed := Apply(nodes, findings); len(fixed) != 1 || fixed[0] {
+		t.Errorf("have %v; want [false]", fixed)
+	}
+}
+
+// Locating reports the position of the finding.
+type locating struct{ field string }
+
+func (locating) Name() string { return "locating" }
+
+func (r locating) Check(nodes []parse.Node) []Finding {
+	return []Finding{{Rule: r.Name(), Key: "b", Field: r.field, Message: "found"}}
+}
+
+func TestRunPos(t *testing.T) {
+	src := "@misc{a,\n  title = {A}\n}\n\n@misc{b,\n  note = {N},\n  title = {B}\n}\n\n" +
+		"@misc{c,\n  title = {x & y}\n}\n"
+	cases := []struct {
+		name string
+		rule Rule
+		want string
+	}{
+		{"field", locating{"title"}, "7:3"},
+		{"entry", locating{``}, "5:1"},
+		{"set-by-rule", Special{}, "11:3"},
+	}
+	nodes := parseNodes(t, src)
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			have := "-"
+			if fs := Run(nodes, c.rule); len(fs) > 0 {
+				have = fs[0].Pos.String()
+			}
+			if have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// UndefinedMacro flags field values referencing @string macros that are not
+// defined in the document, in the Extra table, nor among the built-in month
+// macros. Extra holds the macros of additional string files.
+type UndefinedMacro struct {
+	Extra parse.MacroTable
+}
+
+// Name returns the name of the rule.
+func (UndefinedMacro) Name() string { return "undefined-string" }
+
+// Check reports each undefined macro reference.
+func (r UndefinedMacro) Check(nodes []parse.Node) []Finding {
+	table := parse.NewMacroTable(nodes)
+	result := []Finding{}
+	for _, f := range fields(nodes) {
+		for _, name := range parse.References(f.stmt.Value) {
+			if _, ok := table.Lookup(name); ok {
+				continue
+			}
+			if _, ok := r.Extra.Lookup(name); ok {
+				continue
+			}
+			result = append(result, Finding{
+				Rule:    r.Name(),
+				Key:     f.key,
+				Field:   f.stmt.Key,
+				Pos:     f.stmt.Pos,
+				Message: fmt.Sprintf("undefined @string macro %s", name),
+			})
+		}
+	}
+	return result
+}
+
+// UnusedMacro flags @string macros that are defined but never referenced by
+// the fields of the document, nor listed in Used. Used holds the lower-case
+// names of the macros referenced by other files sharing the definitions. The
+// fix removes the definitions.
+type UnusedMacro struct {
+	Used map[string]bool
+}
+
+// Name returns the name of the rule.
+func (UnusedMacro) Name() string { return "unused-string" }
+
+// Check reports each unused macro definition.
+func (r UnusedMacro) Check(nodes []parse.Node) []Finding {
+	used := MacroUses(nodes)
+	result := []Finding{}
+	for _, n := range nodes {
+		a, ok := n.(*parse.AbbrevDecl)
+		if !ok || a.Field == nil {
+			continue
+		}
+		name := strings.ToLower(a.Field.Key)
+		if used[name] || r.Used[name] {
+			continue
+		}
+		result = append(result, Finding{
+			Rule:    r.Name(),
+			Key:     a.Field.Key,
+			Pos:     a.Field.Pos,
+			Message: "unused @string macro",
+			Fix:     remove(a),
+		})
+	}
+	return result
+}
+
+// MacroUses collects the lower-case names of the macros referenced by the
+// fields and preambles of the declarations.
+func MacroUses(nodes []parse.Node) map[string]bool {
+	result := make(map[string]bool)
+	for _, f := range fields(nodes) {
+		for _, name := range parse.References(f.stmt.Value) {
+			result[strings.ToLower(name)] = true
+		}
+	}
+	for _, n := range nodes {
+		if p, ok := n.(*parse.PreambleDecl); ok {
+			for _, name := range parse.References(p.Value) {
+				result[strings.ToLower(name)] = true
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,49 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestUndefinedMacro(t *testing.T) {
+	src := `@string{acm = {Association for Computing Machinery}}
+@string{pub = acm # " Press"}
+@misc{a, publisher = pub, month = dec, year = 1999}
+@misc{b, publisher = ieee # { Press}, journal = CACM, note = {ieee}}
+`
+	want := []string{
+		`b: publisher: undefined @string macro ieee (undefined-string)`,
+	}
+	extra := parse.MacroTable{"cacm": "{Communications of the ACM}"}
+	checkRule(t, UndefinedMacro{Extra: extra}, src, want, ``)
+}
+
+func TestUnusedMacro(t *testing.T) {
+	src := `@string{acm = {Association for Computing Machinery}}
+@string{pub = acm # " Press"}
+@string{ieee = {IEEE}}
+@string{Old = {Unused}}
+@string{shared = {Shared}}
+@preamble{"\newcommand{\x}{}" # ieee}
+@misc{a, publisher = PUB}
+`
+	want := []string{
+		`Old: unused @string macro (unused-string)`,
+	}
+	fixed := `@string{acm = {Association for Computing Machinery}}
+
+@string{pub = acm # " Press"}
+
+@string{ieee = {IEEE}}
+
+@string{shared = {Shared}}
+
+@preamble{"\newcommand{\x}{}" # ieee}
+
+@misc{a,
+  publisher = PUB
+}
+`
+	checkRule(t, UnusedMacro{Used: map[string]bool{"shared": true}}, src, want, fixed)
+}
@@ -0,0 +1,56 @@
+package lint
+
+import (
+	"fmt"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/transform"
+)
+
+// Month flags month fields that are not written in the Style of the document
+// and months that cannot be recognized at all.
+type Month struct {
+	Style transform.MonthStyle
+}
+
+// Name returns the name of the rule.
+func (Month) Name() string { return "month-style" }
+
+// Check reports the month fields written in another style.
+func (r Month) Check(nodes []parse.Node) []Finding {
+	result := []Finding{}
+	for _, n := range nodes {
+		e, ok := n.(*parse.EntryDecl)
+		if !ok {
+			continue
+		}
+		f, ok := e.Get("month")
+		if !ok {
+			continue
+		}
+		m, ok := transform.ParseMonth(f.Value)
+		if !ok {
+			result = append(result, Finding{
+				Rule:    r.Name(),
+				Key:     e.CiteKey,
+				Field:   f.Key,
+				Pos:     f.Pos,
+				Message: fmt.Sprintf("unrecognized month %s", f.Value),
+			})
+			continue
+		}
+		want := transform.FormatMonth(m, r.Style)
+		if f.Value == want {
+			continue
+		}
+		result = append(result, Finding{
+			Rule:    r.Name(),
+			Key:     e.CiteKey,
+			Field:   f.Key,
+			Pos:     f.Pos,
+			Message: fmt.Sprintf("month %s should be written as %s", f.Value, want),
+			Fix:     rewrite(f, func(string) string { return want }),
+		})
+	}
+	return result
+}
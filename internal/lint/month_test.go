@@ -0,0 +1,56 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/transform"
+)
+
+func TestMonth(t *testing.T) {
+	src := `@misc{macro, month = feb}
+@misc{number, month = 2}
+@misc{name, month = {February}}
+@misc{short, month = "Feb."}
+@misc{day, month = feb # "~14"}
+@misc{none, year = 2000}
+`
+	cases := []struct {
+		name  string
+		style transform.MonthStyle
+		want  []string
+	}{
+		{
+			"macro",
+			transform.MonthMacro,
+			[]string{
+				`number: month: month 2 should be written as feb (month-style)`,
+				`name: month: month {February} should be written as feb (month-style)`,
+				`short: month: month "Feb." should be written as feb (month-style)`,
+				`day: month: unrecognized month feb # "~14" (month-style)`,
+			},
+		},
+		{
+			"name",
+			transform.MonthName,
+			[]string{
+				`macro: month: month feb should be written as {February} (month-style)`,
+				`number: month: month 2 should be written as {February} (month-style)`,
+				`short: month: month "Feb." should be written as {February} (month-style)`,
+				`day: month: unrecognized month feb # "~14" (month-style)`,
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			checkRule(t, Month{Style: c.style}, src, c.want, ``)
+		})
+	}
+}
+
+func TestMonthFix(t *testing.T) {
+	src := "@misc{a,\n  month = {sept}\n}\n"
+	want := "@misc{a,\n  month = 9\n}\n"
+	checkRule(t, Month{Style: transform.MonthNumber}, src, []string{
+		`a: month: month {sept} should be written as 9 (month-style)`,
+	}, want)
+}
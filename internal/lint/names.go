@@ -0,0 +1,195 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/names"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/transform"
+)
+
+const (
+	neutral nameFormat = iota
+	inverted
+	display
+	mixed
+)
+
+// NameFormat tells how the names in a name list are written.
+type nameFormat uint8
+
+var nameFormatNames = [...]string{
+	inverted: `"Last, First"`,
+	display:  `"First Last"`,
+}
+
+// NameFields hold the lists of personal names checked by the NameFormat rule.
+var nameFields = []string{"author", "editor"}
+
+// NameFormat flags name lists separated with a literal & instead of `and`,
+// and name lists deviating from the dominant `Last, First` or `First Last`
+// format of the document. The & separators are always fixable, and the names
+// are rewritten in the dominant format when Normalize is set. Names with a Jr
+// part are kept in the `Last, Jr, First` form the other format cannot express.
+type NameFormat struct {
+	Normalize bool
+}
+
+// Name returns the name of the rule.
+func (NameFormat) Name() string { return "name-format" }
+
+// Check reports the name lists with ampersands and the ones written in other
+// than the dominant format. Ties are resolved in favor of `Last, First`.
+func (r NameFormat) Check(nodes []parse.Node) []Finding {
+	type list struct {
+		key    string
+		stmt   *parse.FieldStmt
+		format nameFormat
+	}
+	lists := []list{}
+	counts := make(map[nameFormat]int)
+	result := []Finding{}
+	for _, n := range nodes {
+		e, ok := n.(*parse.EntryDecl)
+		if !ok {
+			continue
+		}
+		for _, key := range nameFields {
+			f, ok := e.Get(key)
+			if !ok || parse.Unquote(f.Value) == f.Value {
+				continue
+			}
+			value := parse.Unquote(f.Value)
+			if replaced, ok := transform.ReplaceAmpersands(value); ok {
+				result = append(result, Finding{
+					Rule:    r.Name(),
+					Key:     e.CiteKey,
+					Field:   f.Key,
+					Pos:     f.Pos,
+					Message: "names separated with & instead of and",
+					Fix:     fixAmpersands(f),
+				})
+				value = replaced
+			}
+			format := formatOf(value)
+			counts[format]++
+			lists = append(lists, list{e.CiteKey, f, format})
+		}
+	}
+	dominant := inverted
+	if counts[display] > counts[inverted] {
+		dominant = display
+	}
+	for _, l := range lists {
+		if l.format == neutral || l.format == dominant {
+			continue
+		}
+		msg := fmt.Sprintf("names mix formats; the document uses %s", nameFormatNames[dominant])
+		if l.format != mixed {
+			msg = fmt.Sprintf("names in %s format; the document uses %s", nameFormatNames[l.format], nameFormatNames[dominant])
+		}
+		finding := Finding{Rule: r.Name(), Key: l.key, Field: l.stmt.Key, Pos: l.stmt.Pos, Message: msg}
+		if r.Normalize {
+			finding.Fix = fixNameFormat(l.stmt, dominant)
+		}
+		result = append(result, finding)
+	}
+	return result
+}
+
+// FormatOf classifies the name list. Names made of a single word, like
+// corporate names in braces, and the `others` placeholder fit both formats.
+func formatOf(value string) nameFormat {
+	format := neutral
+	for _, s := range names.Split(value) {
+		f := neutral
+		switch {
+		case strings.EqualFold(s, "others"):
+		case hasComma(s):
+			f = inverted
+		case len(strings.Fields(s)) > 1 || strings.Contains(s, "~"):
+			f = display
+		}
+		switch {
+		case f == neutral || f == format:
+		case format == neutral:
+			format = f
+		default:
+			return mixed
+		}
+	}
+	return format
+}
+
+func hasComma(s string) bool {
+	depth := 0
+	for _, c := range s {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Fixes below rewrite the current value of the field statement so that they
+// compose with each other when applied in turn.
+
+// FixAmpersands finds nothing to fix when the & separators are gone, or
+// were escaped by the fix of another rule, by the time it is applied.
+func fixAmpersands(f *parse.FieldStmt) func([]parse.Node) []parse.Node {
+	return func(nodes []parse.Node) []parse.Node {
+		value, ok := transform.ReplaceAmpersands(parse.Unquote(f.Value))
+		if !ok {
+			return nil
+		}
+		f.Value = requote(f.Value, value)
+		return nodes
+	}
+}
+
+func fixNameFormat(f *parse.FieldStmt, format nameFormat) func([]parse.Node) []parse.Node {
+	return func(nodes []parse.Node) []parse.Node {
+		list := []string{}
+		for _, s := range names.Split(parse.Unquote(f.Value)) {
+			if strings.EqualFold(s, "others") || formatOf(s) == neutral {
+				list = append(list, s)
+				continue
+			}
+			list = append(list, formatName(names.Parse(s), format))
+		}
+		f.Value = requote(f.Value, strings.Join(list, " and "))
+		return nodes
+	}
+}
+
+func formatName(n names.Name, format nameFormat) string {
+	if format == display && n.Jr == `` {
+		return join(n.First, n.Von, n.Last)
+	}
+	s := join(n.Von, n.Last)
+	if n.Jr != `` {
+		s += ", " + n.Jr
+	}
+	if n.First != `` {
+		s += ", " + n.First
+	}
+	return s
+}
+
+func join(parts ...string) string {
+	result := []string{}
+	for _, p := range parts {
+		if p != `` {
+			result = append(result, p)
+		}
+	}
+	return strings.Join(result, " ")
+}
@@ -0,0 +1,63 @@
+package lint
+
+import "testing"
+
+const namesSrc = `@misc{a, author = {Cohen, Paul and Gödel, Kurt}}
+@misc{b, author = {Hilbert, David}, editor = {{NASA} and others}}
+@misc{c, author = "Paul Cohen and Kurt G{\"o}del"}
+@misc{d, author = {Cohen, Paul & Ludwig van Beethoven}}
+@misc{e, editor = {Martin Luther King, Jr. and Aristotle}}
+`
+
+func TestNameFormat(t *testing.T) {
+	want := []string{
+		`d: author: names separated with & instead of and (name-format)`,
+		`c: author: names in "First Last" format; the document uses "Last, First" (name-format)`,
+		`d: author: names mix formats; the document uses "Last, First" (name-format)`,
+	}
+	fixed := `@misc{a,
+  author = {Cohen, Paul and Gödel, Kurt}
+}
+
+@misc{b,
+  author = {Hilbert, David},
+  editor = {{NASA} and others}
+}
+
+@misc{c,
+  author = "Cohen, Paul and G{\"o}del, Kurt"
+}
+
+@misc{d,
+  author = {Cohen, Paul and van Beethoven, Ludwig}
+}
+
+@misc{e,
+  editor = {Martin Luther King, Jr. and Aristotle}
+}
+`
+	checkRule(t, NameFormat{Normalize: true}, namesSrc, want, fixed)
+}
+
+func TestNameFormatDisplay(t *testing.T) {
+	src := `@misc{a, author = {Paul Cohen}}
+@misc{b, author = {Kurt Gödel}}
+@misc{c, author = {Hilbert, David and van Beethoven, Ludwig and King, Jr., Martin Luther}}
+`
+	want := []string{
+		`c: author: names in "Last, First" format; the document uses "First Last" (name-format)`,
+	}
+	fixed := `@misc{a,
+  author = {Paul Cohen}
+}
+
+@misc{b,
+  author = {Kurt Gödel}
+}
+
+@misc{c,
+  author = {David Hilbert and Ludwig van Beethoven and King, Jr., Martin Luther}
+}
+`
+	checkRule(t, NameFormat{Normalize: true}, src, want, fixed)
+}
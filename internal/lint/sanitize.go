@@ -0,0 +1,43 @@
+package lint
+
+import (
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/transform"
+)
+
+// Sanitize flags field values with stray white space or redundant braces as
+// cleaned up by transform.SanitizeValue. The fix applies the cleanup.
+type Sanitize struct{}
+
+// Name returns the name of the rule.
+func (Sanitize) Name() string { return "sanitize" }
+
+// Check reports each field value changed by the cleanup.
+func (r Sanitize) Check(nodes []parse.Node) []Finding {
+	result := []Finding{}
+	for _, f := range fields(nodes) {
+		v := transform.SanitizeValue(f.stmt.Key, f.stmt.Value)
+		if v == f.stmt.Value {
+			continue
+		}
+		msg := "redundant braces"
+		if strings.Count(v, "{") == strings.Count(f.stmt.Value, "{") {
+			msg = "stray white space"
+		}
+		result = append(result, Finding{
+			Rule:    r.Name(),
+			Key:     f.key,
+			Field:   f.stmt.Key,
+			Pos:     f.stmt.Pos,
+			Message: msg,
+			Fix:     rewrite(f.stmt, sanitizer(f.stmt.Key)),
+		})
+	}
+	return result
+}
+
+func sanitizer(key string) func(string) string {
+	return func(v string) string { return transform.SanitizeValue(key, v) }
+}
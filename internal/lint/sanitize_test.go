@@ -0,0 +1,20 @@
+package lint
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	src := `@misc{a, title = { The
+    Title }, journal = {{PNAS}}, note = {Clean}}
+`
+	want := []string{
+		`a: title: stray white space (sanitize)`,
+		`a: journal: redundant braces (sanitize)`,
+	}
+	fixed := `@misc{a,
+  title   = {The Title},
+  journal = {PNAS},
+  note    = {Clean}
+}
+`
+	checkRule(t, Sanitize{}, src, want, fixed)
+}
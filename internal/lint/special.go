@@ -0,0 +1,123 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/transform"
+)
+
+// Escaped maps the special characters onto their escaped forms.
+var escaped = map[rune]string{
+	'%': `\%`,
+	'&': `\&`,
+	'_': `\_`,
+	'#': `\#`,
+	'~': `\textasciitilde{}`,
+}
+
+// Special flags bare %, &, _, # and ~ characters in field values, which break
+// the compilation of LaTeX documents or change their meaning. Math mode and
+// the arguments of \url and \href are left out, and a tilde is only flagged
+// when it does not join two words as a tie. An & in a name list is left to
+// the NameFormat rule, which takes it for a separator. The fix escapes the
+// characters.
+type Special struct{}
+
+// Name returns the name of the rule.
+func (Special) Name() string { return "latex-special" }
+
+// Check reports each field value with unescaped special characters once.
+func (r Special) Check(nodes []parse.Node) []Finding {
+	result := []Finding{}
+	for _, f := range fields(nodes) {
+		if transform.IsVerbatim(f.stmt.Key) {
+			continue
+		}
+		names := transform.IsNameList(f.stmt.Key)
+		_, found := escapeSpecials(f.stmt.Value, names)
+		if len(found) == 0 {
+			continue
+		}
+		result = append(result, Finding{
+			Rule:    r.Name(),
+			Key:     f.key,
+			Field:   f.stmt.Key,
+			Pos:     f.stmt.Pos,
+			Message: fmt.Sprintf("unescaped LaTeX special character %q, use %s", found[0], escaped[found[0]]),
+			Fix: rewrite(f.stmt, func(v string) string {
+				v, _ = escapeSpecials(v, names)
+				return v
+			}),
+		})
+	}
+	return result
+}
+
+// EscapeSpecials escapes the special characters in the delimited parts of the
+// raw field value and returns the result along with the characters found.
+// The & separators of name lists are kept unless they are nested in braces.
+func escapeSpecials(value string, names bool) (string, []rune) {
+	var b strings.Builder
+	found := []rune{}
+	rs := []rune(value)
+	depth, quoted, math := 0, false, false
+	skip := -1 // depth of the verbatim command argument being skipped
+	for i := 0; i < len(rs); i++ {
+		c := rs[i]
+		switch {
+		case c == '\\' && i+1 < len(rs):
+			name := commandName(rs[i+1:])
+			b.WriteString(`\` + name)
+			i += len([]rune(name))
+			if (name == "url" || name == "href") && skip < 0 {
+				skip = depth
+			}
+			continue
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == skip {
+				skip = -1
+			}
+		case c == '"' && depth == 0:
+			quoted = !quoted
+		case depth == 0 && !quoted:
+			// Macro names and the # concatenation operator.
+		case c == '$':
+			math = !math
+		case c == '&' && names && (depth == 1 && !quoted || depth == 0 && quoted):
+		case !math && skip < 0 && escaped[c] != ``:
+			if c != '~' || !isTie(rs, i) {
+				found = append(found, c)
+				b.WriteString(escaped[c])
+				continue
+			}
+		}
+		b.WriteRune(c)
+	}
+	return b.String(), found
+}
+
+// CommandName reads the name of the command following a backslash: either a
+// run of letters or a single other character.
+func commandName(rs []rune) string {
+	if !unicode.IsLetter(rs[0]) {
+		return string(rs[0])
+	}
+	n := 0
+	for n < len(rs) && unicode.IsLetter(rs[n]) {
+		n++
+	}
+	return string(rs[:n])
+}
+
+func isTie(rs []rune, i int) bool {
+	isWord := func(j int) bool {
+		return j >= 0 && j < len(rs) && !unicode.IsSpace(rs[j]) && rs[j] != '{' && rs[j] != '}' && rs[j] != '"'
+	}
+	return isWord(i-1) && isWord(i+1)
+}
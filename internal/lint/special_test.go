@@ -0,0 +1,53 @@
+package lint
+
+import "testing"
+
+func TestEscapeSpecials(t *testing.T) {
+	cases := []struct {
+		name  string
+		value string
+		names bool
+		want  string
+		found string
+	}{
+		{"plain", "{Tom and Jerry}", false, "{Tom and Jerry}", ""},
+		{"ampersand", "{Tom & Jerry}", false, `{Tom \& Jerry}`, "&"},
+		{"escaped", `{Tom \& Jerry 50\%}`, false, `{Tom \& Jerry 50\%}`, ""},
+		{"all", `"50% of A_1 #2 ~ x"`, false, `"50\% of A\_1 \#2 \textasciitilde{} x"`, "%_#~"},
+		{"tie", "{D.~E. Knuth}", false, "{D.~E. Knuth}", ""},
+		{"math", "{Sets $A_1 \\& A_2$}", false, "{Sets $A_1 \\& A_2$}", ""},
+		{"url", `{See \url{http://x.org/~a_b#c} & more}`, false, `{See \url{http://x.org/~a_b#c} \& more}`, "&"},
+		{"href", `{\href{http://x.org/a_b}{a_b}}`, false, `{\href{http://x.org/a_b}{a\_b}}`, "_"},
+		{"concatenation", `jan # "~1 & 2"`, false, `jan # "\textasciitilde{}1 \& 2"`, "~&"},
+		{"name-separator", "{Müller, Jürgen & Smith, A.}", true, "{Müller, Jürgen & Smith, A.}", ""},
+		{"quoted-name-separator", `"Tom & Jerry"`, true, `"Tom & Jerry"`, ""},
+		{"corporate-name", "{{Smith & Sons} and Doe, J.}", true, `{{Smith \& Sons} and Doe, J.}`, "&"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			have, found := escapeSpecials(c.value, c.names)
+			if have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+			if string(found) != c.found {
+				t.Errorf("have %q; want %q", string(found), c.found)
+			}
+		})
+	}
+}
+
+func TestSpecial(t *testing.T) {
+	src := `@misc{a, title = {Tom & Jerry}, url = {http://x.org/~a_b}, note = {100%}}
+`
+	want := []string{
+		`a: title: unescaped LaTeX special character '&', use \& (latex-special)`,
+		`a: note: unescaped LaTeX special character '%', use \% (latex-special)`,
+	}
+	fixed := `@misc{a,
+  title = {Tom \& Jerry},
+  url   = {http://x.org/~a_b},
+  note  = {100\%}
+}
+`
+	checkRule(t, Special{}, src, want, fixed)
+}
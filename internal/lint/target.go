@@ -0,0 +1,163 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// Backend is the program processing the bibliography of a document.
+type Backend uint8
+
+const (
+	BibTeX Backend = iota
+	BibTeX8
+	Biber
+)
+
+var backendNames = [...]string{"bibtex", "bibtex8", "biber"}
+
+func (b Backend) String() string {
+	if int(b) < len(backendNames) {
+		return backendNames[b]
+	}
+	return fmt.Sprintf("Backend(%d)", b)
+}
+
+// ParseBackend returns the backend of the name.
+func ParseBackend(name string) (Backend, bool) {
+	for i, n := range backendNames {
+		if strings.EqualFold(n, name) {
+			return Backend(i), true
+		}
+	}
+	return 0, false
+}
+
+// MaxFieldLength is the size in bytes of the buffer the classic BibTeX
+// engines read a field value into. Longer values stop them.
+const MaxFieldLength = 20000
+
+// Entry types of the standard BibTeX styles. Other types are typeset as
+// misc entries.
+var bibtexTypes = map[string]bool{
+	"article": true, "book": true, "booklet": true, "conference": true,
+	"inbook": true, "incollection": true, "inproceedings": true, "manual": true,
+	"mastersthesis": true, "misc": true, "phdthesis": true, "proceedings": true,
+	"techreport": true, "unpublished": true,
+}
+
+// Entry types of the biblatex data model, along with the BibTeX types biber
+// maps onto them.
+var biberTypes = map[string]bool{
+	"article": true, "book": true, "mvbook": true, "inbook": true,
+	"bookinbook": true, "suppbook": true, "booklet": true, "collection": true,
+	"mvcollection": true, "incollection": true, "suppcollection": true,
+	"dataset": true, "manual": true, "misc": true, "online": true,
+	"patent": true, "periodical": true, "suppperiodical": true,
+	"proceedings": true, "mvproceedings": true, "inproceedings": true,
+	"reference": true, "mvreference": true, "inreference": true,
+	"report": true, "set": true, "software": true, "thesis": true,
+	"unpublished": true, "xdata": true, "artwork": true, "audio": true,
+	"bibnote": true, "commentary": true, "image": true, "jurisdiction": true,
+	"legislation": true, "legal": true, "letter": true, "movie": true,
+	"music": true, "performance": true, "review": true, "standard": true,
+	"video": true, "customa": true, "customb": true, "customc": true,
+	"customd": true, "custome": true, "customf": true,
+	"conference": true, "electronic": true, "mastersthesis": true,
+	"phdthesis": true, "techreport": true, "www": true,
+}
+
+// Biblatex fields the standard BibTeX styles know nothing of and drop.
+var biblatexFields = map[string]bool{
+	"date": true, "urldate": true, "eventdate": true, "origdate": true,
+	"journaltitle": true, "location": true, "subtitle": true,
+	"titleaddon": true, "maintitle": true, "eprinttype": true,
+	"eprintclass": true, "langid": true, "shorthand": true, "sortname": true,
+	"translator": true, "pubstate": true,
+}
+
+// Date fields biber parses as ISO 8601 dates, possibly ranges.
+var dateFields = []string{"date", "urldate", "eventdate", "origdate"}
+
+var (
+	isoDate  = regexp.MustCompile(`^(\d{4}(-\d{2}(-\d{2})?)?[?~%]?)?(/(\.\.|\d{4}(-\d{2}(-\d{2})?)?[?~%]?)?)?$`)
+	integral = regexp.MustCompile(`^\d+$`)
+)
+
+// Target flags the constructs the Backend rejects or silently drops, as
+// files accepted by one engine often fail with another: raw UTF-8, reported
+// as by NonASCII, and over-long fields under the classic BibTeX engines, entry types and fields
+// they do not know, and the dates and months biber cannot read. Biblatex
+// fields with a BibTeX alias, such as journaltitle, are reported by
+// FieldAlias instead.
+type Target struct {
+	Backend Backend
+}
+
+// Name returns the name of the rule.
+func (Target) Name() string { return "target" }
+
+// Check reports the constructs the backend does not handle.
+func (r Target) Check(nodes []parse.Node) []Finding {
+	result := []Finding{}
+	add := func(key string, f *parse.FieldStmt, format string, args ...any) {
+		finding := Finding{Rule: r.Name(), Key: key, Message: fmt.Sprintf(format, args...)}
+		if f != nil {
+			finding.Field, finding.Pos = f.Key, f.Pos
+		}
+		result = append(result, finding)
+	}
+	for _, n := range nodes {
+		e, ok := n.(*parse.EntryDecl)
+		if !ok {
+			continue
+		}
+		typ := strings.ToLower(e.Name)
+		switch r.Backend {
+		case BibTeX, BibTeX8:
+			if !bibtexTypes[typ] {
+				add(e.CiteKey, nil, "entry type @%s is unknown to %s and typeset as misc", e.Name, r.Backend)
+			}
+			if c, ok := firstNonASCII(e.CiteKey); ok {
+				add(e.CiteKey, nil, "%s cannot read the cite key with %q", r.Backend, c)
+			}
+			for _, f := range e.Fields {
+				// Aliases of BibTeX fields are left to FieldAlias.
+				if key := strings.ToLower(f.Key); biblatexFields[key] && parse.CanonicalKey(key) == key {
+					add(e.CiteKey, f, "field is dropped by %s", r.Backend)
+				}
+			}
+		case Biber:
+			if !biberTypes[typ] {
+				add(e.CiteKey, nil, "entry type @%s is unknown to biber and read as misc", e.Name)
+			}
+			for _, key := range dateFields {
+				if f, ok := e.Get(key); ok && !isoDate.MatchString(parse.Unquote(f.Value)) {
+					add(e.CiteKey, f, "date %s is not in ISO 8601 format and is dropped by biber", f.Value)
+				}
+			}
+			if f, ok := e.Get("month"); ok && isLiteral(f.Value) && !integral.MatchString(parse.Unquote(f.Value)) {
+				add(e.CiteKey, f, "month %s is not an integer and is sorted wrongly by biber", f.Value)
+			}
+		}
+	}
+	if r.Backend == Biber {
+		return result
+	}
+	result = append(result, NonASCII{}.Check(nodes)...)
+	for _, f := range fields(nodes) {
+		if len(f.stmt.Value) > MaxFieldLength {
+			add(f.key, f.stmt, "value of %d bytes overflows the %d byte buffer of %s", len(f.stmt.Value), MaxFieldLength, r.Backend)
+		}
+	}
+	return result
+}
+
+// IsLiteral tells if the value is enclosed in delimiters rather than being
+// a macro, a number or a concatenation.
+func isLiteral(v string) bool {
+	return parse.Unquote(v) != v
+}
@@ -0,0 +1,105 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTarget(t *testing.T) {
+	src := `@string{pub = "Ünivers"}
+@online{web, title = {Café}, urldate = {2020-01-02}}
+@article{ok, title = {Plain}, journaltitle = {J}, month = jan, date = {2001-02/2001-03}}
+@book{bad, date = {Spring 2001}, month = {March}}
+@blog{post, year = 2001}
+`
+	cases := []struct {
+		name    string
+		backend Backend
+		want    []string
+	}{
+		{
+			"bibtex",
+			BibTeX,
+			[]string{
+				`web: entry type @online is unknown to bibtex and typeset as misc (target)`,
+				`web: urldate: field is dropped by bibtex (target)`,
+				`ok: date: field is dropped by bibtex (target)`,
+				`bad: date: field is dropped by bibtex (target)`,
+				`post: entry type @blog is unknown to bibtex and typeset as misc (target)`,
+				`pub: pub: non-ASCII character 'Ü' needs TeX escaping (non-ascii)`,
+				`web: title: non-ASCII character 'é' needs TeX escaping (non-ascii)`,
+			},
+		},
+		{
+			"biber",
+			Biber,
+			[]string{
+				`bad: date: date {Spring 2001} is not in ISO 8601 format and is dropped by biber (target)`,
+				`bad: month: month {March} is not an integer and is sorted wrongly by biber (target)`,
+				`post: entry type @blog is unknown to biber and read as misc (target)`,
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			checkRule(t, Target{Backend: c.backend}, src, c.want, ``)
+		})
+	}
+}
+
+func TestBackendString(t *testing.T) {
+	if have, want := Backend(7).String(), "Backend(7)"; have != want {
+		t.Errorf("have %s; want %s", have, want)
+	}
+}
+
+func TestBackendRules(t *testing.T) {
+	cases := []struct {
+		backend Backend
+		want    bool
+	}{
+		{BibTeX, true},
+		{BibTeX8, true},
+		{Biber, false},
+	}
+	for _, c := range cases {
+		t.Run(c.backend.String(), func(t *testing.T) {
+			have := false
+			for _, r := range BackendRules(c.backend) {
+				have = have || r.Name() == FieldAlias{}.Name()
+			}
+			if have != c.want {
+				t.Errorf("have field-alias %v; want %v", have, c.want)
+			}
+		})
+	}
+}
+
+func TestTargetLength(t *testing.T) {
+	src := "@misc{long, abstract = {" + strings.Repeat("a", MaxFieldLength) + "}}"
+	checkRule(t, Target{Backend: BibTeX8}, src, []string{
+		`long: abstract: value of 20002 bytes overflows the 20000 byte buffer of bibtex8 (target)`,
+	}, ``)
+	checkRule(t, Target{Backend: Biber}, src, nil, ``)
+}
+
+func TestParseBackend(t *testing.T) {
+	cases := []struct {
+		name string
+		want Backend
+		ok   bool
+	}{
+		{"bibtex", BibTeX, true},
+		{"BibTeX8", BibTeX8, true},
+		{"biber", Biber, true},
+		{"biblatex", 0, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			have, ok := ParseBackend(c.name)
+			if have != c.want || ok != c.ok {
+				t.Errorf("have %v, %t; want %v, %t", have, ok, c.want, c.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package lint
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// Year flags year fields that are not 4-digit numbers, lie in the future, or
+// disagree with the year of the date field. Now is the time the future is
+// measured from; the current time is used when it is zero.
+type Year struct {
+	Now time.Time
+}
+
+// Name returns the name of the rule.
+func (Year) Name() string { return "year" }
+
+// Check reports at most one problem with the year of each entry.
+func (r Year) Check(nodes []parse.Node) []Finding {
+	now := r.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	result := []Finding{}
+	for _, n := range nodes {
+		e, ok := n.(*parse.EntryDecl)
+		if !ok {
+			continue
+		}
+		f, ok := e.Get("year")
+		if !ok {
+			continue
+		}
+		if msg := checkYear(e, f.Value, now); msg != `` {
+			result = append(result, Finding{Rule: r.Name(), Key: e.CiteKey, Field: f.Key, Pos: f.Pos, Message: msg})
+		}
+	}
+	return result
+}
+
+func checkYear(e *parse.EntryDecl, value string, now time.Time) string {
+	year := strings.TrimSpace(parse.Unquote(value))
+	if year == value && !isNumber(year) {
+		// A macro reference that cannot be resolved here.
+		return ``
+	}
+	if len(year) != 4 || !isNumber(year) {
+		return fmt.Sprintf("year %q is not a 4-digit number", year)
+	}
+	if y, _ := strconv.Atoi(year); y > now.Year() {
+		return fmt.Sprintf("year %s is in the future", year)
+	}
+	if d, ok := e.Get("date"); ok {
+		date := strings.TrimSpace(parse.Unquote(d.Value))
+		if len(date) >= 4 && isNumber(date[:4]) && date[:4] != year {
+			return fmt.Sprintf("year %s disagrees with date %s", year, date)
+		}
+	}
+	return ``
+}
+
+func isNumber(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ``
+}
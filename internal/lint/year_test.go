@@ -0,0 +1,25 @@
+package lint
+
+import (
+	"testing"
+	"time"
+)
+
+func TestYear(t *testing.T) {
+	src := `@misc{ok, year = 1963, date = {1963-12}}
+@misc{macro, year = y}
+@misc{short, year = {63}}
+@misc{text, year = "199x"}
+@misc{future, year = {2031}}
+@misc{date, year = 1999, date = {2000-01-02}}
+@misc{none, title = {T}}
+`
+	want := []string{
+		`short: year: year "63" is not a 4-digit number (year)`,
+		`text: year: year "199x" is not a 4-digit number (year)`,
+		`future: year: year 2031 is in the future (year)`,
+		`date: year: year 1999 disagrees with date 2000-01-02 (year)`,
+	}
+	now := time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC)
+	checkRule(t, Year{Now: now}, src, want, ``)
+}
@@ -0,0 +1,7 @@
+/*
+Lsp package implements a language server for BibTeX files speaking the
+Language Server Protocol over a stream such as the standard input and output
+of the process. It publishes lint and validation findings as diagnostics and
+provides navigation, hover, completion and formatting.
+*/
+package lsp
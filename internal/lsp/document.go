@@ -0,0 +1,444 @@
+package lsp
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/lint"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/report"
+	"github.com/mdm-code/bibx/internal/resolve"
+	"github.com/mdm-code/bibx/internal/scan"
+	"github.com/mdm-code/bibx/internal/validate"
+)
+
+// Document is an open BibTeX file along with the declarations parsed from
+// it. Declarations following a syntax error are missing, and the error is
+// kept to be reported.
+type document struct {
+	tree   *parse.Tree
+	text   string
+	lines  []string
+	nodes  []parse.Node
+	err    error
+	macros parse.MacroTable
+}
+
+func newDocument(text string) *document {
+	return treeDocument(parse.NewTree(text))
+}
+
+func treeDocument(t *parse.Tree) *document {
+	text := t.Source()
+	nodes := t.Nodes()
+	return &document{
+		tree:   t,
+		text:   text,
+		lines:  strings.Split(text, "\n"),
+		nodes:  nodes,
+		err:    t.Err(),
+		macros: parse.NewMacroTable(nodes),
+	}
+}
+
+// Edit replaces the text within the range and returns the updated document.
+// Only the declarations the edit touches are parsed again.
+func (d *document) edit(r span, text string) *document {
+	start, end := d.offset(d.pos(r.Start)), d.offset(d.pos(r.End))
+	if end < start {
+		start, end = end, start
+	}
+	d.tree.Edit(start, end, text)
+	return treeDocument(d.tree)
+}
+
+// Entries lists the entries of the document.
+func (d *document) entries() []*parse.EntryDecl {
+	result := []*parse.EntryDecl{}
+	for _, n := range d.nodes {
+		if e, ok := n.(*parse.EntryDecl); ok {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Entry returns the entry under the cite key, matched case-insensitively.
+func (d *document) entry(key string) *parse.EntryDecl {
+	for _, e := range d.entries() {
+		if strings.EqualFold(e.CiteKey, key) {
+			return e
+		}
+	}
+	return nil
+}
+
+// Macro returns the @string declaration of the macro.
+func (d *document) macro(name string) *parse.AbbrevDecl {
+	var result *parse.AbbrevDecl
+	for _, n := range d.nodes {
+		if a, ok := n.(*parse.AbbrevDecl); ok && a.Field != nil && strings.EqualFold(a.Field.Key, name) {
+			result = a
+		}
+	}
+	return result
+}
+
+// Diagnostics collects the lint findings, the schema violations and the
+// broken crossref and xdata references of the document.
+func (d *document) diagnostics(rules []lint.Rule, schemas *validate.Set) []diagnostic {
+	result := []diagnostic{}
+	if d.err != nil {
+		f := report.Failure(d.err, d.tree.ErrPos())
+		result = append(result, diagnostic{
+			Range:    d.lineSpan(f.Pos),
+			Severity: severityError,
+			Code:     code(f.Rule),
+			Source:   "bibx",
+			Message:  f.Message,
+		})
+	}
+	findings := []report.Finding{}
+	for _, f := range lint.Run(d.nodes, rules...) {
+		findings = append(findings, f.Report())
+	}
+	entries := d.entries()
+	for _, e := range entries {
+		for _, v := range schemas.Validate(e) {
+			findings = append(findings, v.Report())
+		}
+	}
+	for _, err := range resolve.Check(entries) {
+		if re, ok := err.(*resolve.Error); ok {
+			findings = append(findings, re.Report())
+		}
+	}
+	for _, f := range findings {
+		result = append(result, diagnostic{
+			Range:    d.lineSpan(f.Pos),
+			Severity: severity(f.Severity),
+			Code:     code(f.Rule),
+			Source:   "bibx",
+			Message:  f.Message,
+		})
+	}
+	return result
+}
+
+// Code returns the stable code of the rule, or the rule ID itself for rules
+// without one.
+func code(rule string) string {
+	if c := report.Code(rule); c != `` {
+		return c
+	}
+	return rule
+}
+
+func severity(s report.Severity) int {
+	switch s {
+	case report.Error:
+		return severityError
+	case report.Warning:
+		return severityWarning
+	default:
+		return severityInformation
+	}
+}
+
+// Definition locates the entry referenced by the crossref or xdata field or
+// the @string declaration of the macro under the cursor.
+func (d *document) definition(p scan.Pos) (scan.Pos, bool) {
+	n, f := d.at(p)
+	word := d.word(p)
+	if f == nil || word == `` {
+		return scan.Pos{}, false
+	}
+	if _, ok := n.(*parse.EntryDecl); ok && isReference(f.Key) {
+		if e := d.entry(word); e != nil {
+			return e.Pos, true
+		}
+		return scan.Pos{}, false
+	}
+	if isMacroReference(f, word) {
+		if a := d.macro(word); a != nil {
+			return a.Field.Pos, true
+		}
+	}
+	return scan.Pos{}, false
+}
+
+// Hover describes the entry referenced by the crossref or xdata field or the
+// macro under the cursor, or else shows the value of the field with its
+// macros expanded.
+func (d *document) hover(p scan.Pos) string {
+	n, f := d.at(p)
+	if f == nil {
+		return ``
+	}
+	word := d.word(p)
+	if _, ok := n.(*parse.EntryDecl); ok && isReference(f.Key) && word != `` {
+		if e := d.entry(word); e != nil {
+			return summary(e)
+		}
+		return ``
+	}
+	if word != `` && isMacroReference(f, word) {
+		if v, ok := d.macros.Lookup(word); ok {
+			return word + " = " + d.macros.Expand(v)
+		}
+		return ``
+	}
+	if len(parse.References(f.Value)) == 0 {
+		return ``
+	}
+	return f.Key + " = " + d.macros.Expand(f.Value)
+}
+
+// Summary describes the entry by its type, cite key, author, title and year.
+func summary(e *parse.EntryDecl) string {
+	lines := []string{"@" + e.Name + "{" + e.CiteKey + "}"}
+	for _, key := range []string{"author", "editor", "title", "year"} {
+		if f, ok := e.Lookup(key); ok {
+			lines = append(lines, key+": "+parse.Unquote(f.Value))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isReference(key string) bool {
+	key = strings.ToLower(key)
+	return key == "crossref" || key == "xdata"
+}
+
+func isMacroReference(f *parse.FieldStmt, word string) bool {
+	for _, ref := range parse.References(f.Value) {
+		if strings.EqualFold(ref, word) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	entryStart = regexp.MustCompile(`(?m)^[ \t]*@[ \t]*([A-Za-z]+)[ \t]*[{(]`)
+	citeKey    = regexp.MustCompile(`(?m)^[ \t]*@[ \t]*([A-Za-z]+)[ \t]*[{(][ \t]*([^,\s{}()]+)[ \t]*,`)
+	macroName  = regexp.MustCompile(`(?mi)^[ \t]*@[ \t]*string[ \t]*[{(][ \t]*([^=\s{}()]+)[ \t]*=`)
+	fieldName  = regexp.MustCompile(`(?m)^[ \t]*([A-Za-z_-]+)[ \t]*=`)
+	keyPrefix  = regexp.MustCompile(`^[ \t]*,?[ \t]*[A-Za-z_-]*$`)
+	refValue   = regexp.MustCompile(`(?i)(crossref|xdata)[ \t]*=[ \t]*[{"]?[^{}"]*$`)
+	macroValue = regexp.MustCompile(`[=#][ \t]*[^\s{}"#,=]*$`)
+)
+
+// Completion suggests field names at the start of a field of an entry, cite
+// keys in crossref and xdata values, and macro names elsewhere in values.
+// The candidates are collected from the text rather than the declarations so
+// that the completion keeps working while the document is being edited and
+// does not parse.
+func (d *document) completion(p scan.Pos, schemas *validate.Set) []completionItem {
+	offset := d.offset(p)
+	before := d.text[:offset]
+	line := before[strings.LastIndex(before, "\n")+1:]
+	starts := entryStart.FindAllStringSubmatchIndex(before, -1)
+	if len(starts) == 0 {
+		return []completionItem{}
+	}
+	start := starts[len(starts)-1]
+	typ := strings.ToLower(before[start[2]:start[3]])
+	bare := isBare(before[start[1]:])
+	result := []completionItem{}
+	switch {
+	case refValue.MatchString(line):
+		for _, m := range citeKey.FindAllStringSubmatch(d.text, -1) {
+			if t := strings.ToLower(m[1]); t != "string" && t != "preamble" && t != "comment" {
+				result = append(result, completionItem{Label: m[2], Kind: kindReference, Detail: "@" + t})
+			}
+		}
+	case bare && macroValue.MatchString(line):
+		seen := make(map[string]bool)
+		for _, m := range macroName.FindAllStringSubmatch(d.text, -1) {
+			if name := strings.ToLower(m[1]); !seen[name] {
+				seen[name] = true
+				result = append(result, completionItem{Label: m[1], Kind: kindConstant, Detail: "@string"})
+			}
+		}
+		for name, v := range parse.BuiltinMacros {
+			if !seen[name] {
+				result = append(result, completionItem{Label: name, Kind: kindConstant, Detail: v})
+			}
+		}
+	case bare && keyPrefix.MatchString(line) && typ != "string" && typ != "preamble" && typ != "comment":
+		// Fields already present in the entry are not suggested again.
+		body := d.text[start[1]:]
+		if next := entryStart.FindStringIndex(body); next != nil {
+			body = body[:next[0]]
+		}
+		present := make(map[string]bool)
+		for _, m := range fieldName.FindAllStringSubmatch(body, -1) {
+			present[strings.ToLower(m[1])] = true
+		}
+		for _, name := range schemas.Fields(typ) {
+			if !present[name] {
+				result = append(result, completionItem{Label: name, Kind: kindField})
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Label < result[j].Label })
+	return result
+}
+
+// IsBare checks if the end of the body of an open declaration lies outside
+// of braces and quotes, where field names and macros are written.
+func isBare(body string) bool {
+	depth, quoted := 0, false
+	for _, c := range body {
+		switch {
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+		case c == '"' && depth == 0:
+			quoted = !quoted
+		}
+	}
+	return depth == 0 && !quoted
+}
+
+// At returns the declaration around the position along with its field the
+// position falls into. A field extends up to the next one, so that the
+// positions within multi-line values belong to it.
+func (d *document) at(p scan.Pos) (parse.Node, *parse.FieldStmt) {
+	var node parse.Node
+	var fields []*parse.FieldStmt
+	for _, n := range d.nodes {
+		switch n := n.(type) {
+		case *parse.EntryDecl:
+			if before(n.Pos, p) {
+				node, fields = n, n.Fields
+			}
+		case *parse.AbbrevDecl:
+			if before(n.Pos, p) && n.Field != nil {
+				node, fields = n, []*parse.FieldStmt{n.Field}
+			}
+		case *parse.PreambleDecl:
+			if before(n.Pos, p) {
+				node, fields = n, nil
+			}
+		}
+	}
+	var field *parse.FieldStmt
+	for _, f := range fields {
+		if before(f.Pos, p) {
+			field = f
+		}
+	}
+	return node, field
+}
+
+// Before checks if position a comes before position b or is the same.
+func before(a, b scan.Pos) bool {
+	return a.IsValid() && (a.Line < b.Line || a.Line == b.Line && a.Column <= b.Column)
+}
+
+// Word returns the cite key or macro name under the cursor.
+func (d *document) word(p scan.Pos) string {
+	if p.Line < 1 || p.Line > len(d.lines) {
+		return ``
+	}
+	line := []rune(d.lines[p.Line-1])
+	i := p.Column - 1
+	if i > len(line) {
+		i = len(line)
+	}
+	start, end := i, i
+	for start > 0 && isWordRune(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isWordRune(line[end]) {
+		end++
+	}
+	return string(line[start:end])
+}
+
+func isWordRune(r rune) bool {
+	return !strings.ContainsRune(" \t\r{}\"(),=#%", r)
+}
+
+// Offset returns the byte offset of the position in the text.
+func (d *document) offset(p scan.Pos) int {
+	result := 0
+	for i := 0; i < p.Line-1 && i < len(d.lines); i++ {
+		result += len(d.lines[i]) + 1
+	}
+	if p.Line < 1 || p.Line > len(d.lines) {
+		return len(d.text)
+	}
+	line := []rune(d.lines[p.Line-1])
+	col := p.Column - 1
+	if col > len(line) {
+		col = len(line)
+	}
+	return result + len(string(line[:col]))
+}
+
+// Pos converts the protocol position into a source position.
+func (d *document) pos(p position) scan.Pos {
+	if p.Line < 0 || p.Line >= len(d.lines) {
+		return scan.Pos{Line: p.Line + 1, Column: 1}
+	}
+	col, units := 0, 0
+	for _, r := range d.lines[p.Line] {
+		if units >= p.Character {
+			break
+		}
+		units += utf16Len(r)
+		col++
+	}
+	return scan.Pos{Line: p.Line + 1, Column: col + 1}
+}
+
+// Position converts the source position into a protocol position. Unknown
+// positions point at the start of the document.
+func (d *document) position(p scan.Pos) position {
+	if !p.IsValid() || p.Line > len(d.lines) {
+		return position{}
+	}
+	units := 0
+	for i, r := range []rune(d.lines[p.Line-1]) {
+		if i >= p.Column-1 {
+			break
+		}
+		units += utf16Len(r)
+	}
+	return position{Line: p.Line - 1, Character: units}
+}
+
+// LineSpan spans from the position to the end of its line.
+func (d *document) lineSpan(p scan.Pos) span {
+	start := d.position(p)
+	end := start
+	if start.Line < len(d.lines) {
+		end.Character = 0
+		for _, r := range strings.TrimRight(d.lines[start.Line], "\r") {
+			end.Character += utf16Len(r)
+		}
+	}
+	return span{Start: start, End: end}
+}
+
+// Span covers the whole document.
+func (d *document) span() span {
+	last := len(d.lines) - 1
+	end := position{Line: last}
+	for _, r := range d.lines[last] {
+		end.Character += utf16Len(r)
+	}
+	return span{End: end}
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
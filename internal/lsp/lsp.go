@@ -0,0 +1,288 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/lint"
+	"github.com/mdm-code/bibx/internal/metrics"
+	"github.com/mdm-code/bibx/internal/validate"
+)
+
+// ErrExit is returned by Run when the client asks the server to exit without
+// shutting it down first.
+var ErrExit = errors.New("lsp: exit without shutdown")
+
+// Server is a language server for BibTeX documents. Documents are synced
+// incrementally, re-parsing only the declarations each change touches, and
+// each change publishes the diagnostics reported by the lint Rules, the
+// Schemas and the crossref and xdata resolution.
+type Server struct {
+	Rules   []lint.Rule
+	Schemas *validate.Set
+
+	in       *bufio.Reader
+	out      io.Writer
+	docs     map[string]*document
+	shutdown bool
+	metrics  *serverMetrics
+}
+
+// ServerMetrics are the metrics of a server.
+type serverMetrics struct {
+	registry    metrics.Registry
+	messages    *metrics.Counter
+	errors      *metrics.Counter
+	parses      *metrics.Histogram
+	parseErrors *metrics.Counter
+	entries     *metrics.Gauge
+}
+
+func newServerMetrics() *serverMetrics {
+	m := &serverMetrics{}
+	m.messages = m.registry.Counter("bibx_lsp_messages_total", "Messages received from the client by method.", "method")
+	m.errors = m.registry.Counter("bibx_lsp_errors_total", "Error responses sent to the client by JSON-RPC error code.", "code")
+	m.parses = m.registry.Histogram("bibx_parse_duration_seconds", "Time spent parsing documents after each change.", metrics.DefaultBuckets)
+	m.parseErrors = m.registry.Counter("bibx_parse_errors_total", "Changes leaving a document with a syntax error.")
+	m.entries = m.registry.Gauge("bibx_entries", "Entries in the open documents.")
+	return m
+}
+
+// NewServer returns a server reading messages from r and writing them to w
+// with the default lint rules and the built-in schemas.
+func NewServer(r io.Reader, w io.Writer) *Server {
+	return &Server{
+		Rules:   lint.DefaultRules(),
+		Schemas: validate.Builtin,
+		in:      bufio.NewReader(r),
+		out:     w,
+		docs:    make(map[string]*document),
+		metrics: newServerMetrics(),
+	}
+}
+
+// Metrics returns the metrics of the server, which may be served over HTTP
+// to a Prometheus scraper while the server runs.
+func (s *Server) Metrics() *metrics.Registry { return &s.metrics.registry }
+
+// Run serves messages until the client exits or closes the stream.
+func (s *Server) Run() error {
+	for {
+		data, err := readMessage(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var m message
+		if err := json.Unmarshal(data, &m); err != nil {
+			if err := s.fail(json.RawMessage("null"), parseError, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		s.metrics.messages.Inc(methodLabel(m.Method))
+		if m.Method == "exit" {
+			if !s.shutdown {
+				return ErrExit
+			}
+			return nil
+		}
+		if err := s.handle(&m); err != nil {
+			return err
+		}
+	}
+}
+
+// Methods are the methods counted under their names by the message metrics.
+var methods = map[string]bool{
+	"initialize":              true,
+	"initialized":             true,
+	"shutdown":                true,
+	"exit":                    true,
+	"textDocument/didOpen":    true,
+	"textDocument/didChange":  true,
+	"textDocument/didClose":   true,
+	"textDocument/definition": true,
+	"textDocument/hover":      true,
+	"textDocument/completion": true,
+	"textDocument/formatting": true,
+}
+
+// MethodLabel returns the method as the label of the message metrics, with
+// the unknown methods counted as other, so that clients cannot grow the
+// metrics without bound.
+func methodLabel(method string) string {
+	if methods[method] {
+		return method
+	}
+	return "other"
+}
+
+// Handle dispatches the message to its method. Only errors writing to the
+// client are returned; the others are sent to the client.
+func (s *Server) handle(m *message) error {
+	switch m.Method {
+	case "initialize":
+		return s.reply(m.ID, map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":           2,
+				"definitionProvider":         true,
+				"hoverProvider":              true,
+				"completionProvider":         map[string]any{"triggerCharacters": []string{"=", "#", "{"}},
+				"documentFormattingProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "bibx"},
+		})
+	case "shutdown":
+		s.shutdown = true
+		return s.reply(m.ID, nil)
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if json.Unmarshal(m.Params, &params) != nil {
+			return nil
+		}
+		return s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if json.Unmarshal(m.Params, &params) != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		uri := params.TextDocument.URI
+		start := time.Now()
+		d, ok := s.docs[uri]
+		for _, c := range params.ContentChanges {
+			if c.Range == nil || !ok {
+				d, ok = newDocument(c.Text), true
+				continue
+			}
+			d = d.edit(*c.Range, c.Text)
+		}
+		s.store(uri, d, start)
+		return s.publish(uri, d.diagnostics(s.Rules, s.Schemas))
+	case "textDocument/didClose":
+		var params didCloseParams
+		if json.Unmarshal(m.Params, &params) != nil {
+			return nil
+		}
+		delete(s.docs, params.TextDocument.URI)
+		s.countEntries()
+		return s.publish(params.TextDocument.URI, []diagnostic{})
+	case "textDocument/definition":
+		d, p, err := s.position(m)
+		if err != nil {
+			return s.fail(m.ID, invalidParams, err.Error())
+		}
+		if pos, ok := d.definition(d.pos(p.Position)); ok {
+			return s.reply(m.ID, location{URI: p.TextDocument.URI, Range: d.lineSpan(pos)})
+		}
+		return s.reply(m.ID, nil)
+	case "textDocument/hover":
+		d, p, err := s.position(m)
+		if err != nil {
+			return s.fail(m.ID, invalidParams, err.Error())
+		}
+		if text := d.hover(d.pos(p.Position)); text != `` {
+			return s.reply(m.ID, hover{Contents: markupContent{Kind: "plaintext", Value: text}})
+		}
+		return s.reply(m.ID, nil)
+	case "textDocument/completion":
+		d, p, err := s.position(m)
+		if err != nil {
+			return s.fail(m.ID, invalidParams, err.Error())
+		}
+		return s.reply(m.ID, d.completion(d.pos(p.Position), s.Schemas))
+	case "textDocument/formatting":
+		var params formattingParams
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			return s.fail(m.ID, invalidParams, err.Error())
+		}
+		d, ok := s.docs[params.TextDocument.URI]
+		if !ok {
+			return s.fail(m.ID, invalidParams, "unknown document "+params.TextDocument.URI)
+		}
+		src, err := format.Source([]byte(d.text))
+		if err != nil {
+			return s.fail(m.ID, internalError, err.Error())
+		}
+		if string(src) == d.text {
+			return s.reply(m.ID, []textEdit{})
+		}
+		return s.reply(m.ID, []textEdit{{Range: d.span(), NewText: string(src)}})
+	default:
+		// Unknown notifications are ignored as the protocol requires.
+		if m.ID != nil {
+			return s.fail(m.ID, methodNotFound, "method not found: "+m.Method)
+		}
+		return nil
+	}
+}
+
+// Update replaces the text of the document and publishes its diagnostics.
+func (s *Server) update(uri, text string) error {
+	start := time.Now()
+	d := newDocument(text)
+	s.store(uri, d, start)
+	return s.publish(uri, d.diagnostics(s.Rules, s.Schemas))
+}
+
+// Store makes the document parsed since start current under the URI and
+// records the time the parse took.
+func (s *Server) store(uri string, d *document, start time.Time) {
+	s.metrics.parses.Observe(time.Since(start).Seconds())
+	if d.err != nil {
+		s.metrics.parseErrors.Inc()
+	}
+	s.docs[uri] = d
+	s.countEntries()
+}
+
+// CountEntries updates the number of entries in the open documents.
+func (s *Server) countEntries() {
+	n := 0
+	for _, d := range s.docs {
+		n += len(d.entries())
+	}
+	s.metrics.entries.Set(float64(n))
+}
+
+// Position decodes the parameters of a request made at a position in an
+// open document.
+func (s *Server) position(m *message) (*document, positionParams, error) {
+	var params positionParams
+	if err := json.Unmarshal(m.Params, &params); err != nil {
+		return nil, params, err
+	}
+	d, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return nil, params, errors.New("unknown document " + params.TextDocument.URI)
+	}
+	return d, params, nil
+}
+
+func (s *Server) publish(uri string, diagnostics []diagnostic) error {
+	params, err := json.Marshal(publishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+	if err != nil {
+		return err
+	}
+	return writeMessage(s.out, &message{Method: "textDocument/publishDiagnostics", Params: params})
+}
+
+func (s *Server) reply(id json.RawMessage, result any) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return writeMessage(s.out, &message{ID: id, Result: data})
+}
+
+func (s *Server) fail(id json.RawMessage, code int, msg string) error {
+	s.metrics.errors.Inc(strconv.Itoa(code))
+	return writeMessage(s.out, &message{ID: id, Error: &rpcError{Code: code, Message: msg}})
+}
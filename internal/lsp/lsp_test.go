@@ -0,0 +1,320 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const testURI = "file:///library.bib"
+
+const testBib = `@string{pnas = "Proceedings of the National Academy of Sciences"}
+
+@article{Cohen1963,
+  author  = {Paul Cohen},
+  title   = {The independence of the continuum hypothesis},
+  journal = pnas,
+  year    = 1963
+}
+
+@inproceedings{Smith2001,
+  author    = {John Smith},
+  title     = {A paper},
+  booktitle = {Proceedings},
+  year      = 2001,
+  crossref  = {Proc2001}
+}
+
+@proceedings{Proc2001,
+  title = {Proceedings of the conference},
+  year  = 2001
+}
+`
+
+// Session runs the server over the messages and returns the messages it
+// wrote in order.
+func session(t *testing.T, msgs ...string) []message {
+	t.Helper()
+	var in, out bytes.Buffer
+	for _, m := range msgs {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(m), m)
+	}
+	if err := NewServer(&in, &out).Run(); err != nil {
+		t.Fatal(err)
+	}
+	result := []message{}
+	r := bufio.NewReader(&out)
+	for {
+		data, err := readMessage(r)
+		if err != nil {
+			break
+		}
+		var m message
+		if err := json.Unmarshal(data, &m); err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, m)
+	}
+	return result
+}
+
+func didOpen(text string) string {
+	data, _ := json.Marshal(text)
+	return `{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"` + testURI + `","languageId":"bibtex","version":1,"text":` + string(data) + `}}}`
+}
+
+func request(method string, line, character int) string {
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":%q,"params":{"textDocument":{"uri":%q},"position":{"line":%d,"character":%d}}}`, method, testURI, line, character)
+}
+
+func TestInitialize(t *testing.T) {
+	msgs := session(t,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	)
+	if len(msgs) != 2 {
+		t.Fatalf("have %d messages; want 2", len(msgs))
+	}
+	if !strings.Contains(string(msgs[0].Result), `"definitionProvider":true`) {
+		t.Errorf("missing capabilities: %s", msgs[0].Result)
+	}
+	if string(msgs[1].ID) != "2" || string(msgs[1].Result) != "null" {
+		t.Errorf("have shutdown reply %s %s", msgs[1].ID, msgs[1].Result)
+	}
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+	in := bytes.NewBufferString("Content-Length: 33\r\n\r\n{\"jsonrpc\":\"2.0\",\"method\":\"exit\"}")
+	if err := NewServer(in, &bytes.Buffer{}).Run(); err != ErrExit {
+		t.Errorf("have %v; want %v", err, ErrExit)
+	}
+}
+
+func TestUnknownMethod(t *testing.T) {
+	msgs := session(t, `{"jsonrpc":"2.0","id":7,"method":"workspace/symbol","params":{}}`)
+	if len(msgs) != 1 || msgs[0].Error == nil || msgs[0].Error.Code != methodNotFound {
+		t.Errorf("have %+v", msgs)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	src := "@article{Doe2020,\n  title = {A paper},\n  year = {twenty},\n  crossref = {Nobody}\n}\n"
+	msgs := session(t, didOpen(src))
+	if len(msgs) != 1 || msgs[0].Method != "textDocument/publishDiagnostics" {
+		t.Fatalf("have %+v", msgs)
+	}
+	var params publishDiagnosticsParams
+	if err := json.Unmarshal(msgs[0].Params, &params); err != nil {
+		t.Fatal(err)
+	}
+	codes := map[string]diagnostic{}
+	for _, d := range params.Diagnostics {
+		codes[d.Code] = d
+	}
+	for _, code := range []string{"BIBX0105", "BIBX0203", "BIBX0301"} {
+		if _, ok := codes[code]; !ok {
+			t.Errorf("missing %s diagnostic in %+v", code, params.Diagnostics)
+		}
+	}
+	want := span{Start: position{Line: 2, Character: 2}, End: position{Line: 2, Character: 18}}
+	if have := codes["BIBX0203"].Range; have != want {
+		t.Errorf("have range %+v; want %+v", have, want)
+	}
+}
+
+func TestDidChange(t *testing.T) {
+	src := "@article{Doe2020,\n  title = {A paper},\n  year = 2020\n}\n"
+	change := func(changes string) string {
+		return `{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"` + testURI + `","version":2},"contentChanges":[` + changes + `]}}`
+	}
+	cases := []struct {
+		name    string
+		changes string
+		want    []string
+	}{
+		{"range", `{"range":{"start":{"line":2,"character":9},"end":{"line":2,"character":13}},"text":"{twenty}"}`, []string{"BIBX0105", "BIBX0201", "BIBX0201", "BIBX0203"}},
+		{"ranges", `{"range":{"start":{"line":2,"character":9},"end":{"line":2,"character":13}},"text":"{twenty}"},{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":0}},"text":"@misc{x,}\n"}`, []string{"BIBX0105", "BIBX0201", "BIBX0201", "BIBX0203"}},
+		{"full", `{"text":"@article{Doe2020, crossref = {Nobody}}"}`, []string{"BIBX0301"}},
+		{"break", `{"range":{"start":{"line":3,"character":0},"end":{"line":3,"character":1}},"text":""}`, []string{"BIBX0001"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			msgs := session(t, didOpen(src), change(c.changes))
+			if len(msgs) != 2 {
+				t.Fatalf("have %d messages; want 2", len(msgs))
+			}
+			var params publishDiagnosticsParams
+			if err := json.Unmarshal(msgs[1].Params, &params); err != nil {
+				t.Fatal(err)
+			}
+			have := []string{}
+			for _, d := range params.Diagnostics {
+				have = append(have, d.Code)
+			}
+			sort.Strings(have)
+			if !reflect.DeepEqual(have, c.want) {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	cases := []struct {
+		name      string
+		line, col int
+		want      string
+	}{
+		{"macro", 5, 13, `{"uri":"file:///library.bib","range":{"start":{"line":0,"character":8},"end":{"line":0,"character":65}}}`},
+		{"crossref", 14, 18, `{"uri":"file:///library.bib","range":{"start":{"line":17,"character":0},"end":{"line":17,"character":22}}}`},
+		{"plain value", 3, 16, `null`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			msgs := session(t, didOpen(testBib), request("textDocument/definition", c.line, c.col))
+			if have := string(msgs[len(msgs)-1].Result); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestHover(t *testing.T) {
+	cases := []struct {
+		name      string
+		line, col int
+		want      string
+	}{
+		{"macro", 5, 13, "pnas = Proceedings of the National Academy of Sciences"},
+		{"field", 5, 3, "journal = Proceedings of the National Academy of Sciences"},
+		{"crossref", 14, 18, "@proceedings{Proc2001}\ntitle: Proceedings of the conference\nyear: 2001"},
+		{"plain value", 3, 16, ``},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			msgs := session(t, didOpen(testBib), request("textDocument/hover", c.line, c.col))
+			var h *hover
+			if err := json.Unmarshal(msgs[len(msgs)-1].Result, &h); err != nil {
+				t.Fatal(err)
+			}
+			have := ``
+			if h != nil {
+				have = h.Contents.Value
+			}
+			if have != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	cases := []struct {
+		name      string
+		src       string
+		line, col int
+		want      []string
+	}{
+		{"field", "@misc{Doe2020,\n  title = {A},\n  \n}\n", 2, 2, []string{"abstract", "annote", "archiveprefix", "author", "crossref", "doi", "eprint", "howpublished", "isbn", "issn", "key", "keywords", "language", "month", "note", "pmid", "primaryclass", "url", "urldate", "year"}},
+		{"crossref", "@book{Proc,\n  title = {P}\n}\n@inbook{Doe,\n  crossref = {\n}\n", 4, 14, []string{"Doe", "Proc"}},
+		{"macro", "@string{pnas = {PNAS}}\n@article{Doe,\n  journal = \n}\n", 2, 12, []string{"apr", "aug", "dec", "feb", "jan", "jul", "jun", "mar", "may", "nov", "oct", "pnas", "sep"}},
+		{"braced value", "@misc{Doe,\n  title = {A\n  \n}\n", 2, 2, []string{}},
+		{"outside", "@misc{Doe,\n  title = {A}\n}\n\n", 3, 0, []string{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			msgs := session(t, didOpen(c.src), request("textDocument/completion", c.line, c.col))
+			var items []completionItem
+			if err := json.Unmarshal(msgs[len(msgs)-1].Result, &items); err != nil {
+				t.Fatal(err)
+			}
+			have := []string{}
+			for _, it := range items {
+				have = append(have, it.Label)
+			}
+			if !reflect.DeepEqual(have, c.want) {
+				t.Errorf("have %v; want %v", have, c.want)
+			}
+		})
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	src := "@misc{Doe2020,\n title={A},\n  year=2020}\n"
+	req := `{"jsonrpc":"2.0","id":1,"method":"textDocument/formatting","params":{"textDocument":{"uri":"` + testURI + `"},"options":{"tabSize":2,"insertSpaces":true}}}`
+	msgs := session(t, didOpen(src), req)
+	var edits []textEdit
+	if err := json.Unmarshal(msgs[len(msgs)-1].Result, &edits); err != nil {
+		t.Fatal(err)
+	}
+	want := []textEdit{{
+		Range:   span{End: position{Line: 3}},
+		NewText: "@misc{Doe2020,\n  title = {A},\n  year  = 2020\n}\n",
+	}}
+	if !reflect.DeepEqual(edits, want) {
+		t.Errorf("have %+v; want %+v", edits, want)
+	}
+}
+
+func TestPosition(t *testing.T) {
+	d := newDocument("@misc{x,\n  title = {𝔄é b}\n}\n")
+	cases := []struct {
+		char int
+		col  int
+	}{
+		{0, 1},
+		{11, 12},
+		{13, 13},
+		{14, 14},
+	}
+	for _, c := range cases {
+		p := d.pos(position{Line: 1, Character: c.char})
+		if p.Column != c.col {
+			t.Errorf("have column %d for character %d; want %d", p.Column, c.char, c.col)
+		}
+		if back := d.position(p); back.Character != c.char {
+			t.Errorf("have character %d; want %d", back.Character, c.char)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	msgs := []string{
+		didOpen("@article{Doe2020,\n  title = {A paper},\n  year = 2020\n}\n@misc{x,}\n"),
+		request("textDocument/hover", 0, 2),
+		`{"jsonrpc":"2.0","id":9,"method":"bibx/unknown"}`,
+		didOpen("@misc{"),
+	}
+	var in, out bytes.Buffer
+	for _, m := range msgs {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(m), m)
+	}
+	s := NewServer(&in, &out)
+	if err := s.Run(); err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := s.Metrics().WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`bibx_lsp_messages_total{method="textDocument/didOpen"} 2`,
+		`bibx_lsp_messages_total{method="textDocument/hover"} 1`,
+		`bibx_lsp_messages_total{method="other"} 1`,
+		`bibx_lsp_errors_total{code="-32601"} 1`,
+		"bibx_parse_duration_seconds_count 2",
+		"bibx_parse_errors_total 1",
+		"bibx_entries 0",
+	} {
+		if !strings.Contains(b.String(), want+"\n") {
+			t.Errorf("missing %s in\n%s", want, b.String())
+		}
+	}
+}
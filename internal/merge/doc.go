@@ -0,0 +1,5 @@
+/*
+Merge package merges two BibTeX files changed independently from a common
+ancestor declaration by declaration and field by field.
+*/
+package merge
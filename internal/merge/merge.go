@@ -0,0 +1,326 @@
+package merge
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// Labels of the sides of the conflict markers.
+const (
+	Ours   = "ours"
+	Theirs = "theirs"
+)
+
+// Conflict describes a change made on both sides that could not be merged.
+// Field is empty when the conflict concerns the declaration as a whole.
+type Conflict struct {
+	Key     string
+	Field   string
+	Message string
+}
+
+// Error formats the conflict as a message prefixed with the key and the
+// field.
+func (c Conflict) Error() string {
+	if c.Field == `` {
+		return c.Key + ": " + c.Message
+	}
+	return c.Key + ": " + c.Field + ": " + c.Message
+}
+
+// Merge performs a three-way merge of the declarations of ours and theirs
+// against their common ancestor base. Entries are matched by their cite keys
+// and @string declarations by their macro names, both case-insensitively,
+// while the other declarations are matched by their contents.
+//
+// Changes made on one side only are taken over, fields included, so that
+// edits of different fields of the same entry merge cleanly. A field changed
+// differently on both sides gets a value holding both versions between
+// conflict markers, which does not parse until it is resolved. Conflicting
+// deletions and changes keep the changed declaration, and conflicting entry
+// types and comments keep ours; all of them are reported.
+//
+// The result follows the order of ours, with the declarations added in
+// theirs placed after their predecessors.
+func Merge(base, ours, theirs []parse.Node) ([]parse.Node, []Conflict) {
+	b, o, t := index(base), index(ours), index(theirs)
+	conflicts := []Conflict{}
+	keys := []string{}
+	nodes := make(map[string]parse.Node)
+	for _, k := range o.keys {
+		n, cs := merge(b.nodes[k], o.nodes[k], t.nodes[k])
+		conflicts = append(conflicts, cs...)
+		if n != nil {
+			keys = append(keys, k)
+			nodes[k] = n
+		}
+	}
+	for i, k := range t.keys {
+		if _, ok := o.nodes[k]; ok {
+			continue
+		}
+		n, cs := merge(b.nodes[k], nil, t.nodes[k])
+		conflicts = append(conflicts, cs...)
+		if n == nil {
+			continue
+		}
+		keys = insert(keys, k, predecessor(t.keys[:i], nodes))
+		nodes[k] = n
+	}
+	result := make([]parse.Node, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, nodes[k])
+	}
+	return result, conflicts
+}
+
+// Predecessor returns the last of the keys present in the nodes.
+func predecessor(keys []string, nodes map[string]parse.Node) string {
+	for i := len(keys) - 1; i >= 0; i-- {
+		if _, ok := nodes[keys[i]]; ok {
+			return keys[i]
+		}
+	}
+	return ``
+}
+
+// Insert inserts the key after the other one, or at the start if it is
+// missing.
+func insert(keys []string, key, after string) []string {
+	i := 0
+	for j, k := range keys {
+		if k == after {
+			i = j + 1
+			break
+		}
+	}
+	keys = append(keys, ``)
+	copy(keys[i+1:], keys[i:])
+	keys[i] = key
+	return keys
+}
+
+type indexed struct {
+	keys  []string
+	nodes map[string]parse.Node
+}
+
+// Index maps the declarations onto their identities. Repeated identities are
+// numbered in the order they appear.
+func index(nodes []parse.Node) indexed {
+	result := indexed{nodes: make(map[string]parse.Node)}
+	seen := make(map[string]int)
+	for _, n := range nodes {
+		k := identity(n)
+		if seen[k]++; seen[k] > 1 {
+			k = fmt.Sprintf("%s#%d", k, seen[k])
+		}
+		result.keys = append(result.keys, k)
+		result.nodes[k] = n
+	}
+	return result
+}
+
+func identity(n parse.Node) string {
+	switch d := n.(type) {
+	case *parse.EntryDecl:
+		return "entry:" + strings.ToLower(d.CiteKey)
+	case *parse.AbbrevDecl:
+		if d.Field != nil {
+			return "string:" + strings.ToLower(d.Field.Key)
+		}
+		return "string:"
+	case *parse.PreambleDecl:
+		return "preamble:" + d.Value
+	case *parse.CommentGroupExpr:
+		return "comment:" + comments(d)
+	default:
+		return fmt.Sprint(n)
+	}
+}
+
+// Name returns the cite key of the entry or the name of the macro the
+// declaration defines.
+func name(n parse.Node) string {
+	switch d := n.(type) {
+	case *parse.EntryDecl:
+		return d.CiteKey
+	case *parse.AbbrevDecl:
+		if d.Field != nil {
+			return d.Field.Key
+		}
+	}
+	return fmt.Sprint(n)
+}
+
+// Merge merges a single declaration present on at least one side. A nil
+// result stands for a deleted declaration.
+func merge(base, ours, theirs parse.Node) (parse.Node, []Conflict) {
+	switch {
+	case ours == nil || theirs == nil:
+		kept := ours
+		if kept == nil {
+			kept = theirs
+		}
+		if base == nil {
+			return kept, nil
+		}
+		if kept.Eq(base) {
+			return nil, nil
+		}
+		side := Theirs
+		if ours == nil {
+			side = Ours
+		}
+		return kept, []Conflict{{Key: name(kept), Message: "deleted in " + side + " but changed on the other side"}}
+	case ours.Eq(theirs):
+		return ours, nil
+	}
+	switch o := ours.(type) {
+	case *parse.EntryDecl:
+		b, _ := base.(*parse.EntryDecl)
+		return mergeEntry(b, o, theirs.(*parse.EntryDecl))
+	case *parse.AbbrevDecl:
+		t := theirs.(*parse.AbbrevDecl)
+		if o.Field == nil || t.Field == nil {
+			return ours, nil
+		}
+		b, _ := base.(*parse.AbbrevDecl)
+		var bf []*parse.FieldStmt
+		if b != nil && b.Field != nil {
+			bf = []*parse.FieldStmt{b.Field}
+		}
+		fs, conflicts := mergeFields(o.Field.Key, bf, []*parse.FieldStmt{o.Field}, []*parse.FieldStmt{t.Field})
+		for i := range conflicts {
+			conflicts[i].Field = ``
+		}
+		return &parse.AbbrevDecl{Comments: o.Comments, Field: fs[0], Pos: o.Pos}, conflicts
+	default:
+		// Other declarations are matched by their contents, so they only
+		// differ in their comments.
+		return ours, nil
+	}
+}
+
+func mergeEntry(base, ours, theirs *parse.EntryDecl) (*parse.EntryDecl, []Conflict) {
+	if base == nil {
+		base = &parse.EntryDecl{}
+	}
+	conflicts := []Conflict{}
+	result := &parse.EntryDecl{Comments: ours.Comments, Pos: ours.Pos}
+	var ok bool
+	if result.Name, ok = merge3(base.Name, ours.Name, theirs.Name); !ok {
+		conflicts = append(conflicts, Conflict{
+			Key:     ours.CiteKey,
+			Message: fmt.Sprintf("entry type changed to %s in %s and to %s in %s", ours.Name, Ours, theirs.Name, Theirs),
+		})
+	}
+	if result.CiteKey, ok = merge3(base.CiteKey, ours.CiteKey, theirs.CiteKey); !ok {
+		conflicts = append(conflicts, Conflict{
+			Key:     ours.CiteKey,
+			Message: fmt.Sprintf("cite key changed to %s in %s and to %s in %s", ours.CiteKey, Ours, theirs.CiteKey, Theirs),
+		})
+	}
+	bc, oc, tc := comments(base.Comments), comments(ours.Comments), comments(theirs.Comments)
+	if _, ok := merge3(bc, oc, tc); !ok {
+		conflicts = append(conflicts, Conflict{Key: ours.CiteKey, Message: "comments changed on both sides; kept " + Ours})
+	} else if oc == bc {
+		result.Comments = theirs.Comments
+	}
+	fs, cs := mergeFields(result.CiteKey, base.Fields, ours.Fields, theirs.Fields)
+	result.Fields = fs
+	return result, append(conflicts, cs...)
+}
+
+// MergeFields merges the fields matched by their case-insensitive names,
+// keeping the order of ours followed by the fields added in theirs.
+func mergeFields(key string, base, ours, theirs []*parse.FieldStmt) ([]*parse.FieldStmt, []Conflict) {
+	b, o, t := fieldMap(base), fieldMap(ours), fieldMap(theirs)
+	names := []string{}
+	for _, f := range ours {
+		names = append(names, strings.ToLower(f.Key))
+	}
+	for _, f := range theirs {
+		if _, ok := o[strings.ToLower(f.Key)]; !ok {
+			names = append(names, strings.ToLower(f.Key))
+		}
+	}
+	result := []*parse.FieldStmt{}
+	conflicts := []Conflict{}
+	for _, name := range names {
+		of, tf := o[name], t[name]
+		v, ok := merge3(value(b[name]), value(of), value(tf))
+		if !ok {
+			v = markers(value(of), value(tf))
+			conflicts = append(conflicts, Conflict{Key: key, Field: name, Message: "changed on both sides"})
+		}
+		if v == `` {
+			continue
+		}
+		f := of
+		if f == nil {
+			f = tf
+		}
+		result = append(result, &parse.FieldStmt{Key: f.Key, Value: v, Pos: f.Pos})
+	}
+	return result, conflicts
+}
+
+func fieldMap(fs []*parse.FieldStmt) map[string]*parse.FieldStmt {
+	result := make(map[string]*parse.FieldStmt)
+	for _, f := range fs {
+		result[strings.ToLower(f.Key)] = f
+	}
+	return result
+}
+
+// Value returns the raw value of the field, which is empty for missing
+// fields.
+func value(f *parse.FieldStmt) string {
+	if f == nil {
+		return ``
+	}
+	return f.Value
+}
+
+// Merge3 merges a value changed on both sides and reports whether the
+// changes do not conflict. Ours is returned on a conflict.
+func merge3(base, ours, theirs string) (string, bool) {
+	switch {
+	case ours == theirs, theirs == base:
+		return ours, true
+	case ours == base:
+		return theirs, true
+	default:
+		return ours, false
+	}
+}
+
+// Markers lays out both versions of a conflicting value between conflict
+// markers starting lines of their own. A missing value leaves its side empty.
+func markers(ours, theirs string) string {
+	var b strings.Builder
+	b.WriteString("\n<<<<<<< " + Ours + "\n")
+	if ours != `` {
+		b.WriteString(ours + "\n")
+	}
+	b.WriteString("=======\n")
+	if theirs != `` {
+		b.WriteString(theirs + "\n")
+	}
+	b.WriteString(">>>>>>> " + Theirs)
+	return b.String()
+}
+
+func comments(c *parse.CommentGroupExpr) string {
+	if c == nil {
+		return ``
+	}
+	values := []string{}
+	for _, v := range c.Values {
+		values = append(values, v.Value)
+	}
+	return strings.Join(values, "\n")
+}
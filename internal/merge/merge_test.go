@@ -0,0 +1,187 @@
+package merge
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+func parseNodes(t *testing.T, src string) []parse.Node {
+	t.Helper()
+	p := parse.NewParser(scan.NewScanner(scan.NewReader(strings.NewReader(src))))
+	result := []parse.Node{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		result = append(result, n)
+	}
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+const base = `@string{pnas = "PNAS"}
+
+@article{Cohen1963,
+  author  = {Paul Cohen},
+  title   = {The independence of the continuum hypothesis},
+  journal = pnas,
+  year    = 1963
+}
+
+@book{Babington1993,
+  author = {Peter Babington},
+  title  = {The title of the work},
+  year   = 1993
+}
+`
+
+func TestMerge(t *testing.T) {
+	cases := []struct {
+		name          string
+		ours, theirs  string
+		want          string
+		wantConflicts []Conflict
+	}{
+		{
+			name:   "different fields",
+			ours:   strings.Replace(base, "year    = 1963", "year    = 1964", 1),
+			theirs: strings.Replace(base, "title   = {The independence", "title   = {On the independence", 1),
+			want: `@string{pnas = "PNAS"}
+
+@article{Cohen1963,
+  author  = {Paul Cohen},
+  title   = {On the independence of the continuum hypothesis},
+  journal = pnas,
+  year    = 1964
+}
+
+@book{Babington1993,
+  author = {Peter Babington},
+  title  = {The title of the work},
+  year   = 1993
+}
+`,
+			wantConflicts: []Conflict{},
+		},
+		{
+			name:   "added fields and entries",
+			ours:   strings.Replace(base, "  year   = 1993\n", "  year   = 1993,\n  isbn   = {0-19-852663-6}\n", 1) + "\n@misc{Ours2020,\n  title = {O}\n}\n",
+			theirs: strings.Replace(base, "  year   = 1993\n", "  year   = 1993,\n  note   = {Reprint}\n", 1) + "\n@misc{Theirs2021,\n  title = {T}\n}\n",
+			want: `@string{pnas = "PNAS"}
+
+@article{Cohen1963,
+  author  = {Paul Cohen},
+  title   = {The independence of the continuum hypothesis},
+  journal = pnas,
+  year    = 1963
+}
+
+@book{Babington1993,
+  author = {Peter Babington},
+  title  = {The title of the work},
+  year   = 1993,
+  isbn   = {0-19-852663-6},
+  note   = {Reprint}
+}
+
+@misc{Theirs2021,
+  title = {T}
+}
+
+@misc{Ours2020,
+  title = {O}
+}
+`,
+			wantConflicts: []Conflict{},
+		},
+		{
+			name:   "deleted entry",
+			ours:   strings.Replace(base, "journal = pnas", "journal = {PNAS}", 1),
+			theirs: base[:strings.Index(base, "@book")],
+			want: `@string{pnas = "PNAS"}
+
+@article{Cohen1963,
+  author  = {Paul Cohen},
+  title   = {The independence of the continuum hypothesis},
+  journal = {PNAS},
+  year    = 1963
+}
+`,
+			wantConflicts: []Conflict{},
+		},
+		{
+			name:   "conflicting field",
+			ours:   strings.Replace(base, "year    = 1963", "year    = 1964", 1),
+			theirs: strings.Replace(base, "year    = 1963", "year    = 1965", 1),
+			want: `@string{pnas = "PNAS"}
+
+@article{Cohen1963,
+  author  = {Paul Cohen},
+  title   = {The independence of the continuum hypothesis},
+  journal = pnas,
+  year    = 
+<<<<<<< ours
+1964
+=======
+1965
+>>>>>>> theirs
+}
+
+@book{Babington1993,
+  author = {Peter Babington},
+  title  = {The title of the work},
+  year   = 1993
+}
+`,
+			wantConflicts: []Conflict{{Key: "Cohen1963", Field: "year", Message: "changed on both sides"}},
+		},
+		{
+			name:   "deleted and changed",
+			ours:   base[:strings.Index(base, "@book")],
+			theirs: strings.Replace(base, "year   = 1993", "year   = 1994", 1),
+			want: `@string{pnas = "PNAS"}
+
+@article{Cohen1963,
+  author  = {Paul Cohen},
+  title   = {The independence of the continuum hypothesis},
+  journal = pnas,
+  year    = 1963
+}
+
+@book{Babington1993,
+  author = {Peter Babington},
+  title  = {The title of the work},
+  year   = 1994
+}
+`,
+			wantConflicts: []Conflict{{Key: "Babington1993", Message: "deleted in ours but changed on the other side"}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			nodes, conflicts := Merge(parseNodes(t, base), parseNodes(t, c.ours), parseNodes(t, c.theirs))
+			var b bytes.Buffer
+			if err := format.Nodes(&b, nodes); err != nil {
+				t.Fatal(err)
+			}
+			if have := b.String(); have != c.want {
+				t.Errorf("have:\n%s\nwant:\n%s", have, c.want)
+			}
+			if !reflect.DeepEqual(conflicts, c.wantConflicts) {
+				t.Errorf("have %v; want %v", conflicts, c.wantConflicts)
+			}
+		})
+	}
+}
+
+func TestConflictError(t *testing.T) {
+	c := Conflict{Key: "Cohen1963", Field: "year", Message: "changed on both sides"}
+	if have, want := c.Error(), "Cohen1963: year: changed on both sides"; have != want {
+		t.Errorf("have %q; want %q", have, want)
+	}
+}
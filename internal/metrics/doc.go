@@ -0,0 +1,6 @@
+/*
+Metrics package counts what the long-running commands, the REST server and
+the language server, do and exposes the counts in the Prometheus text format
+for operational monitoring.
+*/
+package metrics
@@ -0,0 +1,248 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds of histogram buckets suited to
+// durations in seconds from a millisecond to ten seconds.
+var DefaultBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry holds metrics and writes them in the order they were created.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// Counter creates a counter with the label names registered with r.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c := &Counter{newVec(name, help, "counter", labels)}
+	r.register(c)
+	return c
+}
+
+// Gauge creates a gauge with the label names registered with r.
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{newVec(name, help, "gauge", labels)}
+	r.register(g)
+	return g
+}
+
+// Histogram creates a histogram with the bucket upper bounds, given in
+// increasing order, registered with r.
+func (r *Registry) Histogram(name, help string, buckets []float64) *Histogram {
+	h := &Histogram{name: name, help: help, bounds: buckets, counts: make([]uint64, len(buckets))}
+	r.register(h)
+	return h
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteText writes the metrics in the Prometheus text exposition format.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	metrics := append([]metric{}, r.metrics...)
+	r.mu.Unlock()
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP serves the metrics to a Prometheus scraper.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteText(w)
+}
+
+// Vec holds the values of a metric by the values of its labels.
+type vec struct {
+	name, help, typ string
+	labels          []string
+	mu              sync.Mutex
+	values          map[string]float64
+}
+
+func newVec(name, help, typ string, labels []string) vec {
+	return vec{name: name, help: help, typ: typ, labels: labels, values: make(map[string]float64)}
+}
+
+// Key joins the label values, which must be as many as the labels.
+func (v *vec) key(values []string) string {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values; have %d", v.name, len(v.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+func (v *vec) add(delta float64, values []string) {
+	k := v.key(values)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values[k] += delta
+}
+
+func (v *vec) set(x float64, values []string) {
+	k := v.key(values)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values[k] = x
+}
+
+func (v *vec) get(values []string) float64 {
+	k := v.key(values)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.values[k]
+}
+
+func (v *vec) write(w *bufio.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	header(w, v.name, v.help, v.typ)
+	keys := make([]string, 0, len(v.values))
+	for k := range v.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		pairs := []string{}
+		if len(v.labels) > 0 {
+			for i, val := range strings.Split(k, "\xff") {
+				pairs = append(pairs, v.labels[i], val)
+			}
+		}
+		sample(w, v.name, v.values[k], pairs...)
+	}
+}
+
+// Counter is a metric that only goes up, such as the number of requests.
+type Counter struct{ vec }
+
+// Inc adds one to the counter with the label values.
+func (c *Counter) Inc(values ...string) { c.add(1, values) }
+
+// Add adds delta, which must not be negative, to the counter with the label
+// values.
+func (c *Counter) Add(delta float64, values ...string) {
+	if delta < 0 {
+		panic("metrics: counter decreased")
+	}
+	c.add(delta, values)
+}
+
+// Value returns the counter with the label values.
+func (c *Counter) Value(values ...string) float64 { return c.get(values) }
+
+// Gauge is a metric that goes up and down, such as the number of entries.
+type Gauge struct{ vec }
+
+// Set sets the gauge with the label values.
+func (g *Gauge) Set(x float64, values ...string) { g.set(x, values) }
+
+// Add adds delta to the gauge with the label values.
+func (g *Gauge) Add(delta float64, values ...string) { g.add(delta, values) }
+
+// Value returns the gauge with the label values.
+func (g *Gauge) Value(values ...string) float64 { return g.get(values) }
+
+// Histogram counts observations, such as durations, in buckets.
+type Histogram struct {
+	name, help string
+	bounds     []float64
+	mu         sync.Mutex
+	counts     []uint64
+	count      uint64
+	sum        float64
+}
+
+// Observe adds the value to the histogram.
+func (h *Histogram) Observe(x float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if i := sort.SearchFloat64s(h.bounds, x); i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += x
+}
+
+// Count returns the number of values observed.
+func (h *Histogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	header(w, h.name, h.help, "histogram")
+	var cumulative uint64
+	for i, b := range h.bounds {
+		cumulative += h.counts[i]
+		sample(w, h.name+"_bucket", float64(cumulative), "le", number(b))
+	}
+	sample(w, h.name+"_bucket", float64(h.count), "le", "+Inf")
+	sample(w, h.name+"_sum", h.sum)
+	sample(w, h.name+"_count", float64(h.count))
+}
+
+func header(w *bufio.Writer, name, help, typ string) {
+	help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// Sample writes a line with the value of the metric and its label name and
+// value pairs.
+func sample(w *bufio.Writer, name string, x float64, pairs ...string) {
+	w.WriteString(name)
+	if len(pairs) > 0 {
+		esc := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+		w.WriteByte('{')
+		for i := 0; i < len(pairs); i += 2 {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, pairs[i], esc.Replace(pairs[i+1]))
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(number(x))
+	w.WriteByte('\n')
+}
+
+func number(x float64) string {
+	switch {
+	case math.IsInf(x, 1):
+		return "+Inf"
+	case math.IsInf(x, -1):
+		return "-Inf"
+	case math.IsNaN(x):
+		return "NaN"
+	}
+	return strconv.FormatFloat(x, 'g', -1, 64)
+}
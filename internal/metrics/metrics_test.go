@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := &Registry{}
+	c := r.Counter("requests_total", "Requests served.", "method", "code")
+	g := r.Gauge("entries", "Entries held.")
+	h := r.Histogram("duration_seconds", "Durations.", []float64{0.1, 1})
+	c.Inc("GET", "200")
+	c.Inc("GET", "200")
+	c.Add(3, "POST", `4"0\0`)
+	g.Set(7)
+	g.Add(-2)
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(2)
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{method="GET",code="200"} 2
+requests_total{method="POST",code="4\"0\\0"} 3
+# HELP entries Entries held.
+# TYPE entries gauge
+entries 5
+# HELP duration_seconds Durations.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{le="0.1"} 1
+duration_seconds_bucket{le="1"} 2
+duration_seconds_bucket{le="+Inf"} 3
+duration_seconds_sum 2.55
+duration_seconds_count 3
+`
+	if have := b.String(); have != want {
+		t.Errorf("have\n%s\nwant\n%s", have, want)
+	}
+}
+
+func TestLabelValues(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("want panic on missing label values")
+		}
+	}()
+	(&Registry{}).Counter("c", "C.", "a", "b").Inc("x")
+}
+
+func TestServeHTTP(t *testing.T) {
+	r := &Registry{}
+	r.Counter("c", "C.").Inc()
+	cases := []struct {
+		method string
+		code   int
+	}{
+		{http.MethodGet, http.StatusOK},
+		{http.MethodPost, http.StatusMethodNotAllowed},
+	}
+	for _, c := range cases {
+		t.Run(c.method, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(c.method, "/metrics", nil))
+			if w.Code != c.code {
+				t.Errorf("have status %d; want %d", w.Code, c.code)
+			}
+			if c.code == http.StatusOK && !strings.Contains(w.Body.String(), "\nc 1\n") {
+				t.Errorf("have %q", w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+/*
+Names package splits BibTeX name lists and parses individual personal names
+into their First, von, Last and Jr parts.
+*/
+package names
@@ -0,0 +1,87 @@
+package names
+
+import (
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+const (
+	// DisplayOrder writes names as `First von Last, Jr`.
+	DisplayOrder Order = iota
+	// SortOrder writes names as `von Last, Jr, First`.
+	SortOrder
+	// FirstSortOrder writes the first name of a list in the sort order and
+	// the others in the display order.
+	FirstSortOrder
+)
+
+// Order is the order the parts of a name are written in.
+type Order uint8
+
+// ListFormat tells how to write a name list. Names are decoded from TeX into
+// plain text.
+//
+// Lists longer than MaxNames are truncated to their first MinNames names,
+// at least one and at most MaxNames, followed by EtAl, and so are lists ending with `others`.
+// Names are joined with Sep, or with Pair when there are just two of them,
+// and the last one of three or more names is joined with Last. The names of
+// a truncated list are all joined with Sep. A zero MaxNames keeps all names.
+type ListFormat struct {
+	Order              Order
+	Initials           bool
+	MaxNames, MinNames int
+	EtAl               string
+	Sep, Pair, Last    string
+}
+
+// Format writes the name list.
+func (f ListFormat) Format(list []Name) string {
+	truncated := false
+	if n := len(list); n > 0 && list[n-1].IsOthers() {
+		list, truncated = list[:n-1], true
+	}
+	if f.MaxNames > 0 && len(list) > f.MaxNames {
+		list, truncated = list[:min(max(f.MinNames, 1), len(list), f.MaxNames)], true
+	}
+	result := make([]string, 0, len(list))
+	for i, n := range list {
+		result = append(result, f.Name(n, i))
+	}
+	var s string
+	switch {
+	case truncated || len(result) < 2:
+		s = strings.Join(result, f.Sep)
+	case len(result) == 2:
+		s = result[0] + f.Pair + result[1]
+	default:
+		s = strings.Join(result[:len(result)-1], f.Sep) + f.Last + result[len(result)-1]
+	}
+	if truncated {
+		s += f.EtAl
+	}
+	return s
+}
+
+// Name writes the name found at index i of a list.
+func (f ListFormat) Name(n Name, i int) string {
+	first := tex.Decode(n.First)
+	if f.Initials {
+		first = n.Initials()
+	}
+	last := tex.Decode(join(n.Von, n.Last))
+	if f.Order == SortOrder || f.Order == FirstSortOrder && i == 0 {
+		if n.Jr != `` {
+			last += ", " + tex.Decode(n.Jr)
+		}
+		if first != `` {
+			last += ", " + first
+		}
+		return last
+	}
+	s := join(first, last)
+	if n.Jr != `` {
+		s += ", " + tex.Decode(n.Jr)
+	}
+	return s
+}
@@ -0,0 +1,188 @@
+package names
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+// Name is a single personal name split into the four BibTeX name parts.
+type Name struct {
+	First, Von, Last, Jr string
+}
+
+// Split breaks a BibTeX name list on the `and` separators found outside of
+// braces.
+func Split(s string) []string {
+	words := fields(s, isSpace)
+	result := []string{}
+	curr := []string{}
+	for _, w := range words {
+		if strings.EqualFold(w, "and") {
+			if len(curr) > 0 {
+				result = append(result, strings.Join(curr, " "))
+			}
+			curr = []string{}
+			continue
+		}
+		curr = append(curr, w)
+	}
+	if len(curr) > 0 {
+		result = append(result, strings.Join(curr, " "))
+	}
+	return result
+}
+
+// Parse splits a single name into its parts following the BibTeX rules for the
+// `First von Last`, `von Last, First` and `von Last, Jr, First` forms.
+func Parse(s string) Name {
+	parts := fields(s, func(r rune) bool { return r == ',' })
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	var n Name
+	switch len(parts) {
+	case 0:
+		return n
+	case 1:
+		words := fields(parts[0], isSpace)
+		if len(words) == 0 {
+			return n
+		}
+		last := len(words) - 1
+		von := -1
+		for i := 0; i < last; i++ {
+			if isLower(words[i]) {
+				von = i
+				break
+			}
+		}
+		if von < 0 {
+			n.First = strings.Join(words[:last], " ")
+			n.Last = words[last]
+			return n
+		}
+		end := von
+		for i := von; i < last; i++ {
+			if isLower(words[i]) {
+				end = i
+			}
+		}
+		n.First = strings.Join(words[:von], " ")
+		n.Von = strings.Join(words[von:end+1], " ")
+		n.Last = strings.Join(words[end+1:], " ")
+	default:
+		n.Von, n.Last = splitVonLast(parts[0])
+		if len(parts) == 2 {
+			n.First = parts[1]
+		} else {
+			n.Jr = parts[1]
+			n.First = strings.Join(parts[2:], ", ")
+		}
+	}
+	return n
+}
+
+// ParseList splits and parses all the names in a BibTeX name list.
+func ParseList(s string) []Name {
+	result := []Name{}
+	for _, n := range Split(s) {
+		result = append(result, Parse(n))
+	}
+	return result
+}
+
+// String returns the name in the `First von Last, Jr` display order.
+func (n Name) String() string {
+	s := join(n.First, n.Von, n.Last)
+	if n.Jr != `` {
+		s += ", " + n.Jr
+	}
+	return s
+}
+
+// Initials abbreviates the first names to their initials, e.g. `P. J.`.
+func (n Name) Initials() string {
+	result := []string{}
+	for _, w := range strings.Fields(tex.Decode(n.First)) {
+		parts := strings.Split(w, "-")
+		for i, p := range parts {
+			if r := []rune(p); len(r) > 0 {
+				parts[i] = string(r[0]) + "."
+			}
+		}
+		result = append(result, strings.Join(parts, "-"))
+	}
+	return strings.Join(result, " ")
+}
+
+// IsOthers reports if the name is the `others` placeholder closing a
+// truncated name list.
+func (n Name) IsOthers() bool {
+	return n.First == `` && n.Von == `` && n.Jr == `` && n.Last == "others"
+}
+
+func splitVonLast(s string) (von, last string) {
+	words := fields(s, isSpace)
+	end := -1
+	for i := 0; i < len(words)-1; i++ {
+		if isLower(words[i]) {
+			end = i
+		}
+	}
+	return strings.Join(words[:end+1], " "), strings.Join(words[end+1:], " ")
+}
+
+// Fields splits the string at runes satisfying f that are not nested within
+// braces. Empty fields are omitted.
+func fields(s string, f func(rune) bool) []string {
+	result := []string{}
+	depth, start := 0, 0
+	for i, r := range s {
+		switch {
+		case r == '{':
+			depth++
+		case r == '}' && depth > 0:
+			depth--
+		case depth == 0 && f(r):
+			if w := s[start:i]; strings.TrimSpace(w) != `` {
+				result = append(result, w)
+			}
+			start = i + len(string(r))
+		}
+	}
+	if w := s[start:]; strings.TrimSpace(w) != `` {
+		result = append(result, w)
+	}
+	return result
+}
+
+// IsLower checks if the word starts with a lowercase letter. Words beginning
+// with a brace group count as uppercase unless the group starts with a TeX
+// command, in which case the decoded text is checked.
+func isLower(w string) bool {
+	if strings.HasPrefix(w, "{") && !strings.HasPrefix(w, `{\`) {
+		return false
+	}
+	for _, r := range tex.Decode(w) {
+		if unicode.IsLetter(r) {
+			return unicode.IsLower(r)
+		}
+	}
+	return false
+}
+
+func isSpace(r rune) bool {
+	return unicode.IsSpace(r) || r == '~'
+}
+
+func join(parts ...string) string {
+	result := []string{}
+	for _, p := range parts {
+		if p != `` {
+			result = append(result, p)
+		}
+	}
+	return strings.Join(result, " ")
+}
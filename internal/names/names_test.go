@@ -0,0 +1,146 @@
+package names
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	cases := []struct {
+		name      string
+		testInput string
+		want      []string
+	}{
+		{"single", "Peter Babington", []string{"Peter Babington"}},
+		{"two", "P. J. Cohen and M. R. Thompson", []string{"P. J. Cohen", "M. R. Thompson"}},
+		{"inverted", "Cohen, P. J. AND Thompson, M. R.", []string{"Cohen, P. J.", "Thompson, M. R."}},
+		{"braced", "{Barnes and Noble} and Doe, John", []string{"{Barnes and Noble}", "Doe, John"}},
+		{"newlines", "Peter Isley and\n  John Doe", []string{"Peter Isley", "John Doe"}},
+		{"empty", "", []string{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := Split(c.testInput); !reflect.DeepEqual(have, c.want) {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name      string
+		testInput string
+		want      Name
+	}{
+		{"first-last", "Paul Joseph Cohen", Name{First: "Paul Joseph", Last: "Cohen"}},
+		{"last-only", "Aristotle", Name{Last: "Aristotle"}},
+		{"von", "Ludwig van Beethoven", Name{First: "Ludwig", Von: "van", Last: "Beethoven"}},
+		{"von-multiple", "Charles Louis Xavier Joseph de la Vallee Poussin", Name{First: "Charles Louis Xavier Joseph", Von: "de la", Last: "Vallee Poussin"}},
+		{"inverted", "Cohen, Paul J.", Name{First: "Paul J.", Last: "Cohen"}},
+		{"inverted-von", "van Beethoven, Ludwig", Name{First: "Ludwig", Von: "van", Last: "Beethoven"}},
+		{"jr", "Ford, Jr., Henry", Name{First: "Henry", Last: "Ford", Jr: "Jr."}},
+		{"braced", "{Barnes and Noble}", Name{Last: "{Barnes and Noble}"}},
+		{"accent", `Kurt G{\"o}del`, Name{First: "Kurt", Last: `G{\"o}del`}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := Parse(c.testInput); have != c.want {
+				t.Errorf("have %#v; want %#v", have, c.want)
+			}
+		})
+	}
+}
+
+func TestInitials(t *testing.T) {
+	cases := []struct {
+		name      string
+		testInput Name
+		want      string
+	}{
+		{"two", Name{First: "Paul Joseph", Last: "Cohen"}, "P. J."},
+		{"initials", Name{First: "P. J.", Last: "Cohen"}, "P. J."},
+		{"hyphenated", Name{First: "Jean-Paul", Last: "Sartre"}, "J.-P."},
+		{"accent", Name{First: `{\'E}mile`, Last: "Zola"}, "É."},
+		{"none", Name{Last: "Aristotle"}, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := c.testInput.Initials(); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestListFormat(t *testing.T) {
+	list := ParseList(`Knuth, Donald E. and van der Berg, Jan and Ford, Jr., Henry and G{\"o}del, Kurt`)
+	cases := []struct {
+		name   string
+		format ListFormat
+		list   []Name
+		want   string
+	}{
+		{
+			name:   "display",
+			format: ListFormat{Sep: ", ", Pair: " and ", Last: ", and "},
+			list:   list,
+			want:   "Donald E. Knuth, Jan van der Berg, Henry Ford, Jr., and Kurt Gödel",
+		},
+		{
+			name:   "sort-initials",
+			format: ListFormat{Order: SortOrder, Initials: true, Sep: "; ", Pair: " & ", Last: " & "},
+			list:   list,
+			want:   "Knuth, D. E.; van der Berg, J.; Ford, Jr., H. & Gödel, K.",
+		},
+		{
+			name:   "first-sort",
+			format: ListFormat{Order: FirstSortOrder, Sep: ", ", Pair: " and ", Last: ", and "},
+			list:   list[:2],
+			want:   "Knuth, Donald E. and Jan van der Berg",
+		},
+		{
+			name:   "truncated",
+			format: ListFormat{Order: SortOrder, MaxNames: 3, MinNames: 2, EtAl: ", et al.", Sep: ", ", Pair: " and ", Last: " and "},
+			list:   list,
+			want:   "Knuth, Donald E., van der Berg, Jan, et al.",
+		},
+		{
+			name:   "within-max",
+			format: ListFormat{MaxNames: 4, EtAl: " et al.", Sep: ", ", Pair: " and ", Last: " and "},
+			list:   list,
+			want:   "Donald E. Knuth, Jan van der Berg, Henry Ford, Jr. and Kurt Gödel",
+		},
+		{
+			name:   "min-one",
+			format: ListFormat{MaxNames: 1, EtAl: " et al.", Sep: ", "},
+			list:   list,
+			want:   "Donald E. Knuth et al.",
+		},
+		{
+			name:   "min-over-max",
+			format: ListFormat{MaxNames: 2, MinNames: 5, EtAl: " et al.", Sep: ", "},
+			list:   list[:3],
+			want:   "Donald E. Knuth, Jan van der Berg et al.",
+		},
+		{
+			name:   "others",
+			format: ListFormat{EtAl: " and others", Sep: ", ", Pair: " and ", Last: " and "},
+			list:   ParseList("Knuth, Donald and Ford, Henry and others"),
+			want:   "Donald Knuth, Henry Ford and others",
+		},
+		{
+			name:   "empty",
+			format: ListFormat{Sep: ", "},
+			list:   []Name{},
+			want:   ``,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := c.format.Format(c.list); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
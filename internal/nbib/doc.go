@@ -0,0 +1,5 @@
+/*
+Nbib package imports the PubMed MEDLINE tagged format, exported by PubMed as
+.nbib files, as BibTeX article entries.
+*/
+package nbib
@@ -0,0 +1,199 @@
+package nbib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/mdm-code/bibx/internal/citekey"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+var months = map[string]bool{
+	"jan": true, "feb": true, "mar": true, "apr": true,
+	"may": true, "jun": true, "jul": true, "aug": true,
+	"sep": true, "oct": true, "nov": true, "dec": true,
+}
+
+// Tag is a single MEDLINE tag and its value joined across continuation lines.
+type tag struct {
+	name, value string
+}
+
+type record []tag
+
+// Read converts the PubMed MEDLINE records, as found in .nbib files, into
+// article entries with generated cite keys. The PubMed identifier is kept in
+// the pmid field.
+func Read(r io.Reader) ([]*parse.EntryDecl, error) {
+	records, err := readRecords(r)
+	if err != nil {
+		return nil, err
+	}
+	taken := map[string]bool{}
+	result := []*parse.EntryDecl{}
+	for _, rec := range records {
+		e := rec.entry()
+		e.CiteKey = citekey.Unique(citekey.Generate(e), taken)
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+func readRecords(r io.Reader) ([]record, error) {
+	result := []record{}
+	curr := record{}
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimRightFunc(s.Text(), unicode.IsSpace)
+		switch {
+		case line == ``:
+			if len(curr) > 0 {
+				result = append(result, curr)
+			}
+			curr = record{}
+		case strings.HasPrefix(line, "      "):
+			if len(curr) == 0 {
+				return nil, fmt.Errorf("nbib: line %d: continuation without a tag", n)
+			}
+			curr[len(curr)-1].value += " " + strings.TrimSpace(line)
+		case len(line) >= 5 && line[4] == '-':
+			curr = append(curr, tag{
+				name:  strings.TrimSpace(line[:4]),
+				value: strings.TrimSpace(line[5:]),
+			})
+		default:
+			return nil, fmt.Errorf("nbib: line %d: malformed tag", n)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(curr) > 0 {
+		result = append(result, curr)
+	}
+	return result, nil
+}
+
+func (rec record) all(name string) []string {
+	result := []string{}
+	for _, t := range rec {
+		if t.name == name {
+			result = append(result, t.value)
+		}
+	}
+	return result
+}
+
+func (rec record) first(name string) string {
+	if vs := rec.all(name); len(vs) > 0 {
+		return vs[0]
+	}
+	return ``
+}
+
+func (rec record) entry() *parse.EntryDecl {
+	e := &parse.EntryDecl{Name: "article", Comments: &parse.CommentGroupExpr{}}
+	set := func(key, value string) {
+		if value != `` {
+			e.Set(key, parse.Quote(tex.Escape(value)))
+		}
+	}
+	authors := rec.all("FAU")
+	if len(authors) == 0 {
+		for _, a := range rec.all("AU") {
+			authors = append(authors, shortName(a))
+		}
+	}
+	set("author", strings.Join(authors, " and "))
+	set("title", strings.TrimSuffix(rec.first("TI"), "."))
+	journal := rec.first("JT")
+	if journal == `` {
+		journal = rec.first("TA")
+	}
+	set("journal", journal)
+	year, month := date(rec.first("DP"))
+	if year != `` {
+		e.Set("year", year)
+	}
+	if month != `` {
+		e.Set("month", month)
+	}
+	set("volume", rec.first("VI"))
+	set("number", rec.first("IP"))
+	set("pages", pages(rec.first("PG")))
+	for _, t := range rec {
+		if (t.name == "LID" || t.name == "AID") && strings.HasSuffix(t.value, "[doi]") {
+			set("doi", strings.TrimSpace(strings.TrimSuffix(t.value, "[doi]")))
+			break
+		}
+	}
+	if issn := strings.Fields(rec.first("IS")); len(issn) > 0 {
+		set("issn", issn[0])
+	}
+	set("pmid", rec.first("PMID"))
+	set("pmcid", rec.first("PMC"))
+	set("abstract", rec.first("AB"))
+	return e
+}
+
+// ShortName converts MEDLINE author names such as `Cohen PJ` into `Cohen, P.
+// J.`.
+func shortName(s string) string {
+	i := strings.LastIndexByte(s, ' ')
+	if i < 0 {
+		return s
+	}
+	last, initials := s[:i], s[i+1:]
+	for _, r := range initials {
+		if !unicode.IsUpper(r) {
+			return s
+		}
+	}
+	parts := []string{}
+	for _, r := range initials {
+		parts = append(parts, string(r)+".")
+	}
+	return last + ", " + strings.Join(parts, " ")
+}
+
+// Date extracts the year and the month macro from the MEDLINE publication
+// date such as `1963 Dec 15` or `2020 Jan-Feb`.
+func date(s string) (year, month string) {
+	parts := strings.Fields(s)
+	if len(parts) == 0 || len(parts[0]) != 4 {
+		return ``, ``
+	}
+	year = parts[0]
+	if len(parts) > 1 && len(parts[1]) >= 3 {
+		if m := strings.ToLower(parts[1][:3]); months[m] {
+			month = m
+		}
+	}
+	return year, month
+}
+
+// Pages expands the abbreviated MEDLINE page ranges like `1143-8` into
+// `1143--1148`.
+func pages(s string) string {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok || from == `` || to == `` {
+		return s
+	}
+	if len(to) < len(from) && isDigits(from) && isDigits(to) {
+		to = from[:len(from)-len(to)] + to
+	}
+	return from + "--" + to
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+package nbib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+var testNbib = `PMID- 16591132
+OWN - NLM
+STAT- PubMed-not-MEDLINE
+DP  - 1963 Dec
+TI  - THE INDEPENDENCE OF THE CONTINUUM HYPOTHESIS.
+PG  - 1143-8
+LID - 10.1073/pnas.50.6.1143 [doi]
+AB  - This is the abstract of the paper split across
+      two lines & more.
+FAU - Cohen, P J
+AU  - Cohen PJ
+LA  - eng
+PT  - Journal Article
+TA  - Proc Natl Acad Sci U S A
+JT  - Proceedings of the National Academy of Sciences of the United States of
+      America
+IS  - 0027-8424 (Print)
+VI  - 50
+IP  - 6
+
+PMID- 123
+DP  - 1963
+TI  - Second paper
+AU  - Cohen PJ
+AU  - Thompson MR
+TA  - Proc Natl Acad Sci U S A
+PG  - e1234
+`
+
+func TestRead(t *testing.T) {
+	have, err := Read(strings.NewReader(testNbib))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*parse.EntryDecl{
+		{
+			Name:     "article",
+			CiteKey:  "Cohen1963",
+			Comments: &parse.CommentGroupExpr{},
+			Fields: []*parse.FieldStmt{
+				{Key: "author", Value: "{Cohen, P J}"},
+				{Key: "title", Value: "{THE INDEPENDENCE OF THE CONTINUUM HYPOTHESIS}"},
+				{Key: "journal", Value: "{Proceedings of the National Academy of Sciences of the United States of America}"},
+				{Key: "year", Value: "1963"},
+				{Key: "month", Value: "dec"},
+				{Key: "volume", Value: "{50}"},
+				{Key: "number", Value: "{6}"},
+				{Key: "pages", Value: "{1143--1148}"},
+				{Key: "doi", Value: "{10.1073/pnas.50.6.1143}"},
+				{Key: "issn", Value: "{0027-8424}"},
+				{Key: "pmid", Value: "{16591132}"},
+				{Key: "abstract", Value: `{This is the abstract of the paper split across two lines \& more.}`},
+			},
+		},
+		{
+			Name:     "article",
+			CiteKey:  "Cohen1963a",
+			Comments: &parse.CommentGroupExpr{},
+			Fields: []*parse.FieldStmt{
+				{Key: "author", Value: "{Cohen, P. J. and Thompson, M. R.}"},
+				{Key: "title", Value: "{Second paper}"},
+				{Key: "journal", Value: "{Proc Natl Acad Sci U S A}"},
+				{Key: "year", Value: "1963"},
+				{Key: "pages", Value: "{e1234}"},
+				{Key: "pmid", Value: "{123}"},
+			},
+		},
+	}
+	if len(have) != len(want) {
+		t.Fatalf("have %d entries; want %d", len(have), len(want))
+	}
+	for i := range want {
+		if !have[i].Eq(want[i]) {
+			t.Errorf("have %v; want %v", have[i].Fields, want[i].Fields)
+		}
+	}
+}
+
+func TestReadMalformed(t *testing.T) {
+	cases := []struct {
+		name      string
+		testInput string
+	}{
+		{"no-tag", "PMID- 1\nnot a tag line\n"},
+		{"orphan-continuation", "      continued\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := Read(strings.NewReader(c.testInput)); err == nil {
+				t.Error("have nil; want an error")
+			}
+		})
+	}
+}
+
+func TestPages(t *testing.T) {
+	cases := []struct {
+		name      string
+		testInput string
+		want      string
+	}{
+		{"abbreviated", "1143-8", "1143--1148"},
+		{"full", "1143-1148", "1143--1148"},
+		{"single", "e1234", "e1234"},
+		{"letters", "S1-S8", "S1--S8"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := pages(c.testInput); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
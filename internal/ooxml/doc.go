@@ -0,0 +1,5 @@
+/*
+Ooxml package exports BibTeX entries to the Office Open XML bibliography
+schema, the sources.xml format used by Microsoft Word.
+*/
+package ooxml
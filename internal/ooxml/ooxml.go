@@ -0,0 +1,189 @@
+package ooxml
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/names"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+// Namespace is the namespace of the OOXML bibliography schema.
+const Namespace = "http://schemas.openxmlformats.org/officeDocument/2006/bibliography"
+
+// Word source types corresponding to the BibTeX entry types.
+var sourceTypes = map[string]string{
+	"article":       "JournalArticle",
+	"book":          "Book",
+	"booklet":       "Book",
+	"inbook":        "BookSection",
+	"incollection":  "BookSection",
+	"inproceedings": "ConferenceProceedings",
+	"conference":    "ConferenceProceedings",
+	"proceedings":   "ConferenceProceedings",
+	"techreport":    "Report",
+	"phdthesis":     "Report",
+	"mastersthesis": "Report",
+	"manual":        "Report",
+	"patent":        "Patent",
+	"online":        "InternetSite",
+}
+
+var monthNames = map[string]string{
+	"jan": "January", "feb": "February", "mar": "March", "apr": "April",
+	"may": "May", "jun": "June", "jul": "July", "aug": "August",
+	"sep": "September", "oct": "October", "nov": "November", "dec": "December",
+}
+
+type sources struct {
+	XMLName  xml.Name `xml:"b:Sources"`
+	NS       string   `xml:"xmlns:b,attr"`
+	Default  string   `xml:"xmlns,attr"`
+	Selected string   `xml:"SelectedStyle,attr"`
+	Sources  []source `xml:"b:Source"`
+}
+
+type source struct {
+	Tag            string       `xml:"b:Tag"`
+	SourceType     string       `xml:"b:SourceType"`
+	Author         *contributor `xml:"b:Author,omitempty"`
+	Title          string       `xml:"b:Title,omitempty"`
+	JournalName    string       `xml:"b:JournalName,omitempty"`
+	BookTitle      string       `xml:"b:BookTitle,omitempty"`
+	ConferenceName string       `xml:"b:ConferenceName,omitempty"`
+	Year           string       `xml:"b:Year,omitempty"`
+	Month          string       `xml:"b:Month,omitempty"`
+	Volume         string       `xml:"b:Volume,omitempty"`
+	Issue          string       `xml:"b:Issue,omitempty"`
+	Pages          string       `xml:"b:Pages,omitempty"`
+	Edition        string       `xml:"b:Edition,omitempty"`
+	Publisher      string       `xml:"b:Publisher,omitempty"`
+	City           string       `xml:"b:City,omitempty"`
+	Institution    string       `xml:"b:Institution,omitempty"`
+	ThesisType     string       `xml:"b:ThesisType,omitempty"`
+	StandardNumber string       `xml:"b:StandardNumber,omitempty"`
+	URL            string       `xml:"b:URL,omitempty"`
+	DOI            string       `xml:"b:DOI,omitempty"`
+	Comments       string       `xml:"b:Comments,omitempty"`
+}
+
+type contributor struct {
+	Author *nameList `xml:"b:Author,omitempty"`
+	Editor *nameList `xml:"b:Editor,omitempty"`
+}
+
+type nameList struct {
+	People []person `xml:"b:NameList>b:Person"`
+}
+
+type person struct {
+	Last   string `xml:"b:Last"`
+	First  string `xml:"b:First,omitempty"`
+	Middle string `xml:"b:Middle,omitempty"`
+}
+
+// Write exports the entries as the OOXML sources.xml bibliography part that
+// Microsoft Word's citation manager imports.
+func Write(w io.Writer, entries []*parse.EntryDecl) error {
+	doc := sources{NS: Namespace, Default: Namespace}
+	for _, e := range entries {
+		doc.Sources = append(doc.Sources, newSource(e))
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func newSource(e *parse.EntryDecl) source {
+	get := func(key string) string {
+		if f, ok := e.Get(key); ok {
+			return tex.Decode(parse.Unquote(f.Value))
+		}
+		return ``
+	}
+	s := source{
+		Tag:        e.CiteKey,
+		SourceType: sourceTypes[e.Name],
+		Title:      get("title"),
+		Year:       get("year"),
+		Volume:     get("volume"),
+		Issue:      get("number"),
+		Pages:      get("pages"),
+		Edition:    get("edition"),
+		Publisher:  get("publisher"),
+		City:       get("address"),
+		URL:        get("url"),
+		DOI:        get("doi"),
+		Comments:   get("note"),
+	}
+	if s.SourceType == `` {
+		s.SourceType = "Misc"
+		if s.URL != `` {
+			s.SourceType = "InternetSite"
+		}
+	}
+	if m, ok := monthNames[strings.ToLower(get("month"))]; ok {
+		s.Month = m
+	} else {
+		s.Month = get("month")
+	}
+	switch s.SourceType {
+	case "JournalArticle":
+		s.JournalName = get("journal")
+	case "ConferenceProceedings":
+		s.ConferenceName = get("booktitle")
+	case "BookSection":
+		s.BookTitle = get("booktitle")
+	case "Report":
+		s.Institution = get("institution")
+		if s.Institution == `` {
+			s.Institution = get("school")
+		}
+		switch e.Name {
+		case "phdthesis":
+			s.ThesisType = "PhD thesis"
+		case "mastersthesis":
+			s.ThesisType = "Master's thesis"
+		}
+	}
+	if isbn := get("isbn"); isbn != `` {
+		s.StandardNumber = "ISBN: " + isbn
+	} else if issn := get("issn"); issn != `` {
+		s.StandardNumber = "ISSN: " + issn
+	}
+	authors, editors := people(e, "author"), people(e, "editor")
+	if authors != nil || editors != nil {
+		s.Author = &contributor{Author: authors, Editor: editors}
+	}
+	return s
+}
+
+func people(e *parse.EntryDecl, key string) *nameList {
+	f, ok := e.Get(key)
+	if !ok {
+		return nil
+	}
+	list := &nameList{}
+	for _, n := range names.ParseList(parse.Unquote(f.Value)) {
+		if n.IsOthers() {
+			continue
+		}
+		first := strings.Fields(tex.Decode(n.First))
+		p := person{Last: tex.Decode(strings.TrimSpace(n.Von + " " + n.Last))}
+		if len(first) > 0 {
+			p.First = first[0]
+			p.Middle = strings.Join(first[1:], " ")
+		}
+		list.People = append(list.People, p)
+	}
+	return list
+}
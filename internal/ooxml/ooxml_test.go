@@ -0,0 +1,82 @@
+package ooxml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestWrite(t *testing.T) {
+	entries := []*parse.EntryDecl{
+		{
+			Name:    "article",
+			CiteKey: "Cohen1963",
+			Fields: []*parse.FieldStmt{
+				{Key: "author", Value: `{Paul Joseph C{\"o}hen and others}`},
+				{Key: "title", Value: "{The independence of the continuum hypothesis}"},
+				{Key: "journal", Value: "{PNAS}"},
+				{Key: "year", Value: "1963"},
+				{Key: "month", Value: "dec"},
+				{Key: "pages", Value: "{1143--1148}"},
+			},
+		},
+		{
+			Name:    "phdthesis",
+			CiteKey: "Doe2001",
+			Fields: []*parse.FieldStmt{
+				{Key: "author", Value: "{Doe, John}"},
+				{Key: "title", Value: "{Tom \\& Jerry}"},
+				{Key: "school", Value: "{MIT}"},
+			},
+		},
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<b:Sources xmlns:b="http://schemas.openxmlformats.org/officeDocument/2006/bibliography" xmlns="http://schemas.openxmlformats.org/officeDocument/2006/bibliography" SelectedStyle="">
+  <b:Source>
+    <b:Tag>Cohen1963</b:Tag>
+    <b:SourceType>JournalArticle</b:SourceType>
+    <b:Author>
+      <b:Author>
+        <b:NameList>
+          <b:Person>
+            <b:Last>Cöhen</b:Last>
+            <b:First>Paul</b:First>
+            <b:Middle>Joseph</b:Middle>
+          </b:Person>
+        </b:NameList>
+      </b:Author>
+    </b:Author>
+    <b:Title>The independence of the continuum hypothesis</b:Title>
+    <b:JournalName>PNAS</b:JournalName>
+    <b:Year>1963</b:Year>
+    <b:Month>December</b:Month>
+    <b:Pages>1143–1148</b:Pages>
+  </b:Source>
+  <b:Source>
+    <b:Tag>Doe2001</b:Tag>
+    <b:SourceType>Report</b:SourceType>
+    <b:Author>
+      <b:Author>
+        <b:NameList>
+          <b:Person>
+            <b:Last>Doe</b:Last>
+            <b:First>John</b:First>
+          </b:Person>
+        </b:NameList>
+      </b:Author>
+    </b:Author>
+    <b:Title>Tom &amp; Jerry</b:Title>
+    <b:Institution>MIT</b:Institution>
+    <b:ThesisType>PhD thesis</b:ThesisType>
+  </b:Source>
+</b:Sources>
+`
+	var b strings.Builder
+	if err := Write(&b, entries); err != nil {
+		t.Fatal(err)
+	}
+	if have := b.String(); have != want {
+		t.Errorf("have %s; want %s", have, want)
+	}
+}
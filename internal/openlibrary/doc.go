@@ -0,0 +1,6 @@
+/*
+Openlibrary package retrieves the metadata of books from Open Library by
+their ISBNs and fills in the fields missing from BibTeX entries. Lookups are
+rate limited and may be cached on disk for offline use.
+*/
+package openlibrary
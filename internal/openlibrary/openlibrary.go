@@ -0,0 +1,284 @@
+package openlibrary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/remote"
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+// DefaultEndpoint is the books endpoint of the Open Library API.
+const DefaultEndpoint = "https://openlibrary.org/api/books"
+
+// DefaultInterval is the least time between two requests made by a client
+// unless it is configured otherwise.
+const DefaultInterval = time.Second
+
+// ErrNotFound is returned for ISBNs unknown to Open Library.
+var ErrNotFound = errors.New("openlibrary: ISBN not found")
+
+// ErrOffline is returned for ISBNs missing from the cache of an offline
+// client.
+var ErrOffline = errors.New("openlibrary: ISBN not cached")
+
+var yearPattern = regexp.MustCompile(`\b(1[5-9]|20)\d\d\b`)
+
+// Ordinal editions written as words the way BibTeX styles expect them.
+var editions = map[string]string{
+	"1st": "First", "2nd": "Second", "3rd": "Third", "4th": "Fourth",
+	"5th": "Fifth", "6th": "Sixth", "7th": "Seventh", "8th": "Eighth",
+	"9th": "Ninth", "10th": "Tenth",
+}
+
+// Book holds the metadata of an edition of a book used to enrich entries.
+type Book struct {
+	Title       string   `json:"title"`
+	Publishers  []string `json:"publishers"`
+	PublishDate string   `json:"publish_date"`
+	EditionName string   `json:"edition_name"`
+	Authors     []struct {
+		Name string `json:"name"`
+	} `json:"authors"`
+}
+
+// Client queries the Open Library API for books by their ISBNs. Requests are
+// at least Interval apart, or DefaultInterval if it is zero. Responses are
+// stored in the Cache directory unless it is empty, and an Offline client
+// looks books up in the cache only. Failed requests are retried as the Retry
+// policy tells.
+type Client struct {
+	HTTP     *http.Client
+	Endpoint string
+	Retry    remote.Policy
+	Interval time.Duration
+	Cache    string
+	Offline  bool
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// Fetch retrieves the metadata of the book identified by the ISBN, which is
+// normalized first.
+func (c *Client) Fetch(ctx context.Context, isbn string) (*Book, error) {
+	id, ok := Normalize(isbn)
+	if !ok {
+		return nil, fmt.Errorf("openlibrary: invalid ISBN %q", isbn)
+	}
+	data, err := c.cached(id)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		if c.Offline {
+			return nil, ErrOffline
+		}
+		if data, err = c.get(ctx, id); err != nil {
+			return nil, err
+		}
+		if err := c.store(id, data); err != nil {
+			return nil, err
+		}
+	}
+	return Read(id, strings.NewReader(string(data)))
+}
+
+func (c *Client) get(ctx context.Context, isbn string) ([]byte, error) {
+	endpoint := c.Endpoint
+	if endpoint == `` {
+		endpoint = DefaultEndpoint
+	}
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	q := url.Values{
+		"bibkeys": {"ISBN:" + isbn},
+		"jscmd":   {"details"},
+		"format":  {"json"},
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := remote.Do(ctx, c.HTTP, c.Retry, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("openlibrary: unexpected response status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Wait blocks until the interval since the previous request has passed or
+// the context is done.
+func (c *Client) wait(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	interval := c.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+	if d := time.Until(c.last.Add(interval)); d > 0 {
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	c.last = time.Now()
+	return nil
+}
+
+// Cached returns the cached response for the ISBN or nil if there is none.
+// Responses for unknown ISBNs are cached as well.
+func (c *Client) cached(isbn string) ([]byte, error) {
+	if c.Cache == `` {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Join(c.Cache, isbn+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (c *Client) store(isbn string, data []byte) error {
+	if c.Cache == `` {
+		return nil
+	}
+	if err := os.MkdirAll(c.Cache, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(c.Cache, isbn+".json"), data, 0o644)
+}
+
+// Read decodes the book identified by the normalized ISBN from a response of
+// the Open Library books endpoint.
+func Read(isbn string, r io.Reader) (*Book, error) {
+	var resp map[string]struct {
+		Details *Book `json:"details"`
+	}
+	if err := json.NewDecoder(r).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("openlibrary: %w", err)
+	}
+	b, ok := resp["ISBN:"+isbn]
+	if !ok || b.Details == nil {
+		return nil, ErrNotFound
+	}
+	return b.Details, nil
+}
+
+// Normalize strips the hyphens and spaces from the ISBN and reports whether
+// it is a valid ISBN-10 or ISBN-13 by its check digit.
+func Normalize(isbn string) (string, bool) {
+	id := strings.ToUpper(strings.NewReplacer("-", ``, " ", ``).Replace(strings.TrimSpace(isbn)))
+	sum := 0
+	switch len(id) {
+	case 10:
+		for i, r := range id {
+			d := int(r - '0')
+			if r == 'X' && i == 9 {
+				d = 10
+			} else if r < '0' || r > '9' {
+				return ``, false
+			}
+			sum += (10 - i) * d
+		}
+		return id, sum%11 == 0
+	case 13:
+		for i, r := range id {
+			if r < '0' || r > '9' {
+				return ``, false
+			}
+			if i%2 == 1 {
+				sum += 3 * int(r-'0')
+			} else {
+				sum += int(r - '0')
+			}
+		}
+		return id, sum%10 == 0
+	default:
+		return ``, false
+	}
+}
+
+// Enrich fills in the publisher, year, edition and author fields missing from
+// the entry with the metadata of the book. The author is not filled in for
+// entries with editors. The changed fields are listed in a comment added to
+// the entry and returned.
+func (b *Book) Enrich(e *parse.EntryDecl) []string {
+	result := []string{}
+	fill := func(key, value string) {
+		if _, ok := e.Lookup(key); ok || value == `` {
+			return
+		}
+		e.Set(key, parse.Quote(value))
+		result = append(result, key)
+	}
+	if len(b.Publishers) > 0 {
+		fill("publisher", tex.Escape(clean(b.Publishers[0])))
+	}
+	if year := yearPattern.FindString(b.PublishDate); year != `` {
+		if _, ok := e.Lookup("year"); !ok {
+			e.Set("year", year)
+			result = append(result, "year")
+		}
+	}
+	fill("edition", tex.Escape(edition(b.EditionName)))
+	if _, ok := e.Lookup("editor"); !ok {
+		names := []string{}
+		for _, a := range b.Authors {
+			if n := clean(a.Name); n != `` {
+				names = append(names, tex.Escape(n))
+			}
+		}
+		fill("author", strings.Join(names, " and "))
+	}
+	if len(result) > 0 {
+		if e.Comments == nil {
+			e.Comments = &parse.CommentGroupExpr{}
+		}
+		e.Comments.Values = append(e.Comments.Values, &parse.CommentExpr{
+			Value: "% enriched from Open Library: " + strings.Join(result, ", "),
+		})
+	}
+	return result
+}
+
+// Edition strips the edition name of its `ed.` or `edition` suffix and
+// writes ordinals as words.
+func edition(name string) string {
+	name = clean(name)
+	lower := strings.ToLower(name)
+	for _, suffix := range []string{" edition", " ed.", " ed"} {
+		if strings.HasSuffix(lower, suffix) {
+			name = name[:len(name)-len(suffix)]
+			break
+		}
+	}
+	if word, ok := editions[strings.ToLower(name)]; ok {
+		return word
+	}
+	return name
+}
+
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
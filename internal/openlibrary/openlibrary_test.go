@@ -0,0 +1,121 @@
+package openlibrary
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+const testBook = `{"ISBN:9780198526636": {
+  "bib_key": "ISBN:9780198526636",
+  "details": {
+    "title": "The Emperor's New Mind",
+    "publishers": ["Oxford University Press"],
+    "publish_date": "March 1999",
+    "edition_name": "2nd ed.",
+    "authors": [{"key": "/authors/OL1A", "name": "Roger  Penrose"}]
+  }
+}}`
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		isbn string
+		want string
+		ok   bool
+	}{
+		{"978-0-19-852663-6", "9780198526636", true},
+		{"0-19-852663-6", "0198526636", true},
+		{"0-8044-2957-x", "080442957X", true},
+		{"978-0-19-852663-7", "9780198526637", false},
+		{"12345", ``, false},
+	}
+	for _, c := range cases {
+		t.Run(c.isbn, func(t *testing.T) {
+			have, ok := Normalize(c.isbn)
+			if have != c.want || ok != c.ok {
+				t.Errorf("have %q, %t; want %q, %t", have, ok, c.want, c.ok)
+			}
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Get("bibkeys") != "ISBN:9780198526636" {
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(testBook))
+	}))
+	defer ts.Close()
+	cache := t.TempDir()
+	c := &Client{Endpoint: ts.URL, Interval: time.Millisecond, Cache: cache}
+	b, err := c.Fetch(context.Background(), "978-0-19-852663-6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Title != "The Emperor's New Mind" || b.EditionName != "2nd ed." {
+		t.Errorf("have %+v", b)
+	}
+	if _, err := c.Fetch(context.Background(), "0-8044-2957-X"); err != ErrNotFound {
+		t.Errorf("have %v; want %v", err, ErrNotFound)
+	}
+	offline := &Client{Cache: cache, Offline: true}
+	if _, err := offline.Fetch(context.Background(), "9780198526636"); err != nil {
+		t.Errorf("cached book not found: %v", err)
+	}
+	if _, err := offline.Fetch(context.Background(), "080442957X"); err != ErrNotFound {
+		t.Errorf("have %v; want %v", err, ErrNotFound)
+	}
+	if _, err := offline.Fetch(context.Background(), "0198526636"); err != ErrOffline {
+		t.Errorf("have %v; want %v", err, ErrOffline)
+	}
+	if requests != 2 {
+		t.Errorf("have %d requests; want 2", requests)
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	b, err := Read("9780198526636", strings.NewReader(testBook))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &parse.EntryDecl{
+		Name:     "book",
+		CiteKey:  "Penrose1999",
+		Comments: &parse.CommentGroupExpr{},
+		Fields: []*parse.FieldStmt{
+			{Key: "title", Value: "{The Emperor's New Mind}"},
+			{Key: "isbn", Value: "{978-0-19-852663-6}"},
+		},
+	}
+	filled := b.Enrich(e)
+	if want := []string{"publisher", "year", "edition", "author"}; !reflect.DeepEqual(filled, want) {
+		t.Errorf("have %v; want %v", filled, want)
+	}
+	var buf bytes.Buffer
+	format.Node(&buf, e)
+	want := `% enriched from Open Library: publisher, year, edition, author
+@book{Penrose1999,
+  title     = {The Emperor's New Mind},
+  isbn      = {978-0-19-852663-6},
+  publisher = {Oxford University Press},
+  year      = 1999,
+  edition   = {Second},
+  author    = {Roger Penrose}
+}
+`
+	if have := buf.String(); have != want {
+		t.Errorf("have:\n%s\nwant:\n%s", have, want)
+	}
+}
@@ -0,0 +1,5 @@
+/*
+Orcid package fetches the public works list of a researcher from the ORCID
+public API and converts the works into BibTeX entries.
+*/
+package orcid
@@ -0,0 +1,271 @@
+package orcid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/csl"
+	"github.com/mdm-code/bibx/internal/names"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/remote"
+)
+
+// DefaultEndpoint is the base URL of the ORCID public API.
+const DefaultEndpoint = "https://pub.orcid.org/v3.0/"
+
+// Works are fetched in batches of batchSize, the largest the API allows.
+const batchSize = 100
+
+// ORCID iDs with an optional orcid: or https://orcid.org/ prefix.
+var idPattern = regexp.MustCompile(`^(?i:orcid:|https?://orcid\.org/)?(\d{4}-\d{4}-\d{4}-\d{3}[\dX])$`)
+
+// CSL item types of the ORCID work types. Works of other types become
+// documents.
+var itemTypes = map[string]string{
+	"journal-article":     "article-journal",
+	"book":                "book",
+	"edited-book":         "book",
+	"book-chapter":        "chapter",
+	"conference-paper":    "paper-conference",
+	"dissertation-thesis": "thesis",
+	"dissertation":        "thesis",
+	"report":              "report",
+	"working-paper":       "report",
+	"preprint":            "manuscript",
+}
+
+type value struct {
+	Value string `json:"value"`
+}
+
+type work struct {
+	Title struct {
+		Title    *value `json:"title"`
+		Subtitle *value `json:"subtitle"`
+	} `json:"title"`
+	JournalTitle     *value `json:"journal-title"`
+	ShortDescription string `json:"short-description"`
+	Type             string `json:"type"`
+	PublicationDate  *struct {
+		Year  *value `json:"year"`
+		Month *value `json:"month"`
+		Day   *value `json:"day"`
+	} `json:"publication-date"`
+	ExternalIDs struct {
+		ExternalID []struct {
+			Type         string `json:"external-id-type"`
+			Value        string `json:"external-id-value"`
+			Relationship string `json:"external-id-relationship"`
+		} `json:"external-id"`
+	} `json:"external-ids"`
+	URL          *value `json:"url"`
+	Contributors struct {
+		Contributor []struct {
+			CreditName *value `json:"credit-name"`
+			Attributes *struct {
+				Role string `json:"contributor-role"`
+			} `json:"contributor-attributes"`
+		} `json:"contributor"`
+	} `json:"contributors"`
+}
+
+// Bulk is a response listing works by their put codes. Works that failed to
+// load come as errors in place of works.
+type bulk struct {
+	Bulk []struct {
+		Work *work `json:"work"`
+	} `json:"bulk"`
+}
+
+func (b *bulk) works() []work {
+	result := []work{}
+	for _, w := range b.Bulk {
+		if w.Work != nil {
+			result = append(result, *w.Work)
+		}
+	}
+	return result
+}
+
+// Client queries the ORCID public API for the works of researchers. Failed
+// requests are retried as the Retry policy tells.
+type Client struct {
+	HTTP     *http.Client
+	Endpoint string
+	Retry    remote.Policy
+}
+
+// IsID checks if the string is an ORCID iD, with or without the `orcid:` or
+// `https://orcid.org/` prefix, with a valid check digit.
+func IsID(s string) bool {
+	m := idPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return false
+	}
+	digits := strings.ReplaceAll(m[1], "-", ``)
+	total := 0
+	for _, r := range digits[:15] {
+		total = (total + int(r-'0')) * 2
+	}
+	check := (12 - total%11) % 11
+	want := strconv.Itoa(check)
+	if check == 10 {
+		want = "X"
+	}
+	return digits[15:] == want
+}
+
+// Fetch retrieves the public works of the researcher identified by the ORCID
+// iD. Of the versions of a work added by different sources only the one
+// preferred by the researcher is kept.
+func (c *Client) Fetch(ctx context.Context, id string) ([]*parse.EntryDecl, error) {
+	m := idPattern.FindStringSubmatch(strings.TrimSpace(id))
+	if m == nil {
+		return nil, fmt.Errorf("orcid: invalid ORCID iD %q", id)
+	}
+	id = m[1]
+	var summaries struct {
+		Group []struct {
+			Summary []struct {
+				PutCode int `json:"put-code"`
+			} `json:"work-summary"`
+		} `json:"group"`
+	}
+	if err := c.get(ctx, id+"/works", &summaries); err != nil {
+		return nil, err
+	}
+	codes := []string{}
+	for _, g := range summaries.Group {
+		if len(g.Summary) > 0 {
+			codes = append(codes, strconv.Itoa(g.Summary[0].PutCode))
+		}
+	}
+	works := []work{}
+	for len(codes) > 0 {
+		n := len(codes)
+		if n > batchSize {
+			n = batchSize
+		}
+		var b bulk
+		if err := c.get(ctx, id+"/works/"+strings.Join(codes[:n], ","), &b); err != nil {
+			return nil, err
+		}
+		works = append(works, b.works()...)
+		codes = codes[n:]
+	}
+	return entries(works), nil
+}
+
+func (c *Client) get(ctx context.Context, path string, v any) error {
+	endpoint := c.Endpoint
+	if endpoint == `` {
+		endpoint = DefaultEndpoint
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(endpoint, "/")+"/"+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "bibx (https://github.com/mdm-code/bibx)")
+	resp, err := remote.Do(ctx, c.HTTP, c.Retry, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return fmt.Errorf("orcid: %w", err)
+		}
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("orcid: %s not found", path)
+	default:
+		return fmt.Errorf("orcid: unexpected response status %s", resp.Status)
+	}
+}
+
+// Read converts the works of a bulk works response of the ORCID API into
+// entries with generated cite keys.
+func Read(r io.Reader) ([]*parse.EntryDecl, error) {
+	var b bulk
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, fmt.Errorf("orcid: %w", err)
+	}
+	return entries(b.works()), nil
+}
+
+func entries(works []work) []*parse.EntryDecl {
+	items := make([]csl.Item, 0, len(works))
+	for _, w := range works {
+		items = append(items, w.item())
+	}
+	return csl.Entries(items)
+}
+
+// Item converts the work into a CSL item. Contributors without a role are
+// taken to be authors.
+func (w work) item() csl.Item {
+	it := csl.Item{Type: itemTypes[w.Type], Abstract: w.ShortDescription}
+	if it.Type == `` {
+		it.Type = "document"
+	}
+	if w.Title.Title != nil {
+		it.Title = w.Title.Title.Value
+	}
+	if w.Title.Subtitle != nil && w.Title.Subtitle.Value != `` {
+		it.Title += ": " + w.Title.Subtitle.Value
+	}
+	if w.JournalTitle != nil {
+		it.ContainerTitle = w.JournalTitle.Value
+	}
+	if w.URL != nil {
+		it.URL = w.URL.Value
+	}
+	for _, x := range w.ExternalIDs.ExternalID {
+		self := x.Relationship != "part-of"
+		switch {
+		case x.Type == "doi" && self && it.DOI == ``:
+			it.DOI = x.Value
+		case x.Type == "isbn" && it.ISBN == ``:
+			it.ISBN = x.Value
+		case x.Type == "issn" && it.ISSN == ``:
+			it.ISSN = x.Value
+		}
+	}
+	if d := w.PublicationDate; d != nil && d.Year != nil {
+		parts := []int{}
+		for _, v := range []*value{d.Year, d.Month, d.Day} {
+			if v == nil {
+				break
+			}
+			n, err := strconv.Atoi(v.Value)
+			if err != nil {
+				break
+			}
+			parts = append(parts, n)
+		}
+		if len(parts) > 0 {
+			it.Issued = &csl.Date{Parts: [][]int{parts}}
+		}
+	}
+	for _, c := range w.Contributors.Contributor {
+		if c.CreditName == nil || strings.TrimSpace(c.CreditName.Value) == `` {
+			continue
+		}
+		n := names.Parse(c.CreditName.Value)
+		name := csl.Name{Family: n.Last, Given: n.First, Particle: n.Von, Suffix: n.Jr}
+		if c.Attributes != nil && strings.EqualFold(c.Attributes.Role, "editor") {
+			it.Editor = append(it.Editor, name)
+		} else {
+			it.Author = append(it.Author, name)
+		}
+	}
+	return it
+}
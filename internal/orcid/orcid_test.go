@@ -0,0 +1,128 @@
+package orcid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+const testWorks = `{"group": [
+  {"work-summary": [{"put-code": 11}, {"put-code": 12}]},
+  {"work-summary": [{"put-code": 21}]}
+]}`
+
+const testBulk = `{"bulk": [
+  {"work": {
+    "put-code": 11,
+    "title": {"title": {"value": "The independence of the continuum hypothesis"}, "subtitle": null},
+    "journal-title": {"value": "PNAS"},
+    "type": "journal-article",
+    "publication-date": {"year": {"value": "1963"}, "month": {"value": "12"}, "day": null},
+    "external-ids": {"external-id": [
+      {"external-id-type": "issn", "external-id-value": "0027-8424", "external-id-relationship": "part-of"},
+      {"external-id-type": "doi", "external-id-value": "10.1073/pnas.50.6.1143", "external-id-relationship": "self"}
+    ]},
+    "contributors": {"contributor": [
+      {"credit-name": {"value": "Paul J. Cohen"}, "contributor-attributes": {"contributor-role": "author"}},
+      {"credit-name": {"value": "Kurt Gödel"}, "contributor-attributes": {"contributor-role": "editor"}}
+    ]}
+  }},
+  {"error": {"response-code": 404}},
+  {"work": {
+    "put-code": 21,
+    "title": {"title": {"value": "Set theory"}, "subtitle": {"value": "An introduction"}},
+    "type": "book",
+    "publication-date": {"year": {"value": "1966"}},
+    "contributors": {"contributor": [{"credit-name": {"value": "Cohen, Paul J."}}]}
+  }}
+]}`
+
+func TestIsID(t *testing.T) {
+	cases := []struct {
+		id   string
+		want bool
+	}{
+		{"0000-0002-1825-0097", true},
+		{"orcid:0000-0002-1825-0097", true},
+		{"https://orcid.org/0000-0002-1694-233X", true},
+		{"0000-0002-1825-0098", false},
+		{"0000-0002-1825", false},
+		{"arXiv:1706.03762", false},
+	}
+	for _, c := range cases {
+		if have := IsID(c.id); have != c.want {
+			t.Errorf("%s: have %v; want %v", c.id, have, c.want)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	have, err := Read(strings.NewReader(testBulk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*parse.EntryDecl{
+		{
+			Name:     "article",
+			CiteKey:  "Cohen1963",
+			Comments: &parse.CommentGroupExpr{},
+			Fields: []*parse.FieldStmt{
+				{Key: "author", Value: "{Cohen, Paul J.}"},
+				{Key: "editor", Value: "{Gödel, Kurt}"},
+				{Key: "title", Value: "{The independence of the continuum hypothesis}"},
+				{Key: "journal", Value: "{PNAS}"},
+				{Key: "year", Value: "1963"},
+				{Key: "month", Value: "dec"},
+				{Key: "doi", Value: "{10.1073/pnas.50.6.1143}"},
+				{Key: "issn", Value: "{0027-8424}"},
+			},
+		},
+		{
+			Name:     "book",
+			CiteKey:  "Cohen1966",
+			Comments: &parse.CommentGroupExpr{},
+			Fields: []*parse.FieldStmt{
+				{Key: "author", Value: "{Cohen, Paul J.}"},
+				{Key: "title", Value: "{Set theory: An introduction}"},
+				{Key: "year", Value: "1966"},
+			},
+		},
+	}
+	if len(have) != len(want) {
+		t.Fatalf("have %d entries; want %d", len(have), len(want))
+	}
+	for i := range want {
+		if !have[i].Eq(want[i]) {
+			t.Errorf("have %+v; want %+v", have[i], want[i])
+		}
+	}
+}
+
+func TestFetch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/0000-0002-1825-0097/works":
+			w.Write([]byte(testWorks))
+		case "/0000-0002-1825-0097/works/11,21":
+			w.Write([]byte(testBulk))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	c := &Client{Endpoint: ts.URL}
+	es, err := c.Fetch(context.Background(), "https://orcid.org/0000-0002-1825-0097")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 2 {
+		t.Errorf("have %d entries; want 2", len(es))
+	}
+	if _, err := c.Fetch(context.Background(), "0000-0002-1694-233X"); err == nil {
+		t.Error("have nil error for an unknown ORCID iD")
+	}
+}
@@ -0,0 +1,5 @@
+/*
+Pages package parses the pages field of BibTeX entries into structured page
+ranges.
+*/
+package pages
@@ -0,0 +1,224 @@
+package pages
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrEmpty is returned for page fields without any pages.
+var ErrEmpty = errors.New("pages: empty")
+
+// Dashes separating the first and the last page of a range, longest first.
+var dashes = []string{"---", "--", "—", "–", "-"}
+
+// PageRange is a range of pages or a single page when To is empty. Pages are
+// arabic numbers with an optional letter prefix and suffix, such as 123,
+// e1234 or S12a, or roman numerals.
+type PageRange struct {
+	From string
+	To   string
+}
+
+// String formats the range with the `--` dash typeset as an en dash.
+func (r PageRange) String() string {
+	if r.To == `` {
+		return r.From
+	}
+	return r.From + "--" + r.To
+}
+
+// Count returns the number of pages in the range. It is only known for
+// ranges of plain arabic or roman numbers.
+func (r PageRange) Count() (int, bool) {
+	if r.To == `` {
+		return 1, true
+	}
+	from, fk := value(r.From)
+	to, tk := value(r.To)
+	if fk == other || fk != tk {
+		return 0, false
+	}
+	return to - from + 1, true
+}
+
+// Parse splits the pages field value, stripped of its delimiters, into
+// ranges separated with commas. The abbreviated last page of a range such as
+// 1234-56 is expanded to 1256.
+func Parse(s string) ([]PageRange, error) {
+	result := []PageRange{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == `` {
+			continue
+		}
+		r, err := parseRange(part)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	if len(result) == 0 {
+		return nil, ErrEmpty
+	}
+	return result, nil
+}
+
+// Format writes the ranges in the canonical form used by Normalize.
+func Format(ranges []PageRange) string {
+	parts := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		parts = append(parts, r.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Normalize rewrites the pages field value, stripped of its delimiters, in
+// the canonical form: ranges joined with `--`, expanded last pages and
+// comma-separated ranges.
+func Normalize(s string) (string, error) {
+	ranges, err := Parse(s)
+	if err != nil {
+		return ``, err
+	}
+	return Format(ranges), nil
+}
+
+func parseRange(s string) (PageRange, error) {
+	r := PageRange{From: s}
+	dashed := false
+	for _, d := range dashes {
+		if i := strings.Index(s, d); i >= 0 {
+			r = PageRange{
+				From: strings.TrimSpace(s[:i]),
+				To:   strings.TrimSpace(s[i+len(d):]),
+			}
+			dashed = true
+			break
+		}
+	}
+	if !isPage(r.From) {
+		return PageRange{}, fmt.Errorf("pages: invalid page %q", r.From)
+	}
+	if !dashed {
+		return r, nil
+	}
+	if !isPage(r.To) {
+		return PageRange{}, fmt.Errorf("pages: invalid page %q", r.To)
+	}
+	r.To = expand(r.From, r.To)
+	if n, ok := r.Count(); ok && n < 1 {
+		return PageRange{}, fmt.Errorf("pages: descending range %s", s)
+	}
+	return r, nil
+}
+
+// Expand completes the abbreviated last page of an arabic range with the
+// leading digits of the first page, provided the result is not smaller.
+func expand(from, to string) string {
+	if !isDigits(from) || !isDigits(to) || len(to) >= len(from) {
+		return to
+	}
+	full := from[:len(from)-len(to)] + to
+	if full < from {
+		return to
+	}
+	return full
+}
+
+const (
+	other = iota
+	arabic
+	roman
+)
+
+// Value returns the numeric value of the page and the kind of its number.
+func value(p string) (int, int) {
+	if isDigits(p) {
+		n, _ := strconv.Atoi(p)
+		return n, arabic
+	}
+	if n, ok := romanValue(p); ok {
+		return n, roman
+	}
+	return 0, other
+}
+
+// IsPage checks if the page is an arabic number with at most three prefix
+// letters and one suffix letter, or a roman numeral.
+func isPage(p string) bool {
+	if _, ok := romanValue(p); ok {
+		return true
+	}
+	core := strings.TrimLeft(p, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	if len(p)-len(core) > 3 {
+		return false
+	}
+	if n := len(core); n > 1 && isLetter(core[n-1]) {
+		core = core[:n-1]
+	}
+	return isDigits(core)
+}
+
+var romanDigits = map[byte]int{
+	'i': 1, 'v': 5, 'x': 10, 'l': 50, 'c': 100, 'd': 500, 'm': 1000,
+}
+
+// RomanValue returns the value of a roman numeral written in either case.
+// Numerals that do not read back the same, such as iiii, are rejected.
+func romanValue(p string) (int, bool) {
+	s := strings.ToLower(p)
+	if s == `` || s != p && strings.ToUpper(p) != p {
+		return 0, false
+	}
+	n := 0
+	for i := 0; i < len(s); i++ {
+		v, ok := romanDigits[s[i]]
+		if !ok {
+			return 0, false
+		}
+		if i+1 < len(s) && romanDigits[s[i+1]] > v {
+			n -= v
+		} else {
+			n += v
+		}
+	}
+	if n <= 0 || toRoman(n) != s {
+		return 0, false
+	}
+	return n, true
+}
+
+func toRoman(n int) string {
+	var b strings.Builder
+	for _, d := range []struct {
+		v int
+		s string
+	}{
+		{1000, "m"}, {900, "cm"}, {500, "d"}, {400, "cd"}, {100, "c"}, {90, "xc"},
+		{50, "l"}, {40, "xl"}, {10, "x"}, {9, "ix"}, {5, "v"}, {4, "iv"}, {1, "i"},
+	} {
+		for n >= d.v {
+			b.WriteString(d.s)
+			n -= d.v
+		}
+	}
+	return b.String()
+}
+
+func isDigits(s string) bool {
+	if s == `` {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
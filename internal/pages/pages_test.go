@@ -0,0 +1,99 @@
+package pages
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		s    string
+		want []PageRange
+	}{
+		{"12", []PageRange{{"12", ``}}},
+		{"12--15", []PageRange{{"12", "15"}}},
+		{"12 – 15", []PageRange{{"12", "15"}}},
+		{"e1234", []PageRange{{"e1234", ``}}},
+		{"S12-S19", []PageRange{{"S12", "S19"}}},
+		{"xii-xv", []PageRange{{"xii", "xv"}}},
+		{"1234-56", []PageRange{{"1234", "1256"}}},
+		{"1-5, 7, 9--11", []PageRange{{"1", "5"}, {"7", ``}, {"9", "11"}}},
+		{"123a-124b", []PageRange{{"123a", "124b"}}},
+	}
+	for _, c := range cases {
+		t.Run(c.s, func(t *testing.T) {
+			have, err := Parse(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(have, c.want) {
+				t.Errorf("have %v; want %v", have, c.want)
+			}
+		})
+	}
+}
+
+func TestParseErr(t *testing.T) {
+	cases := []string{
+		``,
+		" , ",
+		"twelve",
+		"15--12",
+		"xv-xii",
+		"iiii",
+		"12--",
+		"abcd12",
+	}
+	for _, c := range cases {
+		t.Run(c, func(t *testing.T) {
+			if have, err := Parse(c); err == nil {
+				t.Errorf("have %v; want error", have)
+			}
+		})
+	}
+}
+
+func TestCount(t *testing.T) {
+	cases := []struct {
+		r    PageRange
+		want int
+		ok   bool
+	}{
+		{PageRange{"12", ``}, 1, true},
+		{PageRange{"12", "15"}, 4, true},
+		{PageRange{"iv", "x"}, 7, true},
+		{PageRange{"e12", "e15"}, 0, false},
+		{PageRange{"x", "12"}, 0, false},
+	}
+	for _, c := range cases {
+		t.Run(c.r.String(), func(t *testing.T) {
+			have, ok := c.r.Count()
+			if have != c.want || ok != c.ok {
+				t.Errorf("have %d, %t; want %d, %t", have, ok, c.want, c.ok)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		s    string
+		want string
+	}{
+		{"12-15", "12--15"},
+		{"12—15,17", "12--15, 17"},
+		{"1234-56", "1234--1256"},
+		{"e1234", "e1234"},
+	}
+	for _, c := range cases {
+		t.Run(c.s, func(t *testing.T) {
+			have, err := Normalize(c.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
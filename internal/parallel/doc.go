@@ -0,0 +1,7 @@
+/*
+Parallel package runs independent pieces of work, such as the files given to
+a command, on a bounded number of goroutines while handing their results over
+in the original order, so that the output of a command does not depend on
+which file happened to finish first.
+*/
+package parallel
@@ -0,0 +1,47 @@
+package parallel
+
+import "sync"
+
+// Ordered runs work for each index from 0 to n-1 on at most jobs goroutines
+// and calls emit with the results in the order of the indices, each one as
+// soon as it and all the results before it are ready. Once emit returns an
+// error no more work is started, and Ordered returns the error after the work
+// in progress is done. A jobs value below 1 stands for 1.
+func Ordered[T any](n, jobs int, work func(i int) T, emit func(i int, v T) error) error {
+	if jobs < 1 {
+		jobs = 1
+	}
+	results := make([]chan T, n)
+	for i := range results {
+		results[i] = make(chan T, 1)
+	}
+	next := make(chan int)
+	stop := make(chan struct{})
+	go func() {
+		defer close(next)
+		for i := 0; i < n; i++ {
+			select {
+			case next <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for w := 0; w < jobs && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] <- work(i)
+			}
+		}()
+	}
+	var err error
+	for i := 0; i < n && err == nil; i++ {
+		err = emit(i, <-results[i])
+	}
+	close(stop)
+	wg.Wait()
+	return err
+}
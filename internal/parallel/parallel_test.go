@@ -0,0 +1,86 @@
+package parallel
+
+import (
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOrdered(t *testing.T) {
+	cases := []struct {
+		name string
+		n    int
+		jobs int
+	}{
+		{"serial", 10, 1},
+		{"parallel", 100, 8},
+		{"more-jobs-than-work", 3, 16},
+		{"no-jobs", 5, 0},
+		{"no-work", 0, 4},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var running, peak int32
+			have, want := []int{}, []int{}
+			work := func(i int) int {
+				if r := atomic.AddInt32(&running, 1); r > atomic.LoadInt32(&peak) {
+					atomic.StoreInt32(&peak, r)
+				}
+				// Let the later items finish first.
+				time.Sleep(time.Duration(c.n-i) * 50 * time.Microsecond)
+				atomic.AddInt32(&running, -1)
+				return i * i
+			}
+			emit := func(i, v int) error {
+				have = append(have, v)
+				return nil
+			}
+			for i := 0; i < c.n; i++ {
+				want = append(want, i*i)
+			}
+			if err := Ordered(c.n, c.jobs, work, emit); err != nil {
+				t.Fatalf("have %v; want nil", err)
+			}
+			if !reflect.DeepEqual(have, want) {
+				t.Errorf("have %v; want %v", have, want)
+			}
+			if max := int32(c.jobs); max > 0 && peak > max {
+				t.Errorf("have %d concurrent jobs; want at most %d", peak, max)
+			}
+		})
+	}
+}
+
+func TestOrderedErr(t *testing.T) {
+	fail := errors.New("fail")
+	failed := make(chan struct{})
+	var started int32
+	work := func(i int) int {
+		atomic.AddInt32(&started, 1)
+		if i > 2 {
+			// Hold the later items until emit fails.
+			<-failed
+		}
+		return i
+	}
+	emitted := []int{}
+	emit := func(i, v int) error {
+		emitted = append(emitted, v)
+		if i == 2 {
+			close(failed)
+			return fail
+		}
+		return nil
+	}
+	if err := Ordered(1000, 2, work, emit); err != fail {
+		t.Errorf("have %v; want %v", err, fail)
+	}
+	if !reflect.DeepEqual(emitted, []int{0, 1, 2}) {
+		t.Errorf("have %v; want %v", emitted, []int{0, 1, 2})
+	}
+	if n := atomic.LoadInt32(&started); n > 10 {
+		t.Errorf("have %d items started; want the work stopped", n)
+	}
+}
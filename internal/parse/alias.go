@@ -0,0 +1,45 @@
+package parse
+
+import "strings"
+
+// Aliases maps the lower-case names of fields known under several names,
+// including common misspellings, onto their canonical BibTeX names.
+var Aliases = map[string]string{
+	"adress":       "address",
+	"adresse":      "address",
+	"location":     "address",
+	"annotation":   "annote",
+	"authors":      "author",
+	"editors":      "editor",
+	"eprintclass":  "primaryclass",
+	"eprinttype":   "archiveprefix",
+	"journaltitle": "journal",
+	"jounral":      "journal",
+	"keyword":      "keywords",
+	"organisation": "organization",
+	"sortkey":      "key",
+}
+
+// CanonicalKey returns the lower-case canonical name of the field.
+func CanonicalKey(key string) string {
+	key = strings.ToLower(key)
+	if c, ok := Aliases[key]; ok {
+		return c
+	}
+	return key
+}
+
+// Lookup is like Get but matches fields under any name sharing the canonical
+// name of the key, preferring the field named exactly like the key.
+func (e *EntryDecl) Lookup(key string) (*FieldStmt, bool) {
+	if f, ok := e.Get(key); ok {
+		return f, true
+	}
+	canonical := CanonicalKey(key)
+	for _, f := range e.Fields {
+		if CanonicalKey(f.Key) == canonical {
+			return f, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,48 @@
+package parse
+
+import "testing"
+
+func TestLookup(t *testing.T) {
+	e := &EntryDecl{Fields: []*FieldStmt{
+		{Key: "Adress", Value: "{Paris}"},
+		{Key: "journaltitle", Value: "{J}"},
+		{Key: "journal", Value: "{K}"},
+	}}
+	cases := []struct {
+		key  string
+		want string
+		ok   bool
+	}{
+		{"address", "{Paris}", true},
+		{"location", "{Paris}", true},
+		{"journal", "{K}", true},
+		{"journaltitle", "{J}", true},
+		{"title", ``, false},
+	}
+	for _, c := range cases {
+		t.Run(c.key, func(t *testing.T) {
+			f, ok := e.Lookup(c.key)
+			if ok != c.ok || ok && f.Value != c.want {
+				t.Errorf("have %v, %t; want %s, %t", f, ok, c.want, c.ok)
+			}
+		})
+	}
+}
+
+func TestCanonicalKey(t *testing.T) {
+	cases := []struct {
+		key  string
+		want string
+	}{
+		{"EprintClass", "primaryclass"},
+		{"sortkey", "key"},
+		{"Title", "title"},
+	}
+	for _, c := range cases {
+		t.Run(c.key, func(t *testing.T) {
+			if have := CanonicalKey(c.key); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
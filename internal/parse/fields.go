@@ -0,0 +1,79 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/names"
+	"github.com/mdm-code/bibx/internal/pages"
+)
+
+// ErrMissingField is returned by the typed accessors of entries lacking the
+// field.
+var ErrMissingField = errors.New("parse: missing field")
+
+// Year returns the year of publication as a number. The year field is read,
+// or in its absence the year of the BibLaTeX date field.
+func (e *EntryDecl) Year() (int, error) {
+	v, err := e.text("year")
+	if err != nil {
+		date, derr := e.text("date")
+		if derr != nil {
+			return 0, err
+		}
+		// Dates start with the year, as in 2020-05-01 or 2020/2021.
+		v = date
+		if i := strings.IndexAny(date, "-/"); i >= 0 {
+			v = date[:i]
+		}
+	}
+	year, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("parse: invalid year %q", v)
+	}
+	return year, nil
+}
+
+// Authors returns the names of the authors listed in the author field.
+func (e *EntryDecl) Authors() ([]names.Name, error) {
+	return e.names("author")
+}
+
+// Editors returns the names of the editors listed in the editor field.
+func (e *EntryDecl) Editors() ([]names.Name, error) {
+	return e.names("editor")
+}
+
+// PageRange returns the ranges of pages listed in the pages field, with the
+// abbreviated last pages expanded.
+func (e *EntryDecl) PageRange() ([]pages.PageRange, error) {
+	v, err := e.text("pages")
+	if err != nil {
+		return nil, err
+	}
+	return pages.Parse(v)
+}
+
+func (e *EntryDecl) names(key string) ([]names.Name, error) {
+	v, err := e.text(key)
+	if err != nil {
+		return nil, err
+	}
+	result := names.ParseList(v)
+	if len(result) == 0 {
+		return nil, fmt.Errorf("parse: no names in %s", key)
+	}
+	return result, nil
+}
+
+// Text returns the value of the field under the key or its aliases stripped
+// of its delimiters and surrounding white space.
+func (e *EntryDecl) text(key string) (string, error) {
+	f, ok := e.Lookup(key)
+	if !ok {
+		return ``, fmt.Errorf("%w %s", ErrMissingField, key)
+	}
+	return strings.TrimSpace(Unquote(f.Value)), nil
+}
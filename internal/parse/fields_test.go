@@ -0,0 +1,65 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestEntryYear(t *testing.T) {
+	cases := []struct {
+		fields []*FieldStmt
+		want   int
+		err    bool
+	}{
+		{[]*FieldStmt{{Key: "year", Value: "1993"}}, 1993, false},
+		{[]*FieldStmt{{Key: "Year", Value: "{ 2001 }"}}, 2001, false},
+		{[]*FieldStmt{{Key: "date", Value: "{2020-05-01}"}}, 2020, false},
+		{[]*FieldStmt{{Key: "date", Value: "{2020/2021}"}}, 2020, false},
+		{[]*FieldStmt{{Key: "year", Value: "{forthcoming}"}}, 0, true},
+		{[]*FieldStmt{}, 0, true},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprint(c.fields), func(t *testing.T) {
+			e := &EntryDecl{Fields: c.fields}
+			have, err := e.Year()
+			if have != c.want || (err != nil) != c.err {
+				t.Errorf("have %d, %v; want %d", have, err, c.want)
+			}
+		})
+	}
+	if _, err := (&EntryDecl{}).Year(); !errors.Is(err, ErrMissingField) {
+		t.Errorf("have %v; want %v", err, ErrMissingField)
+	}
+}
+
+func TestEntryAuthors(t *testing.T) {
+	e := &EntryDecl{Fields: []*FieldStmt{
+		{Key: "authors", Value: "{Cohen, Paul and Kurt G{\\\"o}del}"},
+		{Key: "editor", Value: "{}"},
+	}}
+	have, err := e.Authors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(have) != 2 || have[0].Last != "Cohen" || have[1].First != "Kurt" {
+		t.Errorf("have %v", have)
+	}
+	if _, err := e.Editors(); err == nil {
+		t.Error("have no error for an empty editor list")
+	}
+}
+
+func TestEntryPageRange(t *testing.T) {
+	e := &EntryDecl{Fields: []*FieldStmt{{Key: "pages", Value: `"1234-56, 60"`}}}
+	have, err := e.PageRange()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(have) != 2 || have[0].String() != "1234--1256" || have[1].String() != "60" {
+		t.Errorf("have %v", have)
+	}
+	if _, err := (&EntryDecl{}).PageRange(); !errors.Is(err, ErrMissingField) {
+		t.Errorf("have %v; want %v", err, ErrMissingField)
+	}
+}
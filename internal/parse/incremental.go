@@ -0,0 +1,239 @@
+package parse
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+// Tree is the parse of a source kept up to date as the source is edited.
+// The source is split into chunks each ending with a declaration, and Edit
+// re-parses only the chunks touched by the edit, shifting the positions of
+// the declarations after it in place. The resulting nodes, error and error
+// position are the same as those of parsing the edited source anew.
+type Tree struct {
+	src    string
+	chunks []chunk
+	err    error
+	errPos scan.Pos
+}
+
+// Chunk is a run of the source ending with the closing delimiter of its
+// declaration, so that it starts with the comments preceding it. The last
+// chunk holds the rest of the source after the last declaration along with
+// the trailing comments or the declaration the parser failed at.
+type chunk struct {
+	end   int // byte offset past the end of the chunk
+	nodes []Node
+}
+
+// NewTree parses the source.
+func NewTree(src string) *Tree {
+	t := &Tree{src: src}
+	t.chunks, t.errPos, t.err = parseChunks(src)
+	return t
+}
+
+// Source returns the source the tree was parsed from.
+func (t *Tree) Source() string { return t.src }
+
+// Nodes returns the nodes of the tree in the order of the source.
+func (t *Tree) Nodes() []Node {
+	result := []Node{}
+	for _, c := range t.chunks {
+		result = append(result, c.nodes...)
+	}
+	return result
+}
+
+// Err returns the error that stopped the parser or nil if the whole source
+// parsed.
+func (t *Tree) Err() error { return t.err }
+
+// ErrPos returns the position the parser failed at, or the zero value if it
+// did not fail.
+func (t *Tree) ErrPos() scan.Pos { return t.errPos }
+
+// Edit replaces the bytes of the source between the offsets start and end
+// with text and updates the tree. The nodes returned before by Nodes may be
+// modified, as the positions of the declarations after the edit are shifted
+// in place. Edit panics if the offsets are out of the range of the source.
+func (t *Tree) Edit(start, end int, text string) {
+	old := t.src
+	t.src = old[:start] + text + old[end:]
+	delta := len(text) - (end - start)
+
+	// The chunks from the one holding start up to the one holding end are
+	// touched, including the chunk beginning right at end, whose leading
+	// comments the edit extends.
+	first := sort.Search(len(t.chunks), func(i int) bool { return t.chunks[i].end > start })
+	last := sort.Search(len(t.chunks), func(i int) bool { return t.chunks[i].end > end })
+	if last == len(t.chunks) {
+		last--
+	}
+	if first > last {
+		first = last
+	}
+	from := 0
+	if first > 0 {
+		from = t.chunks[first-1].end
+	}
+
+	// The re-parsed region grows over the following chunks until it ends
+	// with a declaration just like it did before the edit, and the parse of
+	// the rest of the source is bound to stay the same. The region doubles
+	// each time, so that an edit unbalancing the braces early in the source
+	// costs a few parses of the rest of it at most.
+	var chunks []chunk
+	var err error
+	var errPos scan.Pos
+	for grow := 1; ; grow *= 2 {
+		region := t.src[from : t.chunks[last].end+delta]
+		chunks, errPos, err = parseRegion(region)
+		if last == len(t.chunks)-1 {
+			break
+		}
+		if tail := chunks[len(chunks)-1]; err == nil && len(tail.nodes) == 0 && chunkStart(chunks, len(chunks)-1) == len(region) {
+			chunks = chunks[:len(chunks)-1]
+			break
+		}
+		last = min(last+grow, len(t.chunks)-1)
+	}
+
+	base := posAt(t.src, from)
+	for i := range chunks {
+		chunks[i].end += from
+		for _, n := range chunks[i].nodes {
+			shiftNode(n, 1, base.Line-1, base.Column-1)
+		}
+	}
+	rest := t.chunks[last+1:]
+	if len(rest) > 0 {
+		// The positions on the line the region ends on move along with the
+		// end of the region, and the lines below shift by the lines added.
+		was, is := posAt(old, t.chunks[last].end), posAt(t.src, t.chunks[last].end+delta)
+		for i := range rest {
+			rest[i].end += delta
+			for _, n := range rest[i].nodes {
+				shiftNode(n, was.Line, is.Line-was.Line, is.Column-was.Column)
+			}
+		}
+		if t.err != nil {
+			t.errPos = shiftPos(t.errPos, was.Line, is.Line-was.Line, is.Column-was.Column)
+		}
+	} else {
+		t.err, t.errPos = err, errPos
+		if err != nil {
+			t.errPos = shiftPos(errPos, 1, base.Line-1, base.Column-1)
+		}
+	}
+	t.chunks = append(append(t.chunks[:first:first], chunks...), rest...)
+}
+
+// ParseRegion parses the regions of the source touched by edits. Tests
+// replace it to count the bytes re-parsed.
+var parseRegion = parseChunks
+
+// ParseChunks parses the source into chunks ending with each declaration
+// followed by the chunk holding the rest of the source.
+func parseChunks(src string) ([]chunk, scan.Pos, error) {
+	s := &delimScanner{Scanner: scan.NewScanner(scan.NewBytesReader([]byte(src)))}
+	p := NewParser(s)
+	nodes := []Node{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		nodes = append(nodes, n)
+	}
+	ends := offsets(src, s.ends)
+	result := []chunk{}
+	for len(nodes) > 0 && len(result) < len(ends) {
+		if _, ok := nodes[0].(*CommentGroupExpr); ok {
+			break
+		}
+		result = append(result, chunk{end: ends[len(result)], nodes: nodes[:1:1]})
+		nodes = nodes[1:]
+	}
+	result = append(result, chunk{end: len(src), nodes: nodes})
+	return result, p.ErrPos(), p.Err()
+}
+
+// ChunkStart returns the byte offset the chunk starts at.
+func chunkStart(chunks []chunk, i int) int {
+	if i == 0 {
+		return 0
+	}
+	return chunks[i-1].end
+}
+
+// DelimScanner records the positions of the closing delimiters of the
+// declarations scanned.
+type delimScanner struct {
+	*scan.Scanner
+	ends []scan.Pos
+}
+
+func (s *delimScanner) Next() scan.Item {
+	i := s.Scanner.Next()
+	if i.T == scan.ItemRightDelim {
+		s.ends = append(s.ends, s.Pos())
+	}
+	return i
+}
+
+// Offsets converts the positions of the closing delimiters, given in the
+// order of the source, into the byte offsets just past them.
+func offsets(src string, ps []scan.Pos) []int {
+	result := make([]int, 0, len(ps))
+	p := scan.Pos{Line: 1, Column: 1}
+	for off := 0; off < len(src) && len(result) < len(ps); {
+		c, size := utf8.DecodeRuneInString(src[off:])
+		off += size
+		if p == ps[len(result)] {
+			result = append(result, off)
+		}
+		if c == '\n' {
+			p = scan.Pos{Line: p.Line + 1, Column: 1}
+		} else {
+			p.Column++
+		}
+	}
+	return result
+}
+
+// PosAt returns the position of the byte offset in the source.
+func posAt(src string, off int) scan.Pos {
+	line := strings.Count(src[:off], "\n") + 1
+	start := strings.LastIndexByte(src[:off], '\n') + 1
+	return scan.Pos{Line: line, Column: utf8.RuneCountInString(src[start:off]) + 1}
+}
+
+// ShiftNode moves the positions of the declaration and its fields by lines,
+// and the positions on line by columns as well.
+func shiftNode(n Node, line, lines, columns int) {
+	switch n := n.(type) {
+	case *EntryDecl:
+		n.Pos = shiftPos(n.Pos, line, lines, columns)
+		for _, f := range n.Fields {
+			f.Pos = shiftPos(f.Pos, line, lines, columns)
+		}
+	case *AbbrevDecl:
+		n.Pos = shiftPos(n.Pos, line, lines, columns)
+		if n.Field != nil {
+			n.Field.Pos = shiftPos(n.Field.Pos, line, lines, columns)
+		}
+	case *PreambleDecl:
+		n.Pos = shiftPos(n.Pos, line, lines, columns)
+	}
+}
+
+func shiftPos(p scan.Pos, line, lines, columns int) scan.Pos {
+	if !p.IsValid() {
+		return p
+	}
+	if p.Line == line {
+		p.Column += columns
+	}
+	p.Line += lines
+	return p
+}
@@ -0,0 +1,172 @@
+package parse
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+var treeSource = `% Library
+@string{jx = {J. X}}
+
+@book{one,
+  title = {The title},
+  year  = 1993
+}
+@article(two, journal = jx, pages = "1--2") @misc{three, note = {Zażółć}}
+
+% The end.
+`
+
+func TestTreeEdit(t *testing.T) {
+	cases := []struct {
+		name       string
+		start, end int
+		text       string
+	}{
+		{"inside-field", strings.Index(treeSource, "The title"), strings.Index(treeSource, "The title") + 3, "A"},
+		{"new-line-in-field", strings.Index(treeSource, "year"), strings.Index(treeSource, "year"), "\n  "},
+		{"insert-entry", strings.Index(treeSource, "@book"), strings.Index(treeSource, "@book"), "@misc{new, year = 2000}\n"},
+		{"delete-entry", strings.Index(treeSource, "@book"), strings.Index(treeSource, "@article"), ``},
+		{"break-entry", strings.Index(treeSource, "\n}") + 1, strings.Index(treeSource, "\n}") + 2, ``},
+		{"break-and-fix", strings.Index(treeSource, "year"), strings.Index(treeSource, "year"), "} @misc{x,\n  "},
+		{"same-line", strings.Index(treeSource, "two"), strings.Index(treeSource, "two") + 3, "second"},
+		{"drop-at", strings.Index(treeSource, "@misc"), strings.Index(treeSource, "@misc") + 1, ``},
+		{"after-last", len(treeSource), len(treeSource), "@misc{end}"},
+		{"trailing-comment", strings.Index(treeSource, "The end"), strings.Index(treeSource, "The end") + 3, "An"},
+		{"replace-all", 0, len(treeSource), "@misc{k, year = 1}"},
+		{"empty", 0, len(treeSource), ``},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tree := NewTree(treeSource)
+			tree.Edit(c.start, c.end, c.text)
+			checkTree(t, tree)
+		})
+	}
+}
+
+func TestTreeEditRandom(t *testing.T) {
+	snippets := []string{
+		"}", "{", ")", "@", "@misc{k,", ",", "\n", "% note\n", "x", "ł",
+		" = {v}", "@string{m = {M}}", "\"", "@book{b, year = 1}\n", "@comment{c {d}}",
+	}
+	r := rand.New(rand.NewSource(1))
+	tree := NewTree(treeSource)
+	for i := 0; i < 2000; i++ {
+		src := tree.Source()
+		start := r.Intn(len(src) + 1)
+		end := start + r.Intn(len(src)-start+1)%8
+		text := ``
+		if r.Intn(3) > 0 {
+			text = snippets[r.Intn(len(snippets))]
+		}
+		// Keep the offsets on rune boundaries like an editor does.
+		for start > 0 && start < len(src) && !isRuneStart(src, start) {
+			start--
+		}
+		for end < len(src) && !isRuneStart(src, end) {
+			end++
+		}
+		tree.Edit(start, end, text)
+		checkTree(t, tree)
+		if t.Failed() {
+			t.Fatalf("edit %d: %q at %d:%d", i, text, start, end)
+		}
+		if len(tree.Source()) > 2000 {
+			tree = NewTree(treeSource)
+		}
+	}
+}
+
+func TestTreeEditUnbalanced(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 3000; i++ {
+		fmt.Fprintf(&b, "@misc{k%d,\n  title = {Title %d},\n  year  = 2000\n}\n", i, i)
+	}
+	src := b.String()
+	parsed := 0
+	defer func(f func(string) ([]chunk, scan.Pos, error)) { parseRegion = f }(parseRegion)
+	parseRegion = func(region string) ([]chunk, scan.Pos, error) {
+		parsed += len(region)
+		return parseChunks(region)
+	}
+	tree := NewTree(src)
+	at := strings.Index(src, "Title")
+	tree.Edit(at, at, "{")
+	checkTree(t, tree)
+	if parsed > 4*len(src) {
+		t.Errorf("have %d bytes parsed; want at most %d", parsed, 4*len(src))
+	}
+	parsed = 0
+	tree.Edit(at, at+1, ``)
+	checkTree(t, tree)
+	if parsed > 4*len(src) {
+		t.Errorf("have %d bytes parsed; want at most %d", parsed, 4*len(src))
+	}
+}
+
+func isRuneStart(s string, i int) bool { return s[i]&0xC0 != 0x80 }
+
+// CheckTree compares the tree with a parse of its source from scratch.
+func checkTree(t *testing.T, tree *Tree) {
+	t.Helper()
+	p := NewParser(scan.NewScanner(scan.NewReader(strings.NewReader(tree.Source()))))
+	want := []Node{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		want = append(want, n)
+	}
+	have := tree.Nodes()
+	if len(have) != len(want) {
+		t.Fatalf("have %d nodes; want %d", len(have), len(want))
+	}
+	for i := range want {
+		if !have[i].Eq(want[i]) {
+			t.Errorf("node %d: have %v; want %v", i, have[i], want[i])
+		}
+		if hp, wp := positions(have[i]), positions(want[i]); hp != wp {
+			t.Errorf("node %d: have positions %s; want %s", i, hp, wp)
+		}
+	}
+	if have, want := tree.Err(), p.Err(); have != want {
+		t.Errorf("have %v; want %v", have, want)
+	}
+	if have, want := tree.ErrPos(), p.ErrPos(); have != want {
+		t.Errorf("have error position %v; want %v", have, want)
+	}
+}
+
+func positions(n Node) string {
+	ps := []string{}
+	switch d := n.(type) {
+	case *EntryDecl:
+		ps = append(ps, d.Pos.String())
+		for _, f := range d.Fields {
+			ps = append(ps, f.Pos.String())
+		}
+	case *AbbrevDecl:
+		ps = append(ps, d.Pos.String(), d.Field.Pos.String())
+	case *PreambleDecl:
+		ps = append(ps, d.Pos.String())
+	}
+	return strings.Join(ps, " ")
+}
+
+func BenchmarkTreeEdit(b *testing.B) {
+	src := strings.Repeat(haveEntryOne, 1000)
+	at := strings.LastIndex(src, "The title")
+	b.Run("full", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			NewTree(src[:at] + "A" + src[at+1:])
+		}
+	})
+	b.Run("incremental", func(b *testing.B) {
+		tree := NewTree(src)
+		for i := 0; i < b.N; i++ {
+			tree.Edit(at, at+1, "A")
+		}
+	})
+}
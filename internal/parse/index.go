@@ -0,0 +1,157 @@
+package parse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+// ErrNoEntry is returned by LoadEntry for keys not found in the index.
+var ErrNoEntry = errors.New("parse: no entry with the key")
+
+// Span is the region of the source taken by an entry, from its @ up to and
+// including its closing delimiter.
+type Span struct {
+	Key        string
+	Start, End int // byte offsets
+	Pos        scan.Pos
+}
+
+// Index locates the entries of a source by their cite keys without parsing
+// them, so that the entries of large libraries are parsed one at a time as
+// they are asked for. Declarations are told apart by their delimiters alone,
+// so a malformed declaration spoils at most the entries around it, which
+// fail to load rather than the whole index. A declaration left unclosed ends
+// where an @ starts a line outside of its braces and quotes.
+type Index struct {
+	src   []byte
+	spans []Span
+	byKey map[string]int
+}
+
+// NewIndex records the spans of the entries of the source in a single pass
+// over its bytes. The source must not change while the index is used.
+func NewIndex(src []byte) *Index {
+	x := &Index{src: src, spans: []Span{}, byKey: map[string]int{}}
+	line, lineStart := 1, 0
+	// Pos returns the position of the offset, counting the lines up to it.
+	pos := func(off int) scan.Pos {
+		for {
+			i := bytes.IndexByte(src[lineStart:off], '\n')
+			if i < 0 {
+				break
+			}
+			line, lineStart = line+1, lineStart+i+1
+		}
+		return scan.Pos{Line: line, Column: utf8.RuneCount(src[lineStart:off]) + 1}
+	}
+	for off := 0; off < len(src); {
+		at := bytes.IndexByte(src[off:], '@')
+		if at < 0 {
+			break
+		}
+		start := off + at
+		open := bytes.IndexAny(src[start:], "{(")
+		if open < 0 {
+			break
+		}
+		open += start
+		name := strings.ToLower(string(bytes.TrimSpace(src[start+1 : open])))
+		if !scan.IsValidName(name) {
+			// An @ in a comment, which the scanner fails at too.
+			off = start + 1
+			continue
+		}
+		end := closing(src, open)
+		off = end
+		if name == "comment" || name == "preamble" || name == "string" {
+			continue
+		}
+		body := src[open+1 : max(end-1, open+1)]
+		key := body
+		if i := bytes.IndexByte(body, ','); i >= 0 {
+			key = body[:i]
+		}
+		s := Span{Key: string(bytes.TrimSpace(key)), Start: start, End: end, Pos: pos(start)}
+		if k := strings.ToLower(s.Key); s.Key != `` {
+			if _, ok := x.byKey[k]; !ok {
+				x.byKey[k] = len(x.spans)
+			}
+		}
+		x.spans = append(x.spans, s)
+	}
+	return x
+}
+
+// Closing returns the offset past the delimiter closing the one at open.
+// When it is not closed, it returns the offset of the first @ starting a line
+// at the outermost level of the declaration, or else the length of the
+// source. Braces nest within both kinds of delimiters, and parentheses close
+// outside of quoted values only.
+func closing(src []byte, open int) int {
+	depth, quoted := 0, false
+	for i := open + 1; i < len(src); i++ {
+		switch c := src[i]; {
+		case c == '{':
+			depth++
+		case c == '}':
+			if depth == 0 && src[open] == '{' {
+				return i + 1
+			}
+			depth--
+		case c == '"' && depth == 0:
+			quoted = !quoted
+		case c == '@' && depth == 0 && !quoted && src[i-1] == '\n':
+			return i
+		case c == ')' && depth == 0 && !quoted && src[open] == '(':
+			return i + 1
+		}
+	}
+	return len(src)
+}
+
+// Spans returns the spans of the entries in the order of the source.
+func (x *Index) Spans() []Span { return x.spans }
+
+// Keys returns the cite keys of the entries in the order of the source.
+func (x *Index) Keys() []string {
+	result := make([]string, 0, len(x.spans))
+	for _, s := range x.spans {
+		result = append(result, s.Key)
+	}
+	return result
+}
+
+// Lookup returns the span of the first entry with the key. Keys are matched
+// case-insensitively.
+func (x *Index) Lookup(key string) (Span, bool) {
+	i, ok := x.byKey[strings.ToLower(key)]
+	if !ok {
+		return Span{}, false
+	}
+	return x.spans[i], true
+}
+
+// LoadEntry parses the entry with the key from its span of the source, with
+// the positions it has in the whole source. The comments preceding the
+// entry are not part of its span and are left out.
+func (x *Index) LoadEntry(key string) (*EntryDecl, error) {
+	s, ok := x.Lookup(key)
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrNoEntry, key)
+	}
+	p := NewParser(scan.NewScanner(scan.NewBytesReader(x.src[s.Start:s.End], scan.WithStart(s.Pos))))
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		if e, ok := n.(*EntryDecl); ok {
+			return e, nil
+		}
+	}
+	if err := p.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", p.ErrPos(), err)
+	}
+	return nil, fmt.Errorf("%w %s", ErrNoEntry, key)
+}
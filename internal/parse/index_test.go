@@ -0,0 +1,101 @@
+package parse
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+func TestIndex(t *testing.T) {
+	x := NewIndex([]byte(treeSource))
+	if have, want := x.Keys(), []string{"one", "two", "three"}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("have %v; want %v", have, want)
+	}
+	nodes := NewTree(treeSource).Nodes()
+	for _, n := range nodes {
+		want, ok := n.(*EntryDecl)
+		if !ok {
+			continue
+		}
+		have, err := x.LoadEntry(strings.ToUpper(want.CiteKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !have.Eq(want) || have.Pos != want.Pos || have.Fields[0].Pos != want.Fields[0].Pos {
+			t.Errorf("have %s at %s; want %s at %s", have, have.Pos, want, want.Pos)
+		}
+	}
+	if _, err := x.LoadEntry("four"); !errors.Is(err, ErrNoEntry) {
+		t.Errorf("have %v; want %v", err, ErrNoEntry)
+	}
+}
+
+func TestIndexSpans(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want []Span
+	}{
+		{
+			name: "quoted-paren",
+			src:  `@misc(a, title = "x)y") @misc{b}`,
+			want: []Span{
+				{Key: "a", Start: 0, End: 23, Pos: scan.Pos{Line: 1, Column: 1}},
+				{Key: "b", Start: 24, End: 32, Pos: scan.Pos{Line: 1, Column: 25}},
+			},
+		},
+		{
+			name: "comments",
+			src:  "mail me@home page {x}\n@comment{@misc{no}}\n@string{s = {@}}\n  @book{c, title = {{@}}}",
+			want: []Span{
+				{Key: "c", Start: 61, End: 84, Pos: scan.Pos{Line: 4, Column: 3}},
+			},
+		},
+		{
+			name: "unclosed",
+			src:  "@misc{a, title = {x}\n@misc{b}",
+			want: []Span{
+				{Key: "a", Start: 0, End: 21, Pos: scan.Pos{Line: 1, Column: 1}},
+				{Key: "b", Start: 21, End: 29, Pos: scan.Pos{Line: 2, Column: 1}},
+			},
+		},
+		{
+			name: "unclosed at the end",
+			src:  "@misc{b}\n@misc{a, title = {x}",
+			want: []Span{
+				{Key: "b", Start: 0, End: 8, Pos: scan.Pos{Line: 1, Column: 1}},
+				{Key: "a", Start: 9, End: 29, Pos: scan.Pos{Line: 2, Column: 1}},
+			},
+		},
+		{
+			name: "nested at",
+			src:  "@misc{a, note = {x\n@y}}\n@misc{b}",
+			want: []Span{
+				{Key: "a", Start: 0, End: 23, Pos: scan.Pos{Line: 1, Column: 1}},
+				{Key: "b", Start: 24, End: 32, Pos: scan.Pos{Line: 3, Column: 1}},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := NewIndex([]byte(c.src)).Spans(); !reflect.DeepEqual(have, c.want) {
+				t.Errorf("have %+v; want %+v", have, c.want)
+			}
+		})
+	}
+}
+
+func TestIndexBroken(t *testing.T) {
+	x := NewIndex([]byte("@misc{a, title = {x}}\n@misc{b, title = }\n@misc{c, year = 1}"))
+	if _, err := x.LoadEntry("b"); err == nil {
+		t.Error("have nil; want error")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, err := x.LoadEntry(key); err != nil {
+			t.Errorf("%s: %v", key, err)
+		}
+	}
+}
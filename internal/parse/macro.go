@@ -0,0 +1,144 @@
+package parse
+
+import (
+	"strings"
+	"unicode"
+)
+
+// BuiltinMacros are the month abbreviations predefined by the standard BibTeX
+// styles.
+var BuiltinMacros = MacroTable{
+	"jan": "January",
+	"feb": "February",
+	"mar": "March",
+	"apr": "April",
+	"may": "May",
+	"jun": "June",
+	"jul": "July",
+	"aug": "August",
+	"sep": "September",
+	"oct": "October",
+	"nov": "November",
+	"dec": "December",
+}
+
+// MacroTable is the symbol table of @string macros mapping their lower-case
+// names onto their raw values.
+type MacroTable map[string]string
+
+// NewMacroTable collects the @string definitions of the declarations. Later
+// definitions replace earlier ones as they do in BibTeX.
+func NewMacroTable(nodes []Node) MacroTable {
+	t := make(MacroTable)
+	for _, n := range nodes {
+		if a, ok := n.(*AbbrevDecl); ok && a.Field != nil {
+			t[strings.ToLower(a.Field.Key)] = a.Field.Value
+		}
+	}
+	return t
+}
+
+// Lookup returns the value of the macro, falling back on the built-in month
+// macros. Macro names are case-insensitive.
+func (t MacroTable) Lookup(name string) (string, bool) {
+	name = strings.ToLower(name)
+	if v, ok := t[name]; ok {
+		return v, true
+	}
+	v, ok := BuiltinMacros[name]
+	return v, ok
+}
+
+// Expand returns the text of the raw field value with the delimiters of its
+// parts stripped and the macro references replaced with the expanded values
+// of the macros. Undefined macros are kept by name.
+func (t MacroTable) Expand(value string) string {
+	return t.expand(value, 0)
+}
+
+// Expansion depth after which self-referencing macros are kept by name.
+const maxExpansion = 16
+
+func (t MacroTable) expand(value string, depth int) string {
+	var b strings.Builder
+	for _, part := range splitConcat(value) {
+		switch {
+		case isEnclosed(part):
+			b.WriteString(part[1 : len(part)-1])
+		case isNumber(part):
+			b.WriteString(part)
+		default:
+			v, ok := t.Lookup(part)
+			if !ok || depth >= maxExpansion {
+				b.WriteString(part)
+				continue
+			}
+			b.WriteString(t.expand(v, depth+1))
+		}
+	}
+	return b.String()
+}
+
+// SplitConcat splits the raw field value into the parts concatenated with
+// `#` outside of delimiters.
+func splitConcat(value string) []string {
+	result := []string{}
+	depth, quoted, start := 0, false, 0
+	for i, c := range value {
+		switch {
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+		case c == '"' && depth == 0:
+			quoted = !quoted
+		case c == '#' && depth == 0 && !quoted:
+			result = append(result, strings.TrimSpace(value[start:i]))
+			start = i + 1
+		}
+	}
+	return append(result, strings.TrimSpace(value[start:]))
+}
+
+// References lists the names of the macros referenced by the raw field value
+// in the order they appear, skipping the delimited parts and numbers.
+func References(value string) []string {
+	result := []string{}
+	depth, quoted, start := 0, false, -1
+	flush := func(i int) {
+		if start >= 0 {
+			if name := value[start:i]; !isNumber(name) {
+				result = append(result, name)
+			}
+			start = -1
+		}
+	}
+	for i, c := range value {
+		switch {
+		case c == '{':
+			flush(i)
+			depth++
+		case c == '}':
+			depth--
+		case c == '"' && depth == 0:
+			flush(i)
+			quoted = !quoted
+		case depth > 0 || quoted:
+		case c == '#' || unicode.IsSpace(c):
+			flush(i)
+		case start < 0:
+			start = i
+		}
+	}
+	flush(len(value))
+	return result
+}
+
+func isNumber(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ``
+}
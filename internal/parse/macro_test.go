@@ -0,0 +1,81 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReferences(t *testing.T) {
+	cases := []struct {
+		name      string
+		testInput string
+		want      []string
+	}{
+		{"braces", "{The title}", []string{}},
+		{"number", "1963", []string{}},
+		{"macro", "jan", []string{"jan"}},
+		{"concatenated", `jan # " 1 " # acm#{b}`, []string{"jan", "acm"}},
+		{"nested", `{a # {b} c} # "d {e} f" # g`, []string{"g"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			have := References(c.testInput)
+			if strings.Join(have, ",") != strings.Join(c.want, ",") {
+				t.Errorf("have %v; want %v", have, c.want)
+			}
+		})
+	}
+}
+
+func TestMacroTable(t *testing.T) {
+	nodes := []Node{
+		&AbbrevDecl{Field: &FieldStmt{Key: "ACM", Value: "{Assoc.}"}},
+		&EntryDecl{Name: "misc", CiteKey: "a"},
+		&AbbrevDecl{Field: &FieldStmt{Key: "acm", Value: "{Association}"}},
+	}
+	table := NewMacroTable(nodes)
+	cases := []struct {
+		name  string
+		value string
+		ok    bool
+	}{
+		{"Acm", "{Association}", true},
+		{"DEC", "December", true},
+		{"ieee", "", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if v, ok := table.Lookup(c.name); v != c.value || ok != c.ok {
+				t.Errorf("have %s, %v; want %s, %v", v, ok, c.value, c.ok)
+			}
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	table := MacroTable{
+		"acm":  "{Association for Computing Machinery}",
+		"cacm": `"Communications of the " # acm`,
+		"loop": "loop",
+	}
+	cases := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"braces", "{The title}", "The title"},
+		{"number", "1963", "1963"},
+		{"month", "jan", "January"},
+		{"nested", "cacm", "Communications of the Association for Computing Machinery"},
+		{"concatenated", `jan # "~1, " # 1999`, "January~1, 1999"},
+		{"undefined", "ieee", "ieee"},
+		{"self-reference", "loop", "loop"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := table.Expand(c.value); have != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
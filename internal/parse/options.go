@@ -0,0 +1,20 @@
+package parse
+
+import (
+	"log/slog"
+
+	"github.com/mdm-code/bibx/internal/progress"
+)
+
+// Option configures a Parser created by NewParser.
+type Option func(*Parser)
+
+// WithLogger sets the Logger of the parser.
+func WithLogger(l *slog.Logger) Option {
+	return func(p *Parser) { p.Logger = l }
+}
+
+// WithProgress sets the Progress reporter of the parser.
+func WithProgress(r progress.Reporter) Option {
+	return func(p *Parser) { p.Progress = r }
+}
@@ -1,9 +1,12 @@
 package parse
 
 import (
-	"reflect"
+	"context"
+	"errors"
+	"log/slog"
 	"strings"
 
+	"github.com/mdm-code/bibx/internal/progress"
 	"github.com/mdm-code/bibx/internal/scan"
 )
 
@@ -30,6 +33,9 @@ const (
 	eof
 )
 
+// ErrSyntax is returned when the input is not valid BibTeX.
+var ErrSyntax = errors.New("parse: BIBX0001 invalid BibTeX syntax")
+
 var nodeNames = [...]string{
 	NodeBadDecl:          "NodeBadDecl",
 	NodeEntry:            "NodeEntry",
@@ -42,8 +48,15 @@ var nodeNames = [...]string{
 	NodeCommentGroupExpr: "NodeCommentGroupExpr",
 }
 
+// Node is a node of the syntax tree. Eq reports whether the node equals
+// another node of the same type field by field, ignoring source positions.
+// Nil and empty slices are equal, and so are a nil comment group and an
+// empty one, for both stand for no comments. Other nil nodes equal only
+// nil nodes of the same type. Kind names the type of the node, while
+// String renders the node as BibTeX.
 type Node interface {
 	Type() NodeT
+	Kind() string
 	Eq(Node) bool
 }
 
@@ -57,22 +70,26 @@ type (
 		CiteKey  string
 		Comments *CommentGroupExpr
 		Fields   []*FieldStmt
+		Pos      scan.Pos
 	}
 
 	AbbrevDecl struct {
 		Comments *CommentGroupExpr
 		Field    *FieldStmt
+		Pos      scan.Pos
 	}
 
 	PreambleDecl struct {
 		Comments *CommentGroupExpr
 		Value    string
+		Pos      scan.Pos
 	}
 
 	BadDecl struct{}
 
 	FieldStmt struct {
 		Key, Value string
+		Pos        scan.Pos
 	}
 
 	BadStmt struct{}
@@ -88,41 +105,104 @@ type (
 	BadExpr struct{}
 )
 
+// Positioner is implemented by scanners reporting the source position of
+// the item last returned.
+type positioner interface {
+	Pos() scan.Pos
+}
+
+// Failer is implemented by scanners telling why they failed when the reason
+// is other than invalid syntax, such as scan.ErrDepth.
+type failer interface {
+	Err() error
+}
+
 type Parser struct {
+	// Logger, when set, receives a debug event for each declaration parsed
+	// and for the end of the input or the failure that stopped the parser.
+	Logger *slog.Logger
+	// Progress, when set, is told of each entry parsed.
+	Progress progress.Reporter
+
+	failure  error
+	failPos  scan.Pos
 	scanner  scan.Scannable
+	declPos  scan.Pos
 	nodes    chan Node
 	comments *CommentGroupExpr
 	currDecl Node
 	states   map[state]func(*Parser) state
 	state    state
+	decls    int
+}
+
+// States maps each parser state to the function handling it.
+var states = map[state]func(*Parser) state{
+	null:     (*Parser).null,
+	comms:    (*Parser).comms,
+	decl:     (*Parser).decl,
+	entry:    (*Parser).entry,
+	preamble: (*Parser).preamble,
+	abbrev:   (*Parser).abbrev,
+	err:      (*Parser).err,
+	eof:      (*Parser).eof,
 }
 
-func NewParser(s scan.Scannable) *Parser {
-	return &Parser{
-		scanner: s,
-		nodes:   make(chan Node, 2),
-		states: map[state]func(*Parser) state{
-			null:     (*Parser).null,
-			comms:    (*Parser).comms,
-			decl:     (*Parser).decl,
-			entry:    (*Parser).entry,
-			preamble: (*Parser).preamble,
-			abbrev:   (*Parser).abbrev,
-			err:      (*Parser).err,
-			eof:      (*Parser).eof,
-		},
+// NewParser creates a parser reading the items of s, configured with the
+// options applied in order.
+func NewParser(s scan.Scannable, opts ...Option) *Parser {
+	p := &Parser{states: states}
+	p.Reset(s)
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Reset discards the state of the parser and makes it read from s instead,
+// so that a parser can be kept in a sync.Pool and reused for many inputs.
+// The nodes returned before remain valid.
+func (p *Parser) Reset(s scan.Scannable) {
+	nodes := p.nodes
+	if p.state == eof || p.state == err || nodes == nil {
+		// The channel is closed once the parser is done.
+		nodes = make(chan Node, 2)
+	}
+	for len(nodes) > 0 {
+		<-nodes
+	}
+	*p = Parser{
+		Logger:   p.Logger,
+		Progress: p.Progress,
+		scanner:  s,
+		nodes:    nodes,
 		comments: new(CommentGroupExpr),
+		states:   p.states,
 		state:    null,
 	}
 }
 
-func (*EntryDecl) Type() NodeT      { return NodeEntry }
-func (e *EntryDecl) String() string { return nodeNames[e.Type()] }
+func (*EntryDecl) Type() NodeT    { return NodeEntry }
+func (e *EntryDecl) Kind() string { return nodeNames[e.Type()] }
+
+// String renders the entry as BibTeX on a single line without its comments.
+func (e *EntryDecl) String() string {
+	if e == nil {
+		return "<nil>"
+	}
+	var b strings.Builder
+	b.WriteString("@" + e.Name + "{" + e.CiteKey)
+	for _, f := range e.Fields {
+		b.WriteString(", " + f.String())
+	}
+	b.WriteString("}")
+	return b.String()
+}
 
 func (e *EntryDecl) Eq(n Node) bool {
 	d, ok := n.(*EntryDecl)
-	if !ok {
-		return false
+	if !ok || e == nil || d == nil {
+		return ok && e == d
 	}
 	if e.Name != d.Name {
 		return false
@@ -133,33 +213,137 @@ func (e *EntryDecl) Eq(n Node) bool {
 	if !e.Comments.Eq(d.Comments) {
 		return false
 	}
-	if !reflect.DeepEqual(e.Fields, d.Fields) {
+	if len(e.Fields) != len(d.Fields) {
 		return false
 	}
+	// Fields are compared by their keys and values, ignoring positions.
+	for i, f := range e.Fields {
+		if !f.Eq(d.Fields[i]) {
+			return false
+		}
+	}
 	return true
 }
 
-func (*AbbrevDecl) Type() NodeT      { return NodeAbbrev }
-func (a *AbbrevDecl) String() string { return nodeNames[a.Type()] }
+// Get returns the first field whose key matches the key case-insensitively.
+func (e *EntryDecl) Get(key string) (*FieldStmt, bool) {
+	for _, f := range e.Fields {
+		if strings.EqualFold(f.Key, key) {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// Set updates the value of the field under the key or appends a new one.
+func (e *EntryDecl) Set(key, value string) {
+	if f, ok := e.Get(key); ok {
+		f.Value = value
+		return
+	}
+	e.Fields = append(e.Fields, &FieldStmt{Key: key, Value: value})
+}
+
+// Del removes all fields under the key and reports if any was removed.
+func (e *EntryDecl) Del(key string) bool {
+	fields := e.Fields[:0]
+	for _, f := range e.Fields {
+		if !strings.EqualFold(f.Key, key) {
+			fields = append(fields, f)
+		}
+	}
+	ok := len(fields) != len(e.Fields)
+	e.Fields = fields
+	return ok
+}
+
+// Equivalent reports whether the entries are logically the same regardless of
+// the order of fields, the case of the entry type, field names and their
+// aliases listed in Aliases, the delimiters of values, white space, and
+// comments. Cite keys must match exactly. A nil entry is equivalent to
+// another nil entry only.
+func (e *EntryDecl) Equivalent(other *EntryDecl) bool {
+	if e == nil || other == nil {
+		return e == other
+	}
+	if e.CiteKey != other.CiteKey || !strings.EqualFold(e.Name, other.Name) {
+		return false
+	}
+	if len(e.Fields) != len(other.Fields) {
+		return false
+	}
+	counts := make(map[[2]string]int)
+	for _, f := range e.Fields {
+		counts[canonicalField(f)]++
+	}
+	for _, f := range other.Fields {
+		k := canonicalField(f)
+		if counts[k] == 0 {
+			return false
+		}
+		counts[k]--
+	}
+	return true
+}
+
+func canonicalField(f *FieldStmt) [2]string {
+	return [2]string{CanonicalKey(f.Key), CanonicalValue(f.Value)}
+}
+
+// CanonicalValue returns the raw field value with white space collapsed and
+// delimited with braces rather than quotation marks. Bare numbers are
+// enclosed in braces too.
+func CanonicalValue(v string) string {
+	v = strings.Join(strings.Fields(v), " ")
+	if inner := Unquote(v); inner != v || isNumber(v) {
+		v = Quote(strings.TrimSpace(inner))
+	}
+	return v
+}
+
+func (*AbbrevDecl) Type() NodeT    { return NodeAbbrev }
+func (a *AbbrevDecl) Kind() string { return nodeNames[a.Type()] }
+
+// String renders the @string declaration as BibTeX without its comments.
+func (a *AbbrevDecl) String() string {
+	if a == nil {
+		return "<nil>"
+	}
+	if a.Field == nil {
+		return "@string{}"
+	}
+	return "@string{" + a.Field.String() + "}"
+}
 
 func (a *AbbrevDecl) Eq(n Node) bool {
 	d, ok := n.(*AbbrevDecl)
-	if !ok {
-		return false
+	if !ok || a == nil || d == nil {
+		return ok && a == d
 	}
 	if !a.Field.Eq(d.Field) {
 		return false
 	}
+	if !a.Comments.Eq(d.Comments) {
+		return false
+	}
 	return true
 }
 
-func (*PreambleDecl) Type() NodeT      { return NodePreamble }
-func (p *PreambleDecl) String() string { return nodeNames[p.Type()] }
+func (*PreambleDecl) Type() NodeT    { return NodePreamble }
+func (p *PreambleDecl) Kind() string { return nodeNames[p.Type()] }
+
+// String renders the @preamble declaration as BibTeX without its comments.
+func (p *PreambleDecl) String() string {
+	if p == nil {
+		return "<nil>"
+	}
+	return "@preamble{" + p.Value + "}"
+}
 
 func (p *PreambleDecl) Eq(n Node) bool {
 	d, ok := n.(*PreambleDecl)
-	if !ok {
-		return false
+	if !ok || p == nil || d == nil {
+		return ok && p == d
 	}
 	if p.Value != d.Value {
 		return false
@@ -171,7 +355,8 @@ func (p *PreambleDecl) Eq(n Node) bool {
 }
 
 func (*BadDecl) Type() NodeT      { return NodeBadDecl }
-func (b *BadDecl) String() string { return nodeNames[b.Type()] }
+func (b *BadDecl) Kind() string   { return nodeNames[b.Type()] }
+func (b *BadDecl) String() string { return b.Kind() }
 
 func (b *BadDecl) Eq(n Node) bool {
 	if _, ok := n.(*BadDecl); !ok {
@@ -180,13 +365,21 @@ func (b *BadDecl) Eq(n Node) bool {
 	return true
 }
 
-func (*FieldStmt) Type() NodeT      { return NodeFieldStmt }
-func (f *FieldStmt) String() string { return nodeNames[f.Type()] }
+func (*FieldStmt) Type() NodeT    { return NodeFieldStmt }
+func (f *FieldStmt) Kind() string { return nodeNames[f.Type()] }
+
+// String renders the field as BibTeX.
+func (f *FieldStmt) String() string {
+	if f == nil {
+		return "<nil>"
+	}
+	return f.Key + " = " + f.Value
+}
 
 func (f *FieldStmt) Eq(n Node) bool {
 	d, ok := n.(*FieldStmt)
-	if !ok {
-		return false
+	if !ok || f == nil || d == nil {
+		return ok && f == d
 	}
 	if f.Key != d.Key {
 		return false
@@ -206,7 +399,8 @@ func (f *FieldStmt) ok() bool {
 }
 
 func (*BadStmt) Type() NodeT      { return NodeBadStmt }
-func (b *BadStmt) String() string { return nodeNames[b.Type()] }
+func (b *BadStmt) Kind() string   { return nodeNames[b.Type()] }
+func (b *BadStmt) String() string { return b.Kind() }
 
 func (b *BadStmt) Eq(n Node) bool {
 	if _, ok := n.(*BadStmt); !ok {
@@ -215,27 +409,58 @@ func (b *BadStmt) Eq(n Node) bool {
 	return true
 }
 
-func (*CommentGroupExpr) Type() NodeT      { return NodeCommentGroupExpr }
-func (c *CommentGroupExpr) String() string { return nodeNames[c.Type()] }
+func (*CommentGroupExpr) Type() NodeT    { return NodeCommentGroupExpr }
+func (c *CommentGroupExpr) Kind() string { return nodeNames[c.Type()] }
+
+// String renders the comments one per line.
+func (c *CommentGroupExpr) String() string {
+	lines := []string{}
+	for _, v := range c.values() {
+		lines = append(lines, v.String())
+	}
+	return strings.Join(lines, "\n")
+}
 
 func (c *CommentGroupExpr) Eq(n Node) bool {
 	d, ok := n.(*CommentGroupExpr)
 	if !ok {
 		return false
 	}
-	if !reflect.DeepEqual(c.Values, d.Values) {
+	cs, ds := c.values(), d.values()
+	if len(cs) != len(ds) {
 		return false
 	}
+	for i, v := range cs {
+		if !v.Eq(ds[i]) {
+			return false
+		}
+	}
 	return true
 }
 
-func (*CommentExpr) Type() NodeT      { return NodeCommentExpr }
-func (c *CommentExpr) String() string { return nodeNames[c.Type()] }
+// Values returns the comments of the group, which are none for a nil group.
+func (c *CommentGroupExpr) values() []*CommentExpr {
+	if c == nil {
+		return nil
+	}
+	return c.Values
+}
+
+func (*CommentExpr) Type() NodeT    { return NodeCommentExpr }
+func (c *CommentExpr) Kind() string { return nodeNames[c.Type()] }
+
+// String renders the comment as it reads in the source.
+func (c *CommentExpr) String() string {
+	if c == nil {
+		return "<nil>"
+	}
+	return c.Value
+}
 
 func (c *CommentExpr) Eq(n Node) bool {
 	d, ok := n.(*CommentExpr)
-	if !ok {
-		return false
+	if !ok || c == nil || d == nil {
+		return ok && c == d
 	}
 	if c.Value != d.Value {
 		return false
@@ -244,7 +469,8 @@ func (c *CommentExpr) Eq(n Node) bool {
 }
 
 func (*BadExpr) Type() NodeT      { return NodeBadExpr }
-func (b *BadExpr) String() string { return nodeNames[b.Type()] }
+func (b *BadExpr) Kind() string   { return nodeNames[b.Type()] }
+func (b *BadExpr) String() string { return b.Kind() }
 
 func (b *BadExpr) Eq(n Node) bool {
 	if _, ok := n.(*BadExpr); !ok {
@@ -264,6 +490,23 @@ func (p *Parser) Next() (Node, bool) {
 	}
 }
 
+// Err returns the error that stopped the parser or nil if the parser reached
+// the end of the input.
+func (p *Parser) Err() error { return p.failure }
+
+// ErrPos returns the position of the item at which the parser failed, or the
+// zero value if it did not fail or the scanner does not track positions.
+func (p *Parser) ErrPos() scan.Pos { return p.failPos }
+
+// Pos returns the position of the item last read from the scanner if the
+// scanner tracks positions.
+func (p *Parser) pos() scan.Pos {
+	if s, ok := p.scanner.(positioner); ok {
+		return s.Pos()
+	}
+	return scan.Pos{}
+}
+
 func (p *Parser) resetComms() { p.comments = new(CommentGroupExpr) }
 
 func (p *Parser) resetDecl() { p.currDecl = nil }
@@ -274,18 +517,59 @@ func (p *Parser) null() state {
 
 func (p *Parser) err() state {
 	defer close(p.nodes)
+	p.failure = ErrSyntax
+	if s, ok := p.scanner.(failer); ok && s.Err() != nil {
+		p.failure = s.Err()
+	}
+	p.failPos = p.pos()
+	p.log("parse failed", slog.Any("err", p.failure), slog.Int("line", p.failPos.Line), slog.Int("column", p.failPos.Column))
 	return err
 }
 
 func (p *Parser) eof() state {
 	defer close(p.nodes)
+	p.log("parse done", slog.Int("declarations", p.decls))
 	return eof
 }
 
+// Parsed logs the declaration parsed and reports the progress of entries.
+func (p *Parser) parsed(n Node) {
+	var attrs []slog.Attr
+	switch d := n.(type) {
+	case *EntryDecl:
+		attrs = []slog.Attr{slog.String("type", d.Name), slog.String("key", d.CiteKey), slog.Int("line", d.Pos.Line)}
+		if p.Progress != nil {
+			p.Progress.Entries(1)
+		}
+	case *AbbrevDecl:
+		attrs = []slog.Attr{slog.String("type", "string"), slog.Int("line", d.Pos.Line)}
+		if d.Field != nil {
+			attrs = append(attrs, slog.String("key", d.Field.Key))
+		}
+	case *PreambleDecl:
+		attrs = []slog.Attr{slog.String("type", "preamble"), slog.Int("line", d.Pos.Line)}
+	default:
+		return
+	}
+	p.decls++
+	p.log("declaration parsed", attrs...)
+}
+
+func (p *Parser) log(msg string, attrs ...slog.Attr) {
+	if p.Logger != nil {
+		p.Logger.LogAttrs(context.Background(), slog.LevelDebug, msg, attrs...)
+	}
+}
+
 func (p *Parser) comms() state {
 	for {
 		i := p.scanner.Next()
 		if state := checkErr(i.T); state != null {
+			// Comments trailing the last declaration are emitted on their own
+			if state == eof && len(p.comments.Values) > 0 {
+				p.nodes <- p.comments
+				p.resetComms()
+			}
 			return state
 		}
 		switch i.T {
@@ -293,6 +577,7 @@ func (p *Parser) comms() state {
 			v := CommentExpr{i.Val}
 			p.comments.Values = append(p.comments.Values, &v)
 		case scan.ItemEntryDelim:
+			p.declPos = p.pos()
 			return decl
 		default:
 			p.resetComms()
@@ -303,23 +588,28 @@ func (p *Parser) comms() state {
 
 func (p *Parser) decl() state {
 	i := p.scanner.Next()
-	if state := checkErr(i.T); state != null {
+	if state := checkDeclErr(i.T); state != null {
 		return state
 	}
 	switch i.T {
 	case scan.ItemEntry:
 		lower := strings.ToLower(i.Val)
-		decl := EntryDecl{Name: lower}
+		decl := EntryDecl{Name: lower, Pos: p.declPos}
 		p.currDecl = &decl
 		return entry
 	case scan.ItemAbbrev:
-		decl := AbbrevDecl{}
+		decl := AbbrevDecl{Pos: p.declPos}
 		p.currDecl = &decl
 		return abbrev
 	case scan.ItemPreamble:
-		decl := PreambleDecl{}
+		decl := PreambleDecl{Pos: p.declPos}
 		p.currDecl = &decl
 		return preamble
+	case scan.ItemComment:
+		// @comment declarations are kept verbatim among the comments.
+		v := CommentExpr{i.Val}
+		p.comments.Values = append(p.comments.Values, &v)
+		return comms
 	}
 	return err
 }
@@ -335,13 +625,13 @@ func (p *Parser) entry() state {
 
 	// Consume body delimiter
 	i = p.scanner.Next()
-	if state := checkErr(i.T); state != null {
+	if state := checkDeclErr(i.T); state != null {
 		return state
 	}
 
 	// Attempt to assign cite key to the declaration
 	i = p.scanner.Next()
-	if state := checkErr(i.T); state != null {
+	if state := checkDeclErr(i.T); state != null {
 		return state
 	}
 	if i.T != scan.ItemCiteKey {
@@ -351,7 +641,7 @@ func (p *Parser) entry() state {
 
 	for {
 		i = p.scanner.Next()
-		if state := checkErr(i.T); state != null {
+		if state := checkDeclErr(i.T); state != null {
 			return state
 		}
 		switch i.T {
@@ -360,6 +650,7 @@ func (p *Parser) entry() state {
 			p.comments.Values = append(p.comments.Values, &v)
 		case scan.ItemFieldType:
 			stmt.Key = i.Val
+			stmt.Pos = p.pos()
 		case scan.ItemFieldText:
 			stmt.Value = i.Val
 			if !stmt.ok() {
@@ -370,6 +661,7 @@ func (p *Parser) entry() state {
 		case scan.ItemRightDelim:
 			decl.Comments = p.comments
 			p.resetComms()
+			p.parsed(decl)
 			p.nodes <- decl
 			return null
 		case scan.ItemComma, scan.ItemEqSgn: // consume
@@ -388,13 +680,13 @@ func (p *Parser) preamble() state {
 
 	// Consume body delimiter
 	i = p.scanner.Next()
-	if state := checkErr(i.T); state != null {
+	if state := checkDeclErr(i.T); state != null {
 		return state
 	}
 
 	for {
 		i = p.scanner.Next()
-		if state := checkErr(i.T); state != null {
+		if state := checkDeclErr(i.T); state != null {
 			return state
 		}
 		switch i.T {
@@ -406,6 +698,7 @@ func (p *Parser) preamble() state {
 		case scan.ItemRightDelim:
 			decl.Comments = p.comments
 			p.resetComms()
+			p.parsed(decl)
 			p.nodes <- decl
 			return null
 		default:
@@ -425,13 +718,13 @@ func (p *Parser) abbrev() state {
 
 	// Consume body delimiter
 	i = p.scanner.Next()
-	if state := checkErr(i.T); state != null {
+	if state := checkDeclErr(i.T); state != null {
 		return state
 	}
 
 	for {
 		i = p.scanner.Next()
-		if state := checkErr(i.T); state != null {
+		if state := checkDeclErr(i.T); state != null {
 			return state
 		}
 		switch i.T {
@@ -440,6 +733,7 @@ func (p *Parser) abbrev() state {
 			p.comments.Values = append(p.comments.Values, &v)
 		case scan.ItemFieldType:
 			stmt.Key = i.Val
+			stmt.Pos = p.pos()
 		case scan.ItemFieldText:
 			stmt.Value = i.Val
 			if !stmt.ok() {
@@ -449,6 +743,7 @@ func (p *Parser) abbrev() state {
 		case scan.ItemRightDelim:
 			decl.Comments = p.comments
 			p.resetComms()
+			p.parsed(decl)
 			p.nodes <- decl
 			return null
 		case scan.ItemEqSgn: // consume
@@ -458,6 +753,60 @@ func (p *Parser) abbrev() state {
 	}
 }
 
+// Unquote strips the outermost pair of braces or quotation marks from the
+// field value.
+func Unquote(v string) string {
+	if isEnclosed(v) {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
+
+// Quote encloses the field value in braces.
+func Quote(v string) string {
+	return "{" + v + "}"
+}
+
+// IsEnclosed checks if the opening delimiter of the value is closed by its
+// very last character so that strings like `{a} # {b}` are left intact.
+func isEnclosed(v string) bool {
+	if len(v) < 2 {
+		return false
+	}
+	open, last := v[0], len(v)-1
+	if !(open == '{' && v[last] == '}') && !(open == '"' && v[last] == '"') {
+		return false
+	}
+	braces := 0
+	for i := 1; i < last; i++ {
+		switch v[i] {
+		case '\\':
+			i++
+		case '{':
+			braces++
+		case '}':
+			braces--
+			if braces < 0 {
+				return false
+			}
+		case '"':
+			if open == '"' && braces == 0 {
+				return false
+			}
+		}
+	}
+	return braces == 0
+}
+
+// CheckDeclErr is like checkErr but treats the end of file inside of
+// a declaration as an error.
+func checkDeclErr(t scan.ItemType) state {
+	if t == scan.ItemErr || t == scan.ItemEOF {
+		return err
+	}
+	return null
+}
+
 func checkErr(t scan.ItemType) state {
 	if t == scan.ItemErr {
 		return err
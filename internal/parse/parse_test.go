@@ -1,6 +1,8 @@
 package parse
 
 import (
+	"fmt"
+	"log/slog"
 	"strings"
 	"testing"
 
@@ -145,3 +147,366 @@ func TestParsedDecl(t *testing.T) {
 		})
 	}
 }
+
+func TestUnquote(t *testing.T) {
+	cases := []struct {
+		name      string
+		testInput string
+		want      string
+	}{
+		{"braces", "{The title}", "The title"},
+		{"quotes", `"P. J. C{\"o}hen"`, `P. J. C{\"o}hen`},
+		{"integer", "1963", "1963"},
+		{"macro", "jan", "jan"},
+		{"concatenated", "{a} # {b}", "{a} # {b}"},
+		{"quoted-concatenated", `"a" # "b"`, `"a" # "b"`},
+		{"empty", "{}", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := Unquote(c.testInput); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestParserErr(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		nodes  int
+		want   error
+		pos    scan.Pos
+	}{
+		{"valid", haveEntryOne + havePreamble, 2, nil, scan.Pos{}},
+		{"empty", "", 0, nil, scan.Pos{}},
+		{"trailing-comments", haveAbbrev + "\n% The end.\n", 2, nil, scan.Pos{}},
+		{"truncated", "@book{bookExample,\n  title = {The title}", 0, ErrSyntax, scan.Pos{Line: 2, Column: 21}},
+		{"invalid-key", "@book{book Example,\n  title = {The title}}", 0, ErrSyntax, scan.Pos{Line: 1, Column: 19}},
+		{"after-valid", haveEntryTwo + "@misc{", 1, ErrSyntax, scan.Pos{Line: 11, Column: 6}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := NewParser(scan.NewScanner(scan.NewReader(strings.NewReader(c.source))))
+			nodes := 0
+			for _, ok := p.Next(); ok; _, ok = p.Next() {
+				nodes++
+			}
+			if nodes != c.nodes {
+				t.Errorf("have %d nodes; want %d", nodes, c.nodes)
+			}
+			if have := p.Err(); have != c.want {
+				t.Errorf("have %v; want %v", have, c.want)
+			}
+			if have := p.ErrPos(); have != c.pos {
+				t.Errorf("have position %v; want %v", have, c.pos)
+			}
+		})
+	}
+}
+
+func TestParserMaxDepth(t *testing.T) {
+	cases := []struct {
+		name  string
+		depth int
+		want  error
+	}{
+		{"unlimited", 0, nil},
+		{"deep-enough", 4, nil},
+		{"too-deep", 3, scan.ErrDepth},
+	}
+	src := "@misc{key, title = {A {B {C {D}}}}, note = {E}}"
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := NewParser(scan.NewScanner(scan.NewReader(strings.NewReader(src)), scan.WithMaxDepth(c.depth)))
+			for _, ok := p.Next(); ok; _, ok = p.Next() {
+			}
+			if have := p.Err(); have != c.want {
+				t.Errorf("have %v; want %v", have, c.want)
+			}
+			if have, want := p.ErrPos().IsValid(), c.want != nil; have != want {
+				t.Errorf("have error position %v", p.ErrPos())
+			}
+		})
+	}
+}
+
+func TestParserLogger(t *testing.T) {
+	var b strings.Builder
+	p := NewParser(scan.NewScanner(scan.NewReader(strings.NewReader(haveAbbrev + haveEntryOne + "@misc{"))))
+	p.Logger = slog.New(slog.NewTextHandler(&b, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	for _, ok := p.Next(); ok; _, ok = p.Next() {
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	want := []string{
+		`msg="declaration parsed" type=string`,
+		`msg="declaration parsed" type=book key=bookExample line=`,
+		`msg="parse failed" err="parse: BIBX0001 invalid BibTeX syntax"`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("have %q; want %d events", lines, len(want))
+	}
+	for i := range want {
+		if !strings.Contains(lines[i], want[i]) {
+			t.Errorf("have %q; want %s", lines[i], want[i])
+		}
+	}
+}
+
+type entryCounter int
+
+func (c *entryCounter) Read(int)       {}
+func (c *entryCounter) Entries(n int)  { *c += entryCounter(n) }
+func (c *entryCounter) Files(int, int) {}
+
+func TestParserProgress(t *testing.T) {
+	var c entryCounter
+	p := NewParser(scan.NewScanner(scan.NewReader(strings.NewReader(haveAbbrev + haveEntryOne + havePreamble + haveEntryTwo))))
+	p.Progress = &c
+	for _, ok := p.Next(); ok; _, ok = p.Next() {
+	}
+	if c != 2 {
+		t.Errorf("have %d entries; want 2", c)
+	}
+}
+
+func TestParserReset(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		read   int
+	}{
+		{"done", haveEntryOne, -1},
+		{"failed", "@misc{", -1},
+		{"midway", haveEntryOne + haveEntryTwo + havePreamble, 1},
+		{"unread", haveAbbrev, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := scan.NewScanner(scan.NewReader(strings.NewReader(c.source)))
+			p := NewParser(s)
+			for i := 0; i != c.read; i++ {
+				if _, ok := p.Next(); !ok {
+					break
+				}
+			}
+			s.Reset(scan.NewBytesReader([]byte(haveEntryTwo)))
+			p.Reset(s)
+			nodes := []Node{}
+			for n, ok := p.Next(); ok; n, ok = p.Next() {
+				nodes = append(nodes, n)
+			}
+			if len(nodes) != 1 || !nodes[0].Eq(wantEntryTwo) {
+				t.Errorf("have %v; want %v", nodes, []Node{wantEntryTwo})
+			}
+			if err := p.Err(); err != nil {
+				t.Errorf("have %v; want nil", err)
+			}
+		})
+	}
+}
+
+func TestEq(t *testing.T) {
+	comment := func(vs ...string) *CommentGroupExpr {
+		g := &CommentGroupExpr{}
+		for _, v := range vs {
+			g.Values = append(g.Values, &CommentExpr{Value: v})
+		}
+		return g
+	}
+	field := &FieldStmt{Key: "title", Value: "{T}", Pos: scan.Pos{Line: 2, Column: 3}}
+	cases := []struct {
+		name string
+		a, b Node
+		want bool
+	}{
+		{"same", &EntryDecl{Name: "book", CiteKey: "a", Fields: []*FieldStmt{field}}, &EntryDecl{Name: "book", CiteKey: "a", Fields: []*FieldStmt{{Key: "title", Value: "{T}"}}}, true},
+		{"positions", &EntryDecl{Name: "book", Pos: scan.Pos{Line: 1, Column: 1}}, &EntryDecl{Name: "book"}, true},
+		{"field-value", &EntryDecl{Fields: []*FieldStmt{field}}, &EntryDecl{Fields: []*FieldStmt{{Key: "title", Value: "{U}"}}}, false},
+		{"nil-empty-fields", &EntryDecl{Fields: nil}, &EntryDecl{Fields: []*FieldStmt{}}, true},
+		{"nil-empty-comments", &EntryDecl{Comments: nil}, &EntryDecl{Comments: comment()}, true},
+		{"nil-comment-values", comment(), &CommentGroupExpr{Values: []*CommentExpr{}}, true},
+		{"comments", &EntryDecl{Comments: comment("% a")}, &EntryDecl{Comments: comment("% b")}, false},
+		{"missing-comments", &PreambleDecl{Comments: comment("% a")}, &PreambleDecl{}, false},
+		{"abbrev-comments", &AbbrevDecl{Field: field, Comments: comment("% a")}, &AbbrevDecl{Field: field}, false},
+		{"abbrev-nil-field", &AbbrevDecl{}, &AbbrevDecl{Field: field}, false},
+		{"nil-comment", &CommentGroupExpr{Values: []*CommentExpr{nil}}, comment("% a"), false},
+		{"nil-nodes", (*EntryDecl)(nil), (*EntryDecl)(nil), true},
+		{"nil-node", (*EntryDecl)(nil), &EntryDecl{}, false},
+		{"nil-interface", &EntryDecl{}, nil, false},
+		{"types", &BadDecl{}, &BadStmt{}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := c.a.Eq(c.b); have != c.want {
+				t.Errorf("have %t; want %t", have, c.want)
+			}
+			if c.b == nil {
+				return
+			}
+			if have := c.b.Eq(c.a); have != c.want {
+				t.Errorf("have %t reversed; want %t", have, c.want)
+			}
+		})
+	}
+}
+
+func BenchmarkEq(b *testing.B) {
+	p := NewParser(scan.NewScanner(scan.NewReader(strings.NewReader(haveEntryOne))))
+	n, _ := p.Next()
+	q := NewParser(scan.NewScanner(scan.NewReader(strings.NewReader(haveEntryOne))))
+	m, _ := q.Next()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !n.Eq(m) {
+			b.Fatal("entries differ")
+		}
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	base := &EntryDecl{
+		Name:    "article",
+		CiteKey: "Cohen1963",
+		Fields: []*FieldStmt{
+			{Key: "author", Value: "{Paul J. Cohen}"},
+			{Key: "title", Value: "{The independence of the {C}ontinuum hypothesis}"},
+			{Key: "year", Value: "1963"},
+			{Key: "month", Value: "dec"},
+		},
+	}
+	cases := []struct {
+		name  string
+		other *EntryDecl
+		want  bool
+	}{
+		{
+			name: "reordered",
+			other: &EntryDecl{
+				Name:     "Article",
+				CiteKey:  "Cohen1963",
+				Comments: &CommentGroupExpr{Values: []*CommentExpr{{Value: "c"}}},
+				Fields: []*FieldStmt{
+					{Key: "MONTH", Value: "dec"},
+					{Key: "Year", Value: `"1963"`},
+					{Key: "title", Value: "{The independence\n   of the {C}ontinuum hypothesis }"},
+					{Key: "author", Value: `"Paul J. Cohen"`},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "different key",
+			other: &EntryDecl{
+				Name:    "article",
+				CiteKey: "cohen1963",
+				Fields:  base.Fields,
+			},
+			want: false,
+		},
+		{
+			name: "different value",
+			other: &EntryDecl{
+				Name:    "article",
+				CiteKey: "Cohen1963",
+				Fields: []*FieldStmt{
+					{Key: "author", Value: "{Paul J. Cohen}"},
+					{Key: "title", Value: "{The independence of the Continuum hypothesis}"},
+					{Key: "year", Value: "1963"},
+					{Key: "month", Value: "dec"},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "macro",
+			other: &EntryDecl{
+				Name:    "article",
+				CiteKey: "Cohen1963",
+				Fields: []*FieldStmt{
+					{Key: "author", Value: "{Paul J. Cohen}"},
+					{Key: "title", Value: "{The independence of the {C}ontinuum hypothesis}"},
+					{Key: "year", Value: "1963"},
+					{Key: "month", Value: "{dec}"},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "missing field",
+			other: &EntryDecl{
+				Name:    "article",
+				CiteKey: "Cohen1963",
+				Fields:  base.Fields[:3],
+			},
+			want: false,
+		},
+		{
+			name:  "nil",
+			other: nil,
+			want:  false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := base.Equivalent(c.other); have != c.want {
+				t.Errorf("have %v; want %v", have, c.want)
+			}
+		})
+	}
+	var e *EntryDecl
+	if !e.Equivalent(nil) {
+		t.Error("have false; want true for nil entries")
+	}
+}
+
+func TestParsedPos(t *testing.T) {
+	src := "@string{jx = {J. X}}\n\n@book{key,\n  title = {T},\n  year  = 1993\n}\n"
+	p := NewParser(scan.NewScanner(scan.NewReader(strings.NewReader(src))))
+	have := []string{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		switch d := n.(type) {
+		case *AbbrevDecl:
+			have = append(have, d.Pos.String(), d.Field.Pos.String())
+		case *EntryDecl:
+			have = append(have, d.Pos.String())
+			for _, f := range d.Fields {
+				have = append(have, f.Pos.String())
+			}
+		}
+	}
+	want := []string{"1:1", "1:9", "3:1", "4:3", "5:3"}
+	if strings.Join(have, " ") != strings.Join(want, " ") {
+		t.Errorf("have %v; want %v", have, want)
+	}
+}
+
+func TestNodeString(t *testing.T) {
+	cases := []struct {
+		node       Node
+		kind, want string
+	}{
+		{
+			&EntryDecl{Name: "article", CiteKey: "Cohen1963", Fields: []*FieldStmt{{Key: "author", Value: "{Paul Cohen}"}, {Key: "year", Value: "1963"}}},
+			"NodeEntry",
+			"@article{Cohen1963, author = {Paul Cohen}, year = 1963}",
+		},
+		{&EntryDecl{Name: "misc", CiteKey: "empty"}, "NodeEntry", "@misc{empty}"},
+		{&AbbrevDecl{Field: &FieldStmt{Key: "jx", Value: `"J. X"`}}, "NodeAbbrev", `@string{jx = "J. X"}`},
+		{&PreambleDecl{Value: `"\noop"`}, "NodePreamble", `@preamble{"\noop"}`},
+		{&CommentGroupExpr{Values: []*CommentExpr{{Value: "% one"}, {Value: "% two"}}}, "NodeCommentGroupExpr", "% one\n% two"},
+		{&BadDecl{}, "NodeBadDecl", "NodeBadDecl"},
+		{(*EntryDecl)(nil), "NodeEntry", "<nil>"},
+	}
+	for _, c := range cases {
+		t.Run(c.want, func(t *testing.T) {
+			if have := c.node.Kind(); have != c.kind {
+				t.Errorf("have kind %s; want %s", have, c.kind)
+			}
+			if have := fmt.Sprint(c.node); have != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
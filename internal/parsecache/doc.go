@@ -0,0 +1,6 @@
+/*
+Parsecache package keeps the declarations parsed from files on disk, so that
+repeated runs over a large library that has not changed since skip parsing it
+altogether.
+*/
+package parsecache
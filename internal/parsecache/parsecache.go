@@ -0,0 +1,149 @@
+package parsecache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// Version is bumped whenever the declarations the parser yields or their
+// encoding change, so that the entries stored by older versions are missed.
+const version = 2
+
+// DefaultMaxAge is how long the entries of a cache are kept unused unless
+// its MaxAge says otherwise.
+const DefaultMaxAge = 30 * 24 * time.Hour
+
+func init() {
+	gob.Register(&parse.EntryDecl{})
+	gob.Register(&parse.AbbrevDecl{})
+	gob.Register(&parse.PreambleDecl{})
+	gob.Register(&parse.CommentGroupExpr{})
+}
+
+// Cache stores the declarations parsed from sources in the Dir directory.
+// Entries are keyed by a hash of the source, so a source that has changed is
+// missed wherever it is read from, and entries not used for MaxAge, or
+// DefaultMaxAge when it is 0, are removed by Prune. A failure to read or
+// write the cache only costs the parse it would have saved.
+type Cache struct {
+	Dir    string
+	MaxAge time.Duration
+}
+
+// Key returns the key of the source.
+func Key(src []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00", version)
+	h.Write(src)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Load returns the declarations stored under the key, marking the entry as
+// used.
+func (c *Cache) Load(key string) ([]parse.Node, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	now := time.Now()
+	os.Chtimes(c.path(key), now, now)
+	var nodes []parse.Node
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&nodes); err != nil {
+		return nil, false
+	}
+	for _, n := range nodes {
+		restore(n)
+	}
+	return nodes, true
+}
+
+// Store stores the declarations under the key. The entry is written to a
+// temporary file first so that concurrent runs never read it half-written.
+func (c *Cache) Store(key string, nodes []parse.Node) error {
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(nodes); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.Dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+// Prune removes the entries unused for longer than the MaxAge of the cache,
+// along with the temporary files left behind by runs cut short.
+func (c *Cache) Prune() error {
+	maxAge := c.MaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	des, err := os.ReadDir(c.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, de := range des {
+		name := de.Name()
+		if !strings.HasSuffix(name, ".gob") && !strings.HasPrefix(name, ".tmp-") {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil || time.Since(info.ModTime()) <= maxAge {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.Dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Dir, key+".gob")
+}
+
+// Restore brings back the empty comment groups and field lists the parser
+// yields, which the encoding leaves out.
+func restore(n parse.Node) {
+	switch d := n.(type) {
+	case *parse.EntryDecl:
+		if d.Comments == nil {
+			d.Comments = &parse.CommentGroupExpr{}
+		}
+		if d.Fields == nil {
+			d.Fields = []*parse.FieldStmt{}
+		}
+	case *parse.AbbrevDecl:
+		if d.Comments == nil {
+			d.Comments = &parse.CommentGroupExpr{}
+		}
+	case *parse.PreambleDecl:
+		if d.Comments == nil {
+			d.Comments = &parse.CommentGroupExpr{}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package parsecache
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mdm-code/bibx/bibxtest"
+)
+
+const testBib = `% Library
+@string{jx = {J. X}}
+@preamble{"Preamble"}
+
+% A comment on the entry.
+@article{Cohen1963,
+  author  = {Paul Cohen},
+  journal = jx,
+  year    = 1963
+}
+@misc{empty, note = {Zażółć}}
+
+% The end.
+`
+
+func TestCache(t *testing.T) {
+	c := &Cache{Dir: filepath.Join(t.TempDir(), "cache")}
+	key := Key([]byte(testBib))
+	if _, ok := c.Load(key); ok {
+		t.Fatal("have entry in an empty cache")
+	}
+	want := bibxtest.Parse(t, []byte(testBib))
+	if err := c.Store(key, want); err != nil {
+		t.Fatal(err)
+	}
+	have, ok := c.Load(key)
+	if !ok {
+		t.Fatal("missing entry stored")
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v; want %v", have, want)
+	}
+
+	// A change to the source makes a new key.
+	changed := Key([]byte(testBib + "\n"))
+	if changed == key {
+		t.Error("have the same key after the source changed")
+	}
+	if _, ok := c.Load(changed); ok {
+		t.Error("have entry for the changed source")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	c := &Cache{Dir: t.TempDir(), MaxAge: time.Hour}
+	nodes := bibxtest.Parse(t, []byte(testBib))
+	for _, key := range []string{"old", "used", "new"} {
+		if err := c.Store(key, nodes); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tmp := filepath.Join(c.Dir, ".tmp-1")
+	if err := os.WriteFile(tmp, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-2 * time.Hour)
+	for _, name := range []string{c.path("old"), c.path("used"), tmp} {
+		if err := os.Chtimes(name, past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := c.Load("used"); !ok {
+		t.Fatal("missing entry stored")
+	}
+	if err := c.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name string
+		want bool
+	}{
+		{c.path("old"), false},
+		{c.path("used"), true},
+		{c.path("new"), true},
+		{tmp, false},
+	}
+	for _, tc := range cases {
+		_, err := os.Stat(tc.name)
+		if have := err == nil; have != tc.want {
+			t.Errorf("%s: have kept %t; want %t", filepath.Base(tc.name), have, tc.want)
+		}
+	}
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	c := &Cache{Dir: t.TempDir()}
+	if err := os.WriteFile(c.path("k"), []byte("not gob"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Load("k"); ok {
+		t.Error("have entry decoded from garbage")
+	}
+}
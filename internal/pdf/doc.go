@@ -0,0 +1,8 @@
+/*
+Pdf package extracts bibliographic metadata from PDF files: the document
+information dictionary, the XMP metadata stream, and optionally a DOI printed
+in the text. Compressed object streams are read, but the document structure
+is not otherwise interpreted, so text extraction is best-effort and only
+works with fonts that keep the text readable.
+*/
+package pdf
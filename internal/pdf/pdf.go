@@ -0,0 +1,575 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/mdm-code/bibx/internal/citekey"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+// ErrNotPDF is returned for data without the PDF header.
+var ErrNotPDF = errors.New("pdf: not a PDF file")
+
+// Metadata is the bibliographic metadata of a document. Empty fields are
+// unknown.
+type Metadata struct {
+	Title    string
+	Authors  []string
+	Journal  string
+	Volume   string
+	Number   string
+	Pages    string
+	Year     string
+	DOI      string
+	Keywords string
+}
+
+// Namespaces of the XMP properties read, by their prefixes.
+var namespaces = map[string]string{
+	"http://purl.org/dc/elements/1.1/": "dc",
+	"http://ns.adobe.com/xap/1.0/":     "xmp",
+	"http://ns.adobe.com/pdf/1.3/":     "pdf",
+}
+
+var (
+	objects = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	info    = regexp.MustCompile(`/Info\s+(\d+)\s+\d+\s+R`)
+	// The keyword follows the dictionary, so it is not confused with the
+	// word stream in a string.
+	streamStart = regexp.MustCompile(`>>\s*stream(?:\r\n|\n|\r)`)
+	number      = regexp.MustCompile(`/(N|First)\s+(\d+)`)
+	doi         = regexp.MustCompile(`(?i)\b(10\.\d{4,9}/[-._;()/:A-Z0-9]+[A-Z0-9])`)
+	year        = regexp.MustCompile(`(?:^|\D)((?:1[5-9]|20)\d\d)`)
+	junk        = regexp.MustCompile(`(?i)(^untitled$|^microsoft word - |\.(docx?|pdf|dvi|tex|indd|qxd)$)`)
+	nameSeps    = regexp.MustCompile(`\s*(?:;|&|\band\b)\s*`)
+)
+
+// Read returns the metadata of the PDF file. The metadata stream takes
+// precedence over the information dictionary, and with scan set a DOI
+// missing from both is looked for in the text of the document.
+func Read(r io.Reader, scan bool) (*Metadata, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\r\n\t "), []byte("%PDF-")) {
+		return nil, ErrNotPDF
+	}
+	d := newDocument(data)
+	m := &Metadata{}
+	if x := d.xmp(); x != nil {
+		m.fromXMP(x)
+	}
+	m.fromInfo(d.info())
+	if m.DOI == `` && scan {
+		m.DOI = d.scanDOI()
+	}
+	return m, nil
+}
+
+// Document holds the raw data of the file, the streams found in it and the
+// objects read from its object streams. Streams are inflated only once they
+// are looked at, so that a search ending early skips the rest of them.
+type document struct {
+	data     []byte
+	streams  []rawStream // in file order
+	packed   map[int][]byte
+	unpacked bool // the object streams have been read into packed
+}
+
+// RawStream is the dictionary and the data of a stream object as stored in
+// the file.
+type rawStream struct {
+	dict, body []byte
+}
+
+func newDocument(data []byte) *document {
+	d := &document{data: data, packed: make(map[int][]byte)}
+	for _, loc := range objects.FindAllSubmatchIndex(data, -1) {
+		dict, body := d.object(loc[1])
+		if body == nil {
+			continue
+		}
+		// Only streams that are plain or deflated can be read.
+		if !bytes.Contains(dict, []byte("/FlateDecode")) && bytes.Contains(dict, []byte("/Filter")) {
+			continue
+		}
+		d.streams = append(d.streams, rawStream{dict, body})
+	}
+	return d
+}
+
+// Plain returns the data of the stream, inflated if it is compressed, or nil
+// if it cannot be inflated.
+func (s rawStream) plain() []byte {
+	if !bytes.Contains(s.dict, []byte("/FlateDecode")) {
+		return s.body
+	}
+	plain, err := inflate(s.body)
+	if err != nil {
+		return nil
+	}
+	return plain
+}
+
+// Object returns the dictionary and the stream data of the object starting
+// at the offset, or a nil stream for objects without one.
+func (d *document) object(offset int) (dict, stream []byte) {
+	end := bytes.Index(d.data[offset:], []byte("endobj"))
+	if end < 0 {
+		end = len(d.data) - offset
+	}
+	obj := d.data[offset : offset+end]
+	loc := streamStart.FindIndex(obj)
+	if loc == nil {
+		return obj, nil
+	}
+	stream = obj[loc[1]:]
+	if j := bytes.LastIndex(stream, []byte("endstream")); j >= 0 {
+		stream = stream[:j]
+	}
+	return obj[:loc[0]+2], stream
+}
+
+// UnpackAll records the objects of all the object streams of the document
+// the first time it is called.
+func (d *document) unpackAll() {
+	if d.unpacked {
+		return
+	}
+	d.unpacked = true
+	for _, s := range d.streams {
+		if !bytes.Contains(s.dict, []byte("/ObjStm")) {
+			continue
+		}
+		if plain := s.plain(); plain != nil {
+			d.unpack(s.dict, plain)
+		}
+	}
+}
+
+// Unpack records the objects of an object stream, whose header lists pairs
+// of object numbers and offsets relative to the first object.
+func (d *document) unpack(dict, plain []byte) {
+	var n, first int
+	for _, m := range number.FindAllSubmatch(dict, -1) {
+		v, _ := strconv.Atoi(string(m[2]))
+		if string(m[1]) == "N" {
+			n = v
+		} else {
+			first = v
+		}
+	}
+	if first > len(plain) {
+		return
+	}
+	header := strings.Fields(string(plain[:first]))
+	type entry struct{ num, offset int }
+	entries := []entry{}
+	for i := 0; i+1 < len(header) && len(entries) < n; i += 2 {
+		num, err1 := strconv.Atoi(header[i])
+		offset, err2 := strconv.Atoi(header[i+1])
+		if err1 != nil || err2 != nil {
+			return
+		}
+		entries = append(entries, entry{num, offset})
+	}
+	for i, e := range entries {
+		start, end := first+e.offset, len(plain)
+		if i+1 < len(entries) {
+			end = first + entries[i+1].offset
+		}
+		if start <= end && end <= len(plain) {
+			d.packed[e.num] = plain[start:end]
+		}
+	}
+}
+
+// Lookup returns the body of the object with the number.
+func (d *document) lookup(num int) []byte {
+	pattern := regexp.MustCompile(`(?:^|\D)` + strconv.Itoa(num) + `\s+\d+\s+obj\b`)
+	if loc := pattern.FindIndex(d.data); loc != nil {
+		dict, _ := d.object(loc[1])
+		return dict
+	}
+	d.unpackAll()
+	return d.packed[num]
+}
+
+// Info returns the entries of the document information dictionary holding
+// strings.
+func (d *document) info() map[string]string {
+	result := make(map[string]string)
+	ms := info.FindAllSubmatch(d.data, -1)
+	if len(ms) == 0 {
+		return result
+	}
+	// The last trailer belongs to the latest revision of the file.
+	num, _ := strconv.Atoi(string(ms[len(ms)-1][1]))
+	dict := d.lookup(num)
+	for i := 0; i < len(dict); i++ {
+		if dict[i] != '/' {
+			continue
+		}
+		j := i + 1
+		for j < len(dict) && !bytes.ContainsRune([]byte("/()<>[]{} \t\r\n"), rune(dict[j])) {
+			j++
+		}
+		key := string(dict[i+1 : j])
+		k := j
+		for k < len(dict) && isSpace(dict[k]) {
+			k++
+		}
+		if s, n, ok := pdfString(dict[k:]); ok {
+			result[key] = s
+			i = k + n - 1
+		} else {
+			i = j - 1
+		}
+	}
+	return result
+}
+
+// Xmp returns the XMP metadata packet of the document, which is usually
+// stored uncompressed so that other tools can find it.
+func (d *document) xmp() []byte {
+	for i := -1; i < len(d.streams); i++ {
+		src := d.data
+		if i >= 0 {
+			src = d.streams[i].plain()
+		}
+		start := bytes.Index(src, []byte("<x:xmpmeta"))
+		end := bytes.Index(src, []byte("</x:xmpmeta>"))
+		if start >= 0 && end > start {
+			return src[start : end+len("</x:xmpmeta>")]
+		}
+	}
+	return nil
+}
+
+// ScanDOI returns the first DOI found in the strings shown by the content
+// streams, which follow the order of the pages in most files.
+func (d *document) scanDOI() string {
+	for _, st := range d.streams {
+		s := st.plain()
+		var text strings.Builder
+		for i := 0; i < len(s); i++ {
+			if s[i] != '(' {
+				continue
+			}
+			v, n, ok := literal(s[i:])
+			if !ok {
+				break
+			}
+			text.Write(v)
+			i += n - 1
+		}
+		if m := doi.FindString(text.String()); m != `` {
+			return m
+		}
+	}
+	return ``
+}
+
+// FromXMP fills the metadata from the properties of the XMP packet.
+func (m *Metadata) fromXMP(packet []byte) {
+	props := make(map[string][]string)
+	dec := xml.NewDecoder(bytes.NewReader(packet))
+	current := ``
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if key := property(t.Name); key != `` {
+				current = key
+			}
+			for _, a := range t.Attr {
+				if key := property(a.Name); key != `` {
+					props[key] = append(props[key], a.Value)
+				}
+			}
+		case xml.CharData:
+			if s := strings.TrimSpace(string(t)); current != `` && s != `` {
+				props[current] = append(props[current], s)
+			}
+		case xml.EndElement:
+			if property(t.Name) == current {
+				current = ``
+			}
+		}
+	}
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if vs := props[k]; len(vs) > 0 {
+				return vs[0]
+			}
+		}
+		return ``
+	}
+	if t := first("dc:title"); !junk.MatchString(t) {
+		m.Title = t
+	}
+	m.Authors = props["dc:creator"]
+	m.Journal = first("prism:publicationName")
+	m.Volume = first("prism:volume")
+	m.Number = first("prism:number")
+	m.Pages = first("prism:startingPage")
+	if end := first("prism:endingPage"); m.Pages != `` && end != `` {
+		m.Pages += "--" + end
+	}
+	m.DOI = strings.TrimPrefix(first("prism:doi"), "doi:")
+	if m.DOI == `` {
+		m.DOI = doi.FindString(first("dc:identifier"))
+	}
+	m.Year = dateYear(first("prism:publicationDate", "prism:coverDate", "xmp:CreateDate"))
+	m.Keywords = first("pdf:Keywords")
+	if m.Keywords == `` {
+		m.Keywords = strings.Join(props["dc:subject"], ", ")
+	}
+}
+
+// Property returns the prefixed name of an XMP property, or an empty string
+// for other elements and attributes.
+func property(n xml.Name) string {
+	if p, ok := namespaces[n.Space]; ok {
+		return p + ":" + n.Local
+	}
+	// PRISM went through several versions with their own namespaces.
+	if strings.HasPrefix(n.Space, "http://prismstandard.org/namespaces/") {
+		return "prism:" + n.Local
+	}
+	return ``
+}
+
+// FromInfo fills the fields left empty by the metadata stream from the
+// document information dictionary.
+func (m *Metadata) fromInfo(info map[string]string) {
+	if t := strings.TrimSpace(info["Title"]); m.Title == `` && !junk.MatchString(t) {
+		m.Title = t
+	}
+	if a := strings.TrimSpace(info["Author"]); len(m.Authors) == 0 && a != `` {
+		m.Authors = splitAuthors(a)
+	}
+	if m.Year == `` {
+		m.Year = dateYear(info["CreationDate"])
+	}
+	if m.DOI == `` {
+		for _, k := range []string{"doi", "DOI", "Subject"} {
+			if v := doi.FindString(info[k]); v != `` {
+				m.DOI = v
+				break
+			}
+		}
+	}
+	if m.Keywords == `` {
+		m.Keywords = strings.TrimSpace(info["Keywords"])
+	}
+}
+
+// SplitAuthors splits the author string of the information dictionary,
+// which has no fixed format, on semicolons, ampersands and `and`, or on
+// commas when each part holds a full name.
+func splitAuthors(s string) []string {
+	parts := nameSeps.Split(s, -1)
+	if len(parts) == 1 {
+		commas := strings.Split(s, ",")
+		full := len(commas) > 1
+		for _, c := range commas {
+			full = full && len(strings.Fields(c)) > 1
+		}
+		if full {
+			parts = commas
+		}
+	}
+	result := []string{}
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != `` {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// DateYear returns the year of a date in the ISO 8601 or the PDF format,
+// e.g. 2019-05-01 or D:20190501120000Z.
+func dateYear(s string) string {
+	if m := year.FindStringSubmatch(strings.TrimPrefix(s, "D:")); m != nil {
+		return m[1]
+	}
+	return ``
+}
+
+// Entry returns a draft entry with the metadata, linked to the file at the
+// path. Documents with a journal or a DOI are taken for articles.
+func (m *Metadata) Entry(path string) *parse.EntryDecl {
+	e := &parse.EntryDecl{Name: "misc", Comments: &parse.CommentGroupExpr{}}
+	if m.Journal != `` || m.DOI != `` {
+		e.Name = "article"
+	}
+	if len(m.Authors) > 0 {
+		list := make([]string, len(m.Authors))
+		for i, a := range m.Authors {
+			list[i] = tex.Escape(a)
+		}
+		e.Set("author", parse.Quote(strings.Join(list, " and ")))
+	}
+	for _, f := range [][2]string{
+		{"title", m.Title},
+		{"journal", m.Journal},
+		{"volume", m.Volume},
+		{"number", m.Number},
+		{"pages", m.Pages},
+		{"year", m.Year},
+		{"doi", m.DOI},
+		{"keywords", m.Keywords},
+	} {
+		if f[1] != `` {
+			e.Set(f[0], parse.Quote(tex.Escape(f[1])))
+		}
+	}
+	if path != `` {
+		e.Set("file", parse.Quote(path))
+	}
+	e.CiteKey = citekey.Generate(e)
+	return e
+}
+
+// PdfString decodes the literal or hexadecimal string at the start of the
+// data and returns it with the number of bytes it took.
+func pdfString(data []byte) (string, int, bool) {
+	switch {
+	case bytes.HasPrefix(data, []byte("(")):
+		v, n, ok := literal(data)
+		return text(v), n, ok
+	case bytes.HasPrefix(data, []byte("<")) && !bytes.HasPrefix(data, []byte("<<")):
+		end := bytes.IndexByte(data, '>')
+		if end < 0 {
+			return ``, 0, false
+		}
+		digits := strings.Join(strings.Fields(string(data[1:end])), ``)
+		if len(digits)%2 == 1 {
+			digits += "0"
+		}
+		v, err := hex.DecodeString(digits)
+		if err != nil {
+			return ``, 0, false
+		}
+		return text(v), end + 1, true
+	}
+	return ``, 0, false
+}
+
+// Literal decodes the escapes of the literal string in parentheses at the
+// start of the data, which may hold balanced parentheses.
+func literal(data []byte) ([]byte, int, bool) {
+	var b bytes.Buffer
+	depth := 0
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '\\' && i+1 < len(data):
+			i++
+			switch e := data[i]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case '\r', '\n':
+				// A backslash at the end of a line continues the string.
+				if e == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+					i++
+				}
+			default:
+				if e >= '0' && e <= '7' {
+					v := 0
+					j := i
+					for ; j < len(data) && j < i+3 && data[j] >= '0' && data[j] <= '7'; j++ {
+						v = v*8 + int(data[j]-'0')
+					}
+					b.WriteByte(byte(v))
+					i = j - 1
+				} else {
+					b.WriteByte(e)
+				}
+			}
+		case c == '(':
+			if depth > 0 {
+				b.WriteByte(c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return b.Bytes(), i + 1, true
+			}
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return nil, 0, false
+}
+
+// Text decodes a text string, which is either UTF-16BE with a byte order
+// mark, UTF-8 with a byte order mark, or PDFDocEncoding, taken here for
+// Latin-1 which it mostly agrees with.
+func text(v []byte) string {
+	switch {
+	case bytes.HasPrefix(v, []byte{0xFE, 0xFF}):
+		units := make([]uint16, 0, len(v)/2)
+		for i := 2; i+1 < len(v); i += 2 {
+			units = append(units, uint16(v[i])<<8|uint16(v[i+1]))
+		}
+		return string(utf16.Decode(units))
+	case bytes.HasPrefix(v, []byte{0xEF, 0xBB, 0xBF}):
+		return string(v[3:])
+	}
+	rs := make([]rune, len(v))
+	for i, c := range v {
+		rs[i] = rune(c)
+	}
+	return string(rs)
+}
+
+// MaxStream is the size the streams are cut at once inflated, so that a
+// small file cannot inflate into an exhausting amount of data.
+const maxStream = 16 << 20
+
+func inflate(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var b bytes.Buffer
+	// Streams are often followed by stray bytes that upset the checksum, so
+	// whatever was inflated is kept.
+	_, err = io.Copy(&b, io.LimitReader(r, maxStream))
+	if b.Len() > 0 {
+		return b.Bytes(), nil
+	}
+	return nil, err
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
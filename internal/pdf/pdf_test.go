@@ -0,0 +1,178 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// Build assembles a PDF file from the bodies of its objects, numbered from
+// 1, and the trailer. The cross-reference table is left out as the package
+// does not need it.
+func build(trailer string, objs ...string) []byte {
+	var b strings.Builder
+	b.WriteString("%PDF-1.7\n%\xE2\xE3\xCF\xD3\n")
+	for i, o := range objs {
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	fmt.Fprintf(&b, "trailer\n%s\n%%%%EOF\n", trailer)
+	return []byte(b.String())
+}
+
+// Stream returns a stream object holding the data, compressed if asked.
+func stream(dict, data string, compress bool) string {
+	if compress {
+		var b bytes.Buffer
+		w := zlib.NewWriter(&b)
+		w.Write([]byte(data))
+		w.Close()
+		data = b.String()
+		dict += " /Filter /FlateDecode"
+	}
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+const xmpPacket = `<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:dc="http://purl.org/dc/elements/1.1/"
+    xmlns:prism="http://prismstandard.org/namespaces/basic/2.0/"
+    prism:volume="50">
+   <dc:title><rdf:Alt><rdf:li xml:lang="x-default">The independence of the continuum hypothesis</rdf:li></rdf:Alt></dc:title>
+   <dc:creator><rdf:Seq><rdf:li>Paul J. Cohen</rdf:li></rdf:Seq></dc:creator>
+   <prism:publicationName>PNAS</prism:publicationName>
+   <prism:number>6</prism:number>
+   <prism:startingPage>1143</prism:startingPage>
+   <prism:endingPage>1148</prism:endingPage>
+   <prism:coverDate>1963-12-15</prism:coverDate>
+   <prism:doi>10.1073/pnas.50.6.1143</prism:doi>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+func TestRead(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+		scan bool
+		want *Metadata
+	}{
+		{
+			"info",
+			build("<< /Root 2 0 R /Info 1 0 R >>",
+				`<< /Title (On the mainstream \(and others\)) /Author <FEFF004B0075007200740020004700F600640065006C> /CreationDate (D:19400101000000Z) /Keywords (set theory) >>`,
+				"<< /Type /Catalog >>",
+			),
+			false,
+			&Metadata{Title: "On the mainstream (and others)", Authors: []string{"Kurt Gödel"}, Year: "1940", Keywords: "set theory"},
+		},
+		{
+			"xmp",
+			build("<< /Root 1 0 R /Info 3 0 R >>",
+				"<< /Type /Catalog /Metadata 2 0 R >>",
+				stream("/Type /Metadata /Subtype /XML", xmpPacket, false),
+				"<< /Title (paper.dvi) /Author (Someone Else) /CreationDate (D:20200101) >>",
+			),
+			false,
+			&Metadata{
+				Title:   "The independence of the continuum hypothesis",
+				Authors: []string{"Paul J. Cohen"},
+				Journal: "PNAS",
+				Volume:  "50",
+				Number:  "6",
+				Pages:   "1143--1148",
+				Year:    "1963",
+				DOI:     "10.1073/pnas.50.6.1143",
+			},
+		},
+		{
+			"object-stream",
+			build("",
+				stream("/Type /ObjStm /N 2 /First 8", "3 0 4 53 << /Title (Packed) /Author (A. Author; B. Author) >> << /Type /Catalog >>", true),
+				stream("/Type /XRef /Root 4 0 R /Info 3 0 R", "", false),
+			),
+			false,
+			&Metadata{Title: "Packed", Authors: []string{"A. Author", "B. Author"}},
+		},
+		{
+			"scan-doi",
+			build("<< /Info 2 0 R >>",
+				stream("", "BT /F1 10 Tf [(Cohen) -250 (1963)] TJ ET BT [(doi: 10.1073/) (pnas.50.6.1143)] TJ ET", true),
+				"<< /Title (Scanned) >>",
+			),
+			true,
+			&Metadata{Title: "Scanned", DOI: "10.1073/pnas.50.6.1143"},
+		},
+		{
+			"no-scan",
+			build("<< /Info 2 0 R >>",
+				stream("", "BT [(doi: 10.1073/pnas.50.6.1143)] TJ ET", true),
+				"<< /Title (Scanned) >>",
+			),
+			false,
+			&Metadata{Title: "Scanned"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			have, err := Read(bytes.NewReader(c.data), c.scan)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(have, c.want) {
+				t.Errorf("have %+v; want %+v", have, c.want)
+			}
+		})
+	}
+	if _, err := Read(strings.NewReader("@article{a,}"), false); err != ErrNotPDF {
+		t.Errorf("have %v; want %v", err, ErrNotPDF)
+	}
+}
+
+func TestEntry(t *testing.T) {
+	m := &Metadata{
+		Title:   "Sets & classes",
+		Authors: []string{"Paul J. Cohen", "Kurt Gödel"},
+		Journal: "PNAS",
+		Year:    "1963",
+		DOI:     "10.1073/pnas.50.6.1143",
+	}
+	var b strings.Builder
+	if err := format.Nodes(&b, []parse.Node{m.Entry("papers/cohen.pdf")}); err != nil {
+		t.Fatal(err)
+	}
+	want := `@article{Cohen1963,
+  author  = {Paul J. Cohen and Kurt Gödel},
+  title   = {Sets \& classes},
+  journal = {PNAS},
+  year    = {1963},
+  doi     = {10.1073/pnas.50.6.1143},
+  file    = {papers/cohen.pdf}
+}
+`
+	if have := b.String(); have != want {
+		t.Errorf("have %s; want %s", have, want)
+	}
+}
+
+func TestInflateLimit(t *testing.T) {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	w.Write(make([]byte, maxStream+1<<10))
+	w.Close()
+	plain, err := inflate(b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(plain), maxStream; have != want {
+		t.Errorf("have %d bytes; want %d", have, want)
+	}
+}
@@ -0,0 +1,17 @@
+/*
+Plugin package runs lint rules and format converters shipped as external
+executables, so that they can be written in any language and installed
+without rebuilding bibx. Plugins are found on the PATH under the names
+bibx-lint-<name> for lint rules and bibx-convert-<name> for converters.
+
+Entries are exchanged as JSON Lines, one object per entry with the type,
+key and fields members written by the jsonl package. A lint rule reads the
+entries on its standard input and writes one JSON object per finding with
+the key, field and message members, and optionally the line and column of
+the problem, on its standard output. A converter is run with the read
+argument to convert its standard input into entries, and with the write
+argument to convert the entries on its standard input into its format.
+Anything written to the standard error is passed through, and a non-zero
+exit status fails the plugin.
+*/
+package plugin
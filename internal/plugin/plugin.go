@@ -0,0 +1,181 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/jsonl"
+	"github.com/mdm-code/bibx/internal/lint"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+// Kinds of plugins.
+const (
+	Lint    = "lint"
+	Convert = "convert"
+)
+
+// Find returns the path of the executable of the plugin of the kind found on
+// the PATH.
+func Find(kind, name string) (string, error) {
+	path, err := exec.LookPath("bibx-" + kind + "-" + name)
+	if err != nil {
+		return ``, fmt.Errorf("plugin: %s plugin %s not found", kind, name)
+	}
+	return path, nil
+}
+
+// List returns the sorted names of the plugins of the kind found on the
+// PATH.
+func List(kind string) []string {
+	prefix := "bibx-" + kind + "-"
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		matches, _ := filepath.Glob(filepath.Join(dir, prefix+"*"))
+		for _, m := range matches {
+			name := strings.TrimPrefix(filepath.Base(m), prefix)
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+			if info, err := os.Stat(m); err != nil || info.IsDir() || seen[name] {
+				continue
+			}
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Rule is a lint rule run by the executable at Path. Failures of the
+// executable are reported as findings of the rule.
+type Rule struct {
+	RuleName string
+	Path     string
+}
+
+// NewRule returns the lint rule of the plugin found on the PATH.
+func NewRule(name string) (Rule, error) {
+	path, err := Find(Lint, name)
+	if err != nil {
+		return Rule{}, err
+	}
+	return Rule{RuleName: name, Path: path}, nil
+}
+
+// Name returns the name of the plugin.
+func (r Rule) Name() string { return r.RuleName }
+
+// Check runs the executable with the entries of the document.
+func (r Rule) Check(nodes []parse.Node) []lint.Finding {
+	entries := []*parse.EntryDecl{}
+	for _, n := range nodes {
+		if e, ok := n.(*parse.EntryDecl); ok {
+			entries = append(entries, e)
+		}
+	}
+	var in bytes.Buffer
+	if err := jsonl.Write(&in, entries); err != nil {
+		return []lint.Finding{r.failure(err)}
+	}
+	out, err := run(r.Path, &in)
+	if err != nil {
+		return []lint.Finding{r.failure(err)}
+	}
+	result := []lint.Finding{}
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		if strings.TrimSpace(s.Text()) == `` {
+			continue
+		}
+		var f struct {
+			Key     string `json:"key"`
+			Field   string `json:"field"`
+			Message string `json:"message"`
+			Line    int    `json:"line"`
+			Column  int    `json:"column"`
+		}
+		if err := json.Unmarshal(s.Bytes(), &f); err != nil {
+			return append(result, r.failure(err))
+		}
+		result = append(result, lint.Finding{
+			Rule:    r.RuleName,
+			Key:     f.Key,
+			Field:   f.Field,
+			Pos:     scan.Pos{Line: f.Line, Column: f.Column},
+			Message: f.Message,
+		})
+	}
+	return result
+}
+
+func (r Rule) failure(err error) lint.Finding {
+	return lint.Finding{Rule: r.RuleName, Message: "plugin failed: " + err.Error()}
+}
+
+// Reader returns a function converting the format of the converter plugin
+// found on the PATH into entries.
+func Reader(name string) (func(io.Reader) ([]*parse.EntryDecl, error), error) {
+	path, err := Find(Convert, name)
+	if err != nil {
+		return nil, err
+	}
+	return func(r io.Reader) ([]*parse.EntryDecl, error) {
+		out, err := run(path, r, "read")
+		if err != nil {
+			return nil, err
+		}
+		result := []*parse.EntryDecl{}
+		dec := json.NewDecoder(bytes.NewReader(out))
+		for dec.More() {
+			var o jsonl.Object
+			if err := dec.Decode(&o); err != nil {
+				return nil, fmt.Errorf("plugin: %s: %w", name, err)
+			}
+			result = append(result, o.Entry())
+		}
+		return result, nil
+	}, nil
+}
+
+// Writer returns a function converting entries into the format of the
+// converter plugin found on the PATH.
+func Writer(name string) (func(io.Writer, []*parse.EntryDecl) error, error) {
+	path, err := Find(Convert, name)
+	if err != nil {
+		return nil, err
+	}
+	return func(w io.Writer, entries []*parse.EntryDecl) error {
+		var in bytes.Buffer
+		if err := jsonl.Write(&in, entries); err != nil {
+			return err
+		}
+		out, err := run(path, &in, "write")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	}, nil
+}
+
+// Run runs the executable with the input and returns its output.
+func run(path string, in io.Reader, args ...string) ([]byte, error) {
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = in
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("plugin: %s: %w", filepath.Base(path), err)
+	}
+	return out, nil
+}
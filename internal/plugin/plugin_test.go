@@ -0,0 +1,133 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/lint"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+// Install writes the shell script plugins into a directory put on the PATH.
+func install(t *testing.T, scripts map[string]string) {
+	t.Helper()
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no /bin/sh")
+	}
+	dir := t.TempDir()
+	for name, body := range scripts {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func parseNodes(t *testing.T, src string) []parse.Node {
+	t.Helper()
+	p := parse.NewParser(scan.NewScanner(scan.NewReader(strings.NewReader(src))))
+	result := []parse.Node{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		result = append(result, n)
+	}
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestList(t *testing.T) {
+	install(t, map[string]string{
+		"bibx-lint-b":    "",
+		"bibx-lint-a":    "",
+		"bibx-convert-c": "",
+	})
+	if have, want := List(Lint), []string{"a", "b"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v; want %v", have, want)
+	}
+	if _, err := Find(Convert, "d"); err == nil {
+		t.Error("have nil error for a missing plugin")
+	}
+}
+
+func TestRule(t *testing.T) {
+	install(t, map[string]string{
+		"bibx-lint-quiet": "cat >/dev/null\n",
+		"bibx-lint-title": `while read -r line; do
+  case "$line" in
+  *'"title"'*) ;;
+  *) key=${line#*\"key\":\"}; key=${key%%\"*}
+     echo "{\"key\":\"$key\",\"field\":\"title\",\"message\":\"missing title\"}" ;;
+  esac
+done
+`,
+		"bibx-lint-broken": "exit 3\n",
+	})
+	nodes := parseNodes(t, `
+@article{a, title = {Present}}
+@article{b, year = 2000}
+`)
+	cases := []struct {
+		name string
+		want []lint.Finding
+	}{
+		{"quiet", []lint.Finding{}},
+		{"title", []lint.Finding{
+			{Rule: "title", Key: "b", Field: "title", Message: "missing title"},
+		}},
+		{"broken", []lint.Finding{
+			{Rule: "broken", Message: "plugin failed: plugin: bibx-lint-broken: exit status 3"},
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r, err := NewRule(c.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have := r.Check(nodes); !reflect.DeepEqual(have, c.want) {
+				t.Errorf("have %v; want %v", have, c.want)
+			}
+		})
+	}
+}
+
+func TestConverter(t *testing.T) {
+	install(t, map[string]string{
+		"bibx-convert-keys": `if [ "$1" = read ]; then
+  while read -r key; do
+    echo "{\"type\":\"misc\",\"key\":\"$key\",\"fields\":{\"note\":\"imported\"}}"
+  done
+else
+  sed 's/.*"key":"\([^"]*\)".*/\1/'
+fi
+`,
+	})
+	read, err := Reader("keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := read(strings.NewReader("a\nb\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].CiteKey != "b" || entries[1].Name != "misc" {
+		t.Fatalf("have %v; want entries a and b", entries)
+	}
+	write, err := Writer("keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := write(&b, entries); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := b.String(), "a\nb\n"; have != want {
+		t.Errorf("have %q; want %q", have, want)
+	}
+}
@@ -0,0 +1,7 @@
+/*
+Progress package tells how far long operations, such as parsing a large
+bibliography or enriching its entries over the network, have got. The work
+reports the bytes it reads, the entries it processes and the files it is done
+with to a Reporter, which may render them as a status line on a terminal.
+*/
+package progress
@@ -0,0 +1,117 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Reporter receives the progress of an operation. Read and Entries add to
+// the numbers of bytes read and entries processed, while Files gives the
+// number of files done out of the total. Reporters may be called from many
+// goroutines at once.
+type Reporter interface {
+	Read(n int)
+	Entries(n int)
+	Files(done, total int)
+}
+
+// Reader reports the bytes read from R to the Reporter.
+type Reader struct {
+	R        io.Reader
+	Reporter Reporter
+}
+
+// Read reads from R and reports the bytes read.
+func (r *Reader) Read(p []byte) (int, error) {
+	n, err := r.R.Read(p)
+	if n > 0 {
+		r.Reporter.Read(n)
+	}
+	return n, err
+}
+
+// Bar is a Reporter rendering the progress as a status line rewritten in
+// place on W, which is meant to be a terminal. The line is redrawn at most
+// once per Interval, and whenever a file is done.
+type Bar struct {
+	W        io.Writer
+	Interval time.Duration
+
+	mu          sync.Mutex
+	bytes       int64
+	entries     int
+	done, total int
+	drawn       time.Time
+	width       int
+}
+
+// Read adds n to the bytes read.
+func (b *Bar) Read(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.bytes += int64(n)
+	b.draw(false)
+}
+
+// Entries adds n to the entries processed.
+func (b *Bar) Entries(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries += n
+	b.draw(false)
+}
+
+// Files sets the files done out of the total.
+func (b *Bar) Files(done, total int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.done, b.total = done, total
+	b.draw(true)
+}
+
+// Close draws the final state of the line and moves past it.
+func (b *Bar) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.drawn.IsZero() {
+		return nil
+	}
+	b.draw(true)
+	_, err := fmt.Fprintln(b.W)
+	return err
+}
+
+func (b *Bar) draw(force bool) {
+	now := time.Now()
+	if !force && now.Sub(b.drawn) < b.Interval {
+		return
+	}
+	b.drawn = now
+	line := fmt.Sprintf("%s read, %d entries", size(b.bytes), b.entries)
+	if b.total > 0 {
+		line = fmt.Sprintf("%d/%d files, %s", b.done, b.total, line)
+	}
+	// Pad the line over the rest of a longer one drawn before.
+	pad := b.width - len(line)
+	if pad < 0 {
+		pad = 0
+	}
+	b.width = len(line)
+	fmt.Fprintf(b.W, "\r%s%*s", line, pad, ``)
+}
+
+// Size formats the number of bytes in binary units.
+func size(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
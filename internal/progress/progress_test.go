@@ -0,0 +1,85 @@
+package progress
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type counter struct{ bytes, entries, done, total int }
+
+func (c *counter) Read(n int)            { c.bytes += n }
+func (c *counter) Entries(n int)         { c.entries += n }
+func (c *counter) Files(done, total int) { c.done, c.total = done, total }
+
+func TestReader(t *testing.T) {
+	c := &counter{}
+	r := &Reader{R: strings.NewReader(strings.Repeat("x", 10000)), Reporter: c}
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		t.Fatal(err)
+	}
+	if c.bytes != 10000 {
+		t.Errorf("have %d bytes; want 10000", c.bytes)
+	}
+}
+
+func TestBar(t *testing.T) {
+	var w strings.Builder
+	b := &Bar{W: &w}
+	b.Read(3 << 20)
+	b.Entries(120)
+	b.Files(1, 2)
+	b.Read(1)
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(w.String(), "\r")
+	want := []string{
+		``,
+		"3.0 MiB read, 0 entries",
+		"3.0 MiB read, 120 entries",
+		"1/2 files, 3.0 MiB read, 120 entries",
+		"1/2 files, 3.0 MiB read, 120 entries",
+		"1/2 files, 3.0 MiB read, 120 entries\n",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("have %q; want %q", lines, want)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("have %q; want %q", lines[i], want[i])
+		}
+	}
+}
+
+func TestBarInterval(t *testing.T) {
+	var w strings.Builder
+	b := &Bar{W: &w, Interval: time.Hour}
+	for i := 0; i < 100; i++ {
+		b.Entries(1)
+	}
+	b.Files(1, 1)
+	if have := strings.Count(w.String(), "\r"); have != 2 {
+		t.Errorf("have %d redraws; want 2", have)
+	}
+}
+
+func TestSize(t *testing.T) {
+	cases := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KiB"},
+		{5 << 30, "5.0 GiB"},
+	}
+	for _, c := range cases {
+		t.Run(c.want, func(t *testing.T) {
+			if have := size(c.n); have != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
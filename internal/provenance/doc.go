@@ -0,0 +1,6 @@
+/*
+Provenance package records the files the entries and fields of a
+bibliography built from several sources were read from, so that problems
+spotted in the merged result can be traced back to the lines to fix.
+*/
+package provenance
@@ -0,0 +1,79 @@
+package provenance
+
+import (
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+// Origin locates a declaration or field in the file it was read from.
+type Origin struct {
+	Path string
+	Pos  scan.Pos
+}
+
+// String formats the origin as path:line:column.
+func (o Origin) String() string {
+	if !o.Pos.IsValid() {
+		return o.Path
+	}
+	return o.Path + ":" + o.Pos.String()
+}
+
+// Table holds the origins of the entries and fields. The zero value is an
+// empty table ready to use.
+type Table struct {
+	entries map[*parse.EntryDecl]Origin
+	fields  map[*parse.FieldStmt]Origin
+}
+
+// Add records the entries and fields of the nodes parsed from the file at
+// path as coming from it.
+func (t *Table) Add(path string, nodes []parse.Node) {
+	t.add(nodes, func(p scan.Pos) Origin { return Origin{path, p} })
+}
+
+// AddLocated records the entries and fields of the nodes parsed from the
+// reader returned by scan.MultiReader as coming from the sources their
+// positions are located in.
+func (t *Table) AddLocated(r *scan.Reader, nodes []parse.Node) {
+	t.add(nodes, func(p scan.Pos) Origin {
+		name, pos := r.Locate(p)
+		return Origin{name, pos}
+	})
+}
+
+func (t *Table) add(nodes []parse.Node, origin func(scan.Pos) Origin) {
+	if t.entries == nil {
+		t.entries = make(map[*parse.EntryDecl]Origin)
+		t.fields = make(map[*parse.FieldStmt]Origin)
+	}
+	for _, n := range nodes {
+		e, ok := n.(*parse.EntryDecl)
+		if !ok {
+			continue
+		}
+		t.entries[e] = origin(e.Pos)
+		for _, f := range e.Fields {
+			t.fields[f] = origin(f.Pos)
+		}
+	}
+}
+
+// Entry returns the origin of the entry and whether it is known.
+func (t *Table) Entry(e *parse.EntryDecl) (Origin, bool) {
+	o, ok := t.entries[e]
+	return o, ok
+}
+
+// Field returns the origin of the field and whether it is known. Fields
+// added to an entry after it was recorded fall back on the file of the
+// entry without a position.
+func (t *Table) Field(e *parse.EntryDecl, f *parse.FieldStmt) (Origin, bool) {
+	if o, ok := t.fields[f]; ok {
+		return o, true
+	}
+	if o, ok := t.entries[e]; ok {
+		return Origin{Path: o.Path}, true
+	}
+	return Origin{}, false
+}
@@ -0,0 +1,60 @@
+package provenance
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+func parseAll(r *scan.Reader) []parse.Node {
+	p := parse.NewParser(scan.NewScanner(r))
+	result := []parse.Node{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		result = append(result, n)
+	}
+	return result
+}
+
+func TestTable(t *testing.T) {
+	r := scan.MultiReader([]scan.Source{
+		{Name: "strings.bib", R: strings.NewReader("@string{jx = {J. X}}\n@misc{a, note = jx}")},
+		{Name: "refs.bib", R: strings.NewReader("% Refs\n\n@book{b,\n  title = {T}}\n")},
+	})
+	nodes := parseAll(r)
+	var located, single Table
+	located.AddLocated(r, nodes)
+	single.Add("all.bib", nodes)
+	es := []*parse.EntryDecl{}
+	for _, n := range nodes {
+		if e, ok := n.(*parse.EntryDecl); ok {
+			es = append(es, e)
+		}
+	}
+	extra := &parse.FieldStmt{Key: "year", Value: "2000"}
+	cases := []struct {
+		name string
+		have func() (Origin, bool)
+		want string
+	}{
+		{"entry", func() (Origin, bool) { return located.Entry(es[0]) }, "strings.bib:2:1"},
+		{"field", func() (Origin, bool) { return located.Field(es[0], es[0].Fields[0]) }, "strings.bib:2:10"},
+		{"second-source", func() (Origin, bool) { return located.Entry(es[1]) }, "refs.bib:3:1"},
+		{"second-field", func() (Origin, bool) { return located.Field(es[1], es[1].Fields[0]) }, "refs.bib:4:3"},
+		{"added-field", func() (Origin, bool) { return located.Field(es[1], extra) }, "refs.bib"},
+		{"single", func() (Origin, bool) { return single.Entry(es[1]) }, "all.bib:5:1"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			o, ok := c.have()
+			if !ok || o.String() != c.want {
+				t.Errorf("have %v, %t; want %s", o, ok, c.want)
+			}
+		})
+	}
+	var empty Table
+	if _, ok := empty.Entry(es[0]); ok {
+		t.Error("have an origin in an empty table")
+	}
+}
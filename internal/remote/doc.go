@@ -0,0 +1,8 @@
+/*
+Remote package sends the HTTP requests of the clients of the bibliographic
+web services, retrying the ones that fail for transient reasons with an
+exponential backoff, so that a flaky connection or a rate limit does not
+break a long-running script. It also fetches the files given to the commands
+as URLs, keeping copies that are downloaded again only once they change.
+*/
+package remote
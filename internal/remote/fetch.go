@@ -0,0 +1,145 @@
+package remote
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsURL tells if the argument names a file served over HTTP or HTTPS rather
+// than one on disk.
+func IsURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// Fetcher downloads files over HTTP and HTTPS. Unless Cache is empty, a copy
+// of each file is kept in the Cache directory along with its ETag and
+// Last-Modified date, and a file fetched again is revalidated with a
+// conditional request and read from the cache while the server answers 304
+// Not Modified. Requests are retried as the Policy tells. Files larger than
+// MaxSize bytes, or DefaultMaxSize when it is 0, are refused.
+type Fetcher struct {
+	Client  *http.Client
+	Policy  Policy
+	Cache   string
+	MaxSize int64
+}
+
+// DefaultMaxSize is the size of the largest file a Fetcher downloads unless
+// told otherwise.
+const DefaultMaxSize = 256 << 20
+
+// Validators are the headers a cached file is revalidated with.
+type validators struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// Fetch returns the contents of the file at the URL.
+func (f *Fetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	cached, v := f.cached(url)
+	if cached != nil {
+		if v.ETag != `` {
+			req.Header.Set("If-None-Match", v.ETag)
+		}
+		if v.LastModified != `` {
+			req.Header.Set("If-Modified-Since", v.LastModified)
+		}
+	}
+	resp, err := Do(ctx, f.Client, f.Policy, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		return cached, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	limit := f.MaxSize
+	if limit <= 0 {
+		limit = DefaultMaxSize
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %w", url, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("GET %s: file larger than %d bytes", url, limit)
+	}
+	v = validators{URL: url, ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+	if err := f.store(v, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Cached returns the cached copy of the file at the URL and its validators,
+// or nil if there is none.
+func (f *Fetcher) cached(url string) ([]byte, validators) {
+	var v validators
+	if f.Cache == `` {
+		return nil, v
+	}
+	name := f.name(url)
+	meta, err := os.ReadFile(name + ".json")
+	if err != nil || json.Unmarshal(meta, &v) != nil || v.URL != url {
+		return nil, validators{}
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, validators{}
+	}
+	return data, v
+}
+
+// Store saves the file in the cache. Files served without validators are
+// not cached, as they could not be revalidated.
+func (f *Fetcher) store(v validators, data []byte) error {
+	if f.Cache == `` {
+		return nil
+	}
+	name := f.name(v.URL)
+	if v.ETag == `` && v.LastModified == `` {
+		if err := os.Remove(name + ".json"); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(f.Cache, 0o755); err != nil {
+		return err
+	}
+	meta, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	// The validators are written last, so that a copy cut short is never
+	// taken for the file they describe.
+	if err := os.Remove(name + ".json"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(name+".json", meta, 0o644)
+}
+
+// Name returns the path of the cached copy of the file at the URL.
+func (f *Fetcher) name(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(f.Cache, hex.EncodeToString(sum[:16]))
+}
@@ -0,0 +1,112 @@
+package remote
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsURL(t *testing.T) {
+	cases := []struct {
+		s    string
+		want bool
+	}{
+		{"https://example.org/refs.bib", true},
+		{"http://example.org/refs.bib", true},
+		{"refs.bib", false},
+		{"ftp://example.org/refs.bib", false},
+		{"https:refs.bib", false},
+	}
+	for _, c := range cases {
+		t.Run(c.s, func(t *testing.T) {
+			if have := IsURL(c.s); have != c.want {
+				t.Errorf("have %v; want %v", have, c.want)
+			}
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	body, etag := "@misc{Doe2020, year = 2020}\n", `"v1"`
+	sent, revalidated := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/plain.bib" {
+			w.Write([]byte(body))
+			return
+		}
+		if r.Header.Get("If-None-Match") != `` {
+			revalidated++
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		sent++
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	f := &Fetcher{Client: srv.Client(), Policy: Policy{Attempts: 1}, Cache: t.TempDir()}
+	fetch := func(path, want string) {
+		t.Helper()
+		have, err := f.Fetch(context.Background(), srv.URL+path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(have) != want {
+			t.Errorf("have %q; want %q", have, want)
+		}
+	}
+
+	fetch("/refs.bib", body)
+	fetch("/refs.bib", body)
+	if sent != 1 || revalidated != 1 {
+		t.Errorf("have %d sent and %d revalidated; want 1 and 1", sent, revalidated)
+	}
+	// A changed file is fetched anew.
+	body, etag = "@misc{Roe2021, year = 2021}\n", `"v2"`
+	fetch("/refs.bib", body)
+	if sent != 2 || revalidated != 2 {
+		t.Errorf("have %d sent and %d revalidated; want 2 and 2", sent, revalidated)
+	}
+	// Files served without validators are not cached.
+	fetch("/plain.bib", body)
+	if cached, _ := f.cached(srv.URL + "/plain.bib"); cached != nil {
+		t.Errorf("have %q cached; want nothing", cached)
+	}
+}
+
+func TestFetchStatus(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	f := &Fetcher{Client: srv.Client(), Policy: Policy{Attempts: 1, Backoff: time.Millisecond}}
+	_, err := f.Fetch(context.Background(), srv.URL+"/refs.bib")
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("have error %v; want 404", err)
+	}
+}
+
+func TestFetchMaxSize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "@misc{a,}\n")
+	}))
+	defer srv.Close()
+	cases := []struct {
+		max  int64
+		fail bool
+	}{
+		{max: 10, fail: false},
+		{max: 9, fail: true},
+	}
+	for _, c := range cases {
+		f := &Fetcher{Client: srv.Client(), MaxSize: c.max}
+		_, err := f.Fetch(context.Background(), srv.URL+"/refs.bib")
+		if have := err != nil; have != c.fail {
+			t.Errorf("max %d: have error %v; want failure %t", c.max, err, c.fail)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package remote
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Policy tells how persistently requests are retried. The zero value stands
+// for DefaultPolicy.
+type Policy struct {
+	// Attempts is the number of times a request is sent at most, so that 1
+	// disables retries.
+	Attempts int
+	// Backoff is the delay before the first retry. It doubles before each
+	// next retry up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultPolicy retries a request twice, waiting half a second and then a
+// second.
+var DefaultPolicy = Policy{Attempts: 3, Backoff: 500 * time.Millisecond, MaxBackoff: 10 * time.Second}
+
+// Do sends the request with the client, or http.DefaultClient if it is nil,
+// under the context. Requests failing with a network error or with a 429 or
+// 5xx response are sent again as long as the policy allows, after the delay
+// the Retry-After header asks for if there is one. The response of the last
+// attempt is returned, so that the caller can report its status. Requests
+// with a body are retried only if the body can be read again, which is the
+// case for the requests made by http.NewRequest with an in-memory body.
+func Do(ctx context.Context, hc *http.Client, p Policy, req *http.Request) (*http.Response, error) {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	if p == (Policy{}) {
+		p = DefaultPolicy
+	}
+	delay := p.Backoff
+	for attempt := 1; ; attempt++ {
+		r := req.Clone(ctx)
+		if req.Body != nil && req.GetBody != nil && attempt > 1 {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+		resp, err := hc.Do(r)
+		last := attempt >= p.Attempts || ctx.Err() != nil || req.Body != nil && req.GetBody == nil
+		if last || !transient(resp, err) {
+			return resp, err
+		}
+		wait := delay
+		if d, ok := retryAfter(resp); ok {
+			wait = d
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+			wait = p.MaxBackoff
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+		delay *= 2
+	}
+}
+
+// Check tells if the link can be followed, sending a HEAD request with Do
+// and falling back to GET for the servers refusing HEAD. The final status
+// of the redirects followed is returned, or the error of the request.
+func Check(ctx context.Context, hc *http.Client, p Policy, url string) (int, error) {
+	status := 0
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequest(method, url, nil)
+		if err != nil {
+			return 0, err
+		}
+		resp, err := Do(ctx, hc, p, req)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		status = resp.StatusCode
+		if status != http.StatusMethodNotAllowed && status != http.StatusNotImplemented && status != http.StatusForbidden {
+			break
+		}
+	}
+	return status, nil
+}
+
+// Transient checks if the request failed for a reason that may go away.
+func transient(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// RetryAfter returns the delay asked for by the Retry-After header given in
+// seconds or as a date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	v := resp.Header.Get("Retry-After")
+	if v == `` {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// Transport is an http.RoundTripper logging the requests sent through Base,
+// or http.DefaultTransport if it is nil, as debug events of the Logger.
+type Transport struct {
+	Base   http.RoundTripper
+	Logger *slog.Logger
+}
+
+// RoundTrip sends the request and logs its method, URL, duration and the
+// status of the response or the error.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.Redacted()),
+		slog.Duration("duration", time.Since(start)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("err", err))
+	} else {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	}
+	if t.Logger != nil {
+		t.Logger.LogAttrs(req.Context(), slog.LevelDebug, "request sent", attrs...)
+	}
+	return resp, err
+}
@@ -0,0 +1,154 @@
+package remote
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	fast := Policy{Attempts: 3, Backoff: time.Millisecond}
+	cases := []struct {
+		name     string
+		policy   Policy
+		statuses []int
+		header   http.Header
+		want     int
+		requests int
+	}{
+		{"ok", fast, []int{200}, nil, 200, 1},
+		{"unavailable", fast, []int{503, 502, 200}, nil, 200, 3},
+		{"rate-limited", fast, []int{429, 200}, http.Header{"Retry-After": {"0"}}, 200, 2},
+		{"exhausted", fast, []int{500, 500, 500, 200}, nil, 500, 3},
+		{"not-found", fast, []int{404, 200}, nil, 404, 1},
+		{"no-retries", Policy{Attempts: 1}, []int{503, 200}, nil, 503, 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			requests := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, vs := range c.header {
+					w.Header()[k] = vs
+				}
+				w.WriteHeader(c.statuses[requests])
+				requests++
+			}))
+			defer srv.Close()
+			req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := Do(context.Background(), srv.Client(), c.policy, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != c.want {
+				t.Errorf("have status %d; want %d", resp.StatusCode, c.want)
+			}
+			if requests != c.requests {
+				t.Errorf("have %d requests; want %d", requests, c.requests)
+			}
+		})
+	}
+}
+
+func TestDoBody(t *testing.T) {
+	bodies := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	req, err := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := Do(context.Background(), srv.Client(), Policy{Attempts: 2, Backoff: time.Millisecond}, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if strings.Join(bodies, " ") != "data data" {
+		t.Errorf("have bodies %q; want the body sent twice", bodies)
+	}
+}
+
+func TestDoCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	_, err = Do(ctx, srv.Client(), Policy{Attempts: 10, Backoff: time.Hour}, req)
+	if err != context.DeadlineExceeded {
+		t.Errorf("have %v; want %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("have waited %v; want the wait cut short", d)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer srv.Close()
+	var b strings.Builder
+	logger := slog.New(slog.NewTextHandler(&b, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	hc := &http.Client{Transport: &Transport{Base: srv.Client().Transport, Logger: logger}}
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/works?q=x", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := Do(context.Background(), hc, Policy{Attempts: 1}, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	for _, want := range []string{`msg="request sent"`, "method=GET", `url="` + srv.URL + `/works?q=x"`, "status=418"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("have %q; want %s", b.String(), want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    int
+	}{
+		{"ok", func(w http.ResponseWriter, r *http.Request) {}, 200},
+		{"missing", func(w http.ResponseWriter, r *http.Request) { http.NotFound(w, r) }, 404},
+		{"head refused", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		}, 200},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			srv := httptest.NewServer(c.handler)
+			defer srv.Close()
+			have, err := Check(context.Background(), srv.Client(), Policy{Attempts: 1}, srv.URL)
+			if err != nil || have != c.want {
+				t.Errorf("have %d, %v; want %d", have, err, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+/*
+Render package turns parsed BibTeX entries into formatted reference lists for
+presentation outside of LaTeX, such as HTML pages.
+*/
+package render
@@ -0,0 +1,49 @@
+package render
+
+import (
+	"html/template"
+	"io"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+const defaultHTML = `<ol class="bibliography">
+{{- range .}}
+  <li id="{{.Key}}">
+    {{- if .Authors}}{{.Authors}}{{end}}
+    {{- if .Year}} ({{.Year}}){{end}}.
+    {{- if .Title}} <cite>{{.Title}}</cite>.{{end}}
+    {{- if .Venue}} {{.Venue}}.{{end}}
+    {{- if .DOI}} <a href="https://doi.org/{{.DOI}}">doi:{{.DOI}}</a>
+    {{- else if .URL}} <a href="{{.URL}}">{{.URL}}</a>{{end -}}
+  </li>
+{{- end}}
+</ol>
+`
+
+// DefaultHTML renders an ordered list with one item per entry. The cite key
+// of each entry is used as the anchor of its list item.
+var DefaultHTML = template.Must(template.New("html").Parse(defaultHTML))
+
+// HTML renders the entries as a reference list with the template executed on
+// the []Ref slice. DefaultHTML is used when t is nil.
+func HTML(w io.Writer, entries []*parse.EntryDecl, t *template.Template) error {
+	return Options{}.HTML(w, entries, t)
+}
+
+// HTML is like the HTML function but renders the references as the options
+// tell.
+func (o Options) HTML(w io.Writer, entries []*parse.EntryDecl, t *template.Template) error {
+	if t == nil {
+		t = DefaultHTML
+	}
+	return t.Execute(w, o.refs(entries))
+}
+
+func (o Options) refs(entries []*parse.EntryDecl) []Ref {
+	result := make([]Ref, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, o.NewRef(e))
+	}
+	return result
+}
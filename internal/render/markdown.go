@@ -0,0 +1,84 @@
+package render
+
+import (
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+const markdownRef = `{{define "ref" -}}
+{{if .Authors}}{{escape .Authors}}{{end}}
+{{- if .Year}} ({{escape .Year}}){{end}}.
+{{- if .Title}} *{{escape .Title}}*.{{end}}
+{{- if .Venue}} {{escape .Venue}}.{{end}}
+{{- if .DOI}} [doi:{{escape .DOI}}]({{link (print "https://doi.org/" .DOI)}})
+{{- else if .URL}} <{{link .URL}}>{{end}}
+{{- end}}`
+
+const defaultMarkdown = markdownRef + `{{range .}}- {{template "ref" .}}
+{{end}}`
+
+const definitionMarkdown = markdownRef + `{{range $i, $r := .}}{{if $i}}
+{{end}}{{.Key}}
+: {{template "ref" .}}
+{{end}}`
+
+// MarkdownFuncs are the helper functions available to Markdown templates:
+// escape backslash-escapes Markdown syntax in text and link makes a URL safe
+// to use as a link destination.
+var MarkdownFuncs = template.FuncMap{
+	"escape": escapeMarkdown,
+	"link":   escapeLink,
+}
+
+// DefaultMarkdown renders a bullet list with one item per entry.
+var DefaultMarkdown = template.Must(
+	template.New("markdown").Funcs(MarkdownFuncs).Parse(defaultMarkdown),
+)
+
+// DefinitionMarkdown renders a definition list with entries described under
+// their cite keys.
+var DefinitionMarkdown = template.Must(
+	template.New("markdown").Funcs(MarkdownFuncs).Parse(definitionMarkdown),
+)
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	`*`, `\*`,
+	`_`, `\_`,
+	`[`, `\[`,
+	`]`, `\]`,
+	`<`, `\<`,
+	`>`, `\>`,
+	`|`, `\|`,
+)
+
+var linkEscaper = strings.NewReplacer(
+	` `, `%20`,
+	`(`, `%28`,
+	`)`, `%29`,
+	`<`, `%3C`,
+	`>`, `%3E`,
+)
+
+// Markdown renders the entries as a reference list with the template executed
+// on the []Ref slice. DefaultMarkdown is used when t is nil.
+func Markdown(w io.Writer, entries []*parse.EntryDecl, t *template.Template) error {
+	return Options{}.Markdown(w, entries, t)
+}
+
+// Markdown is like the Markdown function but renders the references as the
+// options tell.
+func (o Options) Markdown(w io.Writer, entries []*parse.EntryDecl, t *template.Template) error {
+	if t == nil {
+		t = DefaultMarkdown
+	}
+	return t.Execute(w, o.refs(entries))
+}
+
+func escapeMarkdown(s string) string { return markdownEscaper.Replace(s) }
+
+func escapeLink(s string) string { return linkEscaper.Replace(s) }
@@ -0,0 +1,221 @@
+package render
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/lang"
+	"github.com/mdm-code/bibx/internal/names"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+const (
+	Unsorted Order = iota
+	ByKey
+	ByAuthor
+	ByYear
+	ByTitle
+	ByVenue
+)
+
+// Order defines the sort order of the rendered reference list.
+type Order uint8
+
+// Fields holding the name of the venue in the order of precedence.
+var venueFields = []string{
+	"journal",
+	"booktitle",
+	"publisher",
+	"school",
+	"institution",
+	"organization",
+	"howpublished",
+}
+
+// Articles lists the leading articles skipped in the title and venue sort
+// keys by language. The language of an entry is taken from its langid or
+// language field and defaults to DefaultLanguage. Articles ending with an
+// apostrophe are elided and attach to the following word.
+var Articles = map[string][]string{
+	"english":    {"the", "a", "an"},
+	"french":     {"le", "la", "les", "l'", "un", "une"},
+	"german":     {"der", "die", "das", "ein", "eine"},
+	"italian":    {"il", "lo", "la", "i", "gli", "le", "l'", "un", "una"},
+	"spanish":    {"el", "la", "los", "las", "un", "una"},
+	"portuguese": {"o", "a", "os", "as", "um", "uma"},
+	"dutch":      {"de", "het", "een"},
+}
+
+// DefaultLanguage is the language of entries without a langid or language
+// field.
+var DefaultLanguage = "english"
+
+// Options tell how the references are rendered. The zero value renders all
+// the names of the references.
+type Options struct {
+	// MaxNames truncates the name lists longer than it to their first
+	// MinNames names followed by et al. Zero keeps all the names.
+	MaxNames, MinNames int
+}
+
+// Ref is the plain text view of an entry exposed to the output templates.
+type Ref struct {
+	Key     string
+	Type    string
+	Authors string
+	Year    string
+	Title   string
+	Venue   string
+	DOI     string
+	URL     string
+	Entry   *parse.EntryDecl
+}
+
+// NewRef extracts the decoded values used by the renderers from the entry.
+func NewRef(e *parse.EntryDecl) Ref { return Options{}.NewRef(e) }
+
+// NewRef extracts the decoded values used by the renderers from the entry,
+// truncating its names as the options tell.
+func (o Options) NewRef(e *parse.EntryDecl) Ref {
+	r := Ref{
+		Key:   e.CiteKey,
+		Type:  e.Name,
+		Year:  field(e, "year"),
+		Title: field(e, "title"),
+		DOI:   field(e, "doi"),
+		URL:   field(e, "url"),
+		Entry: e,
+	}
+	for _, f := range venueFields {
+		if v := field(e, f); v != `` {
+			r.Venue = v
+			break
+		}
+	}
+	r.Authors = o.authors(e)
+	return r
+}
+
+// Sort orders the entries in place. Ties are resolved by the following keys in
+// the author, year and title order.
+func Sort(entries []*parse.EntryDecl, o Order) {
+	if o == Unsorted {
+		return
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := sortKeys(entries[i], o), sortKeys(entries[j], o)
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return false
+	})
+}
+
+func sortKeys(e *parse.EntryDecl, o Order) []string {
+	author := ``
+	if ns := names.ParseList(nameList(e)); len(ns) > 0 {
+		author = strings.ToLower(tex.Decode(ns[0].Last))
+	}
+	lang := language(e)
+	year, title := field(e, "year"), SortKey(field(e, "title"), lang)
+	switch o {
+	case ByKey:
+		return []string{strings.ToLower(e.CiteKey)}
+	case ByYear:
+		return []string{year, author, title}
+	case ByTitle:
+		return []string{title, author, year}
+	case ByVenue:
+		return []string{SortKey(NewRef(e).Venue, lang), author, year, title}
+	default:
+		return []string{author, year, title}
+	}
+}
+
+// SortKey returns the lower-case title with the leading article of the
+// language removed so that `The Art of Computer Programming` sorts under A.
+// The language is given by its babel or polyglossia name or its BCP 47 tag,
+// and regional variants such as american, ngerman or de-DE take the articles
+// of their primary language. Titles consisting of the article alone are
+// kept.
+func SortKey(title, language string) string {
+	key := strings.ToLower(strings.TrimSpace(title))
+	for _, a := range Articles[primaryLanguage(language)] {
+		rest := ``
+		if strings.HasSuffix(a, "'") {
+			if !strings.HasPrefix(key, a) {
+				continue
+			}
+			rest = key[len(a):]
+		} else {
+			w, r, ok := strings.Cut(key, " ")
+			if !ok || w != a {
+				continue
+			}
+			rest = r
+		}
+		if rest = strings.TrimSpace(rest); rest != `` {
+			return rest
+		}
+	}
+	return key
+}
+
+// PrimaryLanguage returns the name of the primary language of the language
+// name or tag as listed in Articles, or the name in lower case when it is
+// not known.
+func primaryLanguage(s string) string {
+	if t, ok := lang.Tag(s); ok {
+		primary, _, _ := strings.Cut(t, "-")
+		if n, ok := lang.Name(primary); ok {
+			return n
+		}
+	}
+	return strings.ToLower(s)
+}
+
+// Language returns the lower-case language of the entry.
+func language(e *parse.EntryDecl) string {
+	for _, k := range []string{"langid", "language"} {
+		if v := field(e, k); v != `` {
+			return strings.ToLower(v)
+		}
+	}
+	return DefaultLanguage
+}
+
+// Authors formats the authors, or editors if there are no authors, as
+// `A, B and C`.
+func (o Options) authors(e *parse.EntryDecl) string {
+	f := names.ListFormat{
+		MaxNames: o.MaxNames,
+		MinNames: o.MinNames,
+		EtAl:     " and others",
+		Sep:      ", ",
+		Pair:     " and ",
+		Last:     " and ",
+	}
+	return f.Format(names.ParseList(nameList(e)))
+}
+
+func nameList(e *parse.EntryDecl) string {
+	if f, ok := e.Lookup("author"); ok {
+		return parse.Unquote(f.Value)
+	}
+	if f, ok := e.Lookup("editor"); ok {
+		return parse.Unquote(f.Value)
+	}
+	return ``
+}
+
+// Field returns the decoded value of the field, or of one of its aliases, or
+// an empty string.
+func field(e *parse.EntryDecl, key string) string {
+	if f, ok := e.Lookup(key); ok {
+		return tex.Decode(parse.Unquote(f.Value))
+	}
+	return ``
+}
@@ -0,0 +1,401 @@
+package render
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+var testBib = `
+@article{Cohen1963,
+  author  = "P. J. C{\"o}hen",
+  title   = {The independence of the continuum hypothesis},
+  journal = "Proceedings of the National Academy of Sciences",
+  year    = 1963,
+  doi     = {10.1073/pnas.50.6.1143}
+}
+@book{Babington1993,
+  author    = {Peter Babington and Jane Doe},
+  title     = {The title of the work},
+  publisher = {The name of the publisher},
+  year      = 1993
+}
+@misc{Isley1993,
+  editor = {Peter Isley},
+  title  = {Tom \& Jerry},
+  url    = {https://example.org/?a=1&b=2},
+  year   = 1993
+}
+`
+
+func testEntries(t *testing.T) []*parse.EntryDecl {
+	t.Helper()
+	p := parse.NewParser(scan.NewScanner(scan.NewReader(strings.NewReader(testBib))))
+	result := []*parse.EntryDecl{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		if e, ok := n.(*parse.EntryDecl); ok {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func keys(entries []*parse.EntryDecl) string {
+	result := []string{}
+	for _, e := range entries {
+		result = append(result, e.CiteKey)
+	}
+	return strings.Join(result, " ")
+}
+
+func TestSort(t *testing.T) {
+	cases := []struct {
+		name  string
+		order Order
+		want  string
+	}{
+		{"unsorted", Unsorted, "Cohen1963 Babington1993 Isley1993"},
+		{"key", ByKey, "Babington1993 Cohen1963 Isley1993"},
+		{"author", ByAuthor, "Babington1993 Cohen1963 Isley1993"},
+		{"year", ByYear, "Cohen1963 Babington1993 Isley1993"},
+		{"title", ByTitle, "Cohen1963 Babington1993 Isley1993"},
+		{"venue", ByVenue, "Isley1993 Babington1993 Cohen1963"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			entries := testEntries(t)
+			Sort(entries, c.order)
+			if have := keys(entries); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestSortKey(t *testing.T) {
+	cases := []struct {
+		title string
+		lang  string
+		want  string
+	}{
+		{"The Art of Computer Programming", "english", "art of computer programming"},
+		{"A Theory of Justice", "english", "theory of justice"},
+		{"Anathem", "english", "anathem"},
+		{"The", "english", "the"},
+		{"Der Zauberberg", "german", "zauberberg"},
+		{"Der Zauberberg", "english", "der zauberberg"},
+		{"L'Étranger", "french", "étranger"},
+		{"La Peste", "french", "peste"},
+		{"El Aleph", "klingon", "el aleph"},
+		{"The Great Gatsby", "american", "great gatsby"},
+		{"A Clockwork Orange", "british", "clockwork orange"},
+		{"An Essay", "en", "essay"},
+		{"Das Kapital", "ngerman", "kapital"},
+		{"Die Verwandlung", "de-DE", "verwandlung"},
+		{"Het Achterhuis", "nl-BE", "achterhuis"},
+		{"Les Misérables", "French", "misérables"},
+		{"O Alienista", "brazilian", "alienista"},
+	}
+	for _, c := range cases {
+		t.Run(c.title+"/"+c.lang, func(t *testing.T) {
+			if have := SortKey(c.title, c.lang); have != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
+
+func TestSortLanguage(t *testing.T) {
+	src := `@book{b, title = {Die Blechtrommel}, langid = {german}}
+@book{a, title = {Der Zauberberg}, language = {German}}
+@book{c, title = {Die Hard}}
+`
+	p := parse.NewParser(scan.NewScanner(scan.NewReader(strings.NewReader(src))))
+	entries := []*parse.EntryDecl{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		entries = append(entries, n.(*parse.EntryDecl))
+	}
+	Sort(entries, ByTitle)
+	if have, want := keys(entries), "b c a"; have != want {
+		t.Errorf("have %s; want %s", have, want)
+	}
+}
+
+func TestNewRef(t *testing.T) {
+	entries := testEntries(t)
+	have := NewRef(entries[1])
+	want := Ref{
+		Key:     "Babington1993",
+		Type:    "book",
+		Authors: "Peter Babington and Jane Doe",
+		Year:    "1993",
+		Title:   "The title of the work",
+		Venue:   "The name of the publisher",
+		Entry:   entries[1],
+	}
+	if have != want {
+		t.Errorf("have %v; want %v", have, want)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	want := `<ol class="bibliography">
+  <li id="Cohen1963">P. J. Cöhen (1963). <cite>The independence of the continuum hypothesis</cite>. Proceedings of the National Academy of Sciences. <a href="https://doi.org/10.1073/pnas.50.6.1143">doi:10.1073/pnas.50.6.1143</a></li>
+  <li id="Babington1993">Peter Babington and Jane Doe (1993). <cite>The title of the work</cite>. The name of the publisher.</li>
+  <li id="Isley1993">Peter Isley (1993). <cite>Tom &amp; Jerry</cite>. <a href="https://example.org/?a=1&amp;b=2">https://example.org/?a=1&amp;b=2</a></li>
+</ol>
+`
+	var b strings.Builder
+	if err := HTML(&b, testEntries(t), nil); err != nil {
+		t.Fatal(err)
+	}
+	if have := b.String(); have != want {
+		t.Errorf("have %s; want %s", have, want)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	cases := []struct {
+		name     string
+		template *template.Template
+		want     string
+	}{
+		{
+			name:     "bullets",
+			template: nil,
+			want: `- P. J. Cöhen (1963). *The independence of the continuum hypothesis*. Proceedings of the National Academy of Sciences. [doi:10.1073/pnas.50.6.1143](https://doi.org/10.1073/pnas.50.6.1143)
+- Peter Babington and Jane Doe (1993). *The title of the work*. The name of the publisher.
+- Peter Isley (1993). *Tom & Jerry*. <https://example.org/?a=1&b=2>
+`,
+		},
+		{
+			name:     "definitions",
+			template: DefinitionMarkdown,
+			want: `Cohen1963
+: P. J. Cöhen (1963). *The independence of the continuum hypothesis*. Proceedings of the National Academy of Sciences. [doi:10.1073/pnas.50.6.1143](https://doi.org/10.1073/pnas.50.6.1143)
+
+Babington1993
+: Peter Babington and Jane Doe (1993). *The title of the work*. The name of the publisher.
+
+Isley1993
+: Peter Isley (1993). *Tom & Jerry*. <https://example.org/?a=1&b=2>
+`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := Markdown(&b, testEntries(t), c.template); err != nil {
+				t.Fatal(err)
+			}
+			if have := b.String(); have != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
+
+func TestEscapeMarkdown(t *testing.T) {
+	cases := []struct {
+		name      string
+		testInput string
+		want      string
+	}{
+		{"plain", "The title of the work", "The title of the work"},
+		{"emphasis", "*bold* and _italic_", `\*bold\* and \_italic\_`},
+		{"link", "[not](a link)", `\[not\](a link)`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := escapeMarkdown(c.testInput); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	cases := []struct {
+		name  string
+		style Style
+		want  string
+	}{
+		{
+			name:  "apa",
+			style: APA,
+			want: `Cöhen, P. J. (1963). The independence of the continuum hypothesis. Proceedings of the National Academy of Sciences. https://doi.org/10.1073/pnas.50.6.1143
+Babington, P., & Doe, J. (1993). The title of the work. The name of the publisher.
+Isley, P. (1993). Tom & Jerry. https://example.org/?a=1&b=2
+`,
+		},
+		{
+			name:  "chicago",
+			style: Chicago,
+			want: `Cöhen, P. J. 1963. "The independence of the continuum hypothesis." Proceedings of the National Academy of Sciences. https://doi.org/10.1073/pnas.50.6.1143
+Babington, Peter, and Jane Doe. 1993. The title of the work. The name of the publisher.
+Isley, Peter. 1993. Tom & Jerry. https://example.org/?a=1&b=2
+`,
+		},
+		{
+			name:  "ieee",
+			style: IEEE,
+			want: `[1] P. J. Cöhen, "The independence of the continuum hypothesis," Proceedings of the National Academy of Sciences, 1963, doi: 10.1073/pnas.50.6.1143.
+[2] P. Babington and J. Doe, The title of the work, The name of the publisher, 1993.
+[3] P. Isley, Tom & Jerry, 1993. [Online]. Available: https://example.org/?a=1&b=2
+`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := Text(&b, testEntries(t), c.style); err != nil {
+				t.Fatal(err)
+			}
+			if have := b.String(); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	e := &parse.EntryDecl{
+		Name:    "article",
+		CiteKey: "Cohen1963",
+		Fields: []*parse.FieldStmt{
+			{Key: "author", Value: "{Cohen, Paul J. and Thompson, M. R. and others}"},
+			{Key: "title", Value: "{The independence of the continuum hypothesis}"},
+			{Key: "journal", Value: "{PNAS}"},
+			{Key: "volume", Value: "50"},
+			{Key: "number", Value: "6"},
+			{Key: "pages", Value: "{1143--1148}"},
+		},
+	}
+	cases := []struct {
+		name  string
+		style Style
+		want  string
+	}{
+		{"apa", APA, "Cohen, P. J., Thompson, M. R., et al. (n.d.). The independence of the continuum hypothesis. PNAS, 50(6), 1143–1148."},
+		{"chicago", Chicago, `Cohen, Paul J., M. R. Thompson, et al. "The independence of the continuum hypothesis." PNAS 50 (6): 1143–1148.`},
+		{"ieee", IEEE, `P. J. Cohen, M. R. Thompson, et al., "The independence of the continuum hypothesis," PNAS, vol. 50, no. 6, pp. 1143–1148.`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			have, err := Render(e, c.style)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+	if _, err := Render(e, IEEE+1); !errors.Is(err, ErrStyle) {
+		t.Errorf("have %v; want %v", err, ErrStyle)
+	}
+}
+
+func TestMaxNames(t *testing.T) {
+	e := &parse.EntryDecl{
+		Name:    "book",
+		CiteKey: "Aho1986",
+		Fields: []*parse.FieldStmt{
+			{Key: "author", Value: "{Aho, Alfred V. and Sethi, Ravi and Ullman, Jeffrey D.}"},
+			{Key: "title", Value: "{Compilers}"},
+			{Key: "year", Value: "1986"},
+		},
+	}
+	o := Options{MaxNames: 2, MinNames: 1}
+	cases := []struct {
+		name  string
+		style Style
+		want  string
+	}{
+		{"apa", APA, "Aho, A. V., et al. (1986). Compilers."},
+		{"chicago", Chicago, "Aho, Alfred V., et al. 1986. Compilers."},
+		{"ieee", IEEE, "A. V. Aho, et al., Compilers, 1986."},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			have, err := o.Render(e, c.style)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+	if have, want := o.NewRef(e).Authors, "Alfred V. Aho and others"; have != want {
+		t.Errorf("have %s; want %s", have, want)
+	}
+}
+
+func TestSerializer(t *testing.T) {
+	cases := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "keys",
+			template: "{{.Key}}\n",
+			want:     "Cohen1963\nBabington1993\nIsley1993\n",
+		},
+		{
+			name:     "last-names",
+			template: `{{.Key}}:{{range names (field . "author")}} {{lastName .}}{{end}}` + "\n",
+			want:     "Cohen1963: Cöhen\nBabington1993: Babington Doe\nIsley1993:\n",
+		},
+		{
+			name:     "strip-tex",
+			template: `{{stripTeX (field . "title")}}|{{year .}}` + "\n",
+			want:     "The independence of the continuum hypothesis|1963\nThe title of the work|1993\nTom & Jerry|1993\n",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, err := NewSerializer(c.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b strings.Builder
+			if err := s.SerializeAll(&b, testEntries(t)); err != nil {
+				t.Fatal(err)
+			}
+			if have := b.String(); have != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
+
+func TestSerializerYear(t *testing.T) {
+	e := &parse.EntryDecl{
+		Name:    "online",
+		CiteKey: "ctan",
+		Fields:  []*parse.FieldStmt{{Key: "date", Value: "{2006-03-15}"}},
+	}
+	s, err := NewSerializer("{{year .}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := s.Serialize(&b, e); err != nil {
+		t.Fatal(err)
+	}
+	if have := b.String(); have != "2006" {
+		t.Errorf("have %s; want %s", have, "2006")
+	}
+}
+
+func TestNewSerializerErr(t *testing.T) {
+	if _, err := NewSerializer("{{.Key"); err == nil {
+		t.Error("have nil; want an error")
+	}
+}
@@ -0,0 +1,87 @@
+package render
+
+import (
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/mdm-code/bibx/internal/names"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+// SerializerFuncs are the helper functions available to Serializer templates:
+//
+//	field    returns the raw value of the field of a Ref without delimiters
+//	names    parses a BibTeX name list into a slice of names.Name
+//	lastName returns the decoded von and last parts of a names.Name
+//	stripTeX decodes the TeX markup of a raw value into plain text
+//	year     returns the year of a Ref, falling back on its BibLaTeX date
+var SerializerFuncs = template.FuncMap{
+	"field":    refField,
+	"names":    names.ParseList,
+	"lastName": lastName,
+	"stripTeX": stripTeX,
+	"year":     refYear,
+}
+
+// Serializer writes entries in a user-defined format given as a text/template
+// executed on the Ref of every entry.
+type Serializer struct {
+	Options Options
+	tmpl    *template.Template
+}
+
+// NewSerializer parses the template text with SerializerFuncs available.
+func NewSerializer(text string) (*Serializer, error) {
+	t, err := template.New("serializer").Funcs(SerializerFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &Serializer{tmpl: t}, nil
+}
+
+// Serialize executes the template on a single entry.
+func (s *Serializer) Serialize(w io.Writer, e *parse.EntryDecl) error {
+	return s.tmpl.Execute(w, s.Options.NewRef(e))
+}
+
+// SerializeAll executes the template on each of the entries in turn.
+func (s *Serializer) SerializeAll(w io.Writer, entries []*parse.EntryDecl) error {
+	for _, e := range entries {
+		if err := s.Serialize(w, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func refField(r Ref, key string) string {
+	if f, ok := r.Entry.Get(key); ok {
+		return parse.Unquote(f.Value)
+	}
+	return ``
+}
+
+func lastName(n names.Name) string {
+	return tex.Decode(join(n.Von, n.Last))
+}
+
+func stripTeX(v string) string {
+	return tex.Decode(parse.Unquote(v))
+}
+
+func refYear(r Ref) string {
+	if r.Year != `` {
+		return r.Year
+	}
+	date := refField(r, "date")
+	if len(date) >= 4 && isDigits(date[:4]) {
+		return date[:4]
+	}
+	return ``
+}
+
+func isDigits(s string) bool {
+	return strings.Trim(s, "0123456789") == ``
+}
@@ -0,0 +1,238 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/names"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+const (
+	APA Style = iota
+	Chicago
+	IEEE
+)
+
+// Style is a built-in citation style used to render plain text references.
+type Style uint8
+
+// ErrStyle is returned for a Style that is none of the built-in ones.
+var ErrStyle = errors.New("render: unknown style")
+
+var styleFuncs = [...]func(Ref, []names.Name, Options) string{
+	APA:     apa,
+	Chicago: chicago,
+	IEEE:    ieee,
+}
+
+// Render formats the entry as a plain text reference in the given style. The
+// numeric IEEE label is left out since it depends on the position of the
+// entry in the reference list.
+func Render(e *parse.EntryDecl, s Style) (string, error) {
+	return Options{}.Render(e, s)
+}
+
+// Render is like the Render function but truncates the names as the options
+// tell.
+func (o Options) Render(e *parse.EntryDecl, s Style) (string, error) {
+	if int(s) >= len(styleFuncs) {
+		return ``, fmt.Errorf("%w %d", ErrStyle, s)
+	}
+	return styleFuncs[s](o.NewRef(e), names.ParseList(nameList(e)), o), nil
+}
+
+// Text writes the entries as a plain text reference list in the given style,
+// one reference per line. IEEE references are labeled with their numbers.
+func Text(w io.Writer, entries []*parse.EntryDecl, s Style) error {
+	return Options{}.Text(w, entries, s)
+}
+
+// Text is like the Text function but truncates the names as the options
+// tell.
+func (o Options) Text(w io.Writer, entries []*parse.EntryDecl, s Style) error {
+	for i, e := range entries {
+		ref, err := o.Render(e, s)
+		if err != nil {
+			return err
+		}
+		if s == IEEE {
+			_, err = fmt.Fprintf(w, "[%d] %s\n", i+1, ref)
+		} else {
+			_, err = fmt.Fprintln(w, ref)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// APA formats `Last, F. M., & Last, F. M. (Year). Title. Venue, Vol(No),
+// Pages. DOI`.
+func apa(r Ref, ns []names.Name, o Options) string {
+	var b strings.Builder
+	b.WriteString(o.nameFormat(names.SortOrder, true, ", & ").Format(ns))
+	year := r.Year
+	if year == `` {
+		year = "n.d."
+	}
+	sentence(&b, "("+year+")")
+	sentence(&b, r.Title)
+	venue := r.Venue
+	if vol := field(r.Entry, "volume"); vol != `` && venue != `` {
+		venue += ", " + vol
+		if no := field(r.Entry, "number"); no != `` {
+			venue += "(" + no + ")"
+		}
+	}
+	if pages := field(r.Entry, "pages"); pages != `` && venue != `` {
+		venue += ", " + pages
+	}
+	sentence(&b, venue)
+	link(&b, r)
+	return b.String()
+}
+
+// Chicago formats `Last, First, and First Last. Year. "Title." Venue Vol
+// (No): Pages. DOI` following the author-date system.
+func chicago(r Ref, ns []names.Name, o Options) string {
+	var b strings.Builder
+	b.WriteString(o.nameFormat(names.FirstSortOrder, false, ", and ").Format(ns))
+	sentence(&b, r.Year)
+	if r.Title != `` {
+		if isContained(r.Type) {
+			sentence(&b, `"`+r.Title+`."`)
+		} else {
+			sentence(&b, r.Title)
+		}
+	}
+	venue := r.Venue
+	if vol := field(r.Entry, "volume"); vol != `` && venue != `` {
+		venue += " " + vol
+		if no := field(r.Entry, "number"); no != `` {
+			venue += " (" + no + ")"
+		}
+	}
+	if pages := field(r.Entry, "pages"); pages != `` && venue != `` {
+		venue += ": " + pages
+	}
+	sentence(&b, venue)
+	link(&b, r)
+	return b.String()
+}
+
+// IEEE formats `F. M. Last and F. M. Last, "Title," Venue, vol. Vol, no. No,
+// pp. Pages, Year, doi: DOI.`
+func ieee(r Ref, ns []names.Name, o Options) string {
+	parts := []string{}
+	if len(ns) > 0 {
+		f := o.nameFormat(names.DisplayOrder, true, ", and ")
+		f.Pair = " and "
+		parts = append(parts, f.Format(ns))
+	}
+	if r.Title != `` {
+		if isContained(r.Type) {
+			parts = append(parts, `"`+r.Title+`,"`)
+		} else {
+			parts = append(parts, r.Title)
+		}
+	}
+	if r.Venue != `` {
+		parts = append(parts, r.Venue)
+	}
+	if vol := field(r.Entry, "volume"); vol != `` {
+		parts = append(parts, "vol. "+vol)
+	}
+	if no := field(r.Entry, "number"); no != `` {
+		parts = append(parts, "no. "+no)
+	}
+	if pages := field(r.Entry, "pages"); pages != `` {
+		parts = append(parts, "pp. "+pages)
+	}
+	if r.Year != `` {
+		parts = append(parts, r.Year)
+	}
+	if r.DOI != `` {
+		parts = append(parts, "doi: "+r.DOI)
+	}
+	s := strings.Join(parts, ", ")
+	// The closing comma of a quoted title is not followed by another one.
+	s = strings.ReplaceAll(s, `,", `, `," `)
+	if s != `` && !isTerminated(s) {
+		s += "."
+	}
+	if r.DOI == `` && r.URL != `` {
+		s += " [Online]. Available: " + r.URL
+	}
+	return s
+}
+
+// NameFormat returns the format of the name lists of the style, which joins
+// the last two names with last and truncates the lists as the options tell.
+func (o Options) nameFormat(order names.Order, initials bool, last string) names.ListFormat {
+	return names.ListFormat{
+		Order:    order,
+		Initials: initials,
+		MaxNames: o.MaxNames,
+		MinNames: o.MinNames,
+		EtAl:     ", et al.",
+		Sep:      ", ",
+		Pair:     last,
+		Last:     last,
+	}
+}
+
+// Sentence appends s to the builder as a separate sentence terminated with a
+// period unless it already ends with a punctuation mark.
+func sentence(b *strings.Builder, s string) {
+	if s == `` {
+		return
+	}
+	if b.Len() > 0 {
+		if !isTerminated(b.String()) {
+			b.WriteString(".")
+		}
+		b.WriteString(" ")
+	}
+	b.WriteString(s)
+	if !isTerminated(s) {
+		b.WriteString(".")
+	}
+}
+
+func isTerminated(s string) bool {
+	s = strings.TrimSuffix(s, `"`)
+	return strings.HasSuffix(s, ".") || strings.HasSuffix(s, "?") || strings.HasSuffix(s, "!")
+}
+
+func link(b *strings.Builder, r Ref) {
+	switch {
+	case r.DOI != ``:
+		b.WriteString(" https://doi.org/" + r.DOI)
+	case r.URL != ``:
+		b.WriteString(" " + r.URL)
+	}
+}
+
+// IsContained checks if the entry type is a part of a larger work so that its
+// title is quoted rather than standing on its own.
+func isContained(t string) bool {
+	switch t {
+	case "article", "inproceedings", "incollection", "inbook", "conference":
+		return true
+	}
+	return false
+}
+
+func join(parts ...string) string {
+	result := []string{}
+	for _, p := range parts {
+		if p != `` {
+			result = append(result, p)
+		}
+	}
+	return strings.Join(result, " ")
+}
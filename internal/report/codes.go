@@ -0,0 +1,72 @@
+package report
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+// Codes maps the rule IDs onto their stable codes. The hundreds group the
+// rules by the component reporting them: syntax, lint, schema validation,
+// reference resolution and the remaining commands. A code is never reused
+// or renumbered, and the code of a rule that is removed stays reserved, so
+// that scripts and editors can rely on the codes across versions.
+var codes = map[string]string{
+	"syntax":        "BIBX0001",
+	"nesting-depth": "BIBX0002",
+	"unreadable":    "BIBX0003",
+
+	"latex-special":    "BIBX0101",
+	"sanitize":         "BIBX0102",
+	"field-alias":      "BIBX0103",
+	"arxiv-eprint":     "BIBX0104",
+	"year":             "BIBX0105",
+	"name-format":      "BIBX0106",
+	"duplicate-doi":    "BIBX0107",
+	"undefined-string": "BIBX0108",
+	"unused-string":    "BIBX0109",
+	"month-style":      "BIBX0110",
+	"cite-key":         "BIBX0111",
+	"non-ascii":        "BIBX0112",
+	"target":           "BIBX0113",
+
+	"missing-field":   "BIBX0201",
+	"unknown-field":   "BIBX0202",
+	"invalid-value":   "BIBX0203",
+	"unknown-type":    "BIBX0204",
+	"banned-field":    "BIBX0205",
+	"disallowed-type": "BIBX0206",
+
+	"dangling-reference": "BIBX0301",
+	"reference-cycle":    "BIBX0302",
+	"reference-depth":    "BIBX0303",
+
+	"published-preprint": "BIBX0401",
+	"broken-link":        "BIBX0402",
+}
+
+// Code returns the stable code of the rule, or nothing for rules without
+// one, such as the rules of plugins.
+func Code(rule string) string { return codes[rule] }
+
+// Failure converts the error that stopped a parser at pos into an error of a
+// report.
+func Failure(err error, pos scan.Pos) Finding {
+	f := Finding{Severity: Error, Rule: "syntax", Pos: pos, Message: "invalid BibTeX syntax"}
+	if errors.Is(err, scan.ErrDepth) {
+		f.Rule, f.Message = "nesting-depth", "braces nested too deeply"
+	}
+	return f
+}
+
+// Unreadable converts the error that kept a file from being read into an
+// error of a report. The path is left out of the message since the finding
+// is reported under it.
+func Unreadable(err error) Finding {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		err = pe.Err
+	}
+	return Finding{Severity: Error, Rule: "unreadable", Message: err.Error()}
+}
@@ -0,0 +1,55 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+func TestCodesUnique(t *testing.T) {
+	rules := map[string]string{}
+	for rule, code := range codes {
+		if other, ok := rules[code]; ok {
+			t.Errorf("rules %s and %s share code %s", rule, other, code)
+		}
+		rules[code] = rule
+	}
+}
+
+func TestFailure(t *testing.T) {
+	pos := scan.Pos{Line: 2, Column: 3}
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{errors.New("parse: invalid"), "2:3: error: invalid BibTeX syntax (BIBX0001 syntax)"},
+		{fmt.Errorf("refs.bib: %w", scan.ErrDepth), "2:3: error: braces nested too deeply (BIBX0002 nesting-depth)"},
+	}
+	for _, c := range cases {
+		t.Run(c.want, func(t *testing.T) {
+			if have := Failure(c.err, pos).String(); have != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
+
+func TestUnreadable(t *testing.T) {
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{&fs.PathError{Op: "open", Path: "refs.bib", Err: fs.ErrNotExist}, "error: file does not exist (BIBX0003 unreadable)"},
+		{errors.New("http: 404 Not Found"), "error: http: 404 Not Found (BIBX0003 unreadable)"},
+	}
+	for _, c := range cases {
+		t.Run(c.want, func(t *testing.T) {
+			if have := Unreadable(c.err).String(); have != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
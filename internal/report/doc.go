@@ -0,0 +1,5 @@
+/*
+Report package aggregates the problems found by the validator, the linter and
+the reference resolver, and renders them as text or JSON.
+*/
+package report
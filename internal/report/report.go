@@ -0,0 +1,177 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+// Severity tells how serious a finding is.
+type Severity uint8
+
+var severityNames = [...]string{
+	Info:    "info",
+	Warning: "warning",
+	Error:   "error",
+}
+
+// String returns the lower-case name of the severity.
+func (s Severity) String() string { return severityNames[s] }
+
+// MarshalText encodes the severity as its name.
+func (s Severity) MarshalText() ([]byte, error) { return []byte(s.String()), nil }
+
+// UnmarshalText decodes the severity from its name.
+func (s *Severity) UnmarshalText(text []byte) error {
+	for i, n := range severityNames {
+		if n == string(text) {
+			*s = Severity(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("report: unknown severity %q", text)
+}
+
+// Finding is a single problem found in a source. Rule identifies the check
+// that reported it, Key the entry or @string macro it concerns, and Field
+// the offending field if there is one. The position is the zero value when
+// it is not known. The stable code of the rule is derived from the Rule.
+// Snippet quotes the source at the position, as returned by scan.Snippet,
+// when it is known.
+type Finding struct {
+	Severity Severity `json:"severity"`
+	Rule     string   `json:"rule"`
+	Path     string   `json:"path,omitempty"`
+	Pos      scan.Pos `json:"-"`
+	Key      string   `json:"key,omitempty"`
+	Field    string   `json:"field,omitempty"`
+	Message  string   `json:"message"`
+	Snippet  string   `json:"-"`
+}
+
+// String formats the finding as `path:line:column: severity: key: field:
+// message (code rule)` leaving out the parts that are unknown.
+func (f Finding) String() string {
+	var b strings.Builder
+	loc := f.Path
+	if f.Pos.IsValid() {
+		if loc != `` {
+			loc += ":"
+		}
+		loc += f.Pos.String()
+	}
+	for _, s := range []string{loc, f.Severity.String(), f.Key, f.Field} {
+		if s != `` {
+			b.WriteString(s + ": ")
+		}
+	}
+	if code := Code(f.Rule); code != `` {
+		fmt.Fprintf(&b, "%s (%s %s)", f.Message, code, f.Rule)
+	} else {
+		fmt.Fprintf(&b, "%s (%s)", f.Message, f.Rule)
+	}
+	return b.String()
+}
+
+// MarshalJSON encodes the finding with the code of its rule, and its line
+// and column as top-level members, which are omitted when unknown.
+func (f Finding) MarshalJSON() ([]byte, error) {
+	type finding Finding
+	return json.Marshal(struct {
+		finding
+		Code   string `json:"code,omitempty"`
+		Line   int    `json:"line,omitempty"`
+		Column int    `json:"column,omitempty"`
+	}{finding(f), Code(f.Rule), f.Pos.Line, f.Pos.Column})
+}
+
+// UnmarshalJSON decodes the finding encoded by MarshalJSON.
+func (f *Finding) UnmarshalJSON(data []byte) error {
+	type finding Finding
+	v := struct {
+		*finding
+		Line   int `json:"line"`
+		Column int `json:"column"`
+	}{finding: (*finding)(f)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	f.Pos = scan.Pos{Line: v.Line, Column: v.Column}
+	return nil
+}
+
+// Report is a list of findings in the order they were added.
+type Report struct {
+	Findings []Finding `json:"findings"`
+}
+
+// Add appends the findings to the report, setting their path unless it is
+// already set.
+func (r *Report) Add(path string, fs ...Finding) {
+	for _, f := range fs {
+		if f.Path == `` {
+			f.Path = path
+		}
+		r.Findings = append(r.Findings, f)
+	}
+}
+
+// Quote sets the snippets of the findings in the path that have a position
+// and no snippet yet to the lines of the source they point at.
+func (r *Report) Quote(path string, src []byte) {
+	for i, f := range r.Findings {
+		if f.Path == path && f.Snippet == `` {
+			r.Findings[i].Snippet = scan.Snippet(src, f.Pos)
+		}
+	}
+}
+
+// Count returns the number of findings of at least the given severity.
+func (r *Report) Count(min Severity) int {
+	n := 0
+	for _, f := range r.Findings {
+		if f.Severity >= min {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteText writes the findings one per line, each followed by its snippet
+// indented if it has one.
+func (r *Report) WriteText(w io.Writer) error {
+	for _, f := range r.Findings {
+		if _, err := fmt.Fprintln(w, f); err != nil {
+			return err
+		}
+		if f.Snippet == `` {
+			continue
+		}
+		for _, line := range strings.Split(f.Snippet, "\n") {
+			if _, err := fmt.Fprintln(w, "    "+line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report as an indented JSON object.
+func (r *Report) WriteJSON(w io.Writer) error {
+	if r.Findings == nil {
+		r.Findings = []Finding{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
@@ -0,0 +1,128 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+func TestFindingString(t *testing.T) {
+	cases := []struct {
+		name string
+		f    Finding
+		want string
+	}{
+		{
+			"full",
+			Finding{Severity: Error, Rule: "missing-field", Path: "refs.bib", Pos: scan.Pos{Line: 3, Column: 1}, Key: "a", Field: "title", Message: "missing"},
+			"refs.bib:3:1: error: a: title: missing (BIBX0201 missing-field)",
+		},
+		{
+			"no-path",
+			Finding{Severity: Warning, Rule: "year", Pos: scan.Pos{Line: 2, Column: 5}, Key: "a", Message: "odd year"},
+			"2:5: warning: a: odd year (BIBX0105 year)",
+		},
+		{
+			"no-pos",
+			Finding{Severity: Info, Rule: "r", Path: "<stdin>", Message: "note"},
+			"<stdin>: info: note (r)",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := c.f.String(); have != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	r := &Report{}
+	r.Add("refs.bib",
+		Finding{Severity: Error, Rule: "missing-field", Pos: scan.Pos{Line: 3, Column: 1}, Key: "a", Field: "title", Message: "missing"},
+		Finding{Severity: Warning, Rule: "unused-string", Path: "other.bib", Key: "jx", Message: "unused"},
+	)
+	var b bytes.Buffer
+	if err := r.WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	var raw struct {
+		Findings []map[string]any `json:"findings"`
+	}
+	if err := json.Unmarshal(b.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	if have := raw.Findings[0]["line"]; have != 3.0 {
+		t.Errorf("have line %v; want 3", have)
+	}
+	if _, ok := raw.Findings[1]["line"]; ok {
+		t.Errorf("unknown position encoded")
+	}
+	if have := raw.Findings[0]["severity"]; have != "error" {
+		t.Errorf("have severity %v; want error", have)
+	}
+	decoded := &Report{}
+	if err := json.Unmarshal(b.Bytes(), decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, r) {
+		t.Errorf("have %v; want %v", decoded, r)
+	}
+}
+
+func TestReportCount(t *testing.T) {
+	r := &Report{}
+	r.Add(``, Finding{Severity: Info}, Finding{Severity: Warning}, Finding{Severity: Error})
+	cases := []struct {
+		min  Severity
+		want int
+	}{
+		{Info, 3},
+		{Warning, 2},
+		{Error, 1},
+	}
+	for _, c := range cases {
+		t.Run(c.min.String(), func(t *testing.T) {
+			if have := r.Count(c.min); have != c.want {
+				t.Errorf("have %d; want %d", have, c.want)
+			}
+		})
+	}
+}
+
+func TestEmptyReportJSON(t *testing.T) {
+	var b bytes.Buffer
+	if err := (&Report{}).WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := b.String(), "{\n  \"findings\": []\n}\n"; have != want {
+		t.Errorf("have %q; want %q", have, want)
+	}
+}
+
+func TestReportQuote(t *testing.T) {
+	r := &Report{}
+	r.Add("refs.bib",
+		Finding{Severity: Error, Rule: "syntax", Pos: scan.Pos{Line: 2, Column: 8}, Message: "invalid BibTeX syntax"},
+		Finding{Severity: Warning, Rule: "unused-string", Key: "jx", Message: "unused"},
+	)
+	r.Add("other.bib", Finding{Severity: Warning, Rule: "year", Pos: scan.Pos{Line: 1, Column: 1}, Message: "odd year"})
+	r.Quote("refs.bib", []byte("@misc{a,\n  year = }\n"))
+	var b bytes.Buffer
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `refs.bib:2:8: error: invalid BibTeX syntax (BIBX0001 syntax)
+      year = }
+           ^
+refs.bib: warning: jx: unused (BIBX0109 unused-string)
+other.bib:1:1: warning: odd year (BIBX0105 year)
+`
+	if have := b.String(); have != want {
+		t.Errorf("have %q; want %q", have, want)
+	}
+}
@@ -0,0 +1,5 @@
+/*
+Resolve package resolves the crossref and xdata references between entries,
+letting entries inherit the fields of the entries they refer to.
+*/
+package resolve
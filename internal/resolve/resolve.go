@@ -0,0 +1,235 @@
+package resolve
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/report"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+// Fields never inherited from the referenced entries.
+var private = map[string]bool{
+	"crossref": true,
+	"xdata":    true,
+	"ids":      true,
+	"key":      true,
+}
+
+// DefaultMaxDepth is the longest chain of references resolved by Resolve and
+// Check.
+const DefaultMaxDepth = 32
+
+// Error reports a broken reference in the field of the entry under the Key.
+// Target is the missing cite key of a dangling reference, while Cycle lists
+// the cite keys of a reference cycle starting and ending with the Key. Depth
+// is the limit exceeded by a chain of references too long to resolve. Pos is
+// the position of the referencing field.
+type Error struct {
+	Key    string
+	Field  string
+	Target string
+	Cycle  []string
+	Depth  int
+	Pos    scan.Pos
+}
+
+// Error formats the broken reference as a message prefixed with the cite key
+// and the code of its rule.
+func (e *Error) Error() string {
+	return e.Key + ": " + report.Code(e.rule()) + " " + e.message()
+}
+
+func (e *Error) message() string {
+	if e.Depth > 0 {
+		return fmt.Sprintf("%s references %s nested deeper than %d", e.Field, e.Target, e.Depth)
+	}
+	if len(e.Cycle) > 0 {
+		return fmt.Sprintf("%s reference cycle %s", e.Field, strings.Join(e.Cycle, " -> "))
+	}
+	return fmt.Sprintf("%s refers to undefined entry %s", e.Field, e.Target)
+}
+
+func (e *Error) rule() string {
+	switch {
+	case e.Depth > 0:
+		return "reference-depth"
+	case len(e.Cycle) > 0:
+		return "reference-cycle"
+	}
+	return "dangling-reference"
+}
+
+// Report converts the broken reference into an error of a report.
+func (e *Error) Report() report.Finding {
+	return report.Finding{
+		Severity: report.Error,
+		Rule:     e.rule(),
+		Pos:      e.Pos,
+		Key:      e.Key,
+		Field:    e.Field,
+		Message:  e.message(),
+	}
+}
+
+// Resolve returns copies of the entries with the fields inherited through
+// their crossref and xdata references. Fields already present are kept, and
+// the title of a crossref parent is inherited as the booktitle. References
+// to undefined entries, reference cycles and chains of references longer
+// than DefaultMaxDepth are reported as errors and left unresolved, so
+// resolution always terminates. Cite keys are matched case-insensitively.
+func Resolve(entries []*parse.EntryDecl) ([]*parse.EntryDecl, []error) {
+	return ResolveDepth(entries, DefaultMaxDepth)
+}
+
+// ResolveDepth is like Resolve but resolves chains of references up to
+// maxDepth long, so that services resolving untrusted entries can bound the
+// work done on them.
+func ResolveDepth(entries []*parse.EntryDecl, maxDepth int) ([]*parse.EntryDecl, []error) {
+	r := newResolver(entries)
+	r.maxDepth = maxDepth
+	result := make([]*parse.EntryDecl, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, r.resolve(e))
+	}
+	return result, r.errs
+}
+
+// Check reports the broken references of the entries without resolving
+// them.
+func Check(entries []*parse.EntryDecl) []error {
+	return CheckDepth(entries, DefaultMaxDepth)
+}
+
+// CheckDepth is like Check with the chains of references limited to
+// maxDepth.
+func CheckDepth(entries []*parse.EntryDecl, maxDepth int) []error {
+	_, errs := ResolveDepth(entries, maxDepth)
+	return errs
+}
+
+const (
+	unvisited = iota
+	visiting
+	done
+)
+
+type resolver struct {
+	byKey    map[string]*parse.EntryDecl
+	state    map[*parse.EntryDecl]int
+	resolved map[*parse.EntryDecl]*parse.EntryDecl
+	path     []string
+	maxDepth int
+	errs     []error
+}
+
+func newResolver(entries []*parse.EntryDecl) *resolver {
+	r := &resolver{
+		byKey:    make(map[string]*parse.EntryDecl),
+		state:    make(map[*parse.EntryDecl]int),
+		resolved: make(map[*parse.EntryDecl]*parse.EntryDecl),
+		errs:     []error{},
+	}
+	for _, e := range entries {
+		if k := strings.ToLower(e.CiteKey); r.byKey[k] == nil {
+			r.byKey[k] = e
+		}
+	}
+	return r
+}
+
+// Resolve walks the references depth-first. An entry reached again while
+// its own references are being resolved closes a cycle, and one reached past
+// the longest chain allowed is not resolved.
+func (r *resolver) resolve(e *parse.EntryDecl) *parse.EntryDecl {
+	if res, ok := r.resolved[e]; ok {
+		return res
+	}
+	r.state[e] = visiting
+	r.path = append(r.path, e.CiteKey)
+	res := copyEntry(e)
+	for _, ref := range references(e) {
+		parent, ok := r.byKey[strings.ToLower(ref.target)]
+		if !ok {
+			r.errs = append(r.errs, &Error{Key: e.CiteKey, Field: ref.field, Target: ref.target, Pos: ref.pos})
+			continue
+		}
+		if r.state[parent] == visiting {
+			r.errs = append(r.errs, &Error{Key: e.CiteKey, Field: ref.field, Cycle: r.cycle(parent), Pos: ref.pos})
+			continue
+		}
+		if _, ok := r.resolved[parent]; !ok && len(r.path) > r.maxDepth {
+			r.errs = append(r.errs, &Error{Key: e.CiteKey, Field: ref.field, Target: ref.target, Depth: r.maxDepth, Pos: ref.pos})
+			continue
+		}
+		inherit(res, r.resolve(parent), ref.field == "crossref")
+	}
+	r.path = r.path[:len(r.path)-1]
+	r.state[e] = done
+	r.resolved[e] = res
+	return res
+}
+
+// Cycle lists the keys on the current path from the entry back to itself.
+func (r *resolver) cycle(e *parse.EntryDecl) []string {
+	for i, k := range r.path {
+		if k == e.CiteKey {
+			return append(append([]string{}, r.path[i:]...), e.CiteKey)
+		}
+	}
+	return []string{e.CiteKey}
+}
+
+type reference struct {
+	field, target string
+	pos           scan.Pos
+}
+
+// References lists the xdata references of the entry followed by its
+// crossref so that the fields of the former take precedence.
+func references(e *parse.EntryDecl) []reference {
+	result := []reference{}
+	if f, ok := e.Get("xdata"); ok {
+		for _, k := range strings.Split(parse.Unquote(f.Value), ",") {
+			if k = strings.TrimSpace(k); k != `` {
+				result = append(result, reference{"xdata", k, f.Pos})
+			}
+		}
+	}
+	if f, ok := e.Get("crossref"); ok {
+		if k := strings.TrimSpace(parse.Unquote(f.Value)); k != `` {
+			result = append(result, reference{"crossref", k, f.Pos})
+		}
+	}
+	return result
+}
+
+func inherit(child, parent *parse.EntryDecl, crossref bool) {
+	for _, f := range parent.Fields {
+		key := strings.ToLower(f.Key)
+		if private[key] {
+			continue
+		}
+		if crossref && key == "title" {
+			key = "booktitle"
+		}
+		if _, ok := child.Get(key); !ok {
+			child.Fields = append(child.Fields, &parse.FieldStmt{Key: key, Value: f.Value})
+		}
+	}
+}
+
+func copyEntry(e *parse.EntryDecl) *parse.EntryDecl {
+	res := &parse.EntryDecl{
+		Name:     e.Name,
+		CiteKey:  e.CiteKey,
+		Comments: e.Comments,
+		Pos:      e.Pos,
+		Fields:   make([]*parse.FieldStmt, 0, len(e.Fields)),
+	}
+	for _, f := range e.Fields {
+		res.Fields = append(res.Fields, &parse.FieldStmt{Key: f.Key, Value: f.Value, Pos: f.Pos})
+	}
+	return res
+}
@@ -0,0 +1,96 @@
+package resolve
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mdm-code/bibx/bibxtest"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestResolve(t *testing.T) {
+	entries := []*parse.EntryDecl{
+		bibxtest.Entry("inproceedings", "Cohen1963", "title", "{Sets}", "crossref", "{Proc1963}", "xdata", "{pub}"),
+		bibxtest.Entry("proceedings", "proc1963", "title", "{Proceedings}", "year", "1963", "publisher", "{AMS}", "key", "{p}"),
+		bibxtest.Entry("xdata", "pub", "publisher", "{Springer}", "address", "{Berlin}"),
+	}
+	want := []*parse.EntryDecl{
+		bibxtest.Entry("inproceedings", "Cohen1963",
+			"title", "{Sets}", "crossref", "{Proc1963}", "xdata", "{pub}",
+			"publisher", "{Springer}", "address", "{Berlin}",
+			"booktitle", "{Proceedings}", "year", "1963",
+		),
+		entries[1],
+		entries[2],
+	}
+	have, errs := Resolve(entries)
+	if len(errs) != 0 {
+		t.Fatalf("have %v; want no errors", errs)
+	}
+	for i := range want {
+		if !have[i].Eq(want[i]) {
+			t.Errorf("have %v; want %v", have[i].Fields, want[i].Fields)
+		}
+	}
+	if len(entries[0].Fields) != 3 {
+		t.Error("input entry modified")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	entries := []*parse.EntryDecl{
+		bibxtest.Entry("inbook", "a", "crossref", "{b}"),
+		bibxtest.Entry("book", "b", "crossref", "{c}"),
+		bibxtest.Entry("book", "c", "crossref", "{a}"),
+		bibxtest.Entry("inbook", "d", "crossref", "{missing}", "xdata", "{x1, x2}"),
+		bibxtest.Entry("xdata", "x1", "xdata", "{x1}"),
+	}
+	want := []string{
+		"c: BIBX0302 crossref reference cycle a -> b -> c -> a",
+		"x1: BIBX0302 xdata reference cycle x1 -> x1",
+		"d: BIBX0301 xdata refers to undefined entry x2",
+		"d: BIBX0301 crossref refers to undefined entry missing",
+	}
+	have := Check(entries)
+	if len(have) != len(want) {
+		t.Fatalf("have %v; want %v", have, want)
+	}
+	for i := range want {
+		if have[i].Error() != want[i] {
+			t.Errorf("have %s; want %s", have[i], want[i])
+		}
+	}
+}
+
+func TestResolveDepth(t *testing.T) {
+	entries := []*parse.EntryDecl{
+		bibxtest.Entry("inbook", "a", "crossref", "{b}"),
+		bibxtest.Entry("book", "b", "crossref", "{c}"),
+		bibxtest.Entry("book", "c", "crossref", "{d}"),
+		bibxtest.Entry("book", "d", "publisher", "{P}"),
+	}
+	cases := []struct {
+		depth int
+		want  []string
+	}{
+		{3, []string{}},
+		{2, []string{"c: BIBX0303 crossref references d nested deeper than 2"}},
+		{1, []string{"b: BIBX0303 crossref references c nested deeper than 1"}},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprint(c.depth), func(t *testing.T) {
+			resolved, errs := ResolveDepth(entries, c.depth)
+			if len(errs) != len(c.want) {
+				t.Fatalf("have %v; want %v", errs, c.want)
+			}
+			for i := range c.want {
+				if errs[i].Error() != c.want[i] {
+					t.Errorf("have %s; want %s", errs[i], c.want[i])
+				}
+			}
+			if _, ok := resolved[0].Get("publisher"); ok != (len(c.want) == 0) {
+				t.Errorf("have publisher %t; want %t", ok, len(c.want) == 0)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package scan
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrNoMmap is returned on systems where files cannot be mapped into memory.
+var errNoMmap = errors.New("mmap not supported")
+
+// Mapping is a file mapped read-only into memory.
+type Mapping struct {
+	data   []byte
+	mapped bool
+}
+
+// MapFile maps the file at path into memory so that it can be scanned
+// without reading it all into the heap first. Where mmap is not available,
+// and for files other than regular ones such as pipes and devices, the file
+// is read into memory instead.
+func MapFile(path string) (*Mapping, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return &Mapping{data: data}, nil
+	}
+	size := info.Size()
+	if int64(int(size)) != size {
+		return nil, fmt.Errorf("%s: file too large to map", path)
+	}
+	if size == 0 {
+		return &Mapping{data: []byte{}}, nil
+	}
+	data, err := mmap(f, int(size))
+	if err == nil {
+		return &Mapping{data: data, mapped: true}, nil
+	}
+	if err != errNoMmap {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	data = make([]byte, size)
+	if _, err := io.ReadFull(f, data); err != nil {
+		return nil, err
+	}
+	return &Mapping{data: data}, nil
+}
+
+// Bytes returns the content of the file. It must not be modified, nor used
+// after the mapping is closed.
+func (m *Mapping) Bytes() []byte { return m.data }
+
+// Reader returns a reader over the content of the file. Item values scanned
+// from it are copied out of the mapping, so they stay valid after the mapping
+// is closed, but the reader itself must not be used after that.
+func (m *Mapping) Reader() *Reader {
+	return &Reader{mem: m.data, mapped: true, curr: Pos{Line: 1}}
+}
+
+// Close unmaps the file.
+func (m *Mapping) Close() error {
+	data := m.data
+	m.data = nil
+	if !m.mapped || data == nil {
+		return nil
+	}
+	m.mapped = false
+	return munmap(data)
+}
@@ -0,0 +1,9 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package scan
+
+import "os"
+
+func mmap(*os.File, int) ([]byte, error) { return nil, errNoMmap }
+
+func munmap([]byte) error { return nil }
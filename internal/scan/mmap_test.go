@@ -0,0 +1,90 @@
+package scan
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMapFile(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+	}{
+		{"entry", texEntry},
+		{"unicode", "@book{Żółć, title = {Zażółć gęślą jaźń}}\n"},
+		{"invalid-utf8", "@misc{k, note = {a\xff\xfeb}}"},
+		{"empty", ``},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "refs.bib")
+			if err := os.WriteFile(path, []byte(c.src), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			m, err := MapFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have := string(m.Bytes()); have != c.src {
+				t.Errorf("have %q; want %q", have, c.src)
+			}
+			want, wantPos := scanAll(NewScanner(NewReader(strings.NewReader(c.src))))
+			have, havePos := scanAll(NewScanner(m.Reader()))
+			if err := m.Close(); err != nil {
+				t.Fatal(err)
+			}
+			// The item values must outlive the mapping.
+			if !reflect.DeepEqual(have, want) {
+				t.Errorf("have %v; want %v", have, want)
+			}
+			if !reflect.DeepEqual(havePos, wantPos) {
+				t.Errorf("have positions %v; want %v", havePos, wantPos)
+			}
+		})
+	}
+}
+
+func TestMapFileMissing(t *testing.T) {
+	if _, err := MapFile(filepath.Join(t.TempDir(), "missing.bib")); !os.IsNotExist(err) {
+		t.Errorf("have %v; want a not-exist error", err)
+	}
+}
+
+func TestMapFilePipe(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	path := fmt.Sprintf("/dev/fd/%d", r.Fd())
+	if _, err := os.Stat(path); err != nil {
+		t.Skipf("no %s: %v", path, err)
+	}
+	go func() {
+		w.WriteString(texEntry)
+		w.Close()
+	}()
+	m, err := MapFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if have := string(m.Bytes()); have != texEntry {
+		t.Errorf("have %q; want %q", have, texEntry)
+	}
+}
+
+func scanAll(s *Scanner) ([]Item, []Pos) {
+	items, ps := []Item{}, []Pos{}
+	for {
+		i := s.Next()
+		items, ps = append(items, i), append(ps, s.Pos())
+		if i.T == ItemEOF || i.T == ItemErr {
+			return items, ps
+		}
+	}
+}
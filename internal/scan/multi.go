@@ -0,0 +1,90 @@
+package scan
+
+import (
+	"io"
+	"sort"
+)
+
+// Source is a named input of MultiReader, such as a file and its path.
+type Source struct {
+	Name string
+	R    io.Reader
+}
+
+// MultiReader instantiates a reader reading the sources one after another.
+// Each source starts on a new line, and the positions of the characters read
+// run on across the sources, so that Locate can tell the source and the
+// position within it they come from.
+func MultiReader(sources []Source, opts ...ReaderOption) *Reader {
+	m := &multi{sources: sources}
+	r := NewReader(m, opts...)
+	r.sources = m
+	return r
+}
+
+// Locate returns the name of the source of the position and the position in
+// it. Positions read from a reader other than one returned by MultiReader
+// are returned as they are, without a name.
+func (r *Reader) Locate(p Pos) (string, Pos) {
+	if r.sources == nil || !p.IsValid() {
+		return ``, p
+	}
+	return r.sources.locate(p)
+}
+
+// Multi concatenates the sources, adding a line feed after the sources that
+// do not end with one, and records the line each source starts on.
+type multi struct {
+	sources []Source
+	starts  []int // lines the sources read so far start on
+	lines   int   // line feeds read so far
+	last    byte  // last byte read from the current source
+	feed    bool  // whether a line feed is due before the next source
+	current int
+}
+
+func (m *multi) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for {
+		if m.feed {
+			m.feed = false
+			m.lines++
+			p[0] = '\n'
+			return 1, nil
+		}
+		if m.current == len(m.sources) {
+			return 0, io.EOF
+		}
+		if len(m.starts) == m.current {
+			m.starts = append(m.starts, m.lines+1)
+			m.last = '\n'
+		}
+		n, err := m.sources[m.current].R.Read(p)
+		for _, b := range p[:n] {
+			if b == '\n' {
+				m.lines++
+			}
+		}
+		if n > 0 {
+			m.last = p[n-1]
+		}
+		if err == io.EOF {
+			m.current++
+			m.feed = m.last != '\n'
+			err = nil
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+func (m *multi) locate(p Pos) (string, Pos) {
+	i := sort.Search(len(m.starts), func(i int) bool { return m.starts[i] > p.Line }) - 1
+	if i < 0 {
+		return ``, p
+	}
+	return m.sources[i].Name, Pos{Line: p.Line - m.starts[i] + 1, Column: p.Column}
+}
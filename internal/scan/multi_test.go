@@ -0,0 +1,35 @@
+package scan
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMultiReader(t *testing.T) {
+	r := MultiReader([]Source{
+		{"strings.bib", strings.NewReader("@string{jx = {J. X}}")},
+		{"empty.bib", strings.NewReader(``)},
+		{"refs.bib", strings.NewReader("% Refs\n\n@book{one, journal = jx}\n")},
+		{"more.bib", strings.NewReader("@misc{two}")},
+	}, WithBufferSize(1))
+	s := NewScanner(r)
+	have := []string{}
+	for i := s.Next(); i.T != ItemEOF && i.T != ItemErr; i = s.Next() {
+		if i.T != ItemEntryDelim {
+			continue
+		}
+		name, p := r.Locate(s.Pos())
+		have = append(have, name+":"+p.String())
+	}
+	want := []string{"strings.bib:1:1", "refs.bib:3:1", "more.bib:1:1"}
+	if strings.Join(have, " ") != strings.Join(want, " ") {
+		t.Errorf("have %v; want %v", have, want)
+	}
+}
+
+func TestLocate(t *testing.T) {
+	r := NewReader(strings.NewReader("@misc{k}"))
+	if name, p := r.Locate(Pos{2, 3}); name != `` || p != (Pos{2, 3}) {
+		t.Errorf("have %s %v; want 2:3", name, p)
+	}
+}
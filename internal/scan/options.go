@@ -0,0 +1,42 @@
+package scan
+
+// ReaderOption configures a Reader created by NewReader or NewBytesReader.
+type ReaderOption func(*readerConfig)
+
+type readerConfig struct {
+	size  int
+	start Pos
+}
+
+// WithBufferSize sets the size in bytes of the buffer of a reader reading
+// from an io.Reader. Readers retaining their input in memory or reading from
+// an io.RuneScanner have no buffer and ignore it.
+func WithBufferSize(n int) ReaderOption {
+	return func(c *readerConfig) { c.size = n }
+}
+
+// WithStart sets the position of the first character read, so that a part
+// of a larger source is scanned with the positions it has in the source.
+// Lines and columns below one are taken as one.
+func WithStart(p Pos) ReaderOption {
+	return func(c *readerConfig) { c.start = p }
+}
+
+func newReaderConfig(opts []ReaderOption) readerConfig {
+	c := readerConfig{start: Pos{Line: 1, Column: 1}}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	c.start.Line = max(c.start.Line, 1)
+	c.start.Column = max(c.start.Column, 1)
+	return c
+}
+
+// ScannerOption configures a Scanner created by NewScanner.
+type ScannerOption func(*Scanner)
+
+// WithMaxDepth limits how deeply braces may nest in a field value, as the
+// MaxDepth field of the scanner does.
+func WithMaxDepth(n int) ScannerOption {
+	return func(s *Scanner) { s.MaxDepth = n }
+}
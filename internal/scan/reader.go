@@ -2,7 +2,10 @@ package scan
 
 import (
 	"bufio"
+	"fmt"
 	"io"
+	"strings"
+	"unicode/utf8"
 )
 
 const (
@@ -15,6 +18,35 @@ const (
 type readable interface {
 	Next() char
 	Revert() error
+	Pos() Pos
+}
+
+// Retainer is implemented by readers that may hold the whole input in
+// memory. The scanner uses it to take item values straight out of the source.
+type retainer interface {
+	// Retains reports whether the input is held in memory.
+	retains() bool
+	// Offset returns the byte offset past the last character read.
+	offset() int
+	// Slice returns the source between the byte offsets i and j.
+	slice(i, j int) string
+}
+
+// Pos is a position in the source given as a line and a column counted in
+// runes, both starting at 1. The zero value stands for an unknown position.
+type Pos struct {
+	Line, Column int
+}
+
+// IsValid reports whether the position is known.
+func (p Pos) IsValid() bool { return p.Line > 0 }
+
+// String formats the position as `line:column`.
+func (p Pos) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 // CharStatus describes the status of the read character.
@@ -29,29 +61,128 @@ type char struct {
 
 // Reader handles reading a file and exposing character elements.
 type Reader struct {
-	buf *bufio.Reader
-	pos int
+	buf io.RuneScanner
+	// Source retained by NewBytesReader or the mapped bytes of a file, read
+	// in place when buf is nil.
+	src    string
+	mem    []byte
+	mapped bool
+	// Byte offset past the last character read, and before it.
+	pos, prevOff int
+	// Position of the last character read, and whether it was a newline,
+	// along with the state before the read restored by Revert.
+	curr, prev Pos
+	nl, prevNL bool
+	// Sources concatenated by MultiReader.
+	sources *multi
 }
 
-// NewReader instantiates a new reader.
-func NewReader(r io.Reader) *Reader {
-	return &Reader{bufio.NewReader(r), 0}
+// NewReader instantiates a new reader configured with the options. Readers
+// implementing io.RuneScanner, such as *strings.Reader and *bufio.Reader,
+// are read from directly rather than through another buffer, so that they
+// are left right past the input scanned; others are buffered.
+func NewReader(r io.Reader, opts ...ReaderOption) *Reader {
+	c := newReaderConfig(opts)
+	if rs, ok := r.(io.RuneScanner); ok {
+		return &Reader{buf: rs, curr: before(c.start)}
+	}
+	buf := bufio.NewReader(r)
+	if c.size > 0 {
+		buf = bufio.NewReaderSize(r, c.size)
+	}
+	return &Reader{buf: buf, curr: before(c.start)}
+}
+
+// NewBytesReader instantiates a reader retaining src in memory. Scanners
+// reading from it emit item values sharing a single copy of src instead of
+// building a new string for each token, which cuts down allocations on large
+// inputs considerably.
+func NewBytesReader(src []byte, opts ...ReaderOption) *Reader {
+	c := newReaderConfig(opts)
+	return &Reader{src: validString(src), curr: before(c.start)}
+}
+
+// Before returns the position preceding the first character read, which is
+// at p.
+func before(p Pos) Pos {
+	return Pos{Line: p.Line, Column: p.Column - 1}
 }
 
 // Next returns the next available character.
 func (r *Reader) Next() char {
+	if r.buf == nil {
+		var c rune
+		var s int
+		switch {
+		case r.mapped && r.pos < len(r.mem):
+			c, s = utf8.DecodeRune(r.mem[r.pos:])
+		case !r.mapped && r.pos < len(r.src):
+			c, s = utf8.DecodeRuneInString(r.src[r.pos:])
+		default:
+			r.prevOff = r.pos
+			return char{t: charEOF}
+		}
+		r.advance(c, s)
+		return char{t: charOk, size: s, val: c}
+	}
 	if c, s, err := r.buf.ReadRune(); err != nil {
 		if err == io.EOF {
 			return char{t: charEOF, size: s, val: c}
 		}
 		return char{t: charErr, size: s, val: c}
 	} else {
-		r.pos += s
+		r.advance(c, s)
 		return char{t: charOk, size: s, val: c}
 	}
 }
 
+// Advance moves the reader past the character c of size s.
+func (r *Reader) advance(c rune, s int) {
+	r.prevOff, r.pos = r.pos, r.pos+s
+	r.prev, r.prevNL = r.curr, r.nl
+	if r.nl {
+		r.curr = Pos{Line: r.curr.Line + 1, Column: 1}
+	} else {
+		r.curr.Column++
+	}
+	r.nl = c == '\n'
+}
+
 // Revert unreads a single rune from the buffer.
 func (r *Reader) Revert() error {
-	return r.buf.UnreadRune()
+	if r.buf == nil {
+		if r.pos == r.prevOff {
+			return bufio.ErrInvalidUnreadRune
+		}
+	} else if err := r.buf.UnreadRune(); err != nil {
+		return err
+	}
+	r.pos = r.prevOff
+	r.curr, r.nl = r.prev, r.prevNL
+	return nil
+}
+
+// Pos returns the position of the last character read.
+func (r *Reader) Pos() Pos { return r.curr }
+
+func (r *Reader) retains() bool { return r.buf == nil }
+
+func (r *Reader) offset() int { return r.pos }
+
+func (r *Reader) slice(i, j int) string {
+	if r.mapped {
+		// Copy the text out of the mapping, which may be gone by the time the
+		// item value is used.
+		return validString(r.mem[i:j])
+	}
+	return r.src[i:j]
+}
+
+// ValidString converts b to a string, replacing each byte of invalid UTF-8
+// with the replacement character the way bufio.Reader does.
+func validString(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	return strings.Map(func(r rune) rune { return r }, string(b))
 }
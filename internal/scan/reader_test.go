@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -35,3 +36,47 @@ outer:
 		t.Errorf("want %s; have %s", string(result), texEntry)
 	}
 }
+
+func TestReaderOptions(t *testing.T) {
+	src := "ab\ncd"
+	cases := []struct {
+		name string
+		r    *Reader
+		want []Pos
+	}{
+		{"default", NewReader(strings.NewReader(src)), []Pos{{1, 1}, {1, 2}, {1, 3}, {2, 1}, {2, 2}}},
+		{"buffer", NewReader(strings.NewReader(src), WithBufferSize(16)), []Pos{{1, 1}, {1, 2}, {1, 3}, {2, 1}, {2, 2}}},
+		{"start", NewReader(strings.NewReader(src), WithStart(Pos{4, 7})), []Pos{{4, 7}, {4, 8}, {4, 9}, {5, 1}, {5, 2}}},
+		{"bytes-start", NewBytesReader([]byte(src), WithStart(Pos{2, 1})), []Pos{{2, 1}, {2, 2}, {2, 3}, {3, 1}, {3, 2}}},
+		{"zero-start", NewBytesReader([]byte(src), WithStart(Pos{})), []Pos{{1, 1}, {1, 2}, {1, 3}, {2, 1}, {2, 2}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			have := []Pos{}
+			for ch := c.r.Next(); ch.t == charOk; ch = c.r.Next() {
+				have = append(have, c.r.Pos())
+			}
+			if !reflect.DeepEqual(have, c.want) {
+				t.Errorf("have %v; want %v", have, c.want)
+			}
+		})
+	}
+}
+
+func TestReaderRuneScanner(t *testing.T) {
+	src := strings.NewReader("@misc{key}\nrest")
+	r := NewReader(src)
+	for i := 0; i < 4; i++ {
+		r.Next()
+	}
+	if err := r.Revert(); err != nil {
+		t.Fatal(err)
+	}
+	// The characters read are taken from the caller's reader and no more.
+	if have, want := src.Len(), len("sc{key}\nrest"); have != want {
+		t.Errorf("have %d bytes left; want %d", have, want)
+	}
+	if have := r.Pos(); have != (Pos{1, 3}) {
+		t.Errorf("have %v; want 1:3", have)
+	}
+}
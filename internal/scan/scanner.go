@@ -1,8 +1,12 @@
 package scan
 
 import (
+	"bytes"
+	"errors"
 	"strings"
+	"sync"
 	"unicode"
+	"unicode/utf8"
 )
 
 const (
@@ -72,15 +76,59 @@ type Item struct {
 	Val string
 }
 
+// ErrDepth is reported by Err when the braces of a field value nest deeper
+// than the scanner allows.
+var ErrDepth = errors.New("scan: BIBX0002 braces nested too deeply")
+
 // Scanner parses BibTeX entries.
 type Scanner struct {
+	// MaxDepth limits how deeply braces may nest in a field value, with the
+	// braces delimiting the value at depth one, so that inputs from untrusted
+	// sources cannot exhaust the stack of the code walking the values
+	// recursively. Zero means no limit.
+	MaxDepth int
+
 	reader  readable
+	src     retainer // set when the reader retains the input
+	buf     *[]byte  // text of the item being buffered, unless src is set
+	growing bool     // whether the item being buffered has any text
+	mark    int      // byte offsets of the item being buffered in src
+	end     int
 	items   chan Item
+	queue   []Pos // positions of the items waiting in the channel
+	pos     Pos
 	states  map[state]func(*Scanner) state
 	state   state
 	bracers int
 	entryT  entryT
 	delim   rune
+	failure error
+}
+
+// MaxPooledText is the capacity above which text buffers are not pooled.
+const maxPooledText = 64 << 10
+
+// Texts pools the buffers holding the text of items while they are scanned,
+// so that scanners parsing many inputs in a row do not allocate them anew.
+var texts = sync.Pool{New: func() any { return new([]byte) }}
+
+// States maps each scanner state to the function handling it.
+var states = map[state]func(*Scanner) state{
+	null:                (*Scanner).null,
+	topLvlComment:       (*Scanner).topLvlComment,
+	entryComment:        (*Scanner).entryComment,
+	entryDelim:          (*Scanner).entryDelim,
+	entryType:           (*Scanner).entryType,
+	entryLeftBodyDelim:  (*Scanner).leftBodyDelim,
+	entryRightBodyDelim: (*Scanner).rightBodyDelim,
+	entryCiteKey:        (*Scanner).citeKey,
+	entryComma:          (*Scanner).entryComma,
+	entryFieldType:      (*Scanner).entryFieldType,
+	entryEqSgn:          (*Scanner).entryEqSgn,
+	entryFieldText:      (*Scanner).entryFieldText,
+	entryTypeOrBrace:    (*Scanner).entryTypeOrBrace,
+	eof:                 (*Scanner).eof,
+	err:                 (*Scanner).err,
 }
 
 var delims = map[rune]rune{
@@ -90,30 +138,41 @@ var delims = map[rune]rune{
 	')': '(',
 }
 
-// NewScanner creates a new Scanner instance.
-func NewScanner(r readable) *Scanner {
-	return &Scanner{
-		reader: r,
+// NewScanner creates a new Scanner instance. Given a reader retaining its
+// input, such as the one returned by NewBytesReader, the scanner emits item
+// values referencing the input rather than copies of it. The options are
+// applied in order.
+func NewScanner(r readable, opts ...ScannerOption) *Scanner {
+	s := &Scanner{
 		items:  make(chan Item, 2), // buffered channel of size 2 is necessary and sufficent
-		states: map[state]func(*Scanner) state{
-			null:                (*Scanner).null,
-			topLvlComment:       (*Scanner).topLvlComment,
-			entryComment:        (*Scanner).entryComment,
-			entryDelim:          (*Scanner).entryDelim,
-			entryType:           (*Scanner).entryType,
-			entryLeftBodyDelim:  (*Scanner).leftBodyDelim,
-			entryRightBodyDelim: (*Scanner).rightBodyDelim,
-			entryCiteKey:        (*Scanner).citeKey,
-			entryComma:          (*Scanner).entryComma,
-			entryFieldType:      (*Scanner).entryFieldType,
-			entryEqSgn:          (*Scanner).entryEqSgn,
-			entryFieldText:      (*Scanner).entryFieldText,
-			entryTypeOrBrace:    (*Scanner).entryTypeOrBrace,
-			eof:                 (*Scanner).eof,
-			err:                 (*Scanner).err,
-		},
-		state: null,
+		states: states,
+	}
+	s.Reset(r)
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
+}
+
+// Reset discards the state of the scanner and makes it read from r instead,
+// so that a scanner can be kept in a sync.Pool and reused for many inputs.
+func (s *Scanner) Reset(r readable) {
+	for len(s.items) > 0 {
+		<-s.items
+	}
+	*s = Scanner{
+		MaxDepth: s.MaxDepth,
+		reader:   r,
+		buf:      s.buf,
+		items:    s.items,
+		queue:    s.queue[:0],
+		states:   s.states,
+		state:    null,
+	}
+	if src, ok := r.(retainer); ok && src.retains() {
+		s.src = src
+	}
+	s.discard()
 }
 
 // Item returns the next valid Item parsed by the scanner.
@@ -121,6 +180,7 @@ func (s *Scanner) Next() Item {
 	for {
 		select {
 		case i := <-s.items:
+			s.pos, s.queue = s.queue[0], s.queue[1:]
 			return i
 		default:
 			s.state = s.states[s.state](s)
@@ -128,28 +188,113 @@ func (s *Scanner) Next() Item {
 	}
 }
 
+// Pos returns the position in the source where the item last returned by
+// Next starts.
+func (s *Scanner) Pos() Pos { return s.pos }
+
+// Err returns the reason the scanner emitted ItemErr when it is other than
+// invalid syntax, or nil otherwise.
+func (s *Scanner) Err() error { return s.failure }
+
+// Emit sends the item along with the position it starts at.
+func (s *Scanner) emit(t ItemType, val string, pos Pos) {
+	s.queue = append(s.queue, pos)
+	s.items <- Item{T: t, Val: val}
+}
+
+// Discard drops the text of the item being buffered.
+func (s *Scanner) discard() {
+	if s.buf != nil {
+		*s.buf = (*s.buf)[:0]
+	}
+	s.growing = false
+}
+
+// Grow adds the character to the text of the item being buffered and records
+// the position of its first non-space rune in start.
+func (s *Scanner) grow(c char, start *Pos) {
+	if !start.IsValid() && !unicode.IsSpace(c.val) {
+		*start = s.reader.Pos()
+	}
+	if s.src != nil {
+		// The item text is contiguous in the source, so it is enough to
+		// extend the slice up to the character just read.
+		end := s.src.offset()
+		if !s.growing {
+			s.mark, s.growing = end-c.size, true
+		}
+		s.end = end
+		return
+	}
+	if s.buf == nil {
+		s.buf = texts.Get().(*[]byte)
+	}
+	*s.buf = utf8.AppendRune(*s.buf, c.val)
+}
+
+// Text returns the text of the item buffered so far with the surrounding
+// white space removed.
+func (s *Scanner) text() string {
+	switch {
+	case s.src != nil && s.growing:
+		return strings.TrimSpace(s.src.slice(s.mark, s.end))
+	case s.buf != nil:
+		return string(bytes.TrimSpace(*s.buf))
+	}
+	return ``
+}
+
+// Release returns the text buffer to the pool once the scanner is done.
+func (s *Scanner) release() {
+	if s.buf == nil {
+		return
+	}
+	// Keep large buffers out of the pool so that a single huge field does
+	// not pin its memory for good.
+	if cap(*s.buf) <= maxPooledText {
+		*s.buf = (*s.buf)[:0]
+		texts.Put(s.buf)
+	}
+	s.buf = nil
+}
+
+// Lexeme returns the text of the character just read.
+func (s *Scanner) lexeme(c char) string {
+	if s.src != nil {
+		end := s.src.offset()
+		return s.src.slice(end-c.size, end)
+	}
+	return string(c.val)
+}
+
 // Null is the default startup scanner state.
 func (s *Scanner) null() state {
 	return topLvlComment
 }
 
 func (s *Scanner) topLvlComment() state {
-	buf := ``
+	s.discard()
+	var start Pos
 	for {
 		char := s.reader.Next()
 		if state := checkErr(char); state != null {
+			// Emit the comments trailing the last entry before the end of file
+			buf := s.text()
+			if state == eof && buf != "" {
+				s.emit(ItemComment, buf, start)
+			}
 			return state
 		}
 		switch char.val {
 		case '@':
 			defer s.reader.Revert()
-			buf = strings.TrimSpace(buf)
+			buf := s.text()
 			if buf != "" {
-				s.items <- Item{T: ItemComment, Val: buf}
+				s.emit(ItemComment, buf, start)
 			}
 			return entryDelim
 		default:
-			buf += string(char.val)
+			s.grow(char, &start)
 		}
 	}
 }
@@ -163,7 +308,7 @@ func (s *Scanner) entryDelim() state {
 		}
 		switch char.val {
 		case '@':
-			s.items <- Item{T: ItemEntryDelim, Val: string(char.val)}
+			s.emit(ItemEntryDelim, s.lexeme(char), s.reader.Pos())
 			return entryType
 		}
 	}
@@ -171,7 +316,8 @@ func (s *Scanner) entryDelim() state {
 
 // EntryType parses the specified BibTeX entry type.
 func (s *Scanner) entryType() state {
-	buf := ``
+	s.discard()
+	var start Pos
 	for {
 		char := s.reader.Next()
 		if state := checkErr(char); state != null {
@@ -180,8 +326,11 @@ func (s *Scanner) entryType() state {
 		var t ItemType
 		switch char.val {
 		case '{', '(':
-			buf = strings.TrimSpace(buf)
+			buf := s.text()
 			lower := strings.ToLower(buf)
+			if lower == "comment" {
+				return s.commentBody(buf, char.val, start)
+			}
 			if lower == "preamble" {
 				s.entryT = preamble
 				t = ItemPreamble
@@ -195,11 +344,38 @@ func (s *Scanner) entryType() state {
 			if !IsValidName(buf) {
 				return err
 			}
-			s.items <- Item{T: t, Val: buf}
+			s.emit(t, buf, start)
 			defer s.reader.Revert()
 			return entryLeftBodyDelim
 		default:
-			buf += string(char.val)
+			s.grow(char, &start)
+		}
+	}
+}
+
+// CommentBody reads the body of a @comment declaration up to the delimiter
+// balancing the opening one and emits the whole declaration as a comment, so
+// that the metadata kept in it by tools such as JabRef and BibDesk is passed
+// through as it is.
+func (s *Scanner) commentBody(name string, open rune, start Pos) state {
+	var b strings.Builder
+	b.WriteString("@" + name)
+	b.WriteRune(open)
+	depth := 1
+	for {
+		char := s.reader.Next()
+		if state := checkErr(char); state != null {
+			return state
+		}
+		b.WriteRune(char.val)
+		switch char.val {
+		case open:
+			depth++
+		case delims[open]:
+			if depth--; depth == 0 {
+				s.emit(ItemComment, b.String(), start)
+				return topLvlComment
+			}
 		}
 	}
 }
@@ -213,7 +389,7 @@ func (s *Scanner) leftBodyDelim() state {
 		}
 		switch char.val {
 		case '{', '(':
-			s.items <- Item{T: ItemLeftDelim, Val: string(char.val)}
+			s.emit(ItemLeftDelim, s.lexeme(char), s.reader.Pos())
 			s.delim = char.val
 			s.bracers++
 			switch s.entryT {
@@ -240,7 +416,7 @@ func (s *Scanner) rightBodyDelim() state {
 			if !delimsMatch(s.delim, char.val) {
 				return err
 			}
-			s.items <- Item{T: ItemRightDelim, Val: string(char.val)}
+			s.emit(ItemRightDelim, s.lexeme(char), s.reader.Pos())
 			s.bracers--
 			return null
 		}
@@ -249,7 +425,8 @@ func (s *Scanner) rightBodyDelim() state {
 
 // CiteKey parses the provided BibTeX cite key.
 func (s *Scanner) citeKey() state {
-	buf := ``
+	s.discard()
+	var start Pos
 	for {
 		char := s.reader.Next()
 		if state := checkErr(char); state != null {
@@ -257,15 +434,15 @@ func (s *Scanner) citeKey() state {
 		}
 		switch c := char.val; {
 		case c == ',':
-			buf = strings.TrimSpace(buf)
+			buf := s.text()
 			if !IsValidName(buf) {
 				return err
 			}
-			s.items <- Item{T: ItemCiteKey, Val: buf}
+			s.emit(ItemCiteKey, buf, start)
 			defer s.reader.Revert()
 			return entryComma
 		default:
-			buf += string(c)
+			s.grow(char, &start)
 		}
 	}
 }
@@ -279,14 +456,15 @@ func (s *Scanner) entryComma() state {
 		}
 		switch char.val {
 		case ',':
-			s.items <- Item{T: ItemComma, Val: string(char.val)}
+			s.emit(ItemComma, s.lexeme(char), s.reader.Pos())
 			return entryTypeOrBrace
 		}
 	}
 }
 
 func (s *Scanner) entryComment() state {
-	buf := ``
+	s.discard()
+	var start Pos
 	for {
 		char := s.reader.Next()
 		if state := checkErr(char); state != null {
@@ -295,13 +473,13 @@ func (s *Scanner) entryComment() state {
 		switch char.val {
 		case '\n':
 			// emit the item and traverse to the next state
-			buf = strings.TrimSpace(buf)
+			buf := s.text()
 			if buf != "" {
-				s.items <- Item{T: ItemComment, Val: buf}
+				s.emit(ItemComment, buf, start)
 			}
 			goto cont
 		default:
-			buf += string(char.val)
+			s.grow(char, &start)
 		}
 	}
 
@@ -347,7 +525,8 @@ func (s *Scanner) entryTypeOrBrace() state {
 
 // EntryFieldType parses the field type identifier.
 func (s *Scanner) entryFieldType() state {
-	buf := ``
+	s.discard()
+	var start Pos
 	for {
 		char := s.reader.Next()
 		if state := checkErr(char); state != null {
@@ -355,15 +534,15 @@ func (s *Scanner) entryFieldType() state {
 		}
 		switch char.val {
 		case '=':
-			buf = strings.TrimSpace(buf)
+			buf := s.text()
 			if !IsValidName(buf) {
 				return err
 			}
-			s.items <- Item{T: ItemFieldType, Val: buf}
+			s.emit(ItemFieldType, buf, start)
 			defer s.reader.Revert()
 			return entryEqSgn
 		default:
-			buf += string(char.val)
+			s.grow(char, &start)
 		}
 	}
 }
@@ -377,7 +556,7 @@ func (s *Scanner) entryEqSgn() state {
 		}
 		switch char.val {
 		case '=':
-			s.items <- Item{T: ItemEqSgn, Val: string(char.val)}
+			s.emit(ItemEqSgn, s.lexeme(char), s.reader.Pos())
 			return entryFieldText
 		}
 	}
@@ -386,7 +565,8 @@ func (s *Scanner) entryEqSgn() state {
 // EntryFieldText reads character from the reader looking for the text
 // delimiter.
 func (s *Scanner) entryFieldText() state {
-	buf := ``
+	s.discard()
+	var start Pos
 	quotes := 0
 	var prev rune
 	for {
@@ -397,46 +577,51 @@ func (s *Scanner) entryFieldText() state {
 		switch c := char.val; {
 		case c == '{':
 			s.bracers++
-			buf += string(char.val)
+			// The delimiter of the entry body counts as one of the bracers.
+			if s.MaxDepth > 0 && s.bracers-1 > s.MaxDepth {
+				s.failure = ErrDepth
+				return err
+			}
+			s.grow(char, &start)
 		case c == '"':
 			if prev != '\\' {
 				quotes++
 			}
-			buf += string(char.val)
+			s.grow(char, &start)
 		case (c == '}' || c == ')') && s.bracers == 1:
-			buf = strings.TrimSpace(buf)
+			buf := s.text()
 			if !isValidInt(buf) {
 				if !isProperQuoted(buf) {
 					return err
 				}
 			}
-			s.items <- Item{T: ItemFieldText, Val: buf}
+			s.emit(ItemFieldText, buf, start)
 			defer s.reader.Revert()
 			return entryRightBodyDelim
 		case c == '%' && s.bracers == 1:
-			buf = strings.TrimSpace(buf)
+			buf := s.text()
 			if !isValidInt(buf) {
 				if !isProperQuoted(buf) {
 					return err
 				}
 			}
-			s.items <- Item{T: ItemFieldText, Val: buf}
+			s.emit(ItemFieldText, buf, start)
 			return entryComment
 		case c == '}' && s.bracers > 0:
 			s.bracers--
-			buf += string(char.val)
+			s.grow(char, &start)
 		case c == ',' && quotes%2 == 0 && s.bracers == 1:
-			buf = strings.TrimSpace(buf)
+			buf := s.text()
 			if !isValidInt(buf) {
 				if !isProperQuoted(buf) {
 					return err
 				}
 			}
-			s.items <- Item{T: ItemFieldText, Val: buf}
+			s.emit(ItemFieldText, buf, start)
 			defer s.reader.Revert()
 			return entryComma
 		default:
-			buf += string(char.val)
+			s.grow(char, &start)
 		}
 		prev = char.val
 	}
@@ -444,13 +629,15 @@ func (s *Scanner) entryFieldText() state {
 
 // Eof puts the scanner in the continuous end-of-file state.
 func (s *Scanner) eof() state {
-	s.items <- Item{T: ItemEOF, Val: ``}
+	s.release()
+	s.emit(ItemEOF, ``, s.reader.Pos())
 	return eof
 }
 
 // Err puts the scanner in the continuous error state.
 func (s *Scanner) err() state {
-	s.items <- Item{T: ItemErr, Val: ``}
+	s.release()
+	s.emit(ItemErr, ``, s.reader.Pos())
 	return err
 }
 
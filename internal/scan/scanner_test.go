@@ -1,7 +1,9 @@
 package scan
 
 import (
+	"bytes"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -250,3 +252,165 @@ func TestIsProperQuoted(t *testing.T) {
 		})
 	}
 }
+
+func TestScannerPos(t *testing.T) {
+	src := "% note\n@article{key,\n  title = {T},\n  year = 2000\n}\n"
+	want := map[ItemType][]Pos{
+		ItemComment:    {{1, 1}},
+		ItemEntryDelim: {{2, 1}},
+		ItemCiteKey:    {{2, 10}},
+		ItemFieldType:  {{3, 3}, {4, 3}},
+		ItemFieldText:  {{3, 11}, {4, 10}},
+	}
+	have := make(map[ItemType][]Pos)
+	s := NewScanner(NewReader(strings.NewReader(src)))
+	for i := s.Next(); i.T != ItemEOF && i.T != ItemErr; i = s.Next() {
+		if _, ok := want[i.T]; ok {
+			have[i.T] = append(have[i.T], s.Pos())
+		}
+	}
+	for typ, ps := range want {
+		if !reflect.DeepEqual(have[typ], ps) {
+			t.Errorf("item %v: have %v; want %v", typ, have[typ], ps)
+		}
+	}
+}
+
+func TestPosString(t *testing.T) {
+	cases := []struct {
+		pos  Pos
+		want string
+	}{
+		{Pos{}, "-"},
+		{Pos{Line: 3, Column: 7}, "3:7"},
+	}
+	for _, c := range cases {
+		t.Run(c.want, func(t *testing.T) {
+			if have := c.pos.String(); have != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
+
+func TestBytesReader(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+	}{
+		{"entry", texEntry},
+		{"preamble", texPreamble},
+		{"strings", texStrings},
+		{"unicode", "% Łódź\n@book{Żółć, title = {Zażółć gęślą jaźń}, year = 2001}\n"},
+		{"invalid-utf8", "@misc{k, note = {a\xffb}}"},
+		{"syntax-error", "@article{key title = {T}}"},
+		{"empty", ``},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			want := NewScanner(NewReader(strings.NewReader(c.src)))
+			have := NewScanner(NewBytesReader([]byte(c.src)))
+			for {
+				w, h := want.Next(), have.Next()
+				if h != w || have.Pos() != want.Pos() {
+					t.Fatalf("have %v at %v; want %v at %v", h, have.Pos(), w, want.Pos())
+				}
+				if w.T == ItemEOF || w.T == ItemErr {
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestBytesReaderRevert(t *testing.T) {
+	r := NewBytesReader([]byte("ab"))
+	if err := r.Revert(); err == nil {
+		t.Error("have nil; want error reverting before the first read")
+	}
+	r.Next()
+	if err := r.Revert(); err != nil {
+		t.Errorf("have %v; want nil", err)
+	}
+	if err := r.Revert(); err == nil {
+		t.Error("have nil; want error reverting twice")
+	}
+	if c := r.Next(); c.val != 'a' {
+		t.Errorf("have %q; want %q", c.val, 'a')
+	}
+}
+
+func TestScannerReset(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		read int
+	}{
+		{"done", texEntry, -1},
+		{"failed", "@article{key title = {T}}", -1},
+		{"midway", texEntry, 12},
+		{"unread", texPreamble, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := NewScanner(NewReader(strings.NewReader(c.src)))
+			for i := 0; i != c.read; i++ {
+				if it := s.Next(); it.T == ItemEOF || it.T == ItemErr {
+					break
+				}
+			}
+			s.Reset(NewReader(testTexString()))
+			have := []Item{}
+			for it := s.Next(); it.T != ItemEOF && it.T != ItemErr; it = s.Next() {
+				have = append(have, it)
+			}
+			if !reflect.DeepEqual(have, stringItems) {
+				t.Errorf("have %v; want %v", have, stringItems)
+			}
+		})
+	}
+}
+
+func BenchmarkScanner(b *testing.B) {
+	src := []byte(strings.Repeat(texEntry, 200))
+	readers := []struct {
+		name string
+		new  func() readable
+	}{
+		{"reader", func() readable { return NewReader(bytes.NewReader(src)) }},
+		{"bytes", func() readable { return NewBytesReader(src) }},
+	}
+	for _, r := range readers {
+		b.Run(r.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s := NewScanner(r.new())
+				for it := s.Next(); it.T != ItemEOF && it.T != ItemErr; it = s.Next() {
+				}
+			}
+		})
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	src := []byte("@misc{a,\n\ttitle = {T}},\r\n  yéar = 1\n")
+	cases := []struct {
+		pos  Pos
+		want string
+	}{
+		{Pos{1, 1}, "@misc{a,\n^"},
+		{Pos{2, 13}, "\ttitle = {T}},\n\t           ^"},
+		{Pos{3, 5}, "  yéar = 1\n    ^"},
+		{Pos{3, 20}, "  yéar = 1\n          ^"},
+		{Pos{4, 1}, "\n^"},
+		{Pos{5, 1}, ``},
+		{Pos{}, ``},
+	}
+	for _, c := range cases {
+		t.Run(c.pos.String(), func(t *testing.T) {
+			if have := Snippet(src, c.pos); have != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package scan
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Snippet returns the line of the source holding the position followed by a
+// line with a caret under its column, the way compilers point at errors.
+// Tabs before the column are kept in the caret line so that the caret lines
+// up however wide tabs are shown. The carriage return of a CRLF line ending
+// is dropped. Snippet returns nothing if the position is not in the source.
+func Snippet(src []byte, p Pos) string {
+	if !p.IsValid() {
+		return ``
+	}
+	for i := 1; i < p.Line; i++ {
+		nl := bytes.IndexByte(src, '\n')
+		if nl < 0 {
+			return ``
+		}
+		src = src[nl+1:]
+	}
+	if nl := bytes.IndexByte(src, '\n'); nl >= 0 {
+		src = src[:nl]
+	}
+	line := strings.TrimSuffix(string(bytes.ToValidUTF8(src, []byte("�"))), "\r")
+	var caret strings.Builder
+	col := 1
+	for _, c := range line {
+		if col >= p.Column {
+			break
+		}
+		if c == '\t' {
+			caret.WriteByte('\t')
+		} else {
+			caret.WriteByte(' ')
+		}
+		col++
+	}
+	// Positions past the end of the line, such as that of the end of the
+	// input, point just past its last character.
+	caret.WriteString("^")
+	return line + "\n" + caret.String()
+}
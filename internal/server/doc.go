@@ -0,0 +1,5 @@
+/*
+Server package serves the entries of a BibTeX file over a small REST API
+that reads and writes entries as JSON or BibTeX.
+*/
+package server
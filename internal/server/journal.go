@@ -0,0 +1,170 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/library"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// JournalSuffix is appended to the path of the served file to name its
+// journal.
+const JournalSuffix = ".journal"
+
+// Journal is the write-ahead log of the batches accepted by a server. Each
+// batch is appended to the journal and synced to disk before the file is
+// rewritten, and the journal is removed once the file has been replaced, so
+// a journal left behind holds the batches a crash may have kept out of the
+// file. They are replayed when the server starts.
+type journal struct {
+	path    string
+	mark    int64 // size of the journal before the pending batch
+	pending bool  // whether a batch was appended but not yet written
+}
+
+// Record is a single batch in the journal, written as a line of JSON.
+type record struct {
+	Changes []change `json:"changes"`
+}
+
+// Change is an entry written in BibTeX, or the deletion of the entry under
+// Key when Entry is empty.
+type change struct {
+	Key   string `json:"key"`
+	Entry string `json:"entry,omitempty"`
+}
+
+// Append logs the changes of a batch and syncs the journal.
+func (j *journal) append(changes []library.Change) error {
+	r := record{Changes: make([]change, 0, len(changes))}
+	for _, c := range changes {
+		ch := change{Key: c.Key}
+		if c.Entry != nil {
+			var b strings.Builder
+			if err := format.Node(&b, c.Entry); err != nil {
+				return err
+			}
+			ch.Entry = b.String()
+		}
+		r.Changes = append(r.Changes, ch)
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	j.mark, j.pending = info.Size(), true
+	_, err = f.Write(append(line, '\n'))
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		// Leave no partial line for the next batch to follow.
+		j.undo()
+	}
+	return err
+}
+
+// Undo drops the pending batch from the journal when writing it to the file
+// failed, so that a batch turned down is not replayed.
+func (j *journal) undo() error {
+	if !j.pending {
+		return nil
+	}
+	j.pending = false
+	return os.Truncate(j.path, j.mark)
+}
+
+// Reset removes the journal once the batches in it are safely in the file.
+func (j *journal) reset() error {
+	j.pending = false
+	if err := os.Remove(j.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Records reads the batches of the journal. A last line cut short, left by
+// a crash while it was written, belongs to a batch that was never accepted
+// and is skipped.
+func (j *journal) records() ([]record, error) {
+	data, err := os.ReadFile(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := []record{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, len(data)+1)
+	for n := 1; sc.Scan(); n++ {
+		var r record
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			if !bytes.HasSuffix(data, []byte("\n")) && bytes.HasSuffix(data, sc.Bytes()) {
+				break
+			}
+			return nil, fmt.Errorf("%s:%d: %w", j.path, n, err)
+		}
+		result = append(result, r)
+	}
+	return result, sc.Err()
+}
+
+// Replay applies the batches of the journal to the library as a single
+// batch, which writes them to the file, and removes the journal. It returns
+// the number of changes replayed.
+func (j *journal) replay(lib *library.Library) (int, error) {
+	records, err := j.records()
+	if err != nil || len(records) == 0 {
+		return 0, err
+	}
+	count := 0
+	err = lib.Batch(func(tx *library.Tx) error {
+		for _, r := range records {
+			for _, c := range r.Changes {
+				count++
+				if c.Entry == `` {
+					if _, err := tx.Delete(c.Key); err != nil {
+						return err
+					}
+					continue
+				}
+				nodes, err := parseNodes([]byte(c.Entry), 0)
+				if err != nil {
+					return fmt.Errorf("%s: entry %s: %w", j.path, c.Key, err)
+				}
+				for _, n := range nodes {
+					if e, ok := n.(*parse.EntryDecl); ok {
+						if err := tx.Put(e); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, j.reset()
+}
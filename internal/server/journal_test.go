@@ -0,0 +1,88 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/library"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestJournalReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "library.bib")
+	if err := os.WriteFile(path, []byte(testBib), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	j := &journal{path: path + JournalSuffix}
+	batches := [][]library.Change{
+		{{Key: "Doe2020", Entry: &parse.EntryDecl{Name: "misc", CiteKey: "Doe2020", Fields: []*parse.FieldStmt{{Key: "year", Value: "2020"}}}}},
+		{{Key: "Cohen1963"}, {Key: "Roe2021", Entry: &parse.EntryDecl{Name: "misc", CiteKey: "Roe2021"}}},
+	}
+	for _, b := range batches {
+		if err := j.append(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A batch cut short by a crash.
+	f, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"changes":[{"key":"Lost`)
+	f.Close()
+
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := s.Replayed(), 3; have != want {
+		t.Errorf("have %d changes replayed; want %d", have, want)
+	}
+	src, _ := os.ReadFile(path)
+	for _, want := range []string{"@misc{Doe2020,\n  year = 2020\n}", "@misc{Roe2021", "Babington1993"} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("%s not written back:\n%s", want, src)
+		}
+	}
+	if strings.Contains(string(src), "Cohen1963") || strings.Contains(string(src), "Lost") {
+		t.Errorf("unexpected entries:\n%s", src)
+	}
+	if _, err := os.Stat(j.path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("journal left behind: %v", err)
+	}
+}
+
+func TestJournalCommit(t *testing.T) {
+	s, path := testServer(t)
+	body := `{"type":"misc","key":"Doe2020","fields":{"year":"2020"}}`
+	if w := do(s, http.MethodPost, "/entries", body, "Content-Type", "application/json"); w.Code != http.StatusCreated {
+		t.Fatalf("have status %d; want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	if _, err := os.Stat(path + JournalSuffix); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("journal left behind: %v", err)
+	}
+
+	// Once the file cannot be replaced, the batch is turned down and must
+	// not be replayed.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(path, "blocked"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	body = `{"type":"misc","key":"Roe2021","fields":{"year":"2021"}}`
+	if w := do(s, http.MethodPost, "/entries", body, "Content-Type", "application/json"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("have status %d; want %d: %s", w.Code, http.StatusInternalServerError, w.Body)
+	}
+	records, err := s.journal.records()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Errorf("have %d batches in the journal; want none", len(records))
+	}
+}
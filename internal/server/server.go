@@ -0,0 +1,658 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mdm-code/bibx/internal/atomicfile"
+	"github.com/mdm-code/bibx/internal/dedupe"
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/jsonl"
+	"github.com/mdm-code/bibx/internal/library"
+	"github.com/mdm-code/bibx/internal/metrics"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+// BibTeX is the media type of BibTeX requests and responses.
+const BibTeX = "application/x-bibtex"
+
+// DefaultMaxDepth is the deepest nesting of braces accepted by default in
+// the values of the entries sent in requests.
+const DefaultMaxDepth = 32
+
+// DefaultMaxBodySize is the largest request body in bytes accepted by
+// default.
+const DefaultMaxBodySize = 10 << 20
+
+// Query parameters other than field filters.
+var reserved = map[string]bool{
+	"q":      true,
+	"type":   true,
+	"format": true,
+}
+
+// Server exposes the entries of a BibTeX file over HTTP. Every change is
+// written back to the file, which keeps its @string, @preamble and comment
+// declarations.
+//
+// The entries are listed at /entries, where new entries are created with
+// POST, and each entry lives at /entries/{key}. Updates and deletions must
+// carry the ETag of the entry in the If-Match header so that concurrent
+// changes are not lost. A change is turned down with 409 Conflict when the
+// file has been edited by other programs since the server last read or
+// wrote it. Changes to many entries are applied together, or not
+// at all, by POST to /batch. The metrics of the server are exposed at
+// /metrics in the Prometheus text format.
+type Server struct {
+	// MaxDepth limits how deeply braces may nest in the values of the
+	// entries sent in requests. Zero means DefaultMaxDepth.
+	MaxDepth int
+	// MaxBodySize limits the size of request bodies in bytes. Zero means
+	// DefaultMaxBodySize.
+	MaxBodySize int64
+
+	path     string
+	digest   [sha256.Size]byte // contents of the file as last read or written
+	lib      *library.Library
+	journal  *journal
+	replayed int
+	metrics  *serverMetrics
+}
+
+// ServerMetrics are the metrics exposed by a server.
+type serverMetrics struct {
+	registry    metrics.Registry
+	requests    *metrics.Counter
+	parses      *metrics.Histogram
+	parseErrors *metrics.Counter
+	entries     *metrics.Gauge
+}
+
+func newServerMetrics() *serverMetrics {
+	m := &serverMetrics{}
+	m.requests = m.registry.Counter("bibx_http_requests_total", "HTTP requests served by method and status code.", "method", "code")
+	m.parses = m.registry.Histogram("bibx_parse_duration_seconds", "Time spent parsing BibTeX sources.", metrics.DefaultBuckets)
+	m.parseErrors = m.registry.Counter("bibx_parse_errors_total", "BibTeX sources that failed to parse.")
+	m.entries = m.registry.Gauge("bibx_entries", "Entries in the served file.")
+	return m
+}
+
+// New loads the BibTeX file at path and replays the changes left in its
+// journal.
+func New(path string) (*Server, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{path: path, digest: sha256.Sum256(src), metrics: newServerMetrics()}
+	nodes, err := s.parse(src, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	s.lib = library.New(nodes)
+	s.lib.Commit = s.commit
+	s.count(nodes)
+	s.journal = &journal{path: path + JournalSuffix}
+	if s.replayed, err = s.journal.replay(s.lib); err != nil {
+		return nil, err
+	}
+	s.lib.Journal = s.journal.append
+	return s, nil
+}
+
+// Replayed returns the number of changes recovered from the journal when the
+// server was created, which a crash had kept out of the file.
+func (s *Server) Replayed() int { return s.replayed }
+
+// Parse parses the source like parseNodes and records the time it took and
+// whether it failed.
+func (s *Server) parse(src []byte, maxDepth int) ([]parse.Node, error) {
+	start := time.Now()
+	nodes, err := parseNodes(src, maxDepth)
+	s.metrics.parses.Observe(time.Since(start).Seconds())
+	if err != nil {
+		s.metrics.parseErrors.Inc()
+	}
+	return nodes, err
+}
+
+// Count records the number of entries of the declarations.
+func (s *Server) count(nodes []parse.Node) {
+	entries := 0
+	for _, n := range nodes {
+		if _, ok := n.(*parse.EntryDecl); ok {
+			entries++
+		}
+	}
+	s.metrics.entries.Set(float64(entries))
+}
+
+// Parser is a scanner and parser pair reused across requests.
+type parser struct {
+	s *scan.Scanner
+	p *parse.Parser
+}
+
+// Parsers pools the parsers used to read the request bodies.
+var parsers = sync.Pool{
+	New: func() any {
+		s := scan.NewScanner(scan.NewBytesReader(nil))
+		return &parser{s: s, p: parse.NewParser(s)}
+	},
+}
+
+// ParseNodes parses the source with the braces of the values nested at most
+// maxDepth deep, or to any depth if maxDepth is zero.
+func parseNodes(src []byte, maxDepth int) ([]parse.Node, error) {
+	pp := parsers.Get().(*parser)
+	defer func() {
+		// Drop the references to src before the parser goes back to the pool.
+		pp.s.Reset(scan.NewBytesReader(nil))
+		pp.s.MaxDepth = 0
+		pp.p.Reset(pp.s)
+		parsers.Put(pp)
+	}()
+	pp.s.Reset(scan.NewBytesReader(src))
+	pp.s.MaxDepth = maxDepth
+	pp.p.Reset(pp.s)
+	result := []parse.Node{}
+	for n, ok := pp.p.Next(); ok; n, ok = pp.p.Next() {
+		result = append(result, n)
+	}
+	return result, pp.p.Err()
+}
+
+// ServeHTTP serves the metrics or routes the request to the collection or to
+// a single entry, counting the requests by their status.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/metrics" {
+		s.metrics.registry.ServeHTTP(w, r)
+		return
+	}
+	limit := s.MaxBodySize
+	if limit == 0 {
+		limit = DefaultMaxBodySize
+	}
+	rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+	r.Body = http.MaxBytesReader(rec, r.Body, limit)
+	s.route(rec, r)
+	s.metrics.requests.Inc(methodLabel(r.Method), strconv.Itoa(rec.code))
+}
+
+// MethodLabel returns the method as the label of the request metrics, with
+// the methods the server does not serve counted as other, so that clients
+// cannot grow the metrics without bound.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodDelete:
+		return method
+	}
+	return "other"
+}
+
+// StatusRecorder keeps the status code written to the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/entries":
+		switch r.Method {
+		case http.MethodGet:
+			s.list(w, r)
+		case http.MethodPost:
+			s.create(w, r)
+		default:
+			methodNotAllowed(w, "GET, POST")
+		}
+	case r.URL.Path == "/batch":
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, "POST")
+			return
+		}
+		s.batch(w, r)
+	case strings.HasPrefix(r.URL.Path, "/entries/"):
+		key, err := url.PathUnescape(strings.TrimPrefix(r.URL.Path, "/entries/"))
+		if err != nil || key == `` || strings.Contains(key, "/") {
+			fail(w, http.StatusNotFound, "not found")
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			s.get(w, r, key)
+		case http.MethodPut:
+			s.update(w, r, key)
+		case http.MethodDelete:
+			s.delete(w, r, key)
+		default:
+			methodNotAllowed(w, "GET, PUT, DELETE")
+		}
+	default:
+		fail(w, http.StatusNotFound, "not found")
+	}
+}
+
+// List writes the entries matching the query: q matches any field value,
+// type the entry type, and all other parameters the values of the fields
+// they are named after. Matching is case-insensitive and by substring.
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	result := []*parse.EntryDecl{}
+	for _, n := range s.lib.Nodes() {
+		if e, ok := n.(*parse.EntryDecl); ok && matches(e, q) {
+			result = append(result, e)
+		}
+	}
+	writeList(w, r, http.StatusOK, result)
+}
+
+func matches(e *parse.EntryDecl, q url.Values) bool {
+	if t := q.Get("type"); t != `` && !strings.EqualFold(e.Name, t) {
+		return false
+	}
+	if text := q.Get("q"); text != `` {
+		found := strings.Contains(strings.ToLower(e.CiteKey), strings.ToLower(text))
+		for _, f := range e.Fields {
+			found = found || contains(f.Value, text)
+		}
+		if !found {
+			return false
+		}
+	}
+	for k := range q {
+		if reserved[k] {
+			continue
+		}
+		f, ok := e.Lookup(k)
+		if !ok || !contains(f.Value, q.Get(k)) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(value, text string) bool {
+	return strings.Contains(strings.ToLower(parse.Unquote(value)), strings.ToLower(text))
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request, key string) {
+	var e *parse.EntryDecl
+	s.lib.View(func(tx *library.Tx) error {
+		e = tx.Get(key)
+		return nil
+	})
+	if e == nil {
+		fail(w, http.StatusNotFound, fmt.Sprintf("entry %s not found", key))
+		return
+	}
+	writeEntry(w, r, http.StatusOK, e)
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	e, err := s.readEntry(r)
+	if err != nil {
+		failBody(w, err)
+		return
+	}
+	if e.CiteKey == `` {
+		fail(w, http.StatusBadRequest, "missing cite key")
+		return
+	}
+	err = s.lib.Batch(func(tx *library.Tx) error { return create(tx, e) })
+	if err != nil {
+		failWith(w, err)
+		return
+	}
+	w.Header().Set("Location", "/entries/"+url.PathEscape(e.CiteKey))
+	writeEntry(w, r, http.StatusCreated, e)
+}
+
+func (s *Server) update(w http.ResponseWriter, r *http.Request, key string) {
+	e, err := s.readEntry(r)
+	if err != nil {
+		failBody(w, err)
+		return
+	}
+	if e.CiteKey == `` {
+		e.CiteKey = key
+	}
+	if e.CiteKey != key {
+		fail(w, http.StatusBadRequest, fmt.Sprintf("cite key %s does not match %s", e.CiteKey, key))
+		return
+	}
+	match := r.Header.Get("If-Match")
+	err = s.lib.Batch(func(tx *library.Tx) error { return update(tx, e, match) })
+	if err != nil {
+		failWith(w, err)
+		return
+	}
+	writeEntry(w, r, http.StatusOK, e)
+}
+
+func (s *Server) delete(w http.ResponseWriter, r *http.Request, key string) {
+	match := r.Header.Get("If-Match")
+	err := s.lib.Batch(func(tx *library.Tx) error { return remove(tx, key, match) })
+	if err != nil {
+		failWith(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Operation is a change to a single entry within a batch. Op is create,
+// update or delete, and IfMatch stands for the If-Match header of the
+// request making the same change on its own. Deletions name the entry by Key.
+type operation struct {
+	Op      string        `json:"op"`
+	Key     string        `json:"key"`
+	Entry   *jsonl.Object `json:"entry"`
+	IfMatch string        `json:"if-match"`
+}
+
+// Batch applies the JSON array of operations in the request body in order
+// and writes the entries created or updated. If any of them fails, none is
+// applied, and the response is that of the failed operation.
+func (s *Server) batch(w http.ResponseWriter, r *http.Request) {
+	var ops []operation
+	if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+		failBody(w, err)
+		return
+	}
+	written := []*parse.EntryDecl{}
+	err := s.lib.Batch(func(tx *library.Tx) error {
+		for i, op := range ops {
+			if err := apply(tx, op, &written); err != nil {
+				return fmt.Errorf("operation %d: %w", i, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		failWith(w, err)
+		return
+	}
+	writeList(w, r, http.StatusOK, written)
+}
+
+// Apply makes the change of the operation in the transaction and adds the
+// entry it creates or updates to written.
+func apply(tx *library.Tx, op operation, written *[]*parse.EntryDecl) error {
+	if op.Op == "delete" {
+		return remove(tx, op.Key, op.IfMatch)
+	}
+	if op.Op != "create" && op.Op != "update" {
+		return &requestError{status: http.StatusBadRequest, msg: fmt.Sprintf("unknown operation %q", op.Op)}
+	}
+	if op.Entry == nil || op.Entry.Type == `` || op.Entry.Key == `` {
+		return &requestError{status: http.StatusBadRequest, msg: "missing entry type or cite key"}
+	}
+	e := op.Entry.Entry()
+	var err error
+	if op.Op == "create" {
+		err = create(tx, e)
+	} else {
+		err = update(tx, e, op.IfMatch)
+	}
+	if err == nil {
+		*written = append(*written, e)
+	}
+	return err
+}
+
+// RequestError is an error of a change to the entries answered with the
+// status code. A failed precondition carries the ETag of the current entry.
+type requestError struct {
+	status int
+	msg    string
+	etag   string
+}
+
+func (e *requestError) Error() string { return e.msg }
+
+// Create adds the entry unless there is one under its cite key already.
+func create(tx *library.Tx, e *parse.EntryDecl) error {
+	if tx.Get(e.CiteKey) != nil {
+		return &requestError{status: http.StatusConflict, msg: fmt.Sprintf("entry %s already exists", e.CiteKey)}
+	}
+	return tx.Put(e)
+}
+
+// Update replaces the entry under the cite key of e, which keeps the
+// comments of the entry it replaces, if the ETag matches.
+func update(tx *library.Tx, e *parse.EntryDecl, match string) error {
+	old := tx.Get(e.CiteKey)
+	if old == nil {
+		return &requestError{status: http.StatusNotFound, msg: fmt.Sprintf("entry %s not found", e.CiteKey)}
+	}
+	if err := precondition(match, old); err != nil {
+		return err
+	}
+	e.Comments = old.Comments
+	return tx.Put(e)
+}
+
+// Remove deletes the entry under the cite key if the ETag matches.
+func remove(tx *library.Tx, key, match string) error {
+	old := tx.Get(key)
+	if old == nil {
+		return &requestError{status: http.StatusNotFound, msg: fmt.Sprintf("entry %s not found", key)}
+	}
+	if err := precondition(match, old); err != nil {
+		return err
+	}
+	_, err := tx.Delete(key)
+	return err
+}
+
+// Commit writes the declarations of a batch to the file, which has been
+// logged to the journal. The file is replaced atomically so that a failed
+// write leaves it intact, in which case the batch is dropped from the
+// journal, and the journal is cleared once the file is replaced. A file
+// edited by other programs is not overwritten.
+func (s *Server) commit(nodes []parse.Node) error {
+	if err := s.checkFile(); err != nil {
+		s.journal.undo()
+		return err
+	}
+	if err := s.write(nodes); err != nil {
+		s.journal.undo()
+		return err
+	}
+	// A journal left behind is replayed to the same effect, so failing to
+	// remove it does not fail the batch.
+	s.journal.reset()
+	s.count(nodes)
+	return nil
+}
+
+// CheckFile fails with a conflict if the contents of the file are not those
+// the server last read or wrote.
+func (s *Server) checkFile() error {
+	src, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	if sha256.Sum256(src) != s.digest {
+		return &requestError{status: http.StatusConflict, msg: fmt.Sprintf("%s has been changed by another program; restart the server to load it", s.path)}
+	}
+	return nil
+}
+
+func (s *Server) write(nodes []parse.Node) error {
+	var b bytes.Buffer
+	if err := format.Nodes(&b, nodes); err != nil {
+		return err
+	}
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(s.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := atomicfile.Write(s.path, b.Bytes(), mode); err != nil {
+		return err
+	}
+	s.digest = sha256.Sum256(b.Bytes())
+	return nil
+}
+
+// Precondition checks the If-Match header of a change against the ETag of
+// the current entry.
+func precondition(match string, e *parse.EntryDecl) error {
+	switch {
+	case match == ``:
+		return &requestError{status: http.StatusPreconditionRequired, msg: "missing If-Match header"}
+	case match != "*" && match != etag(e):
+		return &requestError{status: http.StatusPreconditionFailed, msg: "entry has been modified", etag: etag(e)}
+	}
+	return nil
+}
+
+// ETag identifies the version of the entry by its content.
+func etag(e *parse.EntryDecl) string {
+	return `"` + dedupe.Fingerprint(e)[:16] + `"`
+}
+
+// ReadEntry decodes the single entry in the request body, written in BibTeX
+// or as a JSON object.
+func (s *Server) readEntry(r *http.Request) (*parse.EntryDecl, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if isBibTeX(r.Header.Get("Content-Type")) {
+		depth := s.MaxDepth
+		if depth == 0 {
+			depth = DefaultMaxDepth
+		}
+		nodes, err := s.parse(body, depth)
+		if err != nil {
+			return nil, err
+		}
+		es := []*parse.EntryDecl{}
+		for _, n := range nodes {
+			if e, ok := n.(*parse.EntryDecl); ok {
+				es = append(es, e)
+			}
+		}
+		if len(es) != 1 {
+			return nil, fmt.Errorf("want a single entry; have %d", len(es))
+		}
+		return es[0], nil
+	}
+	var o jsonl.Object
+	if err := json.Unmarshal(body, &o); err != nil {
+		return nil, err
+	}
+	if o.Type == `` {
+		return nil, errors.New("missing entry type")
+	}
+	return o.Entry(), nil
+}
+
+func isBibTeX(contentType string) bool {
+	t, _, _ := mime.ParseMediaType(contentType)
+	return t == BibTeX || t == "text/x-bibtex"
+}
+
+// WriteList encodes the entries as a JSON array or in BibTeX.
+func writeList(w http.ResponseWriter, r *http.Request, status int, entries []*parse.EntryDecl) {
+	if wantsBibTeX(r) {
+		writeBibTeX(w, status, entries...)
+		return
+	}
+	objects := make([]jsonl.Object, 0, len(entries))
+	for _, e := range entries {
+		objects = append(objects, jsonl.NewObject(e))
+	}
+	writeJSON(w, status, objects)
+}
+
+// WriteEntry encodes the entry as a JSON object or in BibTeX.
+func writeEntry(w http.ResponseWriter, r *http.Request, status int, e *parse.EntryDecl) {
+	w.Header().Set("ETag", etag(e))
+	if wantsBibTeX(r) {
+		writeBibTeX(w, status, e)
+		return
+	}
+	writeJSON(w, status, jsonl.NewObject(e))
+}
+
+// WantsBibTeX checks if the format parameter or, in its absence, the Accept
+// header of the request ask for BibTeX.
+func wantsBibTeX(r *http.Request) bool {
+	if f := r.URL.Query().Get("format"); f != `` {
+		return f == "bibtex"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "bibtex")
+}
+
+func writeBibTeX(w http.ResponseWriter, status int, entries ...*parse.EntryDecl) {
+	nodes := make([]parse.Node, 0, len(entries))
+	for _, e := range entries {
+		nodes = append(nodes, e)
+	}
+	w.Header().Set("Content-Type", BibTeX+"; charset=utf-8")
+	w.WriteHeader(status)
+	format.Nodes(w, nodes)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
+
+func fail(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// FailWith writes the response of the error of a change: the status of a
+// request error, or an internal server error.
+func failWith(w http.ResponseWriter, err error) {
+	var re *requestError
+	if !errors.As(err, &re) {
+		fail(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if re.etag != `` {
+		w.Header().Set("ETag", re.etag)
+	}
+	fail(w, re.status, err.Error())
+}
+
+// FailBody writes the response of a request body that cannot be read: too
+// large, or malformed.
+func failBody(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		fail(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	fail(w, http.StatusBadRequest, err.Error())
+}
+
+func methodNotAllowed(w http.ResponseWriter, allow string) {
+	w.Header().Set("Allow", allow)
+	fail(w, http.StatusMethodNotAllowed, "method not allowed")
+}
@@ -0,0 +1,311 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testBib = `@string{pnas = "PNAS"}
+
+@article{Cohen1963,
+  author  = {Paul Cohen},
+  title   = {The independence of the continuum hypothesis},
+  journal = pnas,
+  year    = 1963
+}
+
+@book{Babington1993,
+  author = {Peter Babington},
+  title  = {The title of the work},
+  year   = 1993
+}
+`
+
+func testServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "library.bib")
+	if err := os.WriteFile(path, []byte(testBib), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, path
+}
+
+func do(s *Server, method, target, body string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestList(t *testing.T) {
+	s, _ := testServer(t)
+	cases := []struct {
+		target string
+		want   []string
+	}{
+		{"/entries", []string{"Cohen1963", "Babington1993"}},
+		{"/entries?type=book", []string{"Babington1993"}},
+		{"/entries?q=continuum", []string{"Cohen1963"}},
+		{"/entries?year=1993", []string{"Babington1993"}},
+		{"/entries?author=peter&year=1963", []string{}},
+	}
+	for _, c := range cases {
+		t.Run(c.target, func(t *testing.T) {
+			w := do(s, http.MethodGet, c.target, ``)
+			if w.Code != http.StatusOK {
+				t.Fatalf("have status %d; want %d", w.Code, http.StatusOK)
+			}
+			have := strings.Count(w.Body.String(), `"key"`)
+			if have != len(c.want) {
+				t.Errorf("have %d entries; want %d", have, len(c.want))
+			}
+			for _, k := range c.want {
+				if !strings.Contains(w.Body.String(), `"key":"`+k+`"`) {
+					t.Errorf("missing %s in %s", k, w.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	s, _ := testServer(t)
+	w := do(s, http.MethodGet, "/entries/Babington1993?format=bibtex", ``)
+	if w.Code != http.StatusOK {
+		t.Fatalf("have status %d; want %d", w.Code, http.StatusOK)
+	}
+	if w.Header().Get("ETag") == `` {
+		t.Error("missing ETag")
+	}
+	want := "@book{Babington1993,\n  author = {Peter Babington},\n  title  = {The title of the work},\n  year   = 1993\n}\n"
+	if have := w.Body.String(); have != want {
+		t.Errorf("have %q; want %q", have, want)
+	}
+	if w := do(s, http.MethodGet, "/entries/Nobody", ``); w.Code != http.StatusNotFound {
+		t.Errorf("have status %d; want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	s, path := testServer(t)
+	body := `{"type":"misc","key":"Doe2020","fields":{"title":"A note","year":"2020"}}`
+	w := do(s, http.MethodPost, "/entries", body, "Content-Type", "application/json")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("have status %d; want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	if have := w.Header().Get("Location"); have != "/entries/Doe2020" {
+		t.Errorf("have location %s", have)
+	}
+	src, _ := os.ReadFile(path)
+	if !strings.HasSuffix(string(src), "@misc{Doe2020,\n  title = {A note},\n  year  = 2020\n}\n") {
+		t.Errorf("entry not written back:\n%s", src)
+	}
+	if !strings.HasPrefix(string(src), `@string{pnas = "PNAS"}`) {
+		t.Errorf("@string declaration lost:\n%s", src)
+	}
+	if w := do(s, http.MethodPost, "/entries", body); w.Code != http.StatusConflict {
+		t.Errorf("have status %d; want %d", w.Code, http.StatusConflict)
+	}
+	bib := "@misc{Roe2021,\n  title = {B}\n}\n"
+	if w := do(s, http.MethodPost, "/entries", bib, "Content-Type", BibTeX); w.Code != http.StatusCreated {
+		t.Errorf("have status %d; want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+}
+
+func TestCreateMaxDepth(t *testing.T) {
+	s, _ := testServer(t)
+	s.MaxDepth = 2
+	nested := "@misc{Deep,\n  title = {A {B {C}}}\n}\n"
+	if w := do(s, http.MethodPost, "/entries", nested, "Content-Type", BibTeX); w.Code != http.StatusBadRequest {
+		t.Errorf("have status %d; want %d", w.Code, http.StatusBadRequest)
+	}
+	s.MaxDepth = 3
+	if w := do(s, http.MethodPost, "/entries", nested, "Content-Type", BibTeX); w.Code != http.StatusCreated {
+		t.Errorf("have status %d; want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	s, _ := testServer(t)
+	s.MaxBodySize = 32
+	entry := "@misc{Long,\n  title = {A title longer than the limit}\n}\n"
+	if w := do(s, http.MethodPost, "/entries", entry, "Content-Type", BibTeX); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("have status %d; want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+	batch := `[{"op":"delete","key":"Cohen1963","if-match":"*"}]`
+	if w := do(s, http.MethodPost, "/batch", batch); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("have status %d; want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestExternalEdit(t *testing.T) {
+	s, path := testServer(t)
+	edited := testBib + "\n@misc{Added, year = 2024}\n"
+	if err := os.WriteFile(path, []byte(edited), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	body := `{"type":"misc","key":"Doe2020","fields":{"year":"2020"}}`
+	if w := do(s, http.MethodPost, "/entries", body, "Content-Type", "application/json"); w.Code != http.StatusConflict {
+		t.Errorf("have status %d; want %d: %s", w.Code, http.StatusConflict, w.Body)
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(src) != edited {
+		t.Errorf("have file\n%s\nwant it left as edited", src)
+	}
+	if w := do(s, http.MethodGet, "/entries/Doe2020", ``); w.Code != http.StatusNotFound {
+		t.Errorf("have status %d; want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	s, path := testServer(t)
+	tag := do(s, http.MethodGet, "/entries/Babington1993", ``).Header().Get("ETag")
+	body := "@book{Babington1993,\n  author = {Peter Babington},\n  title = {A new title},\n  year = 1994\n}\n"
+	cases := []struct {
+		name   string
+		header []string
+		want   int
+	}{
+		{"missing", []string{"Content-Type", BibTeX}, http.StatusPreconditionRequired},
+		{"stale", []string{"Content-Type", BibTeX, "If-Match", `"0000"`}, http.StatusPreconditionFailed},
+		{"current", []string{"Content-Type", BibTeX, "If-Match", tag}, http.StatusOK},
+		{"outdated", []string{"Content-Type", BibTeX, "If-Match", tag}, http.StatusPreconditionFailed},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := do(s, http.MethodPut, "/entries/Babington1993", body, c.header...)
+			if w.Code != c.want {
+				t.Errorf("have status %d; want %d: %s", w.Code, c.want, w.Body)
+			}
+		})
+	}
+	src, _ := os.ReadFile(path)
+	if !strings.Contains(string(src), "A new title") {
+		t.Errorf("update not written back:\n%s", src)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	s, path := testServer(t)
+	if w := do(s, http.MethodDelete, "/entries/Cohen1963", ``, "If-Match", "*"); w.Code != http.StatusNoContent {
+		t.Fatalf("have status %d; want %d", w.Code, http.StatusNoContent)
+	}
+	src, _ := os.ReadFile(path)
+	if strings.Contains(string(src), "Cohen1963") {
+		t.Errorf("entry not deleted:\n%s", src)
+	}
+	if w := do(s, http.MethodDelete, "/entries/Cohen1963", ``, "If-Match", "*"); w.Code != http.StatusNotFound {
+		t.Errorf("have status %d; want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		want int
+		keys []string
+	}{
+		{
+			name: "applied",
+			body: `[
+				{"op": "create", "entry": {"type": "misc", "key": "Doe2020", "fields": {"year": "2020"}}},
+				{"op": "update", "if-match": "*", "entry": {"type": "book", "key": "Babington1993", "fields": {"year": "1994"}}},
+				{"op": "delete", "key": "Cohen1963", "if-match": "*"}
+			]`,
+			want: http.StatusOK,
+			keys: []string{"Babington1993", "Doe2020"},
+		},
+		{
+			name: "rolled-back",
+			body: `[
+				{"op": "delete", "key": "Cohen1963", "if-match": "*"},
+				{"op": "create", "entry": {"type": "book", "key": "Babington1993"}}
+			]`,
+			want: http.StatusConflict,
+			keys: []string{"Cohen1963", "Babington1993"},
+		},
+		{
+			name: "stale",
+			body: `[{"op": "delete", "key": "Cohen1963", "if-match": "\"0000\""}]`,
+			want: http.StatusPreconditionFailed,
+			keys: []string{"Cohen1963", "Babington1993"},
+		},
+		{
+			name: "unknown",
+			body: `[{"op": "rename", "key": "Cohen1963"}]`,
+			want: http.StatusBadRequest,
+			keys: []string{"Cohen1963", "Babington1993"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, path := testServer(t)
+			w := do(s, http.MethodPost, "/batch", c.body, "Content-Type", "application/json")
+			if w.Code != c.want {
+				t.Fatalf("have status %d; want %d: %s", w.Code, c.want, w.Body)
+			}
+			src, _ := os.ReadFile(path)
+			w = do(s, http.MethodGet, "/entries", ``)
+			for _, k := range []string{"Cohen1963", "Babington1993", "Doe2020"} {
+				want := false
+				for _, ck := range c.keys {
+					want = want || ck == k
+				}
+				if have := strings.Contains(w.Body.String(), `"key":"`+k+`"`); have != want {
+					t.Errorf("%s: have listed %v; want %v", k, have, want)
+				}
+				if have := strings.Contains(string(src), k); have != want {
+					t.Errorf("%s: have written %v; want %v", k, have, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	s, _ := testServer(t)
+	w := do(s, http.MethodPatch, "/entries", ``)
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, POST" {
+		t.Errorf("have status %d, allow %q", w.Code, w.Header().Get("Allow"))
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	s, _ := testServer(t)
+	do(s, http.MethodGet, "/entries", ``)
+	do(s, http.MethodGet, "/entries/Nobody", ``)
+	do(s, http.MethodPost, "/entries", "@misc{", "Content-Type", BibTeX)
+	do(s, "BREW", "/entries", ``)
+	w := do(s, http.MethodGet, "/metrics", ``)
+	if w.Code != http.StatusOK {
+		t.Fatalf("have status %d; want %d", w.Code, http.StatusOK)
+	}
+	for _, want := range []string{
+		`bibx_http_requests_total{method="GET",code="200"} 1`,
+		`bibx_http_requests_total{method="GET",code="404"} 1`,
+		`bibx_http_requests_total{method="POST",code="400"} 1`,
+		`bibx_http_requests_total{method="other",code="405"} 1`,
+		"bibx_parse_duration_seconds_count 2",
+		"bibx_parse_errors_total 1",
+		"bibx_entries 2",
+	} {
+		if !strings.Contains(w.Body.String(), want+"\n") {
+			t.Errorf("missing %s in\n%s", want, w.Body)
+		}
+	}
+}
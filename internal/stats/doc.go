@@ -0,0 +1,6 @@
+/*
+Stats package computes statistics of a bibliography, such as the numbers of
+entries per type and per year, the most frequent venues and authors, and how
+complete the entries are, and writes them as text, JSON or Markdown tables.
+*/
+package stats
@@ -0,0 +1,275 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/names"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/tex"
+	"github.com/mdm-code/bibx/internal/validate"
+)
+
+// Width of the longest bar of the text histogram of years.
+const barWidth = 40
+
+// Count is the number of entries sharing the Name, such as an entry type, a
+// year, a venue or an author.
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Score is the average completeness of the entries of a type.
+type Score struct {
+	Type         string  `json:"type"`
+	Completeness float64 `json:"completeness"`
+}
+
+// Report holds the statistics of a bibliography. Years list the years from
+// the earliest to the latest one, including the years without entries within
+// gaps of up to maxGap years, and Undated counts the entries without a numeric year. Completeness is the
+// average share of the required fields present in the entries.
+type Report struct {
+	Entries        int     `json:"entries"`
+	FieldsPerEntry float64 `json:"fields_per_entry"`
+	Completeness   float64 `json:"completeness"`
+	Types          []Count `json:"types"`
+	Years          []Count `json:"years"`
+	Undated        int     `json:"undated"`
+	Venues         []Count `json:"venues"`
+	Authors        []Count `json:"authors"`
+	Scores         []Score `json:"scores"`
+}
+
+// Compute computes the statistics of the entries with the completeness
+// measured against the schemas. Venues and authors are limited to the top
+// most frequent ones unless top is zero or less.
+func Compute(entries []*parse.EntryDecl, schemas *validate.Set, top int) *Report {
+	r := &Report{Entries: len(entries)}
+	types := counter{}
+	venues := counter{}
+	authors := counter{}
+	years := map[int]int{}
+	scores := map[string]float64{}
+	fields := 0
+	total := 0.0
+	for _, e := range entries {
+		typ := strings.ToLower(e.Name)
+		types.add(typ)
+		fields += len(e.Fields)
+		score := schemas.Completeness(e)
+		scores[typ] += score
+		total += score
+		if year, err := strconv.Atoi(text(e, "year")); err == nil {
+			years[year]++
+		} else {
+			r.Undated++
+		}
+		venue := text(e, "journal")
+		if venue == `` {
+			venue = text(e, "booktitle")
+		}
+		if venue != `` {
+			venues.add(venue)
+		}
+		if f, ok := e.Get("author"); ok {
+			for _, n := range names.ParseList(parse.Unquote(f.Value)) {
+				if !n.IsOthers() {
+					authors.add(tex.Decode(n.String()))
+				}
+			}
+		}
+	}
+	if len(entries) > 0 {
+		r.FieldsPerEntry = float64(fields) / float64(len(entries))
+		r.Completeness = total / float64(len(entries))
+	}
+	r.Types = types.sorted(0)
+	r.Venues = venues.sorted(top)
+	r.Authors = authors.sorted(top)
+	r.Years = histogram(years)
+	r.Scores = []Score{}
+	for _, c := range r.Types {
+		r.Scores = append(r.Scores, Score{Type: c.Name, Completeness: scores[c.Name] / float64(c.Count)})
+	}
+	sort.Slice(r.Scores, func(i, j int) bool { return r.Scores[i].Type < r.Scores[j].Type })
+	return r
+}
+
+func text(e *parse.EntryDecl, key string) string {
+	if f, ok := e.Get(key); ok {
+		return tex.Decode(parse.Unquote(f.Value))
+	}
+	return ``
+}
+
+// Counter counts the names in the order they first appear.
+type counter struct {
+	names  []string
+	counts map[string]int
+}
+
+func (c *counter) add(name string) {
+	if c.counts == nil {
+		c.counts = make(map[string]int)
+	}
+	if c.counts[name] == 0 {
+		c.names = append(c.names, name)
+	}
+	c.counts[name]++
+}
+
+// Sorted returns the top counts from the most frequent names down, with ties
+// broken by name.
+func (c *counter) sorted(top int) []Count {
+	result := []Count{}
+	for _, n := range c.names {
+		result = append(result, Count{Name: n, Count: c.counts[n]})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	if top > 0 && len(result) > top {
+		result = result[:top]
+	}
+	return result
+}
+
+// MaxGap is the longest run of years without entries listed in the
+// histogram. Longer runs are left out, so that an outlying year does not add
+// a count for every year up to it.
+const maxGap = 10
+
+// Histogram lists the counts of the years in order, along with the years
+// without entries in the gaps of up to maxGap years between them.
+func histogram(years map[int]int) []Count {
+	sorted := make([]int, 0, len(years))
+	for y := range years {
+		sorted = append(sorted, y)
+	}
+	sort.Ints(sorted)
+	result := []Count{}
+	for i, y := range sorted {
+		if i > 0 && y-sorted[i-1]-1 <= maxGap {
+			for g := sorted[i-1] + 1; g < y; g++ {
+				result = append(result, Count{Name: strconv.Itoa(g)})
+			}
+		}
+		result = append(result, Count{Name: strconv.Itoa(y), Count: years[y]})
+	}
+	return result
+}
+
+// WriteText writes the report as aligned plain text with a histogram of the
+// years.
+func (r *Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Entries:          %d\n", r.Entries)
+	fmt.Fprintf(&b, "Fields per entry: %.1f\n", r.FieldsPerEntry)
+	fmt.Fprintf(&b, "Completeness:     %.1f%%\n", r.Completeness*100)
+	writeCounts(&b, "Types", r.Types)
+	if len(r.Years) > 0 || r.Undated > 0 {
+		b.WriteString("\nYears\n")
+		peak := 0
+		for _, c := range r.Years {
+			peak = max(peak, c.Count)
+		}
+		for _, c := range r.Years {
+			bar := strings.Repeat("#", (c.Count*barWidth+peak-1)/peak)
+			fmt.Fprintln(&b, strings.TrimRight(fmt.Sprintf("  %s %4d %s", c.Name, c.Count, bar), " "))
+		}
+		if r.Undated > 0 {
+			fmt.Fprintf(&b, "  n.d. %4d\n", r.Undated)
+		}
+	}
+	writeCounts(&b, "Venues", r.Venues)
+	writeCounts(&b, "Authors", r.Authors)
+	if len(r.Scores) > 0 {
+		b.WriteString("\nCompleteness by type\n")
+		width := 0
+		for _, s := range r.Scores {
+			if len(s.Type) > width {
+				width = len(s.Type)
+			}
+		}
+		for _, s := range r.Scores {
+			fmt.Fprintf(&b, "  %-*s %5.1f%%\n", width, s.Type, s.Completeness*100)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeCounts(b *strings.Builder, title string, counts []Count) {
+	if len(counts) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n%s\n", title)
+	width := 0
+	for _, c := range counts {
+		if n := len([]rune(c.Name)); n > width {
+			width = n
+		}
+	}
+	for _, c := range counts {
+		pad := strings.Repeat(" ", width-len([]rune(c.Name)))
+		fmt.Fprintf(b, "  %s%s %4d\n", c.Name, pad, c.Count)
+	}
+}
+
+// WriteJSON writes the report as an indented JSON object.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteMarkdown writes the report as a list of the totals followed by
+// Markdown tables.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "- Entries: %d\n", r.Entries)
+	fmt.Fprintf(&b, "- Fields per entry: %.1f\n", r.FieldsPerEntry)
+	fmt.Fprintf(&b, "- Completeness: %.1f%%\n", r.Completeness*100)
+	writeTable(&b, "Type", r.Types)
+	years := r.Years
+	if r.Undated > 0 {
+		years = append(append([]Count{}, years...), Count{Name: "n.d.", Count: r.Undated})
+	}
+	writeTable(&b, "Year", years)
+	writeTable(&b, "Venue", r.Venues)
+	writeTable(&b, "Author", r.Authors)
+	if len(r.Scores) > 0 {
+		b.WriteString("\n| Type | Completeness |\n| --- | ---: |\n")
+		for _, s := range r.Scores {
+			fmt.Fprintf(&b, "| %s | %.1f%% |\n", escapeCell(s.Type), s.Completeness*100)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeTable(b *strings.Builder, title string, counts []Count) {
+	if len(counts) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n| %s | Entries |\n| --- | ---: |\n", title)
+	for _, c := range counts {
+		fmt.Fprintf(b, "| %s | %d |\n", escapeCell(c.Name), c.Count)
+	}
+}
+
+// EscapeCell keeps the pipes and the line breaks of the text from breaking
+// a table row.
+func escapeCell(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ").Replace(s)
+}
@@ -0,0 +1,115 @@
+package stats
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/mdm-code/bibx/bibxtest"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/validate"
+)
+
+var testEntries = []*parse.EntryDecl{
+	bibxtest.Entry("article", "key", "author", "{Cohen, Paul and G{\\\"o}del, Kurt}", "title", "{T}", "journal", "{PNAS}", "year", "1963"),
+	bibxtest.Entry("Article", "key", "author", "{Paul Cohen and others}", "title", "{T}", "journal", "{PNAS}", "year", "1965"),
+	bibxtest.Entry("inproceedings", "key", "author", "{Kurt G{\\\"o}del}", "booktitle", "{Proc}", "year", "1963"),
+	bibxtest.Entry("misc", "key", "title", "{M}"),
+}
+
+func TestCompute(t *testing.T) {
+	have := Compute(testEntries, validate.Builtin, 1)
+	want := &Report{
+		Entries:        4,
+		FieldsPerEntry: 3,
+		Completeness:   (1 + 1 + 0.75 + 1) / 4,
+		Types:          []Count{{"article", 2}, {"inproceedings", 1}, {"misc", 1}},
+		Years:          []Count{{"1963", 2}, {"1964", 0}, {"1965", 1}},
+		Undated:        1,
+		Venues:         []Count{{"PNAS", 2}},
+		Authors:        []Count{{"Kurt Gödel", 2}},
+		Scores:         []Score{{"article", 1}, {"inproceedings", 0.75}, {"misc", 1}},
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have %+v; want %+v", have, want)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var b bytes.Buffer
+	if err := Compute(testEntries[:2], validate.Builtin, 0).WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `Entries:          2
+Fields per entry: 4.0
+Completeness:     100.0%
+
+Types
+  article    2
+
+Years
+  1963    1 ########################################
+  1964    0
+  1965    1 ########################################
+
+Venues
+  PNAS    2
+
+Authors
+  Paul Cohen    2
+  Kurt Gödel    1
+
+Completeness by type
+  article 100.0%
+`
+	if have := b.String(); have != want {
+		t.Errorf("have\n%s\nwant\n%s", have, want)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var b bytes.Buffer
+	r := &Report{Entries: 1, Types: []Count{{"misc", 1}}, Undated: 1, Venues: []Count{{"A|B", 1}}}
+	if err := r.WriteMarkdown(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `- Entries: 1
+- Fields per entry: 0.0
+- Completeness: 0.0%
+
+| Type | Entries |
+| --- | ---: |
+| misc | 1 |
+
+| Year | Entries |
+| --- | ---: |
+| n.d. | 1 |
+
+| Venue | Entries |
+| --- | ---: |
+| A\|B | 1 |
+`
+	if have := b.String(); have != want {
+		t.Errorf("have\n%s\nwant\n%s", have, want)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	cases := []struct {
+		name  string
+		years map[int]int
+		want  []Count
+	}{
+		{"empty", map[int]int{}, []Count{}},
+		{"gap", map[int]int{1963: 2, 1965: 1}, []Count{{"1963", 2}, {"1964", 0}, {"1965", 1}}},
+		{"outlier", map[int]int{1: 1, 2000: 1, 2001: 3}, []Count{{"1", 1}, {"2000", 1}, {"2001", 3}}},
+		{"negative", map[int]int{-1: 1, 1: 1}, []Count{{"-1", 1}, {"0", 0}, {"1", 1}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := histogram(c.years); !reflect.DeepEqual(have, c.want) {
+				t.Errorf("have %v; want %v", have, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+/*
+Tabular package imports bibliography records kept in CSV or TSV spreadsheets
+as BibTeX entries, using a mapping from columns onto fields.
+*/
+package tabular
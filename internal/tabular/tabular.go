@@ -0,0 +1,158 @@
+package tabular
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/citekey"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/scan"
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+// Mapping describes how the columns of delimited data translate into entries.
+type Mapping struct {
+	// Comma is the column delimiter; a comma is used when it is zero.
+	Comma rune
+	// Columns maps column headers onto field keys. When nil, every column
+	// other than the type and key columns becomes a field named after its
+	// lowercased header.
+	Columns map[string]string
+	// TypeColumn names the column holding the entry type.
+	TypeColumn string
+	// KeyColumn names the column holding the cite key. Keys are generated
+	// for rows without one.
+	KeyColumn string
+	// DefaultType is the type of entries without one; misc when empty.
+	DefaultType string
+}
+
+// Read converts delimited data with a header row into entries. Plain text
+// cell values are escaped for TeX and empty cells are skipped.
+func Read(r io.Reader, m Mapping) ([]*parse.EntryDecl, error) {
+	cr := csv.NewReader(r)
+	if m.Comma != 0 {
+		cr.Comma = m.Comma
+	}
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err == io.EOF {
+		return []*parse.EntryDecl{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cols, err := m.columns(header)
+	if err != nil {
+		return nil, err
+	}
+	taken := map[string]bool{}
+	result := []*parse.EntryDecl{}
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		e, err := m.entry(header, cols, row)
+		if err != nil {
+			return nil, fmt.Errorf("tabular: line %d: %w", line, err)
+		}
+		if e.CiteKey == `` {
+			e.CiteKey = citekey.Unique(citekey.Generate(e), taken)
+		} else if taken[e.CiteKey] {
+			return nil, fmt.Errorf("tabular: line %d: duplicate cite key %q", line, e.CiteKey)
+		} else {
+			taken[e.CiteKey] = true
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+// Columns resolves the field key of every column, leaving unmapped columns
+// with an empty key.
+func (m Mapping) columns(header []string) ([]string, error) {
+	index := map[string]bool{}
+	for _, h := range header {
+		index[h] = true
+	}
+	for _, c := range []string{m.TypeColumn, m.KeyColumn} {
+		if c != `` && !index[c] {
+			return nil, fmt.Errorf("tabular: unknown column %q", c)
+		}
+	}
+	for c := range m.Columns {
+		if !index[c] {
+			return nil, fmt.Errorf("tabular: unknown column %q", c)
+		}
+	}
+	result := make([]string, len(header))
+	for i, h := range header {
+		if h == m.TypeColumn || h == m.KeyColumn {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(h))
+		if m.Columns != nil {
+			key = m.Columns[h]
+		}
+		if key != `` && !scan.IsValidName(key) {
+			return nil, fmt.Errorf("tabular: invalid field name %q", key)
+		}
+		result[i] = key
+	}
+	return result, nil
+}
+
+func (m Mapping) entry(header, cols, row []string) (*parse.EntryDecl, error) {
+	e := &parse.EntryDecl{Name: m.DefaultType, Comments: &parse.CommentGroupExpr{}}
+	if e.Name == `` {
+		e.Name = "misc"
+	}
+	for i, v := range row {
+		if i >= len(header) {
+			return nil, errors.New("more cells than columns")
+		}
+		v = strings.TrimSpace(v)
+		if v == `` {
+			continue
+		}
+		switch header[i] {
+		case m.TypeColumn:
+			e.Name = strings.ToLower(v)
+			continue
+		case m.KeyColumn:
+			e.CiteKey = v
+			continue
+		}
+		if cols[i] == `` {
+			continue
+		}
+		if isNumber(v) {
+			e.Set(cols[i], v)
+		} else {
+			e.Set(cols[i], parse.Quote(tex.Escape(v)))
+		}
+	}
+	if !scan.IsValidName(e.Name) {
+		return nil, fmt.Errorf("invalid entry type %q", e.Name)
+	}
+	if e.CiteKey != `` && !scan.IsValidName(e.CiteKey) {
+		return nil, fmt.Errorf("invalid cite key %q", e.CiteKey)
+	}
+	return e, nil
+}
+
+func isNumber(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ``
+}
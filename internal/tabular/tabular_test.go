@@ -0,0 +1,115 @@
+package tabular
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestRead(t *testing.T) {
+	cases := []struct {
+		name    string
+		source  string
+		mapping Mapping
+		want    []*parse.EntryDecl
+	}{
+		{
+			name: "mapped",
+			source: `Kind,Authors,Title,Year,Notes
+article,"Cohen, Paul J.",The independence of the continuum hypothesis,1963,ignored
+,Peter Babington,Tom & Jerry,1993,
+`,
+			mapping: Mapping{
+				Columns:     map[string]string{"Authors": "author", "Title": "title", "Year": "year"},
+				TypeColumn:  "Kind",
+				DefaultType: "book",
+			},
+			want: []*parse.EntryDecl{
+				{
+					Name:     "article",
+					CiteKey:  "Cohen1963",
+					Comments: &parse.CommentGroupExpr{},
+					Fields: []*parse.FieldStmt{
+						{Key: "author", Value: "{Cohen, Paul J.}"},
+						{Key: "title", Value: "{The independence of the continuum hypothesis}"},
+						{Key: "year", Value: "1963"},
+					},
+				},
+				{
+					Name:     "book",
+					CiteKey:  "Babington1993",
+					Comments: &parse.CommentGroupExpr{},
+					Fields: []*parse.FieldStmt{
+						{Key: "author", Value: "{Peter Babington}"},
+						{Key: "title", Value: `{Tom \& Jerry}`},
+						{Key: "year", Value: "1993"},
+					},
+				},
+			},
+		},
+		{
+			name:    "tsv-identity",
+			source:  "key\tAuthor\tyear\nCohen1963\tP. J. Cohen\t1963\n\tP. J. Cohen\t1963\n",
+			mapping: Mapping{Comma: '\t', KeyColumn: "key"},
+			want: []*parse.EntryDecl{
+				{
+					Name:     "misc",
+					CiteKey:  "Cohen1963",
+					Comments: &parse.CommentGroupExpr{},
+					Fields: []*parse.FieldStmt{
+						{Key: "author", Value: "{P. J. Cohen}"},
+						{Key: "year", Value: "1963"},
+					},
+				},
+				{
+					Name:     "misc",
+					CiteKey:  "Cohen1963a",
+					Comments: &parse.CommentGroupExpr{},
+					Fields: []*parse.FieldStmt{
+						{Key: "author", Value: "{P. J. Cohen}"},
+						{Key: "year", Value: "1963"},
+					},
+				},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			have, err := Read(strings.NewReader(c.source), c.mapping)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(have) != len(c.want) {
+				t.Fatalf("have %d entries; want %d", len(have), len(c.want))
+			}
+			for i := range c.want {
+				if !have[i].Eq(c.want[i]) {
+					t.Errorf("have %s %v; want %s %v", have[i].CiteKey, have[i].Fields, c.want[i].CiteKey, c.want[i].Fields)
+				}
+			}
+		})
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		source  string
+		mapping Mapping
+	}{
+		{"unknown-column", "a,b\n1,2\n", Mapping{Columns: map[string]string{"c": "title"}}},
+		{"unknown-type-column", "a,b\n1,2\n", Mapping{TypeColumn: "type"}},
+		{"invalid-field", "a b\nx\n", Mapping{}},
+		{"invalid-type", "type,title\nnot valid,x\n", Mapping{TypeColumn: "type"}},
+		{"duplicate-key", "key\nCohen1963\nCohen1963\n", Mapping{KeyColumn: "key"}},
+		{"extra-cells", "title\nx,y\n", Mapping{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := Read(strings.NewReader(c.source), c.mapping); err == nil {
+				t.Error("have nil; want an error")
+			}
+		})
+	}
+}
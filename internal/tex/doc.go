@@ -0,0 +1,5 @@
+/*
+Tex package translates between the TeX markup used inside BibTeX field values
+and plain Unicode text.
+*/
+package tex
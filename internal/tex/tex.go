@@ -0,0 +1,419 @@
+package tex
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Accents lists pairs of base and accented characters per accent command.
+var accents = map[string]string{
+	`'`: "aáeéiíoóuúyýcćnńsśzźrŕlĺgǵAÁEÉIÍOÓUÚYÝCĆNŃSŚZŹRŔLĹ",
+	"`": "aàeèiìoòuùnǹAÀEÈIÌOÒUÙNǸ",
+	`^`: "aâeêiîoôuûcĉgĝhĥjĵsŝwŵyŷAÂEÊIÎOÔUÛCĈGĜHĤJĴSŜWŴYŶ",
+	`"`: "aäeëiïoöuüyÿAÄEËIÏOÖUÜYŸ",
+	`~`: "aãnñoõiĩuũAÃNÑOÕIĨUŨ",
+	`c`: "cçsştţgģkķlļnņrŗCÇSŞTŢGĢKĶLĻNŅRŖ",
+	`v`: "cčdďeěnňrřsštťzžCČDĎEĚNŇRŘSŠTŤZŽ",
+	`=`: "aāeēiīoōuūAĀEĒIĪOŌUŪ",
+	`u`: "aăgğuŭeĕoŏiĭAĂGĞUŬEĔOŎIĬ",
+	`.`: "cċeėgġzżIİCĊEĖGĠZŻ",
+	`r`: "aåuůAÅUŮ",
+	`H`: "oőuűOŐUŰ",
+	`k`: "aąeęiįuųAĄEĘIĮUŲ",
+}
+
+// Marks maps accent commands to combining characters used as the fallback
+// for base characters without a precomposed form.
+var marks = map[string]rune{
+	`'`: '\u0301',
+	"`": '\u0300',
+	`^`: '\u0302',
+	`"`: '\u0308',
+	`~`: '\u0303',
+	`c`: '\u0327',
+	`v`: '\u030C',
+	`=`: '\u0304',
+	`u`: '\u0306',
+	`.`: '\u0307',
+	`r`: '\u030A',
+	`H`: '\u030B',
+	`k`: '\u0328',
+}
+
+// Symbols maps argument-less TeX commands onto Unicode text.
+var symbols = map[string]string{
+	"\\":                " ",
+	"-":                 "",
+	"/":                 "",
+	",":                 " ",
+	"ss":                "ß",
+	"SS":                "SS",
+	"o":                 "ø",
+	"O":                 "Ø",
+	"ae":                "æ",
+	"AE":                "Æ",
+	"oe":                "œ",
+	"OE":                "Œ",
+	"aa":                "å",
+	"AA":                "Å",
+	"l":                 "ł",
+	"L":                 "Ł",
+	"i":                 "ı",
+	"j":                 "ȷ",
+	"dh":                "ð",
+	"DH":                "Ð",
+	"th":                "þ",
+	"TH":                "Þ",
+	"ng":                "ŋ",
+	"NG":                "Ŋ",
+	"textendash":        "–",
+	"textemdash":        "—",
+	"dots":              "…",
+	"ldots":             "…",
+	"textellipsis":      "…",
+	"S":                 "§",
+	"P":                 "¶",
+	"copyright":         "©",
+	"textregistered":    "®",
+	"texttrademark":     "™",
+	"pounds":            "£",
+	"euro":              "€",
+	"textdegree":        "°",
+	"textbackslash":     `\`,
+	"textasciitilde":    "~",
+	"textasciicircum":   "^",
+	"TeX":               "TeX",
+	"LaTeX":             "LaTeX",
+	"BibTeX":            "BibTeX",
+	"textquoteleft":     "‘",
+	"textquoteright":    "’",
+	"textquotedblleft":  "“",
+	"textquotedblright": "”",
+}
+
+// Spelled lists the letters Fold spells out in ASCII, as their symbol
+// commands do. Punctuation is left alone.
+var spelled = []struct {
+	from rune
+	to   string
+}{
+	{'ß', "ss"}, {'ø', "o"}, {'Ø', "O"}, {'æ', "ae"}, {'Æ', "AE"},
+	{'œ', "oe"}, {'Œ', "OE"}, {'å', "aa"}, {'Å', "AA"}, {'ł', "l"},
+	{'Ł', "L"}, {'ı', "i"}, {'ȷ', "j"}, {'ð', "dh"}, {'Ð', "DH"},
+	{'þ', "th"}, {'Þ', "TH"}, {'ŋ', "ng"}, {'Ŋ', "NG"},
+}
+
+// Ligatures lists the TeX input ligatures in the order they are matched.
+var ligatures = []struct{ from, to string }{
+	{"---", "—"},
+	{"--", "–"},
+	{"``", "“"},
+	{"''", "”"},
+	{"!`", "¡"},
+	{"?`", "¿"},
+}
+
+// Specials are escaped to be taken literally by TeX.
+var specials = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`&`, `\&`,
+	`%`, `\%`,
+	`$`, `\$`,
+	`#`, `\#`,
+	`_`, `\_`,
+	`~`, `\textasciitilde{}`,
+	`^`, `\textasciicircum{}`,
+)
+
+var composed = compose()
+
+var folded = fold()
+
+var encoded = encode()
+
+var markCommands = func() map[rune]string {
+	m := make(map[rune]string)
+	for cmd, r := range marks {
+		m[r] = cmd
+	}
+	return m
+}()
+
+func compose() map[string]rune {
+	m := make(map[string]rune)
+	for cmd, pairs := range accents {
+		rs := []rune(pairs)
+		for i := 0; i+1 < len(rs); i += 2 {
+			m[cmd+string(rs[i])] = rs[i+1]
+		}
+	}
+	return m
+}
+
+func fold() map[rune]string {
+	m := make(map[rune]string)
+	for cmd, r := range composed {
+		m[r] = string([]rune(cmd)[1])
+	}
+	for _, l := range spelled {
+		m[l.from] = l.to
+	}
+	return m
+}
+
+// Encode lists the TeX markup of non-ASCII characters. Where several symbol
+// commands produce the same character, the first one in alphabetical order is
+// used, and input ligatures take precedence over symbol commands.
+func encode() map[rune]string {
+	m := make(map[rune]string)
+	for cmd, r := range composed {
+		rs := []rune(cmd)
+		m[r] = accented(string(rs[0]), rs[1])
+	}
+	cmds := make([]string, 0, len(symbols))
+	for cmd := range symbols {
+		cmds = append(cmds, cmd)
+	}
+	sort.Strings(cmds)
+	for _, cmd := range cmds {
+		r := []rune(symbols[cmd])
+		if len(r) != 1 || r[0] <= unicode.MaxASCII {
+			continue
+		}
+		if _, ok := m[r[0]]; !ok {
+			m[r[0]] = `{\` + cmd + "}"
+		}
+	}
+	for _, l := range ligatures {
+		m[[]rune(l.to)[0]] = l.from
+	}
+	return m
+}
+
+// Escape prepares plain text for the use in a field value by escaping the
+// characters with a special meaning in TeX.
+func Escape(s string) string {
+	return specials.Replace(s)
+}
+
+const (
+	PassThrough Encoding = iota
+	ASCII
+)
+
+// Encoding selects how non-ASCII characters are written in BibTeX output.
+// PassThrough keeps Unicode text for biber, XeLaTeX and LuaLaTeX, while ASCII
+// encodes it as TeX markup for classic BibTeX and pdfLaTeX.
+type Encoding uint8
+
+// Apply converts the field value to the encoding.
+func (enc Encoding) Apply(s string) string {
+	if enc == ASCII {
+		return Encode(s)
+	}
+	return s
+}
+
+// Encode converts non-ASCII characters in the field value into TeX markup
+// understood by classic BibTeX engines, such as {\"o} for ö or -- for the en
+// dash. Characters followed by combining accents are encoded as accented
+// letters. ASCII text, including any markup already present, is left intact,
+// and so are the characters that have no TeX equivalent.
+func Encode(s string) string {
+	var b strings.Builder
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		if i+1 < len(rs) && unicode.IsLetter(r) {
+			if cmd, ok := markCommands[rs[i+1]]; ok {
+				b.WriteString(accented(cmd, r))
+				i++
+				continue
+			}
+		}
+		if e, ok := encoded[r]; ok {
+			b.WriteString(e)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func accented(cmd string, r rune) string {
+	if unicode.IsLetter([]rune(cmd)[0]) {
+		return `{\` + cmd + "{" + string(r) + "}}"
+	}
+	return `{\` + cmd + string(r) + "}"
+}
+
+// Fold strips diacritics from the decoded text and spells out letters such
+// as ß or æ with their ASCII counterparts. Other characters are left as is.
+func Fold(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if f, ok := folded[r]; ok {
+			b.WriteString(f)
+		} else if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Decode converts TeX markup commonly found in BibTeX field values into plain
+// Unicode text. Accent and symbol commands are resolved, formatting commands
+// are replaced with their arguments, and grouping braces are removed.
+func Decode(s string) string {
+	d := decoder{src: []rune(s)}
+	d.run()
+	return strings.Join(strings.Fields(d.buf.String()), " ")
+}
+
+type decoder struct {
+	src []rune
+	pos int
+	buf strings.Builder
+}
+
+func (d *decoder) run() {
+	for d.pos < len(d.src) {
+		c := d.src[d.pos]
+		switch c {
+		case '\\':
+			d.pos++
+			d.command()
+		case '{', '}', '$':
+			d.pos++
+		case '~':
+			d.pos++
+			d.buf.WriteRune(' ')
+		default:
+			if d.ligature() {
+				continue
+			}
+			d.pos++
+			d.buf.WriteRune(c)
+		}
+	}
+}
+
+func (d *decoder) ligature() bool {
+	rest := string(d.src[d.pos:min(d.pos+3, len(d.src))])
+	for _, l := range ligatures {
+		if strings.HasPrefix(rest, l.from) {
+			d.buf.WriteString(l.to)
+			d.pos += len([]rune(l.from))
+			return true
+		}
+	}
+	return false
+}
+
+func (d *decoder) command() {
+	if d.pos >= len(d.src) {
+		return
+	}
+	name := d.name()
+	if _, ok := marks[name]; ok {
+		d.accent(name)
+		return
+	}
+	if sym, ok := symbols[name]; ok {
+		d.buf.WriteString(sym)
+		d.skipSpace(name)
+		return
+	}
+	if len([]rune(name)) == 1 && !unicode.IsLetter([]rune(name)[0]) {
+		// Escaped special characters like \&, \%, \{ and control spaces.
+		d.buf.WriteString(name)
+		return
+	}
+	// Unknown and formatting commands such as \emph or \textbf are dropped
+	// leaving their arguments in place.
+	d.skipSpace(name)
+}
+
+// Name reads the command name: either a run of letters or a single
+// non-letter character.
+func (d *decoder) name() string {
+	start := d.pos
+	if !unicode.IsLetter(d.src[d.pos]) {
+		d.pos++
+		return string(d.src[start:d.pos])
+	}
+	for d.pos < len(d.src) && unicode.IsLetter(d.src[d.pos]) {
+		d.pos++
+	}
+	return string(d.src[start:d.pos])
+}
+
+// SkipSpace consumes the white space terminating letter-named commands.
+func (d *decoder) skipSpace(name string) {
+	if !unicode.IsLetter([]rune(name)[0]) {
+		return
+	}
+	for d.pos < len(d.src) && d.src[d.pos] == ' ' {
+		d.pos++
+	}
+}
+
+func (d *decoder) accent(cmd string) {
+	d.skipSpace(cmd)
+	base := d.argument()
+	if base == `` {
+		d.buf.WriteRune(marks[cmd])
+		return
+	}
+	rs := []rune(base)
+	first := string(rs[0])
+	if first == "ı" {
+		first = "i"
+	} else if first == "ȷ" {
+		first = "j"
+	}
+	if r, ok := composed[cmd+first]; ok {
+		d.buf.WriteRune(r)
+	} else {
+		d.buf.WriteString(first)
+		d.buf.WriteRune(marks[cmd])
+	}
+	d.buf.WriteString(string(rs[1:]))
+}
+
+// Argument reads a single character or a braced group and returns it decoded.
+func (d *decoder) argument() string {
+	if d.pos >= len(d.src) {
+		return ``
+	}
+	switch c := d.src[d.pos]; c {
+	case '{':
+		depth, start := 0, d.pos
+		for d.pos < len(d.src) {
+			switch d.src[d.pos] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			}
+			d.pos++
+			if depth == 0 {
+				return Decode(string(d.src[start+1 : d.pos-1]))
+			}
+		}
+		return Decode(string(d.src[start+1:]))
+	case '\\':
+		d.pos++
+		name := d.name()
+		if sym, ok := symbols[name]; ok {
+			return sym
+		}
+		return ``
+	default:
+		d.pos++
+		return string(c)
+	}
+}
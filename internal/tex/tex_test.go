@@ -0,0 +1,112 @@
+package tex
+
+import "testing"
+
+func TestDecode(t *testing.T) {
+	cases := []struct {
+		name      string
+		testInput string
+		want      string
+	}{
+		{"plain", "The independence of the hypothesis", "The independence of the hypothesis"},
+		{"braced-accent", `P. J. C{\"o}hen`, "P. J. Cöhen"},
+		{"accent-argument", `Erd\H{o}s`, "Erdős"},
+		{"accent-space", `\c c`, "ç"},
+		{"dotless-i", `Mart\'{\i}n`, "Martín"},
+		{"symbols", `Gau\ss{} and \O{}stergaard`, "Gauß and Østergaard"},
+		{"formatting", `The \emph{Death} of an {\textsc{Author}}`, "The Death of an Author"},
+		{"escapes", `Smith \& Sons, 50\%`, "Smith & Sons, 50%"},
+		{"ligatures", "pages 12--15 --- ``quoted''", "pages 12–15 — “quoted”"},
+		{"tie", "P.~J. Cohen", "P. J. Cohen"},
+		{"math", `$\eq{2}$ {Academy}`, "2 Academy"},
+		{"unknown-accent-base", `\"w`, "w\u0308"},
+		{"unbalanced", `\'{e`, "é"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := Decode(c.testInput); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestEscape(t *testing.T) {
+	cases := []struct {
+		name      string
+		testInput string
+		want      string
+	}{
+		{"plain", "The title of the work", "The title of the work"},
+		{"specials", "Smith & Sons: 50% of $5 #1 a_b", `Smith \& Sons: 50\% of \$5 \#1 a\_b`},
+		{"braces", "{x}", `\{x\}`},
+		{"backslash", `a\b`, `a\textbackslash{}b`},
+		{"tilde", "~user", `\textasciitilde{}user`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			have := Escape(c.testInput)
+			if have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+			if back := Decode(have); back != c.testInput {
+				t.Errorf("have %s; want %s", back, c.testInput)
+			}
+		})
+	}
+}
+
+func TestFold(t *testing.T) {
+	cases := []struct {
+		name      string
+		testInput string
+		want      string
+	}{
+		{"ascii", "Cohen", "Cohen"},
+		{"accents", "Gödel Erdős Čech", "Godel Erdos Cech"},
+		{"letters", "Gauß Østergaard Łukasiewicz", "Gauss Ostergaard Lukasiewicz"},
+		{"combining", "w\u0308", "w"},
+		{"punctuation", "1–2 … © §", "1–2 … © §"},
+		{"other", "Ελληνικά", "Ελληνικά"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := Fold(c.testInput); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	cases := []struct {
+		name      string
+		testInput string
+		want      string
+	}{
+		{"ascii", `C{\"o}hen \& Co`, `C{\"o}hen \& Co`},
+		{"accents", "Gödel Erdős Čech", `G{\"o}del Erd{\H{o}}s {\v{C}}ech`},
+		{"letters", "Gauß Łukasiewicz", `Gau{\ss} {\L}ukasiewicz`},
+		{"dashes", "1–2 — “quoted”", "1--2 --- ``quoted''"},
+		{"symbols", "… ©", `{\dots} {\copyright}`},
+		{"combining", "w\u0308", `{\"w}`},
+		{"other", "Ελληνικά", "Ελληνικά"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := Encode(c.testInput); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	for _, s := range []string{"Gödel Erdős Čech", "Gauß Łukasiewicz", "1–2 — “quoted”", "ı İ ç"} {
+		t.Run(s, func(t *testing.T) {
+			if have := Decode(Encode(s)); have != s {
+				t.Errorf("have %s; want %s", have, s)
+			}
+		})
+	}
+}
@@ -0,0 +1,7 @@
+/*
+Tidy package runs a pipeline of clean-up steps over parsed BibTeX
+declarations in one pass, in the spirit of bibtex-tidy: normalizing the case
+of names, the separators of name lists, page ranges and months, sorting
+fields and entries, and removing duplicate entries.
+*/
+package tidy
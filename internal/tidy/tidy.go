@@ -0,0 +1,141 @@
+package tidy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/dedupe"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/render"
+	"github.com/mdm-code/bibx/internal/transform"
+)
+
+// Names of the steps of the pipeline.
+const (
+	Case        = "case"
+	Names       = "names"
+	Pages       = "pages"
+	Months      = "months"
+	SortFields  = "sort-fields"
+	SortEntries = "sort-entries"
+	Dedupe      = "dedupe"
+)
+
+// Steps lists the steps in the order the pipeline runs them.
+var Steps = []string{Case, Names, Pages, Months, SortFields, SortEntries, Dedupe}
+
+// Options select the steps to run and configure them. Steps run in the order
+// of Steps regardless of the order they are listed in. A nil FieldOrder
+// stands for transform.DefaultFieldOrder.
+type Options struct {
+	Steps      []string
+	Months     transform.MonthStyle
+	Order      render.Order
+	FieldOrder []string
+}
+
+// DefaultOptions runs all the steps with month macros and entries sorted by
+// cite key.
+func DefaultOptions() Options {
+	return Options{Steps: Steps, Months: transform.MonthMacro, Order: render.ByKey}
+}
+
+// Result holds the tidied declarations and the duplicate entries removed
+// from them.
+type Result struct {
+	Nodes   []parse.Node
+	Removed []*parse.EntryDecl
+}
+
+// Run tidies the declarations in place and returns them without the removed
+// duplicates. Unknown steps are reported as errors before anything changes.
+func Run(nodes []parse.Node, o Options) (*Result, error) {
+	enabled := make(map[string]bool)
+	for _, s := range o.Steps {
+		if !contains(Steps, s) {
+			return nil, fmt.Errorf("tidy: unknown step %q", s)
+		}
+		enabled[s] = true
+	}
+	order := o.FieldOrder
+	if order == nil {
+		order = transform.DefaultFieldOrder
+	}
+	for _, n := range nodes {
+		e, ok := n.(*parse.EntryDecl)
+		if !ok {
+			continue
+		}
+		if enabled[Case] {
+			transform.LowercaseNames(e)
+		}
+		if enabled[Names] {
+			transform.NormalizeSeparators(e)
+		}
+		if enabled[Pages] {
+			transform.NormalizePages(e)
+		}
+		if enabled[Months] {
+			transform.NormalizeMonth(e, o.Months)
+		}
+		if enabled[SortFields] {
+			transform.SortFields(e, order)
+		}
+	}
+	if enabled[SortEntries] {
+		sortEntries(nodes, o.Order)
+	}
+	r := &Result{Nodes: nodes, Removed: []*parse.EntryDecl{}}
+	if enabled[Dedupe] {
+		r.Nodes, r.Removed = removeDuplicates(nodes)
+	}
+	return r, nil
+}
+
+// SortEntries orders the entries among themselves. Strings, preambles and
+// comments keep their places so that macros stay defined before their use.
+func sortEntries(nodes []parse.Node, o render.Order) {
+	slots := []int{}
+	entries := []*parse.EntryDecl{}
+	for i, n := range nodes {
+		if e, ok := n.(*parse.EntryDecl); ok {
+			slots = append(slots, i)
+			entries = append(entries, e)
+		}
+	}
+	render.Sort(entries, o)
+	for i, slot := range slots {
+		nodes[slot] = entries[i]
+	}
+}
+
+// RemoveDuplicates drops the entries repeating an earlier entry, that is
+// with the same cite key compared case-insensitively and the same content.
+// Entries with the same content under other keys are kept as they may be
+// cited under either key.
+func removeDuplicates(nodes []parse.Node) ([]parse.Node, []*parse.EntryDecl) {
+	seen := make(map[string]bool)
+	result := []parse.Node{}
+	removed := []*parse.EntryDecl{}
+	for _, n := range nodes {
+		if e, ok := n.(*parse.EntryDecl); ok {
+			id := strings.ToLower(e.CiteKey) + "\n" + dedupe.Fingerprint(e)
+			if seen[id] {
+				removed = append(removed, e)
+				continue
+			}
+			seen[id] = true
+		}
+		result = append(result, n)
+	}
+	return result, removed
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package tidy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/format"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/render"
+	"github.com/mdm-code/bibx/internal/scan"
+	"github.com/mdm-code/bibx/internal/transform"
+)
+
+const testBib = `@string{pnas = {PNAS}}
+
+@Article{Zeta2001,
+  YEAR = 2001,
+  title = {Z},
+  month = {March},
+  pages = {12-15},
+  author = {Z & Y}
+}
+
+@misc{alpha,
+  title = {A}
+}
+
+@misc{Alpha,
+  title = {A}
+}
+`
+
+func parseNodes(t *testing.T, src string) []parse.Node {
+	t.Helper()
+	p := parse.NewParser(scan.NewScanner(scan.NewReader(strings.NewReader(src))))
+	nodes := []parse.Node{}
+	for n, ok := p.Next(); ok; n, ok = p.Next() {
+		nodes = append(nodes, n)
+	}
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return nodes
+}
+
+func TestRun(t *testing.T) {
+	cases := []struct {
+		name    string
+		opts    Options
+		want    string
+		removed int
+	}{
+		{
+			"all steps",
+			DefaultOptions(),
+			`@string{pnas = {PNAS}}
+
+@misc{alpha,
+  title = {A}
+}
+
+@article{Zeta2001,
+  author = {Z and Y},
+  title  = {Z},
+  pages  = {12--15},
+  year   = 2001,
+  month  = mar
+}
+`,
+			1,
+		},
+		{
+			"case only",
+			Options{Steps: []string{Case}},
+			`@string{pnas = {PNAS}}
+
+@article{Zeta2001,
+  year   = 2001,
+  title  = {Z},
+  month  = {March},
+  pages  = {12-15},
+  author = {Z & Y}
+}
+
+@misc{alpha,
+  title = {A}
+}
+
+@misc{Alpha,
+  title = {A}
+}
+`,
+			0,
+		},
+		{
+			"custom order",
+			Options{Steps: []string{SortFields, SortEntries}, FieldOrder: []string{"title"}, Order: render.ByYear, Months: transform.MonthNumber},
+			`@string{pnas = {PNAS}}
+
+@misc{alpha,
+  title = {A}
+}
+
+@misc{Alpha,
+  title = {A}
+}
+
+@article{Zeta2001,
+  title  = {Z},
+  YEAR   = 2001,
+  month  = {March},
+  pages  = {12-15},
+  author = {Z & Y}
+}
+`,
+			0,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r, err := Run(parseNodes(t, testBib), c.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b bytes.Buffer
+			if err := format.Nodes(&b, r.Nodes); err != nil {
+				t.Fatal(err)
+			}
+			if have := b.String(); have != c.want {
+				t.Errorf("have\n%s\nwant\n%s", have, c.want)
+			}
+			if len(r.Removed) != c.removed {
+				t.Errorf("have %d removed; want %d", len(r.Removed), c.removed)
+			}
+		})
+	}
+}
+
+func TestUnknownStep(t *testing.T) {
+	if _, err := Run(nil, Options{Steps: []string{"curly"}}); err == nil {
+		t.Error("have nil error for an unknown step")
+	}
+}
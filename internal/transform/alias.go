@@ -0,0 +1,27 @@
+package transform
+
+import (
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// CanonicalizeFields renames the fields of the entry known under an alias to
+// their canonical names listed in parse.Aliases. Aliased fields whose
+// canonical name is already taken are kept as they are. It reports whether
+// any field was renamed.
+func CanonicalizeFields(e *parse.EntryDecl) bool {
+	changed := false
+	for _, f := range e.Fields {
+		c := parse.CanonicalKey(f.Key)
+		if c == strings.ToLower(f.Key) {
+			continue
+		}
+		if _, taken := e.Get(c); taken {
+			continue
+		}
+		f.Key = c
+		changed = true
+	}
+	return changed
+}
@@ -0,0 +1,38 @@
+package transform
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestCanonicalizeFields(t *testing.T) {
+	cases := []struct {
+		name    string
+		keys    []string
+		want    []string
+		changed bool
+	}{
+		{"typo", []string{"Adress", "title"}, []string{"address", "title"}, true},
+		{"biblatex", []string{"eprintclass", "sortkey"}, []string{"primaryclass", "key"}, true},
+		{"taken", []string{"address", "location"}, []string{"address", "location"}, false},
+		{"canonical", []string{"author", "Journal"}, []string{"author", "Journal"}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := &parse.EntryDecl{}
+			for _, k := range c.keys {
+				e.Fields = append(e.Fields, &parse.FieldStmt{Key: k, Value: "{v}"})
+			}
+			changed := CanonicalizeFields(e)
+			have := []string{}
+			for _, f := range e.Fields {
+				have = append(have, f.Key)
+			}
+			if !reflect.DeepEqual(have, c.want) || changed != c.changed {
+				t.Errorf("have %v, %t; want %v, %t", have, changed, c.want, c.changed)
+			}
+		})
+	}
+}
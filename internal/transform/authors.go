@@ -0,0 +1,136 @@
+package transform
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mdm-code/bibx/internal/names"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// NormalizeSeparators rewrites the name lists of the entry, as told by
+// IsNameList, so that names are separated with ` and ` only. Lists holding
+// macro references are left intact.
+func NormalizeSeparators(e *parse.EntryDecl) {
+	for _, f := range e.Fields {
+		if !IsNameList(f.Key) || parse.Unquote(f.Value) == f.Value {
+			continue
+		}
+		list := NormalizeNameList(parse.Unquote(f.Value))
+		if strings.HasPrefix(f.Value, `"`) {
+			f.Value = `"` + list + `"`
+		} else {
+			f.Value = parse.Quote(list)
+		}
+	}
+}
+
+// NormalizeNameList replaces the `&` and `;` separators of the name list with
+// `and`, and splits the comma-separated parts of the list into names when
+// they are names in their own right, as in `John Smith, Jane Doe` or `Smith,
+// J., Doe, A.`. Commas of single `Last, First` or `Last, Jr, First` names are
+// kept. Separators nested in braces are left alone.
+func NormalizeNameList(s string) string {
+	s, _ = ReplaceAmpersands(strings.Join(splitTop(s, ';'), " and "))
+	result := []string{}
+	for _, n := range names.Split(s) {
+		result = append(result, splitCommas(n)...)
+	}
+	return strings.Join(result, " and ")
+}
+
+// ReplaceAmpersands replaces the `&` separators of the name list outside of
+// braces with `and` and reports whether there were any. White space between
+// the words of the list is collapsed.
+func ReplaceAmpersands(s string) (string, bool) {
+	words := fieldsTop(s)
+	found := false
+	for i, w := range words {
+		if w == "&" {
+			words[i], found = "and", true
+		}
+	}
+	return strings.Join(words, " "), found
+}
+
+// SplitCommas breaks the comma-separated parts of a name into separate names
+// if each part has more than one word, or if there are at least two pairs of
+// last names and first names.
+func splitCommas(n string) []string {
+	parts := []string{}
+	for _, p := range splitTop(n, ',') {
+		if p != `` {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) < 2 {
+		return []string{strings.Join(parts, ``)}
+	}
+	multi := true
+	for _, p := range parts {
+		if len(fieldsTop(p)) < 2 {
+			multi = false
+			break
+		}
+	}
+	switch {
+	case multi:
+		return parts
+	case len(parts) >= 4 && len(parts)%2 == 0:
+		result := []string{}
+		for i := 0; i < len(parts); i += 2 {
+			result = append(result, parts[i]+", "+parts[i+1])
+		}
+		return result
+	default:
+		return []string{strings.Join(parts, ", ")}
+	}
+}
+
+// SplitTop splits the string at the separators outside of braces and trims
+// white space around the parts.
+func splitTop(s string, sep rune) []string {
+	result := []string{}
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case sep:
+			if depth == 0 {
+				result = append(result, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(result, strings.TrimSpace(s[start:]))
+}
+
+// FieldsTop splits the string into words at white space outside of braces.
+func fieldsTop(s string) []string {
+	result := []string{}
+	depth, start := 0, -1
+	for i, c := range s {
+		switch {
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+		case unicode.IsSpace(c) && depth == 0:
+			if start >= 0 {
+				result = append(result, s[start:i])
+			}
+			start = -1
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		result = append(result, s[start:])
+	}
+	return result
+}
@@ -0,0 +1,59 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestNormalizeNameList(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"canonical", "Cohen, Paul and Kurt Gödel", "Cohen, Paul and Kurt Gödel"},
+		{"ampersand", "Tom Smith & Jerry Doe", "Tom Smith and Jerry Doe"},
+		{"semicolon", "Smith, Tom; Doe, Jerry;", "Smith, Tom and Doe, Jerry"},
+		{"commas", "John Smith, Jane Doe, and Paul Cohen", "John Smith and Jane Doe and Paul Cohen"},
+		{"pairs", "Smith, J., Doe, A.", "Smith, J. and Doe, A."},
+		{"jr", "Martin Luther King, Jr.", "Martin Luther King, Jr."},
+		{"inverted jr", "King, Jr., Martin", "King, Jr., Martin"},
+		{"braces", "{Barnes & Noble, Inc.} and {Tom; Jerry}", "{Barnes & Noble, Inc.} and {Tom; Jerry}"},
+		{"single", "Aristotle", "Aristotle"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := NormalizeNameList(c.input); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeSeparators(t *testing.T) {
+	have := &parse.EntryDecl{
+		Name:     "book",
+		CiteKey:  "a",
+		Comments: &parse.CommentGroupExpr{},
+		Fields: []*parse.FieldStmt{
+			{Key: "author", Value: `"Smith, Tom; Doe, Jerry"`},
+			{Key: "editor", Value: "eds"},
+			{Key: "title", Value: "{Tom & Jerry}"},
+		},
+	}
+	want := &parse.EntryDecl{
+		Name:     "book",
+		CiteKey:  "a",
+		Comments: &parse.CommentGroupExpr{},
+		Fields: []*parse.FieldStmt{
+			{Key: "author", Value: `"Smith, Tom and Doe, Jerry"`},
+			{Key: "editor", Value: "eds"},
+			{Key: "title", Value: "{Tom & Jerry}"},
+		},
+	}
+	NormalizeSeparators(have)
+	if !have.Eq(want) {
+		t.Errorf("have %v; want %v", have.Fields, want.Fields)
+	}
+}
@@ -0,0 +1,135 @@
+package transform
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// BibLaTeX entry types mapped onto the closest classic BibTeX counterparts.
+var downgradeTypes = map[string]string{
+	"online":         "misc",
+	"electronic":     "misc",
+	"www":            "misc",
+	"software":       "misc",
+	"dataset":        "misc",
+	"patent":         "misc",
+	"periodical":     "misc",
+	"artwork":        "misc",
+	"audio":          "misc",
+	"video":          "misc",
+	"music":          "misc",
+	"image":          "misc",
+	"movie":          "misc",
+	"report":         "techreport",
+	"mvbook":         "book",
+	"collection":     "book",
+	"mvcollection":   "book",
+	"reference":      "book",
+	"mvreference":    "book",
+	"bookinbook":     "inbook",
+	"suppbook":       "inbook",
+	"inreference":    "incollection",
+	"suppcollection": "incollection",
+	"mvproceedings":  "proceedings",
+	"suppperiodical": "article",
+}
+
+// BibLaTeX fields renamed to the classic BibTeX ones.
+var downgradeFields = map[string]string{
+	"journaltitle": "journal",
+	"location":     "address",
+	"annotation":   "annote",
+	"eprinttype":   "archiveprefix",
+	"eprintclass":  "primaryclass",
+	"langid":       "language",
+}
+
+var monthMacros = [...]string{
+	"jan", "feb", "mar", "apr", "may", "jun",
+	"jul", "aug", "sep", "oct", "nov", "dec",
+}
+
+// Downgrade rewrites BibLaTeX-only entry types and fields of the entry into
+// their classic BibTeX counterparts. Fields already present in the entry take
+// precedence over the values carried over from the BibLaTeX ones.
+func Downgrade(e *parse.EntryDecl) {
+	downgradeType(e)
+	for from, to := range downgradeFields {
+		rename(e, from, to)
+	}
+	if e.Name == "phdthesis" || e.Name == "mastersthesis" {
+		rename(e, "institution", "school")
+	}
+	NameLanguages(e)
+	if f, ok := e.Get("date"); ok {
+		year, month, ok := splitDate(parse.Unquote(f.Value))
+		if ok {
+			if _, has := e.Get("year"); !has {
+				e.Set("year", year)
+			}
+			if _, has := e.Get("month"); !has && month != `` {
+				e.Set("month", month)
+			}
+			e.Del("date")
+		}
+	}
+}
+
+func downgradeType(e *parse.EntryDecl) {
+	if e.Name == "thesis" {
+		e.Name = "phdthesis"
+		if f, ok := e.Get("type"); ok {
+			switch strings.ToLower(parse.Unquote(f.Value)) {
+			case "mathesis", "mastersthesis":
+				e.Name = "mastersthesis"
+				e.Del("type")
+			case "phdthesis":
+				e.Del("type")
+			}
+		}
+		return
+	}
+	if t, ok := downgradeTypes[e.Name]; ok {
+		e.Name = t
+	}
+}
+
+// Rename moves the field under a new key unless the key is already taken, in
+// which case the old field is dropped.
+func rename(e *parse.EntryDecl, from, to string) {
+	f, ok := e.Get(from)
+	if !ok {
+		return
+	}
+	if _, ok := e.Get(to); ok {
+		e.Del(from)
+		return
+	}
+	f.Key = to
+}
+
+// SplitDate extracts the year and the month macro from the ISO 8601 date used
+// by BibLaTeX. Ranges are reduced to their start date.
+func splitDate(date string) (year, month string, ok bool) {
+	date = strings.TrimSpace(date)
+	if i := strings.IndexByte(date, '/'); i >= 0 {
+		date = date[:i]
+	}
+	parts := strings.Split(date, "-")
+	if len(parts[0]) != 4 {
+		return ``, ``, false
+	}
+	if _, err := strconv.Atoi(parts[0]); err != nil {
+		return ``, ``, false
+	}
+	if len(parts) > 1 {
+		m, err := strconv.Atoi(parts[1])
+		if err != nil || m < 1 || m > 12 {
+			return ``, ``, false
+		}
+		month = monthMacros[m-1]
+	}
+	return parts[0], month, true
+}
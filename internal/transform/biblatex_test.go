@@ -0,0 +1,153 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestDowngrade(t *testing.T) {
+	cases := []struct {
+		name string
+		have *parse.EntryDecl
+		want *parse.EntryDecl
+	}{
+		{
+			name: "online",
+			have: &parse.EntryDecl{
+				Name:     "online",
+				CiteKey:  "ctan",
+				Comments: &parse.CommentGroupExpr{},
+				Fields: []*parse.FieldStmt{
+					{Key: "title", Value: "{CTAN}"},
+					{Key: "date", Value: "{2006-03-15}"},
+				},
+			},
+			want: &parse.EntryDecl{
+				Name:     "misc",
+				CiteKey:  "ctan",
+				Comments: &parse.CommentGroupExpr{},
+				Fields: []*parse.FieldStmt{
+					{Key: "title", Value: "{CTAN}"},
+					{Key: "year", Value: "2006"},
+					{Key: "month", Value: "mar"},
+				},
+			},
+		},
+		{
+			name: "langid",
+			have: &parse.EntryDecl{
+				Name:     "book",
+				CiteKey:  "mann",
+				Comments: &parse.CommentGroupExpr{},
+				Fields: []*parse.FieldStmt{
+					{Key: "title", Value: "{Der Zauberberg}"},
+					{Key: "langid", Value: "{de-AT}"},
+				},
+			},
+			want: &parse.EntryDecl{
+				Name:     "book",
+				CiteKey:  "mann",
+				Comments: &parse.CommentGroupExpr{},
+				Fields: []*parse.FieldStmt{
+					{Key: "title", Value: "{Der Zauberberg}"},
+					{Key: "language", Value: "{austrian}"},
+				},
+			},
+		},
+		{
+			name: "article",
+			have: &parse.EntryDecl{
+				Name:     "article",
+				CiteKey:  "Cohen1963",
+				Comments: &parse.CommentGroupExpr{},
+				Fields: []*parse.FieldStmt{
+					{Key: "journaltitle", Value: "{Proceedings}"},
+					{Key: "Location", Value: "{Washington}"},
+					{Key: "date", Value: "1963"},
+				},
+			},
+			want: &parse.EntryDecl{
+				Name:     "article",
+				CiteKey:  "Cohen1963",
+				Comments: &parse.CommentGroupExpr{},
+				Fields: []*parse.FieldStmt{
+					{Key: "journal", Value: "{Proceedings}"},
+					{Key: "address", Value: "{Washington}"},
+					{Key: "year", Value: "1963"},
+				},
+			},
+		},
+		{
+			name: "existing-fields",
+			have: &parse.EntryDecl{
+				Name:     "article",
+				CiteKey:  "Cohen1963",
+				Comments: &parse.CommentGroupExpr{},
+				Fields: []*parse.FieldStmt{
+					{Key: "journal", Value: "{Proc.}"},
+					{Key: "journaltitle", Value: "{Proceedings}"},
+					{Key: "year", Value: "1964"},
+					{Key: "date", Value: "{1963-12}"},
+				},
+			},
+			want: &parse.EntryDecl{
+				Name:     "article",
+				CiteKey:  "Cohen1963",
+				Comments: &parse.CommentGroupExpr{},
+				Fields: []*parse.FieldStmt{
+					{Key: "journal", Value: "{Proc.}"},
+					{Key: "year", Value: "1964"},
+					{Key: "month", Value: "dec"},
+				},
+			},
+		},
+		{
+			name: "thesis",
+			have: &parse.EntryDecl{
+				Name:     "thesis",
+				CiteKey:  "Doe2001",
+				Comments: &parse.CommentGroupExpr{},
+				Fields: []*parse.FieldStmt{
+					{Key: "type", Value: "{mathesis}"},
+					{Key: "institution", Value: "{MIT}"},
+				},
+			},
+			want: &parse.EntryDecl{
+				Name:     "mastersthesis",
+				CiteKey:  "Doe2001",
+				Comments: &parse.CommentGroupExpr{},
+				Fields: []*parse.FieldStmt{
+					{Key: "school", Value: "{MIT}"},
+				},
+			},
+		},
+		{
+			name: "invalid-date",
+			have: &parse.EntryDecl{
+				Name:     "report",
+				CiteKey:  "Doe2001",
+				Comments: &parse.CommentGroupExpr{},
+				Fields: []*parse.FieldStmt{
+					{Key: "date", Value: "{circa 2001}"},
+				},
+			},
+			want: &parse.EntryDecl{
+				Name:     "techreport",
+				CiteKey:  "Doe2001",
+				Comments: &parse.CommentGroupExpr{},
+				Fields: []*parse.FieldStmt{
+					{Key: "date", Value: "{circa 2001}"},
+				},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			Downgrade(c.have)
+			if !c.have.Eq(c.want) {
+				t.Errorf("have %v; want %v", c.have.Fields, c.want.Fields)
+			}
+		})
+	}
+}
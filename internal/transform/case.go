@@ -0,0 +1,29 @@
+package transform
+
+import (
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/bibdesk"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// LowercaseNames writes the entry type and the field names of the entry in
+// lower case, except for the fields of BibDesk, which BibDesk capitalizes.
+// It reports whether any of them changed.
+func LowercaseNames(e *parse.EntryDecl) bool {
+	changed := false
+	if name := strings.ToLower(e.Name); name != e.Name {
+		e.Name = name
+		changed = true
+	}
+	for _, f := range e.Fields {
+		if bibdesk.IsField(f.Key) {
+			continue
+		}
+		if key := strings.ToLower(f.Key); key != f.Key {
+			f.Key = key
+			changed = true
+		}
+	}
+	return changed
+}
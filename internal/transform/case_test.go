@@ -0,0 +1,20 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestLowercaseNames(t *testing.T) {
+	e := &parse.EntryDecl{Name: "Article", Fields: []*parse.FieldStmt{{Key: "TITLE", Value: "{A Title}"}, {Key: "year", Value: "2000"}, {Key: "Bdsk-File-1", Value: "{YnBsaXN0MDA=}"}}}
+	if !LowercaseNames(e) {
+		t.Error("have unchanged entry")
+	}
+	if e.Name != "article" || e.Fields[0].Key != "title" || e.Fields[0].Value != "{A Title}" || e.Fields[2].Key != "Bdsk-File-1" {
+		t.Errorf("have %+v", e)
+	}
+	if LowercaseNames(e) {
+		t.Error("have changed lower-case entry")
+	}
+}
@@ -0,0 +1,6 @@
+/*
+Transform package implements passes that rewrite parsed BibTeX declarations in
+place, such as converting BibLaTeX databases for use with classic BibTeX
+styles.
+*/
+package transform
@@ -0,0 +1,46 @@
+package transform
+
+import (
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+// Verbatim fields are typeset with \url or similar commands taking their
+// contents literally, or are not typeset at all, so that their values must
+// be kept as they are.
+var verbatim = map[string]bool{
+	"url":    true,
+	"doi":    true,
+	"eprint": true,
+	"file":   true,
+}
+
+// IsVerbatim tells if the values of the field are taken literally, as those
+// of url, doi, eprint and file are, and of the Bdsk-Url-N and Bdsk-File-N
+// fields of BibDesk.
+func IsVerbatim(key string) bool {
+	key = strings.ToLower(key)
+	return verbatim[key] || strings.HasPrefix(key, "bdsk-url-") || strings.HasPrefix(key, "bdsk-file-")
+}
+
+// Encode rewrites the field values of entries, the @string values and the
+// preamble of the declaration in the encoding. Verbatim fields are left
+// alone.
+func Encode(n parse.Node, enc tex.Encoding) {
+	switch d := n.(type) {
+	case *parse.EntryDecl:
+		for _, f := range d.Fields {
+			if !IsVerbatim(f.Key) {
+				f.Value = enc.Apply(f.Value)
+			}
+		}
+	case *parse.AbbrevDecl:
+		if d.Field != nil {
+			d.Field.Value = enc.Apply(d.Field.Value)
+		}
+	case *parse.PreambleDecl:
+		d.Value = enc.Apply(d.Value)
+	}
+}
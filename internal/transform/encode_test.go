@@ -0,0 +1,70 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/tex"
+)
+
+func TestEncode(t *testing.T) {
+	cases := []struct {
+		name string
+		enc  tex.Encoding
+		have parse.Node
+		want parse.Node
+	}{
+		{
+			name: "entry",
+			enc:  tex.ASCII,
+			have: &parse.EntryDecl{
+				Name:     "misc",
+				CiteKey:  "Godel1931",
+				Comments: &parse.CommentGroupExpr{},
+				Fields: []*parse.FieldStmt{
+					{Key: "author", Value: "{Kurt Gödel}"},
+					{Key: "pages", Value: "{173–198}"},
+					{Key: "url", Value: "{https://example.org/gödel_1931%20x}"},
+					{Key: "Bdsk-Url-1", Value: "{https://example.org/a_b}"},
+				},
+			},
+			want: &parse.EntryDecl{
+				Name:     "misc",
+				CiteKey:  "Godel1931",
+				Comments: &parse.CommentGroupExpr{},
+				Fields: []*parse.FieldStmt{
+					{Key: "author", Value: `{Kurt G{\"o}del}`},
+					{Key: "pages", Value: "{173--198}"},
+					{Key: "url", Value: "{https://example.org/gödel_1931%20x}"},
+					{Key: "Bdsk-Url-1", Value: "{https://example.org/a_b}"},
+				},
+			},
+		},
+		{
+			name: "string",
+			enc:  tex.ASCII,
+			have: &parse.AbbrevDecl{
+				Comments: &parse.CommentGroupExpr{},
+				Field:    &parse.FieldStmt{Key: "pub", Value: `"Springer–Verlag"`},
+			},
+			want: &parse.AbbrevDecl{
+				Comments: &parse.CommentGroupExpr{},
+				Field:    &parse.FieldStmt{Key: "pub", Value: `"Springer--Verlag"`},
+			},
+		},
+		{
+			name: "pass-through",
+			enc:  tex.PassThrough,
+			have: &parse.PreambleDecl{Comments: &parse.CommentGroupExpr{}, Value: `"Gödel"`},
+			want: &parse.PreambleDecl{Comments: &parse.CommentGroupExpr{}, Value: `"Gödel"`},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			Encode(c.have, c.enc)
+			if !c.have.Eq(c.want) {
+				t.Errorf("have %v; want %v", c.have, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package transform
+
+import (
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/lang"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// LanguageTag returns the BCP 47 tag of the language of the entry as used by
+// CSL, taken from the langid field or from the first language listed in the
+// language field.
+func LanguageTag(e *parse.EntryDecl) (string, bool) {
+	for _, key := range []string{"langid", "language"} {
+		f, ok := e.Get(key)
+		if !ok {
+			continue
+		}
+		first := strings.Split(strings.ReplaceAll(parse.Unquote(f.Value), " and ", ","), ",")[0]
+		if t, ok := lang.Tag(strings.Trim(first, " {}")); ok {
+			return t, true
+		}
+	}
+	return ``, false
+}
+
+// NameLanguages rewrites BCP 47 tags found in the langid and language fields
+// of the entry into the babel and polyglossia names expected by BibTeX and
+// BibLaTeX. Unknown languages are left untouched. It reports whether any of
+// the fields was changed.
+func NameLanguages(e *parse.EntryDecl) bool {
+	changed := false
+	for _, key := range []string{"langid", "language"} {
+		f, ok := e.Get(key)
+		if !ok {
+			continue
+		}
+		v := parse.Unquote(f.Value)
+		if v == f.Value {
+			continue
+		}
+		list := strings.Split(v, " and ")
+		for i, l := range list {
+			if l = strings.TrimSpace(l); !lang.IsTag(l) {
+				continue
+			}
+			if n, ok := lang.Name(l); ok {
+				list[i] = n
+			}
+		}
+		if nv := strings.Join(list, " and "); nv != v {
+			f.Value = f.Value[:1] + nv + f.Value[len(f.Value)-1:]
+			changed = true
+		}
+	}
+	return changed
+}
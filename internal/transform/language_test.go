@@ -0,0 +1,52 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestLanguageTag(t *testing.T) {
+	cases := []struct {
+		name   string
+		fields []*parse.FieldStmt
+		want   string
+		ok     bool
+	}{
+		{"langid", []*parse.FieldStmt{{Key: "language", Value: "{english}"}, {Key: "langid", Value: "{ngerman}"}}, "de", true},
+		{"list", []*parse.FieldStmt{{Key: "language", Value: "{British and French}"}}, "en-GB", true},
+		{"tag", []*parse.FieldStmt{{Key: "language", Value: `"pt_br"`}}, "pt-BR", true},
+		{"unknown", []*parse.FieldStmt{{Key: "language", Value: "{Klingon}"}}, ``, false},
+		{"none", nil, ``, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			have, ok := LanguageTag(&parse.EntryDecl{Fields: c.fields})
+			if have != c.want || ok != c.ok {
+				t.Errorf("have %q, %t; want %q, %t", have, ok, c.want, c.ok)
+			}
+		})
+	}
+}
+
+func TestNameLanguages(t *testing.T) {
+	cases := []struct {
+		value string
+		want  string
+	}{
+		{"{en-US}", "{american}"},
+		{`"fr and de"`, `"french and german"`},
+		{"{english and pl}", "{english and polish}"},
+		{"{xx}", "{xx}"},
+		{"en", "en"},
+	}
+	for _, c := range cases {
+		t.Run(c.value, func(t *testing.T) {
+			e := &parse.EntryDecl{Fields: []*parse.FieldStmt{{Key: "langid", Value: c.value}}}
+			changed := NameLanguages(e)
+			if have := e.Fields[0].Value; have != c.want || changed != (c.value != c.want) {
+				t.Errorf("have %s, %t; want %s", have, changed, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package transform
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+const (
+	MonthMacro MonthStyle = iota
+	MonthNumber
+	MonthName
+)
+
+// MonthStyle is the representation of month fields: the predefined
+// three-letter macro as in `jan`, the bare number as in `1`, or the full
+// English name as in `{January}`.
+type MonthStyle uint8
+
+var monthNames = [...]string{
+	"January", "February", "March", "April", "May", "June",
+	"July", "August", "September", "October", "November", "December",
+}
+
+// ParseMonth returns the number of the month written as a month macro, a
+// number, or an English month name or its abbreviation, optionally followed
+// by a period. Values of any other form are not recognized.
+func ParseMonth(value string) (int, bool) {
+	v := strings.TrimSpace(value)
+	if !strings.HasPrefix(v, "{") && !strings.HasPrefix(v, `"`) {
+		if n, err := strconv.Atoi(v); err == nil {
+			return checkMonth(n)
+		}
+		for i, m := range monthMacros {
+			if strings.EqualFold(v, m) {
+				return i + 1, true
+			}
+		}
+		return 0, false
+	}
+	v = strings.TrimSuffix(strings.TrimSpace(parse.Unquote(v)), ".")
+	if n, err := strconv.Atoi(v); err == nil {
+		return checkMonth(n)
+	}
+	if len(v) < 3 {
+		return 0, false
+	}
+	v = strings.ToLower(v)
+	for i, m := range monthNames {
+		if strings.HasPrefix(strings.ToLower(m), v) {
+			return i + 1, true
+		}
+	}
+	if v == "sept" {
+		return 9, true
+	}
+	return 0, false
+}
+
+func checkMonth(n int) (int, bool) {
+	if n < 1 || n > 12 {
+		return 0, false
+	}
+	return n, true
+}
+
+// FormatMonth writes the month with the given number in the style.
+func FormatMonth(n int, s MonthStyle) string {
+	switch s {
+	case MonthNumber:
+		return strconv.Itoa(n)
+	case MonthName:
+		return parse.Quote(monthNames[n-1])
+	default:
+		return monthMacros[n-1]
+	}
+}
+
+// NormalizeMonth rewrites the month field of the entry in the style. Months
+// that are not recognized by ParseMonth are left untouched. It reports
+// whether the field was changed.
+func NormalizeMonth(e *parse.EntryDecl, s MonthStyle) bool {
+	f, ok := e.Get("month")
+	if !ok {
+		return false
+	}
+	n, ok := ParseMonth(f.Value)
+	if !ok {
+		return false
+	}
+	v := FormatMonth(n, s)
+	if v == f.Value {
+		return false
+	}
+	f.Value = v
+	return true
+}
@@ -0,0 +1,61 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestParseMonth(t *testing.T) {
+	cases := []struct {
+		value string
+		want  int
+		ok    bool
+	}{
+		{"jan", 1, true},
+		{"DEC", 12, true},
+		{"7", 7, true},
+		{"{07}", 7, true},
+		{"{September}", 9, true},
+		{`"Sept."`, 9, true},
+		{"{Oct.}", 10, true},
+		{"{may}", 5, true},
+		{"13", 0, false},
+		{"{Ju}", 0, false},
+		{"{Spring}", 0, false},
+		{"june", 0, false},
+		{`jan # "~1"`, 0, false},
+	}
+	for _, c := range cases {
+		t.Run(c.value, func(t *testing.T) {
+			have, ok := ParseMonth(c.value)
+			if have != c.want || ok != c.ok {
+				t.Errorf("have %d, %t; want %d, %t", have, ok, c.want, c.ok)
+			}
+		})
+	}
+}
+
+func TestNormalizeMonth(t *testing.T) {
+	cases := []struct {
+		value   string
+		style   MonthStyle
+		want    string
+		changed bool
+	}{
+		{"{March}", MonthMacro, "mar", true},
+		{"mar", MonthNumber, "3", true},
+		{"3", MonthName, "{March}", true},
+		{"{March}", MonthName, "{March}", false},
+		{"{Spring}", MonthMacro, "{Spring}", false},
+	}
+	for _, c := range cases {
+		t.Run(c.value, func(t *testing.T) {
+			e := &parse.EntryDecl{Fields: []*parse.FieldStmt{{Key: "month", Value: c.value}}}
+			changed := NormalizeMonth(e, c.style)
+			if have := e.Fields[0].Value; have != c.want || changed != c.changed {
+				t.Errorf("have %s, %t; want %s, %t", have, changed, c.want, c.changed)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package transform
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// DefaultFieldOrder is the order of the fields used by SortFields when no
+// other order is given, listing the people and the title first followed by
+// the publication details and the identifiers.
+var DefaultFieldOrder = []string{
+	"author", "editor", "title", "subtitle", "shorttitle",
+	"journal", "journaltitle", "booktitle", "series", "edition",
+	"volume", "number", "pages", "chapter",
+	"publisher", "organization", "institution", "school", "address", "location",
+	"howpublished", "type", "year", "month", "date",
+	"doi", "eprint", "archiveprefix", "primaryclass",
+	"isbn", "issn", "url", "urldate",
+	"crossref", "xdata", "keywords", "note", "abstract",
+}
+
+// SortFields orders the fields of the entry by their positions in the order
+// compared case-insensitively. Fields missing from the order follow the
+// others in their original order. It reports whether the order changed.
+func SortFields(e *parse.EntryDecl, order []string) bool {
+	rank := make(map[string]int, len(order))
+	for i, key := range order {
+		if _, ok := rank[strings.ToLower(key)]; !ok {
+			rank[strings.ToLower(key)] = i
+		}
+	}
+	pos := func(f *parse.FieldStmt) int {
+		if i, ok := rank[strings.ToLower(f.Key)]; ok {
+			return i
+		}
+		return len(order)
+	}
+	sorted := append([]*parse.FieldStmt{}, e.Fields...)
+	sort.SliceStable(sorted, func(i, j int) bool { return pos(sorted[i]) < pos(sorted[j]) })
+	changed := false
+	for i := range sorted {
+		if sorted[i] != e.Fields[i] {
+			changed = true
+		}
+	}
+	e.Fields = sorted
+	return changed
+}
@@ -0,0 +1,37 @@
+package transform
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestSortFields(t *testing.T) {
+	cases := []struct {
+		name  string
+		keys  []string
+		order []string
+		want  []string
+	}{
+		{"default", []string{"year", "custom", "Title", "author", "abstract"}, DefaultFieldOrder, []string{"author", "Title", "year", "abstract", "custom"}},
+		{"custom", []string{"year", "b", "a", "title"}, []string{"title", "year"}, []string{"title", "year", "b", "a"}},
+		{"sorted", []string{"author", "title"}, DefaultFieldOrder, []string{"author", "title"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := &parse.EntryDecl{}
+			for _, k := range c.keys {
+				e.Fields = append(e.Fields, &parse.FieldStmt{Key: k})
+			}
+			changed := SortFields(e, c.order)
+			have := []string{}
+			for _, f := range e.Fields {
+				have = append(have, f.Key)
+			}
+			if !reflect.DeepEqual(have, c.want) || changed != !reflect.DeepEqual(c.keys, c.want) {
+				t.Errorf("have %v, %t; want %v", have, changed, c.want)
+			}
+		})
+	}
+}
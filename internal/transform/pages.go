@@ -0,0 +1,27 @@
+package transform
+
+import (
+	"github.com/mdm-code/bibx/internal/pages"
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// NormalizePages rewrites the pages field of the entry in the canonical form
+// of pages.Normalize keeping its delimiters. Fields that cannot be parsed and
+// bare numbers or macros are left untouched. It reports whether the field was
+// changed.
+func NormalizePages(e *parse.EntryDecl) bool {
+	f, ok := e.Get("pages")
+	if !ok {
+		return false
+	}
+	v := parse.Unquote(f.Value)
+	if v == f.Value {
+		return false
+	}
+	nv, err := pages.Normalize(v)
+	if err != nil || nv == v {
+		return false
+	}
+	f.Value = f.Value[:1] + nv + f.Value[len(f.Value)-1:]
+	return true
+}
@@ -0,0 +1,30 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestNormalizePages(t *testing.T) {
+	cases := []struct {
+		value string
+		want  string
+	}{
+		{"{12-15}", "{12--15}"},
+		{`"1234-56"`, `"1234--1256"`},
+		{"{12--15}", "{12--15}"},
+		{"{15-12}", "{15-12}"},
+		{"12", "12"},
+		{"pp", "pp"},
+	}
+	for _, c := range cases {
+		t.Run(c.value, func(t *testing.T) {
+			e := &parse.EntryDecl{Fields: []*parse.FieldStmt{{Key: "pages", Value: c.value}}}
+			changed := NormalizePages(e)
+			if have := e.Fields[0].Value; have != c.want || changed != (c.value != c.want) {
+				t.Errorf("have %s, %t; want %s", have, changed, c.want)
+			}
+		})
+	}
+}
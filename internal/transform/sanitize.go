@@ -0,0 +1,92 @@
+package transform
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+// Fields whose case is changed by the bibliography styles, so that inner
+// braces protecting the case of their contents are significant.
+var caseFields = map[string]bool{
+	"title":      true,
+	"booktitle":  true,
+	"series":     true,
+	"shorttitle": true,
+	"subtitle":   true,
+}
+
+// Fields holding name lists, where inner braces keep corporate names in one
+// piece.
+var nameFields = map[string]bool{
+	"author":       true,
+	"editor":       true,
+	"translator":   true,
+	"bookauthor":   true,
+	"editora":      true,
+	"editorb":      true,
+	"editorc":      true,
+	"annotator":    true,
+	"commentator":  true,
+	"introduction": true,
+	"foreword":     true,
+	"afterword":    true,
+	"holder":       true,
+}
+
+// IsNameList tells if the field holds a list of names separated with and, as
+// author, editor, translator and the other name fields of biblatex do.
+func IsNameList(key string) bool { return nameFields[strings.ToLower(key)] }
+
+// Sanitize cleans up the field values of the entry or the @string
+// declaration with SanitizeValue.
+func Sanitize(n parse.Node) {
+	switch d := n.(type) {
+	case *parse.EntryDecl:
+		for _, f := range d.Fields {
+			f.Value = SanitizeValue(f.Key, f.Value)
+		}
+	case *parse.AbbrevDecl:
+		if d.Field != nil {
+			d.Field.Value = SanitizeValue(d.Field.Key, d.Field.Value)
+		}
+	}
+}
+
+// SanitizeValue collapses runs of white space and newlines in the raw value
+// of the field into single spaces, trims the spaces inside the delimiters,
+// and removes doubly-nested braces such as {{Title}} when the inner braces
+// make no difference. That is the case outside of name lists and of fields
+// whose case is changed by the styles, or when there are no uppercase letters
+// to protect.
+func SanitizeValue(key, value string) string {
+	value = strings.Join(strings.FieldsFunc(value, unicode.IsSpace), " ")
+	inner := parse.Unquote(value)
+	if inner == value {
+		return value
+	}
+	open, close := value[:1], value[len(value)-1:]
+	inner = strings.TrimSpace(inner)
+	for open == "{" && isGroup(inner) && isSafe(key, parse.Unquote(inner)) {
+		inner = strings.TrimSpace(parse.Unquote(inner))
+	}
+	return open + inner + close
+}
+
+// IsGroup checks if the value is a single brace group.
+func isGroup(v string) bool {
+	return strings.HasPrefix(v, "{") && parse.Unquote(v) != v
+}
+
+func isSafe(key, contents string) bool {
+	if strings.HasPrefix(contents, `\`) {
+		// Special characters such as {\"o} keep their group.
+		return false
+	}
+	key = strings.ToLower(key)
+	if !caseFields[key] && !nameFields[key] {
+		return true
+	}
+	return strings.IndexFunc(contents, unicode.IsUpper) < 0
+}
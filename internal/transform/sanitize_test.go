@@ -0,0 +1,52 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/parse"
+)
+
+func TestSanitizeValue(t *testing.T) {
+	cases := []struct {
+		name  string
+		key   string
+		value string
+		want  string
+	}{
+		{"clean", "title", "{The Title}", "{The Title}"},
+		{"whitespace", "title", "{The\n    Title\t of  it}", "{The Title of it}"},
+		{"stray spaces", "journal", `"  PNAS "`, `"PNAS"`},
+		{"macro", "month", "dec", "dec"},
+		{"concatenation", "note", "jan  #   { 1 }", "jan # { 1 }"},
+		{"nested", "journal", "{{PNAS}}", "{PNAS}"},
+		{"deeply nested", "publisher", "{ {{ Springer }} }", "{Springer}"},
+		{"protected title", "title", "{{The Title}}", "{{The Title}}"},
+		{"lowercase title", "title", "{{on sets}}", "{on sets}"},
+		{"corporate name", "author", "{{World Health Organization}}", "{{World Health Organization}}"},
+		{"special character", "journal", `{{\"O}sterreich}`, `{{\"O}sterreich}`},
+		{"partial", "journal", "{{IEEE} Transactions}", "{{IEEE} Transactions}"},
+		{"two groups", "journal", "{{a} {b}}", "{{a} {b}}"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := SanitizeValue(c.key, c.value); have != c.want {
+				t.Errorf("have %s; want %s", have, c.want)
+			}
+		})
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	have := &parse.AbbrevDecl{
+		Comments: &parse.CommentGroupExpr{},
+		Field:    &parse.FieldStmt{Key: "pub", Value: "{{Springer}\n  }"},
+	}
+	want := &parse.AbbrevDecl{
+		Comments: &parse.CommentGroupExpr{},
+		Field:    &parse.FieldStmt{Key: "pub", Value: "{Springer}"},
+	}
+	Sanitize(have)
+	if !have.Eq(want) {
+		t.Errorf("have %v; want %v", have.Field, want.Field)
+	}
+}
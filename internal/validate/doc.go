@@ -0,0 +1,5 @@
+/*
+Validate package checks BibTeX entries against schemas listing the required
+and optional fields of each entry type and the kinds of values fields hold.
+*/
+package validate
@@ -0,0 +1,429 @@
+package validate
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/lang"
+	"github.com/mdm-code/bibx/internal/pages"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/report"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+const (
+	Text Kind = iota
+	Integer
+	Month
+	Language
+	Pages
+)
+
+// Kind is the kind of value a field is expected to hold.
+type Kind uint8
+
+var kindNames = [...]string{
+	Text:     "text",
+	Integer:  "integer",
+	Month:    "month",
+	Language: "language",
+	Pages:    "pages",
+}
+
+// String returns the name of the kind as used in schema definitions, or its
+// number for a kind that has no name.
+func (k Kind) String() string {
+	if int(k) >= len(kindNames) {
+		return fmt.Sprintf("Kind(%d)", k)
+	}
+	return kindNames[k]
+}
+
+// UnmarshalText decodes the kind from its name.
+func (k *Kind) UnmarshalText(text []byte) error {
+	for i, n := range kindNames {
+		if n == string(text) {
+			*k = Kind(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("validate: unknown value kind %q", text)
+}
+
+const (
+	Missing Reason = iota
+	Unknown
+	Invalid
+	UnknownType
+	Banned
+	Disallowed
+)
+
+// Reason tells why an entry violates its schema.
+type Reason uint8
+
+var reasonRules = [...]string{
+	Missing:     "missing-field",
+	Unknown:     "unknown-field",
+	Invalid:     "invalid-value",
+	UnknownType: "unknown-type",
+	Banned:      "banned-field",
+	Disallowed:  "disallowed-type",
+}
+
+// String returns the rule ID of the reason used in reports.
+func (r Reason) String() string { return reasonRules[r] }
+
+// Schema lists the fields of an entry type. Alternative required fields, one
+// of which must be present, are separated with `|` as in `author|editor`.
+type Schema struct {
+	Required []string `json:"required"`
+	Optional []string `json:"optional"`
+}
+
+// Set is a collection of entry type schemas. Common fields are allowed in all
+// entry types, and Kinds maps field names onto the kinds of their values.
+// Fields not listed in Kinds are Text.
+//
+// The remaining members hold house rules: Allowed restricts the entry types
+// that may be used unless it is empty, Banned lists fields that may not be
+// used at all, and Required lists fields required on top of the schema of
+// the entry type, with the `*` key applying to all entry types.
+type Set struct {
+	Types    map[string]Schema   `json:"types"`
+	Common   []string            `json:"common"`
+	Kinds    map[string]Kind     `json:"kinds"`
+	Allowed  []string            `json:"allowed"`
+	Banned   []string            `json:"banned"`
+	Required map[string][]string `json:"required"`
+}
+
+// Violation describes a single problem found in an entry. Field holds the
+// name of the offending field, the alternatives of a missing required field,
+// or the entry type when it is unknown. Pos is the position of the offending
+// field or of the entry.
+type Violation struct {
+	Reason Reason
+	Key    string
+	Field  string
+	Kind   Kind
+	Pos    scan.Pos
+}
+
+// Error formats the violation as a message prefixed with the cite key and
+// the code of its rule.
+func (v Violation) Error() string {
+	return v.Key + ": " + report.Code(v.Reason.String()) + " " + v.message()
+}
+
+func (v Violation) message() string {
+	switch v.Reason {
+	case Missing:
+		return fmt.Sprintf("missing required field %s", strings.ReplaceAll(v.Field, "|", " or "))
+	case Unknown:
+		return fmt.Sprintf("unknown field %s", v.Field)
+	case Invalid:
+		return fmt.Sprintf("field %s is not a valid %s value", v.Field, v.Kind)
+	case Banned:
+		return fmt.Sprintf("field %s is not allowed", v.Field)
+	case Disallowed:
+		return fmt.Sprintf("entry type %s is not allowed", v.Field)
+	default:
+		return fmt.Sprintf("unknown entry type %s", v.Field)
+	}
+}
+
+// Report converts the violation into a report finding. Unknown fields are
+// reported as warnings since they are ignored by BibTeX, and all other
+// violations as errors.
+func (v Violation) Report() report.Finding {
+	f := report.Finding{
+		Severity: report.Error,
+		Rule:     v.Reason.String(),
+		Pos:      v.Pos,
+		Key:      v.Key,
+		Message:  v.message(),
+	}
+	if v.Reason == Unknown {
+		f.Severity = report.Warning
+	}
+	if v.Reason != UnknownType && v.Reason != Disallowed {
+		f.Field = v.Field
+	}
+	return f
+}
+
+//go:embed schemas.json
+var builtin []byte
+
+// Builtin holds the schemas of the standard BibTeX entry types.
+var Builtin = mustLoad(builtin)
+
+func mustLoad(data []byte) *Set {
+	s, err := Load(data)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Load decodes a set of schemas and house rules from its JSON definition.
+func Load(data []byte) (*Set, error) {
+	s := &Set{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("validate: %w", err)
+	}
+	return s, nil
+}
+
+// LoadYAML decodes a set of schemas and house rules from its YAML definition.
+// Only the block and flow collections of strings needed to describe a set are
+// supported.
+func LoadYAML(data []byte) (*Set, error) {
+	v, err := decodeYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	data, err = json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return Load(data)
+}
+
+// LoadFile reads a set from a file, decoding it as YAML when the file has the
+// .yaml or .yml extension and as JSON otherwise.
+func LoadFile(path string) (*Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return LoadYAML(data)
+	default:
+		return Load(data)
+	}
+}
+
+// Merge returns a new set combining the schemas and rules of both sets. The
+// schemas and kinds of o take precedence over those of s, while the lists are
+// joined.
+func (s *Set) Merge(o *Set) *Set {
+	result := &Set{
+		Types:    make(map[string]Schema),
+		Kinds:    make(map[string]Kind),
+		Required: make(map[string][]string),
+	}
+	for _, set := range []*Set{s, o} {
+		for k, v := range set.Types {
+			result.Types[strings.ToLower(k)] = v
+		}
+		for k, v := range set.Kinds {
+			result.Kinds[strings.ToLower(k)] = v
+		}
+		for k, v := range set.Required {
+			k = strings.ToLower(k)
+			result.Required[k] = append(result.Required[k], v...)
+		}
+		result.Common = append(result.Common, set.Common...)
+		result.Allowed = append(result.Allowed, set.Allowed...)
+		result.Banned = append(result.Banned, set.Banned...)
+	}
+	return result
+}
+
+// Validate checks the entry against the built-in schemas.
+func Validate(e *parse.EntryDecl) []Violation {
+	return Builtin.Validate(e)
+}
+
+// Validate checks the entry against the schema of its type and the house
+// rules, and returns the violations in the order of the schema and entry
+// fields. Entries with a crossref field inherit fields from the referenced
+// entry, so their required fields are not checked.
+func (s *Set) Validate(e *parse.EntryDecl) []Violation {
+	result := []Violation{}
+	typ := strings.ToLower(e.Name)
+	schema, ok := s.Types[typ]
+	if !ok {
+		result = append(result, Violation{Reason: UnknownType, Key: e.CiteKey, Field: typ, Pos: e.Pos})
+	}
+	if len(s.Allowed) > 0 && !contains(s.Allowed, typ) {
+		result = append(result, Violation{Reason: Disallowed, Key: e.CiteKey, Field: typ, Pos: e.Pos})
+	}
+	if _, crossref := e.Get("crossref"); !crossref {
+		for _, req := range s.required(schema, typ) {
+			if !hasAny(e, strings.Split(req, "|")) {
+				result = append(result, Violation{Reason: Missing, Key: e.CiteKey, Field: req, Pos: e.Pos})
+			}
+		}
+	}
+	allowed := s.allowed(schema, typ)
+	for _, f := range e.Fields {
+		key := strings.ToLower(f.Key)
+		if contains(s.Banned, key) {
+			result = append(result, Violation{Reason: Banned, Key: e.CiteKey, Field: key, Pos: f.Pos})
+			continue
+		}
+		if ok && !allowed[key] {
+			result = append(result, Violation{Reason: Unknown, Key: e.CiteKey, Field: key, Pos: f.Pos})
+			continue
+		}
+		if k := s.Kinds[key]; !isKind(f.Value, k) {
+			result = append(result, Violation{Reason: Invalid, Key: e.CiteKey, Field: key, Kind: k, Pos: f.Pos})
+		}
+	}
+	return result
+}
+
+// Completeness returns the share of the required fields of the entry type
+// present in the entry, from 0 to 1. Entries of types without required
+// fields and entries inheriting fields through crossref are complete.
+func (s *Set) Completeness(e *parse.EntryDecl) float64 {
+	if _, crossref := e.Get("crossref"); crossref {
+		return 1
+	}
+	typ := strings.ToLower(e.Name)
+	required := s.required(s.Types[typ], typ)
+	if len(required) == 0 {
+		return 1
+	}
+	present := 0
+	for _, req := range required {
+		if hasAny(e, strings.Split(req, "|")) {
+			present++
+		}
+	}
+	return float64(present) / float64(len(required))
+}
+
+// RequiredFields lists the fields required in the entry type by its schema
+// and the house rules, with alternatives separated with `|`. Unknown entry
+// types require the fields of the house rules only.
+func (s *Set) RequiredFields(typ string) []string {
+	typ = strings.ToLower(typ)
+	return s.required(s.Types[typ], typ)
+}
+
+// Required lists the required fields of the entry type without duplicates,
+// extended with the ones required by the house rules.
+func (s *Set) required(schema Schema, typ string) []string {
+	result := []string{}
+	for _, list := range [][]string{schema.Required, s.Required["*"], s.Required[typ]} {
+		for _, f := range list {
+			if f = strings.ToLower(f); !contains(result, f) {
+				result = append(result, f)
+			}
+		}
+	}
+	return result
+}
+
+// Fields lists the names of the fields allowed in the entry type in sorted
+// order, with the alternatives of required fields listed separately. Unknown
+// entry types allow the common fields only.
+func (s *Set) Fields(typ string) []string {
+	typ = strings.ToLower(typ)
+	result := []string{}
+	for f := range s.allowed(s.Types[typ], typ) {
+		result = append(result, f)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (s *Set) allowed(schema Schema, typ string) map[string]bool {
+	result := make(map[string]bool)
+	for _, list := range [][]string{schema.Required, schema.Optional, s.Common, s.Required["*"], s.Required[typ]} {
+		for _, f := range list {
+			for _, alt := range strings.Split(f, "|") {
+				result[strings.ToLower(alt)] = true
+			}
+		}
+	}
+	return result
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAny checks if the entry has any of the fields under their names or
+// aliases.
+func hasAny(e *parse.EntryDecl, keys []string) bool {
+	for _, k := range keys {
+		if _, ok := e.Lookup(k); ok {
+			return true
+		}
+	}
+	return false
+}
+
+var months = map[string]bool{
+	"jan": true, "feb": true, "mar": true, "apr": true, "may": true, "jun": true,
+	"jul": true, "aug": true, "sep": true, "oct": true, "nov": true, "dec": true,
+	"january": true, "february": true, "march": true, "april": true,
+	"june": true, "july": true, "august": true, "september": true,
+	"october": true, "november": true, "december": true,
+}
+
+// IsKind checks if the raw field value holds the kind of value. Values
+// referencing macros other than the month abbreviations cannot be resolved
+// here and are accepted.
+func isKind(value string, k Kind) bool {
+	if k == Text || strings.Contains(value, "#") {
+		return true
+	}
+	v := parse.Unquote(value)
+	isMacro := v == value && !isDigits(v)
+	switch k {
+	case Integer:
+		return isMacro || isDigits(v)
+	case Language:
+		return isMacro || isLanguage(v)
+	case Pages:
+		_, err := pages.Parse(v)
+		return isMacro || err == nil
+	default:
+		if isDigits(v) {
+			var n int
+			fmt.Sscan(v, &n)
+			return n >= 1 && n <= 12
+		}
+		return months[strings.ToLower(v)]
+	}
+}
+
+// IsLanguage checks if the value lists language names or BCP 47 tags
+// separated with `and` or commas as BibLaTeX allows.
+func isLanguage(v string) bool {
+	for _, l := range strings.Split(strings.ReplaceAll(v, " and ", ","), ",") {
+		if !lang.Valid(strings.Trim(l, " {}")) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigits(s string) bool {
+	if s == `` {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
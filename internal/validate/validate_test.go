@@ -0,0 +1,294 @@
+package validate
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mdm-code/bibx/bibxtest"
+	"github.com/mdm-code/bibx/internal/parse"
+	"github.com/mdm-code/bibx/internal/scan"
+)
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name  string
+		entry *parse.EntryDecl
+		want  []Violation
+	}{
+		{
+			name:  "valid",
+			entry: bibxtest.Entry("article", "key", "author", "{A}", "title", "{T}", "journal", "{J}", "year", "1963", "month", "dec", "doi", "{10.1/x}"),
+			want:  []Violation{},
+		},
+		{
+			name:  "missing",
+			entry: bibxtest.Entry("book", "key", "title", "{T}", "year", "{2001}"),
+			want: []Violation{
+				{Reason: Missing, Key: "key", Field: "author|editor"},
+				{Reason: Missing, Key: "key", Field: "publisher"},
+			},
+		},
+		{
+			name:  "alternative",
+			entry: bibxtest.Entry("Book", "key", "Editor", "{E}", "title", "{T}", "publisher", "{P}", "year", "2001"),
+			want:  []Violation{},
+		},
+		{
+			name:  "unknown field",
+			entry: bibxtest.Entry("misc", "key", "colour", "{red}"),
+			want:  []Violation{{Reason: Unknown, Key: "key", Field: "colour"}},
+		},
+		{
+			name:  "invalid kinds",
+			entry: bibxtest.Entry("misc", "key", "year", "{199x}", "month", "{13}", "language", "{Englsh}"),
+			want: []Violation{
+				{Reason: Invalid, Key: "key", Field: "year", Kind: Integer},
+				{Reason: Invalid, Key: "key", Field: "month", Kind: Month},
+				{Reason: Invalid, Key: "key", Field: "language", Kind: Language},
+			},
+		},
+		{
+			name:  "valid kinds",
+			entry: bibxtest.Entry("misc", "key", "year", "macro", "month", `"December"`, "language", "{English and en-GB}"),
+			want:  []Violation{},
+		},
+		{
+			name:  "pages",
+			entry: bibxtest.Entry("article", "key", "author", "{A}", "title", "{T}", "journal", "{J}", "year", "2001", "pages", "{15--12}"),
+			want:  []Violation{{Reason: Invalid, Key: "key", Field: "pages", Kind: Pages}},
+		},
+		{
+			name:  "valid pages",
+			entry: bibxtest.Entry("article", "key", "author", "{A}", "title", "{T}", "journal", "{J}", "year", "2001", "pages", "{e1234, xi-xv}"),
+			want:  []Violation{},
+		},
+		{
+			name:  "crossref",
+			entry: bibxtest.Entry("inproceedings", "key", "author", "{A}", "title", "{T}", "crossref", "{proc}"),
+			want:  []Violation{},
+		},
+		{
+			name:  "unknown type",
+			entry: bibxtest.Entry("dataset", "key", "colour", "{red}", "year", "{x}"),
+			want: []Violation{
+				{Reason: UnknownType, Key: "key", Field: "dataset"},
+				{Reason: Invalid, Key: "key", Field: "year", Kind: Integer},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := Validate(c.entry); !reflect.DeepEqual(have, c.want) {
+				t.Errorf("have %v; want %v", have, c.want)
+			}
+		})
+	}
+}
+
+func TestKindString(t *testing.T) {
+	cases := []struct {
+		k    Kind
+		want string
+	}{
+		{Text, "text"},
+		{Pages, "pages"},
+		{Pages + 1, "Kind(5)"},
+	}
+	for _, c := range cases {
+		t.Run(c.want, func(t *testing.T) {
+			if have := c.k.String(); have != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
+
+func TestViolationError(t *testing.T) {
+	cases := []struct {
+		v    Violation
+		want string
+	}{
+		{Violation{Reason: Missing, Key: "a", Field: "author|editor"}, "a: BIBX0201 missing required field author or editor"},
+		{Violation{Reason: Unknown, Key: "a", Field: "colour"}, "a: BIBX0202 unknown field colour"},
+		{Violation{Reason: Invalid, Key: "a", Field: "year", Kind: Integer}, "a: BIBX0203 field year is not a valid integer value"},
+		{Violation{Reason: UnknownType, Key: "a", Field: "dataset"}, "a: BIBX0204 unknown entry type dataset"},
+	}
+	for _, c := range cases {
+		t.Run(c.want, func(t *testing.T) {
+			if have := c.v.Error(); have != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
+
+func TestViolationReport(t *testing.T) {
+	cases := []struct {
+		v    Violation
+		want string
+	}{
+		{Violation{Reason: Missing, Key: "a", Field: "title", Pos: scan.Pos{Line: 1, Column: 1}}, "1:1: error: a: title: missing required field title (BIBX0201 missing-field)"},
+		{Violation{Reason: Unknown, Key: "a", Field: "colour", Pos: scan.Pos{Line: 4, Column: 3}}, "4:3: warning: a: colour: unknown field colour (BIBX0202 unknown-field)"},
+		{Violation{Reason: UnknownType, Key: "a", Field: "dataset"}, "error: a: unknown entry type dataset (BIBX0204 unknown-type)"},
+	}
+	for _, c := range cases {
+		t.Run(c.want, func(t *testing.T) {
+			if have := c.v.Report().String(); have != c.want {
+				t.Errorf("have %q; want %q", have, c.want)
+			}
+		})
+	}
+}
+
+const houseJSON = `{
+  "allowed": ["article", "book", "thesis"],
+  "banned": ["abstract"],
+  "required": {"*": ["doi"], "article": ["pages", "year"]},
+  "types": {"thesis": {"required": ["author", "title"], "optional": ["year"]}}
+}`
+
+const houseYAML = `# House rules
+allowed: [article, book, thesis]
+banned:
+  - abstract
+required:
+  "*": [doi]
+  article:
+  - pages
+  - year  # already required
+types:
+  thesis:
+    required: ['author', 'title']
+    optional:
+      - year
+`
+
+func TestHouseRules(t *testing.T) {
+	house, err := Load([]byte(houseJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Builtin.Merge(house)
+	cases := []struct {
+		name  string
+		entry *parse.EntryDecl
+		want  []Violation
+	}{
+		{
+			name:  "extra required",
+			entry: bibxtest.Entry("article", "key", "author", "{A}", "title", "{T}", "journal", "{J}", "year", "1963"),
+			want: []Violation{
+				{Reason: Missing, Key: "key", Field: "doi"},
+				{Reason: Missing, Key: "key", Field: "pages"},
+			},
+		},
+		{
+			name:  "banned",
+			entry: bibxtest.Entry("thesis", "key", "author", "{A}", "title", "{T}", "doi", "{10.1/x}", "abstract", "{...}"),
+			want:  []Violation{{Reason: Banned, Key: "key", Field: "abstract"}},
+		},
+		{
+			name:  "disallowed",
+			entry: bibxtest.Entry("misc", "key", "doi", "{10.1/x}"),
+			want:  []Violation{{Reason: Disallowed, Key: "key", Field: "misc"}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := s.Validate(c.entry); !reflect.DeepEqual(have, c.want) {
+				t.Errorf("have %v; want %v", have, c.want)
+			}
+		})
+	}
+}
+
+func TestLoadYAML(t *testing.T) {
+	want, err := Load([]byte(houseJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	have, err := LoadYAML([]byte(houseYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have %+v; want %+v", have, want)
+	}
+}
+
+func TestLoadYAMLErr(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+	}{
+		{"no key", "allowed\n"},
+		{"indentation", "allowed: [a]\n  banned: [b]\n"},
+		{"tabs", "required:\n\t\"*\": [doi]\n"},
+		{"kind", "kinds:\n  year: date\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := LoadYAML([]byte(c.input)); err == nil {
+				t.Error("have nil; want error")
+			}
+		})
+	}
+}
+
+func TestFields(t *testing.T) {
+	cases := []struct {
+		typ  string
+		want []string
+	}{
+		{"misc", []string{"author", "howpublished", "month", "note", "title", "year"}},
+		{"Book", []string{"address", "author", "edition", "editor", "month", "note", "number", "publisher", "series", "title", "volume", "year"}},
+	}
+	s := &Set{Types: Builtin.Types}
+	for _, c := range cases {
+		t.Run(c.typ, func(t *testing.T) {
+			if have := s.Fields(c.typ); !reflect.DeepEqual(have, c.want) {
+				t.Errorf("have %v; want %v", have, c.want)
+			}
+		})
+	}
+}
+
+func TestRequiredFields(t *testing.T) {
+	cases := []struct {
+		typ  string
+		want []string
+	}{
+		{"Book", []string{"author|editor", "title", "publisher", "year", "doi"}},
+		{"misc", []string{"doi"}},
+		{"article", []string{"author", "title", "journal", "year", "doi", "url"}},
+		{"unknown", []string{"doi"}},
+	}
+	s := &Set{Types: Builtin.Types, Required: map[string][]string{"*": {"DOI"}, "article": {"url", "doi"}}}
+	for _, c := range cases {
+		t.Run(c.typ, func(t *testing.T) {
+			if have := s.RequiredFields(c.typ); !reflect.DeepEqual(have, c.want) {
+				t.Errorf("have %v; want %v", have, c.want)
+			}
+		})
+	}
+}
+
+func TestCompleteness(t *testing.T) {
+	cases := []struct {
+		name string
+		e    *parse.EntryDecl
+		want float64
+	}{
+		{"complete", bibxtest.Entry("article", "key", "author", "{A}", "title", "{T}", "journal", "{J}", "year", "2000"), 1},
+		{"half", bibxtest.Entry("article", "key", "author", "{A}", "title", "{T}"), 0.5},
+		{"crossref", bibxtest.Entry("inproceedings", "key", "crossref", "{P}"), 1},
+		{"no required fields", bibxtest.Entry("misc", "key"), 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if have := Builtin.Completeness(c.e); have != c.want {
+				t.Errorf("have %v; want %v", have, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package validate
+
+import (
+	"fmt"
+	"strings"
+)
+
+type yamlLine struct {
+	no     int
+	indent int
+	text   string
+}
+
+// YamlParser decodes the subset of YAML made of block mappings, block
+// sequences, flow sequences and scalars into values accepted by json.Marshal.
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func decodeYAML(data []byte) (interface{}, error) {
+	p := &yamlParser{}
+	for i, l := range strings.Split(string(data), "\n") {
+		l = strings.TrimRight(stripComment(l), " \t\r")
+		text := strings.TrimLeft(l, " ")
+		if text == `` || text == "---" {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("validate: line %d: tabs are not allowed in indentation", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{no: i + 1, indent: len(l) - len(text), text: text})
+	}
+	if len(p.lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	v, err := p.block(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return v, nil
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("validate: line %d: %s", p.lines[p.pos].no, fmt.Sprintf(format, args...))
+}
+
+func (p *yamlParser) block(indent int) (interface{}, error) {
+	if isItem(p.lines[p.pos].text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) sequence(indent int) ([]interface{}, error) {
+	result := []interface{}{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isItem(p.lines[p.pos].text) {
+		rest := strings.TrimSpace(strings.TrimPrefix(p.lines[p.pos].text, "-"))
+		p.pos++
+		if rest != `` {
+			result = append(result, scalar(rest))
+			continue
+		}
+		v, err := p.nested(indent)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v)
+	}
+	return result, nil
+}
+
+func (p *yamlParser) mapping(indent int) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		key, value, found := strings.Cut(p.lines[p.pos].text, ":")
+		if !found || (value != `` && value[0] != ' ') || isItem(key) {
+			return nil, p.errorf("expected a mapping key")
+		}
+		p.pos++
+		key = unquoteYAML(strings.TrimSpace(key))
+		if value = strings.TrimSpace(value); value != `` {
+			result[key] = scalar(value)
+			continue
+		}
+		// A sequence of a mapping value may share the indentation of its key.
+		if p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isItem(p.lines[p.pos].text) {
+			v, err := p.sequence(indent)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = v
+			continue
+		}
+		v, err := p.nested(indent)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = v
+	}
+	return result, nil
+}
+
+// Nested parses the block indented deeper than the parent, if there is one.
+func (p *yamlParser) nested(indent int) (interface{}, error) {
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return p.block(p.lines[p.pos].indent)
+	}
+	return nil, nil
+}
+
+func scalar(s string) interface{} {
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		result := []interface{}{}
+		for _, item := range strings.Split(s[1:len(s)-1], ",") {
+			if item = strings.TrimSpace(item); item != `` {
+				result = append(result, unquoteYAML(item))
+			}
+		}
+		return result
+	}
+	return unquoteYAML(s)
+}
+
+func unquoteYAML(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func isItem(s string) bool {
+	return s == "-" || strings.HasPrefix(s, "- ")
+}
+
+// StripComment removes a comment starting with `#` at the beginning of the
+// line or after a space unless it is inside quotes.
+func stripComment(l string) string {
+	var quote rune
+	for i, r := range l {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || l[i-1] == ' ' || l[i-1] == '\t'):
+			return l[:i]
+		}
+	}
+	return l
+}
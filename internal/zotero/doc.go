@@ -0,0 +1,6 @@
+/*
+Zotero package talks to the Zotero Web API to list, create and update the
+items of a library or of one of its collections. Items are exchanged as CSL
+items so that they can be converted to and from BibTeX entries.
+*/
+package zotero
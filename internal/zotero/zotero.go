@@ -0,0 +1,356 @@
+package zotero
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/mdm-code/bibx/internal/csl"
+	"github.com/mdm-code/bibx/internal/remote"
+)
+
+// DefaultEndpoint is the base URL of the Zotero Web API.
+const DefaultEndpoint = "https://api.zotero.org"
+
+// Items are listed in pages of pageSize and created in batches of
+// batchSize, the largest the API allows.
+const (
+	pageSize  = 100
+	batchSize = 50
+)
+
+// ErrModified is returned by Update when the item has been modified in the
+// library since its version was read.
+var ErrModified = errors.New("zotero: item modified since it was read")
+
+// Item types, the fields valid for them and the CSL item types they map
+// onto. Items of other CSL types are stored as documents.
+var itemTypes = map[string]itemType{
+	"article-journal":  {"journalArticle", []string{"title", "abstractNote", "publicationTitle", "volume", "issue", "pages", "date", "series", "DOI", "ISSN", "url", "extra"}, true},
+	"book":             {"book", []string{"title", "abstractNote", "series", "volume", "edition", "place", "publisher", "date", "ISBN", "url", "extra"}, true},
+	"chapter":          {"bookSection", []string{"title", "abstractNote", "bookTitle", "series", "volume", "edition", "place", "publisher", "date", "pages", "ISBN", "url", "extra"}, true},
+	"paper-conference": {"conferencePaper", []string{"title", "abstractNote", "date", "proceedingsTitle", "place", "publisher", "volume", "pages", "series", "DOI", "ISBN", "url", "extra"}, true},
+	"thesis":           {"thesis", []string{"title", "abstractNote", "thesisType", "university", "place", "date", "url", "extra"}, false},
+	"report":           {"report", []string{"title", "abstractNote", "reportNumber", "reportType", "seriesTitle", "place", "institution", "date", "pages", "url", "extra"}, false},
+	"manuscript":       {"manuscript", []string{"title", "abstractNote", "manuscriptType", "place", "date", "url", "extra"}, false},
+	"webpage":          {"webpage", []string{"title", "abstractNote", "websiteTitle", "date", "url", "extra"}, false},
+	"document":         {"document", []string{"title", "abstractNote", "publisher", "date", "url", "extra"}, true},
+}
+
+// CSL variables held by the Zotero fields.
+var variables = map[string]func(it *csl.Item) string{
+	"title":            func(it *csl.Item) string { return it.Title },
+	"abstractNote":     func(it *csl.Item) string { return it.Abstract },
+	"publicationTitle": func(it *csl.Item) string { return it.ContainerTitle },
+	"bookTitle":        func(it *csl.Item) string { return it.ContainerTitle },
+	"proceedingsTitle": func(it *csl.Item) string { return it.ContainerTitle },
+	"websiteTitle":     func(it *csl.Item) string { return it.ContainerTitle },
+	"series":           func(it *csl.Item) string { return it.CollectionTitle },
+	"seriesTitle":      func(it *csl.Item) string { return it.CollectionTitle },
+	"volume":           func(it *csl.Item) string { return string(it.Volume) },
+	"issue":            func(it *csl.Item) string { return string(it.Issue) },
+	"edition":          func(it *csl.Item) string { return string(it.Edition) },
+	"reportNumber":     func(it *csl.Item) string { return string(it.Number) },
+	"pages":            func(it *csl.Item) string { return it.Page },
+	"publisher":        func(it *csl.Item) string { return it.Publisher },
+	"university":       func(it *csl.Item) string { return it.Publisher },
+	"institution":      func(it *csl.Item) string { return it.Publisher },
+	"place":            func(it *csl.Item) string { return it.PublisherPlace },
+	"thesisType":       func(it *csl.Item) string { return it.Genre },
+	"reportType":       func(it *csl.Item) string { return it.Genre },
+	"manuscriptType":   func(it *csl.Item) string { return it.Genre },
+	"DOI":              func(it *csl.Item) string { return it.DOI },
+	"ISBN":             func(it *csl.Item) string { return it.ISBN },
+	"ISSN":             func(it *csl.Item) string { return it.ISSN },
+	"url":              func(it *csl.Item) string { return it.URL },
+	"date":             date,
+	"extra":            extra,
+}
+
+type itemType struct {
+	name    string
+	fields  []string
+	editors bool
+}
+
+// Item is an item of a library with its key, its version and its data in
+// the CSL form.
+type Item struct {
+	Key     string
+	Version int
+	CSL     csl.Item
+}
+
+// Client reads and writes the items of a user or a group library. Library
+// is either `users/<userID>` or `groups/<groupID>`, and Key is an API key
+// with access to the library. Failed requests are retried as the Retry
+// policy tells.
+type Client struct {
+	HTTP     *http.Client
+	Endpoint string
+	Retry    remote.Policy
+	Library  string
+	Key      string
+}
+
+// Items lists the top-level items of the collection, or of the whole library
+// if the collection is empty. Notes and attachments are skipped.
+func (c *Client) Items(ctx context.Context, collection string) ([]Item, error) {
+	path := "/items/top"
+	if collection != `` {
+		path = "/collections/" + url.PathEscape(collection) + path
+	}
+	result := []Item{}
+	for start := 0; ; start += pageSize {
+		q := url.Values{
+			"format":  {"json"},
+			"include": {"csljson,data"},
+			"limit":   {strconv.Itoa(pageSize)},
+			"start":   {strconv.Itoa(start)},
+		}
+		resp, err := c.do(ctx, http.MethodGet, path+"?"+q.Encode(), nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		page, err := Read(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, page...)
+		total, err := strconv.Atoi(resp.Header.Get("Total-Results"))
+		if err != nil || len(page) == 0 || start+pageSize >= total {
+			return result, nil
+		}
+	}
+}
+
+// Read decodes the items of a JSON response listing items with their CSL
+// data included. The citation key missing from the CSL data is taken from
+// the Citation Key line of the extra field.
+func Read(r io.Reader) ([]Item, error) {
+	var resp []struct {
+		Key     string   `json:"key"`
+		Version int      `json:"version"`
+		CSL     csl.Item `json:"csljson"`
+		Data    struct {
+			ItemType string `json:"itemType"`
+			Extra    string `json:"extra"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(r).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("zotero: %w", err)
+	}
+	result := []Item{}
+	for _, it := range resp {
+		if it.Data.ItemType == "note" || it.Data.ItemType == "attachment" {
+			continue
+		}
+		if it.CSL.CitationKey == `` {
+			it.CSL.CitationKey = citationKey(it.Data.Extra)
+		}
+		result = append(result, Item{Key: it.Key, Version: it.Version, CSL: it.CSL})
+	}
+	return result, nil
+}
+
+// Create adds the items to the library and to the collection unless it is
+// empty. Each batch of items carries a write token, so that a retried batch
+// is not created twice.
+func (c *Client) Create(ctx context.Context, collection string, items []csl.Item) error {
+	for len(items) > 0 {
+		n := len(items)
+		if n > batchSize {
+			n = batchSize
+		}
+		batch := []map[string]any{}
+		for _, it := range items[:n] {
+			data := Data(it)
+			if collection != `` {
+				data["collections"] = []string{collection}
+			}
+			batch = append(batch, data)
+		}
+		body, err := json.Marshal(batch)
+		if err != nil {
+			return err
+		}
+		token, err := writeToken()
+		if err != nil {
+			return err
+		}
+		header := http.Header{"Zotero-Write-Token": {token}}
+		resp, err := c.do(ctx, http.MethodPost, "/items", body, header)
+		if err != nil {
+			return err
+		}
+		var result struct {
+			Failed map[string]struct {
+				Message string `json:"message"`
+			} `json:"failed"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("zotero: %w", err)
+		}
+		for i := 0; i < n; i++ {
+			if f, ok := result.Failed[strconv.Itoa(i)]; ok {
+				return fmt.Errorf("zotero: creating %s: %s", items[i].CitationKey, f.Message)
+			}
+		}
+		items = items[n:]
+	}
+	return nil
+}
+
+// Update replaces the data of the item with the CSL item. It fails with
+// ErrModified if the item has been modified since its version was read.
+func (c *Client) Update(ctx context.Context, item Item, it csl.Item) error {
+	body, err := json.Marshal(Data(it))
+	if err != nil {
+		return err
+	}
+	header := http.Header{"If-Unmodified-Since-Version": {strconv.Itoa(item.Version)}}
+	resp, err := c.do(ctx, http.MethodPatch, "/items/"+url.PathEscape(item.Key), body, header)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Data converts the CSL item into the data of a Zotero item. All the fields
+// of its item type are set, the missing ones to empty strings, so that the
+// data replaces the whole item when it is used in an update. The citation
+// key and the note are kept in the extra field.
+func Data(it csl.Item) map[string]any {
+	typ, ok := itemTypes[it.Type]
+	if !ok {
+		typ = itemTypes["document"]
+	}
+	data := map[string]any{"itemType": typ.name}
+	for _, f := range typ.fields {
+		data[f] = variables[f](&it)
+	}
+	creators := []map[string]string{}
+	add := func(role string, ns []csl.Name) {
+		for _, n := range ns {
+			if n.Literal != `` {
+				creators = append(creators, map[string]string{"creatorType": role, "name": n.Literal})
+				continue
+			}
+			last, first := n.Family, n.Given
+			if n.Particle != `` {
+				last = n.Particle + " " + last
+			}
+			if n.Suffix != `` {
+				first += ", " + n.Suffix
+			}
+			creators = append(creators, map[string]string{"creatorType": role, "firstName": first, "lastName": last})
+		}
+	}
+	add("author", it.Author)
+	if typ.editors {
+		add("editor", it.Editor)
+	} else {
+		add("contributor", it.Editor)
+	}
+	data["creators"] = creators
+	return data
+}
+
+// Date writes the issued date in the ISO 8601 form Zotero parses.
+func date(it *csl.Item) string {
+	if it.Issued == nil || len(it.Issued.Parts) == 0 {
+		return ``
+	}
+	parts := []string{}
+	for i, p := range it.Issued.Parts[0] {
+		if i == 0 {
+			parts = append(parts, strconv.Itoa(p))
+		} else {
+			parts = append(parts, fmt.Sprintf("%02d", p))
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// Extra writes the citation key and the note into the extra field.
+func extra(it *csl.Item) string {
+	lines := []string{}
+	if it.CitationKey != `` {
+		lines = append(lines, "Citation Key: "+it.CitationKey)
+	}
+	if it.Note != `` {
+		lines = append(lines, it.Note)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CitationKey returns the key of the Citation Key line of the extra field.
+func citationKey(extra string) string {
+	for _, line := range strings.Split(extra, "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Citation Key") {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ``
+}
+
+// WriteToken returns a random token identifying a write request.
+func writeToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ``, err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Do sends an authenticated request to the path of the library and returns
+// the response if it succeeded.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, header http.Header) (*http.Response, error) {
+	endpoint := c.Endpoint
+	if endpoint == `` {
+		endpoint = DefaultEndpoint
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(endpoint, "/")+"/"+c.Library+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Zotero-API-Version", "3")
+	req.Header.Set("User-Agent", "bibx (https://github.com/mdm-code/bibx)")
+	if c.Key != `` {
+		req.Header.Set("Zotero-API-Key", c.Key)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := remote.Do(ctx, c.HTTP, c.Retry, req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return resp, nil
+	case http.StatusPreconditionFailed:
+		resp.Body.Close()
+		return nil, ErrModified
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("zotero: unexpected response status %s", resp.Status)
+	}
+}
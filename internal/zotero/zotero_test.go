@@ -0,0 +1,148 @@
+package zotero
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mdm-code/bibx/internal/csl"
+)
+
+func TestItems(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/1/collections/COLL/items/top" || r.Header.Get("Zotero-API-Key") != "secret" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		w.Header().Set("Total-Results", "101")
+		if start == 0 {
+			items := []string{}
+			for i := 0; i < pageSize-1; i++ {
+				items = append(items, fmt.Sprintf(`{"key":"K%d","version":1,"csljson":{"id":"1/K%d","type":"book"},"data":{"itemType":"book"}}`, i, i))
+			}
+			items = append(items, `{"key":"N","version":1,"data":{"itemType":"note"}}`)
+			fmt.Fprintf(w, "[%s]", strings.Join(items, ","))
+			return
+		}
+		w.Write([]byte(`[{"key":"LAST","version":7,"csljson":{"id":"1/LAST","type":"article-journal","title":"T"},"data":{"itemType":"journalArticle","extra":"tex.x: y\nCitation Key: Doe2020"}}]`))
+	}))
+	defer ts.Close()
+	c := &Client{Endpoint: ts.URL, Library: "users/1", Key: "secret"}
+	items, err := c.Items(context.Background(), "COLL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != pageSize {
+		t.Fatalf("have %d items; want %d", len(items), pageSize)
+	}
+	want := Item{Key: "LAST", Version: 7, CSL: csl.Item{ID: "1/LAST", Type: "article-journal", Title: "T", CitationKey: "Doe2020"}}
+	if have := items[len(items)-1]; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %+v; want %+v", have, want)
+	}
+	c.Key = "wrong"
+	if _, err := c.Items(context.Background(), "COLL"); err == nil {
+		t.Error("have nil error for a forbidden request")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	batches := [][]map[string]any{}
+	tokens := make(map[string]bool)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil || r.Method != http.MethodPost {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		tokens[r.Header.Get("Zotero-Write-Token")] = true
+		batches = append(batches, batch)
+		if len(batches) == 2 {
+			w.Write([]byte(`{"successful":{},"failed":{"1":{"code":400,"message":"invalid field"}}}`))
+			return
+		}
+		w.Write([]byte(`{"successful":{},"failed":{}}`))
+	}))
+	defer ts.Close()
+	items := []csl.Item{}
+	for i := 0; i < batchSize+2; i++ {
+		items = append(items, csl.Item{Type: "book", CitationKey: fmt.Sprintf("Key%d", i)})
+	}
+	c := &Client{Endpoint: ts.URL, Library: "groups/2"}
+	err := c.Create(context.Background(), "COLL", items)
+	if want := "zotero: creating Key51: invalid field"; err == nil || err.Error() != want {
+		t.Errorf("have %v; want %s", err, want)
+	}
+	if len(batches) != 2 || len(batches[0]) != batchSize || len(batches[1]) != 2 {
+		t.Fatalf("have %d batches", len(batches))
+	}
+	if have := batches[0][0]["collections"]; !reflect.DeepEqual(have, []any{"COLL"}) {
+		t.Errorf("have collections %v", have)
+	}
+	if len(tokens) != 2 || tokens[``] {
+		t.Errorf("have write tokens %v; want one per batch", tokens)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if r.Method != http.MethodPatch || r.URL.Path != "/users/1/items/ABCD" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("If-Unmodified-Since-Version") != "3" {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	c := &Client{Endpoint: ts.URL, Library: "users/1"}
+	if err := c.Update(context.Background(), Item{Key: "ABCD", Version: 3}, csl.Item{Type: "book"}); err != nil {
+		t.Errorf("have %v; want nil", err)
+	}
+	if err := c.Update(context.Background(), Item{Key: "ABCD", Version: 2}, csl.Item{Type: "book"}); err != ErrModified {
+		t.Errorf("have %v; want %v", err, ErrModified)
+	}
+}
+
+func TestData(t *testing.T) {
+	it := csl.Item{
+		Type:           "thesis",
+		CitationKey:    "Doe2020",
+		Title:          "A thesis",
+		Publisher:      "MIT",
+		Genre:          "PhD thesis",
+		ContainerTitle: "dropped",
+		Note:           "Unpublished.",
+		Author:         []csl.Name{{Family: "Vallée Poussin", Given: "Charles", Particle: "de la"}},
+		Editor:         []csl.Name{{Literal: "MIT Press"}},
+		Issued:         &csl.Date{Parts: [][]int{{2020, 3}}},
+	}
+	want := map[string]any{
+		"itemType":     "thesis",
+		"title":        "A thesis",
+		"abstractNote": "",
+		"thesisType":   "PhD thesis",
+		"university":   "MIT",
+		"place":        "",
+		"date":         "2020-03",
+		"url":          "",
+		"extra":        "Citation Key: Doe2020\nUnpublished.",
+		"creators": []map[string]string{
+			{"creatorType": "author", "firstName": "Charles", "lastName": "de la Vallée Poussin"},
+			{"creatorType": "contributor", "name": "MIT Press"},
+		},
+	}
+	if have := Data(it); !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v; want %v", have, want)
+	}
+}